@@ -0,0 +1,135 @@
+// Command inkctl is the operator CLI for the ink backend. It talks to the tenant
+// databases directly using the same configuration as the server.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+const usage = `usage: inkctl [-json] <command> [arguments]
+
+commands:
+  tenant create          provision a new tenant database, studio and owner (-password-stdin)
+  tenant list            list tenants in the registry
+  tenant suspend         stop serving a tenant (-tenant)
+  tenant resume          serve a suspended tenant again (-tenant)
+  tenant export          write a tenant's data and files to a tar.gz archive (-tenant, -out)
+  tenant import          restore an archive into a provisioned tenant (-tenant, -in, -replace)
+  migrate up             apply pending migrations to every tenant (-tenant, -dry-run)
+  migrate down           revert the last migrations of a tenant (-tenant, -steps, -dry-run)
+  migrate status         list applied, pending and drifted migrations per tenant
+  user create            add a user to a tenant (-tenant, -email, -role, -password-stdin)
+  user reset-password    set a new password and revoke the user's sessions (-password-stdin)
+  user list              list the users of a tenant (-tenant)
+  session revoke         revoke every refresh token of a user (-tenant, -user)
+
+flags:
+  -json                  print results as JSON
+
+Passwords are read from INKCTL_PASSWORD, or from the first line of stdin with
+-password-stdin; they are never taken as flags, which would leave them in the
+shell history and the process list.
+`
+
+func main() {
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	jsonOutput := flag.Bool("json", false, "print results as JSON")
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	out := newPrinter(*jsonOutput)
+
+	if err := logger.Init(zap.WarnLevel, zap.String("service", "inkctl")); err != nil {
+		out.fail(err)
+	}
+
+	cfg, err := config.InitConfig()
+	if err != nil {
+		out.fail(err)
+	}
+
+	switch args[0] {
+	case "tenant":
+		err = runTenant(&cfg, out, args[1:])
+	case "migrate":
+		err = runMigrate(&cfg, out, args[1:])
+	case "user":
+		err = runUser(&cfg, out, args[1:])
+	case "session":
+		err = runSession(&cfg, out, args[1:])
+	case "help":
+		fmt.Print(usage)
+		return
+	default:
+		err = fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+	}
+
+	if err != nil {
+		out.fail(err)
+	}
+}
+
+// passwordEnv holds the password of commands run without -password-stdin
+const passwordEnv = "INKCTL_PASSWORD"
+
+// readPassword returns the first line of stdin when fromStdin is set, or else
+// the value of INKCTL_PASSWORD
+func readPassword(fromStdin bool) (string, error) {
+	password := os.Getenv(passwordEnv)
+	if fromStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return "", fmt.Errorf("a password is required: set %s or pass it on stdin with -password-stdin", passwordEnv)
+	}
+	return password, nil
+}
+
+// openRegistry connects to the tenant registry and sets it on cfg
+func openRegistry(cfg *config.Config) (func(), error) {
+	registry, err := internal.NewTenantRegistry(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Registry = registry
+	return registry.Close, nil
+}
+
+// openTenants connects to the registry and returns managers that open tenant
+// pools on demand, plus a func that closes everything. The pools never create
+// or migrate databases; that is left to inkctl migrate.
+func openTenants(cfg *config.Config) (*config.TenantDBManager, *config.TenantRedisManager, func(), error) {
+	closeRegistry, err := openRegistry(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	dbManager := internal.NewConnectOnlyTenantDBManager(cfg)
+	redisManager := &config.TenantRedisManager{Tenants: map[string]*config.TenantRedis{}, Config: cfg}
+
+	return dbManager, redisManager, func() {
+		dbManager.Close()
+		redisManager.Close()
+		closeRegistry()
+	}, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal"
+)
+
+// migrateResult reports one tenant's migrations. Up and down list the affected
+// migrations; status lists every migration with its state.
+type migrateResult struct {
+	Tenant     string                    `json:"tenant"`
+	Action     string                    `json:"action"`
+	DryRun     bool                      `json:"dry_run,omitempty"`
+	Migrations []string                  `json:"migrations,omitempty"`
+	Status     []internal.MigrationState `json:"status,omitempty"`
+	Error      string                    `json:"error,omitempty"`
+}
+
+func runMigrate(cfg *config.Config, out *printer, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: inkctl migrate up|down|status [flags]")
+	}
+
+	var (
+		subdomain string
+		dryRun    bool
+		steps     int
+	)
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	fs.StringVar(&subdomain, "tenant", "", "only this tenant (required for down)")
+	switch args[0] {
+	case "up":
+		fs.BoolVar(&dryRun, "dry-run", false, "print pending migrations without applying them")
+	case "down":
+		fs.BoolVar(&dryRun, "dry-run", false, "print the migrations that would be reverted")
+		fs.IntVar(&steps, "steps", 1, "number of migrations to revert")
+	case "status":
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if args[0] == "down" && subdomain == "" {
+		return errors.New("migrate down requires -tenant")
+	}
+
+	closeRegistry, err := openRegistry(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeRegistry()
+
+	ctx := context.Background()
+	tenants, err := selectTenants(ctx, cfg, subdomain)
+	if err != nil {
+		return err
+	}
+
+	var (
+		results []migrateResult
+		failed  []string
+	)
+	for _, tenant := range tenants {
+		result := migrateTenant(ctx, cfg, &tenant, args[0], dryRun, steps)
+		if result.Error != "" {
+			failed = append(failed, tenant.Subdomain)
+		}
+		results = append(results, result)
+	}
+
+	if err = out.print(results, func(w io.Writer) { printMigrateResults(w, results) }); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("migration failed for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func migrateTenant(ctx context.Context, cfg *config.Config, tenant *config.TenantConfig, action string, dryRun bool, steps int) migrateResult {
+	result := migrateResult{Tenant: tenant.Subdomain, Action: action, DryRun: dryRun}
+
+	migrator, closeMigrator, err := internal.NewTenantMigrator(cfg, tenant)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer closeMigrator()
+
+	switch action {
+	case "status":
+		result.Status, err = migrator.Status(ctx)
+	case "up":
+		result.Migrations, err = migrator.Up(ctx, dryRun)
+	default:
+		result.Migrations, err = migrator.Down(ctx, steps, dryRun)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func printMigrateResults(w io.Writer, results []migrateResult) {
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Fprintf(w, "%s\terror\t%s\n", result.Tenant, result.Error)
+			continue
+		}
+
+		if result.Action == "status" {
+			for _, state := range result.Status {
+				appliedAt := ""
+				if state.AppliedAt != nil {
+					appliedAt = state.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Tenant, state.Name, state.Status, appliedAt)
+			}
+			continue
+		}
+
+		verb := map[string]string{"up": "applied", "down": "reverted"}[result.Action]
+		if result.DryRun {
+			verb = map[string]string{"up": "pending", "down": "would revert"}[result.Action]
+		}
+		if len(result.Migrations) == 0 {
+			fmt.Fprintf(w, "%s\tup to date\n", result.Tenant)
+		}
+		for _, name := range result.Migrations {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", result.Tenant, name, verb)
+		}
+	}
+}
+
+// selectTenants returns the active tenants, or only the named one
+func selectTenants(ctx context.Context, cfg *config.Config, subdomain string) ([]config.TenantConfig, error) {
+	if subdomain != "" {
+		tenant, err := cfg.GetTenantConfig(ctx, subdomain)
+		if err != nil {
+			return nil, err
+		}
+		return []config.TenantConfig{*tenant}, nil
+	}
+
+	tenants, err := internal.LoadTenants(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	active := tenants[:0]
+	for _, tenant := range tenants {
+		if tenant.Status == config.TenantStatusActive {
+			active = append(active, tenant)
+		}
+	}
+	return active, nil
+}
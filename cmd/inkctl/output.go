@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// printer writes command results as aligned text, or as a single JSON document
+// when inkctl runs with -json so the output can be scripted
+type printer struct {
+	json bool
+	out  io.Writer
+	err  io.Writer
+}
+
+func newPrinter(jsonOutput bool) *printer {
+	return &printer{json: jsonOutput, out: os.Stdout, err: os.Stderr}
+}
+
+// print writes v as JSON, or calls text with a tab-aligned writer
+func (p *printer) print(v any, text func(w io.Writer)) error {
+	if p.json {
+		enc := json.NewEncoder(p.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+	text(w)
+	return w.Flush()
+}
+
+// fail reports err and exits with a non-zero status
+func (p *printer) fail(err error) {
+	if p.json {
+		_ = json.NewEncoder(p.err).Encode(map[string]string{"error": err.Error()})
+	} else {
+		fmt.Fprintln(p.err, "inkctl:", err)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/auth"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/user"
+)
+
+type sessionRevokeResult struct {
+	Tenant string `json:"tenant"`
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+func runSession(cfg *config.Config, out *printer, args []string) error {
+	if len(args) == 0 || args[0] != "revoke" {
+		return errors.New("usage: inkctl session revoke -tenant <subdomain> -user <id or email>")
+	}
+
+	var subdomain, userRef string
+	fs := flag.NewFlagSet("session revoke", flag.ContinueOnError)
+	fs.StringVar(&subdomain, "tenant", "", "tenant subdomain (required)")
+	fs.StringVar(&userRef, "user", "", "user id or email (required)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if subdomain == "" || userRef == "" {
+		return errors.New("session revoke requires -tenant and -user")
+	}
+
+	dbManager, redisManager, closeAll, err := openTenants(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	ctx := context.Background()
+	userRepo := user.NewUserRepository(dbManager, redisManager)
+	lookup := userRepo.GetUserByID
+	if strings.Contains(userRef, "@") {
+		lookup = userRepo.GetUserByEmail
+	}
+	target, err := lookup(ctx, subdomain, userRef)
+	if err != nil {
+		return err
+	}
+
+	if err = auth.NewAuthRepository(dbManager, redisManager).InvalidateAllUserRefreshTokens(ctx, subdomain, target.ID); err != nil {
+		return err
+	}
+
+	result := sessionRevokeResult{Tenant: subdomain, UserID: target.ID, Email: target.Email}
+	return out.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "revoked all sessions of %s\n", result.Email)
+	})
+}
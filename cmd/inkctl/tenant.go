@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal"
+)
+
+// tenantResult is the machine-readable form of a tenant
+type tenantResult struct {
+	Subdomain string    `json:"subdomain"`
+	Studio    string    `json:"studio"`
+	Status    string    `json:"status"`
+	Plan      string    `json:"plan"`
+	Isolation string    `json:"isolation"`
+	Database  string    `json:"database"`
+	Schema    string    `json:"schema,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newTenantResult(tenant *config.TenantConfig) tenantResult {
+	isolation := tenant.Database.Isolation
+	if isolation == "" {
+		isolation = config.IsolationDatabase
+	}
+	return tenantResult{
+		Subdomain: tenant.Subdomain,
+		Studio:    tenant.Studio.Name,
+		Status:    tenant.Status,
+		Plan:      tenant.Plan,
+		Isolation: isolation,
+		Database:  tenant.Database.DB,
+		Schema:    tenant.Database.Schema,
+		CreatedAt: tenant.CreatedAt,
+	}
+}
+
+func runTenant(cfg *config.Config, out *printer, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: inkctl tenant create|list|suspend|resume|export|import [flags]")
+	}
+
+	switch args[0] {
+	case "create":
+		return tenantCreate(cfg, out, args[1:])
+	case "list":
+		return tenantList(cfg, out, args[1:])
+	case "suspend":
+		return tenantSetStatus(cfg, out, args[1:], "suspend", config.TenantStatusSuspended)
+	case "resume":
+		return tenantSetStatus(cfg, out, args[1:], "resume", config.TenantStatusActive)
+	case "export":
+		return tenantExport(cfg, out, args[1:])
+	case "import":
+		return tenantImport(cfg, out, args[1:])
+	default:
+		return fmt.Errorf("unknown tenant command %q", args[0])
+	}
+}
+
+func tenantList(cfg *config.Config, out *printer, args []string) error {
+	fs := flag.NewFlagSet("tenant list", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	closeRegistry, err := openRegistry(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeRegistry()
+
+	tenants, err := internal.LoadTenants(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+
+	results := make([]tenantResult, 0, len(tenants))
+	for _, tenant := range tenants {
+		results = append(results, newTenantResult(&tenant))
+	}
+
+	return out.print(results, func(w io.Writer) {
+		fmt.Fprintln(w, "SUBDOMAIN\tSTUDIO\tSTATUS\tPLAN\tISOLATION\tDATABASE\tSCHEMA")
+		for _, t := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.Subdomain, t.Studio, t.Status, t.Plan, t.Isolation, t.Database, t.Schema)
+		}
+	})
+}
+
+// tenantSetStatus suspends or resumes a tenant. Running servers pick the change
+// up on their next registry refresh.
+func tenantSetStatus(cfg *config.Config, out *printer, args []string, command, status string) error {
+	var subdomain string
+	fs := flag.NewFlagSet("tenant "+command, flag.ContinueOnError)
+	fs.StringVar(&subdomain, "tenant", "", "tenant subdomain (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if subdomain == "" {
+		return fmt.Errorf("tenant %s requires -tenant", command)
+	}
+
+	closeRegistry, err := openRegistry(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeRegistry()
+
+	ctx := context.Background()
+	if err = cfg.Registry.SetTenantStatus(ctx, subdomain, status); err != nil {
+		return err
+	}
+	tenant, err := cfg.Registry.GetTenant(ctx, subdomain)
+	if err != nil {
+		return err
+	}
+
+	result := newTenantResult(tenant)
+	return out.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "tenant %s is now %s\n", result.Subdomain, result.Status)
+	})
+}
+
+func tenantCreate(cfg *config.Config, out *printer, args []string) error {
+	var tenant config.TenantConfig
+
+	fs := flag.NewFlagSet("tenant create", flag.ContinueOnError)
+	fs.StringVar(&tenant.Subdomain, "subdomain", "", "tenant subdomain (required)")
+	fs.StringVar(&tenant.Plan, "plan", "", "billing plan (defaults to basic)")
+	fs.StringVar(&tenant.Studio.Name, "studio-name", "", "studio name (required)")
+	fs.StringVar(&tenant.Studio.Address, "studio-address", "", "studio address")
+	fs.StringVar(&tenant.Studio.Phone, "studio-phone", "", "studio phone")
+	fs.StringVar(&tenant.Studio.Email, "studio-email", "", "studio contact email")
+	fs.StringVar(&tenant.Studio.Website, "studio-website", "", "studio website")
+	fs.StringVar(&tenant.Owner.Email, "owner-email", "", "owner email (required)")
+	fs.StringVar(&tenant.Owner.DisplayName, "owner-display-name", "", "owner display name")
+	fs.StringVar(&tenant.Owner.Username, "owner-username", "", "owner username")
+	fs.StringVar(&tenant.Owner.FirstName, "owner-first-name", "", "owner first name")
+	fs.StringVar(&tenant.Owner.LastName, "owner-last-name", "", "owner last name")
+	fs.StringVar(&tenant.Database.Isolation, "isolation", "", "database or schema (defaults to the mode configured for the plan)")
+	fs.StringVar(&tenant.Database.DB, "db", "", "database name (defaults to tattoo_studio_<subdomain>, or isolation.shared_db in schema mode)")
+	fs.StringVar(&tenant.Database.Schema, "schema", "", "schema name in schema mode (defaults to tenant_<subdomain>)")
+	fs.StringVar(&tenant.Database.Host, "db-host", "", "database host (defaults to database.host)")
+	fs.StringVar(&tenant.Database.Port, "db-port", "", "database port (defaults to database.port)")
+	passwordStdin := fs.Bool("password-stdin", false, "read the owner password from stdin instead of "+passwordEnv)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	password, err := readPassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+	tenant.Owner.Password = password
+
+	closeRegistry, err := openRegistry(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeRegistry()
+
+	dbManager := &config.TenantDBManager{Tenants: map[string]*config.TenantDatabase{}, Config: cfg}
+	redisManager := &config.TenantRedisManager{Tenants: map[string]*config.TenantRedis{}, Config: cfg}
+	defer dbManager.Close()
+	defer redisManager.Close()
+
+	provisioner := internal.NewTenantProvisioner(cfg, dbManager, redisManager)
+	if err := provisioner.Provision(context.Background(), &tenant); err != nil {
+		return err
+	}
+
+	tenant.Status = config.TenantStatusActive
+	result := newTenantResult(&tenant)
+	return out.print(result, func(w io.Writer) {
+		if result.Isolation == config.IsolationSchema {
+			fmt.Fprintf(w, "tenant %s provisioned (database %s, schema %s)\n", result.Subdomain, result.Database, result.Schema)
+			return
+		}
+		fmt.Fprintf(w, "tenant %s provisioned (database %s)\n", result.Subdomain, result.Database)
+	})
+}
+
+// archiveResult summarises an export or import
+type archiveResult struct {
+	Tenant        string         `json:"tenant"`
+	File          string         `json:"file"`
+	SchemaVersion string         `json:"schema_version"`
+	Rows          map[string]int `json:"rows"`
+	Files         int            `json:"files"`
+}
+
+func newArchiveResult(tenant, file string, manifest *internal.ArchiveManifest) archiveResult {
+	return archiveResult{Tenant: tenant, File: file, SchemaVersion: manifest.SchemaVersion, Rows: manifest.Rows, Files: manifest.Files}
+}
+
+func printArchiveResult(w io.Writer, verb string, result archiveResult) {
+	fmt.Fprintf(w, "tenant %s %s %s (schema %s)\n", result.Tenant, verb, result.File, result.SchemaVersion)
+	for _, table := range internal.ArchiveTables() {
+		if count, ok := result.Rows[table]; ok {
+			fmt.Fprintf(w, "  %s\t%d\n", table, count)
+		}
+	}
+	fmt.Fprintf(w, "  files\t%d\n", result.Files)
+}
+
+func tenantExport(cfg *config.Config, out *printer, args []string) error {
+	var (
+		subdomain, file string
+		opts            internal.ExportOptions
+	)
+	fs := flag.NewFlagSet("tenant export", flag.ContinueOnError)
+	fs.StringVar(&subdomain, "tenant", "", "tenant subdomain (required)")
+	fs.StringVar(&file, "out", "", "archive to write (defaults to <tenant>.tar.gz)")
+	fs.BoolVar(&opts.IncludePasswordHashes, "include-password-hashes", false, "keep password hashes so users can log in after an import")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if subdomain == "" {
+		return errors.New("tenant export requires -tenant")
+	}
+	if file == "" {
+		file = subdomain + ".tar.gz"
+	}
+
+	archiver, closeAll, err := openArchiver(cfg, subdomain)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	manifest, err := archiver.Export(context.Background(), f, opts)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file)
+		return err
+	}
+
+	result := newArchiveResult(subdomain, file, manifest)
+	return out.print(result, func(w io.Writer) { printArchiveResult(w, "exported to", result) })
+}
+
+func tenantImport(cfg *config.Config, out *printer, args []string) error {
+	var (
+		subdomain, file string
+		opts            internal.ImportOptions
+	)
+	fs := flag.NewFlagSet("tenant import", flag.ContinueOnError)
+	fs.StringVar(&subdomain, "tenant", "", "tenant subdomain (required)")
+	fs.StringVar(&file, "in", "", "archive to read (required)")
+	fs.BoolVar(&opts.Replace, "replace", false, "delete the tenant's existing data, such as the studio and owner created on provisioning")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if subdomain == "" || file == "" {
+		return errors.New("tenant import requires -tenant and -in")
+	}
+
+	archiver, closeAll, err := openArchiver(cfg, subdomain)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	manifest, err := archiver.Import(context.Background(), f, opts)
+	if err != nil {
+		return err
+	}
+
+	result := newArchiveResult(subdomain, file, manifest)
+	return out.print(result, func(w io.Writer) { printArchiveResult(w, "imported from", result) })
+}
+
+// openArchiver connects to the registry and the tenant's database
+func openArchiver(cfg *config.Config, subdomain string) (*internal.Archiver, func(), error) {
+	closeRegistry, err := openRegistry(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tenant, err := cfg.GetTenantConfig(context.Background(), subdomain)
+	if err != nil {
+		closeRegistry()
+		return nil, nil, err
+	}
+
+	archiver, closeArchiver, err := internal.NewTenantArchiver(cfg, tenant)
+	if err != nil {
+		closeRegistry()
+		return nil, nil, err
+	}
+	return archiver, func() {
+		closeArchiver()
+		closeRegistry()
+	}, nil
+}
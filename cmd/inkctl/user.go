@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/auth"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/user"
+)
+
+// userResult is the machine-readable form of a user
+type userResult struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+func runUser(cfg *config.Config, out *printer, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: inkctl user create|reset-password|list [flags]")
+	}
+
+	switch args[0] {
+	case "create":
+		return userCreate(cfg, out, args[1:])
+	case "reset-password":
+		return userResetPassword(cfg, out, args[1:])
+	case "list":
+		return userList(cfg, out, args[1:])
+	default:
+		return fmt.Errorf("unknown user command %q", args[0])
+	}
+}
+
+// userCreate adds a user to the tenant's studio and its staff
+func userCreate(cfg *config.Config, out *printer, args []string) error {
+	var subdomain, username, email, role string
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	fs.StringVar(&subdomain, "tenant", "", "tenant subdomain (required)")
+	fs.StringVar(&email, "email", "", "user email (required)")
+	fs.StringVar(&username, "username", "", "username")
+	fs.StringVar(&role, "role", "ARTIST", "role: OWNER, ARTIST or ASSISTANT")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin instead of "+passwordEnv)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if subdomain == "" || email == "" {
+		return errors.New("user create requires -tenant and -email")
+	}
+	password, err := readPassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	dbManager, redisManager, closeAll, err := openTenants(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	created := &domain.User{
+		Username: username,
+		Email:    email,
+		Password: string(hashedPassword),
+		Role:     strings.ToUpper(role),
+	}
+	if err = user.NewUserRepository(dbManager, redisManager).InsertUser(context.Background(), subdomain, created); err != nil {
+		return err
+	}
+
+	result := userResult{ID: created.ID, Username: created.Username, Email: created.Email, Role: created.Role}
+	return out.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "user %s created in %s (id %s)\n", result.Email, subdomain, result.ID)
+	})
+}
+
+func userResetPassword(cfg *config.Config, out *printer, args []string) error {
+	var subdomain, email string
+	fs := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	fs.StringVar(&subdomain, "tenant", "", "tenant subdomain (required)")
+	fs.StringVar(&email, "email", "", "user email (required)")
+	passwordStdin := fs.Bool("password-stdin", false, "read the new password from stdin instead of "+passwordEnv)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if subdomain == "" || email == "" {
+		return errors.New("user reset-password requires -tenant and -email")
+	}
+	password, err := readPassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	dbManager, redisManager, closeAll, err := openTenants(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	ctx := context.Background()
+	target, err := user.NewUserRepository(dbManager, redisManager).GetUserByEmail(ctx, subdomain, email)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	authRepo := auth.NewAuthRepository(dbManager, redisManager)
+	if err = authRepo.UpdatePassword(ctx, subdomain, target.ID, string(hashedPassword)); err != nil {
+		return err
+	}
+	// A reset must log the user out everywhere
+	if err = authRepo.InvalidateAllUserRefreshTokens(ctx, subdomain, target.ID); err != nil {
+		return err
+	}
+
+	result := userResult{ID: target.ID, Username: target.Username, Email: target.Email, Role: target.Role}
+	return out.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "password reset for %s; existing sessions revoked\n", result.Email)
+	})
+}
+
+func userList(cfg *config.Config, out *printer, args []string) error {
+	var subdomain string
+	fs := flag.NewFlagSet("user list", flag.ContinueOnError)
+	fs.StringVar(&subdomain, "tenant", "", "tenant subdomain (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if subdomain == "" {
+		return errors.New("user list requires -tenant")
+	}
+
+	dbManager, redisManager, closeAll, err := openTenants(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	users, err := user.NewUserRepository(dbManager, redisManager).GetAllUsers(context.Background(), subdomain)
+	if err != nil {
+		return err
+	}
+
+	results := make([]userResult, 0, len(users))
+	for _, u := range users {
+		results = append(results, userResult{ID: u.ID, Username: u.Username, Email: u.Email, Role: u.Role})
+	}
+	return out.print(results, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tEMAIL\tUSERNAME\tROLE")
+		for _, u := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", u.ID, u.Email, u.Username, u.Role)
+		}
+	})
+}
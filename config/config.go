@@ -2,25 +2,54 @@ package config
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 
 	"github.com/spf13/viper"
+	"golang.org/x/sync/singleflight"
 )
 
 //go:embed config.yml
 var embeddedConfig []byte
 
+// Tenant lifecycle states stored in the control-plane registry
+const (
+	TenantStatusActive    = "active"
+	TenantStatusSuspended = "suspended"
+	TenantStatusDeleted   = "deleted"
+)
+
+// Tenant isolation modes: a dedicated database, or a schema inside a shared database
+const (
+	IsolationDatabase = "database"
+	IsolationSchema   = "schema"
+)
+
 // TenantConfig represents configuration for a single tenant (studio)
 type TenantConfig struct {
 	Studio    StudioConfig   `mapstructure:"studio"`
 	Owner     OwnerConfig    `mapstructure:"owner"`
 	Database  DatabaseConfig `mapstructure:"database"`
 	Subdomain string         `mapstructure:"subdomain"`
+	Status    string         `mapstructure:"status"`
+	Plan      string         `mapstructure:"plan"`
+	CreatedAt time.Time      `mapstructure:"-"`
+}
+
+// TenantRegistry is the control-plane source of truth for tenants
+type TenantRegistry interface {
+	ListTenants(ctx context.Context) ([]TenantConfig, error)
+	GetTenant(ctx context.Context, subdomain string) (*TenantConfig, error)
+	RegisterTenant(ctx context.Context, tenant *TenantConfig) error
+	SetTenantStatus(ctx context.Context, subdomain, status string) error
+	DeleteTenant(ctx context.Context, subdomain string) error
 }
 
 // StudioConfig represents studio-specific details
@@ -51,17 +80,37 @@ type DatabaseConfig struct {
 	DB                string `mapstructure:"db"`
 	SSLMODE           string `mapstructure:"sslmode"`
 	MaxConWaitingTime int    `mapstructure:"max_con_waiting_time"`
+	Isolation         string `mapstructure:"isolation"` // IsolationDatabase (default) or IsolationSchema
+	Schema            string `mapstructure:"schema"`    // tenant schema when Isolation is IsolationSchema
+}
+
+// IsolationConfig chooses where newly provisioned tenants are stored
+type IsolationConfig struct {
+	DefaultMode string   `mapstructure:"default_mode"`
+	SharedDB    string   `mapstructure:"shared_db"`    // database holding schema-mode tenants
+	SchemaPlans []string `mapstructure:"schema_plans"` // plans provisioned in schema mode
 }
 
 // Config represents the overall application configuration
 type Config struct {
 	Mode             string                 `mapstructure:"mode"`
 	Dotenv           string                 `mapstructure:"dotenv"`
-	Tenants          []TenantConfig         `mapstructure:"tenants"`
+	Database         DatabaseConfig         `mapstructure:"database"`
+	ControlPlane     DatabaseConfig         `mapstructure:"control_plane"`
+	TenantRefresh    time.Duration          `mapstructure:"tenant_refresh_interval"`
+	Admin            AdminConfig            `mapstructure:"admin"`
+	Tenants          []TenantConfig         `mapstructure:"tenants"` // Deprecated: imported into the registry at startup
+	Registry         TenantRegistry         `mapstructure:"-"`
+	TenantPools      TenantPoolConfig       `mapstructure:"tenant_pools"`
+	Isolation        IsolationConfig        `mapstructure:"isolation"`
 	Handlers         HandlersConfig         `mapstructure:"handlers"`
 	Server           ServerConfig           `mapstructure:"server"`
 	UpstreamServices UpstreamServicesConfig `mapstructure:"upstream_services"`
 	Redis            RedisConfig            `mapstructure:"redis"`
+	Reminders        ReminderConfig         `mapstructure:"reminders"`
+	Waitlist         WaitlistConfig         `mapstructure:"waitlist"`
+	Storage          StorageConfig          `mapstructure:"storage"`
+	Payments         PaymentsConfig         `mapstructure:"payments"`
 }
 
 // HandlersConfig, ServerConfig, UpstreamServicesConfig, RedisConfig remain similar
@@ -103,6 +152,11 @@ type UpstreamServicesConfig struct {
 	Meals       string `mapstructure:"meals"`
 }
 
+// AdminConfig holds settings for the operator-facing admin API
+type AdminConfig struct {
+	Token string `mapstructure:"token"`
+}
+
 type RedisConfig struct {
 	Host string        `mapstructure:"host"`
 	Port string        `mapstructure:"port"`
@@ -111,13 +165,99 @@ type RedisConfig struct {
 	TTL  time.Duration `mapstructure:"ttl"`
 }
 
+// TenantPoolConfig bounds the connection pools opened for tenant databases
+type TenantPoolConfig struct {
+	MaxTotalConns     int32         `mapstructure:"max_total_conns"`
+	MaxConnsPerTenant int32         `mapstructure:"max_conns_per_tenant"`
+	IdleTTL           time.Duration `mapstructure:"idle_ttl"`
+}
+
+// ReminderConfig tunes the appointment reminder scheduler
+type ReminderConfig struct {
+	Interval  time.Duration `mapstructure:"interval"`
+	BatchSize int           `mapstructure:"batch_size"`
+}
+
+// WaitlistConfig tunes the waitlist backfill. OfferHold is how long an offer
+// holds a freed slot for the customer it is offered to.
+type WaitlistConfig struct {
+	Interval  time.Duration `mapstructure:"interval"`
+	OfferHold time.Duration `mapstructure:"offer_hold"`
+	BatchSize int           `mapstructure:"batch_size"`
+}
+
+// ModeDevelopment is the Mode of local development setups
+const ModeDevelopment = "development"
+
+// PaymentsConfig chooses the payment processor. Without one, payment RPCs
+// answer Unavailable. The "fake" processor keeps intents in memory and
+// accepts every payment, so it is only allowed in development mode.
+type PaymentsConfig struct {
+	Provider string `mapstructure:"provider"` // "" (disabled) or "fake"
+}
+
+// StorageConfig chooses where uploaded files are kept and how they are served.
+// Downloads go through URLs under PublicURL signed with URLSecret that expire
+// after URLTTL. MaxUploadSize caps an upload in bytes by tenant plan; the
+// "default" entry covers the other plans.
+type StorageConfig struct {
+	Backend       string             `mapstructure:"backend"` // "local" (default) or "s3"
+	Local         LocalStorageConfig `mapstructure:"local"`
+	S3            S3StorageConfig    `mapstructure:"s3"`
+	PublicURL     string             `mapstructure:"public_url"`
+	URLSecret     string             `mapstructure:"url_secret"`
+	URLTTL        time.Duration      `mapstructure:"url_ttl"`
+	MaxUploadSize map[string]int64   `mapstructure:"max_upload_size"`
+}
+
+// LocalStorageConfig keeps files in a directory of the server
+type LocalStorageConfig struct {
+	Dir string `mapstructure:"dir"`
+}
+
+// S3StorageConfig keeps files in a bucket of an S3-compatible service. Set
+// PathStyle for services such as MinIO that do not serve buckets as
+// subdomains.
+type S3StorageConfig struct {
+	Endpoint  string `mapstructure:"endpoint"`
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	PathStyle bool   `mapstructure:"path_style"`
+}
+
 type TenantDatabase struct {
 	Pool *pgxpool.Pool
+
+	// lastUsed is the unix nano time of the last GetTenantDB call for the tenant
+	lastUsed atomic.Int64
 }
 
+// TenantDBOpener opens the pool for a tenant the first time it is requested
+type TenantDBOpener func(ctx context.Context, subdomain string) (*pgxpool.Pool, error)
+
 type TenantDBManager struct {
+	mu      sync.RWMutex
 	Tenants map[string]*TenantDatabase // Key is subdomain
 	Config  *Config
+
+	// Opener, when set, lets GetTenantDB open pools lazily
+	Opener TenantDBOpener
+	// MaxTotalConns caps the summed MaxConns of all open and draining pools; 0
+	// means no cap
+	MaxTotalConns int32
+
+	// opens lets concurrent first requests for a tenant share one pool
+	// without holding up other tenants
+	opens     singleflight.Group
+	evictions atomic.Int64
+
+	// draining sums the MaxConns of evicted pools that are not closed yet;
+	// they still count against MaxTotalConns. drained is closed and replaced
+	// each time one of them closes. Both are guarded by mu.
+	draining int32
+	drained  chan struct{}
 }
 
 type TenantRedis struct {
@@ -125,6 +265,7 @@ type TenantRedis struct {
 }
 
 type TenantRedisManager struct {
+	mu      sync.RWMutex
 	Tenants map[string]*TenantRedis // Key is subdomain
 	Config  *Config
 }
@@ -160,8 +301,12 @@ func InitConfig() (Config, error) {
 	return config, nil
 }
 
-// GetTenantConfig retrieves a tenant's configuration by subdomain
-func (c *Config) GetTenantConfig(subdomain string) (*TenantConfig, error) {
+// GetTenantConfig retrieves a tenant's configuration by subdomain from the registry,
+// falling back to tenants still listed in the config file
+func (c *Config) GetTenantConfig(ctx context.Context, subdomain string) (*TenantConfig, error) {
+	if c.Registry != nil {
+		return c.Registry.GetTenant(ctx, subdomain)
+	}
 	for _, tenant := range c.Tenants {
 		if tenant.Subdomain == subdomain {
 			return &tenant, nil
@@ -170,17 +315,333 @@ func (c *Config) GetTenantConfig(subdomain string) (*TenantConfig, error) {
 	return nil, fmt.Errorf("tenant with subdomain %s not found", subdomain)
 }
 
+// GetTenantDB returns the tenant pool, opening it on first use when an Opener is
+// configured. Opening a pool may evict the least recently used pools to stay
+// within MaxTotalConns, and waits for evicted pools to close when they still
+// hold the room it needs.
 func (m *TenantDBManager) GetTenantDB(subdomain string) (*pgxpool.Pool, error) {
-	if tenantDB, ok := m.Tenants[subdomain]; ok {
-		return tenantDB.Pool, nil
+	if pool, ok := m.lookup(subdomain); ok {
+		return pool, nil
+	}
+	if m.Opener == nil {
+		return nil, fmt.Errorf("no database found for tenant with subdomain: %s", subdomain)
+	}
+
+	pool, err, _ := m.opens.Do(subdomain, func() (interface{}, error) {
+		// Another request may have opened the pool in the meantime
+		if pool, ok := m.lookup(subdomain); ok {
+			return pool, nil
+		}
+		pool, err := m.Opener(context.Background(), subdomain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database for tenant %s: %w", subdomain, err)
+		}
+		if err = m.admit(subdomain, pool); err != nil {
+			pool.Close()
+			return nil, err
+		}
+		return pool, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pool.(*pgxpool.Pool), nil
+}
+
+func (m *TenantDBManager) lookup(subdomain string) (*pgxpool.Pool, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenantDB, ok := m.Tenants[subdomain]
+	if !ok {
+		return nil, false
+	}
+	tenantDB.lastUsed.Store(time.Now().UnixNano())
+	return tenantDB.Pool, true
+}
+
+// HasTenant reports whether a pool is currently open for the subdomain
+func (m *TenantDBManager) HasTenant(subdomain string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.Tenants[subdomain]
+	return ok
+}
+
+// AddTenant registers a tenant pool so it can be served without a restart. Least
+// recently used pools are closed when the new pool would exceed MaxTotalConns.
+// Unlike GetTenantDB it does not wait for evicted pools to close.
+func (m *TenantDBManager) AddTenant(subdomain string, pool *pgxpool.Pool) {
+	m.mu.Lock()
+	evicted := m.evictLocked(subdomain, pool.Config().MaxConns)
+	evicted = append(evicted, m.putLocked(subdomain, pool)...)
+	m.mu.Unlock()
+
+	m.drainPools(evicted)
+}
+
+// admit registers a newly opened pool once the open and draining pools leave
+// room for it. It gives up after poolAdmitTimeout, but admits the pool anyway
+// when no draining pool is left to wait for.
+func (m *TenantDBManager) admit(subdomain string, pool *pgxpool.Pool) error {
+	need := pool.Config().MaxConns
+	timeout := time.NewTimer(poolAdmitTimeout)
+	defer timeout.Stop()
+
+	for {
+		m.mu.Lock()
+		evicted := m.evictLocked(subdomain, need)
+		room := m.MaxTotalConns <= 0 || m.draining == 0 ||
+			m.openConnsLocked(subdomain)+m.draining+need <= m.MaxTotalConns
+		if room {
+			evicted = append(evicted, m.putLocked(subdomain, pool)...)
+		}
+		if m.drained == nil {
+			m.drained = make(chan struct{})
+		}
+		drained := m.drained
+		m.mu.Unlock()
+
+		m.drainPools(evicted)
+		if room {
+			return nil
+		}
+		select {
+		case <-drained:
+		case <-timeout.C:
+			return fmt.Errorf("no room for tenant %s within max_total_conns: evicted pools are still draining", subdomain)
+		}
+	}
+}
+
+// putLocked stores the pool of a tenant and returns the pool it replaced
+func (m *TenantDBManager) putLocked(subdomain string, pool *pgxpool.Pool) []*TenantDatabase {
+	tenantDB := &TenantDatabase{Pool: pool}
+	tenantDB.lastUsed.Store(time.Now().UnixNano())
+	var evicted []*TenantDatabase
+	if old, ok := m.Tenants[subdomain]; ok && old.Pool != pool {
+		m.draining += old.Pool.Config().MaxConns
+		evicted = append(evicted, old)
+	}
+	m.Tenants[subdomain] = tenantDB
+	return evicted
+}
+
+// openConnsLocked sums the MaxConns of the open pools, except the one named
+// by skip
+func (m *TenantDBManager) openConnsLocked(skip string) int32 {
+	var total int32
+	for subdomain, tenantDB := range m.Tenants {
+		if subdomain != skip {
+			total += tenantDB.Pool.Config().MaxConns
+		}
+	}
+	return total
+}
+
+// evictLocked removes least recently used pools, never the one named by keep,
+// until the open pools and a pool of need connections for keep fit within
+// MaxTotalConns. Evicted pools count as draining until they are closed.
+func (m *TenantDBManager) evictLocked(keep string, need int32) []*TenantDatabase {
+	if m.MaxTotalConns <= 0 {
+		return nil
+	}
+
+	total := m.openConnsLocked(keep) + need
+	var evicted []*TenantDatabase
+	for total > m.MaxTotalConns {
+		victim, oldest := "", int64(0)
+		for subdomain, tenantDB := range m.Tenants {
+			if subdomain == keep {
+				continue
+			}
+			if used := tenantDB.lastUsed.Load(); victim == "" || used < oldest {
+				victim, oldest = subdomain, used
+			}
+		}
+		if victim == "" {
+			break
+		}
+		tenantDB := m.Tenants[victim]
+		total -= tenantDB.Pool.Config().MaxConns
+		m.draining += tenantDB.Pool.Config().MaxConns
+		delete(m.Tenants, victim)
+		evicted = append(evicted, tenantDB)
+		m.evictions.Add(1)
+	}
+	return evicted
+}
+
+// EvictIdle closes the pools that have not been used for longer than ttl and
+// returns the affected subdomains
+func (m *TenantDBManager) EvictIdle(ttl time.Duration) []string {
+	cutoff := time.Now().Add(-ttl).UnixNano()
+
+	m.mu.Lock()
+	var (
+		subdomains []string
+		evicted    []*TenantDatabase
+	)
+	for subdomain, tenantDB := range m.Tenants {
+		if tenantDB.lastUsed.Load() < cutoff && tenantDB.Pool.Stat().AcquiredConns() == 0 {
+			subdomains = append(subdomains, subdomain)
+			evicted = append(evicted, tenantDB)
+			m.draining += tenantDB.Pool.Config().MaxConns
+			delete(m.Tenants, subdomain)
+		}
+	}
+	m.evictions.Add(int64(len(evicted)))
+	m.mu.Unlock()
+
+	m.drainPools(evicted)
+	return subdomains
+}
+
+// Stats returns a snapshot of every open tenant pool keyed by subdomain
+func (m *TenantDBManager) Stats() map[string]*pgxpool.Stat {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stats := make(map[string]*pgxpool.Stat, len(m.Tenants))
+	for subdomain, tenantDB := range m.Tenants {
+		stats[subdomain] = tenantDB.Pool.Stat()
+	}
+	return stats
+}
+
+// Evictions returns how many pools have been closed for being idle or to make room
+func (m *TenantDBManager) Evictions() int64 {
+	return m.evictions.Load()
+}
+
+const (
+	// poolDrainGrace is how long an evicted pool stays usable after it was
+	// last handed out, so requests already holding it can finish
+	poolDrainGrace = time.Minute
+	// poolDrainInterval is how often a draining pool is checked
+	poolDrainInterval = 5 * time.Second
+	// poolAdmitTimeout is how long GetTenantDB waits for draining pools to
+	// make room; every pool evicted before the wait has closed by then unless
+	// it still has acquired connections
+	poolAdmitTimeout = poolDrainGrace + 2*poolDrainInterval
+)
+
+// drainPools closes evicted pools in the background. Callers of GetTenantDB
+// may still hold an evicted pool, so its idle connections are released at once
+// but the pool is only closed when it has been unused for poolDrainGrace and
+// has no acquired connections.
+func (m *TenantDBManager) drainPools(evicted []*TenantDatabase) {
+	for _, tenantDB := range evicted {
+		tenantDB.Pool.Reset()
+		go m.drainPool(tenantDB)
+	}
+}
+
+func (m *TenantDBManager) drainPool(tenantDB *TenantDatabase) {
+	ticker := time.NewTicker(poolDrainInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		unused := time.Since(time.Unix(0, tenantDB.lastUsed.Load()))
+		if unused >= poolDrainGrace && tenantDB.Pool.Stat().AcquiredConns() == 0 {
+			break
+		}
+	}
+	tenantDB.Pool.Close()
+
+	m.mu.Lock()
+	m.draining -= tenantDB.Pool.Config().MaxConns
+	if m.drained != nil {
+		close(m.drained)
+		m.drained = nil
+	}
+	m.mu.Unlock()
+}
+
+// RemoveTenant unregisters a tenant and returns its pool so the caller can close it
+func (m *TenantDBManager) RemoveTenant(subdomain string) *pgxpool.Pool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenantDB, ok := m.Tenants[subdomain]
+	if !ok {
+		return nil
+	}
+	delete(m.Tenants, subdomain)
+	return tenantDB.Pool
+}
+
+// Subdomains lists the tenants that currently have a pool registered
+func (m *TenantDBManager) Subdomains() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	subdomains := make([]string, 0, len(m.Tenants))
+	for subdomain := range m.Tenants {
+		subdomains = append(subdomains, subdomain)
+	}
+	return subdomains
+}
+
+// Close closes every registered tenant pool
+func (m *TenantDBManager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for subdomain, tenantDB := range m.Tenants {
+		tenantDB.Pool.Close()
+		delete(m.Tenants, subdomain)
 	}
-	return nil, fmt.Errorf("no database found for tenant with subdomain: %s", subdomain)
 }
 
 func (m *TenantRedisManager) GetTenantRedis(subdomain string) (*redis.Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	tenant, exists := m.Tenants[subdomain]
 	if !exists {
 		return nil, fmt.Errorf("tenant not found: %s", subdomain)
 	}
 	return tenant.Client, nil
 }
+
+// HasTenant reports whether a Redis client is registered for the subdomain
+func (m *TenantRedisManager) HasTenant(subdomain string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.Tenants[subdomain]
+	return ok
+}
+
+// AddTenant registers a tenant Redis client so it can be served without a restart
+func (m *TenantRedisManager) AddTenant(subdomain string, client *redis.Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Tenants[subdomain] = &TenantRedis{Client: client}
+}
+
+// RemoveTenant unregisters a tenant and returns its client so the caller can close it
+func (m *TenantRedisManager) RemoveTenant(subdomain string) *redis.Client {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenant, ok := m.Tenants[subdomain]
+	if !ok {
+		return nil
+	}
+	delete(m.Tenants, subdomain)
+	return tenant.Client
+}
+
+// Subdomains lists the tenants that currently have a Redis client registered
+func (m *TenantRedisManager) Subdomains() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	subdomains := make([]string, 0, len(m.Tenants))
+	for subdomain := range m.Tenants {
+		subdomains = append(subdomains, subdomain)
+	}
+	return subdomains
+}
+
+// Close closes every registered tenant Redis client
+func (m *TenantRedisManager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for subdomain, tenant := range m.Tenants {
+		_ = tenant.Client.Close()
+		delete(m.Tenants, subdomain)
+	}
+}
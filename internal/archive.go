@@ -0,0 +1,519 @@
+package internal
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/storage"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+// ArchiveFormatVersion is bumped when the layout of the archive itself changes.
+// Version 2 added the stored files of attachments.
+const ArchiveFormatVersion = 2
+
+const (
+	archiveManifest = "manifest.json"
+	archiveTableDir = "tables/"
+	archiveFileDir  = "files/"
+	importBatchSize = 500
+)
+
+// omittedTables stay behind on export. Invitations, refresh tokens and calendar
+// feed tokens are credentials, social integrations hold third-party tokens and
+// role permissions come with the schema.
+var omittedTables = []string{
+	"invitations",
+	"refresh_tokens",
+	"calendar_feeds",
+	"social_integrations",
+	"role_permissions",
+}
+
+// archiveTables are exported and imported in this order so foreign keys are
+// satisfied on import. Busy blocks follow the appointments so importing them
+// does not trip the overlap check.
+var archiveTables = []string{
+	"studios",
+	"studio_settings",
+	"users",
+	"studio_staff",
+	"staff_permissions",
+	"artist_working_hours",
+	"artist_schedule_overrides",
+	"artist_time_off",
+	"customers",
+	"customer_artists",
+	"conversations",
+	"conversation_participants",
+	"projects",
+	"appointments",
+	"calendar_busy_blocks",
+	"waitlist_entries",
+	"waitlist_openings",
+	"waitlist_offers",
+	"walk_ins",
+	"booking_requests",
+	"payments",
+	"ledger_entries",
+	"appointment_reminders",
+	"notifications",
+	"notification_devices",
+	"customer_notes",
+	"customer_history",
+	"messages",
+	"portfolio_items",
+	"attachments",
+}
+
+// importDefaults fill NOT NULL columns left out of the archive. Users exported
+// without their password hash get one no password matches, so they must reset it.
+var importDefaults = map[string]string{
+	"users": `{"hashed_password": "!"}`,
+}
+
+// ArchiveTables lists the tables an archive holds, in import order
+func ArchiveTables() []string {
+	return slices.Clone(archiveTables)
+}
+
+// ArchiveManifest is the first entry of a tenant archive
+type ArchiveManifest struct {
+	FormatVersion          int            `json:"format_version"`
+	Tenant                 string         `json:"tenant"`
+	SchemaVersion          string         `json:"schema_version"`
+	Migrations             []string       `json:"migrations"`
+	IncludesPasswordHashes bool           `json:"includes_password_hashes"`
+	ExportedAt             time.Time      `json:"exported_at"`
+	Tables                 []string       `json:"tables"`
+	OmittedTables          []string       `json:"omitted_tables"`
+	Rows                   map[string]int `json:"rows,omitempty"`
+	// Files counts the stored files of attachments, kept under files/<key>
+	Files int `json:"files"`
+}
+
+// ExportOptions control what goes into an archive
+type ExportOptions struct {
+	IncludePasswordHashes bool
+}
+
+// ImportOptions control how an archive is restored
+type ImportOptions struct {
+	// Replace deletes the tenant's existing data first. A freshly provisioned
+	// tenant already holds its studio and owner.
+	Replace bool
+}
+
+// Archiver writes a tenant's data to a gzipped tar archive of JSON lines, with
+// the files of its attachments, and restores it into another tenant on the same
+// schema version
+type Archiver struct {
+	conn   *pgxpool.Pool
+	blobs  domain.BlobStore
+	tenant string
+}
+
+// NewArchiver creates an Archiver for the tenant reachable through conn whose
+// files are kept in blobs
+func NewArchiver(conn *pgxpool.Pool, blobs domain.BlobStore, tenant string) *Archiver {
+	return &Archiver{conn: conn, blobs: blobs, tenant: tenant}
+}
+
+// NewTenantArchiver connects to a tenant's database, or its schema in schema
+// mode, and to the configured blob store, and returns an Archiver together with
+// a func that closes the connection
+func NewTenantArchiver(cfg *config.Config, tenant *config.TenantConfig) (*Archiver, func(), error) {
+	blobs, err := storage.NewBlobStore(cfg.Storage)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open blob store: %w", err)
+	}
+	pool, err := openTenantPool(cfg, tenant.Database)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to tenant %s: %w", tenant.Subdomain, err)
+	}
+	return NewArchiver(pool, blobs, tenant.Subdomain), pool.Close, nil
+}
+
+// Export writes the archive to w from a single snapshot of the tenant. The
+// returned manifest includes the row count of every table.
+func (a *Archiver) Export(ctx context.Context, w io.Writer, opts ExportOptions) (*ArchiveManifest, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest := &ArchiveManifest{
+		FormatVersion:          ArchiveFormatVersion,
+		Tenant:                 a.tenant,
+		IncludesPasswordHashes: opts.IncludePasswordHashes,
+		ExportedAt:             time.Now().UTC(),
+		Tables:                 archiveTables,
+		OmittedTables:          omittedTables,
+		Rows:                   make(map[string]int, len(archiveTables)),
+	}
+
+	err := pgx.BeginTxFunc(ctx, a.conn, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		migrations, err := appliedMigrationNames(ctx, tx)
+		if err != nil {
+			return err
+		}
+		manifest.Migrations = migrations
+		manifest.SchemaVersion = schemaVersion(migrations)
+		if err = tx.QueryRow(ctx, `select count(distinct storage_key) from attachments`).Scan(&manifest.Files); err != nil {
+			return fmt.Errorf("failed to count attachments: %w", err)
+		}
+
+		body, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err = writeTarFile(tw, archiveManifest, body, manifest.ExportedAt); err != nil {
+			return err
+		}
+
+		for _, table := range archiveTables {
+			rows, err := exportTable(ctx, tx, table, opts)
+			if err != nil {
+				return err
+			}
+			manifest.Rows[table] = bytes.Count(rows, []byte("\n"))
+			if err = writeTarFile(tw, archiveTableDir+table+".jsonl", rows, manifest.ExportedAt); err != nil {
+				return err
+			}
+		}
+		return a.exportFiles(ctx, tx, tw, manifest.ExportedAt)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export tenant %s: %w", a.tenant, err)
+	}
+
+	if err = tw.Close(); err != nil {
+		return nil, err
+	}
+	if err = gz.Close(); err != nil {
+		return nil, err
+	}
+
+	logger.Log.Info("tenant exported", zap.String("tenant", a.tenant), zap.String("schema_version", manifest.SchemaVersion))
+	return manifest, nil
+}
+
+// Import restores an archive in one transaction. It refuses archives whose
+// migrations differ from the ones applied to this tenant. The studio of the
+// source tenant takes this tenant's subdomain, and its files are stored under
+// this tenant's keys. Files are written as they are read, so a failed import
+// can leave some behind in the blob store.
+func (a *Archiver) Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ArchiveManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	manifest, err := readManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Rows = make(map[string]int, len(manifest.Tables))
+
+	var files int
+	err = pgx.BeginFunc(ctx, a.conn, func(tx pgx.Tx) error {
+		migrations, err := appliedMigrationNames(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if !slices.Equal(migrations, manifest.Migrations) {
+			return fmt.Errorf("archive schema version %s does not match tenant schema version %s",
+				manifest.SchemaVersion, schemaVersion(migrations))
+		}
+
+		if opts.Replace {
+			if _, err = tx.Exec(ctx, "truncate "+strings.Join(archiveTables, ", ")+" cascade"); err != nil {
+				return fmt.Errorf("failed to clear tenant data: %w", err)
+			}
+		} else {
+			var hasData bool
+			if err = tx.QueryRow(ctx, `select exists (select 1 from studios)`).Scan(&hasData); err != nil {
+				return err
+			}
+			if hasData {
+				return errors.New("tenant already has data; import with replace to overwrite it")
+			}
+		}
+
+		for {
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read archive: %w", err)
+			}
+
+			if key, ok := strings.CutPrefix(header.Name, archiveFileDir); ok {
+				if err = a.importFile(ctx, tx, manifest.Tenant, key, tr, header.Size); err != nil {
+					return err
+				}
+				files++
+				continue
+			}
+
+			table := strings.TrimSuffix(strings.TrimPrefix(header.Name, archiveTableDir), ".jsonl")
+			if !slices.Contains(archiveTables, table) || header.Name != archiveTableDir+table+".jsonl" {
+				return fmt.Errorf("unexpected archive entry %s", header.Name)
+			}
+
+			count, err := importTable(ctx, tx, table, tr)
+			if err != nil {
+				return err
+			}
+			manifest.Rows[table] = count
+		}
+
+		var stored int
+		if err = tx.QueryRow(ctx, `select count(distinct storage_key) from attachments`).Scan(&stored); err != nil {
+			return fmt.Errorf("failed to count attachments: %w", err)
+		}
+		if files != stored {
+			return fmt.Errorf("archive holds %d files for %d attachments", files, stored)
+		}
+
+		// The studio is looked up by the subdomain of the tenant serving it,
+		// and blob keys start with it
+		if manifest.Tenant != a.tenant {
+			_, err = tx.Exec(ctx, `update studios set subdomain = $1 where subdomain = $2`, a.tenant, manifest.Tenant)
+			if err != nil {
+				return fmt.Errorf("failed to rename studio subdomain: %w", err)
+			}
+			_, err = tx.Exec(ctx, `update attachments set storage_key = $1 || substr(storage_key, length($2) + 1)
+				where starts_with(storage_key, $2 || '/')`, a.tenant, manifest.Tenant)
+			if err != nil {
+				return fmt.Errorf("failed to rename attachment keys: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import into tenant %s: %w", a.tenant, err)
+	}
+
+	manifest.Files = files
+
+	logger.Log.Info("tenant imported", zap.String("tenant", a.tenant), zap.String("from", manifest.Tenant))
+	return manifest, nil
+}
+
+// exportFiles writes the stored file of every attachment to files/<key>
+func (a *Archiver) exportFiles(ctx context.Context, tx pgx.Tx, tw *tar.Writer, modTime time.Time) error {
+	rows, err := tx.Query(ctx, `select storage_key, max(size_bytes) from attachments group by storage_key order by storage_key`)
+	if err != nil {
+		return fmt.Errorf("failed to list attachments: %w", err)
+	}
+	type file struct {
+		key  string
+		size int64
+	}
+	files, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (file, error) {
+		var f file
+		err := row.Scan(&f.key, &f.size)
+		return f, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list attachments: %w", err)
+	}
+
+	for _, f := range files {
+		if err = a.exportFile(ctx, tw, f.key, f.size, modTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Archiver) exportFile(ctx context.Context, tw *tar.Writer, key string, size int64, modTime time.Time) error {
+	body, err := a.blobs.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", key, err)
+	}
+	defer body.Close()
+
+	if err = tw.WriteHeader(&tar.Header{Name: archiveFileDir + key, Mode: 0o600, Size: size, ModTime: modTime}); err != nil {
+		return err
+	}
+	if _, err = io.CopyN(tw, body, size); err != nil {
+		return fmt.Errorf("failed to archive file %s of %d bytes: %w", key, size, err)
+	}
+	return nil
+}
+
+// importFile stores a file of the archive under the key its attachment gets in
+// this tenant. Files no imported attachment refers to are refused.
+func (a *Archiver) importFile(ctx context.Context, tx pgx.Tx, from, key string, body io.Reader, size int64) error {
+	var contentType string
+	err := tx.QueryRow(ctx, `select content_type from attachments where storage_key = $1 limit 1`, key).Scan(&contentType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("archive file %s belongs to no attachment", key)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up file %s: %w", key, err)
+	}
+
+	if rest, ok := strings.CutPrefix(key, from+"/"); ok {
+		key = a.tenant + "/" + rest
+	}
+	if err = a.blobs.Put(ctx, key, contentType, body, size); err != nil {
+		return fmt.Errorf("failed to store file %s: %w", key, err)
+	}
+	return nil
+}
+
+// exportTable returns one JSON object per row, one per line
+func exportTable(ctx context.Context, tx pgx.Tx, table string, opts ExportOptions) ([]byte, error) {
+	row := "to_jsonb(t)"
+	if table == "users" && !opts.IncludePasswordHashes {
+		row = "to_jsonb(t) - 'hashed_password'"
+	}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf("select %s::text from %s t", row, table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to export %s: %w", table, err)
+	}
+
+	var (
+		buf  bytes.Buffer
+		line string
+	)
+	_, err = pgx.ForEachRow(rows, []any{&line}, func() error {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export %s: %w", table, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// importTable inserts the JSON lines of r into table in batches
+func importTable(ctx context.Context, tx pgx.Tx, table string, r io.Reader) (int, error) {
+	defaults := importDefaults[table]
+	if defaults == "" {
+		defaults = "{}"
+	}
+	query := fmt.Sprintf("insert into %[1]s select * from jsonb_populate_record(null::%[1]s, $1::jsonb || $2::jsonb)", table)
+
+	var (
+		batch pgx.Batch
+		count int
+	)
+	flush := func() error {
+		if batch.Len() == 0 {
+			return nil
+		}
+		err := tx.SendBatch(ctx, &batch).Close()
+		batch = pgx.Batch{}
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", table, err)
+		}
+		return nil
+	}
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if !json.Valid(line) {
+				return count, fmt.Errorf("invalid row %d in %s", count+1, table)
+			}
+			batch.Queue(query, defaults, string(line))
+			count++
+			if batch.Len() >= importBatchSize {
+				if err := flush(); err != nil {
+					return count, err
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to read %s: %w", table, err)
+		}
+	}
+	return count, flush()
+}
+
+func readManifest(tr *tar.Reader) (*ArchiveManifest, error) {
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if header.Name != archiveManifest {
+		return nil, fmt.Errorf("archive must start with %s, found %s", archiveManifest, header.Name)
+	}
+
+	var manifest ArchiveManifest
+	if err = json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid archive manifest: %w", err)
+	}
+	// Version 1 archives differ only in carrying no files
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > ArchiveFormatVersion {
+		return nil, fmt.Errorf("unsupported archive format version %d", manifest.FormatVersion)
+	}
+	return &manifest, nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, body []byte, modTime time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(body)),
+		ModTime: modTime,
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(body)
+	return err
+}
+
+// appliedMigrationNames lists _migrations in version order. Rows recorded before
+// up/down pairs were introduced are named after their file.
+func appliedMigrationNames(ctx context.Context, tx pgx.Tx) ([]string, error) {
+	rows, err := tx.Query(ctx, `select name from _migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read _migrations: %w", err)
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read _migrations: %w", err)
+	}
+	for i, name := range names {
+		names[i] = strings.TrimSuffix(strings.TrimSuffix(name, ".sql"), ".up")
+	}
+	sort.Slice(names, func(i, j int) bool { return migrationVersion(names[i]) < migrationVersion(names[j]) })
+	return names, nil
+}
+
+// schemaVersion is the newest applied migration
+func schemaVersion(migrations []string) string {
+	if len(migrations) == 0 {
+		return ""
+	}
+	return migrations[len(migrations)-1]
+}
@@ -4,9 +4,22 @@ import (
 	"context"
 
 	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/appointment"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/attachment"
 	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/auth"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/calendar"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/conversation"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/customer"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/notification"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/payment"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/portfolio"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/public"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/reminder"
 	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/studio"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/tenant"
 	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/user"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/walkin"
 )
 
 type AppContainer struct {
@@ -16,28 +29,98 @@ type AppContainer struct {
 
 	// Service managers
 	//StudioService *studio.StudioService
-	AuthService   *auth.StudioAuthService
-	StudioService *studio.StudioService
-	UserService   *user.UserService
+	AuthService         *auth.StudioAuthService
+	StudioService       *studio.StudioService
+	UserService         *user.UserService
+	CustomerService     *customer.ServiceCustomer
+	AppointmentService  *appointment.AppointmentService
+	AvailabilityService *appointment.AvailabilityService
+	BookingService      *appointment.BookingService
+	ProjectService      *appointment.ProjectService
+	WaitlistService     *appointment.WaitlistService
+	CalendarService     *calendar.CalendarService
+	NotificationService *notification.NotificationService
+	PaymentService      *payment.PaymentService
+	LedgerService       *payment.LedgerService
+	ReminderService     *reminder.ReminderService
+	WalkInService       *walkin.WalkInService
+	ConversationService *conversation.ConversationService
+	AttachmentService   *attachment.AttachmentService
+	PortfolioService    *portfolio.PortfolioService
+	PublicService       *public.PublicService
+	TenantService       *tenant.TenantService
 	// Add other services as needed
+
+	Provisioner       *TenantProvisioner
+	ReminderScheduler *reminder.Scheduler
+	WaitlistScheduler *appointment.WaitlistScheduler
+	CalendarFeed      *calendar.FeedHandler
+	Files             *attachment.FileHandler
+	Gallery           *portfolio.GalleryHandler
 }
 
-func NewAppContainer(ctx context.Context, dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *AppContainer {
+func NewAppContainer(ctx context.Context, dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager, blobs domain.BlobStore, paymentProvider domain.PaymentProvider) *AppContainer {
 	// Create repositories with tenant awareness
 	studioAuthRepo := auth.NewAuthRepository(dbManager, redisManager)
 	studioRepo := studio.NewStudioRepository(dbManager, redisManager)
 	userRepo := user.NewUserRepository(dbManager, redisManager)
+	customerRepo := customer.NewCustomerRepository(dbManager, redisManager)
+	appointmentRepo := appointment.NewAppointmentRepository(dbManager, redisManager)
+	availabilityRepo := appointment.NewAvailabilityRepository(dbManager, redisManager)
+	bookingRepo := appointment.NewBookingRequestRepository(dbManager, redisManager)
+	projectRepo := appointment.NewProjectRepository(dbManager, redisManager)
+	waitlistRepo := appointment.NewWaitlistRepository(dbManager, redisManager)
+	calendarRepo := calendar.NewCalendarRepository(dbManager, redisManager)
+	notificationRepo := notification.NewNotificationRepository(dbManager, redisManager)
+	paymentRepo := payment.NewPaymentRepository(dbManager, redisManager)
+	reminderRepo := reminder.NewReminderRepository(dbManager, redisManager)
+	walkInRepo := walkin.NewWalkInRepository(dbManager, redisManager)
+	conversationRepo := conversation.NewConversationRepository(dbManager, redisManager)
+	attachmentRepo := attachment.NewAttachmentRepository(dbManager, redisManager)
+	portfolioRepo := portfolio.NewPortfolioRepository(dbManager, redisManager)
+	publicRepo := public.NewPublicRepository(dbManager, redisManager)
+	provisioner := NewTenantProvisioner(dbManager.Config, dbManager, redisManager)
 
 	// // Get a pool from the manager for initialization
 	// defaultPool := dbManager.GetDefaultPool()
 	// defaultRedis := redisManager.GetDefaultClient()
 
+	bookingService := appointment.NewBookingService(bookingRepo, notificationRepo)
+	listTenants := func(ctx context.Context) ([]config.TenantConfig, error) {
+		return LoadTenants(ctx, dbManager.Config)
+	}
+	fileURLs := attachment.NewURLSigner(dbManager.Config.Storage)
+	uploadLimits := attachment.PlanUploadLimits(dbManager.Config.Storage.MaxUploadSize, dbManager.Config.GetTenantConfig)
+
 	return &AppContainer{
-		Ctx:           ctx,
-		DBManager:     dbManager,
-		RedisManager:  redisManager,
-		StudioService: studio.NewStudioService(studioRepo),
-		AuthService:   auth.NewStudioAuthService(studioAuthRepo, userRepo),
-		UserService:   user.NewUserService(userRepo),
+		Ctx:                 ctx,
+		DBManager:           dbManager,
+		RedisManager:        redisManager,
+		StudioService:       studio.NewStudioService(studioRepo),
+		AuthService:         auth.NewStudioAuthService(studioAuthRepo, userRepo),
+		UserService:         user.NewUserService(userRepo),
+		CustomerService:     customer.NewCustomerService(customerRepo),
+		AppointmentService:  appointment.NewAppointmentService(appointmentRepo, availabilityRepo, bookingService, reminderRepo),
+		AvailabilityService: appointment.NewAvailabilityService(availabilityRepo),
+		BookingService:      bookingService,
+		ProjectService:      appointment.NewProjectService(projectRepo),
+		WaitlistService:     appointment.NewWaitlistService(waitlistRepo),
+		CalendarService:     calendar.NewCalendarService(calendarRepo),
+		NotificationService: notification.NewNotificationService(notificationRepo),
+		PaymentService:      payment.NewPaymentService(paymentRepo, paymentProvider),
+		LedgerService:       payment.NewLedgerService(paymentRepo),
+		ReminderService:     reminder.NewReminderService(reminderRepo),
+		WalkInService:       walkin.NewWalkInService(walkInRepo),
+		ConversationService: conversation.NewConversationService(conversationRepo, attachmentRepo, fileURLs),
+		AttachmentService:   attachment.NewAttachmentService(attachmentRepo, blobs, fileURLs, uploadLimits),
+		PortfolioService:    portfolio.NewPortfolioService(portfolioRepo, blobs, fileURLs, uploadLimits, dbManager.Config.Storage.PublicURL),
+		PublicService:       public.NewPublicService(publicRepo, portfolioRepo, availabilityRepo, redisManager, dbManager.Config.Storage.PublicURL),
+		TenantService:       tenant.NewTenantService(provisioner, dbManager.Config.Admin.Token),
+		Provisioner:         provisioner,
+		ReminderScheduler:   reminder.NewScheduler(reminderRepo, notificationRepo, dbManager, redisManager, listTenants, dbManager.Config.Reminders),
+		WaitlistScheduler:   appointment.NewWaitlistScheduler(waitlistRepo, notificationRepo, listTenants, dbManager.Config.Waitlist),
+		CalendarFeed:        calendar.NewFeedHandler(calendarRepo),
+		Files:               attachment.NewFileHandler(attachmentRepo, blobs, fileURLs),
+		Gallery:             portfolio.NewGalleryHandler(portfolioRepo, blobs, NewTenantValidator(dbManager.Config, dbManager), dbManager.Config.Storage.PublicURL),
 	}
 }
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS tenant_databases;
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,24 @@
+-- Control-plane registry: one row per tenant (studio) served by this deployment
+CREATE TABLE IF NOT EXISTS tenants (
+                       id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                       subdomain     VARCHAR(100) UNIQUE NOT NULL,
+                       studio_name   VARCHAR(150) NOT NULL,
+                       status        VARCHAR(20) NOT NULL DEFAULT 'active',  -- 'active', 'suspended', 'deleted'
+                       plan          VARCHAR(50) NOT NULL DEFAULT 'basic',
+                       created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+                       updated_at    TIMESTAMPTZ,
+                       CONSTRAINT chk_tenant_status CHECK (status IN ('active', 'suspended', 'deleted'))
+);
+
+-- Where each tenant's data lives; credentials come from the bootstrap config
+CREATE TABLE IF NOT EXISTS tenant_databases (
+                       tenant_id     UUID PRIMARY KEY,
+                       host          VARCHAR(255) NOT NULL,
+                       port          VARCHAR(10) NOT NULL,
+                       db_name       VARCHAR(63) NOT NULL,
+                       sslmode       VARCHAR(20) NOT NULL DEFAULT 'disable',
+                       created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+                       updated_at    TIMESTAMPTZ,
+                       CONSTRAINT fk_tenant_database
+                         FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+ALTER TABLE tenant_databases DROP CONSTRAINT IF EXISTS chk_tenant_isolation;
+ALTER TABLE tenant_databases
+    DROP COLUMN IF EXISTS schema_name,
+    DROP COLUMN IF EXISTS isolation;
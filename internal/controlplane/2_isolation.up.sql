@@ -0,0 +1,10 @@
+-- Tenants on cheaper plans share a database and are isolated by schema
+ALTER TABLE tenant_databases
+    ADD COLUMN IF NOT EXISTS isolation   VARCHAR(20) NOT NULL DEFAULT 'database',
+    ADD COLUMN IF NOT EXISTS schema_name VARCHAR(63);
+
+ALTER TABLE tenant_databases
+    ADD CONSTRAINT chk_tenant_isolation CHECK (
+        (isolation = 'database' AND schema_name IS NULL) OR
+        (isolation = 'schema' AND schema_name IS NOT NULL)
+    );
@@ -5,7 +5,7 @@ import (
 	"embed"
 	"fmt"
 	"net/url"
-	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -51,11 +51,16 @@ func NewRedisConfig() (*redis.Client, error) {
 	}), nil
 }
 
+// NewTenantDBManager creates the tenant pool manager. Pools are opened on first
+// use by GetTenantDB and closed again when idle or evicted to respect the
+// tenant_pools limits.
 func NewTenantDBManager(cfg *config.Config) (*config.TenantDBManager, error) {
 	log := logger.Log
 	manager := &config.TenantDBManager{
-		Tenants: make(map[string]*config.TenantDatabase),
-		Config:  cfg,
+		Tenants:       make(map[string]*config.TenantDatabase),
+		Config:        cfg,
+		Opener:        newTenantDBOpener(cfg, true),
+		MaxTotalConns: cfg.TenantPools.MaxTotalConns,
 	}
 
 	if err := EnsureDatabasesExist(cfg); err != nil {
@@ -63,53 +68,60 @@ func NewTenantDBManager(cfg *config.Config) (*config.TenantDBManager, error) {
 		return nil, err
 	}
 
-	for _, tenant := range cfg.Tenants {
-		log.Info("Loaded tenant configuration",
-			zap.String("subdomain", tenant.Subdomain),
-			zap.String("studio_name", tenant.Studio.Name),
-			zap.String("owner_email", tenant.Owner.Email),
-			zap.String("database", tenant.Database.DB))
-
-		dbConfig := tenant.Database
-		connURL := url.URL{
-			Scheme: "postgres",
-			User:   url.UserPassword(dbConfig.Username, dbConfig.Password),
-			Host:   fmt.Sprintf("%s:%s", dbConfig.Host, dbConfig.Port),
-			Path:   dbConfig.DB,
-			RawQuery: url.Values{
-				"sslmode":  []string{dbConfig.SSLMODE},
-				"timezone": []string{"utc"},
-			}.Encode(),
-		}
+	log.Info("Tenant database manager ready",
+		zap.Int32("max_total_conns", cfg.TenantPools.MaxTotalConns),
+		zap.Int32("max_conns_per_tenant", cfg.TenantPools.MaxConnsPerTenant),
+		zap.Duration("idle_ttl", cfg.TenantPools.IdleTTL))
+	return manager, nil
+}
 
-		log.Info("Connecting to tenant database",
-			zap.String("subdomain", tenant.Subdomain),
-			zap.String("connection_url", connURL.String()))
+// NewConnectOnlyTenantDBManager creates a tenant pool manager for tools. Its
+// pools only connect: databases are never created, migrated or seeded, so
+// migrating stays an explicit step.
+func NewConnectOnlyTenantDBManager(cfg *config.Config) *config.TenantDBManager {
+	return &config.TenantDBManager{
+		Tenants:       make(map[string]*config.TenantDatabase),
+		Config:        cfg,
+		Opener:        newTenantDBOpener(cfg, false),
+		MaxTotalConns: cfg.TenantPools.MaxTotalConns,
+	}
+}
 
-		pool, err := Init(connURL.String())
-		if err != nil {
-			log.Error("Failed to initialize database for tenant", zap.String("subdomain", tenant.Subdomain), zap.Error(err))
-			return nil, err
-		}
+// connectTenantDB opens the tenant pool and, when migrate is set, brings its
+// schema up to date. Tenants that still carry owner details (imported from
+// config.yml) are seeded as well.
+func connectTenantDB(ctx context.Context, cfg *config.Config, tenant *config.TenantConfig, migrate bool) (*pgxpool.Pool, error) {
+	log := logger.Log
+	log.Info("Connecting to tenant database",
+		zap.String("subdomain", tenant.Subdomain),
+		zap.String("host", tenant.Database.Host),
+		zap.String("database", tenant.Database.DB),
+		zap.String("schema", tenant.Database.Schema))
 
-		WaitForDB(pool)
+	pool, err := openTenantPool(cfg, tenant.Database)
+	if err != nil {
+		return nil, err
+	}
 
-		if err := RunMigrations(dbConfig.Host, dbConfig.Port, dbConfig.Username, dbConfig.Password, dbConfig.DB, tenant.Subdomain); err != nil {
-			log.Error("Failed to run migrations for tenant", zap.String("subdomain", tenant.Subdomain), zap.Error(err))
-			return nil, err
-		}
+	WaitForDB(pool)
 
-		// Initialize the tenant system (create studio and owner if needed)
-		if err := InitializeTenantSystem(context.Background(), pool, &tenant); err != nil {
-			log.Error("Failed to initialize tenant system", zap.String("subdomain", tenant.Subdomain), zap.Error(err))
-			return nil, err
-		}
+	if !migrate {
+		return pool, nil
+	}
 
-		manager.Tenants[tenant.Subdomain] = &config.TenantDatabase{Pool: pool}
-		log.Info("Initialized database for tenant", zap.String("subdomain", tenant.Subdomain))
+	if err = Migrate(pool); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to run migrations for tenant %s: %w", tenant.Subdomain, err)
 	}
 
-	return manager, nil
+	if tenant.Owner.Email != "" {
+		if err = InitializeTenantSystem(ctx, pool, tenant); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to initialize tenant %s: %w", tenant.Subdomain, err)
+		}
+	}
+
+	return pool, nil
 }
 
 func RunMigrations(host, port, username, password, database, subdomain string) error {
@@ -133,45 +145,28 @@ func RunMigrations(host, port, username, password, database, subdomain string) e
 	return Migrate(conn)
 }
 
+// EnsureDatabasesExist creates the database of every active tenant in the registry
 func EnsureDatabasesExist(cfg *config.Config) error {
 	log := logger.Log
 	ctx := context.Background()
 
-	// Connect to the default PostgreSQL database
-	defaultConnURL := url.URL{
-		Scheme: "postgres",
-		User:   url.UserPassword(cfg.Tenants[0].Database.Username, cfg.Tenants[0].Database.Password),
-		Host:   fmt.Sprintf("%s:%s", cfg.Tenants[0].Database.Host, cfg.Tenants[0].Database.Port),
-		Path:   "postgres", // Connect to default database
-		RawQuery: url.Values{
-			"sslmode":  []string{cfg.Tenants[0].Database.SSLMODE},
-			"timezone": []string{"utc"},
-		}.Encode(),
-	}
-
-	// Connect to the PostgreSQL server
-	defaultPool, err := pgxpool.New(ctx, defaultConnURL.String())
+	tenants, err := LoadTenants(ctx, cfg)
 	if err != nil {
-		log.Error("Failed to connect to default PostgreSQL database", zap.Error(err))
+		log.Error("Failed to load tenants", zap.Error(err))
 		return err
 	}
-	defer defaultPool.Close()
 
-	// Check each tenant database and create if it doesn't exist
-	for _, tenant := range cfg.Tenants {
-		var exists bool
-		err := defaultPool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)",
-			tenant.Database.DB).Scan(&exists)
-		if err != nil {
-			log.Error("Failed to check if database exists", zap.String("db", tenant.Database.DB), zap.Error(err))
+	for _, tenant := range tenants {
+		if tenant.Status != config.TenantStatusActive {
+			continue
+		}
+		if _, err := createDatabaseIfNotExists(ctx, cfg, tenant.Database.DB); err != nil {
+			log.Error("Failed to create database", zap.String("db", tenant.Database.DB), zap.Error(err))
 			return err
 		}
-
-		if !exists {
-			log.Info("Creating database", zap.String("db", tenant.Database.DB))
-			_, err = defaultPool.Exec(ctx, fmt.Sprintf("CREATE DATABASE %s", tenant.Database.DB))
-			if err != nil {
-				log.Error("Failed to create database", zap.String("db", tenant.Database.DB), zap.Error(err))
+		if tenant.Database.Isolation == config.IsolationSchema {
+			if _, err := createSchemaIfNotExists(ctx, tenant.Database); err != nil {
+				log.Error("Failed to create schema", zap.String("schema", tenant.Database.Schema), zap.Error(err))
 				return err
 			}
 		}
@@ -180,6 +175,61 @@ func EnsureDatabasesExist(cfg *config.Config) error {
 	return nil
 }
 
+// createDatabaseIfNotExists creates the database if needed and reports whether it did
+func createDatabaseIfNotExists(ctx context.Context, cfg *config.Config, name string) (bool, error) {
+	serverURL := serverConnURL(cfg)
+	conn, err := pgx.Connect(ctx, serverURL.String())
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(ctx)
+
+	var exists bool
+	if err = conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	logger.Log.Info("Creating database", zap.String("db", name))
+	if _, err = conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{name}.Sanitize()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// createSchemaIfNotExists creates a schema-mode tenant's schema in the shared
+// database and reports whether it did. Extensions are installed in public first so
+// the tenant migrations do not create them inside the tenant schema.
+func createSchemaIfNotExists(ctx context.Context, dbConfig config.DatabaseConfig) (bool, error) {
+	connURL := tenantConnURL(dbConfig)
+	conn, err := pgx.Connect(ctx, connURL.String())
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(ctx)
+
+	var exists bool
+	if err = conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_namespace WHERE nspname = $1)", dbConfig.Schema).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	logger.Log.Info("Creating schema", zap.String("db", dbConfig.DB), zap.String("schema", dbConfig.Schema))
+	_, err = conn.Exec(ctx, `
+		CREATE EXTENSION IF NOT EXISTS "citext" WITH SCHEMA public;
+		CREATE EXTENSION IF NOT EXISTS "uuid-ossp" WITH SCHEMA public;
+		CREATE EXTENSION IF NOT EXISTS "btree_gist" WITH SCHEMA public;
+		CREATE SCHEMA `+pgx.Identifier{dbConfig.Schema}.Sanitize())
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func NewTenantRedisManager(cfg *config.Config) (*config.TenantRedisManager, error) {
 	log := logger.Log
 	manager := &config.TenantRedisManager{
@@ -187,14 +237,17 @@ func NewTenantRedisManager(cfg *config.Config) (*config.TenantRedisManager, erro
 		Config:  cfg,
 	}
 
-	for _, tenant := range cfg.Tenants {
-		// You might want to add Redis configuration to your tenant config
-		// For now, we'll use a simple approach using the tenant subdomain as a database index
-		client := redis.NewClient(&redis.Options{
-			Addr:     fmt.Sprintf("%s:%s", cfg.Redis.Host, cfg.Redis.Port),
-			Password: cfg.Redis.Pass,
-			DB:       getTenantRedisDB(tenant.Subdomain),
-		})
+	tenants, err := LoadTenants(context.Background(), cfg)
+	if err != nil {
+		log.Error("Failed to load tenants", zap.Error(err))
+		return nil, err
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Status != config.TenantStatusActive {
+			continue
+		}
+		client := newTenantRedisClient(cfg, tenant.Subdomain)
 
 		// Test the connection
 		_, err := client.Ping(context.Background()).Result()
@@ -210,6 +263,53 @@ func NewTenantRedisManager(cfg *config.Config) (*config.TenantRedisManager, erro
 	return manager, nil
 }
 
+// newTenantRedisClient builds the Redis client for a tenant.
+// For now, we'll use a simple approach using the tenant subdomain as a database index
+func newTenantRedisClient(cfg *config.Config, subdomain string) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", cfg.Redis.Host, cfg.Redis.Port),
+		Password: cfg.Redis.Pass,
+		DB:       getTenantRedisDB(subdomain),
+	})
+}
+
+// tenantConnURL builds the connection URL for a tenant database
+func tenantConnURL(dbConfig config.DatabaseConfig) url.URL {
+	return url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(dbConfig.Username, dbConfig.Password),
+		Host:   fmt.Sprintf("%s:%s", dbConfig.Host, dbConfig.Port),
+		Path:   dbConfig.DB,
+		RawQuery: url.Values{
+			"sslmode":  []string{dbConfig.SSLMODE},
+			"timezone": []string{"utc"},
+		}.Encode(),
+	}
+}
+
+// tenantPoolURL is tenantConnURL with the per-tenant connection limit applied
+func tenantPoolURL(cfg *config.Config, dbConfig config.DatabaseConfig) url.URL {
+	connURL := tenantConnURL(dbConfig)
+	if limit := cfg.TenantPools.MaxConnsPerTenant; limit > 0 {
+		query := connURL.Query()
+		query.Set("pool_max_conns", strconv.Itoa(int(limit)))
+		connURL.RawQuery = query.Encode()
+	}
+	return connURL
+}
+
+// serverConnURL builds the connection URL for the maintenance "postgres" database,
+// used to create tenant databases. It prefers the top-level database settings and
+// falls back to the first configured tenant.
+func serverConnURL(cfg *config.Config) url.URL {
+	dbConfig := cfg.Database
+	if dbConfig.Host == "" && len(cfg.Tenants) > 0 {
+		dbConfig = cfg.Tenants[0].Database
+	}
+	dbConfig.DB = "postgres"
+	return tenantConnURL(dbConfig)
+}
+
 // Helper function to map tenant subdomain to a Redis DB index
 // Redis typically supports 16 databases (0-15) by default
 func getTenantRedisDB(subdomain string) int {
@@ -233,6 +333,35 @@ func Init(connectionURL string) (*pgxpool.Pool, error) {
 	return pgxpool.NewWithConfig(context.Background(), cfg)
 }
 
+// openTenantPool connects to a tenant's data. Schema-mode tenants share a
+// database, so every new connection gets the tenant schema as its search_path and
+// repositories can keep using unqualified table names.
+func openTenantPool(cfg *config.Config, dbConfig config.DatabaseConfig) (*pgxpool.Pool, error) {
+	connURL := tenantPoolURL(cfg, dbConfig)
+	poolCfg, err := pgxpool.ParseConfig(connURL.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var searchPath string
+	if dbConfig.Isolation == config.IsolationSchema {
+		if dbConfig.Schema == "" {
+			return nil, fmt.Errorf("schema isolation requires a schema name for database %s", dbConfig.DB)
+		}
+		searchPath = pgx.Identifier{dbConfig.Schema}.Sanitize() + ", public"
+	}
+
+	poolCfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		uuidpg.Register(conn.TypeMap())
+		if searchPath == "" {
+			return nil
+		}
+		_, err := conn.Exec(ctx, "SET search_path TO "+searchPath)
+		return err
+	}
+	return pgxpool.NewWithConfig(context.Background(), poolCfg)
+}
+
 func WaitForDB(pgpool *pgxpool.Pool) {
 	ctx := context.Background()
 
@@ -259,109 +388,15 @@ func containsCreateDatabase(sql string) bool {
 	return strings.Contains(strings.ToUpper(sql), "CREATE DATABASE")
 }
 
+// Migrate applies the embedded tenant migrations
 func Migrate(conn *pgxpool.Pool) error {
+	return migrateFS(conn, migrationFS, "migrations")
+}
 
-	// migrate db
-	log := logger.Log
-	log.Info("Running migrations")
-	ctx := context.Background()
-	files, err := migrationFS.ReadDir("migrations")
-	if err != nil {
-		log.Error("Failed executing migrations", zap.Error(err))
-		return err
-	}
-
-	log.Info("Creating migrations table")
-	_, err = conn.Exec(ctx, `
-		create table if not exists _migrations (
-			name text primary key,
-			hash text not null,
-			created_at timestamp default now()
-		);
-	`)
-	if err != nil {
-		log.Error("Failed loading Postgres config", zap.Error(err))
-	}
-
-	log.Info("Checking applied migrations")
-	rows, _ := conn.Query(ctx, `select name, hash from _migrations order by created_at desc`)
-	var name, hash string
-	appliedMigrations := make(map[string]string)
-	_, err = pgx.ForEachRow(rows, []any{&name, &hash}, func() error {
-		appliedMigrations[name] = hash
-		return nil
-	})
-
-	if err != nil {
-		log.Error("Failed loading Postgres config", zap.Error(err))
-		return err
-	}
-
-	sort.SliceStable(files, func(i, j int) bool {
-		return files[i].Name() < files[j].Name()
-	})
-
-	for _, file := range files {
-		contents, err := migrationFS.ReadFile("migrations/" + file.Name())
-		if err != nil {
-			return err
-		}
-		contentStr := string(contents)
-		val := hashVal(contents)
-		contentHash := fmt.Sprintf("%x", val)
-
-		if prevHash, ok := appliedMigrations[file.Name()]; ok {
-			if prevHash != contentHash {
-				return fmt.Errorf("hash mismatch for migration %s", file.Name())
-			}
-			log.Info(file.Name() + " already applied")
-			continue
-		}
-
-		// Check if this migration contains CREATE DATABASE
-		if containsCreateDatabase(contentStr) {
-			// Execute CREATE DATABASE outside of transaction
-			_, err = conn.Exec(ctx, contentStr)
-			if err != nil {
-				log.Error("Failed executing CREATE DATABASE", zap.Error(err))
-				return err
-			}
-
-			// Record this migration as applied
-			_, err = conn.Exec(ctx, `insert into _migrations (name, hash) values ($1, $2)`,
-				file.Name(), contentHash)
-			if err != nil {
-				log.Error("Failed recording migration", zap.Error(err))
-				return err
-			}
-
-			log.Info(file.Name() + " applied (with CREATE DATABASE)")
-			continue
-		}
-
-		// For other migrations, use transaction as before
-		err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
-			if _, err = tx.Exec(ctx, contentStr); err != nil {
-				return err
-			}
-
-			if _, err := tx.Exec(ctx, `insert into _migrations (name, hash) values ($1, $2)`,
-				file.Name(), contentHash); err != nil {
-				return err
-			}
-
-			return nil
-		})
-
-		if err != nil {
-			log.Error("Failed loading Postgres config", zap.Error(err))
-			return err
-		}
-		log.Info(file.Name() + " applied")
-	}
-
-	log.Info("Migrations finished")
-	return nil
+// migrateFS applies the pending up migrations found in dir
+func migrateFS(conn *pgxpool.Pool, fsys embed.FS, dir string) error {
+	_, err := NewMigrator(conn, fsys, dir).Up(context.Background(), false)
+	return err
 }
 
 func InitializeTenantSystem(ctx context.Context, pool *pgxpool.Pool, tenantCfg *config.TenantConfig) error {
@@ -420,6 +455,14 @@ func InitializeTenantSystem(ctx context.Context, pool *pgxpool.Pool, tenantCfg *
 		return err
 	}
 
+	_, err = tx.Exec(ctx,
+		`INSERT INTO studio_staff (studio_id, user_id, role, created_at) VALUES ($1, $2, 'OWNER', $3)`,
+		studioID, ownerID, now)
+	if err != nil {
+		log.Error("Failed to add owner to studio staff", zap.Error(err))
+		return err
+	}
+
 	if err = tx.Commit(ctx); err != nil {
 		log.Error("Failed to commit transaction", zap.Error(err))
 		return err
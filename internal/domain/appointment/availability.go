@@ -0,0 +1,433 @@
+package appointment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// AvailabilityRepository stores artist schedules and the studio's scheduling
+// settings in the tenant's database
+type AvailabilityRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewAvailabilityRepository creates a new AvailabilityRepository
+func NewAvailabilityRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *AvailabilityRepository {
+	return &AvailabilityRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+// openingHours is one entry of studio_settings.business_hours
+type openingHours struct {
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+// SetWorkingHours replaces the weekly shifts of an artist
+func (r *AvailabilityRepository) SetWorkingHours(ctx context.Context, tenant, artistID string, hours []domain.WorkingHours) error {
+	if err := ValidateWorkingHours(hours); err != nil {
+		return err
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if err := ensureUser(ctx, tx, artistID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM artist_working_hours WHERE artist_id = $1", artistID); err != nil {
+			return domain.WrapError("failed to clear working hours", err)
+		}
+
+		batch := &pgx.Batch{}
+		for _, h := range hours {
+			batch.Queue(`INSERT INTO artist_working_hours (artist_id, weekday, start_time, end_time) VALUES ($1, $2, $3::time, $4::time)`,
+				artistID, int(h.Weekday), h.Start, h.End)
+		}
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return domain.WrapError("failed to insert working hours", err)
+		}
+		return nil
+	})
+}
+
+// GetWorkingHours returns the weekly shifts of an artist ordered by weekday
+func (r *AvailabilityRepository) GetWorkingHours(ctx context.Context, tenant, artistID string) ([]domain.WorkingHours, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return queryWorkingHours(ctx, pool, artistID)
+}
+
+// SetOverride replaces the weekly shifts of an artist on one date
+func (r *AvailabilityRepository) SetOverride(ctx context.Context, tenant string, override *domain.ScheduleOverride) error {
+	if override == nil || override.ArtistID == "" {
+		return fmt.Errorf("%w: override with artist is required", domain.ErrInvalidArgument)
+	}
+	if _, err := time.Parse(time.DateOnly, override.Date); err != nil {
+		return fmt.Errorf("%w: date must be YYYY-MM-DD", domain.ErrInvalidArgument)
+	}
+	if override.Start != "" || override.End != "" {
+		if _, err := parseClockRange(override.Start, override.End); err != nil {
+			return err
+		}
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	_, err = pool.Exec(ctx,
+		`INSERT INTO artist_schedule_overrides (artist_id, date, start_time, end_time)
+		 VALUES ($1, $2::date, NULLIF($3, '')::time, NULLIF($4, '')::time)
+		 ON CONFLICT (artist_id, date) DO UPDATE SET start_time = EXCLUDED.start_time, end_time = EXCLUDED.end_time`,
+		override.ArtistID, override.Date, override.Start, override.End)
+	if err != nil {
+		return domain.WrapError("failed to set schedule override", err)
+	}
+	return nil
+}
+
+// DeleteOverride restores the weekly shifts of an artist on one date
+func (r *AvailabilityRepository) DeleteOverride(ctx context.Context, tenant, artistID, date string) error {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	tag, err := pool.Exec(ctx, "DELETE FROM artist_schedule_overrides WHERE artist_id = $1 AND date = $2::date", artistID, date)
+	if err != nil {
+		return domain.WrapError("failed to delete schedule override", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("override on %s: %w", date, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// AddTimeOff blocks an artist for a period and sets the new ID on timeOff
+func (r *AvailabilityRepository) AddTimeOff(ctx context.Context, tenant string, timeOff *domain.TimeOff) error {
+	if timeOff == nil || timeOff.ArtistID == "" {
+		return fmt.Errorf("%w: time off with artist is required", domain.ErrInvalidArgument)
+	}
+	if err := validateRange(timeOff.Start, timeOff.End); err != nil {
+		return err
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	err = pool.QueryRow(ctx,
+		`INSERT INTO artist_time_off (artist_id, starts_at, ends_at, reason) VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING id`,
+		timeOff.ArtistID, timeOff.Start, timeOff.End, timeOff.Reason).Scan(&timeOff.ID)
+	if err != nil {
+		return domain.WrapError("failed to add time off", err)
+	}
+	return nil
+}
+
+// RemoveTimeOff deletes one of the artist's periods of time off
+func (r *AvailabilityRepository) RemoveTimeOff(ctx context.Context, tenant, artistID, timeOffID string) error {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	tag, err := pool.Exec(ctx, "DELETE FROM artist_time_off WHERE id = $1 AND artist_id = $2", timeOffID, artistID)
+	if err != nil {
+		return domain.WrapError("failed to remove time off", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("time off %s: %w", timeOffID, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// SetStudioHours stores the time zone, buffer and business hours of a studio.
+// Nil BusinessHours clears them so only the artists' hours apply.
+func (r *AvailabilityRepository) SetStudioHours(ctx context.Context, tenant, studioID string, hours *domain.StudioHours) error {
+	if hours == nil {
+		return fmt.Errorf("%w: studio hours are required", domain.ErrInvalidArgument)
+	}
+	if _, err := loadLocation(hours.TimeZone); err != nil {
+		return err
+	}
+	if hours.BufferMinutes < 0 {
+		return fmt.Errorf("%w: buffer cannot be negative", domain.ErrInvalidArgument)
+	}
+	if err := ValidateWorkingHours(hours.BusinessHours); err != nil {
+		return err
+	}
+
+	var businessHours []byte
+	if hours.BusinessHours != nil {
+		byDay := make(map[string][]openingHours)
+		for _, h := range hours.BusinessHours {
+			day := strings.ToLower(h.Weekday.String())
+			byDay[day] = append(byDay[day], openingHours{Open: h.Start, Close: h.End})
+		}
+		var err error
+		if businessHours, err = json.Marshal(byDay); err != nil {
+			return fmt.Errorf("failed to encode business hours: %w", err)
+		}
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	timeZone := hours.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	_, err = pool.Exec(ctx,
+		`INSERT INTO studio_settings (studio_id, timezone, buffer_minutes, business_hours, updated_at)
+		 VALUES ($1, $2, $3, $4, now())
+		 ON CONFLICT (studio_id) DO UPDATE SET timezone = EXCLUDED.timezone,
+			buffer_minutes = EXCLUDED.buffer_minutes, business_hours = EXCLUDED.business_hours, updated_at = now()`,
+		studioID, timeZone, hours.BufferMinutes, businessHours)
+	if err != nil {
+		return domain.WrapError("failed to save studio hours", err)
+	}
+	return nil
+}
+
+// GetStudioHours returns the scheduling settings of a studio, defaulting to
+// UTC without buffer or business hours
+func (r *AvailabilityRepository) GetStudioHours(ctx context.Context, tenant, studioID string) (*domain.StudioHours, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return queryStudioHours(ctx, pool, "s.id = $1", studioID)
+}
+
+// GetSchedule loads the artist's shifts, the studio settings of the artist's
+// studio and the overrides, time off, external busy blocks and scheduled
+// appointments touching [from, to)
+func (r *AvailabilityRepository) GetSchedule(ctx context.Context, tenant, artistID string, from, to time.Time) (*domain.ArtistSchedule, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	studioHours, err := queryStudioHours(ctx, pool,
+		"s.id = (SELECT studio_id FROM users WHERE id = $1)", artistID)
+	if err != nil {
+		return nil, err
+	}
+	schedule := &domain.ArtistSchedule{
+		ArtistID:      artistID,
+		TimeZone:      studioHours.TimeZone,
+		Buffer:        time.Duration(studioHours.BufferMinutes) * time.Minute,
+		BusinessHours: studioHours.BusinessHours,
+	}
+
+	if schedule.WorkingHours, err = queryWorkingHours(ctx, pool, artistID); err != nil {
+		return nil, err
+	}
+
+	// Overrides are local dates, so allow a day either side of the range
+	rows, err := pool.Query(ctx,
+		`SELECT to_char(date, 'YYYY-MM-DD'), COALESCE(to_char(start_time, 'HH24:MI'), ''), COALESCE(to_char(end_time, 'HH24:MI'), '')
+		 FROM artist_schedule_overrides
+		 WHERE artist_id = $1 AND date BETWEEN ($2::timestamptz - interval '1 day')::date AND ($3::timestamptz + interval '1 day')::date`,
+		artistID, from, to)
+	if err != nil {
+		return nil, domain.WrapError("failed to query schedule overrides", err)
+	}
+	for rows.Next() {
+		override := domain.ScheduleOverride{ArtistID: artistID}
+		if err = rows.Scan(&override.Date, &override.Start, &override.End); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan schedule override: %w", err)
+		}
+		schedule.Overrides = append(schedule.Overrides, override)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to query schedule overrides", err)
+	}
+
+	rows, err = pool.Query(ctx,
+		`SELECT id, starts_at, ends_at, COALESCE(reason, '') FROM artist_time_off
+		 WHERE artist_id = $1 AND ends_at > $2 AND starts_at < $3 ORDER BY starts_at`,
+		artistID, from, to)
+	if err != nil {
+		return nil, domain.WrapError("failed to query time off", err)
+	}
+	for rows.Next() {
+		timeOff := domain.TimeOff{ArtistID: artistID}
+		if err = rows.Scan(&timeOff.ID, &timeOff.Start, &timeOff.End, &timeOff.Reason); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan time off: %w", err)
+		}
+		schedule.TimeOff = append(schedule.TimeOff, timeOff)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to query time off", err)
+	}
+
+	rows, err = pool.Query(ctx,
+		`SELECT starts_at, ends_at FROM calendar_busy_blocks
+		 WHERE artist_id = $1 AND ends_at > $2 AND starts_at < $3 ORDER BY starts_at`,
+		artistID, from, to)
+	if err != nil {
+		return nil, domain.WrapError("failed to query busy blocks", err)
+	}
+	for rows.Next() {
+		var busy domain.TimeRange
+		if err = rows.Scan(&busy.Start, &busy.End); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan busy block: %w", err)
+		}
+		schedule.Busy = append(schedule.Busy, busy)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to query busy blocks", err)
+	}
+
+	rows, err = pool.Query(ctx,
+		`SELECT starts_at, ends_at FROM waitlist_offers
+		 WHERE artist_id = $1 AND status = 'PENDING' AND expires_at > now()
+		   AND ends_at > $2 AND starts_at < $3 ORDER BY starts_at`,
+		artistID, from, to)
+	if err != nil {
+		return nil, domain.WrapError("failed to query held slots", err)
+	}
+	for rows.Next() {
+		var held domain.TimeRange
+		if err = rows.Scan(&held.Start, &held.End); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan held slot: %w", err)
+		}
+		schedule.Busy = append(schedule.Busy, held)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to query held slots", err)
+	}
+
+	// Appointments just outside the range still matter through the buffer
+	rows, err = pool.Query(ctx,
+		`SELECT start_time, end_time FROM appointments
+		 WHERE artist_id = $1 AND status = $2 AND end_time > $3 AND start_time < $4 ORDER BY start_time`,
+		artistID, domain.AppointmentScheduled, from.Add(-schedule.Buffer), to.Add(schedule.Buffer))
+	if err != nil {
+		return nil, domain.WrapError("failed to query appointments", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var busy domain.TimeRange
+		if err = rows.Scan(&busy.Start, &busy.End); err != nil {
+			return nil, fmt.Errorf("failed to scan appointment: %w", err)
+		}
+		schedule.Appointments = append(schedule.Appointments, busy)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to query appointments", err)
+	}
+
+	return schedule, nil
+}
+
+// querier is satisfied by both pools and transactions
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func queryWorkingHours(ctx context.Context, q querier, artistID string) ([]domain.WorkingHours, error) {
+	rows, err := q.Query(ctx,
+		`SELECT weekday, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI')
+		 FROM artist_working_hours WHERE artist_id = $1 ORDER BY weekday, start_time`, artistID)
+	if err != nil {
+		return nil, domain.WrapError("failed to query working hours", err)
+	}
+	defer rows.Close()
+
+	hours := []domain.WorkingHours{}
+	for rows.Next() {
+		var h domain.WorkingHours
+		var weekday int16
+		if err = rows.Scan(&weekday, &h.Start, &h.End); err != nil {
+			return nil, fmt.Errorf("failed to scan working hours: %w", err)
+		}
+		h.Weekday = time.Weekday(weekday)
+		hours = append(hours, h)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to query working hours", err)
+	}
+	return hours, nil
+}
+
+// queryStudioHours reads the settings of the studio selected by condition
+func queryStudioHours(ctx context.Context, q querier, condition, value string) (*domain.StudioHours, error) {
+	hours := &domain.StudioHours{}
+	var businessHours []byte
+	err := q.QueryRow(ctx,
+		`SELECT COALESCE(ss.timezone, 'UTC'), COALESCE(ss.buffer_minutes, 0), ss.business_hours
+		 FROM studios s LEFT JOIN studio_settings ss ON ss.studio_id = s.id
+		 WHERE `+condition, value).Scan(&hours.TimeZone, &hours.BufferMinutes, &businessHours)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("studio: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get studio settings", err)
+	}
+
+	if len(businessHours) > 0 && string(businessHours) != "null" {
+		var byDay map[string][]openingHours
+		if err = json.Unmarshal(businessHours, &byDay); err != nil {
+			return nil, fmt.Errorf("invalid business_hours in studio settings: %w", err)
+		}
+		hours.BusinessHours = []domain.WorkingHours{}
+		for day, entries := range byDay {
+			weekday, ok := parseWeekday(day)
+			if !ok {
+				return nil, fmt.Errorf("invalid weekday %q in studio business_hours", day)
+			}
+			for _, entry := range entries {
+				hours.BusinessHours = append(hours.BusinessHours, domain.WorkingHours{Weekday: weekday, Start: entry.Open, End: entry.Close})
+			}
+		}
+	}
+	return hours, nil
+}
+
+// ensureUser fails unless userID is a user of the tenant
+func ensureUser(ctx context.Context, tx pgx.Tx, userID string) error {
+	var exists bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists); err != nil {
+		return domain.WrapError("failed to check artist", err)
+	}
+	if !exists {
+		return fmt.Errorf("artist %s: %w", userID, domain.ErrNotFound)
+	}
+	return nil
+}
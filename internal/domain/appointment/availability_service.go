@@ -0,0 +1,333 @@
+package appointment
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// AvailabilityServiceName is the fully qualified gRPC name of the availability
+// service. The appointment protos only carry weekly hours and single-day slot
+// lookups, so overrides, time off, studio hours and range searches live here.
+const AvailabilityServiceName = "inkMe.appointment.AvailabilityService"
+
+type OpeningHours struct {
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+type ScheduleOverrideRequest struct {
+	ArtistID string `json:"artist_id"`
+	Date     string `json:"date"`
+	// Start and End are HH:MM; leave both empty to take the day off
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type TimeOffRequest struct {
+	ArtistID string `json:"artist_id"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Reason   string `json:"reason"`
+}
+
+type TimeOffResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+type RemoveTimeOffRequest struct {
+	ID       string `json:"id"`
+	ArtistID string `json:"artist_id"`
+}
+
+type StudioHoursRequest struct {
+	StudioID      string                    `json:"studio_id"`
+	TimeZone      string                    `json:"time_zone"`
+	BufferMinutes int                       `json:"buffer_minutes"`
+	BusinessHours map[string][]OpeningHours `json:"business_hours"`
+}
+
+type StudioHoursResponse struct {
+	StudioID      string                    `json:"studio_id"`
+	TimeZone      string                    `json:"time_zone"`
+	BufferMinutes int                       `json:"buffer_minutes"`
+	BusinessHours map[string][]OpeningHours `json:"business_hours"`
+}
+
+type FindSlotsRequest struct {
+	ArtistID        string `json:"artist_id"`
+	DurationMinutes int    `json:"duration_minutes"`
+	StepMinutes     int    `json:"step_minutes"`
+	// From and To are RFC 3339 timestamps
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type Slot struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type FindSlotsResponse struct {
+	TimeZone string `json:"time_zone"`
+	Slots    []Slot `json:"slots"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// AvailabilityService implements the availability gRPC service
+type AvailabilityService struct {
+	repo domain.AvailabilityRepository
+}
+
+// NewAvailabilityService creates a new AvailabilityService
+func NewAvailabilityService(repo domain.AvailabilityRepository) *AvailabilityService {
+	return &AvailabilityService{repo: repo}
+}
+
+// Register adds the service to a gRPC server
+func (s *AvailabilityService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(AvailabilityServiceName,
+		structrpc.Unary(AvailabilityServiceName, "SetScheduleOverride", s.SetScheduleOverride),
+		structrpc.Unary(AvailabilityServiceName, "DeleteScheduleOverride", s.DeleteScheduleOverride),
+		structrpc.Unary(AvailabilityServiceName, "AddTimeOff", s.AddTimeOff),
+		structrpc.Unary(AvailabilityServiceName, "RemoveTimeOff", s.RemoveTimeOff),
+		structrpc.Unary(AvailabilityServiceName, "SetStudioHours", s.SetStudioHours),
+		structrpc.Unary(AvailabilityServiceName, "GetStudioHours", s.GetStudioHours),
+		structrpc.Unary(AvailabilityServiceName, "FindSlots", s.FindSlots),
+	), s)
+}
+
+// SetScheduleOverride replaces an artist's weekly hours on one date
+func (s *AvailabilityService) SetScheduleOverride(ctx context.Context, req *ScheduleOverrideRequest) (*MessageResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "SetScheduleOverride")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ArtistID == "" {
+		return nil, status.Error(codes.InvalidArgument, "artist_id is required")
+	}
+	if err = requireArtistOrManager(ctx, req.ArtistID); err != nil {
+		return nil, err
+	}
+
+	err = s.repo.SetOverride(ctx, tenant, &domain.ScheduleOverride{
+		ArtistID: req.ArtistID,
+		Date:     req.Date,
+		Start:    req.Start,
+		End:      req.End,
+	})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to set schedule override")
+	}
+
+	return &MessageResponse{Message: "Schedule override saved successfully"}, nil
+}
+
+// DeleteScheduleOverride restores an artist's weekly hours on one date
+func (s *AvailabilityService) DeleteScheduleOverride(ctx context.Context, req *ScheduleOverrideRequest) (*MessageResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "DeleteScheduleOverride")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ArtistID == "" || req.Date == "" {
+		return nil, status.Error(codes.InvalidArgument, "artist_id and date are required")
+	}
+	if err = requireArtistOrManager(ctx, req.ArtistID); err != nil {
+		return nil, err
+	}
+
+	if err = s.repo.DeleteOverride(ctx, tenant, req.ArtistID, req.Date); err != nil {
+		return nil, domain.ToStatus(err, "failed to delete schedule override")
+	}
+
+	return &MessageResponse{Message: "Schedule override deleted successfully"}, nil
+}
+
+// AddTimeOff blocks an artist between two RFC 3339 timestamps
+func (s *AvailabilityService) AddTimeOff(ctx context.Context, req *TimeOffRequest) (*TimeOffResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "AddTimeOff")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ArtistID == "" {
+		return nil, status.Error(codes.InvalidArgument, "artist_id is required")
+	}
+	if err = requireArtistOrManager(ctx, req.ArtistID); err != nil {
+		return nil, err
+	}
+	start, err := parseTime("start", req.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTime("end", req.End)
+	if err != nil {
+		return nil, err
+	}
+
+	timeOff := &domain.TimeOff{ArtistID: req.ArtistID, Start: start, End: end, Reason: req.Reason}
+	if err = s.repo.AddTimeOff(ctx, tenant, timeOff); err != nil {
+		return nil, domain.ToStatus(err, "failed to add time off")
+	}
+
+	span.SetAttributes(attribute.String("time_off.id", timeOff.ID))
+
+	return &TimeOffResponse{ID: timeOff.ID, Message: "Time off added successfully"}, nil
+}
+
+// RemoveTimeOff deletes a period of time off
+func (s *AvailabilityService) RemoveTimeOff(ctx context.Context, req *RemoveTimeOffRequest) (*MessageResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "RemoveTimeOff")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" || req.ArtistID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id and artist_id are required")
+	}
+	if err = requireArtistOrManager(ctx, req.ArtistID); err != nil {
+		return nil, err
+	}
+
+	if err = s.repo.RemoveTimeOff(ctx, tenant, req.ArtistID, req.ID); err != nil {
+		return nil, domain.ToStatus(err, "failed to remove time off")
+	}
+
+	return &MessageResponse{Message: "Time off removed successfully"}, nil
+}
+
+// SetStudioHours stores the studio's time zone, appointment buffer and
+// business hours. Omitting business_hours lifts the studio-wide limit.
+func (s *AvailabilityService) SetStudioHours(ctx context.Context, req *StudioHoursRequest) (*MessageResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "SetStudioHours")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if err = domain.RequireRole(ctx, managerRoles...); err != nil {
+		return nil, err
+	}
+	if req.StudioID == "" {
+		return nil, status.Error(codes.InvalidArgument, "studio_id is required")
+	}
+
+	hours := &domain.StudioHours{TimeZone: req.TimeZone, BufferMinutes: req.BufferMinutes}
+	if req.BusinessHours != nil {
+		hours.BusinessHours = []domain.WorkingHours{}
+		for day, entries := range req.BusinessHours {
+			weekday, ok := parseWeekday(day)
+			if !ok {
+				return nil, status.Errorf(codes.InvalidArgument, "unknown weekday %q", day)
+			}
+			for _, entry := range entries {
+				hours.BusinessHours = append(hours.BusinessHours, domain.WorkingHours{Weekday: weekday, Start: entry.Open, End: entry.Close})
+			}
+		}
+	}
+
+	if err = s.repo.SetStudioHours(ctx, tenant, req.StudioID, hours); err != nil {
+		return nil, domain.ToStatus(err, "failed to set studio hours")
+	}
+
+	return &MessageResponse{Message: "Studio hours saved successfully"}, nil
+}
+
+func (s *AvailabilityService) GetStudioHours(ctx context.Context, req *StudioHoursRequest) (*StudioHoursResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetStudioHours")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.StudioID == "" {
+		return nil, status.Error(codes.InvalidArgument, "studio_id is required")
+	}
+
+	hours, err := s.repo.GetStudioHours(ctx, tenant, req.StudioID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get studio hours")
+	}
+
+	res := &StudioHoursResponse{
+		StudioID:      req.StudioID,
+		TimeZone:      hours.TimeZone,
+		BufferMinutes: hours.BufferMinutes,
+	}
+	if hours.BusinessHours != nil {
+		res.BusinessHours = make(map[string][]OpeningHours)
+		for _, h := range hours.BusinessHours {
+			day := strings.ToLower(h.Weekday.String())
+			res.BusinessHours[day] = append(res.BusinessHours[day], OpeningHours{Open: h.Start, Close: h.End})
+		}
+	}
+	return res, nil
+}
+
+// FindSlots returns an artist's free slots between two RFC 3339 timestamps.
+// Slots are duration_minutes long and start every step_minutes, 30 by default.
+func (s *AvailabilityService) FindSlots(ctx context.Context, req *FindSlotsRequest) (*FindSlotsResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "FindSlots")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ArtistID == "" {
+		return nil, status.Error(codes.InvalidArgument, "artist_id is required")
+	}
+	if req.DurationMinutes <= 0 || req.StepMinutes < 0 {
+		return nil, status.Error(codes.InvalidArgument, "duration_minutes must be positive and step_minutes not negative")
+	}
+	from, err := parseTime("from", req.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseTime("to", req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.repo.GetSchedule(ctx, tenant, req.ArtistID, from, to)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to load schedule")
+	}
+	slots, err := FindSlots(schedule, from, to,
+		time.Duration(req.DurationMinutes)*time.Minute, time.Duration(req.StepMinutes)*time.Minute)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to find slots")
+	}
+
+	res := &FindSlotsResponse{TimeZone: schedule.TimeZone, Slots: make([]Slot, 0, len(slots))}
+	for _, slot := range slots {
+		res.Slots = append(res.Slots, Slot{
+			Start: slot.Start.Format(time.RFC3339),
+			End:   slot.End.Format(time.RFC3339),
+		})
+	}
+
+	span.SetAttributes(
+		attribute.String("artist.id", req.ArtistID),
+		attribute.Int("slots.count", len(res.Slots)),
+	)
+
+	return res, nil
+}
@@ -0,0 +1,344 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/reminder"
+)
+
+// BookingRequestRepository stores clients' booking requests in the tenant's
+// database
+type BookingRequestRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewBookingRequestRepository creates a new BookingRequestRepository
+func NewBookingRequestRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *BookingRequestRepository {
+	return &BookingRequestRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+const bookingRequestColumns = `id, studio_id, customer_id, COALESCE(artist_id::text, ''), requested_start, requested_end,
+	description, COALESCE(placement, ''), COALESCE(size, ''), reference_images, status, proposed_start, proposed_end,
+	COALESCE(response_note, ''), COALESCE(appointment_id::text, ''), created_at, COALESCE(updated_at, created_at)`
+
+// Create stores a PENDING request. Without a StudioID it belongs to the
+// tenant's own studio; a chosen artist must be on that studio's staff.
+func (r *BookingRequestRepository) Create(ctx context.Context, tenant string, request *domain.BookingRequest) error {
+	if request == nil {
+		return fmt.Errorf("%w: booking request is required", domain.ErrInvalidArgument)
+	}
+	if request.CustomerID == "" {
+		return fmt.Errorf("%w: customer is required", domain.ErrInvalidArgument)
+	}
+	if strings.TrimSpace(request.Description) == "" {
+		return fmt.Errorf("%w: a description of the design is required", domain.ErrInvalidArgument)
+	}
+	if err := validateRange(request.RequestedStart, request.RequestedEnd); err != nil {
+		return err
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		studioID, err := resolveStudio(ctx, tx, tenant, request.StudioID)
+		if err != nil {
+			return err
+		}
+		if request.ArtistID != "" {
+			if err := ensureArtist(ctx, tx, studioID, request.ArtistID); err != nil {
+				return err
+			}
+		}
+		images := request.ReferenceImages
+		if images == nil {
+			images = []string{}
+		}
+
+		created, err := scanBookingRequest(tx.QueryRow(ctx,
+			`INSERT INTO booking_requests (studio_id, customer_id, artist_id, requested_start, requested_end,
+				description, placement, size, reference_images, status)
+			 VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9, $10)
+			 RETURNING `+bookingRequestColumns,
+			studioID, request.CustomerID, request.ArtistID, request.RequestedStart, request.RequestedEnd,
+			request.Description, request.Placement, request.Size, images, domain.BookingPending))
+		if err != nil {
+			return domain.WrapError("failed to create booking request", err)
+		}
+		*request = *created
+		return nil
+	})
+}
+
+func (r *BookingRequestRepository) GetByID(ctx context.Context, tenant, id string) (*domain.BookingRequest, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	request, err := scanBookingRequest(pool.QueryRow(ctx,
+		"SELECT "+bookingRequestColumns+" FROM booking_requests WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("booking request %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get booking request", err)
+	}
+	return request, nil
+}
+
+// List pages through the booking requests matching the filter, oldest first
+func (r *BookingRequestRepository) List(ctx context.Context, tenant string, filter domain.BookingRequestFilter) (domain.PagedResult[domain.BookingRequest], error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = domain.DefaultPageSize
+	}
+	result := domain.PagedResult[domain.BookingRequest]{Items: []domain.BookingRequest{}, Page: filter.Page, PageSize: filter.PageSize}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return result, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	where := []string{"TRUE"}
+	var args []interface{}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if filter.StudioID != "" {
+		add("studio_id = $%d", filter.StudioID)
+	}
+	if filter.ArtistID != "" {
+		add("artist_id = $%d", filter.ArtistID)
+	}
+	if filter.CustomerID != "" {
+		add("customer_id = $%d", filter.CustomerID)
+	}
+	if filter.Status != "" {
+		add("status = upper($%d)", filter.Status)
+	}
+	whereClause := strings.Join(where, " AND ")
+
+	if err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM booking_requests WHERE "+whereClause, args...).Scan(&result.TotalCount); err != nil {
+		return result, domain.WrapError("failed to count booking requests", err)
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	rows, err := pool.Query(ctx,
+		"SELECT "+bookingRequestColumns+" FROM booking_requests WHERE "+whereClause+
+			fmt.Sprintf(" ORDER BY created_at, id LIMIT $%d OFFSET $%d", len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return result, domain.WrapError("failed to query booking requests", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		request, err := scanBookingRequest(rows)
+		if err != nil {
+			return result, fmt.Errorf("failed to scan booking request: %w", err)
+		}
+		result.Items = append(result.Items, *request)
+	}
+	if err = rows.Err(); err != nil {
+		return result, domain.WrapError("failed to query booking requests", err)
+	}
+
+	return result, nil
+}
+
+func (r *BookingRequestRepository) Approve(ctx context.Context, tenant, id string, assign func(*domain.BookingRequest) (string, error)) (*domain.BookingRequest, *domain.Appointment, error) {
+	return r.book(ctx, tenant, id, domain.BookingPending, assign)
+}
+
+func (r *BookingRequestRepository) AcceptCounterProposal(ctx context.Context, tenant, id string) (*domain.BookingRequest, *domain.Appointment, error) {
+	return r.book(ctx, tenant, id, domain.BookingCounterProposed, nil)
+}
+
+// book turns a request in status from into a SCHEDULED appointment. The
+// appointment insert and the status change share a transaction, so a double
+// booking leaves the request untouched. A non-nil assign picks the artist.
+func (r *BookingRequestRepository) book(ctx context.Context, tenant, id, from string, assign func(*domain.BookingRequest) (string, error)) (*domain.BookingRequest, *domain.Appointment, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var updated *domain.BookingRequest
+	var appointment *domain.Appointment
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		current, err := lockBookingRequest(ctx, tx, id, from)
+		if err != nil {
+			return err
+		}
+
+		var artistID string
+		if assign != nil {
+			if artistID, err = assign(current); err != nil {
+				return err
+			}
+		}
+		if artistID == "" {
+			artistID = current.ArtistID
+		}
+		if artistID == "" {
+			return fmt.Errorf("%w: an artist is required to book the request", domain.ErrInvalidArgument)
+		}
+		if artistID != current.ArtistID {
+			if err := ensureArtist(ctx, tx, current.StudioID, artistID); err != nil {
+				return err
+			}
+		}
+
+		appointment = &domain.Appointment{
+			StudioID:   current.StudioID,
+			CustomerID: current.CustomerID,
+			ArtistID:   artistID,
+			StartTime:  current.RequestedStart,
+			EndTime:    current.RequestedEnd,
+			Notes:      bookingNotes(current),
+		}
+		if from == domain.BookingCounterProposed {
+			appointment.StartTime, appointment.EndTime = *current.ProposedStart, *current.ProposedEnd
+		}
+		if err := insertAppointment(ctx, tx, appointment); err != nil {
+			return err
+		}
+
+		updated, err = scanBookingRequest(tx.QueryRow(ctx,
+			`UPDATE booking_requests SET status = $1, artist_id = $2, appointment_id = $3, updated_at = $4
+			 WHERE id = $5 RETURNING `+bookingRequestColumns,
+			domain.BookingApproved, artistID, appointment.ID, time.Now(), id))
+		if err != nil {
+			return domain.WrapError("failed to approve booking request", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	reminder.MarkDue(ctx, r.RedisManager, tenant)
+	return updated, appointment, nil
+}
+
+// CounterPropose answers a PENDING request with another slot
+func (r *BookingRequestRepository) CounterPropose(ctx context.Context, tenant, id string, start, end time.Time, note string, authorize func(*domain.BookingRequest) error) (*domain.BookingRequest, error) {
+	if err := validateRange(start, end); err != nil {
+		return nil, err
+	}
+	return r.transition(ctx, tenant, id, []string{domain.BookingPending}, authorize,
+		`status = $1, proposed_start = $2, proposed_end = $3, response_note = NULLIF($4, '')`,
+		domain.BookingCounterProposed, start, end, note)
+}
+
+// Decline closes an open request, either PENDING or COUNTER_PROPOSED
+func (r *BookingRequestRepository) Decline(ctx context.Context, tenant, id, note string, authorize func(*domain.BookingRequest) error) (*domain.BookingRequest, error) {
+	return r.transition(ctx, tenant, id, []string{domain.BookingPending, domain.BookingCounterProposed}, authorize,
+		`status = $1, response_note = NULLIF($2, '')`,
+		domain.BookingDeclined, note)
+}
+
+// Withdraw closes an open request on the client's behalf
+func (r *BookingRequestRepository) Withdraw(ctx context.Context, tenant, id string) (*domain.BookingRequest, error) {
+	return r.transition(ctx, tenant, id, []string{domain.BookingPending, domain.BookingCounterProposed}, nil,
+		`status = $1`, domain.BookingWithdrawn)
+}
+
+// transition applies setClause to a request currently in one of the from
+// statuses. A non-nil authorize checks the locked request first. setClause
+// numbers its parameters from $1.
+func (r *BookingRequestRepository) transition(ctx context.Context, tenant, id string, from []string, authorize func(*domain.BookingRequest) error, setClause string, args ...interface{}) (*domain.BookingRequest, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var updated *domain.BookingRequest
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		current, err := lockBookingRequest(ctx, tx, id, from...)
+		if err != nil {
+			return err
+		}
+		if authorize != nil {
+			if err := authorize(current); err != nil {
+				return err
+			}
+		}
+
+		args = append(args, time.Now(), id)
+		updated, err = scanBookingRequest(tx.QueryRow(ctx,
+			"UPDATE booking_requests SET "+setClause+
+				fmt.Sprintf(", updated_at = $%d WHERE id = $%d RETURNING ", len(args)-1, len(args))+bookingRequestColumns,
+			args...))
+		if err != nil {
+			return domain.WrapError("failed to update booking request", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// lockBookingRequest locks a request and fails unless its status is one of from
+func lockBookingRequest(ctx context.Context, tx pgx.Tx, id string, from ...string) (*domain.BookingRequest, error) {
+	request, err := scanBookingRequest(tx.QueryRow(ctx,
+		"SELECT "+bookingRequestColumns+" FROM booking_requests WHERE id = $1 FOR UPDATE", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("booking request %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get booking request", err)
+	}
+	for _, status := range from {
+		if request.Status == status {
+			return request, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: booking request is %s", domain.ErrFailedPrecondition, request.Status)
+}
+
+// bookingNotes carries the design details of a request over to its appointment
+func bookingNotes(request *domain.BookingRequest) string {
+	notes := []string{request.Description}
+	if request.Placement != "" {
+		notes = append(notes, "Placement: "+request.Placement)
+	}
+	if request.Size != "" {
+		notes = append(notes, "Size: "+request.Size)
+	}
+	for _, image := range request.ReferenceImages {
+		notes = append(notes, "Reference: "+image)
+	}
+	return strings.Join(notes, "\n")
+}
+
+func scanBookingRequest(row pgx.Row) (*domain.BookingRequest, error) {
+	var request domain.BookingRequest
+	err := row.Scan(&request.ID, &request.StudioID, &request.CustomerID, &request.ArtistID,
+		&request.RequestedStart, &request.RequestedEnd, &request.Description, &request.Placement, &request.Size,
+		&request.ReferenceImages, &request.Status, &request.ProposedStart, &request.ProposedEnd,
+		&request.ResponseNote, &request.AppointmentID, &request.CreatedAt, &request.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
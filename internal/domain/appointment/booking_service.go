@@ -0,0 +1,450 @@
+package appointment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// BookingServiceName is the fully qualified gRPC name of the booking request
+// service. The appointment protos only carry a slot and notes for
+// RequestAppointment, so design details and counter-proposals live here.
+const BookingServiceName = "inkMe.appointment.BookingService"
+
+// Notification kinds of the booking workflow
+const (
+	NotifyBookingRequested       = "booking_request.created"
+	NotifyBookingApproved        = "booking_request.approved"
+	NotifyBookingDeclined        = "booking_request.declined"
+	NotifyBookingCounterProposed = "booking_request.counter_proposed"
+	NotifyBookingWithdrawn       = "booking_request.withdrawn"
+)
+
+type BookingRequestInput struct {
+	StudioID   string `json:"studio_id"`
+	CustomerID string `json:"customer_id"`
+	ArtistID   string `json:"artist_id"`
+	// Start and End are RFC 3339 timestamps of the desired slot
+	Start           string   `json:"start"`
+	End             string   `json:"end"`
+	Description     string   `json:"description"`
+	Placement       string   `json:"placement"`
+	Size            string   `json:"size"`
+	ReferenceImages []string `json:"reference_images"`
+}
+
+type BookingRequestOutput struct {
+	ID              string   `json:"id"`
+	StudioID        string   `json:"studio_id"`
+	CustomerID      string   `json:"customer_id"`
+	ArtistID        string   `json:"artist_id,omitempty"`
+	Start           string   `json:"start"`
+	End             string   `json:"end"`
+	Description     string   `json:"description"`
+	Placement       string   `json:"placement,omitempty"`
+	Size            string   `json:"size,omitempty"`
+	ReferenceImages []string `json:"reference_images"`
+	Status          string   `json:"status"`
+	ProposedStart   string   `json:"proposed_start,omitempty"`
+	ProposedEnd     string   `json:"proposed_end,omitempty"`
+	ResponseNote    string   `json:"response_note,omitempty"`
+	AppointmentID   string   `json:"appointment_id,omitempty"`
+	CreatedAt       string   `json:"created_at"`
+	UpdatedAt       string   `json:"updated_at"`
+}
+
+type BookingRequestID struct {
+	ID string `json:"id"`
+}
+
+type ListBookingRequestsRequest struct {
+	StudioID   string `json:"studio_id"`
+	ArtistID   string `json:"artist_id"`
+	CustomerID string `json:"customer_id"`
+	Status     string `json:"status"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"page_size"`
+}
+
+type ListBookingRequestsResponse struct {
+	Requests   []BookingRequestOutput `json:"requests"`
+	TotalCount int64                  `json:"total_count"`
+}
+
+type ApproveBookingRequest struct {
+	ID string `json:"id"`
+	// ArtistID assigns the appointment when the request names no artist or a
+	// manager hands it to someone else; it defaults to the requested artist
+	ArtistID string `json:"artist_id"`
+}
+
+type DeclineBookingRequest struct {
+	ID   string `json:"id"`
+	Note string `json:"note"`
+}
+
+type CounterProposeRequest struct {
+	ID    string `json:"id"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Note  string `json:"note"`
+}
+
+type BookedResponse struct {
+	Request       BookingRequestOutput `json:"request"`
+	AppointmentID string               `json:"appointment_id"`
+}
+
+// BookingService implements the booking request workflow: clients request a
+// slot, the artist approves, declines or proposes another slot, and the client
+// accepts the proposal or withdraws. Both sides are notified of every step.
+type BookingService struct {
+	repo     domain.BookingRequestRepository
+	notifier domain.Notifier
+}
+
+// NewBookingService creates a new BookingService
+func NewBookingService(repo domain.BookingRequestRepository, notifier domain.Notifier) *BookingService {
+	return &BookingService{repo: repo, notifier: notifier}
+}
+
+// Register adds the service to a gRPC server
+func (s *BookingService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(BookingServiceName,
+		structrpc.Unary(BookingServiceName, "CreateBookingRequest", s.CreateBookingRequest),
+		structrpc.Unary(BookingServiceName, "GetBookingRequest", s.GetBookingRequest),
+		structrpc.Unary(BookingServiceName, "ListBookingRequests", s.ListBookingRequests),
+		structrpc.Unary(BookingServiceName, "ApproveBookingRequest", s.ApproveBookingRequest),
+		structrpc.Unary(BookingServiceName, "DeclineBookingRequest", s.DeclineBookingRequest),
+		structrpc.Unary(BookingServiceName, "CounterPropose", s.CounterPropose),
+		structrpc.Unary(BookingServiceName, "AcceptCounterProposal", s.AcceptCounterProposal),
+		structrpc.Unary(BookingServiceName, "WithdrawBookingRequest", s.WithdrawBookingRequest),
+	), s)
+}
+
+func (s *BookingService) CreateBookingRequest(ctx context.Context, req *BookingRequestInput) (*BookingRequestOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "CreateBookingRequest")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	start, err := parseTime("start", req.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTime("end", req.End)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := s.create(ctx, tenant, &domain.BookingRequest{
+		StudioID:        req.StudioID,
+		CustomerID:      req.CustomerID,
+		ArtistID:        req.ArtistID,
+		RequestedStart:  start,
+		RequestedEnd:    end,
+		Description:     req.Description,
+		Placement:       req.Placement,
+		Size:            req.Size,
+		ReferenceImages: req.ReferenceImages,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.String("booking_request.id", request.ID))
+
+	return bookingRequestOutput(request), nil
+}
+
+func (s *BookingService) GetBookingRequest(ctx context.Context, req *BookingRequestID) (*BookingRequestOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetBookingRequest")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	request, err := s.repo.GetByID(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get booking request")
+	}
+	return bookingRequestOutput(request), nil
+}
+
+// ListBookingRequests pages through booking requests, oldest first. Filter by
+// status PENDING to get an artist's queue.
+func (s *BookingService) ListBookingRequests(ctx context.Context, req *ListBookingRequestsRequest) (*ListBookingRequestsResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ListBookingRequests")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	result, err := s.repo.List(ctx, tenant, domain.BookingRequestFilter{
+		StudioID:   req.StudioID,
+		ArtistID:   req.ArtistID,
+		CustomerID: req.CustomerID,
+		Status:     req.Status,
+		Page:       req.Page,
+		PageSize:   min(req.PageSize, domain.MaxPageSize),
+	})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list booking requests")
+	}
+
+	res := &ListBookingRequestsResponse{
+		Requests:   make([]BookingRequestOutput, 0, len(result.Items)),
+		TotalCount: result.TotalCount,
+	}
+	for i := range result.Items {
+		res.Requests = append(res.Requests, *bookingRequestOutput(&result.Items[i]))
+	}
+
+	span.SetAttributes(attribute.Int("booking_requests.count", len(res.Requests)))
+
+	return res, nil
+}
+
+func (s *BookingService) ApproveBookingRequest(ctx context.Context, req *ApproveBookingRequest) (*BookedResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ApproveBookingRequest")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	request, appointment, err := s.approve(ctx, tenant, req.ID, req.ArtistID)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.String("appointment.id", appointment.ID))
+
+	return &BookedResponse{Request: *bookingRequestOutput(request), AppointmentID: appointment.ID}, nil
+}
+
+func (s *BookingService) DeclineBookingRequest(ctx context.Context, req *DeclineBookingRequest) (*BookingRequestOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "DeclineBookingRequest")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	request, err := s.decline(ctx, tenant, req.ID, req.Note)
+	if err != nil {
+		return nil, err
+	}
+	return bookingRequestOutput(request), nil
+}
+
+// CounterPropose offers the client another slot for a pending request
+func (s *BookingService) CounterPropose(ctx context.Context, req *CounterProposeRequest) (*BookingRequestOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "CounterPropose")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	start, err := parseTime("start", req.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTime("end", req.End)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := s.repo.CounterPropose(ctx, tenant, req.ID, start, end, req.Note, canAnswer(ctx))
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to counter-propose")
+	}
+	s.notify(ctx, tenant, request, NotifyBookingCounterProposed, "New time proposed",
+		fmt.Sprintf("The artist proposed %s instead of the requested slot.", formatSlot(*request.ProposedStart, *request.ProposedEnd)))
+
+	return bookingRequestOutput(request), nil
+}
+
+// AcceptCounterProposal books the slot the artist proposed
+func (s *BookingService) AcceptCounterProposal(ctx context.Context, req *BookingRequestID) (*BookedResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "AcceptCounterProposal")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	request, appointment, err := s.repo.AcceptCounterProposal(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to accept counter-proposal")
+	}
+	s.notify(ctx, tenant, request, NotifyBookingApproved, "Booking confirmed",
+		fmt.Sprintf("The proposed slot %s was accepted and booked.", formatSlot(appointment.StartTime, appointment.EndTime)))
+
+	span.SetAttributes(attribute.String("appointment.id", appointment.ID))
+
+	return &BookedResponse{Request: *bookingRequestOutput(request), AppointmentID: appointment.ID}, nil
+}
+
+// WithdrawBookingRequest closes an open request on the client's behalf
+func (s *BookingService) WithdrawBookingRequest(ctx context.Context, req *BookingRequestID) (*BookingRequestOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "WithdrawBookingRequest")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	request, err := s.repo.Withdraw(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to withdraw booking request")
+	}
+	s.notify(ctx, tenant, request, NotifyBookingWithdrawn, "Booking request withdrawn",
+		fmt.Sprintf("The request for %s was withdrawn.", formatSlot(request.RequestedStart, request.RequestedEnd)))
+
+	return bookingRequestOutput(request), nil
+}
+
+// create stores a request and lets the artist and the client know
+func (s *BookingService) create(ctx context.Context, tenant string, request *domain.BookingRequest) (*domain.BookingRequest, error) {
+	if err := s.repo.Create(ctx, tenant, request); err != nil {
+		return nil, domain.ToStatus(err, "failed to create booking request")
+	}
+	s.notify(ctx, tenant, request, NotifyBookingRequested, "New booking request",
+		fmt.Sprintf("A booking was requested for %s.", formatSlot(request.RequestedStart, request.RequestedEnd)))
+	return request, nil
+}
+
+// approve books a pending request with its artist or, when given, artistID.
+// A request without an artist is booked with the approving artist.
+func (s *BookingService) approve(ctx context.Context, tenant, id, artistID string) (*domain.BookingRequest, *domain.Appointment, error) {
+	if id == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	// The caller is checked against the locked request, so a concurrent
+	// reassignment cannot slip between the check and the booking
+	request, appointment, err := s.repo.Approve(ctx, tenant, id, func(current *domain.BookingRequest) (string, error) {
+		if current.ArtistID != "" {
+			if err := requireArtistOrManager(ctx, current.ArtistID); err != nil {
+				return "", err
+			}
+		}
+		if artistID == "" && current.ArtistID == "" {
+			return domain.ExtractUserIDFromContext(ctx)
+		}
+		if artistID != "" && artistID != current.ArtistID {
+			if err := requireArtistOrManager(ctx, artistID); err != nil {
+				return "", err
+			}
+		}
+		return artistID, nil
+	})
+	if err != nil {
+		return nil, nil, domain.ToStatus(err, "failed to approve booking request")
+	}
+	s.notify(ctx, tenant, request, NotifyBookingApproved, "Booking confirmed",
+		fmt.Sprintf("The booking for %s was approved.", formatSlot(appointment.StartTime, appointment.EndTime)))
+	return request, appointment, nil
+}
+
+func (s *BookingService) decline(ctx context.Context, tenant, id, note string) (*domain.BookingRequest, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	request, err := s.repo.Decline(ctx, tenant, id, note, canAnswer(ctx))
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to decline booking request")
+	}
+	body := fmt.Sprintf("The request for %s was declined.", formatSlot(request.RequestedStart, request.RequestedEnd))
+	if note != "" {
+		body += " " + note
+	}
+	s.notify(ctx, tenant, request, NotifyBookingDeclined, "Booking request declined", body)
+	return request, nil
+}
+
+// canAnswer checks that the caller may answer a request: its artist and
+// managers answer assigned requests, any staff member unassigned ones. It runs
+// on the locked request, so a concurrent reassignment cannot slip past it.
+func canAnswer(ctx context.Context) func(*domain.BookingRequest) error {
+	return func(request *domain.BookingRequest) error {
+		if request.ArtistID == "" {
+			return nil
+		}
+		return requireArtistOrManager(ctx, request.ArtistID)
+	}
+}
+
+// notify tells the client and the artist of the request about a step of the
+// workflow. The step has already happened, so failures are only logged.
+func (s *BookingService) notify(ctx context.Context, tenant string, request *domain.BookingRequest, kind, title, body string) {
+	data := map[string]string{"booking_request_id": request.ID, "status": request.Status}
+	if request.AppointmentID != "" {
+		data["appointment_id"] = request.AppointmentID
+	}
+
+	notifications := []domain.Notification{{CustomerID: request.CustomerID, Kind: kind, Title: title, Body: body, Data: data}}
+	if request.ArtistID != "" {
+		notifications = append(notifications, domain.Notification{UserID: request.ArtistID, Kind: kind, Title: title, Body: body, Data: data})
+	}
+
+	if err := s.notifier.Notify(ctx, tenant, notifications...); err != nil {
+		logger.Log.Warn("failed to send booking notifications",
+			zap.String("tenant", tenant),
+			zap.String("booking_request_id", request.ID),
+			zap.Error(err))
+	}
+}
+
+func formatSlot(start, end time.Time) string {
+	return fmt.Sprintf("%s - %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+}
+
+func bookingRequestOutput(request *domain.BookingRequest) *BookingRequestOutput {
+	out := &BookingRequestOutput{
+		ID:              request.ID,
+		StudioID:        request.StudioID,
+		CustomerID:      request.CustomerID,
+		ArtistID:        request.ArtistID,
+		Start:           request.RequestedStart.Format(time.RFC3339),
+		End:             request.RequestedEnd.Format(time.RFC3339),
+		Description:     request.Description,
+		Placement:       request.Placement,
+		Size:            request.Size,
+		ReferenceImages: request.ReferenceImages,
+		Status:          request.Status,
+		ResponseNote:    request.ResponseNote,
+		AppointmentID:   request.AppointmentID,
+		CreatedAt:       request.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       request.UpdatedAt.Format(time.RFC3339),
+	}
+	if request.ProposedStart != nil && request.ProposedEnd != nil {
+		out.ProposedStart = request.ProposedStart.Format(time.RFC3339)
+		out.ProposedEnd = request.ProposedEnd.Format(time.RFC3339)
+	}
+	return out
+}
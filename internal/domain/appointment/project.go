@@ -0,0 +1,508 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/reminder"
+)
+
+// maxProjectSessions caps how many sessions a recurrence can book at once
+const maxProjectSessions = 52
+
+// ProjectRepository stores multi-session projects and books their sessions as
+// appointments
+type ProjectRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewProjectRepository creates a new ProjectRepository
+func NewProjectRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *ProjectRepository {
+	return &ProjectRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+const projectColumns = `p.id, p.studio_id, p.customer_id, p.artist_id, p.title, COALESCE(p.description, ''),
+	COALESCE(p.recurrence_frequency, ''), COALESCE(p.recurrence_interval, 0), p.created_at, COALESCE(p.updated_at, p.created_at),
+	COUNT(a.id),
+	COUNT(a.id) FILTER (WHERE upper(a.status) = 'COMPLETED'),
+	COUNT(a.id) FILTER (WHERE upper(a.status) = 'SCHEDULED'),
+	COUNT(a.id) FILTER (WHERE upper(a.status) = 'CANCELED'),
+	MIN(a.start_time) FILTER (WHERE upper(a.status) = 'SCHEDULED' AND a.start_time > now())`
+
+const projectFrom = ` FROM projects p LEFT JOIN appointments a ON a.project_id = p.id`
+
+// Create stores the project and books all of its sessions, so a double booking
+// of any session fails the whole project
+func (r *ProjectRepository) Create(ctx context.Context, tenant string, project *domain.Project, start, end time.Time) ([]domain.Appointment, error) {
+	if project == nil {
+		return nil, fmt.Errorf("%w: project is required", domain.ErrInvalidArgument)
+	}
+	if project.CustomerID == "" || project.ArtistID == "" {
+		return nil, fmt.Errorf("%w: customer and artist are required", domain.ErrInvalidArgument)
+	}
+	if strings.TrimSpace(project.Title) == "" {
+		return nil, fmt.Errorf("%w: title is required", domain.ErrInvalidArgument)
+	}
+	if err := validateRange(start, end); err != nil {
+		return nil, err
+	}
+	if err := validateRecurrence(project.Recurrence); err != nil {
+		return nil, err
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var sessions []domain.Appointment
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		studioID, err := resolveStudio(ctx, tx, tenant, project.StudioID)
+		if err != nil {
+			return err
+		}
+		if err := ensureArtist(ctx, tx, studioID, project.ArtistID); err != nil {
+			return err
+		}
+		loc, err := studioLocation(ctx, tx, studioID)
+		if err != nil {
+			return err
+		}
+
+		var frequency string
+		var interval int
+		if project.Recurrence != nil {
+			frequency, interval = project.Recurrence.Frequency, project.Recurrence.Interval
+		}
+		err = tx.QueryRow(ctx,
+			`INSERT INTO projects (studio_id, customer_id, artist_id, title, description, recurrence_frequency, recurrence_interval)
+			 VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, 0))
+			 RETURNING id, created_at`,
+			studioID, project.CustomerID, project.ArtistID, project.Title, project.Description, frequency, interval).
+			Scan(&project.ID, &project.CreatedAt)
+		if err != nil {
+			return domain.WrapError("failed to create project", err)
+		}
+		project.StudioID = studioID
+		project.UpdatedAt = project.CreatedAt
+
+		length := end.Sub(start)
+		for i, sessionStart := range sessionStarts(start, loc, project.Recurrence) {
+			session := domain.Appointment{
+				StudioID:      studioID,
+				CustomerID:    project.CustomerID,
+				ArtistID:      project.ArtistID,
+				StartTime:     sessionStart,
+				EndTime:       sessionStart.Add(length),
+				ProjectID:     project.ID,
+				SessionNumber: i + 1,
+			}
+			if err := insertAppointment(ctx, tx, &session); err != nil {
+				return fmt.Errorf("session %d: %w", i+1, err)
+			}
+			sessions = append(sessions, session)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	reminder.MarkDue(ctx, r.RedisManager, tenant)
+
+	project.TotalSessions = len(sessions)
+	project.ScheduledSessions = len(sessions)
+	for i := range sessions {
+		if sessions[i].StartTime.After(time.Now()) {
+			next := sessions[i].StartTime
+			project.NextSession = &next
+			break
+		}
+	}
+	project.Status = projectStatus(project)
+	return sessions, nil
+}
+
+func (r *ProjectRepository) GetByID(ctx context.Context, tenant, id string) (*domain.Project, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	project, err := scanProject(pool.QueryRow(ctx, "SELECT "+projectColumns+projectFrom+" WHERE p.id = $1 GROUP BY p.id", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("project %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get project", err)
+	}
+	return project, nil
+}
+
+// List pages through the projects matching the filter, newest first
+func (r *ProjectRepository) List(ctx context.Context, tenant string, filter domain.ProjectFilter) (domain.PagedResult[domain.Project], error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = domain.DefaultPageSize
+	}
+	result := domain.PagedResult[domain.Project]{Items: []domain.Project{}, Page: filter.Page, PageSize: filter.PageSize}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return result, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	where := []string{"TRUE"}
+	var args []interface{}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if filter.StudioID != "" {
+		add("p.studio_id = $%d", filter.StudioID)
+	}
+	if filter.ArtistID != "" {
+		add("p.artist_id = $%d", filter.ArtistID)
+	}
+	if filter.CustomerID != "" {
+		add("p.customer_id = $%d", filter.CustomerID)
+	}
+	whereClause := strings.Join(where, " AND ")
+
+	if err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM projects p WHERE "+whereClause, args...).Scan(&result.TotalCount); err != nil {
+		return result, domain.WrapError("failed to count projects", err)
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	rows, err := pool.Query(ctx,
+		"SELECT "+projectColumns+projectFrom+" WHERE "+whereClause+
+			fmt.Sprintf(" GROUP BY p.id ORDER BY p.created_at DESC, p.id LIMIT $%d OFFSET $%d", len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return result, domain.WrapError("failed to query projects", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return result, fmt.Errorf("failed to scan project: %w", err)
+		}
+		result.Items = append(result.Items, *project)
+	}
+	if err = rows.Err(); err != nil {
+		return result, domain.WrapError("failed to query projects", err)
+	}
+
+	return result, nil
+}
+
+// Sessions returns the appointments of a project by session number
+func (r *ProjectRepository) Sessions(ctx context.Context, tenant, id string) ([]domain.Appointment, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	rows, err := pool.Query(ctx,
+		"SELECT "+appointmentColumns+" FROM appointments WHERE project_id = $1 ORDER BY session_number, start_time", id)
+	if err != nil {
+		return nil, domain.WrapError("failed to query project sessions", err)
+	}
+	defer rows.Close()
+
+	sessions := []domain.Appointment{}
+	for rows.Next() {
+		session, err := scanAppointment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan appointment: %w", err)
+		}
+		sessions = append(sessions, *session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to query project sessions", err)
+	}
+	return sessions, nil
+}
+
+// AddSession books one more session of the project, numbered after the last
+func (r *ProjectRepository) AddSession(ctx context.Context, tenant, id string, start, end time.Time) (*domain.Appointment, error) {
+	if err := validateRange(start, end); err != nil {
+		return nil, err
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var session *domain.Appointment
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		project, err := lockProject(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		session = &domain.Appointment{
+			StudioID:   project.StudioID,
+			CustomerID: project.CustomerID,
+			ArtistID:   project.ArtistID,
+			StartTime:  start,
+			EndTime:    end,
+			ProjectID:  id,
+		}
+		err = tx.QueryRow(ctx,
+			"SELECT COALESCE(MAX(session_number), 0) + 1 FROM appointments WHERE project_id = $1", id).
+			Scan(&session.SessionNumber)
+		if err != nil {
+			return domain.WrapError("failed to number session", err)
+		}
+		if err = insertAppointment(ctx, tx, session); err != nil {
+			return err
+		}
+		return touchProject(ctx, tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	reminder.MarkDue(ctx, r.RedisManager, tenant)
+	return session, nil
+}
+
+// Reschedule shifts the upcoming sessions by the calendar days and wall clock
+// change of the first one, in the studio's time zone. Cancelled and completed
+// sessions stay where they are.
+func (r *ProjectRepository) Reschedule(ctx context.Context, tenant, id string, fromSession int, start time.Time) ([]domain.Appointment, error) {
+	if start.IsZero() {
+		return nil, fmt.Errorf("%w: start time is required", domain.ErrInvalidArgument)
+	}
+	if !start.After(time.Now()) {
+		return nil, fmt.Errorf("%w: sessions can only move to the future", domain.ErrInvalidArgument)
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var moved []domain.Appointment
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		project, err := lockProject(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		loc, err := studioLocation(ctx, tx, project.StudioID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx,
+			"SELECT "+appointmentColumns+` FROM appointments
+			 WHERE project_id = $1 AND upper(status) = $2 AND start_time > now() AND session_number >= $3
+			 ORDER BY start_time FOR UPDATE`,
+			id, domain.AppointmentScheduled, fromSession)
+		if err != nil {
+			return domain.WrapError("failed to query project sessions", err)
+		}
+		sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Appointment, error) {
+			session, err := scanAppointment(row)
+			if err != nil {
+				return domain.Appointment{}, err
+			}
+			return *session, nil
+		})
+		if err != nil {
+			return domain.WrapError("failed to query project sessions", err)
+		}
+		if len(sessions) == 0 {
+			return fmt.Errorf("%w: project has no upcoming scheduled sessions to move", domain.ErrFailedPrecondition)
+		}
+
+		first := sessions[0].StartTime.In(loc)
+		target := start.In(loc)
+		days := civilDays(first, target)
+		minutes := minuteOfDay(target) - minuteOfDay(first)
+
+		// Move the sessions furthest along the shift first so that sessions
+		// never overlap each other while the others are still in place
+		order := make([]int, len(sessions))
+		for i := range order {
+			order[i] = i
+			if start.After(sessions[0].StartTime) {
+				order[i] = len(sessions) - 1 - i
+			}
+		}
+
+		moved = make([]domain.Appointment, len(sessions))
+		now := time.Now()
+		for _, i := range order {
+			session := sessions[i]
+			newStart := shiftWallClock(session.StartTime, loc, days, minutes)
+			updated, err := scanAppointment(tx.QueryRow(ctx,
+				`UPDATE appointments SET start_time = $1, end_time = $2, legacy_conflict = false, updated_at = $3
+				 WHERE id = $4 RETURNING `+appointmentColumns,
+				newStart, newStart.Add(session.EndTime.Sub(session.StartTime)), now, session.ID))
+			if err != nil {
+				return domain.WrapError(fmt.Sprintf("failed to move session %d", session.SessionNumber), err)
+			}
+			moved[i] = *updated
+		}
+		return touchProject(ctx, tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	reminder.MarkDue(ctx, r.RedisManager, tenant)
+	return moved, nil
+}
+
+func lockProject(ctx context.Context, tx pgx.Tx, id string) (*domain.Project, error) {
+	project := &domain.Project{ID: id}
+	err := tx.QueryRow(ctx,
+		"SELECT studio_id, customer_id, artist_id FROM projects WHERE id = $1 FOR UPDATE", id).
+		Scan(&project.StudioID, &project.CustomerID, &project.ArtistID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("project %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get project", err)
+	}
+	return project, nil
+}
+
+func touchProject(ctx context.Context, tx pgx.Tx, id string) error {
+	if _, err := tx.Exec(ctx, "UPDATE projects SET updated_at = now() WHERE id = $1", id); err != nil {
+		return domain.WrapError("failed to update project", err)
+	}
+	return nil
+}
+
+// studioLocation loads the time zone the studio's calendar runs in
+func studioLocation(ctx context.Context, tx pgx.Tx, studioID string) (*time.Location, error) {
+	hours, err := queryStudioHours(ctx, tx, "s.id = $1", studioID)
+	if err != nil {
+		return nil, err
+	}
+	return loadLocation(hours.TimeZone)
+}
+
+func validateRecurrence(rule *domain.Recurrence) error {
+	if rule == nil {
+		return nil
+	}
+	rule.Frequency = strings.ToUpper(rule.Frequency)
+	switch rule.Frequency {
+	case domain.RecurDaily, domain.RecurWeekly, domain.RecurMonthly:
+	default:
+		return fmt.Errorf("%w: recurrence frequency must be DAILY, WEEKLY or MONTHLY", domain.ErrInvalidArgument)
+	}
+	if rule.Interval == 0 {
+		rule.Interval = 1
+	}
+	if rule.Interval < 0 {
+		return fmt.Errorf("%w: recurrence interval must be positive", domain.ErrInvalidArgument)
+	}
+	if rule.Count < 1 || rule.Count > maxProjectSessions {
+		return fmt.Errorf("%w: recurrence count must be between 1 and %d", domain.ErrInvalidArgument, maxProjectSessions)
+	}
+	return nil
+}
+
+// sessionStarts expands a recurrence from the first session. Every session
+// starts at the first one's wall clock time in loc, so the sessions do not
+// drift by an hour across daylight-saving changes. Monthly sessions on a day
+// the month lacks fall on its last day.
+func sessionStarts(first time.Time, loc *time.Location, rule *domain.Recurrence) []time.Time {
+	if rule == nil {
+		return []time.Time{first}
+	}
+
+	local := first.In(loc)
+	minutes := minuteOfDay(local)
+	starts := []time.Time{first}
+	for i := 1; i < rule.Count; i++ {
+		step := i * rule.Interval
+		var date time.Time
+		switch rule.Frequency {
+		case domain.RecurDaily:
+			date = time.Date(local.Year(), local.Month(), local.Day()+step, 0, 0, 0, 0, time.UTC)
+		case domain.RecurWeekly:
+			date = time.Date(local.Year(), local.Month(), local.Day()+7*step, 0, 0, 0, 0, time.UTC)
+		case domain.RecurMonthly:
+			month := time.Date(local.Year(), local.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+			day := min(local.Day(), month.AddDate(0, 1, -1).Day())
+			date = time.Date(month.Year(), month.Month(), day, 0, 0, 0, 0, time.UTC)
+		}
+		starts = append(starts, wallClock(date, minutes, loc))
+	}
+	return starts
+}
+
+// shiftWallClock moves t by days calendar days and minutes of wall clock time
+// in loc
+func shiftWallClock(t time.Time, loc *time.Location, days, minutes int) time.Time {
+	local := t.In(loc)
+	total := minuteOfDay(local) + minutes
+	// Carry whole days so the minute of the day stays within [0, 1440)
+	carry := total / (24 * 60)
+	if total < 0 {
+		carry = (total - 24*60 + 1) / (24 * 60)
+	}
+	date := time.Date(local.Year(), local.Month(), local.Day()+days+carry, 0, 0, 0, 0, time.UTC)
+	return wallClock(date, total-carry*24*60, loc)
+}
+
+// civilDays counts the calendar days from the date of a to the date of b
+func civilDays(a, b time.Time) int {
+	dateA := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	dateB := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(dateB.Sub(dateA).Hours() / 24)
+}
+
+func minuteOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+// projectStatus derives the progress status from the session counts
+func projectStatus(project *domain.Project) string {
+	switch {
+	case project.ScheduledSessions > 0 && project.CompletedSessions > 0:
+		return domain.ProjectInProgress
+	case project.ScheduledSessions > 0 || project.TotalSessions == 0:
+		return domain.ProjectPlanned
+	case project.CompletedSessions > 0:
+		return domain.ProjectCompleted
+	default:
+		return domain.ProjectCanceled
+	}
+}
+
+func scanProject(row pgx.Row) (*domain.Project, error) {
+	var project domain.Project
+	var frequency string
+	var interval int
+	err := row.Scan(&project.ID, &project.StudioID, &project.CustomerID, &project.ArtistID, &project.Title,
+		&project.Description, &frequency, &interval, &project.CreatedAt, &project.UpdatedAt,
+		&project.TotalSessions, &project.CompletedSessions, &project.ScheduledSessions, &project.CanceledSessions,
+		&project.NextSession)
+	if err != nil {
+		return nil, err
+	}
+	if frequency != "" {
+		project.Recurrence = &domain.Recurrence{Frequency: frequency, Interval: interval}
+	}
+	project.Status = projectStatus(&project)
+	return &project, nil
+}
@@ -0,0 +1,364 @@
+package appointment
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// ProjectServiceName is the fully qualified gRPC name of the project service.
+// The appointment protos know single appointments only, so pieces that take
+// several sessions are planned here. The sessions themselves are ordinary
+// appointments: cancelling or completing one leaves the others untouched.
+const ProjectServiceName = "inkMe.appointment.ProjectService"
+
+type RecurrenceInput struct {
+	// Frequency is DAILY, WEEKLY or MONTHLY
+	Frequency string `json:"frequency"`
+	Interval  int    `json:"interval"`
+	Count     int    `json:"count,omitempty"`
+}
+
+type CreateProjectRequest struct {
+	StudioID    string `json:"studio_id"`
+	CustomerID  string `json:"customer_id"`
+	ArtistID    string `json:"artist_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Start and End are RFC 3339 timestamps of the first session; the
+	// recurrence repeats it, e.g. every 3 weeks for 4 sessions
+	Start      string           `json:"start"`
+	End        string           `json:"end"`
+	Recurrence *RecurrenceInput `json:"recurrence"`
+}
+
+type ProjectProgress struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Scheduled int `json:"scheduled"`
+	Canceled  int `json:"canceled"`
+}
+
+type ProjectOutput struct {
+	ID          string           `json:"id"`
+	StudioID    string           `json:"studio_id"`
+	CustomerID  string           `json:"customer_id"`
+	ArtistID    string           `json:"artist_id"`
+	Title       string           `json:"title"`
+	Description string           `json:"description,omitempty"`
+	Recurrence  *RecurrenceInput `json:"recurrence,omitempty"`
+	Status      string           `json:"status"`
+	Progress    ProjectProgress  `json:"progress"`
+	NextSession string           `json:"next_session,omitempty"`
+	CreatedAt   string           `json:"created_at"`
+	UpdatedAt   string           `json:"updated_at"`
+}
+
+type ProjectSessionOutput struct {
+	AppointmentID string `json:"appointment_id"`
+	SessionNumber int    `json:"session_number"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+	Status        string `json:"status"`
+}
+
+type ProjectResponse struct {
+	Project  ProjectOutput          `json:"project"`
+	Sessions []ProjectSessionOutput `json:"sessions"`
+}
+
+type ProjectID struct {
+	ID string `json:"id"`
+}
+
+type ListProjectsRequest struct {
+	StudioID   string `json:"studio_id"`
+	ArtistID   string `json:"artist_id"`
+	CustomerID string `json:"customer_id"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"page_size"`
+}
+
+type ListProjectsResponse struct {
+	Projects   []ProjectOutput `json:"projects"`
+	TotalCount int64           `json:"total_count"`
+}
+
+type AddProjectSessionRequest struct {
+	ID    string `json:"id"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type RescheduleProjectRequest struct {
+	ID string `json:"id"`
+	// FromSession is the first session number to move; 0 moves every
+	// upcoming session
+	FromSession int `json:"from_session"`
+	// Start is the RFC 3339 new start of the first session moved
+	Start string `json:"start"`
+}
+
+type ProjectSessionsResponse struct {
+	Sessions []ProjectSessionOutput `json:"sessions"`
+}
+
+// ProjectService implements the project gRPC service
+type ProjectService struct {
+	repo domain.ProjectRepository
+}
+
+// NewProjectService creates a new ProjectService
+func NewProjectService(repo domain.ProjectRepository) *ProjectService {
+	return &ProjectService{repo: repo}
+}
+
+// Register adds the service to a gRPC server
+func (s *ProjectService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(ProjectServiceName,
+		structrpc.Unary(ProjectServiceName, "CreateProject", s.CreateProject),
+		structrpc.Unary(ProjectServiceName, "GetProject", s.GetProject),
+		structrpc.Unary(ProjectServiceName, "ListProjects", s.ListProjects),
+		structrpc.Unary(ProjectServiceName, "AddProjectSession", s.AddProjectSession),
+		structrpc.Unary(ProjectServiceName, "RescheduleProject", s.RescheduleProject),
+	), s)
+}
+
+// CreateProject plans a project and books all of its sessions. If any session
+// is double booked, nothing is created.
+func (s *ProjectService) CreateProject(ctx context.Context, req *CreateProjectRequest) (*ProjectResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "CreateProject")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ArtistID == "" {
+		return nil, status.Error(codes.InvalidArgument, "artist_id is required")
+	}
+	if err = requireArtistOrManager(ctx, req.ArtistID); err != nil {
+		return nil, err
+	}
+	start, err := parseTime("start", req.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTime("end", req.End)
+	if err != nil {
+		return nil, err
+	}
+
+	project := &domain.Project{
+		StudioID:    req.StudioID,
+		CustomerID:  req.CustomerID,
+		ArtistID:    req.ArtistID,
+		Title:       req.Title,
+		Description: req.Description,
+	}
+	if req.Recurrence != nil {
+		project.Recurrence = &domain.Recurrence{
+			Frequency: req.Recurrence.Frequency,
+			Interval:  req.Recurrence.Interval,
+			Count:     req.Recurrence.Count,
+		}
+	}
+
+	sessions, err := s.repo.Create(ctx, tenant, project, start, end)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to create project")
+	}
+
+	span.SetAttributes(
+		attribute.String("project.id", project.ID),
+		attribute.Int("project.sessions", len(sessions)),
+	)
+
+	return &ProjectResponse{Project: *projectOutput(project), Sessions: sessionOutputs(sessions)}, nil
+}
+
+// GetProject returns a project with its progress and all of its sessions
+func (s *ProjectService) GetProject(ctx context.Context, req *ProjectID) (*ProjectResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetProject")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	project, err := s.repo.GetByID(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get project")
+	}
+	sessions, err := s.repo.Sessions(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get project sessions")
+	}
+
+	return &ProjectResponse{Project: *projectOutput(project), Sessions: sessionOutputs(sessions)}, nil
+}
+
+// ListProjects pages through projects, newest first
+func (s *ProjectService) ListProjects(ctx context.Context, req *ListProjectsRequest) (*ListProjectsResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ListProjects")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	result, err := s.repo.List(ctx, tenant, domain.ProjectFilter{
+		StudioID:   req.StudioID,
+		ArtistID:   req.ArtistID,
+		CustomerID: req.CustomerID,
+		Page:       req.Page,
+		PageSize:   min(req.PageSize, domain.MaxPageSize),
+	})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list projects")
+	}
+
+	res := &ListProjectsResponse{
+		Projects:   make([]ProjectOutput, 0, len(result.Items)),
+		TotalCount: result.TotalCount,
+	}
+	for i := range result.Items {
+		res.Projects = append(res.Projects, *projectOutput(&result.Items[i]))
+	}
+
+	span.SetAttributes(attribute.Int("projects.count", len(res.Projects)))
+
+	return res, nil
+}
+
+// AddProjectSession books another session, e.g. for touch-ups
+func (s *ProjectService) AddProjectSession(ctx context.Context, req *AddProjectSessionRequest) (*ProjectSessionOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "AddProjectSession")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if _, err = s.loadForArtist(ctx, tenant, req.ID); err != nil {
+		return nil, err
+	}
+	start, err := parseTime("start", req.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTime("end", req.End)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.repo.AddSession(ctx, tenant, req.ID, start, end)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to add project session")
+	}
+
+	span.SetAttributes(attribute.String("appointment.id", session.ID))
+
+	return sessionOutput(session), nil
+}
+
+// RescheduleProject moves the upcoming sessions together, keeping the spacing
+// between them
+func (s *ProjectService) RescheduleProject(ctx context.Context, req *RescheduleProjectRequest) (*ProjectSessionsResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "RescheduleProject")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if _, err = s.loadForArtist(ctx, tenant, req.ID); err != nil {
+		return nil, err
+	}
+	if req.FromSession < 0 {
+		return nil, status.Error(codes.InvalidArgument, "from_session must not be negative")
+	}
+	start, err := parseTime("start", req.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.repo.Reschedule(ctx, tenant, req.ID, req.FromSession, start)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to reschedule project")
+	}
+
+	span.SetAttributes(attribute.Int("project.moved_sessions", len(sessions)))
+
+	return &ProjectSessionsResponse{Sessions: sessionOutputs(sessions)}, nil
+}
+
+// loadForArtist returns the project when the caller is its artist or a manager
+func (s *ProjectService) loadForArtist(ctx context.Context, tenant, id string) (*domain.Project, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	project, err := s.repo.GetByID(ctx, tenant, id)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get project")
+	}
+	if err = requireArtistOrManager(ctx, project.ArtistID); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func projectOutput(project *domain.Project) *ProjectOutput {
+	out := &ProjectOutput{
+		ID:          project.ID,
+		StudioID:    project.StudioID,
+		CustomerID:  project.CustomerID,
+		ArtistID:    project.ArtistID,
+		Title:       project.Title,
+		Description: project.Description,
+		Status:      project.Status,
+		Progress: ProjectProgress{
+			Total:     project.TotalSessions,
+			Completed: project.CompletedSessions,
+			Scheduled: project.ScheduledSessions,
+			Canceled:  project.CanceledSessions,
+		},
+		CreatedAt: project.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: project.UpdatedAt.Format(time.RFC3339),
+	}
+	if project.Recurrence != nil {
+		out.Recurrence = &RecurrenceInput{
+			Frequency: project.Recurrence.Frequency,
+			Interval:  project.Recurrence.Interval,
+			Count:     project.Recurrence.Count,
+		}
+	}
+	if project.NextSession != nil {
+		out.NextSession = project.NextSession.Format(time.RFC3339)
+	}
+	return out
+}
+
+func sessionOutput(session *domain.Appointment) *ProjectSessionOutput {
+	return &ProjectSessionOutput{
+		AppointmentID: session.ID,
+		SessionNumber: session.SessionNumber,
+		Start:         session.StartTime.Format(time.RFC3339),
+		End:           session.EndTime.Format(time.RFC3339),
+		Status:        session.Status,
+	}
+}
+
+func sessionOutputs(sessions []domain.Appointment) []ProjectSessionOutput {
+	out := make([]ProjectSessionOutput, 0, len(sessions))
+	for i := range sessions {
+		out = append(out, *sessionOutput(&sessions[i]))
+	}
+	return out
+}
@@ -0,0 +1,190 @@
+package appointment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+func assertTimes(t *testing.T, got []time.Time, loc *time.Location, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d times %v, want %d %v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if s := got[i].In(loc).Format("2006-01-02 15:04 MST"); s != want[i] {
+			t.Errorf("time %d is %s, want %s", i, s, want[i])
+		}
+	}
+}
+
+func TestSessionStartsKeepWallClock(t *testing.T) {
+	loc := loadZone(t, "Europe/Lisbon")
+
+	tests := []struct {
+		name  string
+		first time.Time
+		rule  *domain.Recurrence
+		want  []string
+	}{
+		{
+			name:  "no recurrence",
+			first: time.Date(2025, 3, 10, 14, 0, 0, 0, loc),
+			want:  []string{"2025-03-10 14:00 WET"},
+		},
+		{
+			// Lisbon springs forward on 30 March 2025
+			name:  "every 3 weeks across spring-forward",
+			first: time.Date(2025, 3, 10, 14, 0, 0, 0, loc),
+			rule:  &domain.Recurrence{Frequency: domain.RecurWeekly, Interval: 3, Count: 3},
+			want:  []string{"2025-03-10 14:00 WET", "2025-03-31 14:00 WEST", "2025-04-21 14:00 WEST"},
+		},
+		{
+			// and falls back on 26 October 2025
+			name:  "every 3 weeks across fall-back",
+			first: time.Date(2025, 10, 6, 14, 0, 0, 0, loc),
+			rule:  &domain.Recurrence{Frequency: domain.RecurWeekly, Interval: 3, Count: 3},
+			want:  []string{"2025-10-06 14:00 WEST", "2025-10-27 14:00 WET", "2025-11-17 14:00 WET"},
+		},
+		{
+			name:  "daily across spring-forward",
+			first: time.Date(2025, 3, 29, 10, 0, 0, 0, loc),
+			rule:  &domain.Recurrence{Frequency: domain.RecurDaily, Interval: 1, Count: 3},
+			want:  []string{"2025-03-29 10:00 WET", "2025-03-30 10:00 WEST", "2025-03-31 10:00 WEST"},
+		},
+		{
+			// Short months take their last day without moving later months
+			name:  "monthly on the 31st",
+			first: time.Date(2025, 1, 31, 10, 0, 0, 0, loc),
+			rule:  &domain.Recurrence{Frequency: domain.RecurMonthly, Interval: 1, Count: 5},
+			want: []string{"2025-01-31 10:00 WET", "2025-02-28 10:00 WET", "2025-03-31 10:00 WEST",
+				"2025-04-30 10:00 WEST", "2025-05-31 10:00 WEST"},
+		},
+		{
+			name:  "monthly on the 31st in a leap year",
+			first: time.Date(2024, 1, 31, 10, 0, 0, 0, loc),
+			rule:  &domain.Recurrence{Frequency: domain.RecurMonthly, Interval: 1, Count: 2},
+			want:  []string{"2024-01-31 10:00 WET", "2024-02-29 10:00 WET"},
+		},
+		{
+			name:  "every other month on the 31st",
+			first: time.Date(2025, 8, 31, 10, 0, 0, 0, loc),
+			rule:  &domain.Recurrence{Frequency: domain.RecurMonthly, Interval: 2, Count: 3},
+			want:  []string{"2025-08-31 10:00 WEST", "2025-10-31 10:00 WET", "2025-12-31 10:00 WET"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTimes(t, sessionStarts(tt.first, loc, tt.rule), loc, tt.want...)
+		})
+	}
+}
+
+func TestCivilDaysCountsCalendarDays(t *testing.T) {
+	loc := loadZone(t, "Europe/Lisbon")
+
+	tests := []struct {
+		name string
+		a, b time.Time
+		want int
+	}{
+		{
+			name: "same day",
+			a:    time.Date(2025, 3, 10, 0, 0, 0, 0, loc),
+			b:    time.Date(2025, 3, 10, 23, 59, 0, 0, loc),
+			want: 0,
+		},
+		{
+			// Only 23 hours pass between the two wall clock times
+			name: "across spring-forward",
+			a:    time.Date(2025, 3, 29, 10, 0, 0, 0, loc),
+			b:    time.Date(2025, 3, 30, 10, 0, 0, 0, loc),
+			want: 1,
+		},
+		{
+			name: "across fall-back",
+			a:    time.Date(2025, 10, 25, 23, 0, 0, 0, loc),
+			b:    time.Date(2025, 10, 27, 0, 0, 0, 0, loc),
+			want: 2,
+		},
+		{
+			name: "backwards",
+			a:    time.Date(2025, 4, 2, 9, 0, 0, 0, loc),
+			b:    time.Date(2025, 3, 28, 18, 0, 0, 0, loc),
+			want: -5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := civilDays(tt.a, tt.b); got != tt.want {
+				t.Errorf("civilDays = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRescheduleShiftAcrossSpringForward applies the shift Reschedule derives
+// from the first session to the later ones
+func TestRescheduleShiftAcrossSpringForward(t *testing.T) {
+	loc := loadZone(t, "Europe/Lisbon")
+
+	tests := []struct {
+		name     string
+		sessions []time.Time
+		target   time.Time
+		want     []string
+	}{
+		{
+			name: "later days, same time",
+			sessions: []time.Time{
+				time.Date(2025, 3, 20, 10, 0, 0, 0, loc),
+				time.Date(2025, 3, 27, 10, 0, 0, 0, loc),
+			},
+			target: time.Date(2025, 3, 31, 10, 0, 0, 0, loc),
+			want:   []string{"2025-03-31 10:00 WEST", "2025-04-07 10:00 WEST"},
+		},
+		{
+			name: "later days, later time",
+			sessions: []time.Time{
+				time.Date(2025, 3, 24, 10, 0, 0, 0, loc),
+				time.Date(2025, 3, 31, 10, 0, 0, 0, loc),
+			},
+			target: time.Date(2025, 3, 28, 11, 30, 0, 0, loc),
+			want:   []string{"2025-03-28 11:30 WET", "2025-04-04 11:30 WEST"},
+		},
+		{
+			// The shift carries past midnight into the spring-forward day
+			name: "past midnight",
+			sessions: []time.Time{
+				time.Date(2025, 3, 21, 23, 0, 0, 0, loc),
+				time.Date(2025, 3, 28, 23, 0, 0, 0, loc),
+			},
+			target: time.Date(2025, 3, 23, 0, 30, 0, 0, loc),
+			want:   []string{"2025-03-23 00:30 WET", "2025-03-30 00:30 WET"},
+		},
+		{
+			name: "earlier time before midnight",
+			sessions: []time.Time{
+				time.Date(2025, 3, 25, 0, 30, 0, 0, loc),
+				time.Date(2025, 4, 1, 0, 30, 0, 0, loc),
+			},
+			target: time.Date(2025, 3, 26, 23, 30, 0, 0, loc),
+			want:   []string{"2025-03-26 23:30 WET", "2025-04-02 23:30 WEST"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := tt.sessions[0].In(loc)
+			target := tt.target.In(loc)
+			days := civilDays(first, target)
+			minutes := minuteOfDay(target) - minuteOfDay(first)
+
+			moved := make([]time.Time, 0, len(tt.sessions))
+			for _, session := range tt.sessions {
+				moved = append(moved, shiftWallClock(session, loc, days, minutes))
+			}
+			assertTimes(t, moved, loc, tt.want...)
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/payment"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/reminder"
+)
+
+// AppointmentRepository stores appointments in the tenant's database
+type AppointmentRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewAppointmentRepository creates a new AppointmentRepository
+func NewAppointmentRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *AppointmentRepository {
+	return &AppointmentRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+const appointmentColumns = `id, studio_id, customers_id, COALESCE(artist_id::text, ''), start_time, end_time,
+	status, COALESCE(notes, ''), created_at, COALESCE(updated_at, created_at),
+	deposit_amount, COALESCE(deposit_currency, ''), deposit_status,
+	COALESCE(project_id::text, ''), COALESCE(session_number, 0)`
+
+// Create books an appointment. Without a StudioID it belongs to the tenant's
+// own studio; the artist must be on that studio's staff. The new ID, status
+// and timestamps are set on appointment.
+func (r *AppointmentRepository) Create(ctx context.Context, tenant string, appointment *domain.Appointment) error {
+	if appointment == nil {
+		return fmt.Errorf("%w: appointment is required", domain.ErrInvalidArgument)
+	}
+	if appointment.CustomerID == "" {
+		return fmt.Errorf("%w: customer is required", domain.ErrInvalidArgument)
+	}
+	if err := validateRange(appointment.StartTime, appointment.EndTime); err != nil {
+		return err
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		studioID, err := resolveStudio(ctx, tx, tenant, appointment.StudioID)
+		if err != nil {
+			return err
+		}
+		appointment.StudioID = studioID
+		if appointment.ArtistID != "" {
+			if err := ensureArtist(ctx, tx, studioID, appointment.ArtistID); err != nil {
+				return err
+			}
+		}
+		return insertAppointment(ctx, tx, appointment)
+	})
+	if err != nil {
+		return err
+	}
+	reminder.MarkDue(ctx, r.RedisManager, tenant)
+	return nil
+}
+
+func (r *AppointmentRepository) GetByID(ctx context.Context, tenant, id string) (*domain.Appointment, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	appointment, err := scanAppointment(pool.QueryRow(ctx, "SELECT "+appointmentColumns+" FROM appointments WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("appointment %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get appointment", err)
+	}
+	return appointment, nil
+}
+
+// List pages through the appointments matching the filter by start time
+func (r *AppointmentRepository) List(ctx context.Context, tenant string, filter domain.AppointmentFilter) (domain.PagedResult[domain.Appointment], error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = domain.DefaultPageSize
+	}
+	result := domain.PagedResult[domain.Appointment]{Items: []domain.Appointment{}, Page: filter.Page, PageSize: filter.PageSize}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return result, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	where := []string{"TRUE"}
+	var args []interface{}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if filter.StudioID != "" {
+		add("studio_id = $%d", filter.StudioID)
+	}
+	if filter.ArtistID != "" {
+		add("artist_id = $%d", filter.ArtistID)
+	}
+	if filter.CustomerID != "" {
+		add("customers_id = $%d", filter.CustomerID)
+	}
+	if filter.ProjectID != "" {
+		add("project_id = $%d", filter.ProjectID)
+	}
+	if filter.Status != "" {
+		add("status = upper($%d)", filter.Status)
+	}
+	if filter.From != nil {
+		add("end_time > $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("start_time < $%d", *filter.To)
+	}
+	whereClause := strings.Join(where, " AND ")
+
+	if err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM appointments WHERE "+whereClause, args...).Scan(&result.TotalCount); err != nil {
+		return result, domain.WrapError("failed to count appointments", err)
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	rows, err := pool.Query(ctx,
+		"SELECT "+appointmentColumns+" FROM appointments WHERE "+whereClause+
+			fmt.Sprintf(" ORDER BY start_time, id LIMIT $%d OFFSET $%d", len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return result, domain.WrapError("failed to query appointments", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		appointment, err := scanAppointment(rows)
+		if err != nil {
+			return result, fmt.Errorf("failed to scan appointment: %w", err)
+		}
+		result.Items = append(result.Items, *appointment)
+	}
+	if err = rows.Err(); err != nil {
+		return result, domain.WrapError("failed to query appointments", err)
+	}
+
+	return result, nil
+}
+
+// Update writes the fields of appointment named in updateMask. Supported paths
+// are notes, status and artist_id; times change through Reschedule. Marking an
+// appointment NO_SHOW forfeits its paid deposit.
+func (r *AppointmentRepository) Update(ctx context.Context, tenant, id string, appointment *domain.Appointment, updateMask *fieldmaskpb.FieldMask) (*domain.Appointment, error) {
+	if appointment == nil {
+		return nil, fmt.Errorf("%w: appointment is required", domain.ErrInvalidArgument)
+	}
+	if updateMask == nil || len(updateMask.Paths) == 0 {
+		return nil, fmt.Errorf("%w: update_mask is required", domain.ErrInvalidArgument)
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var updated *domain.Appointment
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		current, err := lockAppointment(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		var setClauses []string
+		var args []interface{}
+		var forfeit bool
+		set := func(clause string, value interface{}) {
+			args = append(args, value)
+			setClauses = append(setClauses, fmt.Sprintf(clause, len(args)))
+		}
+		for _, path := range updateMask.Paths {
+			switch path {
+			case "notes":
+				set("notes = NULLIF($%d, '')", appointment.Notes)
+			case "status":
+				status := strings.ToUpper(appointment.Status)
+				if err := domain.ValidateAppointmentTransition(current.Status, status); err != nil {
+					return err
+				}
+				set("status = $%d", status)
+				forfeit = status == domain.AppointmentNoShow && current.Status != domain.AppointmentNoShow
+			case "artist_id":
+				if current.Status != domain.AppointmentScheduled {
+					return fmt.Errorf("%w: only scheduled appointments can change artist", domain.ErrFailedPrecondition)
+				}
+				if appointment.ArtistID != "" {
+					if err := ensureArtist(ctx, tx, current.StudioID, appointment.ArtistID); err != nil {
+						return err
+					}
+				}
+				set("artist_id = NULLIF($%d, '')::uuid", appointment.ArtistID)
+				// A moved booking is checked for overlaps again
+				setClauses = append(setClauses, "legacy_conflict = false")
+			default:
+				return fmt.Errorf("%w: unknown field in update_mask: %s", domain.ErrInvalidArgument, path)
+			}
+		}
+		set("updated_at = $%d", time.Now())
+		args = append(args, id)
+
+		// A no-show loses a paid deposit
+		if forfeit {
+			if _, err := payment.ForfeitDepositTx(ctx, tx, id, ""); err != nil && !errors.Is(err, domain.ErrFailedPrecondition) {
+				return err
+			}
+		}
+
+		updated, err = scanAppointment(tx.QueryRow(ctx,
+			"UPDATE appointments SET "+strings.Join(setClauses, ", ")+
+				fmt.Sprintf(" WHERE id = $%d RETURNING ", len(args))+appointmentColumns,
+			args...))
+		if err != nil {
+			return domain.WrapError("failed to update appointment", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	reminder.MarkDue(ctx, r.RedisManager, tenant)
+	return updated, nil
+}
+
+// Reschedule moves a scheduled appointment to a new time and, when artistID is
+// not empty, to another artist
+func (r *AppointmentRepository) Reschedule(ctx context.Context, tenant, id string, start, end time.Time, artistID string) (*domain.Appointment, error) {
+	if err := validateRange(start, end); err != nil {
+		return nil, err
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var updated *domain.Appointment
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		current, err := lockAppointment(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if current.Status != domain.AppointmentScheduled {
+			return fmt.Errorf("%w: appointment is %s and cannot be rescheduled", domain.ErrFailedPrecondition, current.Status)
+		}
+		if artistID != "" && artistID != current.ArtistID {
+			if err := ensureArtist(ctx, tx, current.StudioID, artistID); err != nil {
+				return err
+			}
+		}
+
+		updated, err = scanAppointment(tx.QueryRow(ctx,
+			`UPDATE appointments SET start_time = $1, end_time = $2,
+				artist_id = COALESCE(NULLIF($3, '')::uuid, artist_id), legacy_conflict = false, updated_at = $4
+			 WHERE id = $5 RETURNING `+appointmentColumns,
+			start, end, artistID, time.Now(), id))
+		if err != nil {
+			return domain.WrapError("failed to reschedule appointment", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	reminder.MarkDue(ctx, r.RedisManager, tenant)
+	return updated, nil
+}
+
+// SetStatus moves an appointment to status, enforcing the allowed transitions
+func (r *AppointmentRepository) SetStatus(ctx context.Context, tenant, id, status string) (*domain.Appointment, error) {
+	mask := &fieldmaskpb.FieldMask{Paths: []string{"status"}}
+	return r.Update(ctx, tenant, id, &domain.Appointment{Status: status}, mask)
+}
+
+// insertAppointment books a SCHEDULED appointment inside tx and sets the new
+// ID, status and timestamps on appointment
+func insertAppointment(ctx context.Context, tx pgx.Tx, appointment *domain.Appointment) error {
+	row := tx.QueryRow(ctx,
+		`INSERT INTO appointments (studio_id, customers_id, artist_id, start_time, end_time, status, notes,
+			project_id, session_number)
+		 VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6, NULLIF($7, ''), NULLIF($8, '')::uuid, NULLIF($9, 0))
+		 RETURNING `+appointmentColumns,
+		appointment.StudioID, appointment.CustomerID, appointment.ArtistID,
+		appointment.StartTime, appointment.EndTime, domain.AppointmentScheduled, appointment.Notes,
+		appointment.ProjectID, appointment.SessionNumber)
+	created, err := scanAppointment(row)
+	if err != nil {
+		return domain.WrapError("failed to create appointment", err)
+	}
+	*appointment = *created
+	return nil
+}
+
+// resolveStudio returns studioID, or the tenant's own studio when it is empty
+func resolveStudio(ctx context.Context, tx pgx.Tx, tenant, studioID string) (string, error) {
+	if studioID != "" {
+		return studioID, nil
+	}
+	err := tx.QueryRow(ctx, "SELECT id FROM studios WHERE subdomain = $1", tenant).Scan(&studioID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("studio: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return "", domain.WrapError("failed to find studio", err)
+	}
+	return studioID, nil
+}
+
+func lockAppointment(ctx context.Context, tx pgx.Tx, id string) (*domain.Appointment, error) {
+	appointment, err := scanAppointment(tx.QueryRow(ctx,
+		"SELECT "+appointmentColumns+" FROM appointments WHERE id = $1 FOR UPDATE", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("appointment %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get appointment", err)
+	}
+	return appointment, nil
+}
+
+// ensureArtist fails unless userID is on the staff of the studio
+func ensureArtist(ctx context.Context, tx pgx.Tx, studioID, userID string) error {
+	var exists bool
+	err := tx.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM studio_staff WHERE studio_id = $1 AND user_id = $2)",
+		studioID, userID).Scan(&exists)
+	if err != nil {
+		return domain.WrapError("failed to check artist", err)
+	}
+	if !exists {
+		return fmt.Errorf("artist %s is not on the studio staff: %w", userID, domain.ErrNotFound)
+	}
+	return nil
+}
+
+func validateRange(start, end time.Time) error {
+	if start.IsZero() || end.IsZero() {
+		return fmt.Errorf("%w: start and end time are required", domain.ErrInvalidArgument)
+	}
+	if !end.After(start) {
+		return fmt.Errorf("%w: end time must be after start time", domain.ErrInvalidArgument)
+	}
+	return nil
+}
+
+func scanAppointment(row pgx.Row) (*domain.Appointment, error) {
+	var appointment domain.Appointment
+	err := row.Scan(&appointment.ID, &appointment.StudioID, &appointment.CustomerID, &appointment.ArtistID,
+		&appointment.StartTime, &appointment.EndTime, &appointment.Status, &appointment.Notes,
+		&appointment.CreatedAt, &appointment.UpdatedAt,
+		&appointment.DepositAmount, &appointment.DepositCurrency, &appointment.DepositStatus,
+		&appointment.ProjectID, &appointment.SessionNumber)
+	if err != nil {
+		return nil, err
+	}
+	appointment.Status = strings.ToUpper(appointment.Status)
+	return &appointment, nil
+}
@@ -0,0 +1,608 @@
+package appointment
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	upa "github.com/FACorreiaa/ink-app-backend-protos/modules/appointment/generated"
+)
+
+// Filter metadata of ListAppointments. From and To are RFC 3339 timestamps
+// and select the appointments overlapping that range.
+const (
+	ArtistHeader   = "x-artist-id"
+	CustomerHeader = "x-customer-id"
+	StatusHeader   = "x-status"
+	ProjectHeader  = "x-project-id"
+	FromHeader     = "x-from"
+	ToHeader       = "x-to"
+
+	// DurationHeader sets the slot length of ListAvailableTimeSlots as a Go
+	// duration, e.g. "90m"
+	DurationHeader = "x-duration"
+
+	// Deposit response headers of GetAppointment; the amount is in minor units
+	DepositStatusHeader   = "x-deposit-status"
+	DepositAmountHeader   = "x-deposit-amount"
+	DepositCurrencyHeader = "x-deposit-currency"
+
+	// SessionHeader is the session number GetAppointment returns next to
+	// x-project-id for sessions of a project
+	SessionHeader = "x-session-number"
+)
+
+// managerRoles may manage every artist's schedule
+var managerRoles = []string{"OWNER"}
+
+type AppointmentService struct {
+	upa.UnimplementedAppointmentServiceServer
+	repo         domain.AppointmentRepository
+	availability domain.AvailabilityRepository
+	bookings     *BookingService
+	reminders    domain.ReminderRepository
+}
+
+func NewAppointmentService(repo domain.AppointmentRepository, availability domain.AvailabilityRepository, bookings *BookingService, reminders domain.ReminderRepository) *AppointmentService {
+	return &AppointmentService{repo: repo, availability: availability, bookings: bookings, reminders: reminders}
+}
+
+// CreateAppointment books a SCHEDULED appointment. A double booking of the
+// artist fails with AlreadyExists.
+func (s *AppointmentService) CreateAppointment(ctx context.Context, req *upa.CreateAppointmentRequest) (*upa.CreateAppointmentResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "CreateAppointment")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if req.Appointment == nil {
+		return nil, status.Error(codes.InvalidArgument, "appointment is required")
+	}
+	appointment, err := appointmentFromProto(req.Appointment)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.repo.Create(ctx, tenant, appointment); err != nil {
+		return nil, domain.ToStatus(err, "failed to create appointment")
+	}
+
+	span.SetAttributes(attribute.String("appointment.id", appointment.ID))
+
+	return &upa.CreateAppointmentResponse{
+		Success:     true,
+		Message:     "Appointment created successfully",
+		Appointment: appointmentToProto(appointment),
+		Response:    res,
+	}, nil
+}
+
+func (s *AppointmentService) GetAppointment(ctx context.Context, req *upa.GetAppointmentRequest) (*upa.GetAppointmentResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetAppointment")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if req.AppointmentId == "" {
+		return nil, status.Error(codes.InvalidArgument, "appointment_id is required")
+	}
+
+	appointment, err := s.repo.GetByID(ctx, tenant, req.AppointmentId)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get appointment")
+	}
+	setDepositHeaders(ctx, appointment)
+	setProjectHeaders(ctx, appointment)
+
+	return &upa.GetAppointmentResponse{
+		Success:     true,
+		Message:     "Appointment retrieved successfully",
+		Appointment: appointmentToProto(appointment),
+		CreatedAt:   timestamppb.New(appointment.CreatedAt),
+		UpdatedAt:   timestamppb.New(appointment.UpdatedAt),
+		Response:    res,
+	}, nil
+}
+
+// ListAppointments pages through a studio's appointments by start time. The
+// x-artist-id, x-customer-id, x-project-id, x-status, x-from and x-to headers
+// narrow the list.
+func (s *AppointmentService) ListAppointments(ctx context.Context, req *upa.ListAppointmentsRequest) (*upa.ListAppointmentsResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ListAppointments")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	page, pageSize, err := domain.PageFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := domain.AppointmentFilter{
+		StudioID:   req.StudioId,
+		ArtistID:   domain.MetadataValue(ctx, ArtistHeader),
+		CustomerID: domain.MetadataValue(ctx, CustomerHeader),
+		ProjectID:  domain.MetadataValue(ctx, ProjectHeader),
+		Status:     domain.MetadataValue(ctx, StatusHeader),
+		Page:       page,
+		PageSize:   pageSize,
+	}
+	if filter.From, err = timeHeader(ctx, FromHeader); err != nil {
+		return nil, err
+	}
+	if filter.To, err = timeHeader(ctx, ToHeader); err != nil {
+		return nil, err
+	}
+
+	result, err := s.repo.List(ctx, tenant, filter)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list appointments")
+	}
+	domain.SetTotalCount(ctx, result.TotalCount)
+
+	appointments := make([]*upa.Appointment, 0, len(result.Items))
+	for i := range result.Items {
+		appointments = append(appointments, appointmentToProto(&result.Items[i]))
+	}
+
+	span.SetAttributes(attribute.Int("appointments.count", len(appointments)))
+
+	return &upa.ListAppointmentsResponse{
+		Appointments: appointments,
+		Response:     res,
+	}, nil
+}
+
+// UpdateAppointment changes the notes, artist or status of an appointment.
+// Setting status to COMPLETED or NO_SHOW closes a scheduled appointment.
+func (s *AppointmentService) UpdateAppointment(ctx context.Context, req *upa.UpdateAppointmentRequest) (*upa.UpdateAppointmentResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "UpdateAppointment")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if req.AppointmentId == "" {
+		return nil, status.Error(codes.InvalidArgument, "appointment_id is required")
+	}
+	if req.Appointment == nil {
+		return nil, status.Error(codes.InvalidArgument, "appointment is required")
+	}
+
+	appointment, err := s.repo.Update(ctx, tenant, req.AppointmentId, &domain.Appointment{
+		ArtistID: req.Appointment.ArtistId,
+		Status:   req.Appointment.Status,
+		Notes:    req.Appointment.Notes,
+	}, req.UpdateMask)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to update appointment")
+	}
+
+	span.SetAttributes(attribute.String("appointment.id", appointment.ID))
+
+	return &upa.UpdateAppointmentResponse{
+		Success:     true,
+		Message:     "Appointment updated successfully",
+		Appointment: appointmentToProto(appointment),
+		Response:    res,
+	}, nil
+}
+
+func (s *AppointmentService) CancelAppointment(ctx context.Context, req *upa.CancelAppointmentRequest) (*upa.CancelAppointmentResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "CancelAppointment")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if req.AppointmentId == "" {
+		return nil, status.Error(codes.InvalidArgument, "appointment_id is required")
+	}
+
+	if _, err = s.repo.SetStatus(ctx, tenant, req.AppointmentId, domain.AppointmentCanceled); err != nil {
+		return nil, domain.ToStatus(err, "failed to cancel appointment")
+	}
+
+	span.SetAttributes(attribute.String("appointment.id", req.AppointmentId))
+
+	return &upa.CancelAppointmentResponse{
+		Message:  "Appointment canceled successfully",
+		Response: res,
+	}, nil
+}
+
+// SendAppointmentReminder queues a reminder to the client that the reminder
+// scheduler sends on its next scan
+func (s *AppointmentService) SendAppointmentReminder(ctx context.Context, req *upa.SendAppointmentReminderRequest) (*upa.SendAppointmentReminderResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "SendAppointmentReminder")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if req.AppointmentId == "" {
+		return nil, status.Error(codes.InvalidArgument, "appointment_id is required")
+	}
+
+	reminder, err := s.reminders.EnqueueNow(ctx, tenant, req.AppointmentId)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to send appointment reminder")
+	}
+
+	span.SetAttributes(
+		attribute.String("appointment.id", req.AppointmentId),
+		attribute.String("reminder.id", reminder.ID),
+	)
+
+	return &upa.SendAppointmentReminderResponse{
+		Message:  "Appointment reminder queued successfully",
+		Response: res,
+	}, nil
+}
+
+// RescheduleAppointment moves a scheduled appointment to new RFC 3339 start
+// and end times, optionally with another artist
+func (s *AppointmentService) RescheduleAppointment(ctx context.Context, req *upa.RescheduleAppointmentRequest) (*upa.RescheduleAppointmentResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "RescheduleAppointment")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if req.AppointmentId == "" {
+		return nil, status.Error(codes.InvalidArgument, "appointment_id is required")
+	}
+	start, err := parseTime("new_start_time", req.NewStartTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTime("new_end_time", req.NewEndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.repo.Reschedule(ctx, tenant, req.AppointmentId, start, end, req.NewArtistId); err != nil {
+		return nil, domain.ToStatus(err, "failed to reschedule appointment")
+	}
+
+	span.SetAttributes(attribute.String("appointment.id", req.AppointmentId))
+
+	return &upa.RescheduleAppointmentResponse{
+		Message:  "Appointment rescheduled successfully",
+		Response: res,
+	}, nil
+}
+
+// RequestAppointment asks the artist for a slot on the client's behalf. The
+// notes describe the design; placement, size and reference images are set
+// through BookingService.CreateBookingRequest.
+func (s *AppointmentService) RequestAppointment(ctx context.Context, req *upa.RequestAppointmentRequest) (*upa.RequestAppointmentResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "RequestAppointment")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if req.Appointment == nil {
+		return nil, status.Error(codes.InvalidArgument, "appointment is required")
+	}
+	appointment, err := appointmentFromProto(req.Appointment)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := s.bookings.create(ctx, tenant, &domain.BookingRequest{
+		StudioID:       appointment.StudioID,
+		CustomerID:     appointment.CustomerID,
+		ArtistID:       appointment.ArtistID,
+		RequestedStart: appointment.StartTime,
+		RequestedEnd:   appointment.EndTime,
+		Description:    appointment.Notes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.String("booking_request.id", request.ID))
+
+	return &upa.RequestAppointmentResponse{
+		Message:  fmt.Sprintf("Appointment request %s created successfully", request.ID),
+		Response: res,
+	}, nil
+}
+
+// ApproveAppointmentRequest books the slot of the pending booking request
+// whose id is given as appointment_id
+func (s *AppointmentService) ApproveAppointmentRequest(ctx context.Context, req *upa.ApproveAppointmentRequestRequest) (*upa.ApproveAppointmentRequestResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ApproveAppointmentRequest")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	_, appointment, err := s.bookings.approve(ctx, tenant, req.AppointmentId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.String("appointment.id", appointment.ID))
+
+	return &upa.ApproveAppointmentRequestResponse{
+		Message:  fmt.Sprintf("Appointment request approved as appointment %s", appointment.ID),
+		Response: res,
+	}, nil
+}
+
+// RejectAppointmentRequest declines the booking request whose id is given as
+// appointment_id
+func (s *AppointmentService) RejectAppointmentRequest(ctx context.Context, req *upa.RejectAppointmentRequestRequest) (*upa.RejectAppointmentRequestResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "RejectAppointmentRequest")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if _, err = s.bookings.decline(ctx, tenant, req.AppointmentId, ""); err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.String("booking_request.id", req.AppointmentId))
+
+	return &upa.RejectAppointmentRequestResponse{
+		Message:  "Appointment request rejected successfully",
+		Response: res,
+	}, nil
+}
+
+// SetAvailability replaces the weekly working hours of an artist. Every entry
+// of available_times is a weekday and a range, e.g. "monday 10:00-18:00".
+// Artists manage their own hours; owners and admins manage everyone's.
+func (s *AppointmentService) SetAvailability(ctx context.Context, req *upa.SetAvailabilityRequest) (*upa.SetAvailabilityResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "SetAvailability")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if req.ArtistId == "" {
+		return nil, status.Error(codes.InvalidArgument, "artist_id is required")
+	}
+	if err = requireArtistOrManager(ctx, req.ArtistId); err != nil {
+		return nil, err
+	}
+
+	hours := make([]domain.WorkingHours, 0, len(req.AvailableTimes))
+	for _, entry := range req.AvailableTimes {
+		h, err := ParseWorkingHours(entry)
+		if err != nil {
+			return nil, domain.ToStatus(err, "invalid available_times")
+		}
+		hours = append(hours, h)
+	}
+
+	if err = s.availability.SetWorkingHours(ctx, tenant, req.ArtistId, hours); err != nil {
+		return nil, domain.ToStatus(err, "failed to set availability")
+	}
+
+	span.SetAttributes(attribute.String("artist.id", req.ArtistId))
+
+	return &upa.SetAvailabilityResponse{
+		Message:  "Availability updated successfully",
+		Response: res,
+	}, nil
+}
+
+// GetAvailability returns the weekly working hours of an artist in the format
+// accepted by SetAvailability
+func (s *AppointmentService) GetAvailability(ctx context.Context, req *upa.GetAvailabilityRequest) (*upa.GetAvailabilityResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetAvailability")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if req.ArtistId == "" {
+		return nil, status.Error(codes.InvalidArgument, "artist_id is required")
+	}
+
+	hours, err := s.availability.GetWorkingHours(ctx, tenant, req.ArtistId)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get availability")
+	}
+
+	times := make([]string, 0, len(hours))
+	for _, h := range hours {
+		times = append(times, FormatWorkingHours(h))
+	}
+
+	return &upa.GetAvailabilityResponse{
+		AvailableTimes: times,
+		Response:       res,
+	}, nil
+}
+
+// ListAvailableTimeSlots returns the RFC 3339 start times of the free slots of
+// an artist on a date of the studio's calendar. Slots last an hour unless the
+// x-duration header asks for another length.
+func (s *AppointmentService) ListAvailableTimeSlots(ctx context.Context, req *upa.ListAvailableTimeSlotsRequest) (*upa.ListAvailableTimeSlotsResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ListAvailableTimeSlots")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if req.ArtistId == "" {
+		return nil, status.Error(codes.InvalidArgument, "artist_id is required")
+	}
+	date, err := time.Parse(time.DateOnly, req.Date)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "date must be YYYY-MM-DD")
+	}
+	duration := time.Hour
+	if value := domain.MetadataValue(ctx, DurationHeader); value != "" {
+		if duration, err = time.ParseDuration(value); err != nil || duration <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid %s: %s", DurationHeader, value)
+		}
+	}
+
+	slots, err := s.findDaySlots(ctx, tenant, req.ArtistId, date, duration)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list available time slots")
+	}
+
+	times := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		times = append(times, slot.Start.Format(time.RFC3339))
+	}
+
+	span.SetAttributes(
+		attribute.String("artist.id", req.ArtistId),
+		attribute.Int("slots.count", len(times)),
+	)
+
+	return &upa.ListAvailableTimeSlotsResponse{
+		AvailableTimes: times,
+		Response:       res,
+	}, nil
+}
+
+// findDaySlots searches the slots of one calendar day in the studio's time
+// zone. The schedule is loaded for a day either side because the zone is only
+// known once it is loaded.
+func (s *AppointmentService) findDaySlots(ctx context.Context, tenant, artistID string, date time.Time, duration time.Duration) ([]domain.TimeRange, error) {
+	schedule, err := s.availability.GetSchedule(ctx, tenant, artistID, date.AddDate(0, 0, -1), date.AddDate(0, 0, 2))
+	if err != nil {
+		return nil, err
+	}
+	loc, err := loadLocation(schedule.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	from := wallClock(date, 0, loc)
+	to := wallClock(date.AddDate(0, 0, 1), 0, loc)
+	return FindSlots(schedule, from, to, duration, DefaultSlotStep)
+}
+
+// requireArtistOrManager lets artists act on their own schedule and managers on
+// everyone's
+func requireArtistOrManager(ctx context.Context, artistID string) error {
+	if userID, err := domain.ExtractUserIDFromContext(ctx); err == nil && userID == artistID {
+		return nil
+	}
+	return domain.RequireRole(ctx, managerRoles...)
+}
+
+func appointmentFromProto(protoAppointment *upa.Appointment) (*domain.Appointment, error) {
+	start, err := parseTime("start_time", protoAppointment.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTime("end_time", protoAppointment.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	if protoAppointment.ClientId == "" {
+		return nil, status.Error(codes.InvalidArgument, "client_id is required")
+	}
+
+	return &domain.Appointment{
+		StudioID:   protoAppointment.StudioId,
+		CustomerID: protoAppointment.ClientId,
+		ArtistID:   protoAppointment.ArtistId,
+		StartTime:  start,
+		EndTime:    end,
+		Notes:      protoAppointment.Notes,
+	}, nil
+}
+
+func appointmentToProto(appointment *domain.Appointment) *upa.Appointment {
+	return &upa.Appointment{
+		Id:        appointment.ID,
+		StudioId:  appointment.StudioID,
+		ClientId:  appointment.CustomerID,
+		ArtistId:  appointment.ArtistID,
+		StartTime: appointment.StartTime.Format(time.RFC3339),
+		EndTime:   appointment.EndTime.Format(time.RFC3339),
+		Status:    appointment.Status,
+		Notes:     appointment.Notes,
+	}
+}
+
+func parseTime(field, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "%s is required", field)
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "%s must be an RFC 3339 timestamp: %v", field, err)
+	}
+	return t, nil
+}
+
+// setDepositHeaders reports the deposit of an appointment, which the
+// appointment protos cannot carry, in the x-deposit-* response headers
+func setDepositHeaders(ctx context.Context, appointment *domain.Appointment) {
+	_ = grpc.SetHeader(ctx, metadata.Pairs(
+		DepositStatusHeader, appointment.DepositStatus,
+		DepositAmountHeader, strconv.FormatInt(appointment.DepositAmount, 10),
+		DepositCurrencyHeader, appointment.DepositCurrency,
+	))
+}
+
+func setProjectHeaders(ctx context.Context, appointment *domain.Appointment) {
+	if appointment.ProjectID == "" {
+		return
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(
+		ProjectHeader, appointment.ProjectID,
+		SessionHeader, strconv.Itoa(appointment.SessionNumber),
+	))
+}
+
+// timeHeader parses an optional RFC 3339 timestamp from the incoming metadata
+func timeHeader(ctx context.Context, key string) (*time.Time, error) {
+	value := domain.MetadataValue(ctx, key)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := parseTime(key, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// baseResponse is the response header of a successful call started by
+// domain.StartCall
+func baseResponse(ctx context.Context) *upa.BaseResponse {
+	return &upa.BaseResponse{
+		Success:   true,
+		RequestId: domain.RequestIDFromContext(ctx),
+		TraceId:   domain.TraceIDFromContext(ctx),
+	}
+}
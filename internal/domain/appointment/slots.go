@@ -0,0 +1,341 @@
+package appointment
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// DefaultSlotStep is the distance between the start times of two candidate
+// slots when the caller does not pick one
+const DefaultSlotStep = 30 * time.Minute
+
+// maxSlotSearch bounds the period a single slot search may cover
+const maxSlotSearch = 62 * 24 * time.Hour
+
+// clockRange is a wall-clock interval in minutes since local midnight
+type clockRange struct {
+	start, end int
+}
+
+// FindSlots returns the free slots of the given duration inside [from, to).
+// Working hours, overrides and business hours are wall-clock times in the
+// schedule's time zone, so a shift keeps its local hours across daylight-saving
+// changes and is simply shorter or longer on those days. Candidate slots start
+// at the beginning of every free period and then every step of elapsed time.
+func FindSlots(schedule *domain.ArtistSchedule, from, to time.Time, duration, step time.Duration) ([]domain.TimeRange, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("%w: duration must be positive", domain.ErrInvalidArgument)
+	}
+	if step <= 0 {
+		step = DefaultSlotStep
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: end of the search must be after its start", domain.ErrInvalidArgument)
+	}
+	if to.Sub(from) > maxSlotSearch {
+		return nil, fmt.Errorf("%w: slot search cannot cover more than %d days", domain.ErrInvalidArgument, int(maxSlotSearch.Hours()/24))
+	}
+
+	loc, err := loadLocation(schedule.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	from, to = from.In(loc), to.In(loc)
+	weekly, err := groupByWeekday(schedule.WorkingHours)
+	if err != nil {
+		return nil, err
+	}
+	var business map[time.Weekday][]clockRange
+	if schedule.BusinessHours != nil {
+		if business, err = groupByWeekday(schedule.BusinessHours); err != nil {
+			return nil, err
+		}
+	}
+	overrides := make(map[string][]clockRange, len(schedule.Overrides))
+	for _, override := range schedule.Overrides {
+		var ranges []clockRange
+		if override.Start != "" || override.End != "" {
+			r, err := parseClockRange(override.Start, override.End)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, r)
+		}
+		overrides[override.Date] = append(overrides[override.Date], ranges...)
+	}
+
+	busy := make([]domain.TimeRange, 0, len(schedule.Appointments)+len(schedule.TimeOff)+len(schedule.Busy))
+	for _, appointment := range schedule.Appointments {
+		busy = append(busy, domain.TimeRange{
+			Start: appointment.Start.Add(-schedule.Buffer),
+			End:   appointment.End.Add(schedule.Buffer),
+		})
+	}
+	for _, timeOff := range schedule.TimeOff {
+		busy = append(busy, domain.TimeRange{Start: timeOff.Start, End: timeOff.End})
+	}
+	busy = append(busy, schedule.Busy...)
+	busy = mergeRanges(busy)
+
+	slots := []domain.TimeRange{}
+	year, month, day := from.In(loc).Date()
+	for i := 0; ; i++ {
+		// Calendar arithmetic in UTC keeps days whose local midnight does
+		// not exist from being skipped or repeated
+		date := time.Date(year, month, day+i, 12, 0, 0, 0, time.UTC)
+		if !wallClock(date, 0, loc).Before(to) {
+			break
+		}
+
+		ranges := weekly[date.Weekday()]
+		if override, ok := overrides[date.Format(time.DateOnly)]; ok {
+			ranges = normalizeClock(override)
+		}
+		if business != nil {
+			ranges = intersectClock(ranges, business[date.Weekday()])
+		}
+
+		for _, r := range ranges {
+			window := domain.TimeRange{Start: wallClock(date, r.start, loc), End: wallClock(date, r.end, loc)}
+			if window.Start.Before(from) {
+				window.Start = from
+			}
+			if window.End.After(to) {
+				window.End = to
+			}
+			for _, free := range subtractRanges(window, busy) {
+				for start := free.Start; !start.Add(duration).After(free.End); start = start.Add(step) {
+					slots = append(slots, domain.TimeRange{Start: start, End: start.Add(duration)})
+				}
+			}
+		}
+	}
+	return slots, nil
+}
+
+// ParseClock parses an "HH:MM" wall-clock time into minutes since midnight.
+// "24:00" is accepted as the end of the day.
+func ParseClock(value string) (int, error) {
+	hours, minutes, ok := strings.Cut(strings.TrimSpace(value), ":")
+	h, errH := strconv.Atoi(hours)
+	m, errM := strconv.Atoi(minutes)
+	if !ok || errH != nil || errM != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("%w: invalid time of day %q, expected HH:MM", domain.ErrInvalidArgument, value)
+	}
+	return h*60 + m, nil
+}
+
+// ValidateWorkingHours checks that every shift is a non-empty HH:MM range
+func ValidateWorkingHours(hours []domain.WorkingHours) error {
+	_, err := groupByWeekday(hours)
+	return err
+}
+
+// ParseWorkingHours parses a weekly shift written as "monday 10:00-18:00".
+// Weekdays may be abbreviated to their first three letters.
+func ParseWorkingHours(value string) (domain.WorkingHours, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return domain.WorkingHours{}, fmt.Errorf("%w: %q is not \"<weekday> HH:MM-HH:MM\"", domain.ErrInvalidArgument, value)
+	}
+	weekday, ok := parseWeekday(fields[0])
+	if !ok {
+		return domain.WorkingHours{}, fmt.Errorf("%w: unknown weekday %q", domain.ErrInvalidArgument, fields[0])
+	}
+	start, end, ok := strings.Cut(fields[1], "-")
+	if !ok {
+		return domain.WorkingHours{}, fmt.Errorf("%w: %q is not \"<weekday> HH:MM-HH:MM\"", domain.ErrInvalidArgument, value)
+	}
+	if _, err := parseClockRange(start, end); err != nil {
+		return domain.WorkingHours{}, err
+	}
+	return domain.WorkingHours{Weekday: weekday, Start: start, End: end}, nil
+}
+
+// FormatWorkingHours is the inverse of ParseWorkingHours
+func FormatWorkingHours(h domain.WorkingHours) string {
+	return fmt.Sprintf("%s %s-%s", strings.ToLower(h.Weekday.String()), h.Start, h.End)
+}
+
+func parseWeekday(value string) (time.Weekday, bool) {
+	value = strings.ToLower(value)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := strings.ToLower(weekday.String())
+		if value == name || value == name[:3] {
+			return weekday, true
+		}
+	}
+	return 0, false
+}
+
+func parseClockRange(start, end string) (clockRange, error) {
+	s, err := ParseClock(start)
+	if err != nil {
+		return clockRange{}, err
+	}
+	e, err := ParseClock(end)
+	if err != nil {
+		return clockRange{}, err
+	}
+	if e <= s {
+		return clockRange{}, fmt.Errorf("%w: %s-%s ends before it starts", domain.ErrInvalidArgument, start, end)
+	}
+	return clockRange{start: s, end: e}, nil
+}
+
+func groupByWeekday(hours []domain.WorkingHours) (map[time.Weekday][]clockRange, error) {
+	grouped := make(map[time.Weekday][]clockRange)
+	for _, h := range hours {
+		if h.Weekday < time.Sunday || h.Weekday > time.Saturday {
+			return nil, fmt.Errorf("%w: invalid weekday %d", domain.ErrInvalidArgument, h.Weekday)
+		}
+		r, err := parseClockRange(h.Start, h.End)
+		if err != nil {
+			return nil, err
+		}
+		grouped[h.Weekday] = append(grouped[h.Weekday], r)
+	}
+	for weekday, ranges := range grouped {
+		grouped[weekday] = normalizeClock(ranges)
+	}
+	return grouped, nil
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown time zone %q", domain.ErrInvalidArgument, name)
+	}
+	return loc, nil
+}
+
+// wallClock returns the instant of a wall-clock time on date in loc.
+// time.Date leaves the choice open for times that do not exist or occur twice
+// around a daylight-saving change, so they are resolved here: a time that
+// occurs twice maps to its first occurrence and a time skipped by the clocks
+// going forward maps to the moment they went forward.
+func wallClock(date time.Time, minutes int, loc *time.Location) time.Time {
+	naive := time.Date(date.Year(), date.Month(), date.Day(), minutes/60, minutes%60, 0, 0, time.UTC)
+
+	// The offsets in force a day before and a day after cover any single
+	// transition around the wall time
+	_, before := naive.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := naive.Add(24 * time.Hour).In(loc).Zone()
+
+	var first time.Time
+	for _, offset := range []int{before, after} {
+		t := naive.Add(-time.Duration(offset) * time.Second)
+		if sameWallClock(t.In(loc), naive) && (first.IsZero() || t.Before(first)) {
+			first = t
+		}
+	}
+	if !first.IsZero() {
+		return first.In(loc)
+	}
+
+	// The wall time falls in a gap: search the transition between the last
+	// instant on the old offset and the first instant on the new one
+	lo := naive.Add(-time.Duration(after) * time.Second)
+	hi := naive.Add(-time.Duration(before) * time.Second)
+	for hi.Sub(lo) > time.Second {
+		mid := lo.Add(hi.Sub(lo) / 2)
+		if _, offset := mid.In(loc).Zone(); offset == before {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi.Truncate(time.Second).In(loc)
+}
+
+func sameWallClock(t, naive time.Time) bool {
+	y1, m1, d1 := t.Date()
+	y2, m2, d2 := naive.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2 && t.Hour() == naive.Hour() && t.Minute() == naive.Minute()
+}
+
+// normalizeClock sorts ranges and merges the ones that overlap or touch
+func normalizeClock(ranges []clockRange) []clockRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sorted := append([]clockRange(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].start < sorted[j].start })
+
+	merged := sorted[:1]
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if r.start <= last.end {
+			last.end = max(last.end, r.end)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// intersectClock returns the parts of a that are also covered by b; both must
+// be normalized
+func intersectClock(a, b []clockRange) []clockRange {
+	var out []clockRange
+	for _, x := range a {
+		for _, y := range b {
+			start, end := max(x.start, y.start), min(x.end, y.end)
+			if start < end {
+				out = append(out, clockRange{start: start, end: end})
+			}
+		}
+	}
+	return out
+}
+
+// mergeRanges sorts ranges and merges the ones that overlap or touch
+func mergeRanges(ranges []domain.TimeRange) []domain.TimeRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start.Before(ranges[j].Start) })
+
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if !r.Start.After(last.End) {
+			if r.End.After(last.End) {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// subtractRanges removes the merged busy ranges from window
+func subtractRanges(window domain.TimeRange, busy []domain.TimeRange) []domain.TimeRange {
+	var free []domain.TimeRange
+	start := window.Start
+	for _, b := range busy {
+		if !b.End.After(start) {
+			continue
+		}
+		if !b.Start.Before(window.End) {
+			break
+		}
+		if b.Start.After(start) {
+			free = append(free, domain.TimeRange{Start: start, End: b.Start})
+		}
+		start = b.End
+	}
+	if start.Before(window.End) {
+		free = append(free, domain.TimeRange{Start: start, End: window.End})
+	}
+	return free
+}
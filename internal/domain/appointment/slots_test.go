@@ -0,0 +1,288 @@
+package appointment
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+func loadZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+// localStarts formats the slot start times as wall-clock times with their zone
+func localStarts(slots []domain.TimeRange, loc *time.Location) []string {
+	starts := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		starts = append(starts, slot.Start.In(loc).Format("2006-01-02 15:04 MST"))
+	}
+	return starts
+}
+
+func assertStarts(t *testing.T, got []domain.TimeRange, loc *time.Location, want ...string) {
+	t.Helper()
+	starts := localStarts(got, loc)
+	if len(starts) != len(want) {
+		t.Fatalf("got %d slots %v, want %d %v", len(starts), starts, len(want), want)
+	}
+	for i := range want {
+		if starts[i] != want[i] {
+			t.Errorf("slot %d starts at %s, want %s", i, starts[i], want[i])
+		}
+	}
+}
+
+func TestFindSlotsSubtractsAppointmentsAndBuffer(t *testing.T) {
+	loc := loadZone(t, "Europe/Lisbon")
+	schedule := &domain.ArtistSchedule{
+		TimeZone:     "Europe/Lisbon",
+		Buffer:       15 * time.Minute,
+		WorkingHours: []domain.WorkingHours{{Weekday: time.Monday, Start: "10:00", End: "14:00"}},
+		Appointments: []domain.TimeRange{{
+			Start: time.Date(2025, 1, 13, 11, 0, 0, 0, loc),
+			End:   time.Date(2025, 1, 13, 12, 0, 0, 0, loc),
+		}},
+	}
+
+	slots, err := FindSlots(schedule,
+		time.Date(2025, 1, 13, 0, 0, 0, 0, loc), time.Date(2025, 1, 14, 0, 0, 0, 0, loc),
+		time.Hour, 30*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10:00-10:45 is too short once the buffer is taken before 11:00
+	assertStarts(t, slots, loc, "2025-01-13 12:15 WET", "2025-01-13 12:45 WET")
+	for _, slot := range slots {
+		if slot.End.Sub(slot.Start) != time.Hour {
+			t.Errorf("slot %v lasts %v, want 1h", slot, slot.End.Sub(slot.Start))
+		}
+	}
+}
+
+func TestFindSlotsOverridesAndTimeOff(t *testing.T) {
+	loc := loadZone(t, "Europe/Lisbon")
+	weekdays := []domain.WorkingHours{
+		{Weekday: time.Monday, Start: "10:00", End: "12:00"},
+		{Weekday: time.Tuesday, Start: "10:00", End: "12:00"},
+		{Weekday: time.Wednesday, Start: "10:00", End: "12:00"},
+	}
+	schedule := &domain.ArtistSchedule{
+		TimeZone:     "Europe/Lisbon",
+		WorkingHours: weekdays,
+		Overrides: []domain.ScheduleOverride{
+			{Date: "2025-01-13"}, // Monday off
+			{Date: "2025-01-14", Start: "15:00", End: "16:00"},
+		},
+		TimeOff: []domain.TimeOff{{
+			Start: time.Date(2025, 1, 15, 11, 0, 0, 0, loc),
+			End:   time.Date(2025, 1, 16, 0, 0, 0, 0, loc),
+		}},
+	}
+
+	slots, err := FindSlots(schedule,
+		time.Date(2025, 1, 13, 0, 0, 0, 0, loc), time.Date(2025, 1, 16, 0, 0, 0, 0, loc),
+		time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStarts(t, slots, loc, "2025-01-14 15:00 WET", "2025-01-15 10:00 WET")
+}
+
+func TestFindSlotsBusinessHoursLimitWorkingHours(t *testing.T) {
+	loc := loadZone(t, "Europe/Lisbon")
+	schedule := &domain.ArtistSchedule{
+		TimeZone:      "Europe/Lisbon",
+		WorkingHours:  []domain.WorkingHours{{Weekday: time.Monday, Start: "08:00", End: "20:00"}},
+		BusinessHours: []domain.WorkingHours{{Weekday: time.Monday, Start: "10:00", End: "13:00"}},
+	}
+
+	slots, err := FindSlots(schedule,
+		time.Date(2025, 1, 13, 0, 0, 0, 0, loc), time.Date(2025, 1, 15, 0, 0, 0, 0, loc),
+		time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tuesday has no business hours, so the studio is closed
+	assertStarts(t, slots, loc, "2025-01-13 10:00 WET", "2025-01-13 11:00 WET", "2025-01-13 12:00 WET")
+}
+
+func TestFindSlotsUsesStudioTimeZone(t *testing.T) {
+	loc := loadZone(t, "America/New_York")
+	// 10 March 2025: New York is already on EDT while Europe is still on winter time
+	schedule := &domain.ArtistSchedule{
+		TimeZone:     "America/New_York",
+		WorkingHours: []domain.WorkingHours{{Weekday: time.Monday, Start: "10:00", End: "13:00"}},
+		Appointments: []domain.TimeRange{{
+			Start: time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC),
+			End:   time.Date(2025, 3, 10, 16, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	slots, err := FindSlots(schedule,
+		time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 11, 12, 0, 0, 0, time.UTC),
+		time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStarts(t, slots, loc, "2025-03-10 10:00 EDT", "2025-03-10 12:00 EDT")
+	if want := time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC); !slots[0].Start.Equal(want) {
+		t.Errorf("first slot starts at %v, want %v", slots[0].Start.UTC(), want)
+	}
+}
+
+func TestFindSlotsSpringForward(t *testing.T) {
+	loc := loadZone(t, "Europe/Lisbon")
+	// 30 March 2025: clocks go from 01:00 WET to 02:00 WEST
+	schedule := &domain.ArtistSchedule{
+		TimeZone:     "Europe/Lisbon",
+		WorkingHours: []domain.WorkingHours{{Weekday: time.Sunday, Start: "00:00", End: "06:00"}},
+	}
+
+	slots, err := FindSlots(schedule,
+		time.Date(2025, 3, 30, 0, 0, 0, 0, loc), time.Date(2025, 3, 31, 0, 0, 0, 0, loc),
+		time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The shift only lasts five real hours and the missing hour yields no slot
+	assertStarts(t, slots, loc,
+		"2025-03-30 00:00 WET", "2025-03-30 02:00 WEST", "2025-03-30 03:00 WEST",
+		"2025-03-30 04:00 WEST", "2025-03-30 05:00 WEST")
+}
+
+func TestFindSlotsFallBack(t *testing.T) {
+	loc := loadZone(t, "Europe/Lisbon")
+	// 26 October 2025: clocks go from 02:00 WEST back to 01:00 WET
+	schedule := &domain.ArtistSchedule{
+		TimeZone:     "Europe/Lisbon",
+		WorkingHours: []domain.WorkingHours{{Weekday: time.Sunday, Start: "00:00", End: "06:00"}},
+	}
+
+	slots, err := FindSlots(schedule,
+		time.Date(2025, 10, 26, 0, 0, 0, 0, loc), time.Date(2025, 10, 27, 0, 0, 0, 0, loc),
+		time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The shift lasts seven real hours and 01:00 is offered in both offsets
+	assertStarts(t, slots, loc,
+		"2025-10-26 00:00 WEST", "2025-10-26 01:00 WEST", "2025-10-26 01:00 WET",
+		"2025-10-26 02:00 WET", "2025-10-26 03:00 WET", "2025-10-26 04:00 WET",
+		"2025-10-26 05:00 WET")
+}
+
+func TestFindSlotsShiftStartingInDaylightSavingGap(t *testing.T) {
+	loc := loadZone(t, "America/New_York")
+	// 9 March 2025: 02:00-03:00 does not exist in New York
+	schedule := &domain.ArtistSchedule{
+		TimeZone:     "America/New_York",
+		WorkingHours: []domain.WorkingHours{{Weekday: time.Sunday, Start: "02:30", End: "05:00"}},
+	}
+
+	slots, err := FindSlots(schedule,
+		time.Date(2025, 3, 9, 0, 0, 0, 0, loc), time.Date(2025, 3, 10, 0, 0, 0, 0, loc),
+		time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The shift starts when the clocks jump to 03:00 EDT
+	assertStarts(t, slots, loc, "2025-03-09 03:00 EDT", "2025-03-09 04:00 EDT")
+}
+
+func TestFindSlotsShiftStartingInRepeatedHour(t *testing.T) {
+	loc := loadZone(t, "America/New_York")
+	// 2 November 2025: 01:00-02:00 happens twice in New York
+	schedule := &domain.ArtistSchedule{
+		TimeZone:     "America/New_York",
+		WorkingHours: []domain.WorkingHours{{Weekday: time.Sunday, Start: "01:30", End: "03:00"}},
+	}
+
+	slots, err := FindSlots(schedule,
+		time.Date(2025, 11, 2, 0, 0, 0, 0, loc), time.Date(2025, 11, 3, 0, 0, 0, 0, loc),
+		time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 01:30 is its first occurrence, so the shift lasts two and a half hours
+	assertStarts(t, slots, loc, "2025-11-02 01:30 EDT", "2025-11-02 01:30 EST")
+	if got, want := slots[len(slots)-1].End, time.Date(2025, 11, 2, 7, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("last slot ends at %v, want %v", got.UTC(), want)
+	}
+}
+
+func TestFindSlotsAppointmentAcrossDaylightSavingChange(t *testing.T) {
+	loc := loadZone(t, "Europe/Lisbon")
+	schedule := &domain.ArtistSchedule{
+		TimeZone:     "Europe/Lisbon",
+		WorkingHours: []domain.WorkingHours{{Weekday: time.Sunday, Start: "00:00", End: "04:00"}},
+		// 00:30 WET to 02:30 WEST is one real hour
+		Appointments: []domain.TimeRange{{
+			Start: time.Date(2025, 3, 30, 0, 30, 0, 0, time.UTC),
+			End:   time.Date(2025, 3, 30, 1, 30, 0, 0, time.UTC),
+		}},
+	}
+
+	slots, err := FindSlots(schedule,
+		time.Date(2025, 3, 30, 0, 0, 0, 0, loc), time.Date(2025, 3, 31, 0, 0, 0, 0, loc),
+		30*time.Minute, 30*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStarts(t, slots, loc,
+		"2025-03-30 00:00 WET", "2025-03-30 02:30 WEST", "2025-03-30 03:00 WEST", "2025-03-30 03:30 WEST")
+}
+
+func TestFindSlotsRejectsInvalidInput(t *testing.T) {
+	from := time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		schedule domain.ArtistSchedule
+		to       time.Time
+		duration time.Duration
+	}{
+		{name: "zero duration", to: from.Add(time.Hour)},
+		{name: "empty range", to: from, duration: time.Hour},
+		{name: "range too long", to: from.AddDate(0, 3, 0), duration: time.Hour},
+		{name: "unknown time zone", schedule: domain.ArtistSchedule{TimeZone: "Mars/Olympus"}, to: from.Add(time.Hour), duration: time.Hour},
+		{name: "shift ends before it starts", schedule: domain.ArtistSchedule{
+			WorkingHours: []domain.WorkingHours{{Weekday: time.Monday, Start: "12:00", End: "10:00"}},
+		}, to: from.Add(time.Hour), duration: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FindSlots(&tt.schedule, from, tt.to, tt.duration, time.Hour)
+			if !errors.Is(err, domain.ErrInvalidArgument) {
+				t.Errorf("got %v, want an invalid argument error", err)
+			}
+		})
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{in: "00:00", want: 0},
+		{in: "09:30", want: 570},
+		{in: "24:00", want: 1440},
+		{in: "24:30", wantErr: true},
+		{in: "12:60", wantErr: true},
+		{in: "noon", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseClock(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseClock(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,561 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/reminder"
+)
+
+// WaitlistRepository keeps the waitlist and the offers of freed slots in the
+// tenant's database. A trigger on appointments records the slot of every
+// upcoming appointment that is cancelled or moved as an opening; Backfill
+// offers openings to the waitlist one entry at a time.
+type WaitlistRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewWaitlistRepository creates a new WaitlistRepository
+func NewWaitlistRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *WaitlistRepository {
+	return &WaitlistRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+const waitlistEntryColumns = `id, studio_id, customer_id, artist_id, window_start, window_end, duration_minutes,
+	priority, COALESCE(notes, ''), status, COALESCE(appointment_id::text, ''), created_at, COALESCE(updated_at, created_at)`
+
+const waitlistOfferColumns = `o.id, o.opening_id, o.entry_id, e.customer_id, o.artist_id, o.starts_at, o.ends_at,
+	o.status, o.expires_at, o.responded_at, COALESCE(e.appointment_id::text, ''), o.created_at`
+
+const waitlistOfferFrom = ` FROM waitlist_offers o JOIN waitlist_entries e ON e.id = o.entry_id`
+
+// AddEntry puts a customer on the artist's waitlist. Without a StudioID the
+// entry belongs to the tenant's own studio.
+func (r *WaitlistRepository) AddEntry(ctx context.Context, tenant string, entry *domain.WaitlistEntry) error {
+	if entry == nil {
+		return fmt.Errorf("%w: waitlist entry is required", domain.ErrInvalidArgument)
+	}
+	if entry.CustomerID == "" || entry.ArtistID == "" {
+		return fmt.Errorf("%w: customer and artist are required", domain.ErrInvalidArgument)
+	}
+	if err := validateRange(entry.WindowStart, entry.WindowEnd); err != nil {
+		return err
+	}
+	if !entry.WindowEnd.After(time.Now()) {
+		return fmt.Errorf("%w: the window must not be over", domain.ErrInvalidArgument)
+	}
+	duration := time.Duration(entry.DurationMinutes) * time.Minute
+	if duration <= 0 || duration > entry.WindowEnd.Sub(entry.WindowStart) {
+		return fmt.Errorf("%w: duration must be positive and fit in the window", domain.ErrInvalidArgument)
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		studioID, err := resolveStudio(ctx, tx, tenant, entry.StudioID)
+		if err != nil {
+			return err
+		}
+		if err := ensureArtist(ctx, tx, studioID, entry.ArtistID); err != nil {
+			return err
+		}
+
+		created, err := scanWaitlistEntry(tx.QueryRow(ctx,
+			`INSERT INTO waitlist_entries (studio_id, customer_id, artist_id, window_start, window_end,
+				duration_minutes, priority, notes)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+			 RETURNING `+waitlistEntryColumns,
+			studioID, entry.CustomerID, entry.ArtistID, entry.WindowStart, entry.WindowEnd,
+			entry.DurationMinutes, entry.Priority, entry.Notes))
+		if err != nil {
+			return domain.WrapError("failed to add waitlist entry", err)
+		}
+		*entry = *created
+		return nil
+	})
+}
+
+func (r *WaitlistRepository) GetEntry(ctx context.Context, tenant, id string) (*domain.WaitlistEntry, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	entry, err := scanWaitlistEntry(pool.QueryRow(ctx,
+		"SELECT "+waitlistEntryColumns+" FROM waitlist_entries WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("waitlist entry %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get waitlist entry", err)
+	}
+	return entry, nil
+}
+
+// ListEntries pages through the waitlist in the order slots are offered
+func (r *WaitlistRepository) ListEntries(ctx context.Context, tenant string, filter domain.WaitlistFilter) (domain.PagedResult[domain.WaitlistEntry], error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = domain.DefaultPageSize
+	}
+	result := domain.PagedResult[domain.WaitlistEntry]{Items: []domain.WaitlistEntry{}, Page: filter.Page, PageSize: filter.PageSize}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return result, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	where := []string{"TRUE"}
+	var args []interface{}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if filter.StudioID != "" {
+		add("studio_id = $%d", filter.StudioID)
+	}
+	if filter.ArtistID != "" {
+		add("artist_id = $%d", filter.ArtistID)
+	}
+	if filter.CustomerID != "" {
+		add("customer_id = $%d", filter.CustomerID)
+	}
+	if filter.Status != "" {
+		add("status = upper($%d)", filter.Status)
+	}
+	whereClause := strings.Join(where, " AND ")
+
+	if err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM waitlist_entries WHERE "+whereClause, args...).Scan(&result.TotalCount); err != nil {
+		return result, domain.WrapError("failed to count waitlist entries", err)
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	rows, err := pool.Query(ctx,
+		"SELECT "+waitlistEntryColumns+" FROM waitlist_entries WHERE "+whereClause+
+			fmt.Sprintf(" ORDER BY priority DESC, created_at, id LIMIT $%d OFFSET $%d", len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return result, domain.WrapError("failed to query waitlist entries", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanWaitlistEntry(rows)
+		if err != nil {
+			return result, fmt.Errorf("failed to scan waitlist entry: %w", err)
+		}
+		result.Items = append(result.Items, *entry)
+	}
+	if err = rows.Err(); err != nil {
+		return result, domain.WrapError("failed to query waitlist entries", err)
+	}
+
+	return result, nil
+}
+
+// RemoveEntry takes an entry off the waitlist. Its pending offer is withdrawn,
+// so the slot goes to the next entry on the following backfill.
+func (r *WaitlistRepository) RemoveEntry(ctx context.Context, tenant, id string) (*domain.WaitlistEntry, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var removed *domain.WaitlistEntry
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := lockWaitlistEntry(ctx, tx, id, domain.WaitlistWaiting, domain.WaitlistOffered); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if _, err := tx.Exec(ctx,
+			`UPDATE waitlist_offers SET status = $1, responded_at = $2 WHERE entry_id = $3 AND status = $4`,
+			domain.OfferWithdrawn, now, id, domain.OfferPending); err != nil {
+			return domain.WrapError("failed to withdraw waitlist offer", err)
+		}
+
+		var err error
+		removed, err = scanWaitlistEntry(tx.QueryRow(ctx,
+			`UPDATE waitlist_entries SET status = $1, updated_at = $2 WHERE id = $3 RETURNING `+waitlistEntryColumns,
+			domain.WaitlistRemoved, now, id))
+		if err != nil {
+			return domain.WrapError("failed to remove waitlist entry", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+func (r *WaitlistRepository) GetOffer(ctx context.Context, tenant, id string) (*domain.WaitlistOffer, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	offer, err := scanWaitlistOffer(pool.QueryRow(ctx,
+		"SELECT "+waitlistOfferColumns+waitlistOfferFrom+" WHERE o.id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("waitlist offer %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get waitlist offer", err)
+	}
+	return offer, nil
+}
+
+// ListOffers returns the offers made to an entry, newest first
+func (r *WaitlistRepository) ListOffers(ctx context.Context, tenant, entryID string) ([]domain.WaitlistOffer, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	rows, err := pool.Query(ctx,
+		"SELECT "+waitlistOfferColumns+waitlistOfferFrom+" WHERE o.entry_id = $1 ORDER BY o.created_at DESC", entryID)
+	if err != nil {
+		return nil, domain.WrapError("failed to query waitlist offers", err)
+	}
+	defer rows.Close()
+
+	offers := []domain.WaitlistOffer{}
+	for rows.Next() {
+		offer, err := scanWaitlistOffer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan waitlist offer: %w", err)
+		}
+		offers = append(offers, *offer)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to query waitlist offers", err)
+	}
+	return offers, nil
+}
+
+// AcceptOffer books the offered slot. The offer stops holding the slot before
+// the appointment is inserted, all in one transaction.
+func (r *WaitlistRepository) AcceptOffer(ctx context.Context, tenant, id string) (*domain.WaitlistOffer, *domain.Appointment, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var accepted *domain.WaitlistOffer
+	var appointment *domain.Appointment
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		now := time.Now()
+		offer, err := lockPendingOffer(ctx, tx, id, now)
+		if err != nil {
+			return err
+		}
+		entry, err := lockWaitlistEntry(ctx, tx, offer.EntryID, domain.WaitlistOffered)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx,
+			`UPDATE waitlist_offers SET status = $1, responded_at = $2 WHERE id = $3`,
+			domain.OfferAccepted, now, id); err != nil {
+			return domain.WrapError("failed to accept waitlist offer", err)
+		}
+
+		appointment = &domain.Appointment{
+			StudioID:   entry.StudioID,
+			CustomerID: entry.CustomerID,
+			ArtistID:   offer.ArtistID,
+			StartTime:  offer.Start,
+			EndTime:    offer.End,
+			Notes:      entry.Notes,
+		}
+		if err := insertAppointment(ctx, tx, appointment); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx,
+			`UPDATE waitlist_entries SET status = $1, appointment_id = $2, updated_at = $3 WHERE id = $4`,
+			domain.WaitlistBooked, appointment.ID, now, entry.ID); err != nil {
+			return domain.WrapError("failed to book waitlist entry", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`UPDATE waitlist_openings SET status = 'FILLED', closed_at = $1 WHERE id = $2`,
+			now, offer.OpeningID); err != nil {
+			return domain.WrapError("failed to fill waitlist opening", err)
+		}
+
+		accepted, err = scanWaitlistOffer(tx.QueryRow(ctx,
+			"SELECT "+waitlistOfferColumns+waitlistOfferFrom+" WHERE o.id = $1", id))
+		if err != nil {
+			return domain.WrapError("failed to get waitlist offer", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	reminder.MarkDue(ctx, r.RedisManager, tenant)
+	return accepted, appointment, nil
+}
+
+// DeclineOffer puts the entry back on the waitlist. The slot goes to the next
+// entry on the following backfill.
+func (r *WaitlistRepository) DeclineOffer(ctx context.Context, tenant, id string) (*domain.WaitlistOffer, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var declined *domain.WaitlistOffer
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		now := time.Now()
+		offer, err := lockPendingOffer(ctx, tx, id, now)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx,
+			`UPDATE waitlist_offers SET status = $1, responded_at = $2 WHERE id = $3`,
+			domain.OfferDeclined, now, id); err != nil {
+			return domain.WrapError("failed to decline waitlist offer", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`UPDATE waitlist_entries SET status = $1, updated_at = $2 WHERE id = $3 AND status = $4`,
+			domain.WaitlistWaiting, now, offer.EntryID, domain.WaitlistOffered); err != nil {
+			return domain.WrapError("failed to update waitlist entry", err)
+		}
+
+		declined, err = scanWaitlistOffer(tx.QueryRow(ctx,
+			"SELECT "+waitlistOfferColumns+waitlistOfferFrom+" WHERE o.id = $1", id))
+		if err != nil {
+			return domain.WrapError("failed to get waitlist offer", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return declined, nil
+}
+
+// Backfill first expires lapsed offers, which puts their entries back on the
+// waitlist, then offers open slots to the next entries. Each opening is
+// offered in its own transaction with its row locked, so replicas backfilling
+// the same tenant never offer a slot twice.
+func (r *WaitlistRepository) Backfill(ctx context.Context, tenant string, now time.Time, hold time.Duration, limit int) ([]domain.WaitlistOffer, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx,
+			`WITH expired AS (
+				UPDATE waitlist_offers SET status = $1, responded_at = $2
+				WHERE status = $3 AND expires_at <= $2
+				RETURNING entry_id
+			 )
+			 UPDATE waitlist_entries e SET status = $4, updated_at = $2
+			 FROM expired WHERE e.id = expired.entry_id AND e.status = $5`,
+			domain.OfferExpired, now, domain.OfferPending, domain.WaitlistWaiting, domain.WaitlistOffered); err != nil {
+			return domain.WrapError("failed to expire waitlist offers", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`UPDATE waitlist_openings SET status = 'CLOSED', closed_at = $1 WHERE status = 'OPEN' AND ends_at <= $1`,
+			now); err != nil {
+			return domain.WrapError("failed to close waitlist openings", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var offers []domain.WaitlistOffer
+	for len(offers) < limit {
+		var offer *domain.WaitlistOffer
+		var found bool
+		err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+			var err error
+			offer, found, err = offerNextOpening(ctx, tx, now, hold)
+			return err
+		})
+		if err != nil {
+			return offers, err
+		}
+		if !found {
+			break
+		}
+		if offer != nil {
+			offers = append(offers, *offer)
+		}
+	}
+	return offers, nil
+}
+
+// offerNextOpening offers the oldest open opening without a pending offer to
+// the best matching entry, or closes it when none matches. found is false
+// when there is nothing left to offer.
+func offerNextOpening(ctx context.Context, tx pgx.Tx, now time.Time, hold time.Duration) (*domain.WaitlistOffer, bool, error) {
+	var openingID, artistID string
+	var start, end time.Time
+	err := tx.QueryRow(ctx,
+		`SELECT id, artist_id, starts_at, ends_at FROM waitlist_openings w
+		 WHERE status = 'OPEN' AND NOT EXISTS (
+			SELECT 1 FROM waitlist_offers o WHERE o.opening_id = w.id AND o.status = $1)
+		 ORDER BY created_at, id
+		 LIMIT 1 FOR UPDATE SKIP LOCKED`, domain.OfferPending).Scan(&openingID, &artistID, &start, &end)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, domain.WrapError("failed to get waitlist opening", err)
+	}
+
+	// The slot starts as early as both the opening and the entry's window
+	// allow, must still be ahead and must not clash with anything booked,
+	// blocked or held since the opening was recorded
+	var entryID string
+	var slotStart, slotEnd time.Time
+	err = tx.QueryRow(ctx,
+		`SELECT e.id, slot.starts_at, slot.ends_at
+		 FROM waitlist_entries e
+		 CROSS JOIN LATERAL (
+			SELECT greatest($3::timestamptz, e.window_start) AS starts_at,
+			       greatest($3::timestamptz, e.window_start) + make_interval(mins => e.duration_minutes) AS ends_at
+		 ) slot
+		 WHERE e.artist_id = $2 AND e.status = $5
+		   AND slot.starts_at > $6
+		   AND slot.ends_at <= least($4::timestamptz, e.window_end)
+		   AND NOT EXISTS (SELECT 1 FROM waitlist_offers o WHERE o.opening_id = $1 AND o.entry_id = e.id)
+		   AND NOT EXISTS (
+			SELECT 1 FROM appointments a
+			WHERE a.artist_id = $2 AND a.status = 'SCHEDULED'
+			  AND tstzrange(a.start_time, a.end_time, '[)') && tstzrange(slot.starts_at, slot.ends_at, '[)'))
+		   AND NOT EXISTS (
+			SELECT 1 FROM calendar_busy_blocks b
+			WHERE b.artist_id = $2
+			  AND tstzrange(b.starts_at, b.ends_at, '[)') && tstzrange(slot.starts_at, slot.ends_at, '[)'))
+		   AND NOT EXISTS (
+			SELECT 1 FROM waitlist_offers o
+			WHERE o.artist_id = $2 AND o.status = 'PENDING' AND o.expires_at > $6
+			  AND tstzrange(o.starts_at, o.ends_at, '[)') && tstzrange(slot.starts_at, slot.ends_at, '[)'))
+		 ORDER BY e.priority DESC, e.created_at, e.id
+		 LIMIT 1 FOR UPDATE OF e`,
+		openingID, artistID, start, end, domain.WaitlistWaiting, now).Scan(&entryID, &slotStart, &slotEnd)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err = tx.Exec(ctx,
+			`UPDATE waitlist_openings SET status = 'CLOSED', closed_at = $1 WHERE id = $2`, now, openingID); err != nil {
+			return nil, false, domain.WrapError("failed to close waitlist opening", err)
+		}
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, domain.WrapError("failed to match waitlist entries", err)
+	}
+
+	// An offer never holds the slot past its start
+	expires := now.Add(hold)
+	if slotStart.Before(expires) {
+		expires = slotStart
+	}
+
+	var offerID string
+	if err = tx.QueryRow(ctx,
+		`INSERT INTO waitlist_offers (opening_id, entry_id, artist_id, starts_at, ends_at, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		openingID, entryID, artistID, slotStart, slotEnd, expires).Scan(&offerID); err != nil {
+		return nil, false, domain.WrapError("failed to create waitlist offer", err)
+	}
+	if _, err = tx.Exec(ctx,
+		`UPDATE waitlist_entries SET status = $1, updated_at = $2 WHERE id = $3`,
+		domain.WaitlistOffered, now, entryID); err != nil {
+		return nil, false, domain.WrapError("failed to update waitlist entry", err)
+	}
+
+	offer, err := scanWaitlistOffer(tx.QueryRow(ctx,
+		"SELECT "+waitlistOfferColumns+waitlistOfferFrom+" WHERE o.id = $1", offerID))
+	if err != nil {
+		return nil, false, domain.WrapError("failed to get waitlist offer", err)
+	}
+	return offer, true, nil
+}
+
+// lockWaitlistEntry locks an entry and fails unless its status is one of from
+func lockWaitlistEntry(ctx context.Context, tx pgx.Tx, id string, from ...string) (*domain.WaitlistEntry, error) {
+	entry, err := scanWaitlistEntry(tx.QueryRow(ctx,
+		"SELECT "+waitlistEntryColumns+" FROM waitlist_entries WHERE id = $1 FOR UPDATE", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("waitlist entry %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get waitlist entry", err)
+	}
+	for _, status := range from {
+		if entry.Status == status {
+			return entry, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: waitlist entry is %s", domain.ErrFailedPrecondition, entry.Status)
+}
+
+// lockPendingOffer locks an offer and fails unless it is pending and has not
+// expired
+func lockPendingOffer(ctx context.Context, tx pgx.Tx, id string, now time.Time) (*domain.WaitlistOffer, error) {
+	offer, err := scanWaitlistOffer(tx.QueryRow(ctx,
+		"SELECT "+waitlistOfferColumns+waitlistOfferFrom+" WHERE o.id = $1 FOR UPDATE OF o", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("waitlist offer %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get waitlist offer", err)
+	}
+	if offer.Status != domain.OfferPending {
+		return nil, fmt.Errorf("%w: waitlist offer is %s", domain.ErrFailedPrecondition, offer.Status)
+	}
+	if !offer.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("%w: waitlist offer has expired", domain.ErrFailedPrecondition)
+	}
+	return offer, nil
+}
+
+func scanWaitlistEntry(row pgx.Row) (*domain.WaitlistEntry, error) {
+	var entry domain.WaitlistEntry
+	err := row.Scan(&entry.ID, &entry.StudioID, &entry.CustomerID, &entry.ArtistID,
+		&entry.WindowStart, &entry.WindowEnd, &entry.DurationMinutes, &entry.Priority, &entry.Notes,
+		&entry.Status, &entry.AppointmentID, &entry.CreatedAt, &entry.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func scanWaitlistOffer(row pgx.Row) (*domain.WaitlistOffer, error) {
+	var offer domain.WaitlistOffer
+	err := row.Scan(&offer.ID, &offer.OpeningID, &offer.EntryID, &offer.CustomerID, &offer.ArtistID,
+		&offer.Start, &offer.End, &offer.Status, &offer.ExpiresAt, &offer.RespondedAt,
+		&offer.AppointmentID, &offer.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if offer.Status != domain.OfferAccepted {
+		// The entry's appointment belongs to another offer
+		offer.AppointmentID = ""
+	}
+	return &offer, nil
+}
@@ -0,0 +1,133 @@
+package appointment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+const (
+	defaultBackfillInterval  = time.Minute
+	defaultOfferHold         = 2 * time.Hour
+	defaultBackfillBatchSize = 50
+)
+
+// TenantLister returns the tenants the waitlist backfill scans
+type TenantLister func(ctx context.Context) ([]config.TenantConfig, error)
+
+// WaitlistScheduler periodically offers the slots freed in every active
+// tenant to its waitlist and tells the customers about their offers. Every
+// replica runs one; the row locks taken by Backfill keep them from offering
+// the same slot twice.
+type WaitlistScheduler struct {
+	repo      domain.WaitlistRepository
+	notifier  domain.Notifier
+	tenants   TenantLister
+	interval  time.Duration
+	hold      time.Duration
+	batchSize int
+}
+
+// NewWaitlistScheduler creates a new WaitlistScheduler
+func NewWaitlistScheduler(repo domain.WaitlistRepository, notifier domain.Notifier, tenants TenantLister, cfg config.WaitlistConfig) *WaitlistScheduler {
+	scheduler := &WaitlistScheduler{
+		repo:      repo,
+		notifier:  notifier,
+		tenants:   tenants,
+		interval:  cfg.Interval,
+		hold:      cfg.OfferHold,
+		batchSize: cfg.BatchSize,
+	}
+	if scheduler.interval <= 0 {
+		scheduler.interval = defaultBackfillInterval
+	}
+	if scheduler.hold <= 0 {
+		scheduler.hold = defaultOfferHold
+	}
+	if scheduler.batchSize <= 0 {
+		scheduler.batchSize = defaultBackfillBatchSize
+	}
+	return scheduler
+}
+
+// Run scans the tenants every interval. It returns when ctx is cancelled.
+func (s *WaitlistScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.scan(ctx)
+		}
+	}
+}
+
+func (s *WaitlistScheduler) scan(ctx context.Context) {
+	tenants, err := s.tenants(ctx)
+	if err != nil {
+		logger.Log.Error("Failed to list tenants for the waitlist", zap.Error(err))
+		return
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Status != config.TenantStatusActive {
+			continue
+		}
+		offers, err := s.repo.Backfill(ctx, tenant.Subdomain, time.Now(), s.hold, s.batchSize)
+		s.notify(ctx, tenant.Subdomain, offers)
+		if err != nil {
+			logger.Log.Warn("Failed to backfill waitlist",
+				zap.String("tenant", tenant.Subdomain),
+				zap.Int("offers", len(offers)),
+				zap.Error(err))
+			continue
+		}
+		if len(offers) > 0 {
+			logger.Log.Info("Offered freed slots to the waitlist",
+				zap.String("tenant", tenant.Subdomain),
+				zap.Int("offers", len(offers)))
+		}
+	}
+}
+
+// notify tells customers about their offers. The offers stand either way, so
+// failures are only logged.
+func (s *WaitlistScheduler) notify(ctx context.Context, tenant string, offers []domain.WaitlistOffer) {
+	if len(offers) == 0 {
+		return
+	}
+
+	notifications := make([]domain.Notification, 0, len(offers))
+	for _, offer := range offers {
+		notifications = append(notifications, domain.Notification{
+			CustomerID: offer.CustomerID,
+			Kind:       NotifyWaitlistOffer,
+			Title:      "A slot opened up",
+			Body: fmt.Sprintf("A slot on %s is held for you until %s",
+				formatSlot(offer.Start, offer.End), offer.ExpiresAt.Format(time.RFC3339)),
+			Data: map[string]string{
+				"waitlist_offer_id": offer.ID,
+				"waitlist_entry_id": offer.EntryID,
+				"start_time":        offer.Start.Format(time.RFC3339),
+				"end_time":          offer.End.Format(time.RFC3339),
+				"expires_at":        offer.ExpiresAt.Format(time.RFC3339),
+			},
+		})
+	}
+
+	if err := s.notifier.Notify(ctx, tenant, notifications...); err != nil {
+		logger.Log.Warn("failed to send waitlist offers",
+			zap.String("tenant", tenant),
+			zap.Int("offers", len(offers)),
+			zap.Error(err))
+	}
+}
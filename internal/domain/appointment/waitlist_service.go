@@ -0,0 +1,327 @@
+package appointment
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// WaitlistServiceName is the fully qualified gRPC name of the waitlist
+// service. Customers wait for a slot with an artist; when an upcoming
+// appointment of that artist is cancelled or moved, the freed slot is offered
+// to the waitlist in priority order and held for a while for each customer.
+const WaitlistServiceName = "inkMe.appointment.WaitlistService"
+
+// NotifyWaitlistOffer is the notification kind of waitlist offers
+const NotifyWaitlistOffer = "waitlist.offer"
+
+type WaitlistEntryInput struct {
+	StudioID   string `json:"studio_id"`
+	CustomerID string `json:"customer_id"`
+	ArtistID   string `json:"artist_id"`
+	// WindowStart and WindowEnd are RFC 3339 timestamps of the dates the
+	// customer can come in
+	WindowStart     string `json:"window_start"`
+	WindowEnd       string `json:"window_end"`
+	DurationMinutes int    `json:"duration_minutes"`
+	// Priority puts the entry ahead of older entries with a lower priority
+	Priority int    `json:"priority"`
+	Notes    string `json:"notes"`
+}
+
+type WaitlistEntryOutput struct {
+	ID              string `json:"id"`
+	StudioID        string `json:"studio_id"`
+	CustomerID      string `json:"customer_id"`
+	ArtistID        string `json:"artist_id"`
+	WindowStart     string `json:"window_start"`
+	WindowEnd       string `json:"window_end"`
+	DurationMinutes int    `json:"duration_minutes"`
+	Priority        int    `json:"priority"`
+	Notes           string `json:"notes,omitempty"`
+	Status          string `json:"status"`
+	AppointmentID   string `json:"appointment_id,omitempty"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
+}
+
+type WaitlistEntryID struct {
+	ID string `json:"id"`
+}
+
+type ListWaitlistRequest struct {
+	StudioID   string `json:"studio_id"`
+	ArtistID   string `json:"artist_id"`
+	CustomerID string `json:"customer_id"`
+	Status     string `json:"status"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"page_size"`
+}
+
+type ListWaitlistResponse struct {
+	Entries    []WaitlistEntryOutput `json:"entries"`
+	TotalCount int64                 `json:"total_count"`
+}
+
+type WaitlistOfferOutput struct {
+	ID            string `json:"id"`
+	EntryID       string `json:"entry_id"`
+	CustomerID    string `json:"customer_id"`
+	ArtistID      string `json:"artist_id"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+	Status        string `json:"status"`
+	ExpiresAt     string `json:"expires_at"`
+	RespondedAt   string `json:"responded_at,omitempty"`
+	AppointmentID string `json:"appointment_id,omitempty"`
+	CreatedAt     string `json:"created_at"`
+}
+
+type WaitlistOfferID struct {
+	ID string `json:"id"`
+}
+
+type ListWaitlistOffersResponse struct {
+	Offers []WaitlistOfferOutput `json:"offers"`
+}
+
+// WaitlistService implements the waitlist gRPC service. The backfill itself
+// runs in WaitlistScheduler.
+type WaitlistService struct {
+	repo domain.WaitlistRepository
+}
+
+// NewWaitlistService creates a new WaitlistService
+func NewWaitlistService(repo domain.WaitlistRepository) *WaitlistService {
+	return &WaitlistService{repo: repo}
+}
+
+// Register adds the service to a gRPC server
+func (s *WaitlistService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(WaitlistServiceName,
+		structrpc.Unary(WaitlistServiceName, "AddWaitlistEntry", s.AddWaitlistEntry),
+		structrpc.Unary(WaitlistServiceName, "GetWaitlistEntry", s.GetWaitlistEntry),
+		structrpc.Unary(WaitlistServiceName, "ListWaitlist", s.ListWaitlist),
+		structrpc.Unary(WaitlistServiceName, "RemoveWaitlistEntry", s.RemoveWaitlistEntry),
+		structrpc.Unary(WaitlistServiceName, "ListWaitlistOffers", s.ListWaitlistOffers),
+		structrpc.Unary(WaitlistServiceName, "AcceptWaitlistOffer", s.AcceptWaitlistOffer),
+		structrpc.Unary(WaitlistServiceName, "DeclineWaitlistOffer", s.DeclineWaitlistOffer),
+	), s)
+}
+
+// AddWaitlistEntry puts a customer on an artist's waitlist
+func (s *WaitlistService) AddWaitlistEntry(ctx context.Context, req *WaitlistEntryInput) (*WaitlistEntryOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "AddWaitlistEntry")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	windowStart, err := parseTime("window_start", req.WindowStart)
+	if err != nil {
+		return nil, err
+	}
+	windowEnd, err := parseTime("window_end", req.WindowEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &domain.WaitlistEntry{
+		StudioID:        req.StudioID,
+		CustomerID:      req.CustomerID,
+		ArtistID:        req.ArtistID,
+		WindowStart:     windowStart,
+		WindowEnd:       windowEnd,
+		DurationMinutes: req.DurationMinutes,
+		Priority:        req.Priority,
+		Notes:           req.Notes,
+	}
+	if err = s.repo.AddEntry(ctx, tenant, entry); err != nil {
+		return nil, domain.ToStatus(err, "failed to add waitlist entry")
+	}
+
+	span.SetAttributes(attribute.String("waitlist.entry_id", entry.ID))
+
+	return waitlistEntryOutput(entry), nil
+}
+
+func (s *WaitlistService) GetWaitlistEntry(ctx context.Context, req *WaitlistEntryID) (*WaitlistEntryOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetWaitlistEntry")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	entry, err := s.repo.GetEntry(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get waitlist entry")
+	}
+	return waitlistEntryOutput(entry), nil
+}
+
+// ListWaitlist pages through the waitlist in the order slots are offered
+func (s *WaitlistService) ListWaitlist(ctx context.Context, req *ListWaitlistRequest) (*ListWaitlistResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ListWaitlist")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	result, err := s.repo.ListEntries(ctx, tenant, domain.WaitlistFilter{
+		StudioID:   req.StudioID,
+		ArtistID:   req.ArtistID,
+		CustomerID: req.CustomerID,
+		Status:     req.Status,
+		Page:       req.Page,
+		PageSize:   min(req.PageSize, domain.MaxPageSize),
+	})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list waitlist")
+	}
+
+	res := &ListWaitlistResponse{
+		Entries:    make([]WaitlistEntryOutput, 0, len(result.Items)),
+		TotalCount: result.TotalCount,
+	}
+	for i := range result.Items {
+		res.Entries = append(res.Entries, *waitlistEntryOutput(&result.Items[i]))
+	}
+
+	span.SetAttributes(attribute.Int("waitlist.count", len(res.Entries)))
+
+	return res, nil
+}
+
+// RemoveWaitlistEntry takes a customer off the waitlist, withdrawing the offer
+// they may be holding
+func (s *WaitlistService) RemoveWaitlistEntry(ctx context.Context, req *WaitlistEntryID) (*WaitlistEntryOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "RemoveWaitlistEntry")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	entry, err := s.repo.RemoveEntry(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to remove waitlist entry")
+	}
+	return waitlistEntryOutput(entry), nil
+}
+
+// ListWaitlistOffers returns the slots offered to an entry, newest first
+func (s *WaitlistService) ListWaitlistOffers(ctx context.Context, req *WaitlistEntryID) (*ListWaitlistOffersResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ListWaitlistOffers")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	offers, err := s.repo.ListOffers(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list waitlist offers")
+	}
+
+	res := &ListWaitlistOffersResponse{Offers: make([]WaitlistOfferOutput, 0, len(offers))}
+	for i := range offers {
+		res.Offers = append(res.Offers, *waitlistOfferOutput(&offers[i]))
+	}
+	return res, nil
+}
+
+// AcceptWaitlistOffer books the held slot for the customer
+func (s *WaitlistService) AcceptWaitlistOffer(ctx context.Context, req *WaitlistOfferID) (*WaitlistOfferOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "AcceptWaitlistOffer")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	offer, appointment, err := s.repo.AcceptOffer(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to accept waitlist offer")
+	}
+
+	span.SetAttributes(attribute.String("appointment.id", appointment.ID))
+
+	return waitlistOfferOutput(offer), nil
+}
+
+// DeclineWaitlistOffer releases the held slot; the customer stays on the
+// waitlist for later openings
+func (s *WaitlistService) DeclineWaitlistOffer(ctx context.Context, req *WaitlistOfferID) (*WaitlistOfferOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "DeclineWaitlistOffer")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	offer, err := s.repo.DeclineOffer(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to decline waitlist offer")
+	}
+	return waitlistOfferOutput(offer), nil
+}
+
+func waitlistEntryOutput(entry *domain.WaitlistEntry) *WaitlistEntryOutput {
+	return &WaitlistEntryOutput{
+		ID:              entry.ID,
+		StudioID:        entry.StudioID,
+		CustomerID:      entry.CustomerID,
+		ArtistID:        entry.ArtistID,
+		WindowStart:     entry.WindowStart.Format(time.RFC3339),
+		WindowEnd:       entry.WindowEnd.Format(time.RFC3339),
+		DurationMinutes: entry.DurationMinutes,
+		Priority:        entry.Priority,
+		Notes:           entry.Notes,
+		Status:          entry.Status,
+		AppointmentID:   entry.AppointmentID,
+		CreatedAt:       entry.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       entry.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func waitlistOfferOutput(offer *domain.WaitlistOffer) *WaitlistOfferOutput {
+	out := &WaitlistOfferOutput{
+		ID:            offer.ID,
+		EntryID:       offer.EntryID,
+		CustomerID:    offer.CustomerID,
+		ArtistID:      offer.ArtistID,
+		Start:         offer.Start.Format(time.RFC3339),
+		End:           offer.End.Format(time.RFC3339),
+		Status:        offer.Status,
+		ExpiresAt:     offer.ExpiresAt.Format(time.RFC3339),
+		AppointmentID: offer.AppointmentID,
+		CreatedAt:     offer.CreatedAt.Format(time.RFC3339),
+	}
+	if offer.RespondedAt != nil {
+		out.RespondedAt = offer.RespondedAt.Format(time.RFC3339)
+	}
+	return out
+}
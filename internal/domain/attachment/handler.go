@@ -0,0 +1,81 @@
+package attachment
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+// FileHandler serves attachments at /files/{tenant}/{id} to holders of a URL
+// signed by URLSigner. Bad signatures, expired URLs and unknown files all
+// answer 404, so nothing tells them apart.
+type FileHandler struct {
+	repo  domain.AttachmentRepository
+	blobs domain.BlobStore
+	urls  *URLSigner
+}
+
+// NewFileHandler creates a new FileHandler
+func NewFileHandler(repo domain.AttachmentRepository, blobs domain.BlobStore, urls *URLSigner) *FileHandler {
+	return &FileHandler{repo: repo, blobs: blobs, urls: urls}
+}
+
+func (h *FileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tenant, id := r.PathValue("tenant"), r.PathValue("id")
+	query := r.URL.Query()
+	now := time.Now()
+	if tenant == "" || id == "" || !h.urls.Verify(tenant, id, query.Get("expires"), query.Get("signature"), now) {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	attachment, err := h.repo.GetByID(ctx, tenant, id)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			logger.Log.Warn("Failed to look up attachment", zap.String("tenant", tenant), zap.Error(err))
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := h.blobs.Get(ctx, attachment.Key)
+	if err != nil {
+		logger.Log.Error("Failed to open attachment",
+			zap.String("tenant", tenant), zap.String("attachment_id", id), zap.Error(err))
+		if errors.Is(err, domain.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "failed to load file", http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	// Browsers may keep the file until the URL expires, but no longer
+	expires, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+	maxAge := max(expires-now.Unix(), 0)
+
+	header := w.Header()
+	header.Set("Content-Type", attachment.ContentType)
+	header.Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	disposition := mime.FormatMediaType("inline", map[string]string{"filename": attachment.Filename})
+	if disposition == "" {
+		disposition = "inline"
+	}
+	header.Set("Content-Disposition", disposition)
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Cache-Control", "private, max-age="+strconv.FormatInt(maxAge, 10))
+	if _, err = io.Copy(w, body); err != nil {
+		logger.Log.Warn("Failed to send attachment",
+			zap.String("tenant", tenant), zap.String("attachment_id", id), zap.Error(err))
+	}
+}
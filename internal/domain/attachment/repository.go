@@ -0,0 +1,83 @@
+package attachment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// AttachmentRepository keeps the records of uploaded files in the tenant's
+// database. The bytes themselves live in a domain.BlobStore.
+type AttachmentRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewAttachmentRepository creates a new AttachmentRepository
+func NewAttachmentRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *AttachmentRepository {
+	return &AttachmentRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+const attachmentColumns = `id, storage_key, filename, content_type, size_bytes, COALESCE(uploaded_by::text, ''),
+	COALESCE(message_id::text, ''), COALESCE(portfolio_item_id::text, ''), COALESCE(variant, ''), created_at`
+
+// Create records an uploaded file that belongs to no message or portfolio
+// item yet
+func (r *AttachmentRepository) Create(ctx context.Context, tenant string, attachment *domain.Attachment) error {
+	if attachment == nil {
+		return fmt.Errorf("%w: attachment is required", domain.ErrInvalidArgument)
+	}
+	if attachment.Key == "" || attachment.Size <= 0 {
+		return fmt.Errorf("%w: attachment needs a key and a size", domain.ErrInvalidArgument)
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	created, err := scanAttachment(pool.QueryRow(ctx,
+		`INSERT INTO attachments (storage_key, filename, content_type, size_bytes, uploaded_by)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid)
+		 RETURNING `+attachmentColumns,
+		attachment.Key, attachment.Filename, attachment.ContentType, attachment.Size, attachment.UploadedBy))
+	if err != nil {
+		return domain.WrapError("failed to create attachment", err)
+	}
+	*attachment = *created
+	return nil
+}
+
+func (r *AttachmentRepository) GetByID(ctx context.Context, tenant, id string) (*domain.Attachment, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	attachment, err := scanAttachment(pool.QueryRow(ctx, "SELECT "+attachmentColumns+" FROM attachments WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("attachment %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get attachment", err)
+	}
+	return attachment, nil
+}
+
+func scanAttachment(row pgx.Row) (*domain.Attachment, error) {
+	var attachment domain.Attachment
+	err := row.Scan(&attachment.ID, &attachment.Key, &attachment.Filename, &attachment.ContentType, &attachment.Size,
+		&attachment.UploadedBy, &attachment.MessageID, &attachment.PortfolioItemID, &attachment.Variant, &attachment.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
@@ -0,0 +1,292 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// AttachmentServiceName is the fully qualified gRPC name of the attachment
+// service
+const AttachmentServiceName = "inkMe.attachment.AttachmentService"
+
+const (
+	// defaultUploadLimit caps uploads of plans without a configured limit
+	defaultUploadLimit = 10 << 20
+	// sniffLength is how much of a file http.DetectContentType looks at
+	sniffLength = 512
+	// maxFilenameLength caps a stored filename, in characters
+	maxFilenameLength = 255
+)
+
+// allowedContentTypes are the sniffed types an upload may have: reference
+// images, design sketches and consent forms
+var allowedContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/heic":      true,
+	"application/pdf": true,
+}
+
+// managerRoles may read every attachment of the tenant
+var managerRoles = []string{"OWNER"}
+
+// UploadChunk is one message of an UploadAttachment stream. The filename is
+// read from the first chunk. Chunks must stay under gRPC's 4 MiB message
+// limit; 1 MiB is a good size.
+type UploadChunk struct {
+	Filename string `json:"filename"`
+	// Data is the next part of the file, base64 encoded in the Struct
+	Data []byte `json:"data"`
+}
+
+type AttachmentID struct {
+	ID string `json:"id"`
+}
+
+type AttachmentOutput struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// URL downloads the file without a session until URLExpiresAt
+	URL             string `json:"url"`
+	URLExpiresAt    string `json:"url_expires_at"`
+	MessageID       string `json:"message_id,omitempty"`
+	PortfolioItemID string `json:"portfolio_item_id,omitempty"`
+	CreatedAt       string `json:"created_at"`
+}
+
+// UploadLimits returns the largest file a tenant may upload, in bytes
+type UploadLimits func(ctx context.Context, tenant string) (int64, error)
+
+// PlanUploadLimits reads the limit of the tenant's plan from limits, falling
+// back to its "default" entry
+func PlanUploadLimits(limits map[string]int64, tenantConfig func(ctx context.Context, subdomain string) (*config.TenantConfig, error)) UploadLimits {
+	return func(ctx context.Context, tenant string) (int64, error) {
+		cfg, err := tenantConfig(ctx, tenant)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get tenant plan: %w", err)
+		}
+		if limit, ok := limits[strings.ToLower(cfg.Plan)]; ok && limit > 0 {
+			return limit, nil
+		}
+		if limit, ok := limits["default"]; ok && limit > 0 {
+			return limit, nil
+		}
+		return defaultUploadLimit, nil
+	}
+}
+
+// AttachmentService implements the attachment gRPC service. Uploaded files
+// belong to their uploader until they are sent with a message or shown on a
+// portfolio item.
+type AttachmentService struct {
+	repo   domain.AttachmentRepository
+	blobs  domain.BlobStore
+	urls   domain.AttachmentURLSigner
+	limits UploadLimits
+}
+
+// NewAttachmentService creates a new AttachmentService
+func NewAttachmentService(repo domain.AttachmentRepository, blobs domain.BlobStore, urls domain.AttachmentURLSigner, limits UploadLimits) *AttachmentService {
+	return &AttachmentService{repo: repo, blobs: blobs, urls: urls, limits: limits}
+}
+
+// Register adds the service to a gRPC server
+func (s *AttachmentService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(AttachmentServiceName,
+		structrpc.ClientStream("UploadAttachment", s.UploadAttachment),
+		structrpc.Unary(AttachmentServiceName, "GetAttachment", s.GetAttachment),
+	), s)
+}
+
+// UploadAttachment stores a file sent in chunks. Its type is sniffed from
+// its contents rather than taken from the client, and it may not exceed the
+// tenant plan's limit.
+func (s *AttachmentService) UploadAttachment(stream *structrpc.Receiver[UploadChunk]) (*AttachmentOutput, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(stream.Context(), "UploadAttachment")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	limit, err := s.limits(ctx, tenant)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get upload limit")
+	}
+
+	// Chunks are spooled to disk so the blob store is handed the whole file
+	// with its size, and a rejected upload never reaches it
+	spool, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to buffer upload")
+	}
+	defer func() {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+	}()
+
+	var filename string
+	var head bytes.Buffer
+	var size int64
+	for first := true; ; first = false {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			filename = CleanFilename(chunk.Filename)
+		}
+		size += int64(len(chunk.Data))
+		if size > limit {
+			return nil, status.Errorf(codes.ResourceExhausted, "file is larger than the %d bytes allowed", limit)
+		}
+		if head.Len() < sniffLength {
+			head.Write(chunk.Data[:min(len(chunk.Data), sniffLength-head.Len())])
+		}
+		if _, err = spool.Write(chunk.Data); err != nil {
+			return nil, status.Error(codes.Internal, "failed to buffer upload")
+		}
+	}
+	if size == 0 {
+		return nil, status.Error(codes.InvalidArgument, "file is empty")
+	}
+
+	contentType := sniffContentType(head.Bytes())
+	if !allowedContentTypes[contentType] {
+		return nil, status.Errorf(codes.InvalidArgument, "files of type %s are not accepted", contentType)
+	}
+
+	if _, err = spool.Seek(0, io.SeekStart); err != nil {
+		return nil, status.Error(codes.Internal, "failed to buffer upload")
+	}
+	key := fmt.Sprintf("%s/attachments/%s", tenant, uuid.NewString())
+	if err = s.blobs.Put(ctx, key, contentType, spool, size); err != nil {
+		return nil, domain.ToStatus(err, "failed to store file")
+	}
+
+	attachment := &domain.Attachment{
+		Key:         key,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		UploadedBy:  userID,
+	}
+	if err = s.repo.Create(ctx, tenant, attachment); err != nil {
+		if delErr := s.blobs.Delete(context.WithoutCancel(ctx), key); delErr != nil {
+			logger.Log.Warn("failed to delete orphaned blob",
+				zap.String("tenant", tenant), zap.String("key", key), zap.Error(delErr))
+		}
+		return nil, domain.ToStatus(err, "failed to store attachment")
+	}
+
+	span.SetAttributes(
+		attribute.String("attachment.id", attachment.ID),
+		attribute.String("attachment.content_type", contentType),
+		attribute.Int64("attachment.size", size),
+	)
+
+	return NewOutput(tenant, attachment, s.urls), nil
+}
+
+// GetAttachment returns an attachment with a fresh download URL. Only its
+// uploader and managers may get it here; participants of a conversation get
+// the URLs of its attachments with its messages.
+func (s *AttachmentService) GetAttachment(ctx context.Context, req *AttachmentID) (*AttachmentOutput, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "GetAttachment")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	attachment, err := s.repo.GetByID(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get attachment")
+	}
+	if attachment.UploadedBy != userID {
+		if err = domain.RequireRole(ctx, managerRoles...); err != nil {
+			return nil, err
+		}
+	}
+	return NewOutput(tenant, attachment, s.urls), nil
+}
+
+// NewOutput describes an attachment with a newly signed download URL
+func NewOutput(tenant string, attachment *domain.Attachment, urls domain.AttachmentURLSigner) *AttachmentOutput {
+	url, expires := urls.SignURL(tenant, attachment.ID)
+	return &AttachmentOutput{
+		ID:              attachment.ID,
+		Filename:        attachment.Filename,
+		ContentType:     attachment.ContentType,
+		Size:            attachment.Size,
+		URL:             url,
+		URLExpiresAt:    expires.Format(time.RFC3339),
+		MessageID:       attachment.MessageID,
+		PortfolioItemID: attachment.PortfolioItemID,
+		CreatedAt:       attachment.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// sniffContentType detects the type of a file from its first bytes. HEIC,
+// what most phones take photos in, is recognised on top of what
+// http.DetectContentType knows.
+func sniffContentType(head []byte) string {
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" {
+		switch string(head[8:12]) {
+		case "heic", "heix", "heim", "heis", "mif1", "msf1":
+			return "image/heic"
+		}
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	return contentType
+}
+
+// CleanFilename keeps the base name of a client's filename without control
+// characters, so it is safe to show and to send back in headers
+func CleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	if utf8.RuneCountInString(name) > maxFilenameLength {
+		name = string([]rune(name)[:maxFilenameLength])
+	}
+	return name
+}
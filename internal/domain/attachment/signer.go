@@ -0,0 +1,79 @@
+package attachment
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+const defaultURLTTL = 15 * time.Minute
+
+// URLSigner issues the download URLs served by FileHandler. A URL carries its
+// expiry and an HMAC of the tenant, attachment and expiry, so it needs no
+// session and cannot be altered to reach another file.
+type URLSigner struct {
+	baseURL string
+	secret  []byte
+	ttl     time.Duration
+}
+
+// NewURLSigner creates a URLSigner from the storage settings. Without a
+// configured secret a random one is used, which only this process knows.
+func NewURLSigner(cfg config.StorageConfig) *URLSigner {
+	secret := []byte(cfg.URLSecret)
+	if len(secret) == 0 {
+		logger.Log.Warn("storage.url_secret is not set; download URLs will not survive a restart or work across replicas")
+		secret = make([]byte, 32)
+		_, _ = rand.Read(secret)
+	}
+	ttl := cfg.URLTTL
+	if ttl <= 0 {
+		ttl = defaultURLTTL
+	}
+	return &URLSigner{
+		baseURL: strings.TrimSuffix(cfg.PublicURL, "/"),
+		secret:  secret,
+		ttl:     ttl,
+	}
+}
+
+// SignURL returns a download URL for the attachment and when it expires
+func (s *URLSigner) SignURL(tenant, attachmentID string) (string, time.Time) {
+	expires := time.Now().Add(s.ttl).Truncate(time.Second)
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{
+		"expires":   {unix},
+		"signature": {s.signature(tenant, attachmentID, unix)},
+	}
+	return fmt.Sprintf("%s/files/%s/%s?%s", s.baseURL, url.PathEscape(tenant), url.PathEscape(attachmentID), query.Encode()), expires
+}
+
+// Verify reports whether signature was issued for the attachment with this
+// expiry and the expiry has not passed
+func (s *URLSigner) Verify(tenant, attachmentID, expires, signature string, now time.Time) bool {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > unix {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	want, _ := hex.DecodeString(s.signature(tenant, attachmentID, expires))
+	return hmac.Equal(got, want)
+}
+
+func (s *URLSigner) signature(tenant, attachmentID, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(tenant + "\n" + attachmentID + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,104 @@
+package attachment
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+)
+
+func testSigner(secret string) *URLSigner {
+	return NewURLSigner(config.StorageConfig{
+		PublicURL: "https://files.example.com/",
+		URLSecret: secret,
+		URLTTL:    10 * time.Minute,
+	})
+}
+
+// signedQuery signs a URL and returns its expires and signature parameters
+func signedQuery(t *testing.T, signer *URLSigner, tenant, attachmentID string) (string, string) {
+	t.Helper()
+	raw, _ := signer.SignURL(tenant, attachmentID)
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("expires"), u.Query().Get("signature")
+}
+
+func TestSignURL(t *testing.T) {
+	signer := testSigner("secret")
+	raw, expires := signer.SignURL("studio one", "a/1")
+
+	want := "https://files.example.com/files/studio%20one/a%2F1?"
+	if !strings.HasPrefix(raw, want) {
+		t.Errorf("got URL %s, want it to start with %s", raw, want)
+	}
+	if ttl := time.Until(expires); ttl <= 9*time.Minute || ttl > 10*time.Minute {
+		t.Errorf("URL expires in %s, want about 10m", ttl)
+	}
+}
+
+func TestURLSignerVerify(t *testing.T) {
+	signer := testSigner("secret")
+	expires, signature := signedQuery(t, signer, "studio", "attachment-1")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(unix, 0).Add(-time.Minute)
+
+	tampered := []byte(signature)
+	if tampered[0] == '0' {
+		tampered[0] = '1'
+	} else {
+		tampered[0] = '0'
+	}
+
+	tests := []struct {
+		name         string
+		signer       *URLSigner
+		tenant       string
+		attachmentID string
+		expires      string
+		signature    string
+		now          time.Time
+		want         bool
+	}{
+		{"valid", signer, "studio", "attachment-1", expires, signature, now, true},
+		{"at expiry", signer, "studio", "attachment-1", expires, signature, time.Unix(unix, 0), true},
+		{"expired", signer, "studio", "attachment-1", expires, signature, time.Unix(unix, 0).Add(time.Second), false},
+		{"extended expiry", signer, "studio", "attachment-1", strconv.FormatInt(unix+3600, 10), signature, now, false},
+		{"tampered signature", signer, "studio", "attachment-1", expires, string(tampered), now, false},
+		{"truncated signature", signer, "studio", "attachment-1", expires, signature[:len(signature)-2], now, false},
+		{"not hex", signer, "studio", "attachment-1", expires, "zz" + signature[2:], now, false},
+		{"empty signature", signer, "studio", "attachment-1", expires, "", now, false},
+		{"bad expiry", signer, "studio", "attachment-1", "soon", signature, now, false},
+		{"wrong tenant", signer, "other-studio", "attachment-1", expires, signature, now, false},
+		{"wrong attachment", signer, "studio", "attachment-2", expires, signature, now, false},
+		{"other secret", testSigner("other secret"), "studio", "attachment-1", expires, signature, now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.signer.Verify(tt.tenant, tt.attachmentID, tt.expires, tt.signature, tt.now); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestURLSignerSeparatesFields checks that moving characters between the
+// tenant and the attachment ID does not keep a signature valid
+func TestURLSignerSeparatesFields(t *testing.T) {
+	signer := testSigner("secret")
+	expires, signature := signedQuery(t, signer, "studio", "abc")
+	unix, _ := strconv.ParseInt(expires, 10, 64)
+	now := time.Unix(unix, 0).Add(-time.Minute)
+
+	if signer.Verify("studioa", "bc", expires, signature, now) {
+		t.Error("signature of studio/abc verified for studioa/bc")
+	}
+}
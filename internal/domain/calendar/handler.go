@@ -0,0 +1,70 @@
+package calendar
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+// Feeds cover the recent past and the coming year
+const (
+	feedPast   = 30 * 24 * time.Hour
+	feedFuture = 365 * 24 * time.Hour
+)
+
+// FeedHandler serves the artists' iCalendar feeds at
+// /calendar/{tenant}/{token}.ics. The token is the only credential, so unknown
+// tenants and tokens both answer 404 and nothing tells them apart.
+type FeedHandler struct {
+	repo domain.CalendarRepository
+}
+
+// NewFeedHandler creates a new FeedHandler
+func NewFeedHandler(repo domain.CalendarRepository) *FeedHandler {
+	return &FeedHandler{repo: repo}
+}
+
+func (h *FeedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tenant := r.PathValue("tenant")
+	token, ok := strings.CutSuffix(r.PathValue("token"), ".ics")
+	if tenant == "" || !ok || token == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	artistID, err := h.repo.ArtistForFeedToken(ctx, tenant, token)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			logger.Log.Warn("Failed to look up calendar feed", zap.String("tenant", tenant), zap.Error(err))
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	now := time.Now()
+	events, err := h.repo.ArtistEvents(ctx, tenant, artistID, now.Add(-feedPast), now.Add(feedFuture))
+	if err != nil {
+		logger.Log.Error("Failed to load calendar feed",
+			zap.String("tenant", tenant), zap.String("artist_id", artistID), zap.Error(err))
+		http.Error(w, "failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+
+	var body bytes.Buffer
+	if err = WriteCalendar(&body, "Appointments", events); err != nil {
+		http.Error(w, "failed to write calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	_, _ = w.Write(body.Bytes())
+}
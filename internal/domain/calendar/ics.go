@@ -0,0 +1,586 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+const (
+	icsDateTime    = "20060102T150405"
+	icsDateTimeUTC = "20060102T150405Z"
+	icsDate        = "20060102"
+
+	// maxLineOctets is the longest content line RFC 5545 allows before folding
+	maxLineOctets = 75
+	// maxOccurrences caps the instances expanded from one recurring event
+	// within the import window, maxIterations the steps taken to get there
+	maxOccurrences = 1000
+	maxIterations  = 100000
+)
+
+// WriteCalendar renders events as an iCalendar object. Times are written in
+// UTC so that every client places them the same way.
+func WriteCalendar(w io.Writer, name string, events []domain.CalendarEvent) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) {
+		writeFolded(bw, s)
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//SyncInk//Ink App//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	if name != "" {
+		line("X-WR-CALNAME:" + escapeText(name))
+	}
+	for _, event := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + escapeText(event.UID))
+		line("DTSTAMP:" + event.Updated.UTC().Format(icsDateTimeUTC))
+		line("LAST-MODIFIED:" + event.Updated.UTC().Format(icsDateTimeUTC))
+		line("DTSTART:" + event.Start.UTC().Format(icsDateTimeUTC))
+		line("DTEND:" + event.End.UTC().Format(icsDateTimeUTC))
+		line("SUMMARY:" + escapeText(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION:" + escapeText(event.Description))
+		}
+		line("STATUS:CONFIRMED")
+		line("TRANSP:OPAQUE")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// writeFolded writes a content line, folding it at 75 octets without
+// splitting a UTF-8 sequence
+func writeFolded(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut--
+		}
+		_, _ = w.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space that counts against the limit
+		limit = maxLineOctets - 1
+	}
+	_, _ = w.WriteString(s + "\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// property is one content line: NAME;PARAM=VALUE:value
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// vevent holds the properties of one VEVENT that matter for busy time
+type vevent struct {
+	uid          string
+	summary      string
+	start        property
+	end          *property
+	duration     string
+	rrule        string
+	exdates      []property
+	recurrenceID *property
+	status       string
+	transparent  bool
+}
+
+// ParseBusyBlocks reads the events of an iCalendar object and returns the
+// busy time they cover within [from, to). Recurring events are expanded,
+// cancelled and transparent (free) events are skipped, and floating times and
+// dates are read in loc.
+func ParseBusyBlocks(r io.Reader, loc *time.Location, from, to time.Time) ([]domain.BusyBlock, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*vevent
+	var current *vevent
+	depth := 0
+	for _, raw := range lines {
+		prop, err := parseProperty(raw)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT"):
+			current = &vevent{}
+			depth = 0
+			continue
+		case current == nil:
+			continue
+		case prop.name == "BEGIN":
+			// Alarms nest inside events and carry their own properties
+			depth++
+			continue
+		case prop.name == "END" && depth > 0:
+			depth--
+			continue
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			events = append(events, current)
+			current = nil
+			continue
+		case depth > 0:
+			continue
+		}
+
+		switch prop.name {
+		case "UID":
+			current.uid = prop.value
+		case "SUMMARY":
+			current.summary = unescapeText(prop.value)
+		case "DTSTART":
+			current.start = prop
+		case "DTEND":
+			end := prop
+			current.end = &end
+		case "DURATION":
+			current.duration = prop.value
+		case "RRULE":
+			current.rrule = prop.value
+		case "EXDATE":
+			current.exdates = append(current.exdates, prop)
+		case "RECURRENCE-ID":
+			id := prop
+			current.recurrenceID = &id
+		case "STATUS":
+			current.status = strings.ToUpper(prop.value)
+		case "TRANSP":
+			current.transparent = strings.EqualFold(prop.value, "TRANSPARENT")
+		}
+	}
+
+	// Instances moved or changed in the source replace the instance of the
+	// series they were derived from
+	overridden := make(map[string]map[int64]bool)
+	for _, event := range events {
+		if event.recurrenceID == nil {
+			continue
+		}
+		at, _, err := parseTime(*event.recurrenceID, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%w: event %s: %v", domain.ErrInvalidArgument, event.uid, err)
+		}
+		if overridden[event.uid] == nil {
+			overridden[event.uid] = make(map[int64]bool)
+		}
+		overridden[event.uid][at.Unix()] = true
+	}
+
+	var blocks []domain.BusyBlock
+	for _, event := range events {
+		if event.status == "CANCELLED" || event.transparent || event.start.value == "" {
+			continue
+		}
+		occurrences, err := event.occurrences(loc, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("%w: event %s: %v", domain.ErrInvalidArgument, event.uid, err)
+		}
+		for _, occurrence := range occurrences {
+			if event.recurrenceID == nil && overridden[event.uid][occurrence.Start.Unix()] {
+				continue
+			}
+			if !occurrence.End.After(from) || !occurrence.Start.Before(to) {
+				continue
+			}
+			blocks = append(blocks, domain.BusyBlock{
+				UID:     event.uid,
+				Summary: event.summary,
+				Start:   occurrence.Start,
+				End:     occurrence.End,
+			})
+		}
+	}
+
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Start.Before(blocks[j].Start) })
+	return blocks, nil
+}
+
+// occurrences expands the event within [after, before), skipping its
+// exception dates
+func (e *vevent) occurrences(loc *time.Location, after, before time.Time) ([]domain.TimeRange, error) {
+	start, allDay, err := parseTime(e.start, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	var length time.Duration
+	var days int
+	switch {
+	case e.end != nil:
+		end, _, err := parseTime(*e.end, loc)
+		if err != nil {
+			return nil, err
+		}
+		if allDay {
+			days = int(end.Sub(start).Hours()+12) / 24
+		} else {
+			length = end.Sub(start)
+		}
+	case e.duration != "":
+		if days, length, err = parseDuration(e.duration); err != nil {
+			return nil, err
+		}
+	case allDay:
+		days = 1
+	}
+
+	excluded := make(map[int64]bool)
+	for _, exdate := range e.exdates {
+		for _, value := range strings.Split(exdate.value, ",") {
+			at, _, err := parseTime(property{name: exdate.name, params: exdate.params, value: value}, loc)
+			if err != nil {
+				return nil, err
+			}
+			excluded[at.Unix()] = true
+		}
+	}
+
+	starts := []time.Time{start}
+	if e.rrule != "" && e.recurrenceID == nil {
+		// Instances that start earlier may still run into the window
+		after = after.AddDate(0, 0, -days).Add(-length)
+		if starts, err = expandRule(e.rrule, start, loc, after, before); err != nil {
+			return nil, err
+		}
+	}
+
+	var ranges []domain.TimeRange
+	for _, s := range starts {
+		if excluded[s.Unix()] {
+			continue
+		}
+		// Day lengths follow the calendar so all-day events cover whole local
+		// days across daylight-saving changes
+		end := s.AddDate(0, 0, days).Add(length)
+		if end.After(s) {
+			ranges = append(ranges, domain.TimeRange{Start: s, End: end})
+		}
+	}
+	return ranges, nil
+}
+
+// expandRule lists the starts of a recurrence rule within [after, before).
+// Earlier instances still count towards COUNT. FREQ, INTERVAL, COUNT, UNTIL,
+// WKST and the BYDAY of weekly rules are supported; any other BY part is
+// rejected rather than expanded into the wrong instances.
+func expandRule(rule string, start time.Time, loc *time.Location, after, before time.Time) ([]time.Time, error) {
+	parts := make(map[string]string)
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		key = strings.ToUpper(key)
+		if !supportedRuleParts[key] {
+			return nil, fmt.Errorf("unsupported RRULE part %s", key)
+		}
+		parts[key] = strings.ToUpper(value)
+	}
+	if _, ok := parts["BYDAY"]; ok && parts["FREQ"] != "WEEKLY" {
+		return nil, fmt.Errorf("unsupported RRULE BYDAY with FREQ=%s", parts["FREQ"])
+	}
+
+	interval := 1
+	if value, ok := parts["INTERVAL"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid RRULE interval %q", value)
+		}
+		interval = n
+	}
+	count := maxIterations
+	if value, ok := parts["COUNT"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid RRULE count %q", value)
+		}
+		count = n
+	}
+	until := before
+	if value, ok := parts["UNTIL"]; ok {
+		t, _, err := parseTime(property{value: value}, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RRULE until %q", value)
+		}
+		// UNTIL is inclusive
+		if t = t.Add(time.Second); t.Before(until) {
+			until = t
+		}
+	}
+
+	var step func(t time.Time, n int) time.Time
+	switch parts["FREQ"] {
+	case "DAILY":
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 0, n) }
+	case "WEEKLY":
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, 0, 7*n) }
+	case "MONTHLY":
+		step = func(t time.Time, n int) time.Time { return t.AddDate(0, n, 0) }
+	case "YEARLY":
+		step = func(t time.Time, n int) time.Time { return t.AddDate(n, 0, 0) }
+	default:
+		// Sub-daily rules are rare in personal calendars; keep the first instance
+		return []time.Time{start}, nil
+	}
+
+	// A weekly BYDAY repeats the event on each listed weekday of every
+	// INTERVAL-th week, counted from the week of the first instance. Weeks
+	// start on WKST, Monday by default.
+	weekStartDay := time.Monday
+	if value, ok := parts["WKST"]; ok {
+		day, ok := weekdayCodes[value]
+		if !ok {
+			return nil, fmt.Errorf("invalid RRULE week start %q", value)
+		}
+		weekStartDay = day
+	}
+	var offsets []int
+	if byDay, ok := parts["BYDAY"]; ok {
+		for _, day := range strings.Split(byDay, ",") {
+			weekday, ok := weekdayCodes[day]
+			if !ok {
+				return nil, fmt.Errorf("invalid RRULE weekday %q", day)
+			}
+			offsets = append(offsets, daysSince(weekday, weekStartDay))
+		}
+		sort.Ints(offsets)
+	}
+
+	local := start.In(loc)
+	var starts []time.Time
+	seen := 0
+	emit := func(t time.Time) {
+		seen++
+		if !t.Before(after) {
+			starts = append(starts, t)
+		}
+	}
+	for i := 0; seen < count && len(starts) < maxOccurrences && i < maxIterations; i++ {
+		base := step(local, i*interval)
+		if len(offsets) == 0 {
+			if !base.Before(until) {
+				break
+			}
+			emit(base)
+			continue
+		}
+
+		weekStart := base.AddDate(0, 0, -daysSince(base.Weekday(), weekStartDay))
+		if !weekStart.Before(until) {
+			break
+		}
+		for _, offset := range offsets {
+			t := weekStart.AddDate(0, 0, offset)
+			if t.Before(local) || !t.Before(until) || seen == count {
+				continue
+			}
+			emit(t)
+		}
+	}
+	return starts, nil
+}
+
+// supportedRuleParts are the RRULE parts expandRule understands
+var supportedRuleParts = map[string]bool{
+	"FREQ": true, "INTERVAL": true, "COUNT": true, "UNTIL": true, "WKST": true, "BYDAY": true,
+}
+
+// daysSince returns how many days day falls after from within a week
+func daysSince(day, from time.Weekday) int {
+	return (int(day) - int(from) + 7) % 7
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseTime reads a DATE or DATE-TIME value. UTC times end in Z, TZID names
+// the zone of local times, and floating times and dates are read in loc. An
+// unknown TZID, e.g. a Windows zone name, also falls back to loc.
+func parseTime(prop property, loc *time.Location) (time.Time, bool, error) {
+	value := strings.TrimSpace(prop.value)
+	if tzid, ok := prop.params["TZID"]; ok {
+		if zone, err := time.LoadLocation(strings.Trim(tzid, `"`)); err == nil {
+			loc = zone
+		}
+	}
+
+	if prop.params["VALUE"] == "DATE" || len(value) == len(icsDate) {
+		t, err := time.ParseInLocation(icsDate, value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icsDateTimeUTC, value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation(icsDateTime, value, loc)
+	return t, false, err
+}
+
+// parseDuration reads an RFC 5545 duration such as P1D, PT1H30M or P2W.
+// Days and weeks are returned apart so they follow the calendar.
+func parseDuration(value string) (int, time.Duration, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	if s == value || s == "" || strings.HasPrefix(value, "-") {
+		return 0, 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var days int
+	var length time.Duration
+	inTime := false
+	number := ""
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid duration %q", value)
+		}
+		number = ""
+		switch {
+		case c == 'W' && !inTime:
+			days += 7 * n
+		case c == 'D' && !inTime:
+			days += n
+		case c == 'H' && inTime:
+			length += time.Duration(n) * time.Hour
+		case c == 'M' && inTime:
+			length += time.Duration(n) * time.Minute
+		case c == 'S' && inTime:
+			length += time.Duration(n) * time.Second
+		default:
+			return 0, 0, fmt.Errorf("invalid duration %q", value)
+		}
+	}
+	if number != "" {
+		return 0, 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return days, length, nil
+}
+
+// unfold joins folded content lines
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to read calendar: %v", domain.ErrInvalidArgument, err)
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("%w: not an iCalendar file", domain.ErrInvalidArgument)
+	}
+	return lines, nil
+}
+
+// parseProperty splits a content line into its name, parameters and value.
+// Parameter values may be quoted and contain ':' or ';'.
+func parseProperty(line string) (property, error) {
+	prop := property{params: make(map[string]string)}
+
+	inQuotes := false
+	nameEnd, valueStart := -1, -1
+	for i, c := range line {
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == ';' && !inQuotes && nameEnd < 0:
+			nameEnd = i
+		case c == ':' && !inQuotes:
+			valueStart = i
+		}
+		if valueStart >= 0 {
+			break
+		}
+	}
+	if valueStart < 0 {
+		return prop, fmt.Errorf("%w: invalid calendar line %q", domain.ErrInvalidArgument, line)
+	}
+	if nameEnd < 0 {
+		nameEnd = valueStart
+	}
+	prop.name = strings.ToUpper(line[:nameEnd])
+	prop.value = line[valueStart+1:]
+
+	if nameEnd < valueStart {
+		for _, param := range splitParams(line[nameEnd+1 : valueStart]) {
+			key, value, _ := strings.Cut(param, "=")
+			prop.params[strings.ToUpper(key)] = strings.ToUpper(strings.Trim(value, `"`))
+		}
+	}
+	if tzid, ok := prop.params["TZID"]; ok {
+		// Zone names are case sensitive; keep the original spelling
+		prop.params["TZID"] = originalParam(line[nameEnd+1:valueStart], "TZID", tzid)
+	}
+	return prop, nil
+}
+
+func splitParams(s string) []string {
+	var params []string
+	inQuotes := false
+	last := 0
+	for i, c := range s {
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == ';' && !inQuotes:
+			params = append(params, s[last:i])
+			last = i + 1
+		}
+	}
+	return append(params, s[last:])
+}
+
+func originalParam(params, key, fallback string) string {
+	for _, param := range splitParams(params) {
+		k, value, _ := strings.Cut(param, "=")
+		if strings.EqualFold(k, key) {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return fallback
+}
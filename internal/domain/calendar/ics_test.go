@@ -0,0 +1,256 @@
+package calendar
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+func loadZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+// calendarOf wraps content lines in a VCALENDAR
+func calendarOf(lines ...string) string {
+	all := append([]string{"BEGIN:VCALENDAR", "VERSION:2.0"}, lines...)
+	all = append(all, "END:VCALENDAR")
+	return strings.Join(all, "\r\n") + "\r\n"
+}
+
+// localStarts formats the block start times as wall-clock times with their zone
+func localStarts(blocks []domain.BusyBlock, loc *time.Location) []string {
+	starts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		starts = append(starts, block.Start.In(loc).Format("2006-01-02 15:04 MST"))
+	}
+	return starts
+}
+
+func assertStarts(t *testing.T, got []domain.BusyBlock, loc *time.Location, want ...string) {
+	t.Helper()
+	starts := localStarts(got, loc)
+	if len(starts) != len(want) {
+		t.Fatalf("got %d blocks %v, want %d %v", len(starts), starts, len(want), want)
+	}
+	for i := range want {
+		if starts[i] != want[i] {
+			t.Errorf("block %d starts at %s, want %s", i, starts[i], want[i])
+		}
+	}
+}
+
+func TestWriteCalendarFoldsLongLinesWithoutSplittingRunes(t *testing.T) {
+	summary := strings.Repeat("Sessão de tatuagem; braço, ", 8)
+	start := time.Date(2025, 1, 13, 10, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	err := WriteCalendar(&buf, "Studio", []domain.CalendarEvent{{
+		UID:     "a1",
+		Summary: summary,
+		Start:   start,
+		End:     start.Add(time.Hour),
+		Updated: start,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	folded := 0
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line of %d octets exceeds the limit: %q", len(line), line)
+		}
+		if strings.HasPrefix(line, " ") {
+			folded++
+			if !isRuneStart(line[1]) {
+				t.Errorf("continuation line starts inside a UTF-8 sequence: %q", line)
+			}
+		}
+	}
+	if folded == 0 {
+		t.Fatal("expected the summary to be folded")
+	}
+
+	blocks, err := ParseBusyBlocks(&buf, time.UTC, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || blocks[0].Summary != summary {
+		t.Fatalf("round trip gave %+v, want summary %q", blocks, summary)
+	}
+}
+
+func TestParseBusyBlocksReadsZonesAndFloatingTimes(t *testing.T) {
+	lisbon := loadZone(t, "Europe/Lisbon")
+	loadZone(t, "America/New_York")
+
+	tests := []struct {
+		name  string
+		start string
+		want  string
+	}{
+		{name: "utc", start: "DTSTART:20250113T100000Z", want: "2025-01-13 10:00 WET"},
+		{name: "tzid", start: "DTSTART;TZID=America/New_York:20250113T050000", want: "2025-01-13 10:00 WET"},
+		{name: "quoted tzid", start: `DTSTART;TZID="America/New_York":20250113T050000`, want: "2025-01-13 10:00 WET"},
+		{name: "floating", start: "DTSTART:20250113T100000", want: "2025-01-13 10:00 WET"},
+		{name: "unknown tzid falls back", start: "DTSTART;TZID=GMT Standard Time:20250113T100000", want: "2025-01-13 10:00 WET"},
+		{name: "all day", start: "DTSTART;VALUE=DATE:20250113", want: "2025-01-13 00:00 WET"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ics := calendarOf("BEGIN:VEVENT", "UID:e1", tt.start, "DURATION:PT1H", "END:VEVENT")
+			blocks, err := ParseBusyBlocks(strings.NewReader(ics), lisbon,
+				time.Date(2025, 1, 12, 0, 0, 0, 0, lisbon), time.Date(2025, 1, 15, 0, 0, 0, 0, lisbon))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStarts(t, blocks, lisbon, tt.want)
+		})
+	}
+}
+
+func TestParseBusyBlocksSkipsExdatesAndOverriddenInstances(t *testing.T) {
+	loc := loadZone(t, "Europe/Lisbon")
+	ics := calendarOf(
+		"BEGIN:VEVENT",
+		"UID:series",
+		"DTSTART;TZID=Europe/Lisbon:20250113T100000",
+		"DTEND;TZID=Europe/Lisbon:20250113T110000",
+		"RRULE:FREQ=DAILY;COUNT=5",
+		"EXDATE;TZID=Europe/Lisbon:20250114T100000,20250116T100000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:series",
+		"RECURRENCE-ID;TZID=Europe/Lisbon:20250115T100000",
+		"DTSTART;TZID=Europe/Lisbon:20250115T150000",
+		"DTEND;TZID=Europe/Lisbon:20250115T160000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:cancelled",
+		"DTSTART;TZID=Europe/Lisbon:20250113T120000",
+		"DURATION:PT1H",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:free",
+		"DTSTART;TZID=Europe/Lisbon:20250113T130000",
+		"DURATION:PT1H",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+	)
+
+	blocks, err := ParseBusyBlocks(strings.NewReader(ics), loc,
+		time.Date(2025, 1, 13, 0, 0, 0, 0, loc), time.Date(2025, 1, 20, 0, 0, 0, 0, loc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The 14th and 16th are excluded and the 15th moved to the afternoon
+	assertStarts(t, blocks, loc,
+		"2025-01-13 10:00 WET",
+		"2025-01-15 15:00 WET",
+		"2025-01-17 10:00 WET",
+	)
+}
+
+func TestParseBusyBlocksKeepsWallClockAcrossDST(t *testing.T) {
+	loc := loadZone(t, "Europe/Lisbon")
+
+	tests := []struct {
+		name   string
+		events []string
+		want   []string
+	}{
+		{
+			// Lisbon springs forward on 30 March 2025
+			name: "daily timed",
+			events: []string{
+				"DTSTART;TZID=Europe/Lisbon:20250329T090000",
+				"DURATION:PT2H",
+				"RRULE:FREQ=DAILY;COUNT=3",
+			},
+			want: []string{"2025-03-29 09:00 WET", "2025-03-30 09:00 WEST", "2025-03-31 09:00 WEST"},
+		},
+		{
+			name: "weekly byday with week start",
+			events: []string{
+				"DTSTART;TZID=Europe/Lisbon:20250324T090000",
+				"DURATION:PT1H",
+				"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU;WKST=MO;UNTIL=20250414T235959Z",
+			},
+			// The Sunday belongs to the Monday-based week of the first instance
+			want: []string{"2025-03-24 09:00 WET", "2025-03-30 09:00 WEST", "2025-04-07 09:00 WEST", "2025-04-13 09:00 WEST"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := append([]string{"BEGIN:VEVENT", "UID:e1"}, tt.events...)
+			ics := calendarOf(append(lines, "END:VEVENT")...)
+			blocks, err := ParseBusyBlocks(strings.NewReader(ics), loc,
+				time.Date(2025, 3, 1, 0, 0, 0, 0, loc), time.Date(2025, 5, 1, 0, 0, 0, 0, loc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStarts(t, blocks, loc, tt.want...)
+			for _, block := range blocks {
+				if block.End.Sub(block.Start) > 2*time.Hour {
+					t.Errorf("block at %s lasts %s", block.Start, block.End.Sub(block.Start))
+				}
+			}
+		})
+	}
+}
+
+func TestParseBusyBlocksAllDayEventCoversLocalDayAcrossDST(t *testing.T) {
+	loc := loadZone(t, "Europe/Lisbon")
+	ics := calendarOf("BEGIN:VEVENT", "UID:e1", "DTSTART;VALUE=DATE:20250330", "END:VEVENT")
+
+	blocks, err := ParseBusyBlocks(strings.NewReader(ics), loc,
+		time.Date(2025, 3, 29, 0, 0, 0, 0, loc), time.Date(2025, 4, 1, 0, 0, 0, 0, loc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 {
+		t.Fatalf("got %d blocks, want 1", len(blocks))
+	}
+	if got := blocks[0].End.Sub(blocks[0].Start); got != 23*time.Hour {
+		t.Errorf("all-day event on the spring-forward day lasts %s, want 23h", got)
+	}
+}
+
+func TestParseBusyBlocksRejectsUnsupportedRules(t *testing.T) {
+	loc := loadZone(t, "Europe/Lisbon")
+
+	tests := []struct {
+		name  string
+		rrule string
+	}{
+		{name: "byday on daily", rrule: "FREQ=DAILY;BYDAY=MO,WE"},
+		{name: "byday on monthly", rrule: "FREQ=MONTHLY;BYDAY=1MO"},
+		{name: "bymonthday", rrule: "FREQ=MONTHLY;BYMONTHDAY=15"},
+		{name: "bysetpos", rrule: "FREQ=WEEKLY;BYDAY=MO,TU;BYSETPOS=1"},
+		{name: "byhour", rrule: "FREQ=DAILY;BYHOUR=9,17"},
+		{name: "ordinal weekday", rrule: "FREQ=WEEKLY;BYDAY=-1FR"},
+		{name: "invalid week start", rrule: "FREQ=WEEKLY;WKST=XX"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ics := calendarOf("BEGIN:VEVENT", "UID:e1",
+				"DTSTART;TZID=Europe/Lisbon:20250113T100000", "DURATION:PT1H",
+				"RRULE:"+tt.rrule, "END:VEVENT")
+			_, err := ParseBusyBlocks(strings.NewReader(ics), loc,
+				time.Date(2025, 1, 1, 0, 0, 0, 0, loc), time.Date(2025, 3, 1, 0, 0, 0, 0, loc))
+			if !errors.Is(err, domain.ErrInvalidArgument) {
+				t.Fatalf("got error %v, want ErrInvalidArgument", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// CalendarRepository keeps feed tokens and imported busy blocks in the
+// tenant's database
+type CalendarRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewCalendarRepository creates a new CalendarRepository
+func NewCalendarRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *CalendarRepository {
+	return &CalendarRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+// eventColumns describe an appointment as a calendar event. The summary names
+// the client and, for project sessions, the project.
+const eventColumns = `a.id, a.start_time, a.end_time, COALESCE(a.updated_at, a.created_at),
+	c.full_name, COALESCE(p.title, ''), COALESCE(a.session_number, 0), COALESCE(a.notes, '')`
+
+const eventFrom = ` FROM appointments a
+	JOIN customers c ON c.id = a.customers_id
+	LEFT JOIN projects p ON p.id = a.project_id`
+
+const busyBlockColumns = `id, artist_id, source, COALESCE(uid, ''), COALESCE(summary, ''), starts_at, ends_at, created_at`
+
+// RotateFeedToken issues a new feed token for the artist
+func (r *CalendarRepository) RotateFeedToken(ctx context.Context, tenant, artistID string) (string, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return "", fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate feed token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	_, err = pool.Exec(ctx,
+		`INSERT INTO calendar_feeds (artist_id, token_hash) VALUES ($1, $2)
+		 ON CONFLICT (artist_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = now()`,
+		artistID, hashToken(token))
+	if err != nil {
+		return "", domain.WrapError("failed to store feed token", err)
+	}
+	return token, nil
+}
+
+// RevokeFeedToken stops the artist's feed
+func (r *CalendarRepository) RevokeFeedToken(ctx context.Context, tenant, artistID string) error {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	tag, err := pool.Exec(ctx, `DELETE FROM calendar_feeds WHERE artist_id = $1`, artistID)
+	if err != nil {
+		return domain.WrapError("failed to revoke feed token", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("calendar feed: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+// ArtistForFeedToken returns the artist whose feed the token opens
+func (r *CalendarRepository) ArtistForFeedToken(ctx context.Context, tenant, token string) (string, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return "", fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var artistID string
+	err = pool.QueryRow(ctx, `SELECT artist_id FROM calendar_feeds WHERE token_hash = $1`, hashToken(token)).Scan(&artistID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("calendar feed: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return "", domain.WrapError("failed to look up feed token", err)
+	}
+	return artistID, nil
+}
+
+// ArtistEvents returns the artist's scheduled and completed appointments that
+// touch [from, to)
+func (r *CalendarRepository) ArtistEvents(ctx context.Context, tenant, artistID string, from, to time.Time) ([]domain.CalendarEvent, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	rows, err := pool.Query(ctx, `SELECT `+eventColumns+eventFrom+`
+		 WHERE a.artist_id = $1 AND a.status IN ('SCHEDULED', 'COMPLETED')
+		   AND a.end_time > $2 AND a.start_time < $3
+		 ORDER BY a.start_time`, artistID, from, to)
+	if err != nil {
+		return nil, domain.WrapError("failed to list calendar events", err)
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.CalendarEvent, error) {
+		return scanEvent(row, tenant)
+	})
+	if err != nil {
+		return nil, domain.WrapError("failed to list calendar events", err)
+	}
+	return events, nil
+}
+
+// AppointmentEvent returns a single appointment as a calendar event
+func (r *CalendarRepository) AppointmentEvent(ctx context.Context, tenant, appointmentID string) (*domain.CalendarEvent, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	event, err := scanEvent(pool.QueryRow(ctx, `SELECT `+eventColumns+eventFrom+` WHERE a.id = $1`, appointmentID), tenant)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("appointment: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get appointment", err)
+	}
+	return &event, nil
+}
+
+// StudioTimeZone returns the time zone of the artist's studio
+func (r *CalendarRepository) StudioTimeZone(ctx context.Context, tenant, artistID string) (string, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return "", fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var timezone string
+	err = pool.QueryRow(ctx,
+		`SELECT COALESCE(ss.timezone, 'UTC')
+		 FROM users u LEFT JOIN studio_settings ss ON ss.studio_id = u.studio_id
+		 WHERE u.id = $1`, artistID).Scan(&timezone)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("artist: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return "", domain.WrapError("failed to get studio time zone", err)
+	}
+	return timezone, nil
+}
+
+// ReplaceBusyBlocks swaps the blocks of one source in a single transaction, so
+// the slot search never sees a half imported calendar
+func (r *CalendarRepository) ReplaceBusyBlocks(ctx context.Context, tenant, artistID, source string, blocks []domain.BusyBlock) ([]string, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var conflicts []string
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, artistID).Scan(&exists); err != nil {
+			return domain.WrapError("failed to get artist", err)
+		}
+		if !exists {
+			return fmt.Errorf("artist: %w", domain.ErrNotFound)
+		}
+
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM calendar_busy_blocks WHERE artist_id = $1 AND source = $2`, artistID, source); err != nil {
+			return domain.WrapError("failed to clear busy blocks", err)
+		}
+
+		if len(blocks) > 0 {
+			rows := make([][]any, 0, len(blocks))
+			for _, block := range blocks {
+				rows = append(rows, []any{artistID, source, block.UID, block.Summary, block.Start, block.End})
+			}
+			if _, err := tx.CopyFrom(ctx, pgx.Identifier{"calendar_busy_blocks"},
+				[]string{"artist_id", "source", "uid", "summary", "starts_at", "ends_at"},
+				pgx.CopyFromRows(rows)); err != nil {
+				return domain.WrapError("failed to store busy blocks", err)
+			}
+		}
+
+		rows, err := tx.Query(ctx,
+			`SELECT DISTINCT a.id::text, a.start_time FROM appointments a
+			 JOIN calendar_busy_blocks b ON b.artist_id = a.artist_id AND b.source = $2
+			 WHERE a.artist_id = $1 AND a.status = 'SCHEDULED' AND a.end_time > now()
+			   AND tstzrange(b.starts_at, b.ends_at, '[)') && tstzrange(a.start_time, a.end_time, '[)')
+			 ORDER BY a.start_time`, artistID, source)
+		if err != nil {
+			return domain.WrapError("failed to find conflicting appointments", err)
+		}
+		conflicts, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (string, error) {
+			var id string
+			var start time.Time
+			err := row.Scan(&id, &start)
+			return id, err
+		})
+		if err != nil {
+			return domain.WrapError("failed to find conflicting appointments", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conflicts, nil
+}
+
+// ListBusyBlocks returns the artist's busy blocks that touch [from, to)
+func (r *CalendarRepository) ListBusyBlocks(ctx context.Context, tenant, artistID string, from, to time.Time) ([]domain.BusyBlock, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	rows, err := pool.Query(ctx, `SELECT `+busyBlockColumns+` FROM calendar_busy_blocks
+		 WHERE artist_id = $1 AND ends_at > $2 AND starts_at < $3
+		 ORDER BY starts_at`, artistID, from, to)
+	if err != nil {
+		return nil, domain.WrapError("failed to list busy blocks", err)
+	}
+	blocks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.BusyBlock, error) {
+		var block domain.BusyBlock
+		err := row.Scan(&block.ID, &block.ArtistID, &block.Source, &block.UID, &block.Summary,
+			&block.Start, &block.End, &block.CreatedAt)
+		return block, err
+	})
+	if err != nil {
+		return nil, domain.WrapError("failed to list busy blocks", err)
+	}
+	return blocks, nil
+}
+
+// scanEvent reads eventColumns. The UID stays the same when the appointment
+// moves, so subscribed calendars update the event instead of adding one.
+func scanEvent(row pgx.Row, tenant string) (domain.CalendarEvent, error) {
+	var event domain.CalendarEvent
+	var id, customer, project, notes string
+	var session int
+	if err := row.Scan(&id, &event.Start, &event.End, &event.Updated, &customer, &project, &session, &notes); err != nil {
+		return event, err
+	}
+
+	event.UID = fmt.Sprintf("appointment-%s@%s", id, tenant)
+	event.Summary = "Tattoo: " + customer
+	if project != "" {
+		event.Summary = fmt.Sprintf("%s (session %d): %s", project, session, customer)
+	}
+	event.Description = notes
+	return event, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
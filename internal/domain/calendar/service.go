@@ -0,0 +1,313 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// CalendarServiceName is the fully qualified gRPC name of the calendar
+// service. Artists subscribe to their appointments from any calendar app and
+// import their private calendars so booking works around them.
+const CalendarServiceName = "inkMe.appointment.CalendarService"
+
+// ContentType is the media type of iCalendar files
+const ContentType = "text/calendar; charset=utf-8"
+
+const (
+	// maxImportBytes bounds the size of an imported calendar
+	maxImportBytes = 2 << 20
+	// maxBusyBlocks bounds the blocks one import may store
+	maxBusyBlocks = 5000
+	// importHorizon is how far ahead imported events block bookings
+	importHorizon = 365 * 24 * time.Hour
+)
+
+// managerRoles may manage the calendars of any artist of the studio
+var managerRoles = []string{"OWNER"}
+
+type ArtistRequest struct {
+	ArtistID string `json:"artist_id"`
+}
+
+type FeedTokenResponse struct {
+	// Token is shown once; only its hash is kept
+	Token string `json:"token"`
+	// FeedPath is the path of the feed on the HTTP server
+	FeedPath string `json:"feed_path"`
+}
+
+type ExportAppointmentRequest struct {
+	AppointmentID string `json:"appointment_id"`
+}
+
+type ExportAppointmentResponse struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"`
+}
+
+type ImportCalendarRequest struct {
+	ArtistID string `json:"artist_id"`
+	// Source names the calendar, e.g. "google"; importing it again replaces
+	// its previous blocks
+	Source string `json:"source"`
+	// Ics is the content of the .ics file
+	Ics string `json:"ics"`
+}
+
+type ImportCalendarResponse struct {
+	Imported int `json:"imported"`
+	// ConflictingAppointments are upcoming appointments that overlap an
+	// imported event; they are kept but cannot be moved into busy time
+	ConflictingAppointments []string `json:"conflicting_appointments"`
+}
+
+type ListBusyBlocksRequest struct {
+	ArtistID string `json:"artist_id"`
+	// From and To are RFC 3339 timestamps; they default to the next 30 days
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type BusyBlockOutput struct {
+	ID      string `json:"id"`
+	Source  string `json:"source"`
+	UID     string `json:"uid,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+type ListBusyBlocksResponse struct {
+	Blocks []BusyBlockOutput `json:"blocks"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// CalendarService implements the calendar gRPC service
+type CalendarService struct {
+	repo domain.CalendarRepository
+}
+
+// NewCalendarService creates a new CalendarService
+func NewCalendarService(repo domain.CalendarRepository) *CalendarService {
+	return &CalendarService{repo: repo}
+}
+
+// Register adds the service to a gRPC server
+func (s *CalendarService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(CalendarServiceName,
+		structrpc.Unary(CalendarServiceName, "CreateFeedToken", s.CreateFeedToken),
+		structrpc.Unary(CalendarServiceName, "RevokeFeedToken", s.RevokeFeedToken),
+		structrpc.Unary(CalendarServiceName, "ExportAppointment", s.ExportAppointment),
+		structrpc.Unary(CalendarServiceName, "ImportCalendar", s.ImportCalendar),
+		structrpc.Unary(CalendarServiceName, "ListBusyBlocks", s.ListBusyBlocks),
+	), s)
+}
+
+// CreateFeedToken issues the artist's feed URL. Calling it again rotates the
+// token and the old URL stops working.
+func (s *CalendarService) CreateFeedToken(ctx context.Context, req *ArtistRequest) (*FeedTokenResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "CreateFeedToken")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if err = requireArtistOrManager(ctx, req.ArtistID); err != nil {
+		return nil, err
+	}
+
+	token, err := s.repo.RotateFeedToken(ctx, tenant, req.ArtistID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to create feed token")
+	}
+
+	return &FeedTokenResponse{
+		Token:    token,
+		FeedPath: fmt.Sprintf("/calendar/%s/%s.ics", tenant, token),
+	}, nil
+}
+
+// RevokeFeedToken turns the artist's feed off
+func (s *CalendarService) RevokeFeedToken(ctx context.Context, req *ArtistRequest) (*MessageResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "RevokeFeedToken")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if err = requireArtistOrManager(ctx, req.ArtistID); err != nil {
+		return nil, err
+	}
+
+	if err = s.repo.RevokeFeedToken(ctx, tenant, req.ArtistID); err != nil {
+		return nil, domain.ToStatus(err, "failed to revoke feed token")
+	}
+
+	return &MessageResponse{Message: "Calendar feed revoked successfully"}, nil
+}
+
+// ExportAppointment returns an appointment as an .ics file to attach to
+// e-mails or open in a calendar app
+func (s *CalendarService) ExportAppointment(ctx context.Context, req *ExportAppointmentRequest) (*ExportAppointmentResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ExportAppointment")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.AppointmentID == "" {
+		return nil, status.Error(codes.InvalidArgument, "appointment_id is required")
+	}
+
+	event, err := s.repo.AppointmentEvent(ctx, tenant, req.AppointmentID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to export appointment")
+	}
+
+	var content bytes.Buffer
+	if err = WriteCalendar(&content, "", []domain.CalendarEvent{*event}); err != nil {
+		return nil, status.Error(codes.Internal, "failed to write calendar")
+	}
+
+	span.SetAttributes(attribute.String("appointment.id", req.AppointmentID))
+
+	return &ExportAppointmentResponse{
+		Filename:    "appointment-" + req.AppointmentID + ".ics",
+		ContentType: ContentType,
+		Content:     content.String(),
+	}, nil
+}
+
+// ImportCalendar stores the events of an .ics file as busy blocks of the
+// artist for the coming year. New appointments cannot overlap them; the ones
+// that already do are reported.
+func (s *CalendarService) ImportCalendar(ctx context.Context, req *ImportCalendarRequest) (*ImportCalendarResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ImportCalendar")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if err = requireArtistOrManager(ctx, req.ArtistID); err != nil {
+		return nil, err
+	}
+	source := strings.TrimSpace(req.Source)
+	if source == "" || len(source) > 100 {
+		return nil, status.Error(codes.InvalidArgument, "source is required and must be at most 100 characters")
+	}
+	if req.Ics == "" {
+		return nil, status.Error(codes.InvalidArgument, "ics is required")
+	}
+	if len(req.Ics) > maxImportBytes {
+		return nil, status.Errorf(codes.InvalidArgument, "ics must be at most %d bytes", maxImportBytes)
+	}
+
+	timezone, err := s.repo.StudioTimeZone(ctx, tenant, req.ArtistID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to import calendar")
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	now := time.Now()
+	blocks, err := ParseBusyBlocks(strings.NewReader(req.Ics), loc, now, now.Add(importHorizon))
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to parse calendar")
+	}
+	if len(blocks) > maxBusyBlocks {
+		return nil, status.Errorf(codes.InvalidArgument, "calendar has more than %d events in the coming year", maxBusyBlocks)
+	}
+
+	conflicts, err := s.repo.ReplaceBusyBlocks(ctx, tenant, req.ArtistID, source, blocks)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to import calendar")
+	}
+
+	span.SetAttributes(
+		attribute.String("artist.id", req.ArtistID),
+		attribute.Int("calendar.blocks", len(blocks)),
+		attribute.Int("calendar.conflicts", len(conflicts)),
+	)
+
+	if conflicts == nil {
+		conflicts = []string{}
+	}
+	return &ImportCalendarResponse{Imported: len(blocks), ConflictingAppointments: conflicts}, nil
+}
+
+// ListBusyBlocks returns the artist's imported busy time
+func (s *CalendarService) ListBusyBlocks(ctx context.Context, req *ListBusyBlocksRequest) (*ListBusyBlocksResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ListBusyBlocks")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ArtistID == "" {
+		return nil, status.Error(codes.InvalidArgument, "artist_id is required")
+	}
+	from, to := time.Now(), time.Now().AddDate(0, 0, 30)
+	if req.From != "" {
+		if from, err = time.Parse(time.RFC3339, req.From); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "from must be an RFC 3339 timestamp")
+		}
+	}
+	if req.To != "" {
+		if to, err = time.Parse(time.RFC3339, req.To); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "to must be an RFC 3339 timestamp")
+		}
+	}
+	if !to.After(from) {
+		return nil, status.Error(codes.InvalidArgument, "to must be after from")
+	}
+
+	blocks, err := s.repo.ListBusyBlocks(ctx, tenant, req.ArtistID, from, to)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list busy blocks")
+	}
+
+	res := &ListBusyBlocksResponse{Blocks: make([]BusyBlockOutput, 0, len(blocks))}
+	for _, block := range blocks {
+		res.Blocks = append(res.Blocks, BusyBlockOutput{
+			ID:      block.ID,
+			Source:  block.Source,
+			UID:     block.UID,
+			Summary: block.Summary,
+			Start:   block.Start.Format(time.RFC3339),
+			End:     block.End.Format(time.RFC3339),
+		})
+	}
+
+	span.SetAttributes(attribute.Int("calendar.blocks", len(res.Blocks)))
+
+	return res, nil
+}
+
+// requireArtistOrManager lets artists manage their own calendars and managers
+// everyone's
+func requireArtistOrManager(ctx context.Context, artistID string) error {
+	if artistID == "" {
+		return status.Error(codes.InvalidArgument, "artist_id is required")
+	}
+	if userID, err := domain.ExtractUserIDFromContext(ctx); err == nil && userID == artistID {
+		return nil
+	}
+	return domain.RequireRole(ctx, managerRoles...)
+}
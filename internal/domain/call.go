@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/middleware/grpcrequest"
+)
+
+// StartCall opens the span of an RPC and resolves its tenant. The returned
+// span must be ended by the caller when err is nil.
+func StartCall(ctx context.Context, method string) (context.Context, trace.Span, string, error) {
+	traceContext, span := otel.Tracer("SyncInk").Start(ctx, method)
+
+	requestID, ok := ctx.Value(grpcrequest.RequestIDKey{}).(string)
+	if !ok {
+		span.End()
+		return nil, nil, "", status.Error(codes.Internal, "request id not found in context")
+	}
+
+	tenant, err := ExtractTenantFromContext(traceContext)
+	if err != nil {
+		span.End()
+		return nil, nil, "", err
+	}
+
+	span.SetAttributes(
+		attribute.String("request.id", requestID),
+		attribute.String("tenant", tenant),
+	)
+	return traceContext, span, tenant, nil
+}
+
+// StartUserCall is StartCall for RPCs that need the caller, and also returns
+// the caller's user id
+func StartUserCall(ctx context.Context, method string) (context.Context, trace.Span, string, string, error) {
+	traceContext, span, tenant, err := StartCall(ctx, method)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+
+	userID, err := ExtractUserIDFromContext(traceContext)
+	if err != nil {
+		span.End()
+		return nil, nil, "", "", err
+	}
+
+	span.SetAttributes(attribute.String("user.id", userID))
+	return traceContext, span, tenant, userID, nil
+}
+
+// RequestIDFromContext returns the id given to the request by the request id
+// interceptor, or "" outside of an RPC
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(grpcrequest.RequestIDKey{}).(string)
+	return requestID
+}
+
+// TraceIDFromContext returns the trace id of the span started by StartCall
+func TraceIDFromContext(ctx context.Context) string {
+	return trace.SpanFromContext(ctx).SpanContext().TraceID().String()
+}
+
+// WrapError prefixes err with msg and maps Postgres constraint and input
+// errors to domain errors. Exclusion violations raised by triggers, such as an
+// external calendar or waitlist hold, carry their own message.
+func WrapError(msg string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23P01": // exclusion_violation
+			if pgErr.ConstraintName == "no_artist_double_booking" {
+				return fmt.Errorf("%s: %w: artist is already booked at that time", msg, ErrAlreadyExists)
+			}
+			return fmt.Errorf("%s: %w: %s", msg, ErrAlreadyExists, pgErr.Message)
+		case "23505": // unique_violation
+			return fmt.Errorf("%s: %w: %s", msg, ErrAlreadyExists, pgErr.Detail)
+		case "23503": // foreign_key_violation
+			return fmt.Errorf("%s: %w: %s", msg, ErrNotFound, pgErr.Detail)
+		case "23502", "23514": // not_null_violation, check_violation
+			return fmt.Errorf("%s: %w: %s", msg, ErrInvalidArgument, pgErr.Message)
+		case "22P02", "22007", "22008": // malformed UUID or timestamp
+			return fmt.Errorf("%s: %w: %s", msg, ErrInvalidArgument, pgErr.Message)
+		}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
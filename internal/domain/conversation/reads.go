@@ -0,0 +1,308 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+const (
+	// unreadCacheTTL bounds how long a cached count can drift from Postgres
+	// when a change races with a rebuild
+	unreadCacheTTL = time.Hour
+	// unreadBuilt is a field every cached hash has, so a user without unread
+	// messages still has a cache entry
+	unreadBuilt = "_"
+)
+
+// incrUnreadScript counts one more unread message, but only in a cache that
+// has been built: a partial hash would hide the other conversations' counts
+var incrUnreadScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("HINCRBY", KEYS[1], ARGV[1], 1)
+end
+return 0`)
+
+// setUnreadScript overwrites a conversation's count in a built cache
+var setUnreadScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+end
+return 0`)
+
+const readMarkerColumns = `conversation_id::text, user_id::text, COALESCE(last_read_message_id::text, ''), read_up_to, read_at`
+
+// unreadQuery counts, per conversation of $1, the messages others sent after
+// the user's read marker
+const unreadQuery = `SELECT p.conversation_id::text, count(*)
+	FROM conversation_participants p
+	JOIN messages m ON m.conversation_id = p.conversation_id
+		AND m.created_at > COALESCE(p.read_up_to, '-infinity')
+		AND m.sender_user_id IS DISTINCT FROM p.user_id
+	WHERE p.user_id = $1`
+
+func unreadKey(tenant, userID string) string {
+	return fmt.Sprintf("conversations:unread:%s:%s", tenant, userID)
+}
+
+// MarkRead moves the participant's read marker forward. Marking an older
+// message than the current marker leaves it where it is.
+func (r *ConversationRepository) MarkRead(ctx context.Context, tenant, conversationID, userID, messageID string) (*domain.ReadMarker, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var marker *domain.ReadMarker
+	var participants []string
+	var unread int
+	moved := false
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		var err error
+		marker, err = scanReadMarker(tx.QueryRow(ctx,
+			"SELECT "+readMarkerColumns+" FROM conversation_participants WHERE conversation_id = $1 AND user_id = $2 FOR UPDATE",
+			conversationID, userID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: not a participant of conversation %s", domain.ErrFailedPrecondition, conversationID)
+		}
+		if err != nil {
+			return domain.WrapError("failed to get read marker", err)
+		}
+
+		var targetID string
+		var targetAt time.Time
+		if messageID != "" {
+			err = tx.QueryRow(ctx, "SELECT id::text, created_at FROM messages WHERE id = $1 AND conversation_id = $2",
+				messageID, conversationID).Scan(&targetID, &targetAt)
+		} else {
+			err = tx.QueryRow(ctx,
+				"SELECT id::text, created_at FROM messages WHERE conversation_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1",
+				conversationID).Scan(&targetID, &targetAt)
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			if messageID != "" {
+				return fmt.Errorf("message %s: %w", messageID, domain.ErrNotFound)
+			}
+			// Nothing to read yet
+			return nil
+		}
+		if err != nil {
+			return domain.WrapError("failed to get message", err)
+		}
+		if marker.UpTo != nil && !targetAt.After(*marker.UpTo) {
+			return nil
+		}
+
+		marker, err = scanReadMarker(tx.QueryRow(ctx,
+			`UPDATE conversation_participants
+			 SET last_read_message_id = $3, read_up_to = $4, read_at = now()
+			 WHERE conversation_id = $1 AND user_id = $2
+			 RETURNING `+readMarkerColumns,
+			conversationID, userID, targetID, targetAt))
+		if err != nil {
+			return domain.WrapError("failed to update read marker", err)
+		}
+		if err = tx.QueryRow(ctx, unreadQuery+" AND p.conversation_id = $2 GROUP BY p.conversation_id", userID, conversationID).Scan(new(string), &unread); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return domain.WrapError("failed to count unread messages", err)
+		}
+		if err = tx.QueryRow(ctx,
+			"SELECT array_agg(user_id::text) FROM conversation_participants WHERE conversation_id = $1",
+			conversationID).Scan(&participants); err != nil {
+			return domain.WrapError("failed to get participants", err)
+		}
+		moved = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if moved {
+		r.setUnread(ctx, tenant, userID, conversationID, unread)
+		r.publish(ctx, tenant, participants, domain.ConversationEvent{
+			Kind:           domain.ConversationEventRead,
+			ConversationID: conversationID,
+			UserID:         userID,
+			Read:           marker,
+		})
+	}
+	return marker, nil
+}
+
+// ReadMarkers returns the read marker of every participant of a conversation
+func (r *ConversationRepository) ReadMarkers(ctx context.Context, tenant, conversationID string) ([]domain.ReadMarker, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	rows, err := pool.Query(ctx,
+		"SELECT "+readMarkerColumns+" FROM conversation_participants WHERE conversation_id = $1 ORDER BY user_id",
+		conversationID)
+	if err != nil {
+		return nil, domain.WrapError("failed to query read markers", err)
+	}
+	markers, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.ReadMarker, error) {
+		marker, err := scanReadMarker(row)
+		if err != nil {
+			return domain.ReadMarker{}, err
+		}
+		return *marker, nil
+	})
+	if err != nil {
+		return nil, domain.WrapError("failed to query read markers", err)
+	}
+	return markers, nil
+}
+
+// UnreadCounts reads the user's counts from Redis, rebuilding them from
+// Postgres when they are not cached. Without Redis the counts come straight
+// from Postgres.
+func (r *ConversationRepository) UnreadCounts(ctx context.Context, tenant, userID string) (map[string]int, error) {
+	client, err := r.RedisManager.GetTenantRedis(tenant)
+	if err == nil {
+		var cached map[string]string
+		cached, err = client.HGetAll(ctx, unreadKey(tenant, userID)).Result()
+		if err == nil && len(cached) > 0 {
+			counts := make(map[string]int, len(cached))
+			for conversationID, value := range cached {
+				n, err := strconv.Atoi(value)
+				if conversationID == unreadBuilt || err != nil || n <= 0 {
+					continue
+				}
+				counts[conversationID] = n
+			}
+			return counts, nil
+		}
+	}
+	if err != nil {
+		logger.Log.Warn("failed to read cached unread counts",
+			zap.String("tenant", tenant),
+			zap.String("user_id", userID),
+			zap.Error(err))
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+	rows, err := pool.Query(ctx, unreadQuery+" GROUP BY p.conversation_id", userID)
+	if err != nil {
+		return nil, domain.WrapError("failed to count unread messages", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var conversationID string
+		var n int
+		if err = rows.Scan(&conversationID, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan unread count: %w", err)
+		}
+		counts[conversationID] = n
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to count unread messages", err)
+	}
+
+	if client != nil {
+		r.cacheUnread(ctx, client, tenant, userID, counts)
+	}
+	return counts, nil
+}
+
+// Typing tells the conversation's other participants that userID is typing
+func (r *ConversationRepository) Typing(ctx context.Context, tenant, conversationID, userID string) error {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var others []string
+	err = pool.QueryRow(ctx,
+		`SELECT COALESCE(array_agg(user_id::text) FILTER (WHERE user_id <> $2), '{}')
+		 FROM conversation_participants WHERE conversation_id = $1`,
+		conversationID, userID).Scan(&others)
+	if err != nil {
+		return domain.WrapError("failed to get participants", err)
+	}
+
+	r.publish(ctx, tenant, others, domain.ConversationEvent{
+		Kind:           domain.ConversationEventTyping,
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	return nil
+}
+
+// cacheUnread replaces the user's cached counts. Caching is best effort.
+func (r *ConversationRepository) cacheUnread(ctx context.Context, client *redis.Client, tenant, userID string, counts map[string]int) {
+	key := unreadKey(tenant, userID)
+	values := []interface{}{unreadBuilt, 0}
+	for conversationID, n := range counts {
+		values = append(values, conversationID, n)
+	}
+
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, values...)
+		pipe.Expire(ctx, key, unreadCacheTTL)
+		return nil
+	})
+	if err != nil {
+		logger.Log.Warn("failed to cache unread counts",
+			zap.String("tenant", tenant),
+			zap.String("user_id", userID),
+			zap.Error(err))
+	}
+}
+
+// incrUnread counts a new message in the user's cached counts. A failure
+// drops the cache so the next read rebuilds it.
+func (r *ConversationRepository) incrUnread(ctx context.Context, tenant, userID, conversationID string) {
+	r.updateUnread(ctx, tenant, userID, func(client *redis.Client, key string) error {
+		return incrUnreadScript.Run(ctx, client, []string{key}, conversationID).Err()
+	})
+}
+
+// setUnread overwrites a conversation's count in the user's cached counts
+func (r *ConversationRepository) setUnread(ctx context.Context, tenant, userID, conversationID string, n int) {
+	r.updateUnread(ctx, tenant, userID, func(client *redis.Client, key string) error {
+		return setUnreadScript.Run(ctx, client, []string{key}, conversationID, n).Err()
+	})
+}
+
+func (r *ConversationRepository) updateUnread(ctx context.Context, tenant, userID string, update func(*redis.Client, string) error) {
+	client, err := r.RedisManager.GetTenantRedis(tenant)
+	if err != nil {
+		return
+	}
+	key := unreadKey(tenant, userID)
+	if err = update(client, key); err == nil {
+		return
+	}
+	if delErr := client.Del(ctx, key).Err(); delErr != nil {
+		logger.Log.Warn("failed to update unread counts",
+			zap.String("tenant", tenant),
+			zap.String("user_id", userID),
+			zap.Error(errors.Join(err, delErr)))
+	}
+}
+
+func scanReadMarker(row pgx.Row) (*domain.ReadMarker, error) {
+	var marker domain.ReadMarker
+	err := row.Scan(&marker.ConversationID, &marker.UserID, &marker.MessageID, &marker.UpTo, &marker.ReadAt)
+	if err != nil {
+		return nil, err
+	}
+	return &marker, nil
+}
@@ -0,0 +1,509 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+// ConversationRepository keeps conversations and their messages in the
+// tenant's database and pushes new messages to the participants over a Redis
+// channel per user, so every replica can serve their live streams
+type ConversationRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewConversationRepository creates a new ConversationRepository
+func NewConversationRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *ConversationRepository {
+	return &ConversationRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+const conversationColumns = `c.id, c.studio_id, c.customers_id, COALESCE(c.subject, ''),
+	COALESCE((SELECT array_agg(p.user_id::text ORDER BY p.user_id)
+		FROM conversation_participants p WHERE p.conversation_id = c.id), '{}'),
+	lm.id, COALESCE(lm.sender_user_id::text, ''), COALESCE(lm.sender_customer_id::text, ''),
+	COALESCE(lm.content, ''), lm.created_at,
+	c.created_at, c.updated_at`
+
+// conversationFrom joins each conversation with its newest message
+const conversationFrom = ` FROM conversations c
+	LEFT JOIN LATERAL (
+		SELECT m.id, m.sender_user_id, m.sender_customer_id, m.content, m.created_at
+		FROM messages m WHERE m.conversation_id = c.id
+		ORDER BY m.created_at DESC, m.id DESC LIMIT 1
+	) lm ON TRUE`
+
+const messageColumns = `id, conversation_id, COALESCE(sender_user_id::text, ''),
+	COALESCE(sender_customer_id::text, ''), COALESCE(content, ''), created_at`
+
+const attachmentColumns = `id, storage_key, filename, content_type, size_bytes, COALESCE(uploaded_by::text, ''),
+	COALESCE(message_id::text, ''), created_at`
+
+func channel(tenant, userID string) string {
+	return fmt.Sprintf("conversations:%s:%s", tenant, userID)
+}
+
+// Create stores a conversation with a customer of the studio. Participants
+// must belong to the studio's staff.
+func (r *ConversationRepository) Create(ctx context.Context, tenant string, conversation *domain.Conversation) error {
+	if conversation == nil {
+		return fmt.Errorf("%w: conversation is required", domain.ErrInvalidArgument)
+	}
+	if conversation.CustomerID == "" {
+		return fmt.Errorf("%w: customer is required", domain.ErrInvalidArgument)
+	}
+	participants := uniqueIDs(conversation.ParticipantIDs)
+	if len(participants) == 0 {
+		return fmt.Errorf("%w: at least one participant is required", domain.ErrInvalidArgument)
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		studioID := conversation.StudioID
+		if studioID == "" {
+			err := tx.QueryRow(ctx, "SELECT id FROM studios WHERE subdomain = $1", tenant).Scan(&studioID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("studio: %w", domain.ErrNotFound)
+			}
+			if err != nil {
+				return domain.WrapError("failed to find studio", err)
+			}
+		}
+
+		var customerFound bool
+		if err := tx.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM customers WHERE id = $1 AND studio_id = $2)",
+			conversation.CustomerID, studioID).Scan(&customerFound); err != nil {
+			return domain.WrapError("failed to check customer", err)
+		}
+		if !customerFound {
+			return fmt.Errorf("customer %s of studio %s: %w", conversation.CustomerID, studioID, domain.ErrNotFound)
+		}
+
+		var staff int
+		if err := tx.QueryRow(ctx,
+			`SELECT count(*) FROM users u
+			 WHERE u.id = ANY($1::uuid[])
+			   AND (u.studio_id = $2 OR EXISTS (
+				SELECT 1 FROM studio_staff s WHERE s.user_id = u.id AND s.studio_id = $2))`,
+			participants, studioID).Scan(&staff); err != nil {
+			return domain.WrapError("failed to check participants", err)
+		}
+		if staff != len(participants) {
+			return fmt.Errorf("participants must be on the studio staff: %w", domain.ErrNotFound)
+		}
+
+		var id string
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO conversations (studio_id, customers_id, subject)
+			 VALUES ($1, $2, NULLIF($3, '')) RETURNING id`,
+			studioID, conversation.CustomerID, conversation.Subject).Scan(&id); err != nil {
+			return domain.WrapError("failed to create conversation", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO conversation_participants (conversation_id, user_id)
+			 SELECT $1, unnest($2::uuid[])`, id, participants); err != nil {
+			return domain.WrapError("failed to add participants", err)
+		}
+
+		created, err := scanConversation(tx.QueryRow(ctx,
+			"SELECT "+conversationColumns+conversationFrom+" WHERE c.id = $1", id))
+		if err != nil {
+			return domain.WrapError("failed to get conversation", err)
+		}
+		*conversation = *created
+		return nil
+	})
+}
+
+func (r *ConversationRepository) GetByID(ctx context.Context, tenant, id string) (*domain.Conversation, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	conversation, err := scanConversation(pool.QueryRow(ctx,
+		"SELECT "+conversationColumns+conversationFrom+" WHERE c.id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("conversation %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get conversation", err)
+	}
+	return conversation, nil
+}
+
+// List pages through conversations matching the filter, most recently active
+// first
+func (r *ConversationRepository) List(ctx context.Context, tenant string, filter domain.ConversationFilter) (domain.PagedResult[domain.Conversation], error) {
+	return r.query(ctx, tenant, filter, false)
+}
+
+// NeedsReply pages through the conversations matching the filter that wait
+// on the studio, the one waiting longest first
+func (r *ConversationRepository) NeedsReply(ctx context.Context, tenant string, filter domain.ConversationFilter) (domain.PagedResult[domain.Conversation], error) {
+	return r.query(ctx, tenant, filter, true)
+}
+
+func (r *ConversationRepository) query(ctx context.Context, tenant string, filter domain.ConversationFilter, needsReply bool) (domain.PagedResult[domain.Conversation], error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = domain.DefaultPageSize
+	}
+	result := domain.PagedResult[domain.Conversation]{Items: []domain.Conversation{}, Page: filter.Page, PageSize: filter.PageSize}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return result, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	where := []string{"TRUE"}
+	var args []interface{}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if filter.UserID != "" {
+		add("EXISTS (SELECT 1 FROM conversation_participants p WHERE p.conversation_id = c.id AND p.user_id = $%d)", filter.UserID)
+	}
+	if filter.CustomerID != "" {
+		add("c.customers_id = $%d", filter.CustomerID)
+	}
+	if filter.StudioID != "" {
+		add("c.studio_id = $%d", filter.StudioID)
+	}
+	order := "COALESCE(c.updated_at, c.created_at) DESC, c.id"
+	if needsReply {
+		where = append(where, "lm.sender_customer_id IS NOT NULL")
+		order = "lm.created_at, c.id"
+	}
+	whereClause := strings.Join(where, " AND ")
+
+	if err = pool.QueryRow(ctx, "SELECT COUNT(*)"+conversationFrom+" WHERE "+whereClause, args...).Scan(&result.TotalCount); err != nil {
+		return result, domain.WrapError("failed to count conversations", err)
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	rows, err := pool.Query(ctx,
+		"SELECT "+conversationColumns+conversationFrom+" WHERE "+whereClause+
+			fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", order, len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return result, domain.WrapError("failed to query conversations", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		conversation, err := scanConversation(rows)
+		if err != nil {
+			return result, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		result.Items = append(result.Items, *conversation)
+	}
+	if err = rows.Err(); err != nil {
+		return result, domain.WrapError("failed to query conversations", err)
+	}
+
+	return result, nil
+}
+
+// AddMessage stores a message, marks its conversation as active and pushes
+// the message to every participant. A customer can only write in their own
+// conversation. The message's attachments must not belong to anything yet.
+func (r *ConversationRepository) AddMessage(ctx context.Context, tenant string, message *domain.Message) error {
+	if message == nil {
+		return fmt.Errorf("%w: message is required", domain.ErrInvalidArgument)
+	}
+	if (message.SenderUserID == "") == (message.SenderCustomerID == "") {
+		return fmt.Errorf("%w: a message needs exactly one sender", domain.ErrInvalidArgument)
+	}
+	if strings.TrimSpace(message.Content) == "" && len(message.Attachments) == 0 {
+		return fmt.Errorf("%w: content or an attachment is required", domain.ErrInvalidArgument)
+	}
+	attachmentIDs := make([]string, 0, len(message.Attachments))
+	for _, attachment := range message.Attachments {
+		attachmentIDs = append(attachmentIDs, attachment.ID)
+	}
+	attachmentIDs = uniqueIDs(attachmentIDs)
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var participants []string
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		var customerID string
+		err := tx.QueryRow(ctx,
+			`UPDATE conversations c SET updated_at = now() WHERE c.id = $1
+			 RETURNING c.customers_id::text,
+				COALESCE((SELECT array_agg(p.user_id::text) FROM conversation_participants p
+					WHERE p.conversation_id = c.id), '{}')`,
+			message.ConversationID).Scan(&customerID, &participants)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("conversation %s: %w", message.ConversationID, domain.ErrNotFound)
+		}
+		if err != nil {
+			return domain.WrapError("failed to update conversation", err)
+		}
+		if message.SenderCustomerID != "" && message.SenderCustomerID != customerID {
+			return fmt.Errorf("%w: customer is not part of the conversation", domain.ErrInvalidArgument)
+		}
+
+		created, err := scanMessage(tx.QueryRow(ctx,
+			`INSERT INTO messages (conversation_id, sender_user_id, sender_customer_id, content)
+			 VALUES ($1, NULLIF($2, '')::uuid, NULLIF($3, '')::uuid, NULLIF($4, ''))
+			 RETURNING `+messageColumns,
+			message.ConversationID, message.SenderUserID, message.SenderCustomerID, message.Content))
+		if err != nil {
+			return domain.WrapError("failed to store message", err)
+		}
+		*message = *created
+
+		if len(attachmentIDs) > 0 {
+			rows, err := tx.Query(ctx,
+				`UPDATE attachments SET message_id = $1
+				 WHERE id = ANY($2::uuid[]) AND message_id IS NULL AND portfolio_item_id IS NULL
+				 RETURNING `+attachmentColumns,
+				message.ID, attachmentIDs)
+			if err != nil {
+				return domain.WrapError("failed to attach files", err)
+			}
+			attachments, err := pgx.CollectRows(rows, collectAttachment)
+			if err != nil {
+				return domain.WrapError("failed to attach files", err)
+			}
+			if len(attachments) != len(attachmentIDs) {
+				return fmt.Errorf("%w: attachments must exist and not be attached elsewhere", domain.ErrFailedPrecondition)
+			}
+			slices.SortFunc(attachments, func(a, b domain.Attachment) int {
+				return a.CreatedAt.Compare(b.CreatedAt)
+			})
+			message.Attachments = attachments
+		}
+
+		// Whoever writes has read the conversation up to their message
+		if message.SenderUserID != "" {
+			if _, err := tx.Exec(ctx,
+				`UPDATE conversation_participants
+				 SET last_read_message_id = $3, read_up_to = $4, read_at = $4
+				 WHERE conversation_id = $1 AND user_id = $2`,
+				message.ConversationID, message.SenderUserID, message.ID, message.CreatedAt); err != nil {
+				return domain.WrapError("failed to update read marker", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range participants {
+		if userID == message.SenderUserID {
+			r.setUnread(ctx, tenant, userID, message.ConversationID, 0)
+		} else {
+			r.incrUnread(ctx, tenant, userID, message.ConversationID)
+		}
+	}
+	r.publish(ctx, tenant, participants, domain.ConversationEvent{
+		Kind:           domain.ConversationEventMessage,
+		ConversationID: message.ConversationID,
+		Message:        message,
+	})
+	return nil
+}
+
+// ListMessages returns up to limit messages sent before before, newest first
+func (r *ConversationRepository) ListMessages(ctx context.Context, tenant, conversationID string, before time.Time, limit int) ([]domain.Message, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	rows, err := pool.Query(ctx,
+		`SELECT `+messageColumns+` FROM messages
+		 WHERE conversation_id = $1 AND created_at < $2
+		 ORDER BY created_at DESC, id DESC LIMIT $3`, conversationID, before, limit)
+	if err != nil {
+		return nil, domain.WrapError("failed to query messages", err)
+	}
+	messages, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Message, error) {
+		message, err := scanMessage(row)
+		if err != nil {
+			return domain.Message{}, err
+		}
+		return *message, nil
+	})
+	if err != nil {
+		return nil, domain.WrapError("failed to query messages", err)
+	}
+	if len(messages) == 0 {
+		return messages, nil
+	}
+
+	ids := make([]string, 0, len(messages))
+	byID := make(map[string]*domain.Message, len(messages))
+	for i := range messages {
+		ids = append(ids, messages[i].ID)
+		byID[messages[i].ID] = &messages[i]
+	}
+	rows, err = pool.Query(ctx,
+		`SELECT `+attachmentColumns+` FROM attachments
+		 WHERE message_id = ANY($1::uuid[]) ORDER BY created_at, id`, ids)
+	if err != nil {
+		return nil, domain.WrapError("failed to query attachments", err)
+	}
+	attachments, err := pgx.CollectRows(rows, collectAttachment)
+	if err != nil {
+		return nil, domain.WrapError("failed to query attachments", err)
+	}
+	for _, attachment := range attachments {
+		if message, ok := byID[attachment.MessageID]; ok {
+			message.Attachments = append(message.Attachments, attachment)
+		}
+	}
+	return messages, nil
+}
+
+// Listen subscribes to the user's Redis channel. The returned channel is
+// closed once ctx is done or the subscription fails.
+func (r *ConversationRepository) Listen(ctx context.Context, tenant, userID string) (<-chan domain.ConversationEvent, error) {
+	client, err := r.RedisManager.GetTenantRedis(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	sub := client.Subscribe(ctx, channel(tenant, userID))
+	// Wait for the confirmation so nothing published after Listen returns is missed
+	if _, err = sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, fmt.Errorf("failed to subscribe to conversations: %w", err)
+	}
+
+	out := make(chan domain.ConversationEvent)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+
+		messages := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event domain.ConversationEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					continue
+				}
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// publish pushes an event to the participants' live streams. The change is
+// already committed and can be read back, so failures are only logged.
+func (r *ConversationRepository) publish(ctx context.Context, tenant string, userIDs []string, event domain.ConversationEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.Log.Warn("failed to encode conversation event", zap.Error(err))
+		return
+	}
+	client, err := r.RedisManager.GetTenantRedis(tenant)
+	if err != nil {
+		logger.Log.Warn("failed to publish conversation event",
+			zap.String("tenant", tenant),
+			zap.String("conversation_id", event.ConversationID),
+			zap.Error(err))
+		return
+	}
+	for _, userID := range userIDs {
+		if err := client.Publish(ctx, channel(tenant, userID), payload).Err(); err != nil {
+			logger.Log.Warn("failed to publish conversation event",
+				zap.String("tenant", tenant),
+				zap.String("conversation_id", event.ConversationID),
+				zap.String("user_id", userID),
+				zap.Error(err))
+		}
+	}
+}
+
+// uniqueIDs drops empty and repeated ids, keeping the first occurrence
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
+
+func scanConversation(row pgx.Row) (*domain.Conversation, error) {
+	var conversation domain.Conversation
+	var last domain.Message
+	var lastID *string
+	var lastAt *time.Time
+	err := row.Scan(&conversation.ID, &conversation.StudioID, &conversation.CustomerID, &conversation.Subject,
+		&conversation.ParticipantIDs,
+		&lastID, &last.SenderUserID, &last.SenderCustomerID, &last.Content, &lastAt,
+		&conversation.CreatedAt, &conversation.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastID != nil && lastAt != nil {
+		last.ID, last.ConversationID, last.CreatedAt = *lastID, conversation.ID, *lastAt
+		conversation.LastMessage = &last
+	}
+	return &conversation, nil
+}
+
+func scanMessage(row pgx.Row) (*domain.Message, error) {
+	var message domain.Message
+	err := row.Scan(&message.ID, &message.ConversationID, &message.SenderUserID,
+		&message.SenderCustomerID, &message.Content, &message.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func collectAttachment(row pgx.CollectableRow) (domain.Attachment, error) {
+	var attachment domain.Attachment
+	err := row.Scan(&attachment.ID, &attachment.Key, &attachment.Filename, &attachment.ContentType, &attachment.Size,
+		&attachment.UploadedBy, &attachment.MessageID, &attachment.CreatedAt)
+	return attachment, err
+}
@@ -0,0 +1,675 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/attachment"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// ConversationServiceName is the fully qualified gRPC name of the conversation
+// service
+const ConversationServiceName = "inkMe.conversation.ConversationService"
+
+const (
+	// maxMessageLength caps a message, in characters
+	maxMessageLength = 4000
+	// defaultMessagePage is how many messages ListMessages returns by default
+	defaultMessagePage = 50
+	// maxAttachments caps the files sent with one message
+	maxAttachments = 10
+)
+
+// Chat event kinds only sent to the stream that made the request
+const (
+	chatEventSent  = "SENT"
+	chatEventError = "ERROR"
+)
+
+// Kinds of ChatRequest
+const (
+	chatSend   = "SEND"
+	chatTyping = "TYPING"
+	chatRead   = "READ"
+)
+
+// managerRoles may read and write in every conversation of the tenant
+var managerRoles = []string{"OWNER"}
+
+type CreateConversationRequest struct {
+	StudioID   string `json:"studio_id"`
+	CustomerID string `json:"customer_id"`
+	Subject    string `json:"subject"`
+	// ParticipantIDs are the staff taking part besides the caller
+	ParticipantIDs []string `json:"participant_ids"`
+}
+
+type ConversationID struct {
+	ID string `json:"id"`
+}
+
+type ConversationOutput struct {
+	ID             string         `json:"id"`
+	StudioID       string         `json:"studio_id"`
+	CustomerID     string         `json:"customer_id"`
+	Subject        string         `json:"subject,omitempty"`
+	ParticipantIDs []string       `json:"participant_ids"`
+	LastMessage    *MessageOutput `json:"last_message,omitempty"`
+	// Unread is how many messages from others the caller has not read
+	Unread    int    `json:"unread"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+type ListConversationsRequest struct {
+	StudioID   string `json:"studio_id"`
+	CustomerID string `json:"customer_id"`
+	// All lists the conversations of every participant; managers only
+	All      bool `json:"all"`
+	Page     int  `json:"page"`
+	PageSize int  `json:"page_size"`
+}
+
+type ListConversationsResponse struct {
+	Conversations []ConversationOutput `json:"conversations"`
+	TotalCount    int64                `json:"total_count"`
+}
+
+type SendMessageRequest struct {
+	ConversationID string `json:"conversation_id"`
+	Content        string `json:"content"`
+	// FromCustomer records a message the customer sent through another
+	// channel, e.g. by text or at the front desk, as written by them
+	FromCustomer bool `json:"from_customer"`
+	// AttachmentIDs are files the caller uploaded with UploadAttachment that
+	// are not attached to anything yet
+	AttachmentIDs []string `json:"attachment_ids"`
+}
+
+type MessageOutput struct {
+	ID               string `json:"id"`
+	ConversationID   string `json:"conversation_id"`
+	SenderUserID     string `json:"sender_user_id,omitempty"`
+	SenderCustomerID string `json:"sender_customer_id,omitempty"`
+	Content          string `json:"content"`
+	// Attachments carry download URLs that expire; list the messages again
+	// for fresh ones
+	Attachments []attachment.AttachmentOutput `json:"attachments,omitempty"`
+	CreatedAt   string                        `json:"created_at"`
+}
+
+type ListMessagesRequest struct {
+	ConversationID string `json:"conversation_id"`
+	// Before is the RFC 3339 time to page back from; empty starts at the
+	// newest message
+	Before string `json:"before"`
+	Limit  int    `json:"limit"`
+}
+
+type ListMessagesResponse struct {
+	Messages []MessageOutput `json:"messages"`
+}
+
+type MarkReadRequest struct {
+	ConversationID string `json:"conversation_id"`
+	// MessageID is the last message read; empty marks the whole conversation
+	MessageID string `json:"message_id"`
+}
+
+type ReadMarkerOutput struct {
+	ConversationID string `json:"conversation_id"`
+	UserID         string `json:"user_id"`
+	MessageID      string `json:"message_id,omitempty"`
+	ReadAt         string `json:"read_at,omitempty"`
+}
+
+type ReadMarkersResponse struct {
+	Markers []ReadMarkerOutput `json:"markers"`
+}
+
+type UnreadCountsRequest struct{}
+
+type UnreadCountsResponse struct {
+	Total int `json:"total"`
+	// Conversations maps conversation ids to their unread count; read ones
+	// are left out
+	Conversations map[string]int `json:"conversations"`
+}
+
+// ChatRequest is sent on a Chat stream. Kind SEND, the default, sends a
+// message; TYPING tells the other participants the caller is typing and READ
+// marks the conversation read up to message_id. ClientID is echoed back on
+// the SENT or ERROR event answering it.
+type ChatRequest struct {
+	Kind      string `json:"kind"`
+	ClientID  string `json:"client_id"`
+	MessageID string `json:"message_id"`
+	SendMessageRequest
+}
+
+// ChatEvent is pushed on Chat streams. MESSAGE, READ and TYPING events come
+// from any of the caller's conversations; SENT and ERROR answer the stream's
+// own requests. UserID is who read or is typing.
+type ChatEvent struct {
+	Kind           string            `json:"kind"`
+	ConversationID string            `json:"conversation_id,omitempty"`
+	ClientID       string            `json:"client_id,omitempty"`
+	UserID         string            `json:"user_id,omitempty"`
+	Message        *MessageOutput    `json:"message,omitempty"`
+	Read           *ReadMarkerOutput `json:"read,omitempty"`
+	Error          string            `json:"error,omitempty"`
+}
+
+// ConversationService implements the conversation gRPC service. Staff see the
+// conversations they take part in; managers see all of them.
+type ConversationService struct {
+	repo        domain.ConversationRepository
+	attachments domain.AttachmentRepository
+	urls        domain.AttachmentURLSigner
+}
+
+// NewConversationService creates a new ConversationService
+func NewConversationService(repo domain.ConversationRepository, attachments domain.AttachmentRepository, urls domain.AttachmentURLSigner) *ConversationService {
+	return &ConversationService{repo: repo, attachments: attachments, urls: urls}
+}
+
+// Register adds the service to a gRPC server
+func (s *ConversationService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(ConversationServiceName,
+		structrpc.Unary(ConversationServiceName, "CreateConversation", s.CreateConversation),
+		structrpc.Unary(ConversationServiceName, "GetConversation", s.GetConversation),
+		structrpc.Unary(ConversationServiceName, "ListConversations", s.ListConversations),
+		structrpc.Unary(ConversationServiceName, "SendMessage", s.SendMessage),
+		structrpc.Unary(ConversationServiceName, "ListMessages", s.ListMessages),
+		structrpc.Unary(ConversationServiceName, "MarkRead", s.MarkRead),
+		structrpc.Unary(ConversationServiceName, "ListReadMarkers", s.ListReadMarkers),
+		structrpc.Unary(ConversationServiceName, "GetUnreadCounts", s.GetUnreadCounts),
+		structrpc.Unary(ConversationServiceName, "ListNeedsReply", s.ListNeedsReply),
+		structrpc.BidiStream("Chat", s.Chat),
+	), s)
+}
+
+// CreateConversation opens a conversation with a customer. The caller always
+// takes part in it.
+func (s *ConversationService) CreateConversation(ctx context.Context, req *CreateConversationRequest) (*ConversationOutput, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "CreateConversation")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.CustomerID == "" {
+		return nil, status.Error(codes.InvalidArgument, "customer_id is required")
+	}
+
+	conversation := &domain.Conversation{
+		StudioID:       req.StudioID,
+		CustomerID:     req.CustomerID,
+		Subject:        strings.TrimSpace(req.Subject),
+		ParticipantIDs: append([]string{userID}, req.ParticipantIDs...),
+	}
+	if err = s.repo.Create(ctx, tenant, conversation); err != nil {
+		return nil, domain.ToStatus(err, "failed to create conversation")
+	}
+
+	span.SetAttributes(attribute.String("conversation.id", conversation.ID))
+
+	return s.conversationOutput(tenant, conversation), nil
+}
+
+func (s *ConversationService) GetConversation(ctx context.Context, req *ConversationID) (*ConversationOutput, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "GetConversation")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	conversation, err := s.load(ctx, tenant, userID, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.conversationOutput(tenant, conversation), nil
+}
+
+// ListConversations pages through the caller's conversations, most recently
+// active first
+func (s *ConversationService) ListConversations(ctx context.Context, req *ListConversationsRequest) (*ListConversationsResponse, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "ListConversations")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	res, err := s.list(ctx, tenant, userID, req, s.repo.List)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("conversations.count", len(res.Conversations)))
+
+	return res, nil
+}
+
+// ListNeedsReply pages through the conversations whose newest message is the
+// customer's, the one waiting longest first. Managers can list the whole
+// studio's with all.
+func (s *ConversationService) ListNeedsReply(ctx context.Context, req *ListConversationsRequest) (*ListConversationsResponse, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "ListNeedsReply")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	res, err := s.list(ctx, tenant, userID, req, s.repo.NeedsReply)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("conversations.count", len(res.Conversations)))
+
+	return res, nil
+}
+
+// SendMessage writes a message in a conversation and pushes it to the Chat
+// streams of its participants
+func (s *ConversationService) SendMessage(ctx context.Context, req *SendMessageRequest) (*MessageOutput, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "SendMessage")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	message, err := s.send(ctx, tenant, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.String("message.id", message.ID))
+
+	return s.messageOutput(tenant, message), nil
+}
+
+// ListMessages pages back through a conversation, newest message first
+func (s *ConversationService) ListMessages(ctx context.Context, req *ListMessagesRequest) (*ListMessagesResponse, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "ListMessages")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	before := time.Now()
+	if req.Before != "" {
+		if before, err = time.Parse(time.RFC3339Nano, req.Before); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "before must be an RFC 3339 time")
+		}
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultMessagePage
+	}
+	limit = min(limit, domain.MaxPageSize)
+
+	if _, err = s.load(ctx, tenant, userID, req.ConversationID); err != nil {
+		return nil, err
+	}
+
+	messages, err := s.repo.ListMessages(ctx, tenant, req.ConversationID, before, limit)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list messages")
+	}
+
+	res := &ListMessagesResponse{Messages: make([]MessageOutput, 0, len(messages))}
+	for i := range messages {
+		res.Messages = append(res.Messages, *s.messageOutput(tenant, &messages[i]))
+	}
+	return res, nil
+}
+
+// MarkRead moves the caller's read marker and tells the other participants
+func (s *ConversationService) MarkRead(ctx context.Context, req *MarkReadRequest) (*ReadMarkerOutput, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "MarkRead")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	return s.markRead(ctx, tenant, userID, req)
+}
+
+// ListReadMarkers returns how far each participant has read, for read receipts
+func (s *ConversationService) ListReadMarkers(ctx context.Context, req *ConversationID) (*ReadMarkersResponse, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "ListReadMarkers")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if _, err = s.load(ctx, tenant, userID, req.ID); err != nil {
+		return nil, err
+	}
+
+	markers, err := s.repo.ReadMarkers(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list read markers")
+	}
+
+	res := &ReadMarkersResponse{Markers: make([]ReadMarkerOutput, 0, len(markers))}
+	for i := range markers {
+		res.Markers = append(res.Markers, *readMarkerOutput(&markers[i]))
+	}
+	return res, nil
+}
+
+// GetUnreadCounts returns the caller's unread messages in total and by
+// conversation
+func (s *ConversationService) GetUnreadCounts(ctx context.Context, _ *UnreadCountsRequest) (*UnreadCountsResponse, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "GetUnreadCounts")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	counts, err := s.repo.UnreadCounts(ctx, tenant, userID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to count unread messages")
+	}
+
+	res := &UnreadCountsResponse{Conversations: counts}
+	for _, n := range counts {
+		res.Total += n
+	}
+
+	span.SetAttributes(attribute.Int("messages.unread", res.Total))
+
+	return res, nil
+}
+
+// Chat pushes every new message, read marker and typing indicator in the
+// caller's conversations as it happens, on any replica, and takes the
+// caller's own messages, typing and reads. A failed request is answered with
+// an ERROR event and leaves the stream open.
+func (s *ConversationService) Chat(stream *structrpc.Stream[ChatRequest, ChatEvent]) error {
+	ctx, span, tenant, userID, err := domain.StartUserCall(stream.Context(), "Chat")
+	if err != nil {
+		return err
+	}
+	defer span.End()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := s.repo.Listen(ctx, tenant, userID)
+	if err != nil {
+		return domain.ToStatus(err, "failed to listen to conversations")
+	}
+
+	// Requests are read on their own goroutine; only this one sends
+	requests := make(chan *ChatRequest)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-recvErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case event, ok := <-events:
+			if !ok {
+				return ctx.Err()
+			}
+			if err = stream.Send(s.chatEvent(tenant, &event)); err != nil {
+				return err
+			}
+		case req := <-requests:
+			reply, err := s.handleChat(ctx, tenant, userID, req)
+			if err != nil {
+				logger.Log.Debug("chat request rejected",
+					zap.String("tenant", tenant),
+					zap.String("conversation_id", req.ConversationID),
+					zap.Error(err))
+				reply = &ChatEvent{Kind: chatEventError, Error: status.Convert(err).Message()}
+			}
+			if reply == nil {
+				continue
+			}
+			reply.ConversationID, reply.ClientID = req.ConversationID, req.ClientID
+			if err = stream.Send(reply); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handleChat carries out a request from a Chat stream and returns the event
+// answering it, if any. Typing and reads are answered by the events every
+// participant gets.
+func (s *ConversationService) handleChat(ctx context.Context, tenant, userID string, req *ChatRequest) (*ChatEvent, error) {
+	switch strings.ToUpper(req.Kind) {
+	case "", chatSend:
+		message, err := s.send(ctx, tenant, userID, &req.SendMessageRequest)
+		if err != nil {
+			return nil, err
+		}
+		return &ChatEvent{Kind: chatEventSent, Message: s.messageOutput(tenant, message)}, nil
+	case chatTyping:
+		if _, err := s.load(ctx, tenant, userID, req.ConversationID); err != nil {
+			return nil, err
+		}
+		if err := s.repo.Typing(ctx, tenant, req.ConversationID, userID); err != nil {
+			return nil, domain.ToStatus(err, "failed to send typing indicator")
+		}
+		return nil, nil
+	case chatRead:
+		_, err := s.markRead(ctx, tenant, userID, &MarkReadRequest{ConversationID: req.ConversationID, MessageID: req.MessageID})
+		return nil, err
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown chat request kind %q", req.Kind)
+	}
+}
+
+func (s *ConversationService) markRead(ctx context.Context, tenant, userID string, req *MarkReadRequest) (*ReadMarkerOutput, error) {
+	if req.ConversationID == "" {
+		return nil, status.Error(codes.InvalidArgument, "conversation id is required")
+	}
+	marker, err := s.repo.MarkRead(ctx, tenant, req.ConversationID, userID, req.MessageID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to mark conversation read")
+	}
+	return readMarkerOutput(marker), nil
+}
+
+// list pages through conversations with the caller's unread counts. Only
+// managers may list beyond their own conversations.
+func (s *ConversationService) list(ctx context.Context, tenant, userID string, req *ListConversationsRequest,
+	query func(context.Context, string, domain.ConversationFilter) (domain.PagedResult[domain.Conversation], error),
+) (*ListConversationsResponse, error) {
+	filter := domain.ConversationFilter{
+		UserID:     userID,
+		CustomerID: req.CustomerID,
+		StudioID:   req.StudioID,
+		Page:       req.Page,
+		PageSize:   min(req.PageSize, domain.MaxPageSize),
+	}
+	if req.All {
+		if err := domain.RequireRole(ctx, managerRoles...); err != nil {
+			return nil, err
+		}
+		filter.UserID = ""
+	}
+
+	result, err := query(ctx, tenant, filter)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list conversations")
+	}
+	unread, err := s.repo.UnreadCounts(ctx, tenant, userID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to count unread messages")
+	}
+
+	res := &ListConversationsResponse{
+		Conversations: make([]ConversationOutput, 0, len(result.Items)),
+		TotalCount:    result.TotalCount,
+	}
+	for i := range result.Items {
+		out := s.conversationOutput(tenant, &result.Items[i])
+		out.Unread = unread[out.ID]
+		res.Conversations = append(res.Conversations, *out)
+	}
+	return res, nil
+}
+
+// send writes a message as the caller, or as the conversation's customer.
+// Only the caller's own uploads can be attached.
+func (s *ConversationService) send(ctx context.Context, tenant, userID string, req *SendMessageRequest) (*domain.Message, error) {
+	content := strings.TrimSpace(req.Content)
+	if content == "" && len(req.AttachmentIDs) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "content or an attachment is required")
+	}
+	if utf8.RuneCountInString(content) > maxMessageLength {
+		return nil, status.Errorf(codes.InvalidArgument, "content is longer than %d characters", maxMessageLength)
+	}
+	if len(req.AttachmentIDs) > maxAttachments {
+		return nil, status.Errorf(codes.InvalidArgument, "a message can carry at most %d attachments", maxAttachments)
+	}
+
+	conversation, err := s.load(ctx, tenant, userID, req.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &domain.Message{ConversationID: conversation.ID, Content: content}
+	for _, id := range uniqueIDs(req.AttachmentIDs) {
+		file, err := s.attachments.GetByID(ctx, tenant, id)
+		if err != nil {
+			return nil, domain.ToStatus(err, "failed to get attachment")
+		}
+		if file.UploadedBy != userID {
+			return nil, status.Errorf(codes.PermissionDenied, "attachment %s was uploaded by someone else", id)
+		}
+		message.Attachments = append(message.Attachments, domain.Attachment{ID: file.ID})
+	}
+	if req.FromCustomer {
+		message.SenderCustomerID = conversation.CustomerID
+	} else {
+		message.SenderUserID = userID
+	}
+	if err = s.repo.AddMessage(ctx, tenant, message); err != nil {
+		return nil, domain.ToStatus(err, "failed to send message")
+	}
+	return message, nil
+}
+
+// load returns the conversation when the caller takes part in it or is a
+// manager
+func (s *ConversationService) load(ctx context.Context, tenant, userID, id string) (*domain.Conversation, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "conversation id is required")
+	}
+	conversation, err := s.repo.GetByID(ctx, tenant, id)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get conversation")
+	}
+	if slices.Contains(conversation.ParticipantIDs, userID) {
+		return conversation, nil
+	}
+	if err = domain.RequireRole(ctx, managerRoles...); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+func (s *ConversationService) conversationOutput(tenant string, conversation *domain.Conversation) *ConversationOutput {
+	out := &ConversationOutput{
+		ID:             conversation.ID,
+		StudioID:       conversation.StudioID,
+		CustomerID:     conversation.CustomerID,
+		Subject:        conversation.Subject,
+		ParticipantIDs: conversation.ParticipantIDs,
+		CreatedAt:      conversation.CreatedAt.Format(time.RFC3339),
+	}
+	if out.ParticipantIDs == nil {
+		out.ParticipantIDs = []string{}
+	}
+	if conversation.LastMessage != nil {
+		out.LastMessage = s.messageOutput(tenant, conversation.LastMessage)
+	}
+	if conversation.UpdatedAt != nil {
+		out.UpdatedAt = conversation.UpdatedAt.Format(time.RFC3339)
+	}
+	return out
+}
+
+func readMarkerOutput(marker *domain.ReadMarker) *ReadMarkerOutput {
+	out := &ReadMarkerOutput{
+		ConversationID: marker.ConversationID,
+		UserID:         marker.UserID,
+		MessageID:      marker.MessageID,
+	}
+	if marker.ReadAt != nil {
+		out.ReadAt = marker.ReadAt.Format(time.RFC3339)
+	}
+	return out
+}
+
+func (s *ConversationService) chatEvent(tenant string, event *domain.ConversationEvent) *ChatEvent {
+	out := &ChatEvent{
+		Kind:           event.Kind,
+		ConversationID: event.ConversationID,
+		UserID:         event.UserID,
+	}
+	if event.Message != nil {
+		out.Message = s.messageOutput(tenant, event.Message)
+	}
+	if event.Read != nil {
+		out.Read = readMarkerOutput(event.Read)
+	}
+	return out
+}
+
+// messageOutput describes a message with newly signed URLs for its
+// attachments
+func (s *ConversationService) messageOutput(tenant string, message *domain.Message) *MessageOutput {
+	out := &MessageOutput{
+		ID:               message.ID,
+		ConversationID:   message.ConversationID,
+		SenderUserID:     message.SenderUserID,
+		SenderCustomerID: message.SenderCustomerID,
+		Content:          message.Content,
+		CreatedAt:        message.CreatedAt.Format(time.RFC3339Nano),
+	}
+	for i := range message.Attachments {
+		out.Attachments = append(out.Attachments, *attachment.NewOutput(tenant, &message.Attachments[i], s.urls))
+	}
+	return out
+}
@@ -2,62 +2,93 @@ package customer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
-	"github.com/redis/go-redis/v9"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
 )
 
-type PostgresCustomerRepository struct {
-	pgpool *pgxpool.Pool
-	redis  *redis.Client
+// CustomerRepository stores customers, their notes and history in the tenant's database
+type CustomerRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewCustomerRepository creates a new CustomerRepository
+func NewCustomerRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *CustomerRepository {
+	return &CustomerRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
 }
 
-// NewRepository creates a new AuthService
-func NewRepository(db *pgxpool.Pool, redis *redis.Client) *PostgresCustomerRepository {
-	return &PostgresCustomerRepository{pgpool: db, redis: redis}
+const customerColumns = `id, studio_id, full_name, COALESCE(email, ''), COALESCE(phone, ''), COALESCE(notes, ''),
+	COALESCE(nif, ''), COALESCE(address, ''), COALESCE(city, ''), COALESCE(postal_code, ''), COALESCE(country, ''),
+	COALESCE(id_card_number, ''), COALESCE(first_name, ''), COALESCE(last_name, ''), birthday,
+	COALESCE(is_archived, false), created_at, COALESCE(updated_at, created_at)`
+
+// customerFields maps update_mask paths to columns
+var customerFields = map[string]string{
+	"full_name":      "full_name",
+	"email":          "email",
+	"phone":          "phone",
+	"notes":          "notes",
+	"nif":            "nif",
+	"address":        "address",
+	"city":           "city",
+	"postal_code":    "postal_code",
+	"country":        "country",
+	"id_card_number": "id_card_number",
+	"first_name":     "first_name",
+	"last_name":      "last_name",
+	"birthday":       "birthday",
+	"is_archived":    "is_archived",
 }
 
-func (r *PostgresCustomerRepository) Create(ctx context.Context, customer *domain.Customer) (string, error) {
-	tx, err := r.pgpool.Begin(ctx)
+// Create inserts a customer. Without a StudioID the customer belongs to the
+// tenant's own studio.
+func (r *CustomerRepository) Create(ctx context.Context, tenant string, customer *domain.Customer) (string, error) {
+	if customer == nil {
+		return "", fmt.Errorf("%w: customer is required", domain.ErrInvalidArgument)
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
 	if err != nil {
-		return "", status.Error(codes.Internal, "could not start transaction")
+		return "", fmt.Errorf("invalid tenant: %w", err)
 	}
-	defer tx.Rollback(ctx)
 
-	if customer == nil {
-		return "", status.Error(codes.InvalidArgument, "customer is required")
+	studioID := customer.StudioID
+	if studioID == "" {
+		err = pool.QueryRow(ctx, "SELECT id FROM studios WHERE subdomain = $1", tenant).Scan(&studioID)
+		if err != nil {
+			return "", fmt.Errorf("studio not found: %w", err)
+		}
 	}
 
 	// Format full name if not provided
 	fullName := customer.FullName
-	if fullName == "" && (customer.FirstName != "" || customer.LastName != "") {
-		fullName = strings.TrimSpace(fmt.Sprintf("%s %s", customer.FirstName, customer.LastName))
+	if fullName == "" {
+		fullName = strings.TrimSpace(customer.FirstName + " " + customer.LastName)
 	}
-
-	// Format birthday as string if available
-	var birthdayStr *string
-	if !customer.DateOfBirth.IsZero() {
-		bdayStr := customer.DateOfBirth.Format("2006-01-02")
-		birthdayStr = &bdayStr
+	if fullName == "" {
+		return "", fmt.Errorf("%w: customer name is required", domain.ErrInvalidArgument)
 	}
 
 	var customerID string
-	query := `INSERT INTO customers (
+	err = pool.QueryRow(ctx, `INSERT INTO customers (
 		studio_id, full_name, email, phone, notes, nif, address,
-		city, postal_code, country, id_card_number, first_name, last_name, birthday, 
+		city, postal_code, country, id_card_number, first_name, last_name, birthday,
 		is_archived, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW(), NOW())
-		RETURNING id`
-
-	err = tx.QueryRow(ctx, query,
-		customer.StudioID,
+		RETURNING id`,
+		studioID,
 		fullName,
 		customer.Email,
 		customer.Phone,
@@ -70,98 +101,386 @@ func (r *PostgresCustomerRepository) Create(ctx context.Context, customer *domai
 		customer.IDCardNumber,
 		customer.FirstName,
 		customer.LastName,
-		birthdayStr,
+		birthday(customer.DateOfBirth),
 		customer.IsArchived,
 	).Scan(&customerID)
-
 	if err != nil {
-		// Check for unique constraint violations
-		if pgErr, ok := err.(*pgconn.PgError); ok {
-			if pgErr.Code == "23505" { // unique_violation
-				if strings.Contains(pgErr.Message, "email") {
-					return "", status.Error(codes.AlreadyExists, "a customer with this email already exists")
-				}
-				if strings.Contains(pgErr.Message, "phone") {
-					return "", status.Error(codes.AlreadyExists, "a customer with this phone already exists")
-				}
-			}
-		}
-		return "", status.Errorf(codes.Internal, "failed to create customer: %v", err)
-	}
-
-	// If we got here, commit the transaction
-	if err = tx.Commit(ctx); err != nil {
-		return "", status.Errorf(codes.Internal, "failed to commit transaction: %v", err)
+		return "", domain.WrapError("failed to create customer", err)
 	}
 
 	return customerID, nil
 }
 
-func (r *PostgresCustomerRepository) Get(ctx context.Context, id string) (*domain.Customer, error) {
-	return nil, nil
-}
+func (r *CustomerRepository) GetByID(ctx context.Context, tenant, id string) (*domain.Customer, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
 
-func (r *PostgresCustomerRepository) GetByID(ctx context.Context, id string) (*domain.Customer, error) {
-	return nil, nil
+	customer, err := scanCustomer(pool.QueryRow(ctx, "SELECT "+customerColumns+" FROM customers WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("customer %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get customer", err)
+	}
+	return customer, nil
 }
 
-func (r *PostgresCustomerRepository) Update(ctx context.Context, customer *domain.Customer) error {
+// Update writes the fields of customer named in updateMask
+func (r *CustomerRepository) Update(ctx context.Context, tenant string, customer *domain.Customer, updateMask *fieldmaskpb.FieldMask) error {
+	if customer == nil {
+		return fmt.Errorf("%w: customer is required", domain.ErrInvalidArgument)
+	}
+	if updateMask == nil || len(updateMask.Paths) == 0 {
+		return fmt.Errorf("%w: update_mask is required", domain.ErrInvalidArgument)
+	}
+
+	values := map[string]interface{}{
+		"full_name":      customer.FullName,
+		"email":          customer.Email,
+		"phone":          customer.Phone,
+		"notes":          customer.Notes,
+		"nif":            customer.NIF,
+		"address":        customer.Address,
+		"city":           customer.City,
+		"postal_code":    customer.PostalCode,
+		"country":        customer.Country,
+		"id_card_number": customer.IDCardNumber,
+		"first_name":     customer.FirstName,
+		"last_name":      customer.LastName,
+		"birthday":       birthday(customer.DateOfBirth),
+		"is_archived":    customer.IsArchived,
+	}
+
+	var setClauses []string
+	var args []interface{}
+	for _, path := range updateMask.Paths {
+		column, ok := customerFields[path]
+		if !ok {
+			return fmt.Errorf("%w: unknown field in update_mask: %s", domain.ErrInvalidArgument, path)
+		}
+		if column == "full_name" && strings.TrimSpace(customer.FullName) == "" {
+			return fmt.Errorf("%w: full_name cannot be empty", domain.ErrInvalidArgument)
+		}
+		args = append(args, values[path])
+		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	args = append(args, time.Now(), customer.ID)
+	setClauses = append(setClauses, fmt.Sprintf("updated_at = $%d", len(args)-1))
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	tag, err := pool.Exec(ctx,
+		"UPDATE customers SET "+strings.Join(setClauses, ", ")+fmt.Sprintf(" WHERE id = $%d", len(args)),
+		args...)
+	if err != nil {
+		return domain.WrapError("failed to update customer", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("customer %s: %w", customer.ID, domain.ErrNotFound)
+	}
 	return nil
 }
 
-func (r *PostgresCustomerRepository) Delete(ctx context.Context, id string) error {
+// Delete removes a customer together with their appointments, conversations,
+// notes and history
+func (r *CustomerRepository) Delete(ctx context.Context, tenant, id string) error {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	tag, err := pool.Exec(ctx, "DELETE FROM customers WHERE id = $1", id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return fmt.Errorf("%w: customer is still referenced: %s", domain.ErrFailedPrecondition, pgErr.Detail)
+		}
+		return domain.WrapError("failed to delete customer", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("customer %s: %w", id, domain.ErrNotFound)
+	}
 	return nil
 }
 
-func (r *PostgresCustomerRepository) Archive(ctx context.Context, id string) error {
+// Archive hides a customer from lists without deleting their records
+func (r *CustomerRepository) Archive(ctx context.Context, tenant, id string) error {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	tag, err := pool.Exec(ctx, "UPDATE customers SET is_archived = true, updated_at = now() WHERE id = $1", id)
+	if err != nil {
+		return domain.WrapError("failed to archive customer", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("customer %s: %w", id, domain.ErrNotFound)
+	}
 	return nil
 }
 
-func (r *PostgresCustomerRepository) List(ctx context.Context, filter domain.CustomerFilter) (domain.PagedResult[domain.Customer], error) {
-	return domain.PagedResult[domain.Customer]{
-		Items:      []domain.Customer{},
-		TotalCount: 0,
-		Page:       filter.Page,
-		PageSize:   filter.PageSize,
-	}, nil
+// List pages through customers matching the filter, newest first
+func (r *CustomerRepository) List(ctx context.Context, tenant string, filter domain.CustomerFilter) (domain.PagedResult[domain.Customer], error) {
+	return r.query(ctx, tenant, filter)
 }
 
-func (r *PostgresCustomerRepository) GetByEmail(ctx context.Context, email string) (*domain.Customer, error) {
-	return nil, nil
+// Search pages through customers whose name, email, phone or NIF contain
+// filter.Query, best matches first
+func (r *CustomerRepository) Search(ctx context.Context, tenant string, filter domain.CustomerFilter) (domain.PagedResult[domain.Customer], error) {
+	if strings.TrimSpace(filter.Query) == "" {
+		return domain.PagedResult[domain.Customer]{}, fmt.Errorf("%w: search query is required", domain.ErrInvalidArgument)
+	}
+	return r.query(ctx, tenant, filter)
 }
 
-func (r *PostgresCustomerRepository) GetByPhone(ctx context.Context, phone string) (*domain.Customer, error) {
-	return nil, nil
+func (r *CustomerRepository) query(ctx context.Context, tenant string, filter domain.CustomerFilter) (domain.PagedResult[domain.Customer], error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = domain.DefaultPageSize
+	}
+	result := domain.PagedResult[domain.Customer]{Items: []domain.Customer{}, Page: filter.Page, PageSize: filter.PageSize}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return result, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	where := []string{"TRUE"}
+	var args []interface{}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if filter.StudioID != "" {
+		add("studio_id = $%d", filter.StudioID)
+	}
+	if !filter.IncludeArchived {
+		where = append(where, "NOT COALESCE(is_archived, false)")
+	}
+	if filter.Name != "" {
+		add("full_name ILIKE '%%' || $%d || '%%'", filter.Name)
+	}
+	if filter.Email != "" {
+		add("email ILIKE $%d", filter.Email)
+	}
+	if filter.Phone != "" {
+		add("phone = $%d", filter.Phone)
+	}
+	if filter.DateOfBirth != nil {
+		add("birthday = $%d::date", filter.DateOfBirth.Format(time.DateOnly))
+	}
+	if filter.CreatedFrom != nil {
+		add("created_at >= $%d", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		add("created_at < $%d", *filter.CreatedTo)
+	}
+
+	orderBy := "created_at DESC, id"
+	if query := strings.TrimSpace(filter.Query); query != "" {
+		args = append(args, query)
+		n := len(args)
+		where = append(where, fmt.Sprintf(`(full_name ILIKE '%%' || $%[1]d || '%%'
+			OR email ILIKE '%%' || $%[1]d || '%%'
+			OR phone ILIKE '%%' || $%[1]d || '%%'
+			OR nif ILIKE '%%' || $%[1]d || '%%')`, n))
+		// Prefix matches on the name rank above matches anywhere else
+		orderBy = fmt.Sprintf("(full_name ILIKE $%d || '%%') DESC, full_name, id", n)
+	}
+	whereClause := strings.Join(where, " AND ")
+
+	if err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM customers WHERE "+whereClause, args...).Scan(&result.TotalCount); err != nil {
+		return result, fmt.Errorf("failed to count customers: %w", err)
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	rows, err := pool.Query(ctx,
+		"SELECT "+customerColumns+" FROM customers WHERE "+whereClause+
+			fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", orderBy, len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return result, fmt.Errorf("failed to query customers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		customer, err := scanCustomer(rows)
+		if err != nil {
+			return result, fmt.Errorf("failed to scan customer: %w", err)
+		}
+		result.Items = append(result.Items, *customer)
+	}
+	if err = rows.Err(); err != nil {
+		return result, fmt.Errorf("failed to query customers: %w", err)
+	}
+
+	return result, nil
 }
 
-func (r *PostgresCustomerRepository) AddHistory(ctx context.Context, history *domain.CustomerHistory) error {
+func (r *CustomerRepository) AddHistory(ctx context.Context, tenant string, history *domain.CustomerHistory) error {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	err = pool.QueryRow(ctx, `INSERT INTO customer_history
+		(customer_id, type, description, artist_id, appointment_id, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, '')::uuid, NULLIF($5, '')::uuid, NULLIF($6, '')::uuid)
+		RETURNING id, created_at`,
+		history.CustomerID, history.Type, history.Description, history.ArtistID, history.AppointmentID, history.CreatedBy,
+	).Scan(&history.ID, &history.Timestamp)
+	if err != nil {
+		return domain.WrapError("failed to add customer history", err)
+	}
 	return nil
 }
 
-func (r *PostgresCustomerRepository) GetHistory(ctx context.Context, customerID string) ([]*domain.CustomerHistory, error) {
-	return nil, nil
+// GetHistory returns the customer's recorded history together with their
+// appointments, newest first
+func (r *CustomerRepository) GetHistory(ctx context.Context, tenant, customerID string) ([]*domain.CustomerHistory, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT id::text, type, COALESCE(description, ''), COALESCE(artist_id::text, ''),
+		       COALESCE(appointment_id::text, ''), COALESCE(created_by::text, ''), created_at
+		FROM customer_history WHERE customer_id = $1
+		UNION ALL
+		SELECT id::text, 'appointment', status || COALESCE(': ' || notes, ''), COALESCE(artist_id::text, ''),
+		       id::text, '', start_time
+		FROM appointments WHERE customers_id = $1
+		ORDER BY 7 DESC`, customerID)
+	if err != nil {
+		return nil, domain.WrapError("failed to query customer history", err)
+	}
+	defer rows.Close()
+
+	var history []*domain.CustomerHistory
+	for rows.Next() {
+		entry := domain.CustomerHistory{CustomerID: customerID}
+		err = rows.Scan(&entry.ID, &entry.Type, &entry.Description, &entry.ArtistID,
+			&entry.AppointmentID, &entry.CreatedBy, &entry.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan customer history: %w", err)
+		}
+		history = append(history, &entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to query customer history", err)
+	}
+
+	return history, nil
 }
 
-func (r *PostgresCustomerRepository) AddNote(ctx context.Context, note *domain.CustomerNote) error {
-	return nil
+func (r *CustomerRepository) AddNote(ctx context.Context, tenant string, note *domain.CustomerNote) error {
+	if strings.TrimSpace(note.Content) == "" {
+		return fmt.Errorf("%w: note cannot be empty", domain.ErrInvalidArgument)
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `INSERT INTO customer_notes (customer_id, content, created_by)
+			VALUES ($1, $2, NULLIF($3, '')::uuid) RETURNING id, created_at`,
+			note.CustomerID, note.Content, note.CreatedBy).Scan(&note.ID, &note.CreatedAt)
+		if err != nil {
+			return domain.WrapError("failed to add customer note", err)
+		}
+
+		_, err = tx.Exec(ctx, `INSERT INTO customer_history (customer_id, type, description, created_by, created_at)
+			VALUES ($1, 'note', $2, NULLIF($3, '')::uuid, $4)`,
+			note.CustomerID, note.Content, note.CreatedBy, note.CreatedAt)
+		if err != nil {
+			return domain.WrapError("failed to add customer history", err)
+		}
+		return nil
+	})
+}
+
+func (r *CustomerRepository) GetNotes(ctx context.Context, tenant, customerID string) ([]*domain.CustomerNote, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	rows, err := pool.Query(ctx, `SELECT id, customer_id, content, COALESCE(created_by::text, ''), created_at
+		FROM customer_notes WHERE customer_id = $1 ORDER BY created_at DESC`, customerID)
+	if err != nil {
+		return nil, domain.WrapError("failed to query customer notes", err)
+	}
+	defer rows.Close()
+
+	var notes []*domain.CustomerNote
+	for rows.Next() {
+		var note domain.CustomerNote
+		if err = rows.Scan(&note.ID, &note.CustomerID, &note.Content, &note.CreatedBy, &note.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan customer note: %w", err)
+		}
+		notes = append(notes, &note)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to query customer notes", err)
+	}
+
+	return notes, nil
 }
 
-func (r *PostgresCustomerRepository) GetNotes(ctx context.Context, customerID string) ([]*domain.CustomerNote, error) {
-	return nil, nil
+func (r *CustomerRepository) ExistsByEmail(ctx context.Context, tenant, email string) (bool, error) {
+	return r.exists(ctx, tenant, "lower(email) = lower($1)", email)
 }
 
-func (r *PostgresCustomerRepository) Search(ctx context.Context, filter domain.CustomerFilter) (domain.PagedResult[domain.Customer], error) {
-	return domain.PagedResult[domain.Customer]{
-		Items:      []domain.Customer{},
-		TotalCount: 0,
-		Page:       filter.Page,
-		PageSize:   filter.PageSize,
-	}, nil
+func (r *CustomerRepository) ExistsByPhone(ctx context.Context, tenant, phone string) (bool, error) {
+	return r.exists(ctx, tenant, "phone = $1", phone)
+}
+
+func (r *CustomerRepository) exists(ctx context.Context, tenant, condition, value string) (bool, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return false, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var exists bool
+	err = pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM customers WHERE "+condition+")", value).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check customer: %w", err)
+	}
+	return exists, nil
 }
-func (r *PostgresCustomerRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
-	return false, nil
+
+func scanCustomer(row pgx.Row) (*domain.Customer, error) {
+	var customer domain.Customer
+	var dateOfBirth *time.Time
+	err := row.Scan(&customer.ID, &customer.StudioID, &customer.FullName, &customer.Email, &customer.Phone,
+		&customer.Notes, &customer.NIF, &customer.Address, &customer.City, &customer.PostalCode,
+		&customer.Country, &customer.IDCardNumber, &customer.FirstName, &customer.LastName, &dateOfBirth,
+		&customer.IsArchived, &customer.CreatedAt, &customer.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if dateOfBirth != nil {
+		customer.DateOfBirth = *dateOfBirth
+	}
+	return &customer, nil
 }
 
-func (r *PostgresCustomerRepository) ExistsByPhone(ctx context.Context, phone string) (bool, error) {
-	return false, nil
+// birthday stores a zero date of birth as NULL
+func birthday(dateOfBirth time.Time) *string {
+	if dateOfBirth.IsZero() {
+		return nil
+	}
+	formatted := dateOfBirth.Format(time.DateOnly)
+	return &formatted
 }
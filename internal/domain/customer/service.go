@@ -2,32 +2,60 @@ package customer
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	upc "github.com/FACorreiaa/ink-app-backend-protos/modules/customer/generated"
-	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/redis/go-redis/v9"
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
-	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/middleware/grpcrequest"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// NoteServiceName is the fully qualified gRPC name of the customer note
+// service. The customer protos can add notes but not read them back.
+const NoteServiceName = "inkMe.customer.CustomerNoteService"
+
+type ListCustomerNotesRequest struct {
+	CustomerID string `json:"customer_id"`
+}
+
+type CustomerNoteOutput struct {
+	ID        string `json:"id"`
+	Content   string `json:"content"`
+	CreatedBy string `json:"created_by,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ListCustomerNotesResponse struct {
+	Notes []CustomerNoteOutput `json:"notes"`
+}
+
+// History entry types recorded by the service
+const (
+	historyCreated  = "created"
+	historyUpdated  = "updated"
+	historyArchived = "archived"
 )
 
 type ServiceCustomer struct {
 	upc.UnimplementedCustomerServiceServer
-	ctx    context.Context
-	repo   domain.CustomerRepository
-	pgpool *pgxpool.Pool
-	redis  *redis.Client
+	repo domain.CustomerRepository
 }
 
-func NewService(ctx context.Context, repo domain.CustomerRepository,
-	db *pgxpool.Pool,
-	redis *redis.Client) *ServiceCustomer {
-	return &ServiceCustomer{ctx: ctx, repo: repo, pgpool: db, redis: redis}
+func NewCustomerService(repo domain.CustomerRepository) *ServiceCustomer {
+	return &ServiceCustomer{repo: repo}
+}
+
+// Register adds the note service to a gRPC server
+func (s *ServiceCustomer) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(NoteServiceName,
+		structrpc.Unary(NoteServiceName, "ListCustomerNotes", s.ListCustomerNotes),
+	), s)
 }
 
 func (s *ServiceCustomer) CreateCustomer(ctx context.Context, req *upc.CreateCustomerRequest) (*upc.CreateCustomerResponse, error) {
@@ -36,63 +64,23 @@ func (s *ServiceCustomer) CreateCustomer(ctx context.Context, req *upc.CreateCus
 		return nil, status.Error(codes.InvalidArgument, "customer details are required")
 	}
 
-	tracer := otel.Tracer("SyncInk")
-	ctx, span := tracer.Start(ctx, "/CustomerService/CreateCustomer")
-	defer span.End()
-
-	requestID, ok := ctx.Value(grpcrequest.RequestIDKey{}).(string)
-	if !ok {
-		return nil, status.Error(codes.Internal, "request id not found in context")
-	}
-
-	if req.Request == nil {
-		req.Request = &upc.BaseRequest{}
-	}
-
-	req.Request.RequestId = requestID
-
-	// Extract customer info from request
-	protoCustomer := req.Customer
-
-	// Parse birthday if provided
-	var dateOfBirth time.Time
-	if protoCustomer.Birthday != "" {
-		var err error
-		dateOfBirth, err = time.Parse("2006-01-02", protoCustomer.Birthday)
-		if err != nil {
-			return nil, status.Errorf(codes.InvalidArgument, "invalid birthday format: %v", err)
-		}
-	}
-
-	userID := ctx.Value("userID").(string)
-	if userID == "" {
-		return nil, status.Error(codes.Unauthenticated, "studioID is missing in metadata")
+	ctx, span, tenant, err := domain.StartCall(ctx, "/CustomerService/CreateCustomer")
+	if err != nil {
+		return nil, err
 	}
+	defer span.End()
+	base := baseResponse(ctx)
 
-	// Map proto customer to domain customer
-	customer := &domain.Customer{
-		StudioID:     userID,
-		FirstName:    protoCustomer.FirstName,
-		LastName:     protoCustomer.LastName,
-		FullName:     protoCustomer.FullName,
-		Email:        protoCustomer.Email,
-		Phone:        protoCustomer.Phone,
-		Notes:        protoCustomer.Notes,
-		NIF:          protoCustomer.Nif,
-		Address:      protoCustomer.Address,
-		City:         protoCustomer.City,
-		PostalCode:   protoCustomer.PostalCode,
-		Country:      protoCustomer.Country,
-		IDCardNumber: protoCustomer.IdCardNumber,
-		DateOfBirth:  dateOfBirth,
-		IsArchived:   protoCustomer.IsArchived,
+	customer, err := customerFromProto(req.Customer)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if customer with same email already exists
 	if customer.Email != "" {
-		exists, err := s.repo.ExistsByEmail(ctx, customer.Email)
+		exists, err := s.repo.ExistsByEmail(ctx, tenant, customer.Email)
 		if err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to check customer existence: %v", err)
+			return nil, domain.ToStatus(err, "failed to check customer existence")
 		}
 		if exists {
 			return nil, status.Error(codes.AlreadyExists, "customer with this email already exists")
@@ -101,64 +89,372 @@ func (s *ServiceCustomer) CreateCustomer(ctx context.Context, req *upc.CreateCus
 
 	// Check if customer with same phone already exists
 	if customer.Phone != "" {
-		exists, err := s.repo.ExistsByPhone(ctx, customer.Phone)
+		exists, err := s.repo.ExistsByPhone(ctx, tenant, customer.Phone)
 		if err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to check customer existence: %v", err)
+			return nil, domain.ToStatus(err, "failed to check customer existence")
 		}
 		if exists {
 			return nil, status.Error(codes.AlreadyExists, "customer with this phone already exists")
 		}
 	}
 
-	// Create customer in repository
-	id, err := s.repo.Create(ctx, customer)
+	id, err := s.repo.Create(ctx, tenant, customer)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create customer: %v", err)
+		return nil, domain.ToStatus(err, "failed to create customer")
 	}
+	s.recordHistory(ctx, tenant, id, historyCreated, "customer created")
 
-	span.SetAttributes(
-		attribute.String("request.id", req.Request.RequestId),
-		attribute.String("request.details", req.String()),
-	)
-
-	// Return response
+	span.SetAttributes(attribute.String("customer.id", id))
 	return &upc.CreateCustomerResponse{
 		CustomerId: id,
 		Message:    "Customer created successfully",
-		Response: &upc.BaseResponse{
-			Status: upc.Status_name[int32(upc.Status_SUCCESS)],
-		},
+		Response:   base,
 	}, nil
 }
 
 func (s *ServiceCustomer) GetCustomer(ctx context.Context, req *upc.GetCustomerRequest) (*upc.GetCustomerResponse, error) {
-	return nil, nil
+	ctx, span, tenant, err := domain.StartCall(ctx, "/CustomerService/GetCustomer")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(ctx)
+
+	if req.CustomerId == "" {
+		return nil, status.Error(codes.InvalidArgument, "customer_id is required")
+	}
+
+	customer, err := s.repo.GetByID(ctx, tenant, req.CustomerId)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get customer")
+	}
+
+	return &upc.GetCustomerResponse{Customer: customerToProto(customer), Response: base}, nil
 }
 
+// UpdateCustomer applies the fields named in update_mask
 func (s *ServiceCustomer) UpdateCustomer(ctx context.Context, req *upc.UpdateCustomerRequest) (*upc.UpdateCustomerResponse, error) {
-	return nil, nil
+	ctx, span, tenant, err := domain.StartCall(ctx, "/CustomerService/UpdateCustomer")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(ctx)
+
+	if req.CustomerId == "" || req.Customer == nil {
+		return nil, status.Error(codes.InvalidArgument, "customer_id and customer are required")
+	}
+
+	customer, err := customerFromProto(req.Customer)
+	if err != nil {
+		return nil, err
+	}
+	customer.ID = req.CustomerId
+
+	if err = s.repo.Update(ctx, tenant, customer, req.UpdateMask); err != nil {
+		return nil, domain.ToStatus(err, "failed to update customer")
+	}
+	s.recordHistory(ctx, tenant, customer.ID, historyUpdated, "updated "+strings.Join(req.UpdateMask.GetPaths(), ", "))
+
+	updated, err := s.repo.GetByID(ctx, tenant, customer.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to load customer")
+	}
+
+	return &upc.UpdateCustomerResponse{
+		Success:  true,
+		Message:  "Customer updated successfully",
+		Customer: customerToProto(updated),
+		Response: base,
+	}, nil
 }
 
+// DeleteCustomer permanently removes a customer and their records. Only owners
+// and admins may delete; everyone else archives.
 func (s *ServiceCustomer) DeleteCustomer(ctx context.Context, req *upc.DeleteCustomerRequest) (*upc.DeleteCustomerResponse, error) {
-	return nil, nil
+	ctx, span, tenant, err := domain.StartCall(ctx, "/CustomerService/DeleteCustomer")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(ctx)
+
+	if err = domain.RequireRole(ctx, "OWNER"); err != nil {
+		return nil, err
+	}
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	if err = s.repo.Delete(ctx, tenant, req.Id); err != nil {
+		return nil, domain.ToStatus(err, "failed to delete customer")
+	}
+
+	return &upc.DeleteCustomerResponse{Success: true, Message: "Customer deleted successfully", Response: base}, nil
 }
 
+// ListCustomers pages through the active customers of a studio, or of the whole
+// tenant when no studio_id is given. Paging uses the x-page and x-page-size
+// metadata; the total is returned in the x-total-count header.
 func (s *ServiceCustomer) ListCustomers(ctx context.Context, req *upc.ListCustomersRequest) (*upc.ListCustomersResponse, error) {
-	return nil, nil
+	ctx, span, tenant, err := domain.StartCall(ctx, "/CustomerService/ListCustomers")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(ctx)
+
+	page, pageSize, err := domain.PageFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.repo.List(ctx, tenant, domain.CustomerFilter{
+		StudioID: req.StudioId,
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list customers")
+	}
+	domain.SetTotalCount(ctx, result.TotalCount)
+
+	return &upc.ListCustomersResponse{Customers: customersToProto(result.Items), Response: base}, nil
 }
 
 func (s *ServiceCustomer) ArchiveCustomer(ctx context.Context, req *upc.ArchiveCustomerRequest) (*upc.ArchiveCustomerResponse, error) {
-	return nil, nil
+	ctx, span, tenant, err := domain.StartCall(ctx, "/CustomerService/ArchiveCustomer")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(ctx)
+
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	if err = s.repo.Archive(ctx, tenant, req.Id); err != nil {
+		return nil, domain.ToStatus(err, "failed to archive customer")
+	}
+	s.recordHistory(ctx, tenant, req.Id, historyArchived, "customer archived")
+
+	return &upc.ArchiveCustomerResponse{Message: "Customer archived successfully", Response: base}, nil
 }
 
+// GetCustomerHistory returns the customer's notes, changes and appointments,
+// newest first
 func (s *ServiceCustomer) GetCustomerHistory(ctx context.Context, req *upc.GetCustomerHistoryRequest) (*upc.GetCustomerHistoryResponse, error) {
-	return nil, nil
+	ctx, span, tenant, err := domain.StartCall(ctx, "/CustomerService/GetCustomerHistory")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(ctx)
+
+	if req.CustomerId == "" {
+		return nil, status.Error(codes.InvalidArgument, "customer_id is required")
+	}
+	if _, err = s.repo.GetByID(ctx, tenant, req.CustomerId); err != nil {
+		return nil, domain.ToStatus(err, "failed to get customer")
+	}
+
+	history, err := s.repo.GetHistory(ctx, tenant, req.CustomerId)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get customer history")
+	}
+
+	res := &upc.GetCustomerHistoryResponse{Response: base}
+	for _, entry := range history {
+		message := entry.Type
+		if entry.Description != "" {
+			message += ": " + entry.Description
+		}
+		res.Interactions = append(res.Interactions, &upc.Interaction{
+			Id:            entry.ID,
+			CustomerId:    entry.CustomerID,
+			ArtistId:      entry.ArtistID,
+			AppointmentId: entry.AppointmentID,
+			Message:       message,
+			CreatedAt:     entry.Timestamp.UTC().Format(time.RFC3339),
+		})
+	}
+	return res, nil
 }
 
 func (s *ServiceCustomer) AddCustomerNote(ctx context.Context, req *upc.AddCustomerNoteRequest) (*upc.AddCustomerNoteResponse, error) {
-	return nil, nil
+	ctx, span, tenant, err := domain.StartCall(ctx, "/CustomerService/AddCustomerNote")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(ctx)
+
+	if req.CustomerId == "" || strings.TrimSpace(req.Note) == "" {
+		return nil, status.Error(codes.InvalidArgument, "customer_id and note are required")
+	}
+
+	userID, err := domain.ExtractUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	note := &domain.CustomerNote{CustomerID: req.CustomerId, Content: req.Note, CreatedBy: userID}
+	if err = s.repo.AddNote(ctx, tenant, note); err != nil {
+		return nil, domain.ToStatus(err, "failed to add customer note")
+	}
+
+	return &upc.AddCustomerNoteResponse{Message: "Note added successfully", Response: base}, nil
 }
 
+// ListCustomerNotes returns a customer's notes, newest first
+func (s *ServiceCustomer) ListCustomerNotes(ctx context.Context, req *ListCustomerNotesRequest) (*ListCustomerNotesResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "/CustomerNoteService/ListCustomerNotes")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.CustomerID == "" {
+		return nil, status.Error(codes.InvalidArgument, "customer_id is required")
+	}
+
+	notes, err := s.repo.GetNotes(ctx, tenant, req.CustomerID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list customer notes")
+	}
+
+	res := &ListCustomerNotesResponse{Notes: make([]CustomerNoteOutput, 0, len(notes))}
+	for _, note := range notes {
+		res.Notes = append(res.Notes, CustomerNoteOutput{
+			ID:        note.ID,
+			Content:   note.Content,
+			CreatedBy: note.CreatedBy,
+			CreatedAt: note.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	span.SetAttributes(attribute.Int("customer_notes.count", len(res.Notes)))
+	return res, nil
+}
+
+// SearchCustomers matches query against name, email, phone and NIF. Paging works
+// as in ListCustomers.
 func (s *ServiceCustomer) SearchCustomers(ctx context.Context, req *upc.SearchCustomersRequest) (*upc.SearchCustomersResponse, error) {
-	return nil, nil
+	ctx, span, tenant, err := domain.StartCall(ctx, "/CustomerService/SearchCustomers")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(ctx)
+
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	page, pageSize, err := domain.PageFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.repo.Search(ctx, tenant, domain.CustomerFilter{
+		Query:    req.Query,
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to search customers")
+	}
+	domain.SetTotalCount(ctx, result.TotalCount)
+
+	return &upc.SearchCustomersResponse{Customers: customersToProto(result.Items), Response: base}, nil
+}
+
+// recordHistory adds a history entry on behalf of the caller. History is an
+// audit aid, so a failure is logged on the span rather than failing the call.
+func (s *ServiceCustomer) recordHistory(ctx context.Context, tenant, customerID, entryType, description string) {
+	userID, _ := ctx.Value(domain.UserIDKey).(string)
+	err := s.repo.AddHistory(ctx, tenant, &domain.CustomerHistory{
+		CustomerID:  customerID,
+		Type:        entryType,
+		Description: description,
+		CreatedBy:   userID,
+	})
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
+
+func customerFromProto(protoCustomer *upc.Customer) (*domain.Customer, error) {
+	// Parse birthday if provided
+	var dateOfBirth time.Time
+	if protoCustomer.Birthday != "" {
+		var err error
+		dateOfBirth, err = time.Parse(time.DateOnly, protoCustomer.Birthday)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid birthday format: %v", err)
+		}
+	}
+
+	return &domain.Customer{
+		ID:           protoCustomer.Id,
+		StudioID:     protoCustomer.StudioId,
+		FirstName:    protoCustomer.FirstName,
+		LastName:     protoCustomer.LastName,
+		FullName:     protoCustomer.FullName,
+		Email:        protoCustomer.Email,
+		Phone:        protoCustomer.Phone,
+		Notes:        protoCustomer.Notes,
+		NIF:          protoCustomer.Nif,
+		Address:      protoCustomer.Address,
+		City:         protoCustomer.City,
+		PostalCode:   protoCustomer.PostalCode,
+		Country:      protoCustomer.Country,
+		IDCardNumber: protoCustomer.IdCardNumber,
+		DateOfBirth:  dateOfBirth,
+		IsArchived:   protoCustomer.IsArchived,
+	}, nil
+}
+
+func customerToProto(customer *domain.Customer) *upc.Customer {
+	var birthday string
+	if !customer.DateOfBirth.IsZero() {
+		birthday = customer.DateOfBirth.Format(time.DateOnly)
+	}
+	return &upc.Customer{
+		Id:           customer.ID,
+		StudioId:     customer.StudioID,
+		FullName:     customer.FullName,
+		Email:        customer.Email,
+		Phone:        customer.Phone,
+		Notes:        customer.Notes,
+		Nif:          customer.NIF,
+		Address:      customer.Address,
+		City:         customer.City,
+		PostalCode:   customer.PostalCode,
+		Country:      customer.Country,
+		IdCardNumber: customer.IDCardNumber,
+		FirstName:    customer.FirstName,
+		LastName:     customer.LastName,
+		Birthday:     birthday,
+		IsArchived:   customer.IsArchived,
+	}
+}
+
+func customersToProto(customers []domain.Customer) []*upc.Customer {
+	protoCustomers := make([]*upc.Customer, 0, len(customers))
+	for i := range customers {
+		protoCustomers = append(protoCustomers, customerToProto(&customers[i]))
+	}
+	return protoCustomers
+}
+
+// baseResponse is the response header of a successful call started by
+// domain.StartCall
+func baseResponse(ctx context.Context) *upc.BaseResponse {
+	return &upc.BaseResponse{
+		Success:   true,
+		RequestId: domain.RequestIDFromContext(ctx),
+		TraceId:   domain.TraceIDFromContext(ctx),
+		Status:    upc.Status_name[int32(upc.Status_SUCCESS)],
+	}
 }
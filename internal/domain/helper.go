@@ -3,13 +3,16 @@ package domain
 import (
 	"context"
 	"errors"
-	"fmt"
+	"strconv"
 	"strings"
 
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
 	ups "github.com/FACorreiaa/ink-app-backend-protos/modules/studio/generated"
 )
 
@@ -36,28 +39,123 @@ func ValidateLoginRequest(req *ups.LoginRequest) error {
 
 type SessionManagerKey struct{}
 
-// extractTenantFromContext extracts tenant from gRPC metadata
+// ExtractTenantFromContext returns the tenant resolved by the tenant interceptor
 func ExtractTenantFromContext(ctx context.Context) (string, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return "", status.Error(codes.Unauthenticated, "no metadata provided")
+	tenant, ok := ctx.Value(TenantKey).(string)
+	if !ok || tenant == "" {
+		return "", status.Error(codes.Unauthenticated, "tenant not specified")
+	}
+	return tenant, nil
+}
+
+// Errors returned by repositories; ToStatus maps them to gRPC codes
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrAlreadyExists      = errors.New("already exists")
+	ErrInvalidArgument    = errors.New("invalid argument")
+	ErrFailedPrecondition = errors.New("failed precondition")
+)
+
+// ToStatus converts a repository error to a gRPC status error, prefixing msg.
+// Errors that do not wrap a domain error become Internal; those are logged
+// and only msg is returned, so database details never reach the caller.
+func ToStatus(err error, msg string) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var code codes.Code
+	switch {
+	case errors.Is(err, ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, ErrAlreadyExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, ErrInvalidArgument):
+		code = codes.InvalidArgument
+	case errors.Is(err, ErrFailedPrecondition):
+		code = codes.FailedPrecondition
+	default:
+		logger.Log.Error(msg, zap.Error(err))
+		return status.Error(codes.Internal, msg)
+	}
+	return status.Errorf(code, "%s: %v", msg, err)
+}
+
+// ExtractUserIDFromContext returns the user id of the caller's access token
+func ExtractUserIDFromContext(ctx context.Context) (string, error) {
+	userID, ok := ctx.Value(UserIDKey).(string)
+	if !ok || userID == "" {
+		return "", status.Error(codes.Unauthenticated, "authentication claims missing from context")
 	}
+	return userID, nil
+}
 
-	tenantValues := md.Get("X-Tenant")
-	if len(tenantValues) > 0 && tenantValues[0] != "" {
-		return tenantValues[0], nil
+// RequireRole fails with PermissionDenied unless the caller's role is one of roles
+func RequireRole(ctx context.Context, roles ...string) error {
+	role, _ := ctx.Value(RoleKey).(string)
+	for _, allowed := range roles {
+		if strings.EqualFold(role, allowed) {
+			return nil
+		}
 	}
+	return status.Error(codes.PermissionDenied, "action requires one of the roles "+strings.Join(roles, ", "))
+}
+
+// Paging metadata. The list RPCs predate paging in the protos, so the page is
+// requested with x-page / x-page-size and the total is returned in the
+// x-total-count response header.
+const (
+	PageHeader       = "x-page"
+	PageSizeHeader   = "x-page-size"
+	TotalCountHeader = "x-total-count"
 
-	fmt.Printf("Metadata: %v\n", md)
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// PageFromContext reads the requested page (starting at 1) and page size from
+// the incoming metadata, applying the defaults and MaxPageSize
+func PageFromContext(ctx context.Context) (page, pageSize int, err error) {
+	page, pageSize = 1, DefaultPageSize
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return page, pageSize, nil
+	}
 
-	hostValues := md.Get(":authority")
-	if len(hostValues) > 0 {
-		host := hostValues[0]
-		parts := strings.Split(host, ".")
-		if len(parts) > 0 && parts[0] != "" {
-			return parts[0], nil
+	if values := md.Get(PageHeader); len(values) > 0 && values[0] != "" {
+		if page, err = strconv.Atoi(values[0]); err != nil || page < 1 {
+			return 0, 0, status.Errorf(codes.InvalidArgument, "invalid %s: %s", PageHeader, values[0])
+		}
+	}
+	if values := md.Get(PageSizeHeader); len(values) > 0 && values[0] != "" {
+		if pageSize, err = strconv.Atoi(values[0]); err != nil || pageSize < 1 {
+			return 0, 0, status.Errorf(codes.InvalidArgument, "invalid %s: %s", PageSizeHeader, values[0])
 		}
+		pageSize = min(pageSize, MaxPageSize)
 	}
+	return page, pageSize, nil
+}
 
-	return "", status.Error(codes.Unauthenticated, "tenant not specified")
+// SetTotalCount sends the total number of items of a paged list as a header
+func SetTotalCount(ctx context.Context, total int64) {
+	_ = grpc.SetHeader(ctx, metadata.Pairs(TotalCountHeader, strconv.FormatInt(total, 10)))
+}
+
+// Filter metadata for list RPCs whose request messages carry no filter fields
+const (
+	QueryHeader  = "x-query"
+	RoleHeader   = "x-role"
+	StudioHeader = "x-studio-id"
+)
+
+// MetadataValue returns the first value of key in the incoming metadata
+func MetadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
 }
@@ -0,0 +1,255 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// NotificationRepository stores notifications in the tenant's database and
+// fans staff notifications out over the tenant's Redis
+type NotificationRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewNotificationRepository creates a new NotificationRepository
+func NewNotificationRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *NotificationRepository {
+	return &NotificationRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+const notificationColumns = `id, COALESCE(user_id::text, ''), COALESCE(customer_id::text, ''), kind, title,
+	COALESCE(body, ''), data, read_at, created_at`
+
+func channel(tenant, userID string) string {
+	return fmt.Sprintf("notifications:%s:%s", tenant, userID)
+}
+
+// Notify stores the notifications in one transaction and then publishes the
+// ones addressed to staff. Publishing is best effort: an error after the
+// insert means the notifications are saved but were not pushed live.
+func (r *NotificationRepository) Notify(ctx context.Context, tenant string, notifications ...domain.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	for _, n := range notifications {
+		if (n.UserID == "") == (n.CustomerID == "") {
+			return fmt.Errorf("%w: a notification needs exactly one recipient", domain.ErrInvalidArgument)
+		}
+		if n.Kind == "" || n.Title == "" {
+			return fmt.Errorf("%w: notification kind and title are required", domain.ErrInvalidArgument)
+		}
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	stored := make([]domain.Notification, 0, len(notifications))
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		for _, n := range notifications {
+			data := n.Data
+			if data == nil {
+				data = map[string]string{}
+			}
+			created, err := scanNotification(tx.QueryRow(ctx,
+				`INSERT INTO notifications (user_id, customer_id, kind, title, body, data)
+				 VALUES (NULLIF($1, '')::uuid, NULLIF($2, '')::uuid, $3, $4, NULLIF($5, ''), $6)
+				 RETURNING `+notificationColumns,
+				n.UserID, n.CustomerID, n.Kind, n.Title, n.Body, data))
+			if err != nil {
+				return domain.WrapError("failed to store notification", err)
+			}
+			stored = append(stored, *created)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	client, err := r.RedisManager.GetTenantRedis(tenant)
+	if err != nil {
+		return fmt.Errorf("failed to publish notifications: %w", err)
+	}
+	var errs []error
+	for _, n := range stored {
+		if n.UserID == "" {
+			continue
+		}
+		payload, err := json.Marshal(n)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err = client.Publish(ctx, channel(tenant, n.UserID), payload).Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err = errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to publish notifications: %w", err)
+	}
+	return nil
+}
+
+// List pages through a user's notifications, newest first
+func (r *NotificationRepository) List(ctx context.Context, tenant, userID string, unreadOnly bool, page, pageSize int) (domain.PagedResult[domain.Notification], error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = domain.DefaultPageSize
+	}
+	result := domain.PagedResult[domain.Notification]{Items: []domain.Notification{}, Page: page, PageSize: pageSize}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return result, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	where := "user_id = $1"
+	if unreadOnly {
+		where += " AND read_at IS NULL"
+	}
+	if err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM notifications WHERE "+where, userID).Scan(&result.TotalCount); err != nil {
+		return result, domain.WrapError("failed to count notifications", err)
+	}
+
+	rows, err := pool.Query(ctx,
+		"SELECT "+notificationColumns+" FROM notifications WHERE "+where+
+			" ORDER BY created_at DESC, id LIMIT $2 OFFSET $3",
+		userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return result, domain.WrapError("failed to query notifications", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return result, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		result.Items = append(result.Items, *n)
+	}
+	if err = rows.Err(); err != nil {
+		return result, domain.WrapError("failed to query notifications", err)
+	}
+
+	return result, nil
+}
+
+// MarkRead marks the given notifications of userID as read; no ids marks all
+func (r *NotificationRepository) MarkRead(ctx context.Context, tenant, userID string, ids []string) error {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	_, err = pool.Exec(ctx,
+		`UPDATE notifications SET read_at = $1
+		 WHERE user_id = $2 AND read_at IS NULL AND (cardinality($3::uuid[]) = 0 OR id = ANY($3::uuid[]))`,
+		time.Now(), userID, ids)
+	if err != nil {
+		return domain.WrapError("failed to mark notifications read", err)
+	}
+	return nil
+}
+
+// AddDevice registers a push token for userID; registering it again is a no-op
+func (r *NotificationRepository) AddDevice(ctx context.Context, tenant, userID, deviceToken string) error {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	_, err = pool.Exec(ctx,
+		`INSERT INTO notification_devices (user_id, device_token) VALUES ($1, $2)
+		 ON CONFLICT (user_id, device_token) DO NOTHING`,
+		userID, deviceToken)
+	if err != nil {
+		return domain.WrapError("failed to add device", err)
+	}
+	return nil
+}
+
+func (r *NotificationRepository) RemoveDevice(ctx context.Context, tenant, userID, deviceToken string) error {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	tag, err := pool.Exec(ctx,
+		"DELETE FROM notification_devices WHERE user_id = $1 AND device_token = $2",
+		userID, deviceToken)
+	if err != nil {
+		return domain.WrapError("failed to remove device", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("device: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+// Listen subscribes to the user's Redis channel. The returned channel is
+// closed once ctx is done or the subscription fails.
+func (r *NotificationRepository) Listen(ctx context.Context, tenant, userID string) (<-chan domain.Notification, error) {
+	client, err := r.RedisManager.GetTenantRedis(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	sub := client.Subscribe(ctx, channel(tenant, userID))
+	// Wait for the confirmation so nothing published after Listen returns is missed
+	if _, err = sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, fmt.Errorf("failed to subscribe to notifications: %w", err)
+	}
+
+	out := make(chan domain.Notification)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+
+		messages := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var n domain.Notification
+				if err := json.Unmarshal([]byte(msg.Payload), &n); err != nil {
+					continue
+				}
+				select {
+				case out <- n:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func scanNotification(row pgx.Row) (*domain.Notification, error) {
+	var n domain.Notification
+	err := row.Scan(&n.ID, &n.UserID, &n.CustomerID, &n.Kind, &n.Title, &n.Body, &n.Data, &n.ReadAt, &n.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
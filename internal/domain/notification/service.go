@@ -0,0 +1,203 @@
+package notification
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+	upn "github.com/FACorreiaa/ink-app-backend-protos/modules/notifications/generated"
+)
+
+// InboxServiceName is the fully qualified gRPC name of the inbox service. The
+// notification protos only stream live notifications, so reading the stored
+// ones lives here.
+const InboxServiceName = "inkMe.notification.InboxService"
+
+type ListNotificationsRequest struct {
+	UnreadOnly bool `json:"unread_only"`
+	Page       int  `json:"page"`
+	PageSize   int  `json:"page_size"`
+}
+
+type NotificationItem struct {
+	ID        string            `json:"id"`
+	Kind      string            `json:"kind"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	Data      map[string]string `json:"data,omitempty"`
+	Read      bool              `json:"read"`
+	CreatedAt string            `json:"created_at"`
+}
+
+type ListNotificationsResponse struct {
+	Notifications []NotificationItem `json:"notifications"`
+	TotalCount    int64              `json:"total_count"`
+}
+
+type MarkReadRequest struct {
+	// IDs to mark as read; empty marks every notification
+	IDs []string `json:"ids"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// NotificationService implements the notification gRPC services. Callers only
+// ever see their own notifications.
+type NotificationService struct {
+	upn.UnimplementedNotificationServiceServer
+	repo domain.NotificationRepository
+}
+
+// NewNotificationService creates a new NotificationService
+func NewNotificationService(repo domain.NotificationRepository) *NotificationService {
+	return &NotificationService{repo: repo}
+}
+
+// Register adds the inbox service to a gRPC server
+func (s *NotificationService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(InboxServiceName,
+		structrpc.Unary(InboxServiceName, "ListNotifications", s.ListNotifications),
+		structrpc.Unary(InboxServiceName, "MarkRead", s.MarkRead),
+	), s)
+}
+
+// Subscribe registers a push device token for the caller
+func (s *NotificationService) Subscribe(ctx context.Context, req *upn.SubscribeRequest) (*upn.SubscribeResponse, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "Subscribe")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if err = checkRecipient(userID, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if req.DeviceToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "device_token is required")
+	}
+
+	if err = s.repo.AddDevice(ctx, tenant, userID, req.DeviceToken); err != nil {
+		return nil, domain.ToStatus(err, "failed to subscribe")
+	}
+
+	return &upn.SubscribeResponse{Message: "Subscribed successfully"}, nil
+}
+
+func (s *NotificationService) Unsubscribe(ctx context.Context, req *upn.UnsubscribeRequest) (*upn.UnsubscribeResponse, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "Unsubscribe")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if err = checkRecipient(userID, req.UserId); err != nil {
+		return nil, err
+	}
+
+	if req.DeviceToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "device_token is required")
+	}
+
+	if err = s.repo.RemoveDevice(ctx, tenant, userID, req.DeviceToken); err != nil {
+		return nil, domain.ToStatus(err, "failed to unsubscribe")
+	}
+
+	return &upn.UnsubscribeResponse{Message: "Unsubscribed successfully"}, nil
+}
+
+// StreamNotifications pushes the caller's notifications as they are created
+// until the client goes away
+func (s *NotificationService) StreamNotifications(req *upn.StreamNotificationsRequest, stream grpc.ServerStreamingServer[upn.Notification]) error {
+	ctx, span, tenant, userID, err := domain.StartUserCall(stream.Context(), "StreamNotifications")
+	if err != nil {
+		return err
+	}
+	defer span.End()
+
+	if err = checkRecipient(userID, req.UserId); err != nil {
+		return err
+	}
+
+	notifications, err := s.repo.Listen(ctx, tenant, userID)
+	if err != nil {
+		return domain.ToStatus(err, "failed to stream notifications")
+	}
+
+	for n := range notifications {
+		err = stream.Send(&upn.Notification{
+			Title:     n.Title,
+			Body:      n.Body,
+			Timestamp: n.CreatedAt.Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// ListNotifications pages through the caller's notifications, newest first
+func (s *NotificationService) ListNotifications(ctx context.Context, req *ListNotificationsRequest) (*ListNotificationsResponse, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "ListNotifications")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	pageSize := min(req.PageSize, domain.MaxPageSize)
+	result, err := s.repo.List(ctx, tenant, userID, req.UnreadOnly, req.Page, pageSize)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list notifications")
+	}
+
+	res := &ListNotificationsResponse{
+		Notifications: make([]NotificationItem, 0, len(result.Items)),
+		TotalCount:    result.TotalCount,
+	}
+	for _, n := range result.Items {
+		res.Notifications = append(res.Notifications, NotificationItem{
+			ID:        n.ID,
+			Kind:      n.Kind,
+			Title:     n.Title,
+			Body:      n.Body,
+			Data:      n.Data,
+			Read:      n.ReadAt != nil,
+			CreatedAt: n.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	span.SetAttributes(attribute.Int("notifications.count", len(res.Notifications)))
+
+	return res, nil
+}
+
+func (s *NotificationService) MarkRead(ctx context.Context, req *MarkReadRequest) (*MessageResponse, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "MarkRead")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if err = s.repo.MarkRead(ctx, tenant, userID, req.IDs); err != nil {
+		return nil, domain.ToStatus(err, "failed to mark notifications read")
+	}
+
+	return &MessageResponse{Message: "Notifications marked as read"}, nil
+}
+
+// checkRecipient fails unless a requested user id is empty or the caller's own
+func checkRecipient(userID, requested string) error {
+	if requested != "" && requested != userID {
+		return status.Error(codes.PermissionDenied, "notifications can only be managed by their recipient")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// FakeProvider is an in-memory PaymentProvider for development and tests. It
+// accepts every payment; intents are lost when the process exits.
+type FakeProvider struct {
+	mu      sync.Mutex
+	intents map[string]*fakeIntent
+}
+
+type fakeIntent struct {
+	amount   int64
+	refunded int64
+	captured bool
+}
+
+// NewFakeProvider creates a new FakeProvider
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{intents: make(map[string]*fakeIntent)}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreateIntent(_ context.Context, amount int64, _ string, _ map[string]string) (*domain.PaymentIntent, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", domain.ErrInvalidArgument)
+	}
+	id, secret := "fake_pi_"+randomHex(12), "fake_secret_"+randomHex(16)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.intents[id] = &fakeIntent{amount: amount}
+	return &domain.PaymentIntent{ID: id, ClientSecret: secret}, nil
+}
+
+// Capture is idempotent, like the capture of real processors
+func (p *FakeProvider) Capture(_ context.Context, intentID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return fmt.Errorf("payment intent %s: %w", intentID, domain.ErrNotFound)
+	}
+	intent.captured = true
+	return nil
+}
+
+func (p *FakeProvider) Refund(_ context.Context, intentID string, amount int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return fmt.Errorf("payment intent %s: %w", intentID, domain.ErrNotFound)
+	}
+	if !intent.captured {
+		return fmt.Errorf("%w: payment intent %s was not captured", domain.ErrFailedPrecondition, intentID)
+	}
+	if amount <= 0 || intent.refunded+amount > intent.amount {
+		return fmt.Errorf("%w: refund exceeds the captured amount", domain.ErrInvalidArgument)
+	}
+	intent.refunded += amount
+	return nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
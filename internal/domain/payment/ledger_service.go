@@ -0,0 +1,234 @@
+package payment
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// LedgerServiceName is the fully qualified gRPC name of the ledger service.
+// The payment protos have no ledger, so balances, charges and forfeits live
+// here. All amounts are in minor units.
+const LedgerServiceName = "inkMe.payment.LedgerService"
+
+type BalanceRequest struct {
+	CustomerID string `json:"customer_id"`
+}
+
+type BalanceAmount struct {
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+}
+
+type BalanceResponse struct {
+	CustomerID string          `json:"customer_id"`
+	Balances   []BalanceAmount `json:"balances"`
+}
+
+type ListLedgerRequest struct {
+	CustomerID    string `json:"customer_id"`
+	AppointmentID string `json:"appointment_id"`
+	Page          int    `json:"page"`
+	PageSize      int    `json:"page_size"`
+}
+
+type LedgerEntryOutput struct {
+	ID            string `json:"id"`
+	CustomerID    string `json:"customer_id"`
+	AppointmentID string `json:"appointment_id,omitempty"`
+	PaymentID     string `json:"payment_id,omitempty"`
+	Type          string `json:"type"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Description   string `json:"description,omitempty"`
+	CreatedBy     string `json:"created_by,omitempty"`
+	CreatedAt     string `json:"created_at"`
+}
+
+type ListLedgerResponse struct {
+	Entries    []LedgerEntryOutput `json:"entries"`
+	TotalCount int64               `json:"total_count"`
+}
+
+type RecordChargeRequest struct {
+	CustomerID    string `json:"customer_id"`
+	AppointmentID string `json:"appointment_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Description   string `json:"description"`
+}
+
+type ForfeitDepositRequest struct {
+	AppointmentID string `json:"appointment_id"`
+}
+
+type ForfeitDepositResponse struct {
+	Entry   *LedgerEntryOutput `json:"entry,omitempty"`
+	Message string             `json:"message"`
+}
+
+// LedgerService implements the ledger gRPC service
+type LedgerService struct {
+	repo domain.PaymentRepository
+}
+
+// NewLedgerService creates a new LedgerService
+func NewLedgerService(repo domain.PaymentRepository) *LedgerService {
+	return &LedgerService{repo: repo}
+}
+
+// Register adds the service to a gRPC server
+func (s *LedgerService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(LedgerServiceName,
+		structrpc.Unary(LedgerServiceName, "GetBalance", s.GetBalance),
+		structrpc.Unary(LedgerServiceName, "ListLedger", s.ListLedger),
+		structrpc.Unary(LedgerServiceName, "RecordCharge", s.RecordCharge),
+		structrpc.Unary(LedgerServiceName, "ForfeitDeposit", s.ForfeitDeposit),
+	), s)
+}
+
+// GetBalance returns a customer's balance per currency. Positive balances are
+// credit, negative ones are owed to the studio.
+func (s *LedgerService) GetBalance(ctx context.Context, req *BalanceRequest) (*BalanceResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetBalance")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.CustomerID == "" {
+		return nil, status.Error(codes.InvalidArgument, "customer_id is required")
+	}
+
+	balances, err := s.repo.Balances(ctx, tenant, req.CustomerID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get balance")
+	}
+
+	res := &BalanceResponse{CustomerID: req.CustomerID, Balances: make([]BalanceAmount, 0, len(balances))}
+	for _, balance := range balances {
+		res.Balances = append(res.Balances, BalanceAmount{Currency: balance.Currency, Amount: balance.Amount})
+	}
+	return res, nil
+}
+
+// ListLedger pages through the ledger of a customer or an appointment
+func (s *LedgerService) ListLedger(ctx context.Context, req *ListLedgerRequest) (*ListLedgerResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ListLedger")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.CustomerID == "" && req.AppointmentID == "" {
+		return nil, status.Error(codes.InvalidArgument, "customer_id or appointment_id is required")
+	}
+
+	result, err := s.repo.ListLedger(ctx, tenant, domain.LedgerFilter{
+		CustomerID:    req.CustomerID,
+		AppointmentID: req.AppointmentID,
+		Page:          req.Page,
+		PageSize:      min(req.PageSize, domain.MaxPageSize),
+	})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list ledger")
+	}
+
+	res := &ListLedgerResponse{
+		Entries:    make([]LedgerEntryOutput, 0, len(result.Items)),
+		TotalCount: result.TotalCount,
+	}
+	for i := range result.Items {
+		res.Entries = append(res.Entries, *ledgerEntryOutput(&result.Items[i]))
+	}
+
+	span.SetAttributes(attribute.Int("ledger.count", len(res.Entries)))
+
+	return res, nil
+}
+
+// RecordCharge debits a customer, e.g. for the price of a session. The amount
+// is the positive price.
+func (s *LedgerService) RecordCharge(ctx context.Context, req *RecordChargeRequest) (*LedgerEntryOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "RecordCharge")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if err = domain.RequireRole(ctx, managerRoles...); err != nil {
+		return nil, err
+	}
+	currency, err := NormalizeCurrency(req.Currency)
+	if err != nil {
+		return nil, domain.ToStatus(err, "invalid currency")
+	}
+	userID, _ := domain.ExtractUserIDFromContext(ctx)
+
+	entry := &domain.LedgerEntry{
+		CustomerID:    req.CustomerID,
+		AppointmentID: req.AppointmentID,
+		Amount:        req.Amount,
+		Currency:      currency,
+		Description:   req.Description,
+		CreatedBy:     userID,
+	}
+	if err = s.repo.RecordCharge(ctx, tenant, entry); err != nil {
+		return nil, domain.ToStatus(err, "failed to record charge")
+	}
+
+	span.SetAttributes(attribute.String("ledger.id", entry.ID))
+
+	return ledgerEntryOutput(entry), nil
+}
+
+// ForfeitDeposit keeps an appointment's paid deposit. Marking the appointment
+// NO_SHOW does the same automatically.
+func (s *LedgerService) ForfeitDeposit(ctx context.Context, req *ForfeitDepositRequest) (*ForfeitDepositResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ForfeitDeposit")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if err = domain.RequireRole(ctx, managerRoles...); err != nil {
+		return nil, err
+	}
+	if req.AppointmentID == "" {
+		return nil, status.Error(codes.InvalidArgument, "appointment_id is required")
+	}
+	userID, _ := domain.ExtractUserIDFromContext(ctx)
+
+	entry, err := s.repo.ForfeitDeposit(ctx, tenant, req.AppointmentID, userID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to forfeit deposit")
+	}
+
+	res := &ForfeitDepositResponse{Message: "Deposit forfeited successfully"}
+	if entry != nil {
+		res.Entry = ledgerEntryOutput(entry)
+	}
+	return res, nil
+}
+
+func ledgerEntryOutput(entry *domain.LedgerEntry) *LedgerEntryOutput {
+	return &LedgerEntryOutput{
+		ID:            entry.ID,
+		CustomerID:    entry.CustomerID,
+		AppointmentID: entry.AppointmentID,
+		PaymentID:     entry.PaymentID,
+		Type:          entry.Type,
+		Amount:        entry.Amount,
+		Currency:      entry.Currency,
+		Description:   entry.Description,
+		CreatedBy:     entry.CreatedBy,
+		CreatedAt:     entry.CreatedAt.Format(time.RFC3339),
+	}
+}
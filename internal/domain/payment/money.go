@@ -0,0 +1,58 @@
+package payment
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// minorUnitExponents lists the ISO 4217 currencies whose minor unit is not a
+// hundredth of the major unit
+var minorUnitExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// NormalizeCurrency upper-cases a three-letter currency code and rejects
+// anything else
+func NormalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) != 3 || strings.Trim(currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("%w: invalid currency %q, expected an ISO 4217 code", domain.ErrInvalidArgument, currency)
+	}
+	return currency, nil
+}
+
+// ToMinor converts an amount in major units, as carried by the payment protos,
+// to minor units of currency
+func ToMinor(amount float64, currency string) (int64, error) {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) || amount < 0 {
+		return 0, fmt.Errorf("%w: invalid amount %v", domain.ErrInvalidArgument, amount)
+	}
+	minor := math.Round(amount * math.Pow10(exponent(currency)))
+	if minor > math.MaxInt64/2 {
+		return 0, fmt.Errorf("%w: amount %v is too large", domain.ErrInvalidArgument, amount)
+	}
+	return int64(minor), nil
+}
+
+// ToMajor converts minor units of currency back to major units
+func ToMajor(amount int64, currency string) float64 {
+	return float64(amount) / math.Pow10(exponent(currency))
+}
+
+// FormatAmount renders minor units of currency, e.g. "12.50 EUR"
+func FormatAmount(amount int64, currency string) string {
+	return strconv.FormatFloat(ToMajor(amount, currency), 'f', exponent(currency), 64) + " " + currency
+}
+
+func exponent(currency string) int {
+	if e, ok := minorUnitExponents[strings.ToUpper(currency)]; ok {
+		return e
+	}
+	return 2
+}
@@ -0,0 +1,32 @@
+package payment
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// Payment providers selectable in the config
+const (
+	ProviderNone = ""
+	ProviderFake = "fake"
+)
+
+// NewProvider creates the configured PaymentProvider, or nil when payments
+// are disabled. The fake is refused outside development: it accepts every
+// capture and forgets its intents on restart and across replicas.
+func NewProvider(cfg config.PaymentsConfig, mode string) (domain.PaymentProvider, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case ProviderNone:
+		return nil, nil
+	case ProviderFake:
+		if mode != config.ModeDevelopment {
+			return nil, fmt.Errorf("the fake payment provider is only allowed in %s mode, not %q", config.ModeDevelopment, mode)
+		}
+		return NewFakeProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", cfg.Provider)
+	}
+}
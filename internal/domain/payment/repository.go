@@ -0,0 +1,599 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// PaymentRepository stores payments and the ledger in the tenant's database
+type PaymentRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewPaymentRepository creates a new PaymentRepository
+func NewPaymentRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *PaymentRepository {
+	return &PaymentRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+const paymentColumns = `id, studio_id, customer_id, COALESCE(appointment_id::text, ''), kind, amount, refunded_amount,
+	currency, status, provider, COALESCE(provider_ref, ''), COALESCE(failure_reason, ''),
+	created_at, COALESCE(updated_at, created_at)`
+
+const ledgerColumns = `id, studio_id, customer_id, COALESCE(appointment_id::text, ''), COALESCE(payment_id::text, ''),
+	type, amount, currency, COALESCE(description, ''), COALESCE(created_by::text, ''), created_at`
+
+// CreatePayment stores a PENDING payment. The studio and customer default to
+// those of the appointment. Creating a deposit marks the appointment's
+// deposit as REQUIRED; a paid deposit cannot be requested again.
+func (r *PaymentRepository) CreatePayment(ctx context.Context, tenant string, payment *domain.Payment) error {
+	if payment == nil {
+		return fmt.Errorf("%w: payment is required", domain.ErrInvalidArgument)
+	}
+	if payment.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be positive", domain.ErrInvalidArgument)
+	}
+	if payment.Kind == domain.PaymentKindDeposit && payment.AppointmentID == "" {
+		return fmt.Errorf("%w: a deposit needs an appointment", domain.ErrInvalidArgument)
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if payment.AppointmentID != "" {
+			var studioID, customerID, status, depositStatus string
+			err := tx.QueryRow(ctx,
+				"SELECT studio_id, customers_id, status, deposit_status FROM appointments WHERE id = $1 FOR UPDATE",
+				payment.AppointmentID).Scan(&studioID, &customerID, &status, &depositStatus)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("appointment %s: %w", payment.AppointmentID, domain.ErrNotFound)
+			}
+			if err != nil {
+				return domain.WrapError("failed to get appointment", err)
+			}
+			if payment.CustomerID != "" && payment.CustomerID != customerID {
+				return fmt.Errorf("%w: the appointment belongs to another customer", domain.ErrInvalidArgument)
+			}
+			payment.StudioID, payment.CustomerID = studioID, customerID
+
+			if payment.Kind == domain.PaymentKindDeposit {
+				if !strings.EqualFold(status, domain.AppointmentScheduled) {
+					return fmt.Errorf("%w: deposits can only be requested for scheduled appointments", domain.ErrFailedPrecondition)
+				}
+				if depositStatus == domain.DepositPaid {
+					return fmt.Errorf("%w: the deposit is already paid", domain.ErrFailedPrecondition)
+				}
+				_, err = tx.Exec(ctx,
+					`UPDATE appointments SET deposit_amount = $1, deposit_currency = $2, deposit_status = $3, updated_at = $4
+					 WHERE id = $5`,
+					payment.Amount, payment.Currency, domain.DepositRequired, time.Now(), payment.AppointmentID)
+				if err != nil {
+					return domain.WrapError("failed to require deposit", err)
+				}
+			}
+		}
+		if payment.CustomerID == "" {
+			return fmt.Errorf("%w: customer is required", domain.ErrInvalidArgument)
+		}
+		if payment.StudioID == "" {
+			err := tx.QueryRow(ctx, "SELECT studio_id FROM customers WHERE id = $1", payment.CustomerID).Scan(&payment.StudioID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("customer %s: %w", payment.CustomerID, domain.ErrNotFound)
+			}
+			if err != nil {
+				return domain.WrapError("failed to get customer", err)
+			}
+		}
+
+		created, err := scanPayment(tx.QueryRow(ctx,
+			`INSERT INTO payments (studio_id, customer_id, appointment_id, kind, amount, currency, status, provider)
+			 VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6, $7, $8)
+			 RETURNING `+paymentColumns,
+			payment.StudioID, payment.CustomerID, payment.AppointmentID, payment.Kind,
+			payment.Amount, payment.Currency, domain.PaymentPending, payment.Provider))
+		if err != nil {
+			return domain.WrapError("failed to create payment", err)
+		}
+		*payment = *created
+		return nil
+	})
+}
+
+// AttachIntent records the provider's intent id of a pending payment
+func (r *PaymentRepository) AttachIntent(ctx context.Context, tenant, id, providerRef string) error {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	tag, err := pool.Exec(ctx,
+		"UPDATE payments SET provider_ref = $1, updated_at = $2 WHERE id = $3 AND status = $4",
+		providerRef, time.Now(), id, domain.PaymentPending)
+	if err != nil {
+		return domain.WrapError("failed to attach payment intent", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("pending payment %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// FailPayment closes a pending payment the provider rejected
+func (r *PaymentRepository) FailPayment(ctx context.Context, tenant, id, reason string) error {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	tag, err := pool.Exec(ctx,
+		"UPDATE payments SET status = $1, failure_reason = NULLIF($2, ''), updated_at = $3 WHERE id = $4 AND status = $5",
+		domain.PaymentFailed, reason, time.Now(), id, domain.PaymentPending)
+	if err != nil {
+		return domain.WrapError("failed to fail payment", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("pending payment %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *PaymentRepository) GetPayment(ctx context.Context, tenant, id string) (*domain.Payment, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	payment, err := scanPayment(pool.QueryRow(ctx, "SELECT "+paymentColumns+" FROM payments WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("payment %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get payment", err)
+	}
+	return payment, nil
+}
+
+// ListPayments pages through the payments matching the filter, newest first
+func (r *PaymentRepository) ListPayments(ctx context.Context, tenant string, filter domain.PaymentFilter) (domain.PagedResult[domain.Payment], error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = domain.DefaultPageSize
+	}
+	result := domain.PagedResult[domain.Payment]{Items: []domain.Payment{}, Page: filter.Page, PageSize: filter.PageSize}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return result, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	where := []string{"TRUE"}
+	var args []interface{}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if filter.StudioID != "" {
+		add("studio_id = $%d", filter.StudioID)
+	}
+	if filter.CustomerID != "" {
+		add("customer_id = $%d", filter.CustomerID)
+	}
+	if filter.AppointmentID != "" {
+		add("appointment_id = $%d", filter.AppointmentID)
+	}
+	whereClause := strings.Join(where, " AND ")
+
+	if err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM payments WHERE "+whereClause, args...).Scan(&result.TotalCount); err != nil {
+		return result, domain.WrapError("failed to count payments", err)
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	rows, err := pool.Query(ctx,
+		"SELECT "+paymentColumns+" FROM payments WHERE "+whereClause+
+			fmt.Sprintf(" ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d", len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return result, domain.WrapError("failed to query payments", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return result, fmt.Errorf("failed to scan payment: %w", err)
+		}
+		result.Items = append(result.Items, *payment)
+	}
+	if err = rows.Err(); err != nil {
+		return result, domain.WrapError("failed to query payments", err)
+	}
+
+	return result, nil
+}
+
+// CompletePayment is idempotent: completing a SUCCEEDED payment returns it
+// unchanged, so a capture retried after a lost response does not double the
+// ledger entry
+func (r *PaymentRepository) CompletePayment(ctx context.Context, tenant, id string) (*domain.Payment, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var completed *domain.Payment
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		current, err := lockPayment(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if current.Status == domain.PaymentSucceeded {
+			completed = current
+			return nil
+		}
+		if current.Status != domain.PaymentPending {
+			return fmt.Errorf("%w: payment is %s", domain.ErrFailedPrecondition, current.Status)
+		}
+
+		completed, err = scanPayment(tx.QueryRow(ctx,
+			"UPDATE payments SET status = $1, updated_at = $2 WHERE id = $3 RETURNING "+paymentColumns,
+			domain.PaymentSucceeded, time.Now(), id))
+		if err != nil {
+			return domain.WrapError("failed to complete payment", err)
+		}
+
+		entryType := domain.LedgerPayment
+		if completed.Kind == domain.PaymentKindDeposit {
+			entryType = domain.LedgerDeposit
+			_, err = tx.Exec(ctx,
+				"UPDATE appointments SET deposit_status = $1, updated_at = $2 WHERE id = $3",
+				domain.DepositPaid, time.Now(), completed.AppointmentID)
+			if err != nil {
+				return domain.WrapError("failed to mark deposit paid", err)
+			}
+		}
+		_, err = insertLedgerEntry(ctx, tx, &domain.LedgerEntry{
+			StudioID:      completed.StudioID,
+			CustomerID:    completed.CustomerID,
+			AppointmentID: completed.AppointmentID,
+			PaymentID:     completed.ID,
+			Type:          entryType,
+			Amount:        completed.Amount,
+			Currency:      completed.Currency,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return completed, nil
+}
+
+// Refund keeps the payment locked while refund runs, so the provider is only
+// asked for what is still refundable. A fully refunded deposit returns its
+// appointment's deposit to REFUNDED.
+func (r *PaymentRepository) Refund(ctx context.Context, tenant, id string, amount int64, createdBy string, refund func(*domain.Payment) error) (*domain.Payment, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var refunded *domain.Payment
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		current, err := lockPayment(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if current.Status != domain.PaymentSucceeded {
+			return fmt.Errorf("%w: payment is %s and cannot be refunded", domain.ErrFailedPrecondition, current.Status)
+		}
+		remaining := current.Amount - current.RefundedAmount
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount < 0 || amount > remaining {
+			return fmt.Errorf("%w: at most %d can be refunded", domain.ErrInvalidArgument, remaining)
+		}
+		if current.Kind == domain.PaymentKindDeposit {
+			var depositStatus string
+			err := tx.QueryRow(ctx, "SELECT deposit_status FROM appointments WHERE id = $1 FOR UPDATE",
+				current.AppointmentID).Scan(&depositStatus)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return domain.WrapError("failed to get deposit", err)
+			}
+			if depositStatus == domain.DepositForfeited {
+				return fmt.Errorf("%w: the deposit was forfeited", domain.ErrFailedPrecondition)
+			}
+		}
+
+		if err = refund(current); err != nil {
+			return err
+		}
+
+		status := domain.PaymentSucceeded
+		if current.RefundedAmount+amount == current.Amount {
+			status = domain.PaymentRefunded
+		}
+		refunded, err = scanPayment(tx.QueryRow(ctx,
+			`UPDATE payments SET refunded_amount = refunded_amount + $1, status = $2, updated_at = $3
+			 WHERE id = $4 RETURNING `+paymentColumns,
+			amount, status, time.Now(), id))
+		if err != nil {
+			return domain.WrapError("failed to refund payment", err)
+		}
+		if refunded.Kind == domain.PaymentKindDeposit && status == domain.PaymentRefunded && refunded.AppointmentID != "" {
+			_, err = tx.Exec(ctx,
+				"UPDATE appointments SET deposit_status = $1, updated_at = $2 WHERE id = $3",
+				domain.DepositRefunded, time.Now(), refunded.AppointmentID)
+			if err != nil {
+				return domain.WrapError("failed to mark deposit refunded", err)
+			}
+		}
+
+		_, err = insertLedgerEntry(ctx, tx, &domain.LedgerEntry{
+			StudioID:      refunded.StudioID,
+			CustomerID:    refunded.CustomerID,
+			AppointmentID: refunded.AppointmentID,
+			PaymentID:     refunded.ID,
+			Type:          domain.LedgerRefund,
+			Amount:        -amount,
+			Currency:      refunded.Currency,
+			CreatedBy:     createdBy,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refunded, nil
+}
+
+func (r *PaymentRepository) ForfeitDeposit(ctx context.Context, tenant, appointmentID, createdBy string) (*domain.LedgerEntry, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var entry *domain.LedgerEntry
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		entry, err = ForfeitDepositTx(ctx, tx, appointmentID, createdBy)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ForfeitDepositTx forfeits the paid deposit of an appointment inside tx. It
+// fails with ErrFailedPrecondition when there is no paid deposit to keep.
+func ForfeitDepositTx(ctx context.Context, tx pgx.Tx, appointmentID, createdBy string) (*domain.LedgerEntry, error) {
+	var studioID, customerID, currency, depositStatus string
+	var amount int64
+	err := tx.QueryRow(ctx,
+		`SELECT studio_id, customers_id, deposit_amount, COALESCE(deposit_currency, ''), deposit_status
+		 FROM appointments WHERE id = $1 FOR UPDATE`,
+		appointmentID).Scan(&studioID, &customerID, &amount, &currency, &depositStatus)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("appointment %s: %w", appointmentID, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get deposit", err)
+	}
+	if depositStatus != domain.DepositPaid {
+		return nil, fmt.Errorf("%w: deposit is %s, not %s", domain.ErrFailedPrecondition, depositStatus, domain.DepositPaid)
+	}
+
+	// Deposits can be partially refunded before the appointment, so only what
+	// is still held is forfeited
+	var held int64
+	err = tx.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount - refunded_amount), 0) FROM payments
+		 WHERE appointment_id = $1 AND kind = $2 AND status = $3`,
+		appointmentID, domain.PaymentKindDeposit, domain.PaymentSucceeded).Scan(&held)
+	if err != nil {
+		return nil, domain.WrapError("failed to sum deposit", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		"UPDATE appointments SET deposit_status = $1, updated_at = $2 WHERE id = $3",
+		domain.DepositForfeited, time.Now(), appointmentID)
+	if err != nil {
+		return nil, domain.WrapError("failed to forfeit deposit", err)
+	}
+	if held == 0 {
+		return nil, nil
+	}
+
+	return insertLedgerEntry(ctx, tx, &domain.LedgerEntry{
+		StudioID:      studioID,
+		CustomerID:    customerID,
+		AppointmentID: appointmentID,
+		Type:          domain.LedgerForfeit,
+		Amount:        -held,
+		Currency:      currency,
+		Description:   "Deposit forfeited for a no-show",
+		CreatedBy:     createdBy,
+	})
+}
+
+// RecordCharge debits the customer for a service, e.g. the price of a session.
+// entry.Amount is the positive price; it is stored negated.
+func (r *PaymentRepository) RecordCharge(ctx context.Context, tenant string, entry *domain.LedgerEntry) error {
+	if entry == nil || entry.CustomerID == "" {
+		return fmt.Errorf("%w: customer is required", domain.ErrInvalidArgument)
+	}
+	if entry.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be positive", domain.ErrInvalidArgument)
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, "SELECT studio_id FROM customers WHERE id = $1", entry.CustomerID).Scan(&entry.StudioID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("customer %s: %w", entry.CustomerID, domain.ErrNotFound)
+		}
+		if err != nil {
+			return domain.WrapError("failed to get customer", err)
+		}
+
+		entry.Type = domain.LedgerCharge
+		entry.Amount = -entry.Amount
+		created, err := insertLedgerEntry(ctx, tx, entry)
+		if err != nil {
+			return err
+		}
+		*entry = *created
+		return nil
+	})
+}
+
+// ListLedger pages through ledger entries, oldest first
+func (r *PaymentRepository) ListLedger(ctx context.Context, tenant string, filter domain.LedgerFilter) (domain.PagedResult[domain.LedgerEntry], error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = domain.DefaultPageSize
+	}
+	result := domain.PagedResult[domain.LedgerEntry]{Items: []domain.LedgerEntry{}, Page: filter.Page, PageSize: filter.PageSize}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return result, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	where := []string{"TRUE"}
+	var args []interface{}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if filter.CustomerID != "" {
+		add("customer_id = $%d", filter.CustomerID)
+	}
+	if filter.AppointmentID != "" {
+		add("appointment_id = $%d", filter.AppointmentID)
+	}
+	whereClause := strings.Join(where, " AND ")
+
+	if err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM ledger_entries WHERE "+whereClause, args...).Scan(&result.TotalCount); err != nil {
+		return result, domain.WrapError("failed to count ledger entries", err)
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	rows, err := pool.Query(ctx,
+		"SELECT "+ledgerColumns+" FROM ledger_entries WHERE "+whereClause+
+			fmt.Sprintf(" ORDER BY created_at, id LIMIT $%d OFFSET $%d", len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return result, domain.WrapError("failed to query ledger entries", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanLedgerEntry(rows)
+		if err != nil {
+			return result, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		result.Items = append(result.Items, *entry)
+	}
+	if err = rows.Err(); err != nil {
+		return result, domain.WrapError("failed to query ledger entries", err)
+	}
+
+	return result, nil
+}
+
+// Balances sums a customer's ledger per currency
+func (r *PaymentRepository) Balances(ctx context.Context, tenant, customerID string) ([]domain.Balance, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	rows, err := pool.Query(ctx,
+		"SELECT currency, SUM(amount) FROM ledger_entries WHERE customer_id = $1 GROUP BY currency ORDER BY currency",
+		customerID)
+	if err != nil {
+		return nil, domain.WrapError("failed to query balances", err)
+	}
+	defer rows.Close()
+
+	balances := []domain.Balance{}
+	for rows.Next() {
+		var balance domain.Balance
+		if err = rows.Scan(&balance.Currency, &balance.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan balance: %w", err)
+		}
+		balances = append(balances, balance)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to query balances", err)
+	}
+	return balances, nil
+}
+
+func lockPayment(ctx context.Context, tx pgx.Tx, id string) (*domain.Payment, error) {
+	payment, err := scanPayment(tx.QueryRow(ctx, "SELECT "+paymentColumns+" FROM payments WHERE id = $1 FOR UPDATE", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("payment %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get payment", err)
+	}
+	return payment, nil
+}
+
+func insertLedgerEntry(ctx context.Context, tx pgx.Tx, entry *domain.LedgerEntry) (*domain.LedgerEntry, error) {
+	created, err := scanLedgerEntry(tx.QueryRow(ctx,
+		`INSERT INTO ledger_entries (studio_id, customer_id, appointment_id, payment_id, type, amount, currency,
+			description, created_by)
+		 VALUES ($1, $2, NULLIF($3, '')::uuid, NULLIF($4, '')::uuid, $5, $6, $7, NULLIF($8, ''), NULLIF($9, '')::uuid)
+		 RETURNING `+ledgerColumns,
+		entry.StudioID, entry.CustomerID, entry.AppointmentID, entry.PaymentID, entry.Type, entry.Amount,
+		entry.Currency, entry.Description, entry.CreatedBy))
+	if err != nil {
+		return nil, domain.WrapError("failed to record ledger entry", err)
+	}
+	return created, nil
+}
+
+func scanPayment(row pgx.Row) (*domain.Payment, error) {
+	var p domain.Payment
+	err := row.Scan(&p.ID, &p.StudioID, &p.CustomerID, &p.AppointmentID, &p.Kind, &p.Amount, &p.RefundedAmount,
+		&p.Currency, &p.Status, &p.Provider, &p.ProviderRef, &p.FailureReason, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func scanLedgerEntry(row pgx.Row) (*domain.LedgerEntry, error) {
+	var e domain.LedgerEntry
+	err := row.Scan(&e.ID, &e.StudioID, &e.CustomerID, &e.AppointmentID, &e.PaymentID,
+		&e.Type, &e.Amount, &e.Currency, &e.Description, &e.CreatedBy, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,397 @@
+package payment
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	upp "github.com/FACorreiaa/ink-app-backend-protos/modules/payment/generated"
+)
+
+// Metadata of the payment RPCs whose messages lack the field
+const (
+	// AppointmentHeader names the appointment of RequestDeposit
+	AppointmentHeader = "x-appointment-id"
+	// ClientSecretHeader returns the provider's client secret of a deposit
+	ClientSecretHeader = "x-client-secret"
+)
+
+// managerRoles may capture, refund, forfeit and charge. Captures are theirs
+// because no provider webhook confirms payments yet: a capture is the
+// studio's word that the money arrived.
+var managerRoles = []string{"OWNER"}
+
+// PaymentService implements the payment gRPC service. The protos carry amounts
+// in major units; they are stored in minor units of the currency. Without a
+// provider, the RPCs that move money answer Unavailable.
+type PaymentService struct {
+	upp.UnimplementedPaymentServiceServer
+	repo     domain.PaymentRepository
+	provider domain.PaymentProvider
+}
+
+// NewPaymentService creates a new PaymentService
+func NewPaymentService(repo domain.PaymentRepository, provider domain.PaymentProvider) *PaymentService {
+	return &PaymentService{repo: repo, provider: provider}
+}
+
+// CreatePaymentIntent starts a payment for an appointment. The returned
+// intent_id is the payment id to capture; the client secret completes the
+// payment with the provider.
+func (s *PaymentService) CreatePaymentIntent(ctx context.Context, req *upp.CreatePaymentIntentRequest) (*upp.CreatePaymentIntentResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "CreatePaymentIntent")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if req.AppointmentId == "" {
+		return nil, status.Error(codes.InvalidArgument, "appointment_id is required")
+	}
+	payment, err := newPayment(domain.PaymentKindPayment, req.StudioId, req.AppointmentId, req.Amount, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	intent, err := s.start(ctx, tenant, payment)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.String("payment.id", payment.ID))
+
+	return &upp.CreatePaymentIntentResponse{
+		IntentId:     payment.ID,
+		ClientSecret: intent.ClientSecret,
+		Message:      "Payment intent created successfully",
+		Response:     res,
+	}, nil
+}
+
+// CapturePayment completes a payment and credits the customer's ledger
+func (s *PaymentService) CapturePayment(ctx context.Context, req *upp.CapturePaymentRequest) (*upp.CapturePaymentResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "CapturePayment")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if err = domain.RequireRole(ctx, managerRoles...); err != nil {
+		return nil, err
+	}
+	if req.IntentId == "" {
+		return nil, status.Error(codes.InvalidArgument, "intent_id is required")
+	}
+	if _, err = s.capture(ctx, tenant, req.IntentId, ""); err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.String("payment.id", req.IntentId))
+
+	return &upp.CapturePaymentResponse{
+		Message:  "Payment captured successfully",
+		Response: res,
+	}, nil
+}
+
+// RefundPayment returns amount of a payment to the customer; an amount of 0
+// refunds everything not refunded yet
+func (s *PaymentService) RefundPayment(ctx context.Context, req *upp.RefundPaymentRequest) (*upp.RefundPaymentResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "RefundPayment")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if err = domain.RequireRole(ctx, managerRoles...); err != nil {
+		return nil, err
+	}
+	if err = s.requireProvider(); err != nil {
+		return nil, err
+	}
+	if req.PaymentId == "" {
+		return nil, status.Error(codes.InvalidArgument, "payment_id is required")
+	}
+	payment, err := s.repo.GetPayment(ctx, tenant, req.PaymentId)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get payment")
+	}
+	amount, err := ToMinor(req.Amount, payment.Currency)
+	if err != nil {
+		return nil, domain.ToStatus(err, "invalid amount")
+	}
+	userID, _ := domain.ExtractUserIDFromContext(ctx)
+
+	var refunded int64
+	payment, err = s.repo.Refund(ctx, tenant, req.PaymentId, amount, userID, func(p *domain.Payment) error {
+		refunded = amount
+		if refunded == 0 {
+			refunded = p.Amount - p.RefundedAmount
+		}
+		return s.provider.Refund(ctx, p.ProviderRef, refunded)
+	})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to refund payment")
+	}
+
+	span.SetAttributes(
+		attribute.String("payment.id", payment.ID),
+		attribute.Int64("refund.amount", refunded),
+	)
+
+	return &upp.RefundPaymentResponse{
+		Message:  "Refunded " + FormatAmount(refunded, payment.Currency),
+		Response: res,
+	}, nil
+}
+
+func (s *PaymentService) GetPayment(ctx context.Context, req *upp.GetPaymentRequest) (*upp.GetPaymentResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetPayment")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if req.PaymentId == "" {
+		return nil, status.Error(codes.InvalidArgument, "payment_id is required")
+	}
+
+	payment, err := s.repo.GetPayment(ctx, tenant, req.PaymentId)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get payment")
+	}
+
+	return &upp.GetPaymentResponse{
+		Payment:  paymentToProto(payment),
+		Response: res,
+	}, nil
+}
+
+// ListPayments pages through the payments of a studio or client, newest first
+func (s *PaymentService) ListPayments(ctx context.Context, req *upp.ListPaymentsRequest) (*upp.ListPaymentsResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ListPayments")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	page, pageSize, err := domain.PageFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.repo.ListPayments(ctx, tenant, domain.PaymentFilter{
+		StudioID:      req.StudioId,
+		CustomerID:    req.ClientId,
+		AppointmentID: domain.MetadataValue(ctx, AppointmentHeader),
+		Page:          page,
+		PageSize:      pageSize,
+	})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list payments")
+	}
+	domain.SetTotalCount(ctx, result.TotalCount)
+
+	payments := make([]*upp.Payment, 0, len(result.Items))
+	for i := range result.Items {
+		payments = append(payments, paymentToProto(&result.Items[i]))
+	}
+
+	span.SetAttributes(attribute.Int("payments.count", len(payments)))
+
+	return &upp.ListPaymentsResponse{
+		Payments: payments,
+		Response: res,
+	}, nil
+}
+
+// RequestDeposit asks the customer of the appointment named by the
+// x-appointment-id header for a deposit. The appointment's deposit becomes
+// REQUIRED and the provider's client secret is returned in x-client-secret.
+func (s *PaymentService) RequestDeposit(ctx context.Context, req *upp.RequestDepositRequest) (*upp.RequestDepositResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "RequestDeposit")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	appointmentID := domain.MetadataValue(ctx, AppointmentHeader)
+	if appointmentID == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "the %s header is required", AppointmentHeader)
+	}
+	payment, err := newPayment(domain.PaymentKindDeposit, req.StudioId, appointmentID, req.Amount, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	intent, err := s.start(ctx, tenant, payment)
+	if err != nil {
+		return nil, err
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(ClientSecretHeader, intent.ClientSecret))
+
+	span.SetAttributes(
+		attribute.String("payment.id", payment.ID),
+		attribute.String("appointment.id", appointmentID),
+	)
+
+	return &upp.RequestDepositResponse{
+		DepositId: payment.ID,
+		Message:   "Deposit requested successfully",
+		Response:  res,
+	}, nil
+}
+
+// ConfirmDeposit captures a deposit and marks the appointment's deposit PAID
+func (s *PaymentService) ConfirmDeposit(ctx context.Context, req *upp.ConfirmDepositRequest) (*upp.ConfirmDepositResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ConfirmDeposit")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	res := baseResponse(ctx)
+
+	if err = domain.RequireRole(ctx, managerRoles...); err != nil {
+		return nil, err
+	}
+	if req.DepositId == "" {
+		return nil, status.Error(codes.InvalidArgument, "deposit_id is required")
+	}
+	payment, err := s.capture(ctx, tenant, req.DepositId, domain.PaymentKindDeposit)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.String("payment.id", payment.ID))
+
+	return &upp.ConfirmDepositResponse{
+		Message:  "Deposit confirmed successfully",
+		Response: res,
+	}, nil
+}
+
+// start stores a pending payment and opens its intent with the provider. A
+// payment the provider refuses is marked FAILED.
+func (s *PaymentService) start(ctx context.Context, tenant string, payment *domain.Payment) (*domain.PaymentIntent, error) {
+	if err := s.requireProvider(); err != nil {
+		return nil, err
+	}
+	payment.Provider = s.provider.Name()
+	if err := s.repo.CreatePayment(ctx, tenant, payment); err != nil {
+		return nil, domain.ToStatus(err, "failed to create payment")
+	}
+
+	intent, err := s.provider.CreateIntent(ctx, payment.Amount, payment.Currency, map[string]string{
+		"tenant":         tenant,
+		"payment_id":     payment.ID,
+		"appointment_id": payment.AppointmentID,
+	})
+	if err != nil {
+		_ = s.repo.FailPayment(ctx, tenant, payment.ID, err.Error())
+		return nil, domain.ToStatus(err, "payment provider refused the payment")
+	}
+	if err = s.repo.AttachIntent(ctx, tenant, payment.ID, intent.ID); err != nil {
+		return nil, domain.ToStatus(err, "failed to store payment intent")
+	}
+	payment.ProviderRef = intent.ID
+	return intent, nil
+}
+
+// capture asks the provider for the money before recording it. Both steps are
+// idempotent, so a capture that failed half-way can simply be retried. A
+// non-empty kind must match the payment's.
+func (s *PaymentService) capture(ctx context.Context, tenant, id, kind string) (*domain.Payment, error) {
+	if err := s.requireProvider(); err != nil {
+		return nil, err
+	}
+	payment, err := s.repo.GetPayment(ctx, tenant, id)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get payment")
+	}
+	if kind != "" && payment.Kind != kind {
+		return nil, status.Errorf(codes.InvalidArgument, "payment is not a %s", strings.ToLower(kind))
+	}
+	if payment.Status != domain.PaymentPending && payment.Status != domain.PaymentSucceeded {
+		return nil, status.Errorf(codes.FailedPrecondition, "payment is %s", payment.Status)
+	}
+	if payment.ProviderRef == "" {
+		return nil, status.Error(codes.FailedPrecondition, "payment has no provider intent")
+	}
+	if payment.Provider != s.provider.Name() {
+		return nil, status.Errorf(codes.FailedPrecondition, "payment was made with %s, not %s", payment.Provider, s.provider.Name())
+	}
+
+	if err = s.provider.Capture(ctx, payment.ProviderRef); err != nil {
+		return nil, domain.ToStatus(err, "payment provider failed to capture the payment")
+	}
+	if payment, err = s.repo.CompletePayment(ctx, tenant, id); err != nil {
+		return nil, domain.ToStatus(err, "failed to complete payment")
+	}
+	return payment, nil
+}
+
+// requireProvider fails with Unavailable when payments are not configured
+func (s *PaymentService) requireProvider() error {
+	if s.provider == nil {
+		return status.Error(codes.Unavailable, "payments are not configured")
+	}
+	return nil
+}
+
+func newPayment(kind, studioID, appointmentID string, amount float64, currency string) (*domain.Payment, error) {
+	currency, err := NormalizeCurrency(currency)
+	if err != nil {
+		return nil, domain.ToStatus(err, "invalid currency")
+	}
+	minor, err := ToMinor(amount, currency)
+	if err != nil {
+		return nil, domain.ToStatus(err, "invalid amount")
+	}
+	if minor == 0 {
+		return nil, status.Error(codes.InvalidArgument, "amount must be positive")
+	}
+	return &domain.Payment{
+		StudioID:      studioID,
+		AppointmentID: appointmentID,
+		Kind:          kind,
+		Amount:        minor,
+		Currency:      currency,
+	}, nil
+}
+
+func paymentToProto(payment *domain.Payment) *upp.Payment {
+	return &upp.Payment{
+		Id:            payment.ID,
+		StudioId:      payment.StudioID,
+		ClientId:      payment.CustomerID,
+		AppointmentId: payment.AppointmentID,
+		Amount:        ToMajor(payment.Amount, payment.Currency),
+		Currency:      payment.Currency,
+		Status:        payment.Status,
+		CreatedAt:     timestamppb.New(payment.CreatedAt),
+		UpdatedAt:     timestamppb.New(payment.UpdatedAt),
+	}
+}
+
+// baseResponse is the response header of a successful call started by
+// domain.StartCall
+func baseResponse(ctx context.Context) *upp.BaseResponse {
+	return &upp.BaseResponse{
+		Success:   true,
+		RequestId: domain.RequestIDFromContext(ctx),
+		TraceId:   domain.TraceIDFromContext(ctx),
+	}
+}
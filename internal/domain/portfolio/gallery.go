@@ -0,0 +1,249 @@
+package portfolio
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+// Browsers and proxies may keep a gallery page for a few minutes; images
+// never change once stored, so they are kept for a year
+const (
+	galleryCacheControl = "public, max-age=300"
+	imageCacheControl   = "public, max-age=31536000, immutable"
+)
+
+// GalleryItem is a portfolio item as the public sees it
+type GalleryItem struct {
+	ID            string   `json:"id"`
+	ArtistID      string   `json:"artist_id"`
+	ArtistName    string   `json:"artist_name,omitempty"`
+	Title         string   `json:"title,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	Tags          []string `json:"tags"`
+	Style         string   `json:"style,omitempty"`
+	BodyPlacement string   `json:"body_placement,omitempty"`
+	ImageURL      string   `json:"image_url"`
+	ThumbnailURL  string   `json:"thumbnail_url"`
+}
+
+type GalleryResponse struct {
+	Items      []GalleryItem `json:"items"`
+	TotalCount int64         `json:"total_count"`
+	Page       int           `json:"page"`
+	PageSize   int           `json:"page_size"`
+}
+
+// ImagePath is where the gallery serves an item's image variant
+func ImagePath(tenant, itemID, variant string) string {
+	return fmt.Sprintf("/gallery/%s/images/%s/%s", url.PathEscape(tenant), url.PathEscape(itemID), variant)
+}
+
+// NewGalleryItem describes an item for the public, with its images under
+// baseURL
+func NewGalleryItem(baseURL, tenant string, item *domain.PortfolioItem) GalleryItem {
+	out := GalleryItem{
+		ID:            item.ID,
+		ArtistID:      item.ArtistID,
+		ArtistName:    item.ArtistName,
+		Title:         item.Title,
+		Description:   item.Description,
+		Tags:          item.Tags,
+		Style:         item.Style,
+		BodyPlacement: item.BodyPlacement,
+		ImageURL:      baseURL + ImagePath(tenant, item.ID, VariantWeb),
+		ThumbnailURL:  baseURL + ImagePath(tenant, item.ID, VariantThumbnail),
+	}
+	if out.Tags == nil {
+		out.Tags = []string{}
+	}
+	return out
+}
+
+// GalleryHandler serves a studio's gallery to anyone: the listing at
+// /gallery/{tenant} and the web and thumbnail images at
+// /gallery/{tenant}/images/{id}/{variant}. Originals are only reachable
+// through signed URLs. Unknown and suspended tenants answer 404.
+type GalleryHandler struct {
+	repo        domain.PortfolioRepository
+	blobs       domain.BlobStore
+	validTenant func(ctx context.Context, tenant string) error
+	baseURL     string
+	mux         *http.ServeMux
+}
+
+// NewGalleryHandler creates a new GalleryHandler. Image URLs in listings
+// start with baseURL.
+func NewGalleryHandler(repo domain.PortfolioRepository, blobs domain.BlobStore, validTenant func(ctx context.Context, tenant string) error, baseURL string) *GalleryHandler {
+	h := &GalleryHandler{
+		repo:        repo,
+		blobs:       blobs,
+		validTenant: validTenant,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		mux:         http.NewServeMux(),
+	}
+	h.mux.HandleFunc("GET /gallery/{tenant}", h.serveListing)
+	h.mux.HandleFunc("GET /gallery/{tenant}/images/{id}/{variant}", h.serveImage)
+	return h
+}
+
+func (h *GalleryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *GalleryHandler) serveListing(w http.ResponseWriter, r *http.Request) {
+	tenant := r.PathValue("tenant")
+	ctx := r.Context()
+	if tenant == "" || h.validTenant(ctx, tenant) != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	result, err := h.repo.List(ctx, tenant, domain.PortfolioFilter{
+		ArtistID: query.Get("artist_id"),
+		Tag:      query.Get("tag"),
+		Style:    query.Get("style"),
+		Page:     page,
+		PageSize: min(pageSize, domain.MaxPageSize),
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidArgument) {
+			http.Error(w, "invalid filter", http.StatusBadRequest)
+			return
+		}
+		logger.Log.Error("Failed to load gallery", zap.String("tenant", tenant), zap.Error(err))
+		http.Error(w, "failed to load gallery", http.StatusInternalServerError)
+		return
+	}
+
+	res := GalleryResponse{
+		Items:      make([]GalleryItem, 0, len(result.Items)),
+		TotalCount: result.TotalCount,
+		Page:       result.Page,
+		PageSize:   result.PageSize,
+	}
+	for i := range result.Items {
+		res.Items = append(res.Items, NewGalleryItem(h.baseURL, tenant, &result.Items[i]))
+	}
+	body, err := json.Marshal(res)
+	if err != nil {
+		http.Error(w, "failed to write gallery", http.StatusInternalServerError)
+		return
+	}
+
+	// The ETag lets caches revalidate a page without downloading it again
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("Cache-Control", galleryCacheControl)
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+func (h *GalleryHandler) serveImage(w http.ResponseWriter, r *http.Request) {
+	tenant, id, variant := r.PathValue("tenant"), r.PathValue("id"), r.PathValue("variant")
+	ctx := r.Context()
+	if variant != VariantWeb && variant != VariantThumbnail || h.validTenant(ctx, tenant) != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	item, err := h.repo.GetByID(ctx, tenant, id)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) && !errors.Is(err, domain.ErrInvalidArgument) {
+			logger.Log.Warn("Failed to look up portfolio item", zap.String("tenant", tenant), zap.Error(err))
+		}
+		http.NotFound(w, r)
+		return
+	}
+	var image *domain.Attachment
+	for i := range item.Images {
+		if item.Images[i].Variant == variant {
+			image = &item.Images[i]
+		}
+	}
+	if image == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	etag := `"` + image.ID + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.Header().Set("Cache-Control", imageCacheControl)
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, err := h.blobs.Get(ctx, image.Key)
+	if err != nil {
+		logger.Log.Error("Failed to open portfolio image",
+			zap.String("tenant", tenant), zap.String("portfolio_item_id", id), zap.Error(err))
+		http.Error(w, "failed to load image", http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	header := w.Header()
+	header.Set("Content-Type", image.ContentType)
+	header.Set("Content-Length", strconv.FormatInt(image.Size, 10))
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Cache-Control", imageCacheControl)
+	header.Set("ETag", etag)
+	if _, err = io.Copy(w, body); err != nil {
+		logger.Log.Warn("Failed to send portfolio image",
+			zap.String("tenant", tenant), zap.String("portfolio_item_id", id), zap.Error(err))
+	}
+}
+
+// imageKey is where a variant of an upload is stored
+func imageKey(tenant, uploadID, variant string) string {
+	return fmt.Sprintf("%s/portfolio/%s/%s", tenant, uploadID, variant)
+}
+
+// variantFilename names a variant after the uploaded file, e.g. rose-web.jpg
+func variantFilename(filename, variant, contentType string) string {
+	if variant == VariantOriginal {
+		return filename
+	}
+	stem := strings.TrimSuffix(filename, extension(filename))
+	return stem + "-" + variant + extensionFor(contentType)
+}
+
+func extension(filename string) string {
+	if i := strings.LastIndexByte(filename, '.'); i > 0 {
+		return filename[i:]
+	}
+	return ""
+}
+
+func extensionFor(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ".jpg"
+	}
+}
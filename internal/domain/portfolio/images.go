@@ -0,0 +1,477 @@
+package portfolio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// Image variants stored for every portfolio item
+const (
+	VariantOriginal  = "original"
+	VariantWeb       = "web"
+	VariantThumbnail = "thumbnail"
+)
+
+const (
+	// webSize bounds the longer side of the web variant
+	webSize = 1600
+	// thumbnailSize is the side of the square thumbnail
+	thumbnailSize = 400
+	// maxPixels rejects images that would take too much memory to decode
+	maxPixels = 50_000_000
+	// originalQuality is used when the original has to be re-encoded to
+	// apply its orientation
+	originalQuality = 92
+	variantQuality  = 85
+)
+
+// encodedImage is one variant of a processed upload
+type encodedImage struct {
+	data        []byte
+	contentType string
+	width       int
+	height      int
+}
+
+// processedImage holds the variants stored for an upload
+type processedImage struct {
+	original  encodedImage
+	web       encodedImage
+	thumbnail encodedImage
+}
+
+// processImage turns an upload into the stored variants. Metadata such as
+// EXIF, including GPS positions, is dropped from all of them. The original
+// keeps its encoding where possible; JPEGs the camera tagged as rotated are
+// re-encoded upright, since the tag goes with the EXIF.
+func processImage(data []byte) (*processedImage, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: portfolio images must be JPEG, PNG or GIF", domain.ErrInvalidArgument)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: image is %dx%d, larger than %d pixels", domain.ErrInvalidArgument, cfg.Width, cfg.Height, maxPixels)
+	}
+
+	var decoded image.Image
+	var original encodedImage
+	switch format {
+	case "jpeg":
+		if decoded, err = jpeg.Decode(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("%w: invalid JPEG: %v", domain.ErrInvalidArgument, err)
+		}
+		if orientation := exifOrientation(data); orientation > 1 {
+			decoded = orient(toRGBA(decoded), orientation)
+			if original, err = encode(decoded, "image/jpeg", originalQuality); err != nil {
+				return nil, err
+			}
+		} else {
+			stripped, err := stripJPEG(data)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid JPEG: %v", domain.ErrInvalidArgument, err)
+			}
+			original = encodedImage{data: stripped, contentType: "image/jpeg"}
+		}
+	case "png":
+		if decoded, err = png.Decode(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("%w: invalid PNG: %v", domain.ErrInvalidArgument, err)
+		}
+		stripped, err := stripPNG(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid PNG: %v", domain.ErrInvalidArgument, err)
+		}
+		original = encodedImage{data: stripped, contentType: "image/png"}
+	case "gif":
+		// Re-encoding keeps the frames and drops comments and application
+		// extensions
+		anim, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(anim.Image) == 0 {
+			return nil, fmt.Errorf("%w: invalid GIF", domain.ErrInvalidArgument)
+		}
+		var buf bytes.Buffer
+		if err = gif.EncodeAll(&buf, &gif.GIF{
+			Image:     anim.Image,
+			Delay:     anim.Delay,
+			Disposal:  anim.Disposal,
+			LoopCount: anim.LoopCount,
+			Config:    anim.Config,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to encode GIF: %w", err)
+		}
+		decoded = anim.Image[0]
+		original = encodedImage{data: buf.Bytes(), contentType: "image/gif"}
+	default:
+		return nil, fmt.Errorf("%w: portfolio images must be JPEG, PNG or GIF", domain.ErrInvalidArgument)
+	}
+
+	src := toRGBA(decoded)
+	bounds := src.Bounds()
+	original.width, original.height = bounds.Dx(), bounds.Dy()
+
+	w, h := fit(bounds.Dx(), bounds.Dy(), webSize)
+	web, err := encodeVariant(resize(src, w, h))
+	if err != nil {
+		return nil, err
+	}
+	thumbnail, err := encodeVariant(resize(squareCrop(src), thumbnailSize, thumbnailSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return &processedImage{original: original, web: web, thumbnail: thumbnail}, nil
+}
+
+// encodeVariant encodes a resized image as JPEG, or as PNG when it has
+// transparency to keep, e.g. flash sheets
+func encodeVariant(img *image.RGBA) (encodedImage, error) {
+	if img.Opaque() {
+		return encode(img, "image/jpeg", variantQuality)
+	}
+	return encode(img, "image/png", 0)
+}
+
+func encode(img image.Image, contentType string, quality int) (encodedImage, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/png" {
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return encodedImage{}, fmt.Errorf("failed to encode image: %w", err)
+	}
+	bounds := img.Bounds()
+	return encodedImage{data: buf.Bytes(), contentType: contentType, width: bounds.Dx(), height: bounds.Dy()}, nil
+}
+
+// fit scales w×h down so its longer side is at most size. Smaller images
+// keep their size.
+func fit(w, h, size int) (int, int) {
+	if w <= size && h <= size {
+		return w, h
+	}
+	if w >= h {
+		return size, max(1, int(math.Round(float64(h)*float64(size)/float64(w))))
+	}
+	return max(1, int(math.Round(float64(w)*float64(size)/float64(h)))), size
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// squareCrop returns the centred square of img
+func squareCrop(img *image.RGBA) *image.RGBA {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	return img.SubImage(image.Rect(x, y, x+side, y+side)).(*image.RGBA)
+}
+
+// span is the run of source pixels a destination pixel covers, with the share
+// of each
+type span struct {
+	start   int
+	weights []float32
+}
+
+// boxSpans maps dstLen destination pixels onto srcLen source pixels. Each
+// destination pixel averages the source pixels it covers, partly covered ones
+// by the part covered. When enlarging, pixels are repeated.
+func boxSpans(srcLen, dstLen int) []span {
+	scale := float64(srcLen) / float64(dstLen)
+	spans := make([]span, dstLen)
+	for i := range spans {
+		lo, hi := float64(i)*scale, float64(i+1)*scale
+		start := min(int(lo), srcLen-1)
+		end := max(min(int(math.Ceil(hi)), srcLen), start+1)
+		weights := make([]float32, end-start)
+		var total float64
+		for j := start; j < end; j++ {
+			w := math.Min(hi, float64(j+1)) - math.Max(lo, float64(j))
+			if w <= 0 {
+				continue
+			}
+			weights[j-start] = float32(w)
+			total += w
+		}
+		if total == 0 {
+			weights[0], total = 1, 1
+		}
+		for j := range weights {
+			weights[j] /= float32(total)
+		}
+		spans[i] = span{start: start, weights: weights}
+	}
+	return spans
+}
+
+// resize scales img to w×h with a box filter. Each destination row first
+// blends the source rows it covers, then the columns, so only one row of
+// floats is held at a time. It works on premultiplied colours, so
+// transparent pixels do not darken their neighbours.
+func resize(img *image.RGBA, w, h int) *image.RGBA {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if srcW == 0 || srcH == 0 {
+		return dst
+	}
+
+	rows := boxSpans(srcH, h)
+	columns := boxSpans(srcW, w)
+	blended := make([]float32, srcW*4)
+	for y, rs := range rows {
+		clear(blended)
+		for j, weight := range rs.weights {
+			offset := img.PixOffset(bounds.Min.X, bounds.Min.Y+rs.start+j)
+			for k, v := range img.Pix[offset : offset+srcW*4] {
+				blended[k] += float32(v) * weight
+			}
+		}
+		for x, cs := range columns {
+			var r, g, b, a float32
+			for j, weight := range cs.weights {
+				k := (cs.start + j) * 4
+				r += blended[k] * weight
+				g += blended[k+1] * weight
+				b += blended[k+2] * weight
+				a += blended[k+3] * weight
+			}
+			o := dst.PixOffset(x, y)
+			dst.Pix[o], dst.Pix[o+1], dst.Pix[o+2], dst.Pix[o+3] = clamp(r), clamp(g), clamp(b), clamp(a)
+		}
+	}
+	return dst
+}
+
+func clamp(v float32) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	default:
+		return uint8(v + 0.5)
+	}
+}
+
+// orient turns an image stored with EXIF orientation o upright
+func orient(img *image.RGBA, o int) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch o {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // upside down
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored upside down
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // turned left, needs a quarter turn clockwise
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // turned right, needs a quarter turn counter-clockwise
+				sx, sy = w-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], img.Pix[img.PixOffset(sx, sy):img.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// JPEG markers read while walking the segments of a file
+const (
+	markerSOI   = 0xD8
+	markerEOI   = 0xD9
+	markerSOS   = 0xDA
+	markerAPP0  = 0xE0
+	markerAPP1  = 0xE1
+	markerAPP2  = 0xE2
+	markerAPP14 = 0xEE
+	markerAPP15 = 0xEF
+	markerCOM   = 0xFE
+)
+
+var errBadJPEG = errors.New("malformed JPEG segments")
+
+// jpegSegments calls fn with every marker segment before the image data and
+// returns the offset where the image data starts
+func jpegSegments(data []byte, fn func(marker byte, segment []byte)) (int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != markerSOI {
+		return 0, errBadJPEG
+	}
+	i := 2
+	for i < len(data) {
+		if data[i] != 0xFF {
+			return 0, errBadJPEG
+		}
+		// Any number of 0xFF may pad a marker
+		for i < len(data) && data[i] == 0xFF {
+			i++
+		}
+		if i >= len(data) {
+			return 0, errBadJPEG
+		}
+		marker := data[i]
+		start := i - 1
+		i++
+		if marker == markerSOS || marker == markerEOI {
+			return start, nil
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			fn(marker, data[start:i])
+			continue
+		}
+		if i+2 > len(data) {
+			return 0, errBadJPEG
+		}
+		length := int(binary.BigEndian.Uint16(data[i:]))
+		if length < 2 || i+length > len(data) {
+			return 0, errBadJPEG
+		}
+		fn(marker, data[start:i+length])
+		i += length
+	}
+	return 0, errBadJPEG
+}
+
+// stripJPEG drops the metadata segments of a JPEG without re-encoding it:
+// EXIF and XMP (APP1), IPTC (APP13), the other application segments and
+// comments. JFIF (APP0), the ICC colour profile (APP2) and the Adobe colour
+// transform (APP14) are kept as they change how the image looks.
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, markerSOI)
+	start, err := jpegSegments(data, func(marker byte, segment []byte) {
+		switch {
+		case marker == markerAPP2 && bytes.HasPrefix(segment[4:], []byte("ICC_PROFILE\x00")):
+		case marker == markerAPP0, marker == markerAPP14:
+		case marker >= markerAPP1 && marker <= markerAPP15, marker == markerCOM:
+			return
+		}
+		out = append(out, segment...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[start:]...), nil
+}
+
+// exifOrientation reads the orientation tag of a JPEG's EXIF, 1 (upright)
+// when there is none
+func exifOrientation(data []byte) int {
+	orientation := 1
+	_, _ = jpegSegments(data, func(marker byte, segment []byte) {
+		if marker != markerAPP1 || len(segment) < 4 || !bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")) {
+			return
+		}
+		if o := tiffOrientation(segment[10:]); o >= 1 && o <= 8 {
+			orientation = o
+		}
+	})
+	return orientation
+}
+
+// tiffOrientation reads tag 0x0112 from the first IFD of a TIFF structure
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		// A SHORT value sits in the first two bytes of the value field
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// pngMetadataChunks are dropped from stored PNGs: EXIF, text and the time of
+// the last edit
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// stripPNG drops the metadata chunks of a PNG without re-encoding it
+func stripPNG(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, errors.New("missing PNG signature")
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+	for i := len(signature); i < len(data); {
+		if i+8 > len(data) {
+			return nil, errors.New("truncated PNG chunk")
+		}
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errors.New("truncated PNG chunk")
+		}
+		chunkType := string(data[i+4 : i+8])
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return out, nil
+}
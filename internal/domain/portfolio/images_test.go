@@ -0,0 +1,389 @@
+package portfolio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// Marker strings planted in the metadata of the test images; none of them may
+// survive stripping
+var (
+	secretGPS     = []byte("GPS-38.7223N-9.1393W")
+	secretXMP     = []byte("secret-xmp-creator")
+	secretComment = []byte("secret-comment")
+	secretText    = []byte("secret-png-text")
+	secretPNGExif = []byte("secret-png-exif")
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 60), G: uint8(y * 60), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// jpegSegment builds a marker segment with its length
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// exifPayload builds an APP1 Exif payload whose first IFD holds the
+// orientation and a pointer to a GPS IFD carrying secretGPS
+func exifPayload(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+
+	entry := func(tag, typ uint16, count, value uint32) []byte {
+		b := make([]byte, 12)
+		order.PutUint16(b, tag)
+		order.PutUint16(b[2:], typ)
+		order.PutUint32(b[4:], count)
+		order.PutUint32(b[8:], value)
+		return b
+	}
+	// IFD0: two entries and the next IFD offset, then the GPS IFD
+	gpsIFD := uint32(8 + 2 + 2*12 + 4)
+	ifd0 := make([]byte, 2)
+	order.PutUint16(ifd0, 2)
+	orientationEntry := entry(0x0112, 3, 1, 0)
+	order.PutUint16(orientationEntry[8:], orientation)
+	ifd0 = append(ifd0, orientationEntry...)
+	ifd0 = append(ifd0, entry(0x8825, 4, 1, gpsIFD)...)
+	ifd0 = append(ifd0, 0, 0, 0, 0)
+
+	gpsData := gpsIFD + 2 + 12 + 4
+	gps := make([]byte, 2)
+	order.PutUint16(gps, 1)
+	gps = append(gps, entry(0x0002, 2, uint32(len(secretGPS)), gpsData)...)
+	gps = append(gps, 0, 0, 0, 0)
+	gps = append(gps, secretGPS...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	payload = append(payload, ifd0...)
+	return append(payload, gps...)
+}
+
+// jpegWithMetadata encodes img and inserts Exif (with GPS), XMP, a comment
+// and an ICC profile after the SOI marker
+func jpegWithMetadata(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+
+	out := []byte{0xFF, markerSOI}
+	out = append(out, jpegSegment(markerAPP0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))...)
+	out = append(out, jpegSegment(markerAPP1, exifPayload(binary.BigEndian, orientation))...)
+	out = append(out, jpegSegment(markerAPP1, append([]byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta>"), append(secretXMP, "</x:xmpmeta>"...)...))...)
+	out = append(out, jpegSegment(markerAPP2, []byte("ICC_PROFILE\x00\x01\x01profile"))...)
+	out = append(out, jpegSegment(markerCOM, secretComment)...)
+	return append(out, encoded[2:]...)
+}
+
+// pngChunk builds a PNG chunk with its CRC
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// pngWithMetadata encodes img and inserts eXIf, tEXt and tIME chunks after
+// the IHDR chunk
+func pngWithMetadata(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	ihdrEnd := 8 + 12 + int(binary.BigEndian.Uint32(encoded[8:]))
+
+	out := append([]byte{}, encoded[:ihdrEnd]...)
+	out = append(out, pngChunk("eXIf", append([]byte("MM\x00\x2a"), secretPNGExif...))...)
+	out = append(out, pngChunk("tEXt", append([]byte("Comment\x00"), secretText...))...)
+	out = append(out, pngChunk("tIME", []byte{0x07, 0xE9, 1, 2, 3, 4, 5})...)
+	return append(out, encoded[ihdrEnd:]...)
+}
+
+func assertSecrets(t *testing.T, data []byte, secrets ...[]byte) {
+	t.Helper()
+	for _, secret := range secrets {
+		if !bytes.Contains(data, secret) {
+			t.Fatalf("test image lacks %q", secret)
+		}
+	}
+}
+
+func assertNoSecrets(t *testing.T, data []byte, secrets ...[]byte) {
+	t.Helper()
+	for _, secret := range secrets {
+		if bytes.Contains(data, secret) {
+			t.Errorf("output still contains %q", secret)
+		}
+	}
+}
+
+func TestJPEGSegments(t *testing.T) {
+	data := jpegWithMetadata(t, testImage(4, 4), 1)
+
+	var markers []byte
+	start, err := jpegSegments(data, func(marker byte, segment []byte) {
+		if segment[0] != 0xFF || segment[1] != marker {
+			t.Errorf("segment of marker %#x starts with % x", marker, segment[:2])
+		}
+		markers = append(markers, marker)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[start] != 0xFF || data[start+1] != markerSOS {
+		t.Errorf("image data starts with % x, want ff da", data[start:start+2])
+	}
+	want := []byte{markerAPP0, markerAPP1, markerAPP1, markerAPP2, markerCOM}
+	if !bytes.HasPrefix(markers, want) {
+		t.Errorf("markers % x, want them to start with % x", markers, want)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"no SOI", []byte{0xFF, 0xD9, 0xFF, 0xD9}},
+		{"only SOI and padding", []byte{0xFF, markerSOI, 0xFF, 0xFF}},
+		{"missing marker prefix", []byte{0xFF, markerSOI, 0x00, markerCOM, 0x00, 0x02}},
+		{"truncated length", []byte{0xFF, markerSOI, 0xFF, markerCOM, 0x00}},
+		{"length below two", []byte{0xFF, markerSOI, 0xFF, markerCOM, 0x00, 0x01}},
+		{"length past the end", []byte{0xFF, markerSOI, 0xFF, markerCOM, 0x00, 0x10, 'x'}},
+		{"no image data", append([]byte{0xFF, markerSOI}, jpegSegment(markerCOM, []byte("x"))...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := jpegSegments(tt.data, func(byte, []byte) {}); err != errBadJPEG {
+				t.Errorf("got error %v, want %v", err, errBadJPEG)
+			}
+		})
+	}
+}
+
+func TestStripJPEG(t *testing.T) {
+	img := testImage(4, 4)
+	data := jpegWithMetadata(t, img, 1)
+	assertSecrets(t, data, secretGPS, secretXMP, secretComment)
+
+	stripped, err := stripJPEG(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoSecrets(t, stripped, secretGPS, secretXMP, secretComment, []byte("Exif\x00\x00"))
+	if !bytes.Contains(stripped, []byte("ICC_PROFILE\x00")) {
+		t.Error("ICC profile was dropped")
+	}
+	if !bytes.Contains(stripped, []byte("JFIF\x00")) {
+		t.Error("JFIF header was dropped")
+	}
+
+	decoded, err := jpeg.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatalf("stripped JPEG does not decode: %v", err)
+	}
+	if decoded.Bounds() != img.Bounds() {
+		t.Errorf("stripped JPEG is %v, want %v", decoded.Bounds(), img.Bounds())
+	}
+
+	if _, err := stripJPEG([]byte("not a jpeg")); err == nil {
+		t.Error("stripping a non-JPEG succeeded")
+	}
+}
+
+func TestExifOrientation(t *testing.T) {
+	img := testImage(4, 2)
+	plain := func() []byte {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, nil); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}()
+	withExif := func(payload []byte) []byte {
+		out := []byte{0xFF, markerSOI}
+		out = append(out, jpegSegment(markerAPP1, payload)...)
+		return append(out, plain[2:]...)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", plain, 1},
+		{"big endian rotated", jpegWithMetadata(t, img, 6), 6},
+		{"little endian upside down", withExif(exifPayload(binary.LittleEndian, 3)), 3},
+		{"out of range", withExif(exifPayload(binary.BigEndian, 9)), 1},
+		{"truncated tiff", withExif([]byte("Exif\x00\x00MM\x00")), 1},
+		{"xmp only", withExif([]byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")), 1},
+		{"malformed jpeg", []byte{0xFF, markerSOI, 0xFF, markerAPP1, 0xFF}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exifOrientation(tt.data); got != tt.want {
+				t.Errorf("got orientation %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTiffOrientation(t *testing.T) {
+	tiff := exifPayload(binary.LittleEndian, 8)[6:]
+
+	wrongType := bytes.Clone(tiff)
+	binary.LittleEndian.PutUint16(wrongType[10+2:], 4) // LONG instead of SHORT
+	badMagic := bytes.Clone(tiff)
+	binary.LittleEndian.PutUint16(badMagic[2:], 43)
+	badOffset := bytes.Clone(tiff)
+	binary.LittleEndian.PutUint32(badOffset[4:], uint32(len(tiff)))
+	tooManyEntries := bytes.Clone(tiff)
+	binary.LittleEndian.PutUint16(tooManyEntries[8:], 0xFFFF)
+	binary.LittleEndian.PutUint16(tooManyEntries[10:], 0x0100) // not the orientation
+
+	tests := []struct {
+		name string
+		tiff []byte
+		want int
+	}{
+		{"little endian", tiff, 8},
+		{"big endian", exifPayload(binary.BigEndian, 5)[6:], 5},
+		{"too short", tiff[:7], 0},
+		{"unknown byte order", append([]byte("XX"), tiff[2:]...), 0},
+		{"bad magic", badMagic, 0},
+		{"IFD offset past the end", badOffset, 0},
+		{"entries past the end", tooManyEntries, 0},
+		{"orientation not a SHORT", wrongType, 0},
+		{"cut inside the IFD", tiff[:15], 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tiffOrientation(tt.tiff); got != tt.want {
+				t.Errorf("got orientation %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	img := testImage(4, 4)
+	data := pngWithMetadata(t, img)
+	assertSecrets(t, data, secretPNGExif, secretText)
+
+	stripped, err := stripPNG(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoSecrets(t, stripped, secretPNGExif, secretText, []byte("eXIf"), []byte("tEXt"), []byte("tIME"))
+
+	decoded, err := png.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatalf("stripped PNG does not decode: %v", err)
+	}
+	if decoded.Bounds() != img.Bounds() {
+		t.Errorf("stripped PNG is %v, want %v", decoded.Bounds(), img.Bounds())
+	}
+
+	signature := data[:8]
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"no signature", []byte("GIF89a")},
+		{"truncated chunk header", append(bytes.Clone(signature), 0, 0, 0)},
+		{"truncated chunk data", data[:40]},
+		{"huge length", append(bytes.Clone(signature), 0xFF, 0xFF, 0xFF, 0xFF, 'I', 'D', 'A', 'T')},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := stripPNG(tt.data); err == nil {
+				t.Error("stripping succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestProcessImageStripsMetadata(t *testing.T) {
+	t.Run("jpeg", func(t *testing.T) {
+		processed, err := processImage(jpegWithMetadata(t, testImage(4, 4), 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, variant := range []encodedImage{processed.original, processed.web, processed.thumbnail} {
+			assertNoSecrets(t, variant.data, secretGPS, secretXMP, secretComment)
+		}
+	})
+
+	t.Run("rotated jpeg", func(t *testing.T) {
+		processed, err := processImage(jpegWithMetadata(t, testImage(4, 2), 6))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if processed.original.width != 2 || processed.original.height != 4 {
+			t.Errorf("original is %dx%d, want 2x4", processed.original.width, processed.original.height)
+		}
+		for _, variant := range []encodedImage{processed.original, processed.web, processed.thumbnail} {
+			assertNoSecrets(t, variant.data, secretGPS, secretXMP, secretComment, []byte("Exif\x00\x00"))
+		}
+	})
+
+	t.Run("png", func(t *testing.T) {
+		processed, err := processImage(pngWithMetadata(t, testImage(4, 4)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, variant := range []encodedImage{processed.original, processed.web, processed.thumbnail} {
+			assertNoSecrets(t, variant.data, secretPNGExif, secretText)
+		}
+	})
+}
+
+// TestTruncatedImagesDoNotPanic feeds every prefix of the test images to the
+// parsers; they must fail cleanly rather than read past the data
+func TestTruncatedImagesDoNotPanic(t *testing.T) {
+	jpegData := jpegWithMetadata(t, testImage(4, 2), 6)
+	for n := range len(jpegData) {
+		prefix := jpegData[:n]
+		_, _ = jpegSegments(prefix, func(byte, []byte) {})
+		_, _ = stripJPEG(prefix)
+		_ = exifOrientation(prefix)
+		_, _ = processImage(prefix)
+	}
+
+	tiff := exifPayload(binary.BigEndian, 6)[6:]
+	for n := range len(tiff) {
+		_ = tiffOrientation(tiff[:n])
+	}
+
+	pngData := pngWithMetadata(t, testImage(4, 4))
+	for n := range len(pngData) {
+		_, _ = stripPNG(pngData[:n])
+		_, _ = processImage(pngData[:n])
+	}
+}
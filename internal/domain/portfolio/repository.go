@@ -0,0 +1,412 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// PortfolioRepository keeps portfolio items in the tenant's database. Their
+// images are attachment rows pointing at blobs in a domain.BlobStore.
+type PortfolioRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewPortfolioRepository creates a new PortfolioRepository
+func NewPortfolioRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *PortfolioRepository {
+	return &PortfolioRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+// artistName is what the gallery calls an artist; their email is never used
+const artistName = `COALESCE(NULLIF(u.display_name, ''), NULLIF(btrim(concat_ws(' ', u.first_name, u.last_name)), ''), u.username, '')`
+
+const itemColumns = `p.id, p.studio_id, p.artist_id, ` + artistName + `, COALESCE(p.title, ''), COALESCE(p.description, ''),
+	p.tags, COALESCE(p.style, ''), COALESCE(p.body_placement, ''), p.position, p.created_at, p.updated_at`
+
+const itemFrom = ` FROM portfolio_items p LEFT JOIN users u ON u.id = p.artist_id`
+
+const imageColumns = `id, storage_key, filename, content_type, size_bytes, COALESCE(uploaded_by::text, ''),
+	portfolio_item_id::text, variant, created_at`
+
+// itemFields maps update paths to columns
+var itemFields = map[string]string{
+	"title":          "title",
+	"description":    "description",
+	"tags":           "tags",
+	"style":          "style",
+	"body_placement": "body_placement",
+}
+
+// Create stores an item with its images. The artist must be on the studio's
+// staff.
+func (r *PortfolioRepository) Create(ctx context.Context, tenant string, item *domain.PortfolioItem) error {
+	if item == nil {
+		return fmt.Errorf("%w: portfolio item is required", domain.ErrInvalidArgument)
+	}
+	if item.ArtistID == "" {
+		return fmt.Errorf("%w: artist is required", domain.ErrInvalidArgument)
+	}
+	if len(item.Images) == 0 {
+		return fmt.Errorf("%w: an image is required", domain.ErrInvalidArgument)
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		studioID := item.StudioID
+		if studioID == "" {
+			err := tx.QueryRow(ctx, "SELECT id FROM studios WHERE subdomain = $1", tenant).Scan(&studioID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("studio: %w", domain.ErrNotFound)
+			}
+			if err != nil {
+				return domain.WrapError("failed to find studio", err)
+			}
+		}
+
+		var staff bool
+		if err := tx.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM users u WHERE u.id = $1
+			   AND (u.studio_id = $2 OR EXISTS (
+				SELECT 1 FROM studio_staff s WHERE s.user_id = u.id AND s.studio_id = $2)))`,
+			item.ArtistID, studioID).Scan(&staff); err != nil {
+			return domain.WrapError("failed to check artist", err)
+		}
+		if !staff {
+			return fmt.Errorf("artist %s of studio %s: %w", item.ArtistID, studioID, domain.ErrNotFound)
+		}
+
+		// Concurrent uploads of one artist wait for each other, so every item
+		// gets its own position
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('portfolio.' || $1))", item.ArtistID); err != nil {
+			return domain.WrapError("failed to lock portfolio", err)
+		}
+
+		// image_url is the gallery path of the web image
+		id := uuid.NewString()
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO portfolio_items (id, studio_id, artist_id, image_url, title, description, tags, style, body_placement, position)
+			 SELECT $1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, NULLIF($8, ''), NULLIF($9, ''),
+				COALESCE(max(position), 0) + 1
+			 FROM portfolio_items WHERE artist_id = $3`,
+			id, studioID, item.ArtistID, ImagePath(tenant, id, VariantWeb), item.Title, item.Description,
+			tags(item.Tags), item.Style, item.BodyPlacement); err != nil {
+			return domain.WrapError("failed to create portfolio item", err)
+		}
+
+		for _, image := range item.Images {
+			if _, err := tx.Exec(ctx,
+				`INSERT INTO attachments (storage_key, filename, content_type, size_bytes, uploaded_by, portfolio_item_id, variant)
+				 VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6, $7)`,
+				image.Key, image.Filename, image.ContentType, image.Size, image.UploadedBy, id, image.Variant); err != nil {
+				return domain.WrapError("failed to store portfolio image", err)
+			}
+		}
+
+		created, err := r.get(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		*item = *created
+		return nil
+	})
+}
+
+func (r *PortfolioRepository) GetByID(ctx context.Context, tenant, id string) (*domain.PortfolioItem, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+	return r.get(ctx, pool, id)
+}
+
+// List pages through items matching the filter, each artist's in their order
+// and the newest first among equals
+func (r *PortfolioRepository) List(ctx context.Context, tenant string, filter domain.PortfolioFilter) (domain.PagedResult[domain.PortfolioItem], error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = domain.DefaultPageSize
+	}
+	result := domain.PagedResult[domain.PortfolioItem]{Items: []domain.PortfolioItem{}, Page: filter.Page, PageSize: filter.PageSize}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return result, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	where := []string{"TRUE"}
+	var args []interface{}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if filter.StudioID != "" {
+		add("p.studio_id = $%d", filter.StudioID)
+	}
+	if filter.ArtistID != "" {
+		add("p.artist_id = $%d", filter.ArtistID)
+	}
+	if filter.Tag != "" {
+		add("p.tags @> ARRAY[$%d]::text[]", normalizeTag(filter.Tag))
+	}
+	if filter.Style != "" {
+		add("lower(p.style) = lower($%d)", filter.Style)
+	}
+	whereClause := strings.Join(where, " AND ")
+
+	if err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM portfolio_items p WHERE "+whereClause, args...).Scan(&result.TotalCount); err != nil {
+		return result, domain.WrapError("failed to count portfolio items", err)
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	rows, err := pool.Query(ctx,
+		"SELECT "+itemColumns+itemFrom+" WHERE "+whereClause+
+			fmt.Sprintf(" ORDER BY p.artist_id, p.position, p.created_at DESC, p.id LIMIT $%d OFFSET $%d", len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return result, domain.WrapError("failed to query portfolio items", err)
+	}
+	items, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.PortfolioItem, error) {
+		item, err := scanItem(row)
+		if err != nil {
+			return domain.PortfolioItem{}, err
+		}
+		return *item, nil
+	})
+	if err != nil {
+		return result, domain.WrapError("failed to query portfolio items", err)
+	}
+	if err = loadImages(ctx, pool, items); err != nil {
+		return result, err
+	}
+	result.Items = items
+	return result, nil
+}
+
+// Update writes the fields of item named in fields
+func (r *PortfolioRepository) Update(ctx context.Context, tenant string, item *domain.PortfolioItem, fields []string) error {
+	if item == nil {
+		return fmt.Errorf("%w: portfolio item is required", domain.ErrInvalidArgument)
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("%w: update_mask is required", domain.ErrInvalidArgument)
+	}
+
+	values := map[string]interface{}{
+		"title":          nullIfEmpty(item.Title),
+		"description":    nullIfEmpty(item.Description),
+		"tags":           tags(item.Tags),
+		"style":          nullIfEmpty(item.Style),
+		"body_placement": nullIfEmpty(item.BodyPlacement),
+	}
+
+	var setClauses []string
+	var args []interface{}
+	seen := make(map[string]bool, len(fields))
+	for _, path := range fields {
+		column, ok := itemFields[path]
+		if !ok {
+			return fmt.Errorf("%w: unknown field in update_mask: %s", domain.ErrInvalidArgument, path)
+		}
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		args = append(args, values[path])
+		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	args = append(args, time.Now(), item.ID)
+	setClauses = append(setClauses, fmt.Sprintf("updated_at = $%d", len(args)-1))
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	tag, err := pool.Exec(ctx,
+		"UPDATE portfolio_items SET "+strings.Join(setClauses, ", ")+fmt.Sprintf(" WHERE id = $%d", len(args)),
+		args...)
+	if err != nil {
+		return domain.WrapError("failed to update portfolio item", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("portfolio item %s: %w", item.ID, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// Delete removes an item; its attachment rows go with it
+func (r *PortfolioRepository) Delete(ctx context.Context, tenant, id string) ([]string, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var keys []string
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx,
+			"SELECT COALESCE(array_agg(storage_key), '{}') FROM attachments WHERE portfolio_item_id = $1",
+			id).Scan(&keys); err != nil {
+			return domain.WrapError("failed to get portfolio images", err)
+		}
+		tag, err := tx.Exec(ctx, "DELETE FROM portfolio_items WHERE id = $1", id)
+		if err != nil {
+			return domain.WrapError("failed to delete portfolio item", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("portfolio item %s: %w", id, domain.ErrNotFound)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Reorder moves the listed items of the artist to the front in the given
+// order. Ids of other artists' items are rejected.
+func (r *PortfolioRepository) Reorder(ctx context.Context, tenant, artistID string, ids []string) error {
+	if artistID == "" {
+		return fmt.Errorf("%w: artist is required", domain.ErrInvalidArgument)
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("%w: at least one item is required", domain.ErrInvalidArgument)
+	}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("%w: item %s is listed twice", domain.ErrInvalidArgument, id)
+		}
+		seen[id] = true
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		var owned int
+		if err := tx.QueryRow(ctx,
+			"SELECT count(*) FROM portfolio_items WHERE id = ANY($1::uuid[]) AND artist_id = $2",
+			ids, artistID).Scan(&owned); err != nil {
+			return domain.WrapError("failed to check portfolio items", err)
+		}
+		if owned != len(ids) {
+			return fmt.Errorf("portfolio items of artist %s: %w", artistID, domain.ErrNotFound)
+		}
+
+		if _, err := tx.Exec(ctx,
+			`WITH wanted AS (
+				SELECT id, ord FROM unnest($2::uuid[]) WITH ORDINALITY AS t(id, ord)
+			 ), ranked AS (
+				SELECT p.id, row_number() OVER (ORDER BY w.ord NULLS LAST, p.position, p.created_at DESC, p.id) AS position
+				FROM portfolio_items p LEFT JOIN wanted w ON w.id = p.id
+				WHERE p.artist_id = $1
+			 )
+			 UPDATE portfolio_items p SET position = r.position, updated_at = now()
+			 FROM ranked r WHERE p.id = r.id AND p.position IS DISTINCT FROM r.position`,
+			artistID, ids); err != nil {
+			return domain.WrapError("failed to reorder portfolio items", err)
+		}
+		return nil
+	})
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func (r *PortfolioRepository) get(ctx context.Context, db querier, id string) (*domain.PortfolioItem, error) {
+	item, err := scanItem(db.QueryRow(ctx, "SELECT "+itemColumns+itemFrom+" WHERE p.id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("portfolio item %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get portfolio item", err)
+	}
+	items := []domain.PortfolioItem{*item}
+	if err = loadImages(ctx, db, items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// loadImages fills in the images of items
+func loadImages(ctx context.Context, db querier, items []domain.PortfolioItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(items))
+	byID := make(map[string]*domain.PortfolioItem, len(items))
+	for i := range items {
+		ids = append(ids, items[i].ID)
+		byID[items[i].ID] = &items[i]
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT `+imageColumns+` FROM attachments
+		 WHERE portfolio_item_id = ANY($1::uuid[]) ORDER BY created_at, id`, ids)
+	if err != nil {
+		return domain.WrapError("failed to query portfolio images", err)
+	}
+	images, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Attachment, error) {
+		var image domain.Attachment
+		err := row.Scan(&image.ID, &image.Key, &image.Filename, &image.ContentType, &image.Size,
+			&image.UploadedBy, &image.PortfolioItemID, &image.Variant, &image.CreatedAt)
+		return image, err
+	})
+	if err != nil {
+		return domain.WrapError("failed to query portfolio images", err)
+	}
+	for _, image := range images {
+		if item, ok := byID[image.PortfolioItemID]; ok {
+			item.Images = append(item.Images, image)
+		}
+	}
+	return nil
+}
+
+func scanItem(row pgx.Row) (*domain.PortfolioItem, error) {
+	var item domain.PortfolioItem
+	err := row.Scan(&item.ID, &item.StudioID, &item.ArtistID, &item.ArtistName, &item.Title, &item.Description,
+		&item.Tags, &item.Style, &item.BodyPlacement, &item.Position, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// tags never passes a nil slice, which pgx would store as NULL
+func tags(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
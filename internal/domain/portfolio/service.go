@@ -0,0 +1,494 @@
+package portfolio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/attachment"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// PortfolioServiceName is the fully qualified gRPC name of the portfolio
+// service
+const PortfolioServiceName = "inkMe.portfolio.PortfolioService"
+
+const (
+	maxTitleLength    = 200
+	maxLabelLength    = 50
+	maxTags           = 20
+	maxTagLength      = 30
+	maxReorderedItems = 500
+)
+
+// managerRoles may manage the portfolio of every artist
+var managerRoles = []string{"OWNER"}
+
+// PortfolioItemInput describes an item. Tags are matched case-insensitively
+// and stored in lower case.
+type PortfolioItemInput struct {
+	StudioID string `json:"studio_id"`
+	// ArtistID defaults to the caller
+	ArtistID      string   `json:"artist_id"`
+	Title         string   `json:"title"`
+	Description   string   `json:"description"`
+	Tags          []string `json:"tags"`
+	Style         string   `json:"style"`
+	BodyPlacement string   `json:"body_placement"`
+}
+
+// UploadPortfolioChunk is one message of a CreatePortfolioItem stream. The
+// item and filename are read from the first chunk, the image from all of
+// them.
+type UploadPortfolioChunk struct {
+	Item     *PortfolioItemInput `json:"item"`
+	Filename string              `json:"filename"`
+	// Data is the next part of the image, base64 encoded in the Struct
+	Data []byte `json:"data"`
+}
+
+type PortfolioItemID struct {
+	ID string `json:"id"`
+}
+
+type UpdatePortfolioItemRequest struct {
+	ID   string             `json:"id"`
+	Item PortfolioItemInput `json:"item"`
+	// UpdateMask names the fields of Item to write: title, description,
+	// tags, style and body_placement
+	UpdateMask []string `json:"update_mask"`
+}
+
+type ListPortfolioItemsRequest struct {
+	StudioID string `json:"studio_id"`
+	ArtistID string `json:"artist_id"`
+	Tag      string `json:"tag"`
+	Style    string `json:"style"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+}
+
+type ReorderPortfolioItemsRequest struct {
+	// ArtistID defaults to the caller
+	ArtistID string `json:"artist_id"`
+	// ItemIDs come first in this order; the artist's other items follow
+	ItemIDs []string `json:"item_ids"`
+}
+
+type PortfolioItemOutput struct {
+	ID            string   `json:"id"`
+	StudioID      string   `json:"studio_id"`
+	ArtistID      string   `json:"artist_id"`
+	ArtistName    string   `json:"artist_name,omitempty"`
+	Title         string   `json:"title,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	Tags          []string `json:"tags"`
+	Style         string   `json:"style,omitempty"`
+	BodyPlacement string   `json:"body_placement,omitempty"`
+	Position      int      `json:"position"`
+	// ImageURL and ThumbnailURL are public gallery URLs
+	ImageURL     string `json:"image_url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	// Original is the upload as stored, behind a signed URL
+	Original  *attachment.AttachmentOutput `json:"original,omitempty"`
+	CreatedAt string                       `json:"created_at"`
+	UpdatedAt string                       `json:"updated_at,omitempty"`
+}
+
+type ListPortfolioItemsResponse struct {
+	Items      []PortfolioItemOutput `json:"items"`
+	TotalCount int64                 `json:"total_count"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// PortfolioService implements the portfolio gRPC service. Artists manage
+// their own items; managers manage everyone's.
+type PortfolioService struct {
+	repo    domain.PortfolioRepository
+	blobs   domain.BlobStore
+	urls    domain.AttachmentURLSigner
+	limits  attachment.UploadLimits
+	baseURL string
+}
+
+// NewPortfolioService creates a new PortfolioService. Gallery URLs start
+// with baseURL.
+func NewPortfolioService(repo domain.PortfolioRepository, blobs domain.BlobStore, urls domain.AttachmentURLSigner, limits attachment.UploadLimits, baseURL string) *PortfolioService {
+	return &PortfolioService{
+		repo:    repo,
+		blobs:   blobs,
+		urls:    urls,
+		limits:  limits,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Register adds the service to a gRPC server
+func (s *PortfolioService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(PortfolioServiceName,
+		structrpc.ClientStream("CreatePortfolioItem", s.CreatePortfolioItem),
+		structrpc.Unary(PortfolioServiceName, "GetPortfolioItem", s.GetPortfolioItem),
+		structrpc.Unary(PortfolioServiceName, "ListPortfolioItems", s.ListPortfolioItems),
+		structrpc.Unary(PortfolioServiceName, "UpdatePortfolioItem", s.UpdatePortfolioItem),
+		structrpc.Unary(PortfolioServiceName, "DeletePortfolioItem", s.DeletePortfolioItem),
+		structrpc.Unary(PortfolioServiceName, "ReorderPortfolioItems", s.ReorderPortfolioItems),
+	), s)
+}
+
+// CreatePortfolioItem stores an image sent in chunks as a new item, last in
+// its artist's order. The original, a web-sized copy and a square thumbnail
+// are stored, all without EXIF metadata.
+func (s *PortfolioService) CreatePortfolioItem(stream *structrpc.Receiver[UploadPortfolioChunk]) (*PortfolioItemOutput, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(stream.Context(), "CreatePortfolioItem")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	limit, err := s.limits(ctx, tenant)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get upload limit")
+	}
+
+	// Images are decoded as a whole, so they are buffered in memory rather
+	// than spooled
+	var item *domain.PortfolioItem
+	var filename string
+	var data bytes.Buffer
+	for first := true; ; first = false {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// The item is checked before the rest of the image is taken in
+		if first {
+			if chunk.Item == nil {
+				return nil, status.Error(codes.InvalidArgument, "item is required in the first chunk")
+			}
+			if item, err = itemFromInput(chunk.Item, nil); err != nil {
+				return nil, err
+			}
+			if item.ArtistID == "" {
+				item.ArtistID = userID
+			}
+			if err = requireArtistOrManager(ctx, item.ArtistID); err != nil {
+				return nil, err
+			}
+			filename = attachment.CleanFilename(chunk.Filename)
+		}
+		if int64(data.Len()+len(chunk.Data)) > limit {
+			return nil, status.Errorf(codes.ResourceExhausted, "image is larger than the %d bytes allowed", limit)
+		}
+		data.Write(chunk.Data)
+	}
+	if data.Len() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "image is empty")
+	}
+
+	processed, err := processImage(data.Bytes())
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to process image")
+	}
+
+	uploadID := uuid.NewString()
+	for _, variant := range []struct {
+		name  string
+		image encodedImage
+	}{
+		{VariantOriginal, processed.original},
+		{VariantWeb, processed.web},
+		{VariantThumbnail, processed.thumbnail},
+	} {
+		key := imageKey(tenant, uploadID, variant.name)
+		if err = s.blobs.Put(ctx, key, variant.image.contentType, bytes.NewReader(variant.image.data), int64(len(variant.image.data))); err != nil {
+			s.deleteBlobs(ctx, tenant, item.Images)
+			return nil, domain.ToStatus(err, "failed to store image")
+		}
+		item.Images = append(item.Images, domain.Attachment{
+			Key:         key,
+			Filename:    variantFilename(filename, variant.name, variant.image.contentType),
+			ContentType: variant.image.contentType,
+			Size:        int64(len(variant.image.data)),
+			UploadedBy:  userID,
+			Variant:     variant.name,
+		})
+	}
+
+	if err = s.repo.Create(ctx, tenant, item); err != nil {
+		s.deleteBlobs(ctx, tenant, item.Images)
+		return nil, domain.ToStatus(err, "failed to create portfolio item")
+	}
+
+	span.SetAttributes(
+		attribute.String("portfolio_item.id", item.ID),
+		attribute.Int("portfolio_item.width", processed.original.width),
+		attribute.Int("portfolio_item.height", processed.original.height),
+	)
+
+	return s.output(tenant, item), nil
+}
+
+// GetPortfolioItem returns an item with a fresh URL of its original
+func (s *PortfolioService) GetPortfolioItem(ctx context.Context, req *PortfolioItemID) (*PortfolioItemOutput, error) {
+	ctx, span, tenant, _, err := domain.StartUserCall(ctx, "GetPortfolioItem")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	item, err := s.repo.GetByID(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get portfolio item")
+	}
+	return s.output(tenant, item), nil
+}
+
+// ListPortfolioItems pages through items, each artist's in their order
+func (s *PortfolioService) ListPortfolioItems(ctx context.Context, req *ListPortfolioItemsRequest) (*ListPortfolioItemsResponse, error) {
+	ctx, span, tenant, _, err := domain.StartUserCall(ctx, "ListPortfolioItems")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	result, err := s.repo.List(ctx, tenant, domain.PortfolioFilter{
+		StudioID: req.StudioID,
+		ArtistID: req.ArtistID,
+		Tag:      req.Tag,
+		Style:    req.Style,
+		Page:     req.Page,
+		PageSize: min(req.PageSize, domain.MaxPageSize),
+	})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list portfolio items")
+	}
+
+	res := &ListPortfolioItemsResponse{
+		Items:      make([]PortfolioItemOutput, 0, len(result.Items)),
+		TotalCount: result.TotalCount,
+	}
+	for i := range result.Items {
+		res.Items = append(res.Items, *s.output(tenant, &result.Items[i]))
+	}
+
+	span.SetAttributes(attribute.Int("portfolio_items.count", len(res.Items)))
+
+	return res, nil
+}
+
+// UpdatePortfolioItem applies the fields named in update_mask
+func (s *PortfolioService) UpdatePortfolioItem(ctx context.Context, req *UpdatePortfolioItemRequest) (*PortfolioItemOutput, error) {
+	ctx, span, tenant, _, err := domain.StartUserCall(ctx, "UpdatePortfolioItem")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	item, err := s.loadForArtist(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if item, err = itemFromInput(&req.Item, item); err != nil {
+		return nil, err
+	}
+	if err = s.repo.Update(ctx, tenant, item, req.UpdateMask); err != nil {
+		return nil, domain.ToStatus(err, "failed to update portfolio item")
+	}
+
+	item, err = s.repo.GetByID(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get portfolio item")
+	}
+	return s.output(tenant, item), nil
+}
+
+// DeletePortfolioItem removes an item and its images
+func (s *PortfolioService) DeletePortfolioItem(ctx context.Context, req *PortfolioItemID) (*MessageResponse, error) {
+	ctx, span, tenant, _, err := domain.StartUserCall(ctx, "DeletePortfolioItem")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if _, err = s.loadForArtist(ctx, tenant, req.ID); err != nil {
+		return nil, err
+	}
+	keys, err := s.repo.Delete(ctx, tenant, req.ID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to delete portfolio item")
+	}
+
+	// The item is gone either way; blobs left behind only cost storage
+	for _, key := range keys {
+		if err = s.blobs.Delete(context.WithoutCancel(ctx), key); err != nil {
+			logger.Log.Warn("failed to delete portfolio image",
+				zap.String("tenant", tenant), zap.String("key", key), zap.Error(err))
+		}
+	}
+
+	return &MessageResponse{Message: "Portfolio item deleted successfully"}, nil
+}
+
+// ReorderPortfolioItems sets the order of an artist's items in the gallery
+func (s *PortfolioService) ReorderPortfolioItems(ctx context.Context, req *ReorderPortfolioItemsRequest) (*ListPortfolioItemsResponse, error) {
+	ctx, span, tenant, userID, err := domain.StartUserCall(ctx, "ReorderPortfolioItems")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	artistID := req.ArtistID
+	if artistID == "" {
+		artistID = userID
+	}
+	if err = requireArtistOrManager(ctx, artistID); err != nil {
+		return nil, err
+	}
+	if len(req.ItemIDs) > maxReorderedItems {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d items can be reordered at once", maxReorderedItems)
+	}
+	if err = s.repo.Reorder(ctx, tenant, artistID, req.ItemIDs); err != nil {
+		return nil, domain.ToStatus(err, "failed to reorder portfolio items")
+	}
+
+	span.SetAttributes(attribute.Int("portfolio_items.reordered", len(req.ItemIDs)))
+
+	// The reordered items are now the artist's first
+	result, err := s.repo.List(ctx, tenant, domain.PortfolioFilter{ArtistID: artistID, PageSize: len(req.ItemIDs)})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list portfolio items")
+	}
+	res := &ListPortfolioItemsResponse{
+		Items:      make([]PortfolioItemOutput, 0, len(result.Items)),
+		TotalCount: result.TotalCount,
+	}
+	for i := range result.Items {
+		res.Items = append(res.Items, *s.output(tenant, &result.Items[i]))
+	}
+	return res, nil
+}
+
+// loadForArtist returns the item when the caller is its artist or a manager
+func (s *PortfolioService) loadForArtist(ctx context.Context, tenant, id string) (*domain.PortfolioItem, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	item, err := s.repo.GetByID(ctx, tenant, id)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get portfolio item")
+	}
+	if err = requireArtistOrManager(ctx, item.ArtistID); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// deleteBlobs removes the stored images of an item that could not be created
+func (s *PortfolioService) deleteBlobs(ctx context.Context, tenant string, images []domain.Attachment) {
+	for _, image := range images {
+		if err := s.blobs.Delete(context.WithoutCancel(ctx), image.Key); err != nil {
+			logger.Log.Warn("failed to delete orphaned blob",
+				zap.String("tenant", tenant), zap.String("key", image.Key), zap.Error(err))
+		}
+	}
+}
+
+func (s *PortfolioService) output(tenant string, item *domain.PortfolioItem) *PortfolioItemOutput {
+	gallery := NewGalleryItem(s.baseURL, tenant, item)
+	out := &PortfolioItemOutput{
+		ID:            item.ID,
+		StudioID:      item.StudioID,
+		ArtistID:      item.ArtistID,
+		ArtistName:    item.ArtistName,
+		Title:         item.Title,
+		Description:   item.Description,
+		Tags:          gallery.Tags,
+		Style:         item.Style,
+		BodyPlacement: item.BodyPlacement,
+		Position:      item.Position,
+		ImageURL:      gallery.ImageURL,
+		ThumbnailURL:  gallery.ThumbnailURL,
+		CreatedAt:     item.CreatedAt.Format(time.RFC3339),
+	}
+	if item.UpdatedAt != nil {
+		out.UpdatedAt = item.UpdatedAt.Format(time.RFC3339)
+	}
+	for i := range item.Images {
+		if item.Images[i].Variant == VariantOriginal {
+			out.Original = attachment.NewOutput(tenant, &item.Images[i], s.urls)
+		}
+	}
+	return out
+}
+
+// itemFromInput validates input and copies it onto item, or a new item when
+// item is nil
+func itemFromInput(input *PortfolioItemInput, item *domain.PortfolioItem) (*domain.PortfolioItem, error) {
+	if item == nil {
+		item = &domain.PortfolioItem{StudioID: input.StudioID, ArtistID: input.ArtistID}
+	}
+	item.Title = strings.TrimSpace(input.Title)
+	item.Description = strings.TrimSpace(input.Description)
+	item.Style = strings.TrimSpace(input.Style)
+	item.BodyPlacement = strings.TrimSpace(input.BodyPlacement)
+	if utf8.RuneCountInString(item.Title) > maxTitleLength {
+		return nil, status.Errorf(codes.InvalidArgument, "title must be at most %d characters", maxTitleLength)
+	}
+	if utf8.RuneCountInString(item.Style) > maxLabelLength || utf8.RuneCountInString(item.BodyPlacement) > maxLabelLength {
+		return nil, status.Errorf(codes.InvalidArgument, "style and body_placement must be at most %d characters", maxLabelLength)
+	}
+
+	item.Tags = make([]string, 0, len(input.Tags))
+	seen := make(map[string]bool, len(input.Tags))
+	for _, raw := range input.Tags {
+		tag := normalizeTag(raw)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, status.Errorf(codes.InvalidArgument, "tag %q is longer than %d characters", tag, maxTagLength)
+		}
+		seen[tag] = true
+		item.Tags = append(item.Tags, tag)
+	}
+	if len(item.Tags) > maxTags {
+		return nil, status.Errorf(codes.InvalidArgument, "an item can have at most %d tags", maxTags)
+	}
+	return item, nil
+}
+
+// normalizeTag lower-cases a tag and drops a leading '#' and extra spaces,
+// so "#Fine  Line" and "fine line" are the same tag
+func normalizeTag(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+func requireArtistOrManager(ctx context.Context, artistID string) error {
+	if userID, err := domain.ExtractUserIDFromContext(ctx); err == nil && userID == artistID {
+		return nil
+	}
+	return domain.RequireRole(ctx, managerRoles...)
+}
@@ -0,0 +1,64 @@
+package public
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+// responseCache keeps public responses in the tenant's Redis. Public pages
+// are read far more often than studios change, so entries simply expire;
+// changes show up within a TTL. Without Redis every call is computed.
+type responseCache struct {
+	redis *config.TenantRedisManager
+}
+
+// cacheKey namespaces a public response of a tenant
+func cacheKey(tenant, name string) string {
+	return fmt.Sprintf("public:%s:%s", tenant, name)
+}
+
+// cached returns the response stored under name, or computes and stores it
+func cached[T any](ctx context.Context, c responseCache, tenant, name string, ttl time.Duration, compute func() (*T, error)) (*T, error) {
+	key := cacheKey(tenant, name)
+	client, err := c.redis.GetTenantRedis(tenant)
+	if err == nil {
+		var data []byte
+		data, err = client.Get(ctx, key).Bytes()
+		if err == nil {
+			var res T
+			if err = json.Unmarshal(data, &res); err == nil {
+				return &res, nil
+			}
+		}
+	}
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logger.Log.Warn("failed to read cached public response",
+			zap.String("tenant", tenant), zap.String("key", key), zap.Error(err))
+	}
+
+	res, err := compute()
+	if err != nil {
+		return nil, err
+	}
+
+	if client != nil {
+		data, err := json.Marshal(res)
+		if err == nil {
+			err = client.Set(ctx, key, data, ttl).Err()
+		}
+		if err != nil {
+			logger.Log.Warn("failed to cache public response",
+				zap.String("tenant", tenant), zap.String("key", key), zap.Error(err))
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,80 @@
+package public
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// PublicRepository reads what a studio shows the public. Every query names
+// its columns, so nothing private is selected by accident.
+type PublicRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewPublicRepository creates a new PublicRepository
+func NewPublicRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *PublicRepository {
+	return &PublicRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+// artistRoles are the staff roles shown as artists
+var artistRoles = []string{"OWNER", "ARTIST"}
+
+// artistName is what the public page calls an artist; their email is never
+// used
+const artistName = `COALESCE(NULLIF(u.display_name, ''), NULLIF(btrim(concat_ws(' ', u.first_name, u.last_name)), ''), u.username, '')`
+
+func (r *PublicRepository) GetStudio(ctx context.Context, tenant string) (*domain.PublicStudio, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var studio domain.PublicStudio
+	err = pool.QueryRow(ctx,
+		`SELECT s.id, s.name, COALESCE(s.address, ''), COALESCE(s.phone, ''), COALESCE(s.website, ''),
+			COALESCE(ss.logo_url, '')
+		 FROM studios s LEFT JOIN studio_settings ss ON ss.studio_id = s.id
+		 WHERE s.subdomain = $1`, tenant).
+		Scan(&studio.ID, &studio.Name, &studio.Address, &studio.Phone, &studio.Website, &studio.LogoURL)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("studio %s: %w", tenant, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get studio: %w", err)
+	}
+
+	rows, err := pool.Query(ctx,
+		`SELECT u.id, `+artistName+`,
+			(SELECT count(*) FROM portfolio_items p WHERE p.artist_id = u.id AND p.studio_id = $1),
+			ARRAY(SELECT p.style FROM portfolio_items p
+				WHERE p.artist_id = u.id AND p.studio_id = $1 AND p.style IS NOT NULL
+				GROUP BY p.style ORDER BY count(*) DESC, p.style)
+		 FROM users u
+		 WHERE (u.studio_id = $1 AND u.role = ANY($2))
+			OR EXISTS (SELECT 1 FROM studio_staff s
+				WHERE s.user_id = u.id AND s.studio_id = $1 AND s.role = ANY($2))
+		 ORDER BY 2, u.id`,
+		studio.ID, artistRoles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query artists: %w", err)
+	}
+	studio.Artists, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.PublicArtist, error) {
+		var artist domain.PublicArtist
+		err := row.Scan(&artist.ID, &artist.Name, &artist.PortfolioItems, &artist.Styles)
+		return artist, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query artists: %w", err)
+	}
+	return &studio, nil
+}
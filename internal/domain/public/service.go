@@ -0,0 +1,354 @@
+package public
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/appointment"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/portfolio"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// PublicServiceName is the fully qualified gRPC name of the public service.
+// Its methods need no session: the studio is the one of the request's
+// subdomain, callers are rate limited per IP and responses are cached.
+const PublicServiceName = "inkMe.public.PublicService"
+
+const (
+	profileTTL = 10 * time.Minute
+	galleryTTL = 5 * time.Minute
+	slotsTTL   = time.Minute
+
+	// featuredItems is how much of each artist's portfolio the profile shows
+	featuredItems = 6
+	// maxFilterLength bounds the tag and style filters of the gallery
+	maxFilterLength = 50
+
+	defaultSlotDays     = 14
+	maxSlotDays         = 31
+	defaultSlotDuration = 60
+	minSlotDuration     = 15
+	maxSlotDuration     = 10 * 60
+	maxSlotsPerArtist   = 100
+	// slotWindow rounds the start of a slot search up, so searches within
+	// it share a cache entry and never offer a slot that has begun
+	slotWindow = 15 * time.Minute
+)
+
+type StudioProfileRequest struct{}
+
+type ArtistProfile struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	PortfolioItems int      `json:"portfolio_items"`
+	Styles         []string `json:"styles"`
+	// Featured are the first items of the artist's portfolio, in their order
+	Featured []portfolio.GalleryItem `json:"featured"`
+}
+
+type StudioProfile struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Address  string `json:"address,omitempty"`
+	Phone    string `json:"phone,omitempty"`
+	Website  string `json:"website,omitempty"`
+	LogoURL  string `json:"logo_url,omitempty"`
+	TimeZone string `json:"time_zone"`
+	// Hours are keyed by lower-case weekday; none means by appointment
+	Hours   map[string][]appointment.OpeningHours `json:"hours,omitempty"`
+	Artists []ArtistProfile                       `json:"artists"`
+}
+
+type GalleryRequest struct {
+	ArtistID string `json:"artist_id"`
+	Tag      string `json:"tag"`
+	Style    string `json:"style"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+}
+
+type OpenSlotsRequest struct {
+	// ArtistID limits the search to one artist; all are searched by default
+	ArtistID string `json:"artist_id"`
+	// DurationMinutes is the length of the session, an hour by default
+	DurationMinutes int `json:"duration_minutes"`
+	// Days is how far ahead to look, two weeks by default
+	Days int `json:"days"`
+}
+
+type ArtistSlots struct {
+	ArtistID   string             `json:"artist_id"`
+	ArtistName string             `json:"artist_name"`
+	Slots      []appointment.Slot `json:"slots"`
+}
+
+type OpenSlotsResponse struct {
+	TimeZone string        `json:"time_zone"`
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Artists  []ArtistSlots `json:"artists"`
+}
+
+// PublicService implements the public gRPC service. It only returns what a
+// studio would put on its website: no emails, no customers, and of the
+// calendar only the free slots.
+type PublicService struct {
+	repo         domain.PublicRepository
+	portfolios   domain.PortfolioRepository
+	availability domain.AvailabilityRepository
+	cache        responseCache
+	baseURL      string
+}
+
+// NewPublicService creates a new PublicService. Gallery image URLs start
+// with baseURL.
+func NewPublicService(repo domain.PublicRepository, portfolios domain.PortfolioRepository, availability domain.AvailabilityRepository, redisManager *config.TenantRedisManager, baseURL string) *PublicService {
+	return &PublicService{
+		repo:         repo,
+		portfolios:   portfolios,
+		availability: availability,
+		cache:        responseCache{redis: redisManager},
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Register adds the service to a gRPC server
+func (s *PublicService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(PublicServiceName,
+		structrpc.Unary(PublicServiceName, "GetStudioProfile", s.GetStudioProfile),
+		structrpc.Unary(PublicServiceName, "ListGallery", s.ListGallery),
+		structrpc.Unary(PublicServiceName, "ListOpenSlots", s.ListOpenSlots),
+	), s)
+}
+
+// GetStudioProfile returns the studio's name, address, opening hours and
+// artists with the start of their portfolios
+func (s *PublicService) GetStudioProfile(ctx context.Context, _ *StudioProfileRequest) (*StudioProfile, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetStudioProfile")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	profile, err := s.profile(ctx, tenant)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get studio profile")
+	}
+
+	span.SetAttributes(attribute.Int("artists.count", len(profile.Artists)))
+
+	return profile, nil
+}
+
+// ListGallery pages through the studio's portfolio, each artist's in their
+// order
+func (s *PublicService) ListGallery(ctx context.Context, req *GalleryRequest) (*portfolio.GalleryResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ListGallery")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if len(req.Tag) > maxFilterLength || len(req.Style) > maxFilterLength {
+		return nil, status.Errorf(codes.InvalidArgument, "tag and style must be at most %d characters", maxFilterLength)
+	}
+	filter := *req
+	filter.Page = max(filter.Page, 1)
+	if filter.PageSize < 1 {
+		filter.PageSize = domain.DefaultPageSize
+	}
+	filter.PageSize = min(filter.PageSize, domain.MaxPageSize)
+
+	res, err := cached(ctx, s.cache, tenant, "gallery:"+hashRequest(filter), galleryTTL, func() (*portfolio.GalleryResponse, error) {
+		profile, err := s.profile(ctx, tenant)
+		if err != nil {
+			return nil, err
+		}
+		return s.gallery(ctx, tenant, domain.PortfolioFilter{
+			StudioID: profile.ID,
+			ArtistID: filter.ArtistID,
+			Tag:      filter.Tag,
+			Style:    filter.Style,
+			Page:     filter.Page,
+			PageSize: filter.PageSize,
+		})
+	})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list gallery")
+	}
+
+	span.SetAttributes(attribute.Int("portfolio_items.count", len(res.Items)))
+
+	return res, nil
+}
+
+// ListOpenSlots returns the free slots of the studio's artists, from the
+// next quarter hour on
+func (s *PublicService) ListOpenSlots(ctx context.Context, req *OpenSlotsRequest) (*OpenSlotsResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ListOpenSlots")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	filter := *req
+	if filter.DurationMinutes == 0 {
+		filter.DurationMinutes = defaultSlotDuration
+	}
+	if filter.Days == 0 {
+		filter.Days = defaultSlotDays
+	}
+	if filter.DurationMinutes < minSlotDuration || filter.DurationMinutes > maxSlotDuration {
+		return nil, status.Errorf(codes.InvalidArgument, "duration_minutes must be between %d and %d", minSlotDuration, maxSlotDuration)
+	}
+	if filter.Days < 1 || filter.Days > maxSlotDays {
+		return nil, status.Errorf(codes.InvalidArgument, "days must be between 1 and %d", maxSlotDays)
+	}
+
+	profile, err := s.profile(ctx, tenant)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get studio profile")
+	}
+	artists := profile.Artists
+	if filter.ArtistID != "" {
+		i := slices.IndexFunc(artists, func(a ArtistProfile) bool { return a.ID == filter.ArtistID })
+		if i < 0 {
+			return nil, status.Error(codes.NotFound, "artist not found")
+		}
+		artists = artists[i : i+1]
+	}
+
+	from := time.Now().Truncate(slotWindow).Add(slotWindow)
+	to := from.AddDate(0, 0, filter.Days)
+	name := "slots:" + from.UTC().Format(time.RFC3339) + ":" + hashRequest(filter)
+	res, err := cached(ctx, s.cache, tenant, name, slotsTTL, func() (*OpenSlotsResponse, error) {
+		res := &OpenSlotsResponse{
+			TimeZone: profile.TimeZone,
+			From:     from.Format(time.RFC3339),
+			To:       to.Format(time.RFC3339),
+			Artists:  make([]ArtistSlots, 0, len(artists)),
+		}
+		for _, artist := range artists {
+			schedule, err := s.availability.GetSchedule(ctx, tenant, artist.ID, from, to)
+			if err != nil {
+				return nil, err
+			}
+			slots, err := appointment.FindSlots(schedule, from, to,
+				time.Duration(filter.DurationMinutes)*time.Minute, appointment.DefaultSlotStep)
+			if err != nil {
+				return nil, err
+			}
+			out := ArtistSlots{ArtistID: artist.ID, ArtistName: artist.Name, Slots: make([]appointment.Slot, 0, min(len(slots), maxSlotsPerArtist))}
+			for _, slot := range slots[:min(len(slots), maxSlotsPerArtist)] {
+				out.Slots = append(out.Slots, appointment.Slot{
+					Start: slot.Start.Format(time.RFC3339),
+					End:   slot.End.Format(time.RFC3339),
+				})
+			}
+			res.Artists = append(res.Artists, out)
+		}
+		return res, nil
+	})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to find open slots")
+	}
+
+	span.SetAttributes(attribute.Int("artists.count", len(res.Artists)))
+
+	return res, nil
+}
+
+// profile builds the studio's profile, or takes it from the cache
+func (s *PublicService) profile(ctx context.Context, tenant string) (*StudioProfile, error) {
+	return cached(ctx, s.cache, tenant, "profile", profileTTL, func() (*StudioProfile, error) {
+		studio, err := s.repo.GetStudio(ctx, tenant)
+		if err != nil {
+			return nil, err
+		}
+		hours, err := s.availability.GetStudioHours(ctx, tenant, studio.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		profile := &StudioProfile{
+			ID:       studio.ID,
+			Name:     studio.Name,
+			Address:  studio.Address,
+			Phone:    studio.Phone,
+			Website:  studio.Website,
+			LogoURL:  studio.LogoURL,
+			TimeZone: hours.TimeZone,
+			Artists:  make([]ArtistProfile, 0, len(studio.Artists)),
+		}
+		if hours.BusinessHours != nil {
+			profile.Hours = make(map[string][]appointment.OpeningHours)
+			for _, h := range hours.BusinessHours {
+				day := strings.ToLower(h.Weekday.String())
+				profile.Hours[day] = append(profile.Hours[day], appointment.OpeningHours{Open: h.Start, Close: h.End})
+			}
+		}
+
+		for _, artist := range studio.Artists {
+			out := ArtistProfile{
+				ID:             artist.ID,
+				Name:           artist.Name,
+				PortfolioItems: artist.PortfolioItems,
+				Styles:         artist.Styles,
+				Featured:       []portfolio.GalleryItem{},
+			}
+			if out.Styles == nil {
+				out.Styles = []string{}
+			}
+			if artist.PortfolioItems > 0 {
+				featured, err := s.gallery(ctx, tenant, domain.PortfolioFilter{
+					StudioID: studio.ID,
+					ArtistID: artist.ID,
+					PageSize: featuredItems,
+				})
+				if err != nil {
+					return nil, err
+				}
+				out.Featured = featured.Items
+			}
+			profile.Artists = append(profile.Artists, out)
+		}
+		return profile, nil
+	})
+}
+
+func (s *PublicService) gallery(ctx context.Context, tenant string, filter domain.PortfolioFilter) (*portfolio.GalleryResponse, error) {
+	result, err := s.portfolios.List(ctx, tenant, filter)
+	if err != nil {
+		return nil, err
+	}
+	res := &portfolio.GalleryResponse{
+		Items:      make([]portfolio.GalleryItem, 0, len(result.Items)),
+		TotalCount: result.TotalCount,
+		Page:       result.Page,
+		PageSize:   result.PageSize,
+	}
+	for i := range result.Items {
+		res.Items = append(res.Items, portfolio.NewGalleryItem(s.baseURL, tenant, &result.Items[i]))
+	}
+	return res, nil
+}
+
+// hashRequest turns a request into a short cache key, whatever its filters
+// contain
+func hashRequest(req any) string {
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:12])
+}
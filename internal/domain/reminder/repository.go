@@ -0,0 +1,361 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// Defaults of studios without reminder settings; they match the column
+// defaults of studio_settings
+const (
+	defaultLeadMinutes = 24 * 60
+	defaultArtistTime  = "08:00"
+
+	// artistLeadMinutes is the furthest an artist reminder, sent on the day
+	// before at the latest, falls ahead of the appointment
+	artistLeadMinutes = 2 * 24 * 60
+)
+
+// ReminderRepository queues appointment reminders in the tenant's database
+type ReminderRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewReminderRepository creates a new ReminderRepository
+func NewReminderRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *ReminderRepository {
+	return &ReminderRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+const reminderColumns = `r.id, r.appointment_id, a.studio_id, a.customers_id, COALESCE(a.artist_id::text, ''),
+	r.recipient, r.starts_at, a.end_time, COALESCE(ss.timezone, 'UTC'), r.due_at, r.status, r.sent_at`
+
+const reminderFrom = ` FROM appointment_reminders r
+	JOIN appointments a ON a.id = r.appointment_id
+	LEFT JOIN studio_settings ss ON ss.studio_id = a.studio_id`
+
+// GetSettings returns the reminder settings of a studio
+func (r *ReminderRepository) GetSettings(ctx context.Context, tenant, studioID string) (*domain.ReminderSettings, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	settings := &domain.ReminderSettings{StudioID: studioID}
+	var leads []int32
+	err = pool.QueryRow(ctx,
+		`SELECT ss.reminder_lead_minutes, COALESCE(to_char(ss.artist_reminder_time, 'HH24:MI'), '')
+		 FROM studios s LEFT JOIN studio_settings ss ON ss.studio_id = s.id
+		 WHERE s.id = $1`, studioID).Scan(&leads, &settings.ArtistTime)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("studio: %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get reminder settings", err)
+	}
+
+	if leads == nil {
+		leads = []int32{defaultLeadMinutes}
+	}
+	if settings.ArtistTime == "" {
+		settings.ArtistTime = defaultArtistTime
+	}
+	settings.LeadMinutes = make([]int, 0, len(leads))
+	for _, lead := range leads {
+		settings.LeadMinutes = append(settings.LeadMinutes, int(lead))
+	}
+	return settings, nil
+}
+
+// SetSettings stores the reminder settings of a studio. Reminders already
+// queued keep their time.
+func (r *ReminderRepository) SetSettings(ctx context.Context, tenant string, settings *domain.ReminderSettings) error {
+	artistTime, err := time.Parse("15:04", settings.ArtistTime)
+	if err != nil {
+		return fmt.Errorf("%w: artist reminder time must be HH:MM", domain.ErrInvalidArgument)
+	}
+	leads := make([]int32, 0, len(settings.LeadMinutes))
+	for _, lead := range settings.LeadMinutes {
+		if lead <= 0 {
+			return fmt.Errorf("%w: reminder lead times must be positive", domain.ErrInvalidArgument)
+		}
+		leads = append(leads, int32(lead))
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	_, err = pool.Exec(ctx,
+		`INSERT INTO studio_settings (studio_id, reminder_lead_minutes, artist_reminder_time, updated_at)
+		 VALUES ($1, $2, $3::time, now())
+		 ON CONFLICT (studio_id) DO UPDATE SET reminder_lead_minutes = EXCLUDED.reminder_lead_minutes,
+			artist_reminder_time = EXCLUDED.artist_reminder_time, updated_at = now()`,
+		settings.StudioID, leads, artistTime.Format("15:04"))
+	if err != nil {
+		return domain.WrapError("failed to save reminder settings", err)
+	}
+	MarkDue(ctx, r.RedisManager, tenant)
+	return nil
+}
+
+// List returns the reminders queued for an appointment, in due order
+func (r *ReminderRepository) List(ctx context.Context, tenant, appointmentID string) ([]domain.Reminder, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	rows, err := pool.Query(ctx,
+		"SELECT "+reminderColumns+reminderFrom+" WHERE r.appointment_id = $1 ORDER BY r.due_at", appointmentID)
+	if err != nil {
+		return nil, domain.WrapError("failed to list reminders", err)
+	}
+	defer rows.Close()
+
+	reminders := []domain.Reminder{}
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, domain.WrapError("failed to scan reminder", err)
+		}
+		reminders = append(reminders, *reminder)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to list reminders", err)
+	}
+	return reminders, nil
+}
+
+// Enqueue queues client reminders at each lead time of the studio and artist
+// reminders on the morning of the appointment, or the day before when the
+// appointment starts earlier. Reminders that would have fallen due before the
+// appointment was booked are not queued.
+func (r *ReminderRepository) Enqueue(ctx context.Context, tenant string, until time.Time) (int, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	clients, err := pool.Exec(ctx,
+		`INSERT INTO appointment_reminders (appointment_id, recipient, starts_at, due_at)
+		 SELECT a.id, 'CLIENT', a.start_time, due.due_at
+		 FROM appointments a
+		 LEFT JOIN studio_settings ss ON ss.studio_id = a.studio_id
+		 CROSS JOIN LATERAL unnest(COALESCE(ss.reminder_lead_minutes, ARRAY[$2::integer])) AS lead(minutes)
+		 CROSS JOIN LATERAL (SELECT a.start_time - make_interval(mins => lead.minutes) AS due_at) due
+		 WHERE a.status = $3 AND a.start_time > now() AND due.due_at BETWEEN a.created_at AND $1
+		 ON CONFLICT DO NOTHING`,
+		until, defaultLeadMinutes, domain.AppointmentScheduled)
+	if err != nil {
+		return 0, domain.WrapError("failed to enqueue client reminders", err)
+	}
+
+	artists, err := pool.Exec(ctx,
+		`INSERT INTO appointment_reminders (appointment_id, recipient, starts_at, due_at)
+		 SELECT a.id, 'ARTIST', a.start_time, due.due_at
+		 FROM appointments a
+		 LEFT JOIN studio_settings ss ON ss.studio_id = a.studio_id
+		 CROSS JOIN LATERAL (SELECT COALESCE(ss.timezone, 'UTC') AS tz,
+			COALESCE(ss.artist_reminder_time, $2::time) AS remind_at) s
+		 CROSS JOIN LATERAL (SELECT (a.start_time AT TIME ZONE s.tz)::date AS day) d
+		 CROSS JOIN LATERAL (SELECT CASE
+			WHEN (d.day + s.remind_at) AT TIME ZONE s.tz < a.start_time THEN (d.day + s.remind_at) AT TIME ZONE s.tz
+			ELSE (d.day - 1 + s.remind_at) AT TIME ZONE s.tz END AS due_at) due
+		 WHERE a.status = $3 AND a.artist_id IS NOT NULL AND a.start_time > now()
+			AND due.due_at BETWEEN a.created_at AND $1
+		 ON CONFLICT DO NOTHING`,
+		until, defaultArtistTime, domain.AppointmentScheduled)
+	if err != nil {
+		return 0, domain.WrapError("failed to enqueue artist reminders", err)
+	}
+
+	return int(clients.RowsAffected() + artists.RowsAffected()), nil
+}
+
+// EnqueueNow queues a client reminder due immediately. The appointment must
+// be SCHEDULED and not have started.
+func (r *ReminderRepository) EnqueueNow(ctx context.Context, tenant, appointmentID string) (*domain.Reminder, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var reminder *domain.Reminder
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		var appointmentStatus string
+		var start time.Time
+		err := tx.QueryRow(ctx, "SELECT status, start_time FROM appointments WHERE id = $1", appointmentID).
+			Scan(&appointmentStatus, &start)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("appointment %s: %w", appointmentID, domain.ErrNotFound)
+		}
+		if err != nil {
+			return domain.WrapError("failed to get appointment", err)
+		}
+		if appointmentStatus != domain.AppointmentScheduled || !start.After(time.Now()) {
+			return fmt.Errorf("%w: only upcoming SCHEDULED appointments get reminders", domain.ErrFailedPrecondition)
+		}
+
+		var id string
+		err = tx.QueryRow(ctx,
+			`INSERT INTO appointment_reminders (appointment_id, recipient, starts_at, due_at)
+			 VALUES ($1, $2, $3, now()) RETURNING id`,
+			appointmentID, domain.ReminderClient, start).Scan(&id)
+		if err != nil {
+			return domain.WrapError("failed to enqueue reminder", err)
+		}
+
+		reminder, err = scanReminder(tx.QueryRow(ctx, "SELECT "+reminderColumns+reminderFrom+" WHERE r.id = $1", id))
+		if err != nil {
+			return domain.WrapError("failed to get reminder", err)
+		}
+		return nil
+	})
+	return reminder, err
+}
+
+// Dispatch sends up to limit due reminders. Each one is claimed in its own
+// transaction, with SKIP LOCKED so concurrent dispatchers never pick the same
+// reminder, and committed as SENT before send is called, so no transaction is
+// held open while a notification goes out. A reminder whose appointment was
+// cancelled, moved or has started is SKIPPED. A failed send puts the reminder
+// back to PENDING for the next dispatch; the errors are returned together
+// after the others were sent.
+func (r *ReminderRepository) Dispatch(ctx context.Context, tenant string, now time.Time, limit int, send func(context.Context, *domain.Reminder) error) (int, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	sent := 0
+	// failed is never nil: a NULL array would exclude every reminder
+	failed := []string{}
+	var sendErrs []error
+	for claimed := 0; claimed < limit; claimed++ {
+		reminder, ok, err := claimReminder(ctx, pool, now, failed)
+		if err != nil {
+			return sent, errors.Join(append(sendErrs, err)...)
+		}
+		if reminder == nil {
+			break
+		}
+		if !ok {
+			continue
+		}
+
+		if err := send(ctx, reminder); err != nil {
+			sendErrs = append(sendErrs, fmt.Errorf("reminder %s: %w", reminder.ID, err))
+			failed = append(failed, reminder.ID)
+			_, err = pool.Exec(context.WithoutCancel(ctx),
+				`UPDATE appointment_reminders SET status = $2, sent_at = NULL WHERE id = $1 AND status = $3`,
+				reminder.ID, domain.ReminderPending, domain.ReminderSent)
+			if err != nil {
+				sendErrs = append(sendErrs, domain.WrapError("failed to release reminder", err))
+			}
+			continue
+		}
+		sent++
+	}
+	return sent, errors.Join(sendErrs...)
+}
+
+// claimReminder locks the earliest due reminder not in skip and commits it as
+// SENT when it still applies, or SKIPPED when it does not. It returns a nil
+// reminder once nothing is due.
+func claimReminder(ctx context.Context, pool *pgxpool.Pool, now time.Time, skip []string) (*domain.Reminder, bool, error) {
+	var (
+		reminder *domain.Reminder
+		ok       bool
+	)
+	err := pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		var appointmentStatus string
+		var start time.Time
+		var err error
+		reminder, err = scanReminder(tx.QueryRow(ctx,
+			`SELECT `+reminderColumns+`, a.status, a.start_time`+reminderFrom+`
+			 WHERE r.status = $1 AND r.due_at <= $2 AND r.id <> ALL($3::uuid[])
+			 ORDER BY r.due_at LIMIT 1
+			 FOR UPDATE OF r SKIP LOCKED`,
+			domain.ReminderPending, now, skip), &appointmentStatus, &start)
+		if errors.Is(err, pgx.ErrNoRows) {
+			reminder = nil
+			return nil
+		}
+		if err != nil {
+			return domain.WrapError("failed to query due reminders", err)
+		}
+
+		next := domain.ReminderSkipped
+		if appointmentStatus == domain.AppointmentScheduled && start.Equal(reminder.StartTime) && start.After(now) {
+			next, ok = domain.ReminderSent, true
+		}
+		_, err = tx.Exec(ctx,
+			`UPDATE appointment_reminders
+			 SET status = $2, sent_at = CASE WHEN $2 = 'SENT' THEN now() END
+			 WHERE id = $1`,
+			reminder.ID, next)
+		if err != nil {
+			return domain.WrapError("failed to update reminder", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return reminder, ok, nil
+}
+
+// NextDue returns when the tenant next has a reminder to queue or send, or the
+// zero time when no SCHEDULED appointment lies ahead. Appointments count from
+// their longest lead time, or two days for the artist reminder, so the time
+// may come early but never late.
+func (r *ReminderRepository) NextDue(ctx context.Context, tenant string) (time.Time, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var next *time.Time
+	err = pool.QueryRow(ctx,
+		`SELECT LEAST(
+			(SELECT min(due_at) FROM appointment_reminders WHERE status = $1),
+			(SELECT min(a.start_time - make_interval(mins => GREATEST(
+				COALESCE((SELECT max(lead) FROM unnest(ss.reminder_lead_minutes) AS lead), $2), $3)))
+			 FROM appointments a
+			 LEFT JOIN studio_settings ss ON ss.studio_id = a.studio_id
+			 WHERE a.status = $4 AND a.start_time > now()))`,
+		domain.ReminderPending, defaultLeadMinutes, artistLeadMinutes, domain.AppointmentScheduled).Scan(&next)
+	if err != nil {
+		return time.Time{}, domain.WrapError("failed to get next reminder", err)
+	}
+	if next == nil {
+		return time.Time{}, nil
+	}
+	return *next, nil
+}
+
+func scanReminder(row pgx.Row, extra ...any) (*domain.Reminder, error) {
+	reminder := &domain.Reminder{}
+	dest := append([]any{
+		&reminder.ID, &reminder.AppointmentID, &reminder.StudioID, &reminder.CustomerID, &reminder.ArtistID,
+		&reminder.Recipient, &reminder.StartTime, &reminder.EndTime, &reminder.Timezone, &reminder.DueAt,
+		&reminder.Status, &reminder.SentAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return reminder, nil
+}
@@ -0,0 +1,262 @@
+package reminder
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+const (
+	defaultInterval  = time.Minute
+	defaultBatchSize = 100
+
+	// dueTTL bounds how long a tenant's next due time is trusted, so changes
+	// made without MarkDue, such as archive imports, are picked up eventually
+	dueTTL = time.Hour
+
+	// NotifyAppointmentReminder is the notification kind of reminders
+	NotifyAppointmentReminder = "appointment.reminder"
+)
+
+// unlockScript deletes the lock only while it still holds our token, so a
+// replica whose lock expired cannot release the next holder's
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// setDueScript stores the next due time only while the key still holds the
+// scan's token; a MarkDue during the scan deletes it, so the stale result is
+// dropped and the tenant is scanned again
+var setDueScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+end
+return 0`)
+
+// TenantLister returns the tenants the scheduler scans
+type TenantLister func(ctx context.Context) ([]config.TenantConfig, error)
+
+// Scheduler periodically queues and sends the appointment reminders of every
+// active tenant. Each replica runs one; a per-tenant Redis lock lets a single
+// replica at a time dispatch a tenant, and the row locks and statuses of the
+// queue make sure no reminder is sent twice. Tenants without an open pool are
+// only scanned once the next due time kept in their Redis has come, so idle
+// tenants do not have their pools opened every interval.
+type Scheduler struct {
+	repo         domain.ReminderRepository
+	notifier     domain.Notifier
+	dbManager    *config.TenantDBManager
+	redisManager *config.TenantRedisManager
+	tenants      TenantLister
+	interval     time.Duration
+	batchSize    int
+}
+
+// NewScheduler creates a new Scheduler
+func NewScheduler(repo domain.ReminderRepository, notifier domain.Notifier, dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager, tenants TenantLister, cfg config.ReminderConfig) *Scheduler {
+	scheduler := &Scheduler{
+		repo:         repo,
+		notifier:     notifier,
+		dbManager:    dbManager,
+		redisManager: redisManager,
+		tenants:      tenants,
+		interval:     cfg.Interval,
+		batchSize:    cfg.BatchSize,
+	}
+	if scheduler.interval <= 0 {
+		scheduler.interval = defaultInterval
+	}
+	if scheduler.batchSize <= 0 {
+		scheduler.batchSize = defaultBatchSize
+	}
+	return scheduler
+}
+
+// Run scans the tenants every interval. It returns when ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.scan(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) scan(ctx context.Context) {
+	tenants, err := s.tenants(ctx)
+	if err != nil {
+		logger.Log.Error("Failed to list tenants for reminders", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	for _, tenant := range tenants {
+		if tenant.Status != config.TenantStatusActive {
+			continue
+		}
+		if !s.dbManager.HasTenant(tenant.Subdomain) && !s.isDue(ctx, tenant.Subdomain, now) {
+			continue
+		}
+		sent, err := s.scanTenant(ctx, tenant.Subdomain)
+		if err != nil {
+			logger.Log.Warn("Failed to send appointment reminders",
+				zap.String("tenant", tenant.Subdomain),
+				zap.Int("sent", sent),
+				zap.Error(err))
+			continue
+		}
+		if sent > 0 {
+			logger.Log.Info("Sent appointment reminders",
+				zap.String("tenant", tenant.Subdomain),
+				zap.Int("sent", sent))
+		}
+	}
+}
+
+// scanTenant queues the tenant's due reminders and sends up to a batch of
+// them, unless another replica holds the tenant's lock
+func (s *Scheduler) scanTenant(ctx context.Context, tenant string) (int, error) {
+	client, err := s.redisManager.GetTenantRedis(tenant)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	key, token := fmt.Sprintf("reminders:lock:%s", tenant), randomToken()
+	locked, err := client.SetNX(ctx, key, token, s.interval).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock reminders: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+	defer func() {
+		if err := unlockScript.Run(context.WithoutCancel(ctx), client, []string{key}, token).Err(); err != nil {
+			logger.Log.Warn("Failed to unlock reminders", zap.String("tenant", tenant), zap.Error(err))
+		}
+	}()
+
+	// Claim the due key before reading the database, so a change made while
+	// the tenant is scanned is not overwritten by the stale result
+	dueKey := reminderDueKey(tenant)
+	if err = client.Set(ctx, dueKey, token, dueTTL).Err(); err != nil {
+		return 0, fmt.Errorf("failed to claim reminder due time: %w", err)
+	}
+
+	now := time.Now()
+	if _, err = s.repo.Enqueue(ctx, tenant, now); err != nil {
+		return 0, err
+	}
+
+	sent, err := s.repo.Dispatch(ctx, tenant, now, s.batchSize, func(ctx context.Context, reminder *domain.Reminder) error {
+		return s.notifier.Notify(ctx, tenant, reminderNotification(reminder))
+	})
+	if err != nil {
+		// The tenant stays due, so failed sends are retried on the next scan
+		return sent, err
+	}
+
+	next, err := s.repo.NextDue(ctx, tenant)
+	if err != nil {
+		return sent, err
+	}
+	var due int64
+	if !next.IsZero() {
+		due = next.Unix()
+	}
+	err = setDueScript.Run(ctx, client, []string{dueKey}, token, due, dueTTL.Milliseconds()).Err()
+	if err != nil {
+		return sent, fmt.Errorf("failed to store reminder due time: %w", err)
+	}
+	return sent, nil
+}
+
+// isDue reports whether a tenant whose pool is closed should be scanned: its
+// next due time has come or is unknown. 0 means nothing is scheduled.
+func (s *Scheduler) isDue(ctx context.Context, tenant string, now time.Time) bool {
+	client, err := s.redisManager.GetTenantRedis(tenant)
+	if err != nil {
+		return false
+	}
+	value, err := client.Get(ctx, reminderDueKey(tenant)).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logger.Log.Warn("Failed to read reminder due time", zap.String("tenant", tenant), zap.Error(err))
+		}
+		return true
+	}
+	due, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		// A scan is in progress or was interrupted
+		return true
+	}
+	return due != 0 && due <= now.Unix()
+}
+
+// MarkDue makes the scheduler scan a tenant on its next run, whether or not
+// its pool is open. Call it after appointments or reminder settings change.
+func MarkDue(ctx context.Context, redisManager *config.TenantRedisManager, tenant string) {
+	client, err := redisManager.GetTenantRedis(tenant)
+	if err == nil {
+		err = client.Del(ctx, reminderDueKey(tenant)).Err()
+	}
+	if err != nil {
+		logger.Log.Warn("Failed to mark reminders due", zap.String("tenant", tenant), zap.Error(err))
+	}
+}
+
+func reminderDueKey(tenant string) string {
+	return fmt.Sprintf("reminders:due:%s", tenant)
+}
+
+// reminderNotification addresses a reminder to the client or the artist,
+// with the start time in the studio's time zone
+func reminderNotification(reminder *domain.Reminder) domain.Notification {
+	location, err := time.LoadLocation(reminder.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	start := reminder.StartTime.In(location)
+
+	notification := domain.Notification{
+		Kind:  NotifyAppointmentReminder,
+		Title: "Appointment reminder",
+		Body:  "Your appointment is on " + start.Format("Monday, 2 January at 15:04"),
+		Data: map[string]string{
+			"appointment_id": reminder.AppointmentID,
+			"reminder_id":    reminder.ID,
+			"start_time":     reminder.StartTime.Format(time.RFC3339),
+		},
+	}
+	if reminder.Recipient == domain.ReminderArtist {
+		notification.UserID = reminder.ArtistID
+		notification.Title = "Upcoming appointment"
+		notification.Body = "You have an appointment on " + start.Format("Monday, 2 January at 15:04")
+		return notification
+	}
+	notification.CustomerID = reminder.CustomerID
+	return notification
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,168 @@
+package reminder
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// ReminderServiceName is the fully qualified gRPC name of the reminder
+// service. The appointment protos can only trigger a reminder, so the studio's
+// reminder settings and the reminder queue live here.
+const ReminderServiceName = "inkMe.appointment.ReminderService"
+
+// managerRoles may change the reminder settings of a studio
+var managerRoles = []string{"OWNER"}
+
+type ReminderSettingsRequest struct {
+	StudioID string `json:"studio_id"`
+	// LeadMinutes lists how long before an appointment clients are reminded
+	LeadMinutes []int `json:"lead_minutes"`
+	// ArtistTime is HH:MM in the studio's time zone
+	ArtistTime string `json:"artist_time"`
+}
+
+type ReminderSettingsResponse struct {
+	StudioID    string `json:"studio_id"`
+	LeadMinutes []int  `json:"lead_minutes"`
+	ArtistTime  string `json:"artist_time"`
+}
+
+type ListRemindersRequest struct {
+	AppointmentID string `json:"appointment_id"`
+}
+
+type ReminderOutput struct {
+	ID            string `json:"id"`
+	AppointmentID string `json:"appointment_id"`
+	Recipient     string `json:"recipient"`
+	DueAt         string `json:"due_at"`
+	Status        string `json:"status"`
+	SentAt        string `json:"sent_at,omitempty"`
+}
+
+type ListRemindersResponse struct {
+	Reminders []ReminderOutput `json:"reminders"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// ReminderService implements the reminder gRPC service
+type ReminderService struct {
+	repo domain.ReminderRepository
+}
+
+// NewReminderService creates a new ReminderService
+func NewReminderService(repo domain.ReminderRepository) *ReminderService {
+	return &ReminderService{repo: repo}
+}
+
+// Register adds the service to a gRPC server
+func (s *ReminderService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(ReminderServiceName,
+		structrpc.Unary(ReminderServiceName, "GetReminderSettings", s.GetReminderSettings),
+		structrpc.Unary(ReminderServiceName, "SetReminderSettings", s.SetReminderSettings),
+		structrpc.Unary(ReminderServiceName, "ListReminders", s.ListReminders),
+	), s)
+}
+
+func (s *ReminderService) GetReminderSettings(ctx context.Context, req *ReminderSettingsRequest) (*ReminderSettingsResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetReminderSettings")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.StudioID == "" {
+		return nil, status.Error(codes.InvalidArgument, "studio_id is required")
+	}
+
+	settings, err := s.repo.GetSettings(ctx, tenant, req.StudioID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get reminder settings")
+	}
+
+	return &ReminderSettingsResponse{
+		StudioID:    settings.StudioID,
+		LeadMinutes: settings.LeadMinutes,
+		ArtistTime:  settings.ArtistTime,
+	}, nil
+}
+
+// SetReminderSettings replaces the studio's reminder lead times and the time
+// artists get their reminders. An empty lead_minutes turns client reminders
+// off.
+func (s *ReminderService) SetReminderSettings(ctx context.Context, req *ReminderSettingsRequest) (*MessageResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "SetReminderSettings")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if err = domain.RequireRole(ctx, managerRoles...); err != nil {
+		return nil, err
+	}
+	if req.StudioID == "" {
+		return nil, status.Error(codes.InvalidArgument, "studio_id is required")
+	}
+
+	settings := &domain.ReminderSettings{
+		StudioID:    req.StudioID,
+		LeadMinutes: req.LeadMinutes,
+		ArtistTime:  req.ArtistTime,
+	}
+	if settings.ArtistTime == "" {
+		settings.ArtistTime = defaultArtistTime
+	}
+	if err = s.repo.SetSettings(ctx, tenant, settings); err != nil {
+		return nil, domain.ToStatus(err, "failed to set reminder settings")
+	}
+
+	return &MessageResponse{Message: "Reminder settings saved successfully"}, nil
+}
+
+// ListReminders returns the queued and sent reminders of an appointment
+func (s *ReminderService) ListReminders(ctx context.Context, req *ListRemindersRequest) (*ListRemindersResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "ListReminders")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.AppointmentID == "" {
+		return nil, status.Error(codes.InvalidArgument, "appointment_id is required")
+	}
+
+	reminders, err := s.repo.List(ctx, tenant, req.AppointmentID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list reminders")
+	}
+
+	res := &ListRemindersResponse{Reminders: make([]ReminderOutput, 0, len(reminders))}
+	for _, reminder := range reminders {
+		out := ReminderOutput{
+			ID:            reminder.ID,
+			AppointmentID: reminder.AppointmentID,
+			Recipient:     reminder.Recipient,
+			DueAt:         reminder.DueAt.Format(time.RFC3339),
+			Status:        reminder.Status,
+		}
+		if reminder.SentAt != nil {
+			out.SentAt = reminder.SentAt.Format(time.RFC3339)
+		}
+		res.Reminders = append(res.Reminders, out)
+	}
+
+	span.SetAttributes(attribute.Int("reminders.count", len(res.Reminders)))
+
+	return res, nil
+}
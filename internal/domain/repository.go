@@ -2,9 +2,12 @@ package domain
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
 )
 
 // type AuthRepository interface {
@@ -78,15 +81,20 @@ type CustomerNote struct {
 
 // CustomerHistory represents a historical interaction with a customer
 type CustomerHistory struct {
-	ID          string
-	CustomerID  string
-	Type        string // e.g., "appointment", "message", "purchase"
-	Description string
-	Timestamp   time.Time
+	ID            string
+	CustomerID    string
+	Type          string // e.g., "appointment", "message", "purchase"
+	Description   string
+	ArtistID      string
+	AppointmentID string
+	CreatedBy     string
+	Timestamp     time.Time
 }
 
 // CustomerFilter defines search criteria for customers
 type CustomerFilter struct {
+	StudioID        string
+	Query           string // matched against name, email, phone and NIF
 	Name            string
 	Email           string
 	Phone           string
@@ -154,31 +162,639 @@ type User struct {
 	DeletedAt *time.Time
 }
 
+// UserFilter defines search criteria for users
+type UserFilter struct {
+	Query    string // matched against email, username and display name
+	Role     string
+	StudioID string
+	Page     int
+	PageSize int
+}
+
+// Appointment statuses. A SCHEDULED appointment can become COMPLETED, CANCELED
+// or NO_SHOW; the other statuses are final.
+const (
+	AppointmentScheduled = "SCHEDULED"
+	AppointmentCompleted = "COMPLETED"
+	AppointmentCanceled  = "CANCELED"
+	AppointmentNoShow    = "NO_SHOW"
+)
+
+// Appointment is a booking of a customer with an artist
+type Appointment struct {
+	ID         string
+	StudioID   string
+	CustomerID string
+	ArtistID   string
+	StartTime  time.Time
+	EndTime    time.Time
+	Status     string
+	Notes      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	// Deposit the customer must pay to hold the slot, in minor units
+	DepositAmount   int64
+	DepositCurrency string
+	DepositStatus   string
+
+	// Project the appointment is a session of, numbered from 1
+	ProjectID     string
+	SessionNumber int
+}
+
+// AppointmentFilter defines search criteria for appointments. From and To
+// select the appointments overlapping that range.
+type AppointmentFilter struct {
+	StudioID   string
+	ArtistID   string
+	CustomerID string
+	ProjectID  string
+	Status     string
+	From       *time.Time
+	To         *time.Time
+	Page       int
+	PageSize   int
+}
+
+// CalendarEvent is an appointment as published in iCalendar feeds
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	Updated     time.Time
+}
+
+// BusyBlock is an event imported from an artist's external calendar. Source
+// names the calendar it came from; re-importing a source replaces its blocks.
+type BusyBlock struct {
+	ID        string
+	ArtistID  string
+	Source    string
+	UID       string
+	Summary   string
+	Start     time.Time
+	End       time.Time
+	CreatedAt time.Time
+}
+
+// Project statuses follow from its sessions: PLANNED until one is completed,
+// IN_PROGRESS while more are scheduled, then COMPLETED, or CANCELED when no
+// session was completed
+const (
+	ProjectPlanned    = "PLANNED"
+	ProjectInProgress = "IN_PROGRESS"
+	ProjectCompleted  = "COMPLETED"
+	ProjectCanceled   = "CANCELED"
+)
+
+// Recurrence frequencies of project sessions
+const (
+	RecurDaily   = "DAILY"
+	RecurWeekly  = "WEEKLY"
+	RecurMonthly = "MONTHLY"
+)
+
+// Recurrence plans Count sessions every Interval days, weeks or months
+type Recurrence struct {
+	Frequency string
+	Interval  int
+	Count     int
+}
+
+// Project groups the sessions of a piece that takes several sittings
+type Project struct {
+	ID          string
+	StudioID    string
+	CustomerID  string
+	ArtistID    string
+	Title       string
+	Description string
+	Recurrence  *Recurrence
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	// Progress, counted over the project's appointments
+	Status            string
+	TotalSessions     int
+	CompletedSessions int
+	ScheduledSessions int
+	CanceledSessions  int
+	NextSession       *time.Time
+}
+
+// ProjectFilter defines search criteria for projects
+type ProjectFilter struct {
+	StudioID   string
+	ArtistID   string
+	CustomerID string
+	Page       int
+	PageSize   int
+}
+
+// TimeRange is a half-open interval [Start, End)
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// WorkingHours is one shift of a weekly schedule. Start and End are "HH:MM"
+// wall-clock times in the studio's time zone; End may be "24:00".
+type WorkingHours struct {
+	Weekday time.Weekday
+	Start   string
+	End     string
+}
+
+// ScheduleOverride replaces an artist's weekly hours on one date. An override
+// without Start and End marks the whole day off.
+type ScheduleOverride struct {
+	ArtistID string
+	Date     string // YYYY-MM-DD in the studio's time zone
+	Start    string
+	End      string
+}
+
+// TimeOff blocks an artist for an absolute period, e.g. holidays
+type TimeOff struct {
+	ID       string
+	ArtistID string
+	Start    time.Time
+	End      time.Time
+	Reason   string
+}
+
+// ArtistSchedule is everything needed to search an artist's free slots over a
+// period. A nil BusinessHours leaves the artist's hours unrestricted.
+type ArtistSchedule struct {
+	ArtistID      string
+	TimeZone      string
+	Buffer        time.Duration // kept free before and after every appointment
+	WorkingHours  []WorkingHours
+	BusinessHours []WorkingHours
+	Overrides     []ScheduleOverride
+	TimeOff       []TimeOff
+	Appointments  []TimeRange
+	Busy          []TimeRange // external calendar events and slots held for the waitlist
+}
+
+// StudioHours are the studio-wide scheduling settings kept in studio_settings
+type StudioHours struct {
+	TimeZone      string
+	BufferMinutes int
+	BusinessHours []WorkingHours
+}
+
+// Booking request statuses. The artist approves, declines or counter-proposes
+// a PENDING request; the client accepts a counter-proposal or withdraws the
+// request while it is open. APPROVED, DECLINED and WITHDRAWN are final.
+const (
+	BookingPending         = "PENDING"
+	BookingCounterProposed = "COUNTER_PROPOSED"
+	BookingApproved        = "APPROVED"
+	BookingDeclined        = "DECLINED"
+	BookingWithdrawn       = "WITHDRAWN"
+)
+
+// BookingRequest is a client's request for a slot with an artist. Approving it
+// books an appointment and records its AppointmentID.
+type BookingRequest struct {
+	ID              string
+	StudioID        string
+	CustomerID      string
+	ArtistID        string // empty when any artist of the studio will do
+	RequestedStart  time.Time
+	RequestedEnd    time.Time
+	Description     string
+	Placement       string
+	Size            string
+	ReferenceImages []string
+	Status          string
+	ProposedStart   *time.Time
+	ProposedEnd     *time.Time
+	ResponseNote    string
+	AppointmentID   string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// BookingRequestFilter defines search criteria for booking requests
+type BookingRequestFilter struct {
+	StudioID   string
+	ArtistID   string
+	CustomerID string
+	Status     string
+	Page       int
+	PageSize   int
+}
+
+// Waitlist entry statuses. An entry WAITING for a slot is OFFERED one at a
+// time; accepting the offer BOOKS it, declining or letting it expire puts the
+// entry back to WAITING.
+const (
+	WaitlistWaiting = "WAITING"
+	WaitlistOffered = "OFFERED"
+	WaitlistBooked  = "BOOKED"
+	WaitlistRemoved = "REMOVED"
+)
+
+// Waitlist offer statuses. A PENDING offer holds its slot until ExpiresAt.
+const (
+	OfferPending   = "PENDING"
+	OfferAccepted  = "ACCEPTED"
+	OfferDeclined  = "DECLINED"
+	OfferExpired   = "EXPIRED"
+	OfferWithdrawn = "WITHDRAWN"
+)
+
+// WaitlistEntry is a customer waiting for a slot of Duration minutes with an
+// artist between WindowStart and WindowEnd
+type WaitlistEntry struct {
+	ID              string
+	StudioID        string
+	CustomerID      string
+	ArtistID        string
+	WindowStart     time.Time
+	WindowEnd       time.Time
+	DurationMinutes int
+	Priority        int
+	Notes           string
+	Status          string
+	AppointmentID   string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// WaitlistFilter defines search criteria for waitlist entries
+type WaitlistFilter struct {
+	StudioID   string
+	ArtistID   string
+	CustomerID string
+	Status     string
+	Page       int
+	PageSize   int
+}
+
+// WaitlistOffer is a freed slot offered to a waitlist entry
+type WaitlistOffer struct {
+	ID            string
+	OpeningID     string
+	EntryID       string
+	CustomerID    string
+	ArtistID      string
+	Start         time.Time
+	End           time.Time
+	Status        string
+	ExpiresAt     time.Time
+	RespondedAt   *time.Time
+	AppointmentID string
+	CreatedAt     time.Time
+}
+
+// Walk-in statuses. WAITING walk-ins are served in order of arrival;
+// assigning one to an artist puts it IN_SERVICE until it is DONE. Walk-ins who
+// are gone when called are a NO_SHOW.
+const (
+	WalkInWaiting   = "WAITING"
+	WalkInInService = "IN_SERVICE"
+	WalkInDone      = "DONE"
+	WalkInNoShow    = "NO_SHOW"
+)
+
+// WalkIn is a customer in a studio's walk-in queue. ArtistID is the requested
+// artist while waiting and the serving artist once in service.
+type WalkIn struct {
+	ID         string
+	StudioID   string
+	CustomerID string
+	Name       string
+	Design     string
+	ArtistID   string
+	Status     string
+	JoinedAt   time.Time
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
+
+// ArtistThroughput is how long an artist takes on average per walk-in, from
+// the walk-ins they finished recently
+type ArtistThroughput struct {
+	ArtistID     string
+	Average      time.Duration
+	Served       int
+	LastFinished time.Time
+}
+
+// Conversation is a message thread between a customer and the studio staff
+// taking part in it
+type Conversation struct {
+	ID             string
+	StudioID       string
+	CustomerID     string
+	Subject        string
+	ParticipantIDs []string
+	// LastMessage is the newest message, if any
+	LastMessage *Message
+	CreatedAt   time.Time
+	UpdatedAt   *time.Time
+}
+
+// ConversationFilter defines search criteria for conversations
+type ConversationFilter struct {
+	// UserID limits the list to the conversations the user takes part in
+	UserID     string
+	CustomerID string
+	StudioID   string
+	Page       int
+	PageSize   int
+}
+
+// Message is written by exactly one of a staff user or the conversation's
+// customer
+type Message struct {
+	ID               string
+	ConversationID   string
+	SenderUserID     string
+	SenderCustomerID string
+	Content          string
+	// Attachments are the files sent with the message. When adding a message
+	// only their IDs are read.
+	Attachments []Attachment
+	CreatedAt   time.Time
+}
+
+// Attachment is an uploaded file kept in a BlobStore under Key. It belongs to
+// at most one of a message or a portfolio item; until then it only belongs to
+// the user who uploaded it.
+type Attachment struct {
+	ID              string
+	Key             string
+	Filename        string
+	ContentType     string
+	Size            int64
+	UploadedBy      string
+	MessageID       string
+	PortfolioItemID string
+	// Variant tells a portfolio item's images apart: original, web or
+	// thumbnail
+	Variant   string
+	CreatedAt time.Time
+}
+
+// PortfolioItem is a piece of an artist's work shown in the studio's gallery.
+// Position orders an artist's items; Images holds a variant of the picture
+// per size.
+type PortfolioItem struct {
+	ID            string
+	StudioID      string
+	ArtistID      string
+	ArtistName    string
+	Title         string
+	Description   string
+	Tags          []string
+	Style         string
+	BodyPlacement string
+	Position      int
+	Images        []Attachment
+	CreatedAt     time.Time
+	UpdatedAt     *time.Time
+}
+
+// PortfolioFilter defines search criteria for portfolio items
+type PortfolioFilter struct {
+	StudioID string
+	ArtistID string
+	Tag      string
+	Style    string
+	Page     int
+	PageSize int
+}
+
+// PublicStudio is what anyone may see of a studio on its public page. It
+// deliberately has no emails: neither the studio's, which is often the
+// owner's, nor the staff's.
+type PublicStudio struct {
+	ID      string
+	Name    string
+	Address string
+	Phone   string
+	Website string
+	LogoURL string
+	Artists []PublicArtist
+}
+
+// PublicArtist is an artist as shown on the studio's public page
+type PublicArtist struct {
+	ID             string
+	Name           string
+	PortfolioItems int
+	// Styles are the styles of the artist's portfolio, most used first
+	Styles []string
+}
+
+// ReadMarker is how far a participant has read a conversation: every message
+// up to and including MessageID, sent at UpTo. UpTo and ReadAt are nil until
+// the participant reads something.
+type ReadMarker struct {
+	ConversationID string
+	UserID         string
+	MessageID      string
+	UpTo           *time.Time
+	ReadAt         *time.Time
+}
+
+// Kinds of conversation events. TYPING events are not stored; clients show
+// them for a few seconds unless they are repeated.
+const (
+	ConversationEventMessage = "MESSAGE"
+	ConversationEventRead    = "READ"
+	ConversationEventTyping  = "TYPING"
+)
+
+// ConversationEvent is pushed live to the participants of a conversation.
+// UserID is who read or is typing.
+type ConversationEvent struct {
+	Kind           string
+	ConversationID string
+	UserID         string
+	Message        *Message
+	Read           *ReadMarker
+}
+
+// Deposit statuses of an appointment
+const (
+	DepositNone      = "NONE"
+	DepositRequired  = "REQUIRED"
+	DepositPaid      = "PAID"
+	DepositRefunded  = "REFUNDED"
+	DepositForfeited = "FORFEITED"
+)
+
+// Payment kinds and statuses. A PENDING payment waits for the provider to
+// capture it; a SUCCEEDED one becomes REFUNDED once fully refunded.
+const (
+	PaymentKindDeposit = "DEPOSIT"
+	PaymentKindPayment = "PAYMENT"
+
+	PaymentPending   = "PENDING"
+	PaymentSucceeded = "SUCCEEDED"
+	PaymentFailed    = "FAILED"
+	PaymentRefunded  = "REFUNDED"
+)
+
+// Ledger entry types. DEPOSIT and PAYMENT credit the customer; CHARGE, REFUND
+// and FORFEIT debit them.
+const (
+	LedgerDeposit = "DEPOSIT"
+	LedgerPayment = "PAYMENT"
+	LedgerCharge  = "CHARGE"
+	LedgerRefund  = "REFUND"
+	LedgerForfeit = "FORFEIT"
+)
+
+// Payment is money collected through a PaymentProvider. Amounts are in minor
+// units of Currency, e.g. cents.
+type Payment struct {
+	ID             string
+	StudioID       string
+	CustomerID     string
+	AppointmentID  string
+	Kind           string
+	Amount         int64
+	RefundedAmount int64
+	Currency       string
+	Status         string
+	Provider       string
+	ProviderRef    string
+	FailureReason  string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// PaymentFilter defines search criteria for payments
+type PaymentFilter struct {
+	StudioID      string
+	CustomerID    string
+	AppointmentID string
+	Page          int
+	PageSize      int
+}
+
+// LedgerEntry is one money movement of a customer. Amount is signed: positive
+// entries are the customer's credit.
+type LedgerEntry struct {
+	ID            string
+	StudioID      string
+	CustomerID    string
+	AppointmentID string
+	PaymentID     string
+	Type          string
+	Amount        int64
+	Currency      string
+	Description   string
+	CreatedBy     string
+	CreatedAt     time.Time
+}
+
+// LedgerFilter defines search criteria for ledger entries
+type LedgerFilter struct {
+	CustomerID    string
+	AppointmentID string
+	Page          int
+	PageSize      int
+}
+
+// Balance is the sum of a customer's ledger in one currency. A negative
+// amount is owed to the studio.
+type Balance struct {
+	Currency string
+	Amount   int64
+}
+
+// PaymentIntent is a provider's pending payment. The client completes it with
+// ClientSecret.
+type PaymentIntent struct {
+	ID           string
+	ClientSecret string
+}
+
+// Reminder recipients and statuses. A PENDING reminder becomes SENT, or
+// SKIPPED when its appointment was cancelled or moved before it was due.
+const (
+	ReminderClient = "CLIENT"
+	ReminderArtist = "ARTIST"
+
+	ReminderPending = "PENDING"
+	ReminderSent    = "SENT"
+	ReminderSkipped = "SKIPPED"
+)
+
+// ReminderSettings configures the reminders of a studio. Clients are reminded
+// each of LeadMinutes before an appointment; artists at ArtistTime, HH:MM in
+// the studio's time zone, on the day of the appointment.
+type ReminderSettings struct {
+	StudioID    string
+	LeadMinutes []int
+	ArtistTime  string
+}
+
+// Reminder is a queued reminder of one appointment
+type Reminder struct {
+	ID            string
+	AppointmentID string
+	StudioID      string
+	CustomerID    string
+	ArtistID      string
+	Recipient     string
+	StartTime     time.Time
+	EndTime       time.Time
+	Timezone      string
+	DueAt         time.Time
+	Status        string
+	SentAt        *time.Time
+}
+
+// Notification is addressed to either a staff user or a customer. Staff read
+// theirs in the app; customer notifications wait for an outbound channel.
+type Notification struct {
+	ID         string
+	UserID     string
+	CustomerID string
+	Kind       string
+	Title      string
+	Body       string
+	Data       map[string]string
+	ReadAt     *time.Time
+	CreatedAt  time.Time
+}
+
 type CustomerRepository interface {
 	// Basic CRUD operations
-	Create(ctx context.Context, customer *Customer) (string, error)
-	GetByID(ctx context.Context, id string) (*Customer, error)
-	List(ctx context.Context, filter CustomerFilter) (PagedResult[Customer], error)
-	Update(ctx context.Context, customer *Customer) error
+	Create(ctx context.Context, tenant string, customer *Customer) (string, error)
+	GetByID(ctx context.Context, tenant, id string) (*Customer, error)
+	List(ctx context.Context, tenant string, filter CustomerFilter) (PagedResult[Customer], error)
+	Update(ctx context.Context, tenant string, customer *Customer, updateMask *fieldmaskpb.FieldMask) error
 
 	// Extended operations
-	Delete(ctx context.Context, id string) error
-	Archive(ctx context.Context, id string) error
+	Delete(ctx context.Context, tenant, id string) error
+	Archive(ctx context.Context, tenant, id string) error
 
 	// History operations
-	AddHistory(ctx context.Context, history *CustomerHistory) error
-	GetHistory(ctx context.Context, customerID string) ([]*CustomerHistory, error)
+	AddHistory(ctx context.Context, tenant string, history *CustomerHistory) error
+	GetHistory(ctx context.Context, tenant, customerID string) ([]*CustomerHistory, error)
 
 	// Note operations
-	AddNote(ctx context.Context, note *CustomerNote) error
-	GetNotes(ctx context.Context, customerID string) ([]*CustomerNote, error)
+	AddNote(ctx context.Context, tenant string, note *CustomerNote) error
+	GetNotes(ctx context.Context, tenant, customerID string) ([]*CustomerNote, error)
 
 	// Search operation
-	Search(ctx context.Context, filter CustomerFilter) (PagedResult[Customer], error)
+	Search(ctx context.Context, tenant string, filter CustomerFilter) (PagedResult[Customer], error)
 
 	// Additional helper functions
-	ExistsByEmail(ctx context.Context, email string) (bool, error)
-	ExistsByPhone(ctx context.Context, phone string) (bool, error)
+	ExistsByEmail(ctx context.Context, tenant, email string) (bool, error)
+	ExistsByPhone(ctx context.Context, tenant, phone string) (bool, error)
 }
 
 type StudioAuthRepository interface {
@@ -209,6 +825,9 @@ type StudioRepository interface {
 	UpdateStaffMember(ctx context.Context, tenant, staffID, role string) error
 	RemoveStaffMember(ctx context.Context, tenant, staffID string) error
 	ListStaffMembers(ctx context.Context, tenant, studioID string) ([]*StaffMember, error)
+	GetStaffMember(ctx context.Context, tenant, staffID string) (*StaffMember, error)
+	// GetStaffRole returns the role of userID on the studio's staff
+	GetStaffRole(ctx context.Context, tenant, studioID, userID string) (string, error)
 
 	// Studio users management
 	AddStudioUser(ctx context.Context, tenant, studioID, email, password, role, displayName, username, firstName, lastName string) (string, error) // userID, error
@@ -223,6 +842,7 @@ type StudioRepository interface {
 type UserRepository interface {
 	GetUserByID(ctx context.Context, tenant, userID string) (*User, error)
 	GetAllUsers(ctx context.Context, tenant string) ([]*User, error)
+	ListUsers(ctx context.Context, tenant string, filter UserFilter) (PagedResult[User], error)
 	UpdateUser(ctx context.Context, tenant string, user *User) error
 	InsertUser(ctx context.Context, tenant string, user *User) error
 	DeleteUser(ctx context.Context, tenant, userID string) error
@@ -233,3 +853,269 @@ type UserRepository interface {
 	GetUserByEmail(ctx context.Context, tenant, email string) (*User, error)
 	GetUserByUsername(ctx context.Context, tenant, username string) (*User, error)
 }
+
+type AppointmentRepository interface {
+	Create(ctx context.Context, tenant string, appointment *Appointment) error
+	GetByID(ctx context.Context, tenant, id string) (*Appointment, error)
+	List(ctx context.Context, tenant string, filter AppointmentFilter) (PagedResult[Appointment], error)
+	Update(ctx context.Context, tenant, id string, appointment *Appointment, updateMask *fieldmaskpb.FieldMask) (*Appointment, error)
+	Reschedule(ctx context.Context, tenant, id string, start, end time.Time, artistID string) (*Appointment, error)
+	SetStatus(ctx context.Context, tenant, id, status string) (*Appointment, error)
+}
+
+type ProjectRepository interface {
+	// Create stores the project and books its sessions in one transaction: the
+	// first at [start, end) and the others following recurrence, when set, in
+	// the studio's time zone
+	Create(ctx context.Context, tenant string, project *Project, start, end time.Time) ([]Appointment, error)
+	GetByID(ctx context.Context, tenant, id string) (*Project, error)
+	List(ctx context.Context, tenant string, filter ProjectFilter) (PagedResult[Project], error)
+	Sessions(ctx context.Context, tenant, id string) ([]Appointment, error)
+	AddSession(ctx context.Context, tenant, id string, start, end time.Time) (*Appointment, error)
+	// Reschedule moves the upcoming scheduled sessions from fromSession on, or
+	// all upcoming ones when it is 0, so that the first starts at start. The
+	// others keep their spacing in calendar days and wall clock time.
+	Reschedule(ctx context.Context, tenant, id string, fromSession int, start time.Time) ([]Appointment, error)
+}
+
+type CalendarRepository interface {
+	// RotateFeedToken issues a new feed token for the artist, revoking the
+	// previous one. Only a hash of the token is stored.
+	RotateFeedToken(ctx context.Context, tenant, artistID string) (string, error)
+	RevokeFeedToken(ctx context.Context, tenant, artistID string) error
+	ArtistForFeedToken(ctx context.Context, tenant, token string) (string, error)
+
+	ArtistEvents(ctx context.Context, tenant, artistID string, from, to time.Time) ([]CalendarEvent, error)
+	AppointmentEvent(ctx context.Context, tenant, appointmentID string) (*CalendarEvent, error)
+	// StudioTimeZone is the zone of the artist's studio, used for floating
+	// times in imported calendars
+	StudioTimeZone(ctx context.Context, tenant, artistID string) (string, error)
+
+	// ReplaceBusyBlocks swaps the artist's blocks from source for blocks and
+	// returns the scheduled appointments that now overlap one
+	ReplaceBusyBlocks(ctx context.Context, tenant, artistID, source string, blocks []BusyBlock) ([]string, error)
+	ListBusyBlocks(ctx context.Context, tenant, artistID string, from, to time.Time) ([]BusyBlock, error)
+}
+
+type AvailabilityRepository interface {
+	SetWorkingHours(ctx context.Context, tenant, artistID string, hours []WorkingHours) error
+	GetWorkingHours(ctx context.Context, tenant, artistID string) ([]WorkingHours, error)
+	SetOverride(ctx context.Context, tenant string, override *ScheduleOverride) error
+	DeleteOverride(ctx context.Context, tenant, artistID, date string) error
+	AddTimeOff(ctx context.Context, tenant string, timeOff *TimeOff) error
+	RemoveTimeOff(ctx context.Context, tenant, artistID, timeOffID string) error
+	SetStudioHours(ctx context.Context, tenant, studioID string, hours *StudioHours) error
+	GetStudioHours(ctx context.Context, tenant, studioID string) (*StudioHours, error)
+
+	// GetSchedule loads the artist's schedule with the overrides, time off,
+	// busy blocks, held waitlist slots and scheduled appointments that touch
+	// [from, to)
+	GetSchedule(ctx context.Context, tenant, artistID string, from, to time.Time) (*ArtistSchedule, error)
+}
+
+type BookingRequestRepository interface {
+	Create(ctx context.Context, tenant string, request *BookingRequest) error
+	GetByID(ctx context.Context, tenant, id string) (*BookingRequest, error)
+	List(ctx context.Context, tenant string, filter BookingRequestFilter) (PagedResult[BookingRequest], error)
+
+	// Approve books the requested slot of a PENDING request and marks it
+	// APPROVED in one transaction. assign runs on the locked request and
+	// returns the artist to book, or "" for the request's own artist; its error
+	// aborts the approval.
+	Approve(ctx context.Context, tenant, id string, assign func(*BookingRequest) (string, error)) (*BookingRequest, *Appointment, error)
+	// AcceptCounterProposal books the proposed slot of a COUNTER_PROPOSED
+	// request and marks it APPROVED in one transaction
+	AcceptCounterProposal(ctx context.Context, tenant, id string) (*BookingRequest, *Appointment, error)
+	// CounterPropose and Decline run authorize on the locked request; its
+	// error aborts the change
+	CounterPropose(ctx context.Context, tenant, id string, start, end time.Time, note string, authorize func(*BookingRequest) error) (*BookingRequest, error)
+	Decline(ctx context.Context, tenant, id, note string, authorize func(*BookingRequest) error) (*BookingRequest, error)
+	Withdraw(ctx context.Context, tenant, id string) (*BookingRequest, error)
+}
+
+type WaitlistRepository interface {
+	AddEntry(ctx context.Context, tenant string, entry *WaitlistEntry) error
+	GetEntry(ctx context.Context, tenant, id string) (*WaitlistEntry, error)
+	ListEntries(ctx context.Context, tenant string, filter WaitlistFilter) (PagedResult[WaitlistEntry], error)
+	// RemoveEntry takes a WAITING or OFFERED entry off the waitlist and
+	// withdraws its pending offer
+	RemoveEntry(ctx context.Context, tenant, id string) (*WaitlistEntry, error)
+
+	GetOffer(ctx context.Context, tenant, id string) (*WaitlistOffer, error)
+	ListOffers(ctx context.Context, tenant, entryID string) ([]WaitlistOffer, error)
+	// AcceptOffer books the slot of a PENDING offer that has not expired
+	AcceptOffer(ctx context.Context, tenant, id string) (*WaitlistOffer, *Appointment, error)
+	DeclineOffer(ctx context.Context, tenant, id string) (*WaitlistOffer, error)
+
+	// Backfill expires lapsed offers and offers up to limit freed slots to
+	// the best matching waiting entries, holding each slot for hold. It
+	// returns the new offers.
+	Backfill(ctx context.Context, tenant string, now time.Time, hold time.Duration, limit int) ([]WaitlistOffer, error)
+}
+
+type WalkInRepository interface {
+	// Add puts a walk-in at the end of the queue of its studio, or of the
+	// tenant's own studio when StudioID is empty
+	Add(ctx context.Context, tenant string, walkIn *WalkIn) error
+	GetByID(ctx context.Context, tenant, id string) (*WalkIn, error)
+	// Queue returns the studio's waiting and in-service walk-ins in order of
+	// arrival
+	Queue(ctx context.Context, tenant, studioID string) ([]WalkIn, error)
+	Throughput(ctx context.Context, tenant, studioID string, since time.Time) ([]ArtistThroughput, error)
+	// Assign puts a WAITING walk-in IN_SERVICE with the artist
+	Assign(ctx context.Context, tenant, id, artistID string) (*WalkIn, error)
+	// Finish marks a walk-in DONE or NO_SHOW
+	Finish(ctx context.Context, tenant, id, status string) (*WalkIn, error)
+	// Watch signals every change to the studio's queue until ctx is done
+	Watch(ctx context.Context, tenant, studioID string) (<-chan struct{}, error)
+}
+
+type ConversationRepository interface {
+	// Create stores a conversation with its participants, in the tenant's own
+	// studio when StudioID is empty
+	Create(ctx context.Context, tenant string, conversation *Conversation) error
+	GetByID(ctx context.Context, tenant, id string) (*Conversation, error)
+	// List pages through conversations, most recently active first
+	List(ctx context.Context, tenant string, filter ConversationFilter) (PagedResult[Conversation], error)
+
+	// AddMessage stores a message and pushes it to the participants
+	AddMessage(ctx context.Context, tenant string, message *Message) error
+	// ListMessages returns up to limit messages of a conversation sent before
+	// before, newest first
+	ListMessages(ctx context.Context, tenant, conversationID string, before time.Time, limit int) ([]Message, error)
+
+	// MarkRead moves userID's read marker forward to messageID, or to the
+	// newest message when messageID is empty, and tells the participants
+	MarkRead(ctx context.Context, tenant, conversationID, userID, messageID string) (*ReadMarker, error)
+	ReadMarkers(ctx context.Context, tenant, conversationID string) ([]ReadMarker, error)
+	// UnreadCounts returns how many messages from others userID has not read,
+	// by conversation. Conversations without unread messages are left out.
+	UnreadCounts(ctx context.Context, tenant, userID string) (map[string]int, error)
+	// NeedsReply pages through the conversations whose newest message is the
+	// customer's, longest waiting first
+	NeedsReply(ctx context.Context, tenant string, filter ConversationFilter) (PagedResult[Conversation], error)
+
+	// Typing tells the other participants that userID is typing
+	Typing(ctx context.Context, tenant, conversationID, userID string) error
+	// Listen delivers the events of userID's conversations published after
+	// the call until ctx is done
+	Listen(ctx context.Context, tenant, userID string) (<-chan ConversationEvent, error)
+}
+
+// BlobStore keeps the bytes of uploaded files under keys chosen by the caller
+type BlobStore interface {
+	// Put stores size bytes read from body under key, replacing what was there
+	Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error
+	// Get opens the blob stored under key; it fails with ErrNotFound when
+	// there is none
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key. Deleting a missing blob succeeds.
+	Delete(ctx context.Context, key string) error
+}
+
+type AttachmentRepository interface {
+	Create(ctx context.Context, tenant string, attachment *Attachment) error
+	GetByID(ctx context.Context, tenant, id string) (*Attachment, error)
+}
+
+type PortfolioRepository interface {
+	// Create stores an item with its images, last in its artist's order. The
+	// item belongs to the tenant's own studio when StudioID is empty.
+	Create(ctx context.Context, tenant string, item *PortfolioItem) error
+	GetByID(ctx context.Context, tenant, id string) (*PortfolioItem, error)
+	// List pages through items, each artist's in their order
+	List(ctx context.Context, tenant string, filter PortfolioFilter) (PagedResult[PortfolioItem], error)
+	// Update writes the fields of item named in fields
+	Update(ctx context.Context, tenant string, item *PortfolioItem, fields []string) error
+	// Delete removes an item and returns the blob keys of its images
+	Delete(ctx context.Context, tenant, id string) ([]string, error)
+	// Reorder puts the artist's items listed in ids first, in that order,
+	// followed by the others in their current order
+	Reorder(ctx context.Context, tenant, artistID string, ids []string) error
+}
+
+// PublicRepository reads the public face of the tenant's studio
+type PublicRepository interface {
+	// GetStudio returns the studio of the tenant's subdomain with its
+	// artists: the owners and artists on its staff
+	GetStudio(ctx context.Context, tenant string) (*PublicStudio, error)
+}
+
+// AttachmentURLSigner issues download URLs for attachments that stop working
+// at the returned time
+type AttachmentURLSigner interface {
+	SignURL(tenant, attachmentID string) (string, time.Time)
+}
+
+// Notifier stores notifications and pushes the ones for staff to their live
+// streams
+type Notifier interface {
+	Notify(ctx context.Context, tenant string, notifications ...Notification) error
+}
+
+type NotificationRepository interface {
+	Notifier
+	List(ctx context.Context, tenant, userID string, unreadOnly bool, page, pageSize int) (PagedResult[Notification], error)
+	MarkRead(ctx context.Context, tenant, userID string, ids []string) error
+	AddDevice(ctx context.Context, tenant, userID, deviceToken string) error
+	RemoveDevice(ctx context.Context, tenant, userID, deviceToken string) error
+
+	// Listen delivers the notifications of userID published after the call
+	// until ctx is done
+	Listen(ctx context.Context, tenant, userID string) (<-chan Notification, error)
+}
+
+type PaymentRepository interface {
+	// CreatePayment stores a PENDING payment. A deposit also marks its
+	// appointment as requiring that deposit.
+	CreatePayment(ctx context.Context, tenant string, payment *Payment) error
+	AttachIntent(ctx context.Context, tenant, id, providerRef string) error
+	FailPayment(ctx context.Context, tenant, id, reason string) error
+	GetPayment(ctx context.Context, tenant, id string) (*Payment, error)
+	ListPayments(ctx context.Context, tenant string, filter PaymentFilter) (PagedResult[Payment], error)
+
+	// CompletePayment marks a PENDING payment SUCCEEDED and credits the ledger;
+	// completing a deposit marks its appointment's deposit PAID
+	CompletePayment(ctx context.Context, tenant, id string) (*Payment, error)
+	// Refund returns amount of a succeeded payment, calling refund with the
+	// payment locked so concurrent refunds cannot exceed what was paid
+	Refund(ctx context.Context, tenant, id string, amount int64, createdBy string, refund func(*Payment) error) (*Payment, error)
+	// ForfeitDeposit keeps the paid deposit of an appointment as a no-show fee
+	ForfeitDeposit(ctx context.Context, tenant, appointmentID, createdBy string) (*LedgerEntry, error)
+
+	RecordCharge(ctx context.Context, tenant string, entry *LedgerEntry) error
+	ListLedger(ctx context.Context, tenant string, filter LedgerFilter) (PagedResult[LedgerEntry], error)
+	Balances(ctx context.Context, tenant, customerID string) ([]Balance, error)
+}
+
+// PaymentProvider moves the money. Amounts are in minor units.
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(ctx context.Context, amount int64, currency string, metadata map[string]string) (*PaymentIntent, error)
+	Capture(ctx context.Context, intentID string) error
+	Refund(ctx context.Context, intentID string, amount int64) error
+}
+
+type ReminderRepository interface {
+	GetSettings(ctx context.Context, tenant, studioID string) (*ReminderSettings, error)
+	SetSettings(ctx context.Context, tenant string, settings *ReminderSettings) error
+	List(ctx context.Context, tenant, appointmentID string) ([]Reminder, error)
+
+	// Enqueue queues the reminders of SCHEDULED appointments that fall due
+	// before until and returns how many were added. Reminders already queued
+	// are left alone, so repeated scans do not duplicate them.
+	Enqueue(ctx context.Context, tenant string, until time.Time) (int, error)
+	// EnqueueNow queues an immediate client reminder of an appointment
+	EnqueueNow(ctx context.Context, tenant, appointmentID string) (*Reminder, error)
+	// Dispatch claims up to limit reminders due by now and calls send for each
+	// one that still applies; a reminder whose send fails goes back to PENDING
+	Dispatch(ctx context.Context, tenant string, now time.Time, limit int, send func(context.Context, *Reminder) error) (int, error)
+	// NextDue returns when the tenant next has reminders to queue or send, or
+	// the zero time when nothing is scheduled
+	NextDue(ctx context.Context, tenant string) (time.Time, error)
+}
+
+// TenantProvisioner creates tenants and registers them with the running server
+type TenantProvisioner interface {
+	Provision(ctx context.Context, tenant *config.TenantConfig) error
+}
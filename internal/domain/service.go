@@ -12,6 +12,12 @@ type contextKey string
 const UserIDKey contextKey = "user_id"
 const RoleKey contextKey = "role"
 
+// TenantKey holds the tenant resolved and validated by the tenant interceptor
+const TenantKey contextKey = "tenant"
+
+// TokenTenantKey holds the Tenant claim of the caller's access token
+const TokenTenantKey contextKey = "token_tenant"
+
 type Claims struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
@@ -2,87 +2,447 @@ package studio
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	"github.com/FACorreiaa/ink-app-backend-grpc/config"
 	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
 )
 
-// StudioAuthRepository handles database operations for studio authentication
+const roleOwner = "OWNER"
+
+// StudioRepository handles database operations for studios and their staff
 type StudioRepository struct {
 	DBManager    *config.TenantDBManager
 	RedisManager *config.TenantRedisManager
 }
 
+// CreateStudio inserts a studio and makes owner its OWNER. An owner with an ID
+// is an existing user; otherwise a new user is created from the owner details.
 func (r *StudioRepository) CreateStudio(ctx context.Context, tenant string, studio *domain.Studio, owner *domain.OwnerInfo) (string, error) {
-	//TODO implement me
-	panic("implement me")
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return "", fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var studioID string
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		now := time.Now()
+		err := tx.QueryRow(ctx,
+			`INSERT INTO studios (name, address, phone, email, website, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $6) RETURNING id`,
+			studio.Name, studio.Address, studio.Phone, studio.Email, studio.Website, now).Scan(&studioID)
+		if err != nil {
+			return fmt.Errorf("failed to insert studio: %w", err)
+		}
+
+		if owner == nil {
+			return nil
+		}
+
+		ownerID := owner.ID
+		if ownerID == "" {
+			ownerID, err = insertUser(ctx, tx, studioID, owner.Email, owner.Password, roleOwner,
+				owner.DisplayName, owner.Username, owner.FirstName, owner.LastName)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = insertStaff(ctx, tx, studioID, ownerID, roleOwner, nil)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return studioID, nil
 }
 
+// UpdateStudio writes the fields named in updateMask
 func (r *StudioRepository) UpdateStudio(ctx context.Context, tenant, studioID string, studio *domain.Studio, updateMask *fieldmaskpb.FieldMask) error {
-	//TODO implement me
-	panic("implement me")
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		current, err := getStudio(ctx, tx, studioID, true)
+		if err != nil {
+			return err
+		}
+
+		_, setClauses, args, err := domain.ApplyStudioFieldMask(current, studio, updateMask)
+		if err != nil {
+			return err
+		}
+
+		args = append(args, studioID)
+		query := "UPDATE studios SET " + strings.Join(setClauses, ", ") + fmt.Sprintf(" WHERE id = $%d", len(args))
+		if _, err = tx.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to update studio: %w", err)
+		}
+		return nil
+	})
 }
 
 func (r *StudioRepository) GetStudio(ctx context.Context, tenant, studioID string) (*domain.Studio, error) {
-	//TODO implement me
-	panic("implement me")
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	studio, err := getStudio(ctx, pool, studioID, false)
+	if err != nil {
+		return nil, err
+	}
+	studio.Tenant = tenant
+	return studio, nil
 }
 
+// ListStudios pages through the studios whose name contains filter, optionally
+// only those where ownerID is an OWNER. pageNumber starts at 1.
 func (r *StudioRepository) ListStudios(ctx context.Context, tenant string, pageSize, pageNumber int32, filter, ownerID string) ([]*domain.Studio, int32, error) {
-	//TODO implement me
-	panic("implement me")
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	where := []string{"TRUE"}
+	var args []interface{}
+	if filter != "" {
+		args = append(args, "%"+filter+"%")
+		where = append(where, fmt.Sprintf("s.name ILIKE $%d", len(args)))
+	}
+	if ownerID != "" {
+		args = append(args, ownerID)
+		where = append(where, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM studio_staff st WHERE st.studio_id = s.id AND st.user_id = $%d AND st.role = '%s')",
+			len(args), roleOwner))
+	}
+	whereClause := strings.Join(where, " AND ")
+
+	var total int32
+	if err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM studios s WHERE "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count studios: %w", err)
+	}
+
+	args = append(args, pageSize, (pageNumber-1)*pageSize)
+	rows, err := pool.Query(ctx,
+		`SELECT `+studioColumns+` FROM studios s WHERE `+whereClause+
+			fmt.Sprintf(" ORDER BY s.created_at, s.id LIMIT $%d OFFSET $%d", len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query studios: %w", err)
+	}
+	defer rows.Close()
+
+	var studios []*domain.Studio
+	for rows.Next() {
+		studio, err := scanStudio(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan studio: %w", err)
+		}
+		studio.Tenant = tenant
+		studios = append(studios, studio)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to query studios: %w", err)
+	}
+
+	return studios, total, nil
 }
 
+// AddStaffMember adds an existing user to a studio with a role and extra
+// permissions on top of the role's
 func (r *StudioRepository) AddStaffMember(ctx context.Context, tenant, studioID, userID, role string, permissions []string) (string, error) {
-	//TODO implement me
-	panic("implement me")
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return "", fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var staffID string
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		staffID, err = insertStaff(ctx, tx, studioID, userID, role, permissions)
+		if err != nil {
+			return err
+		}
+		return syncUserRole(ctx, tx, userID, role)
+	})
+	if err != nil {
+		return "", err
+	}
+	return staffID, nil
 }
 
+// UpdateStaffMember changes a staff member's role. A studio always keeps at
+// least one OWNER.
 func (r *StudioRepository) UpdateStaffMember(ctx context.Context, tenant, staffID, role string) error {
-	//TODO implement me
-	panic("implement me")
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		staff, err := lockStaff(ctx, tx, staffID)
+		if err != nil {
+			return err
+		}
+		if staff.Role == roleOwner && role != roleOwner {
+			if err = ensureAnotherOwner(ctx, tx, staff); err != nil {
+				return err
+			}
+		}
+
+		if _, err = tx.Exec(ctx,
+			`UPDATE studio_staff SET role = $1, updated_at = $2 WHERE id = $3`,
+			role, time.Now(), staffID); err != nil {
+			return fmt.Errorf("failed to update staff member: %w", err)
+		}
+		return syncUserRole(ctx, tx, staff.UserID, role)
+	})
 }
 
+// RemoveStaffMember removes a user from a studio without deleting the user
 func (r *StudioRepository) RemoveStaffMember(ctx context.Context, tenant, staffID string) error {
-	//TODO implement me
-	panic("implement me")
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		staff, err := lockStaff(ctx, tx, staffID)
+		if err != nil {
+			return err
+		}
+		if staff.Role == roleOwner {
+			if err = ensureAnotherOwner(ctx, tx, staff); err != nil {
+				return err
+			}
+		}
+
+		if _, err = tx.Exec(ctx, `DELETE FROM studio_staff WHERE id = $1`, staffID); err != nil {
+			return fmt.Errorf("failed to remove staff member: %w", err)
+		}
+		return nil
+	})
 }
 
+// ListStaffMembers returns a studio's staff with their effective permissions
 func (r *StudioRepository) ListStaffMembers(ctx context.Context, tenant, studioID string) ([]*domain.StaffMember, error) {
-	//TODO implement me
-	panic("implement me")
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	rows, err := pool.Query(ctx,
+		`SELECT `+staffColumns+` FROM studio_staff s WHERE s.studio_id = $1 ORDER BY s.created_at, s.id`,
+		studioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query staff members: %w", err)
+	}
+	defer rows.Close()
+
+	var staff []*domain.StaffMember
+	for rows.Next() {
+		member, err := scanStaff(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan staff member: %w", err)
+		}
+		staff = append(staff, member)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query staff members: %w", err)
+	}
+
+	return staff, nil
 }
 
+// AddStudioUser creates a user and adds it to the studio's staff
 func (r *StudioRepository) AddStudioUser(ctx context.Context, tenant, studioID, email, password, role, displayName, username, firstName, lastName string) (string, error) {
-	//TODO implement me
-	panic("implement me")
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return "", fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var userID string
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		userID, err = insertUser(ctx, tx, studioID, email, password, role, displayName, username, firstName, lastName)
+		if err != nil {
+			return err
+		}
+		_, err = insertStaff(ctx, tx, studioID, userID, role, nil)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return userID, nil
 }
 
+// UpdateStudioUser writes the user fields named in updateMask. A role change is
+// applied to the user's staff record in studioID too.
 func (r *StudioRepository) UpdateStudioUser(ctx context.Context, tenant, userID, studioID, email, role, displayName, username, firstName, lastName string, updateMask *fieldmaskpb.FieldMask) error {
-	//TODO implement me
-	panic("implement me")
+	if updateMask == nil || len(updateMask.Paths) == 0 {
+		return fmt.Errorf("%w: update_mask is required", domain.ErrInvalidArgument)
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	values := map[string]string{
+		"email":        email,
+		"role":         role,
+		"display_name": displayName,
+		"username":     username,
+		"first_name":   firstName,
+		"last_name":    lastName,
+	}
+	var setClauses []string
+	var args []interface{}
+	for _, path := range updateMask.Paths {
+		value, ok := values[path]
+		if !ok {
+			return fmt.Errorf("%w: unknown field in update_mask: %s", domain.ErrInvalidArgument, path)
+		}
+		args = append(args, value)
+		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", path, len(args)))
+	}
+	args = append(args, time.Now(), userID, studioID)
+	setClauses = append(setClauses, fmt.Sprintf("updated_at = $%d", len(args)-2))
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			"UPDATE users SET "+strings.Join(setClauses, ", ")+
+				fmt.Sprintf(" WHERE id = $%d AND studio_id = $%d", len(args)-1, len(args)),
+			args...)
+		if err != nil {
+			return domain.WrapError("failed to update user", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("user %s: %w", userID, domain.ErrNotFound)
+		}
+
+		if slices.Contains(updateMask.Paths, "role") {
+			if _, err = tx.Exec(ctx,
+				`UPDATE studio_staff SET role = $1, updated_at = now() WHERE user_id = $2 AND studio_id = $3`,
+				role, userID, studioID); err != nil {
+				return fmt.Errorf("failed to update staff role: %w", err)
+			}
+		}
+		return nil
+	})
 }
 
+// RemoveStudioUser deletes a user of the studio together with its staff records
 func (r *StudioRepository) RemoveStudioUser(ctx context.Context, tenant, userID, studioID string) error {
-	//TODO implement me
-	panic("implement me")
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		var staff domain.StaffMember
+		err := tx.QueryRow(ctx,
+			`SELECT id, studio_id, user_id, role FROM studio_staff WHERE user_id = $1 AND studio_id = $2 FOR UPDATE`,
+			userID, studioID).Scan(&staff.ID, &staff.StudioID, &staff.UserID, &staff.Role)
+		if err == nil && staff.Role == roleOwner {
+			if err = ensureAnotherOwner(ctx, tx, &staff); err != nil {
+				return err
+			}
+		} else if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to load staff member: %w", err)
+		}
+
+		tag, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1 AND studio_id = $2`, userID, studioID)
+		if err != nil {
+			return domain.WrapError("failed to delete user", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("user %s: %w", userID, domain.ErrNotFound)
+		}
+		return nil
+	})
 }
 
+// SetStaffPermissions replaces the permissions granted to a staff member on top
+// of their role
 func (r *StudioRepository) SetStaffPermissions(ctx context.Context, tenant, staffID string, permissions []string) error {
-	//TODO implement me
-	panic("implement me")
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := lockStaff(ctx, tx, staffID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM staff_permissions WHERE staff_id = $1`, staffID); err != nil {
+			return fmt.Errorf("failed to clear staff permissions: %w", err)
+		}
+		if err := insertPermissions(ctx, tx, staffID, permissions); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `UPDATE studio_staff SET updated_at = now() WHERE id = $1`, staffID)
+		return err
+	})
 }
 
+// GetStaffPermissions returns the staff member's role permissions together with
+// the ones granted to them directly
 func (r *StudioRepository) GetStaffPermissions(ctx context.Context, tenant, staffID string) ([]string, error) {
-	//TODO implement me
-	panic("implement me")
+	staff, err := r.GetStaffMember(ctx, tenant, staffID)
+	if err != nil {
+		return nil, err
+	}
+	return staff.Permissions, nil
+}
+
+// GetStaffMember returns a staff member with their effective permissions
+func (r *StudioRepository) GetStaffMember(ctx context.Context, tenant, staffID string) (*domain.StaffMember, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	staff, err := scanStaff(pool.QueryRow(ctx, `SELECT `+staffColumns+` FROM studio_staff s WHERE s.id = $1`, staffID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("staff member %s: %w", staffID, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load staff member: %w", err)
+	}
+	return staff, nil
 }
 
-// NewStudioAuthRepository creates a new StudioAuthRepository
+// GetStaffRole returns the role of a user on a studio's staff
+func (r *StudioRepository) GetStaffRole(ctx context.Context, tenant, studioID, userID string) (string, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return "", fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var role string
+	err = pool.QueryRow(ctx,
+		`SELECT role FROM studio_staff WHERE studio_id = $1 AND user_id = $2`, studioID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("staff member of studio %s: %w", studioID, domain.ErrNotFound)
+	}
+	if err != nil {
+		return "", domain.WrapError("failed to load staff role", err)
+	}
+	return role, nil
+}
+
+// NewStudioRepository creates a new StudioRepository
 func NewStudioRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *StudioRepository {
 	return &StudioRepository{
 		DBManager:    dbManager,
@@ -90,168 +450,139 @@ func NewStudioRepository(dbManager *config.TenantDBManager, redisManager *config
 	}
 }
 
-// Claims defines JWT claims
-//type Claims struct {
-//	UserID   string `json:"user_id"`
-//	Username string `json:"username"`
-//	Email    string `json:"email"`
-//	Tenant   string `json:"tenant"`
-//	Role     string `json:"role"`
-//	jwt.RegisteredClaims
-//}
-
-// generateAccessToken creates a JWT access token
-func generateAccessToken(userID, username, email, tenant, role string) (string, error) {
-	claims := domain.Claims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
-		Tenant:   tenant,
-		Role:     role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)), // Short-lived
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(domain.JwtSecretKey) // Assume JwtSecretKey is a global secret
+const studioColumns = `s.id, s.name, COALESCE(s.address, ''), COALESCE(s.phone, ''), COALESCE(s.email, ''),
+	COALESCE(s.website, ''), s.created_at, COALESCE(s.updated_at, s.created_at)`
+
+// staffColumns includes the effective permissions: the role's plus the staff
+// member's own grants
+const staffColumns = `s.id, s.studio_id, s.user_id, s.role, s.created_at, COALESCE(s.updated_at, s.created_at),
+	ARRAY(SELECT permission FROM role_permissions WHERE role = s.role
+	      UNION SELECT permission FROM staff_permissions WHERE staff_id = s.id ORDER BY 1)`
+
+func scanStudio(row pgx.Row) (*domain.Studio, error) {
+	var studio domain.Studio
+	err := row.Scan(&studio.ID, &studio.Name, &studio.Address, &studio.Phone, &studio.Email,
+		&studio.Website, &studio.CreatedAt, &studio.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &studio, nil
 }
 
-// GetUserByID retrieves a user by email
-//func (r *StudioAuthRepository) GetUserByID(ctx context.Context, tenant, email string) (string, string, string, error) {
-//	// Get tenant-specific database pool
-//	pool, err := r.DBManager.GetTenantDB(tenant)
-//	if err != nil {
-//		return "", "", "", fmt.Errorf("invalid tenant: %w", err)
-//	}
-//
-//	var id, username, role string
-//	err = pool.QueryRow(ctx,
-//		"SELECT id, username, role FROM users WHERE email = $1",
-//		email).Scan(&id, &username, &role)
-//	if err != nil {
-//		return "", "", "", fmt.Errorf("user not found: %w", err)
-//	}
-//
-//	return id, username, role, nil
-//}
-
-// Register creates a new user in the tenant's database
-
-// ChangePassword updates a user's password
-//func (r *StudioAuthRepository) ChangePassword(ctx context.Context, tenant, email, oldPassword, newPassword string) error {
-//	if tenant == "" {
-//		return errors.New("tenant subdomain is required")
-//	}
-//
-//	pool, err := r.DBManager.GetTenantDB(tenant)
-//	if err != nil {
-//		return fmt.Errorf("invalid tenant: %w", err)
-//	}
-//
-//	var userID, hashedPassword string
-//	err = pool.QueryRow(ctx,
-//		"SELECT id, hashed_password FROM users WHERE email = $1",
-//		email).Scan(&userID, &hashedPassword)
-//	if err != nil {
-//		return fmt.Errorf("user not found: %w", err)
-//	}
-//
-//	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(oldPassword))
-//	if err != nil {
-//		return errors.New("invalid old password")
-//	}
-//
-//	newHashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
-//	if err != nil {
-//		return fmt.Errorf("failed to hash new password: %w", err)
-//	}
-//
-//	_, err = pool.Exec(ctx,
-//		"UPDATE users SET hashed_password = $1, updated_at = $2 WHERE id = $3",
-//		string(newHashedPassword), time.Now(), userID)
-//	if err != nil {
-//		return fmt.Errorf("failed to update password: %w", err)
-//	}
-//
-//	// Invalidate all refresh tokens
-//	_, err = pool.Exec(ctx,
-//		"UPDATE refresh_tokens SET invalidated_at = $1 WHERE user_id = $2 AND invalidated_at IS NULL",
-//		time.Now(), userID)
-//	if err != nil {
-//		fmt.Printf("Warning: failed to invalidate refresh tokens: %v\n", err)
-//	}
-//
-//	return nil
-//}
-
-// ChangeEmail updates a user's email
-//func (r *StudioAuthRepository) ChangeEmail(ctx context.Context, tenant, email, password, newEmail string) error {
-//	if tenant == "" {
-//		return errors.New("tenant subdomain is required")
-//	}
-//
-//	pool, err := r.DBManager.GetTenantDB(tenant)
-//	if err != nil {
-//		return fmt.Errorf("invalid tenant: %w", err)
-//	}
-//
-//	var userID, hashedPassword string
-//	err = pool.QueryRow(ctx,
-//		"SELECT id, hashed_password FROM users WHERE email = $1",
-//		email).Scan(&userID, &hashedPassword)
-//	if err != nil {
-//		return fmt.Errorf("user not found: %w", err)
-//	}
-//
-//	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-//	if err != nil {
-//		return errors.New("invalid credentials")
-//	}
-//
-//	_, err = pool.Exec(ctx,
-//		"UPDATE users SET email = $1, updated_at = $2 WHERE id = $3",
-//		newEmail, time.Now(), userID)
-//	if err != nil {
-//		return fmt.Errorf("failed to update email: %w", err)
-//	}
-//
-//	return nil
-//}
-
-//func (r *StudioAuthRepository) GetUserByID(ctx context.Context, tenant, userID string) (*domain.User, error) {
-//	pool, err := r.DBManager.GetTenantDB(tenant)
-//	if err != nil {
-//		return nil, fmt.Errorf("invalid tenant: %w", err)
-//	}
-//
-//	var user domain.User
-//	err = pool.QueryRow(ctx,
-//		"SELECT id, username, email, role FROM users WHERE id = $1",
-//		userID).Scan(&user.ID, &user.Username, &user.Email, &user.Role)
-//	if err != nil {
-//		return nil, fmt.Errorf("user not found: %w", err)
-//	}
-//
-//	user.StudioID = tenant
-//	user.CreatedAt = time.Now()
-//	user.UpdatedAt = time.Now()
-//
-//	return &user, nil
-//}
-//
-//func (r *StudioAuthRepository) GetAllUsers(ctx context.Context, tenant string) ([]*domain.User, error) {
-//	return nil, nil
-//}
-//
-//func (r *StudioAuthRepository) UpdateUser(ctx context.Context, tenant string, user *domain.User) error {
-//	return nil
-//}
-//
-//func (r *StudioAuthRepository) InsertUser(ctx context.Context, tenant string, user *domain.User) error {
-//	return nil
-//}
-//
-//func (r *StudioAuthRepository) DeleteUser(ctx context.Context, tenant, userID string) error {
-//	return nil
-//}
+func scanStaff(row pgx.Row) (*domain.StaffMember, error) {
+	var staff domain.StaffMember
+	err := row.Scan(&staff.ID, &staff.StudioID, &staff.UserID, &staff.Role,
+		&staff.CreatedAt, &staff.UpdatedAt, &staff.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	return &staff, nil
+}
+
+// getStudio loads a studio, locking the row when forUpdate is set
+func getStudio(ctx context.Context, q interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}, studioID string, forUpdate bool) (*domain.Studio, error) {
+	query := `SELECT ` + studioColumns + ` FROM studios s WHERE s.id = $1`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	studio, err := scanStudio(q.QueryRow(ctx, query, studioID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("studio %s: %w", studioID, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get studio: %w", err)
+	}
+	return studio, nil
+}
+
+// lockStaff loads a staff member and locks its row
+func lockStaff(ctx context.Context, tx pgx.Tx, staffID string) (*domain.StaffMember, error) {
+	var staff domain.StaffMember
+	err := tx.QueryRow(ctx,
+		`SELECT id, studio_id, user_id, role FROM studio_staff WHERE id = $1 FOR UPDATE`,
+		staffID).Scan(&staff.ID, &staff.StudioID, &staff.UserID, &staff.Role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("staff member %s: %w", staffID, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load staff member: %w", err)
+	}
+	return &staff, nil
+}
+
+// ensureAnotherOwner fails unless the studio has an OWNER besides staff. The
+// other owners are locked so two concurrent demotions cannot both pass.
+func ensureAnotherOwner(ctx context.Context, tx pgx.Tx, staff *domain.StaffMember) error {
+	rows, err := tx.Query(ctx,
+		`SELECT id FROM studio_staff WHERE studio_id = $1 AND role = $2 AND id <> $3 FOR UPDATE`,
+		staff.StudioID, roleOwner, staff.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check studio owners: %w", err)
+	}
+	owners, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to check studio owners: %w", err)
+	}
+	if len(owners) == 0 {
+		return fmt.Errorf("%w: a studio must keep at least one owner", domain.ErrFailedPrecondition)
+	}
+	return nil
+}
+
+func insertUser(ctx context.Context, tx pgx.Tx, studioID, email, password, role, displayName, username, firstName, lastName string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var userID string
+	err = tx.QueryRow(ctx,
+		`INSERT INTO users (studio_id, email, hashed_password, role, display_name, username, first_name, last_name, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9) RETURNING id`,
+		studioID, email, string(hashedPassword), role, displayName, username, firstName, lastName, time.Now()).Scan(&userID)
+	if err != nil {
+		return "", domain.WrapError("failed to insert user", err)
+	}
+	return userID, nil
+}
+
+func insertStaff(ctx context.Context, tx pgx.Tx, studioID, userID, role string, permissions []string) (string, error) {
+	var staffID string
+	err := tx.QueryRow(ctx,
+		`INSERT INTO studio_staff (studio_id, user_id, role) VALUES ($1, $2, $3) RETURNING id`,
+		studioID, userID, role).Scan(&staffID)
+	if err != nil {
+		return "", domain.WrapError("failed to add staff member", err)
+	}
+	if err = insertPermissions(ctx, tx, staffID, permissions); err != nil {
+		return "", err
+	}
+	return staffID, nil
+}
+
+func insertPermissions(ctx context.Context, tx pgx.Tx, staffID string, permissions []string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx,
+		`INSERT INTO staff_permissions (staff_id, permission)
+		 SELECT $1, p FROM unnest($2::text[]) AS p ON CONFLICT DO NOTHING`,
+		staffID, permissions)
+	if err != nil {
+		return fmt.Errorf("failed to grant permissions: %w", err)
+	}
+	return nil
+}
+
+// syncUserRole keeps users.role, which access tokens carry, in line with the
+// user's staff role
+func syncUserRole(ctx context.Context, tx pgx.Tx, userID, role string) error {
+	_, err := tx.Exec(ctx, `UPDATE users SET role = $1, updated_at = now() WHERE id = $2`, role, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+	return nil
+}
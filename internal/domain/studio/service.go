@@ -2,345 +2,397 @@ package studio
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	ups "github.com/FACorreiaa/ink-app-backend-protos/modules/studio/generated"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
 )
 
-// StudioAuthService implements the gRPC server
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// managerRoles may change studios and their staff
+var managerRoles = []string{"OWNER"}
+
+// StudioService implements the gRPC studio server
 type StudioService struct {
 	ups.UnimplementedStudioServiceServer
 	repo domain.StudioRepository
 }
 
-func (s *StudioService) CreateStudio(ctx context.Context, request *ups.CreateStudioRequest) (*ups.CreateStudioResponse, error) {
-	//TODO implement me
-	panic("implement me")
+// NewStudioService creates a new StudioService
+func NewStudioService(repo domain.StudioRepository) *StudioService {
+	return &StudioService{repo: repo}
 }
 
-func (s *StudioService) UpdateStudio(ctx context.Context, request *ups.UpdateStudioRequest) (*ups.UpdateStudioResponse, error) {
-	//TODO implement me
-	panic("implement me")
+// CreateStudio creates a studio in the caller's tenant. Without owner details the
+// caller becomes the studio's owner.
+func (s *StudioService) CreateStudio(ctx context.Context, req *ups.CreateStudioRequest) (*ups.CreateStudioResponse, error) {
+	traceContext, span, tenant, err := domain.StartCall(ctx, "CreateStudio")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(traceContext)
+
+	if err = domain.RequireRole(traceContext, managerRoles...); err != nil {
+		return nil, err
+	}
+	if req.Studio == nil || strings.TrimSpace(req.Studio.Name) == "" {
+		return nil, status.Error(codes.InvalidArgument, "studio name is required")
+	}
+
+	owner := &domain.OwnerInfo{}
+	if o := req.Studio.Owner; o != nil && o.Email != "" {
+		if o.Password == "" {
+			return nil, status.Error(codes.InvalidArgument, "owner password is required")
+		}
+		owner = &domain.OwnerInfo{
+			Email:       o.Email,
+			Password:    o.Password,
+			DisplayName: o.DisplayName,
+			Username:    o.Username,
+			FirstName:   o.FirstName,
+			LastName:    o.LastName,
+		}
+	} else if owner.ID, err = domain.ExtractUserIDFromContext(traceContext); err != nil {
+		return nil, err
+	}
+
+	studioID, err := s.repo.CreateStudio(traceContext, tenant, studioFromProto(req.Studio), owner)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to create studio")
+	}
+
+	span.SetAttributes(attribute.String("studio.id", studioID))
+	return &ups.CreateStudioResponse{
+		StudioId: studioID,
+		Message:  "Studio created successfully",
+		Response: base,
+	}, nil
 }
 
-func (s *StudioService) ListStudios(ctx context.Context, request *ups.ListStudiosRequest) (*ups.ListStudiosResponse, error) {
-	//TODO implement me
-	panic("implement me")
+// UpdateStudio applies the fields named in update_mask
+func (s *StudioService) UpdateStudio(ctx context.Context, req *ups.UpdateStudioRequest) (*ups.UpdateStudioResponse, error) {
+	traceContext, span, tenant, err := domain.StartCall(ctx, "UpdateStudio")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(traceContext)
+
+	if req.StudioId == "" {
+		return nil, status.Error(codes.InvalidArgument, "studio_id is required")
+	}
+	if err = s.requireStudioManager(traceContext, tenant, req.StudioId); err != nil {
+		return nil, err
+	}
+
+	if err = s.repo.UpdateStudio(traceContext, tenant, req.StudioId, studioFromProto(req.Studio), req.UpdateMask); err != nil {
+		return nil, domain.ToStatus(err, "failed to update studio")
+	}
+
+	studio, err := s.repo.GetStudio(traceContext, tenant, req.StudioId)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to load studio")
+	}
+
+	return &ups.UpdateStudioResponse{
+		Success:  true,
+		Message:  "Studio updated successfully",
+		Studio:   studioToProto(studio),
+		Response: base,
+	}, nil
 }
 
-func (s *StudioService) AddStaffMember(ctx context.Context, request *ups.AddStaffMemberRequest) (*ups.AddStaffMemberResponse, error) {
-	//TODO implement me
-	panic("implement me")
+// ListStudios pages through the tenant's studios. page_number starts at 1.
+func (s *StudioService) ListStudios(ctx context.Context, req *ups.ListStudiosRequest) (*ups.ListStudiosResponse, error) {
+	traceContext, span, tenant, err := domain.StartCall(ctx, "ListStudios")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(traceContext)
+
+	pageSize, pageNumber := req.PageSize, req.PageNumber
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	if pageNumber <= 0 {
+		pageNumber = 1
+	}
+
+	studios, total, err := s.repo.ListStudios(traceContext, tenant, pageSize, pageNumber, req.Filter, req.OwnerId)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list studios")
+	}
+
+	res := &ups.ListStudiosResponse{
+		TotalCount: total,
+		PageNumber: pageNumber,
+		TotalPages: (total + pageSize - 1) / pageSize,
+		Response:   base,
+	}
+	for _, studio := range studios {
+		res.Studios = append(res.Studios, studioToProto(studio))
+	}
+	return res, nil
 }
 
-func (s *StudioService) UpdateStaffMember(ctx context.Context, request *ups.UpdateStaffMemberRequest) (*ups.UpdateStaffMemberResponse, error) {
-	//TODO implement me
-	panic("implement me")
+// AddStaffMember adds an existing user to a studio
+func (s *StudioService) AddStaffMember(ctx context.Context, req *ups.AddStaffMemberRequest) (*ups.AddStaffMemberResponse, error) {
+	traceContext, span, tenant, err := domain.StartCall(ctx, "AddStaffMember")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(traceContext)
+
+	if req.StudioId == "" || req.UserId == "" || req.Role == "" {
+		return nil, status.Error(codes.InvalidArgument, "studio_id, user_id and role are required")
+	}
+	if err = s.requireStudioManager(traceContext, tenant, req.StudioId); err != nil {
+		return nil, err
+	}
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	staffID, err := s.repo.AddStaffMember(traceContext, tenant, req.StudioId, req.UserId, normalizeRole(req.Role), permissions)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to add staff member")
+	}
+
+	return &ups.AddStaffMemberResponse{
+		StaffId:  staffID,
+		Message:  "Staff member added successfully",
+		Response: base,
+	}, nil
 }
 
-func (s *StudioService) RemoveStaffMember(ctx context.Context, request *ups.RemoveStaffMemberRequest) (*ups.RemoveStaffMemberResponse, error) {
-	//TODO implement me
-	panic("implement me")
+// UpdateStaffMember changes a staff member's role
+func (s *StudioService) UpdateStaffMember(ctx context.Context, req *ups.UpdateStaffMemberRequest) (*ups.UpdateStaffMemberResponse, error) {
+	traceContext, span, tenant, err := domain.StartCall(ctx, "UpdateStaffMember")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(traceContext)
+
+	if req.StaffId == "" || req.Role == "" {
+		return nil, status.Error(codes.InvalidArgument, "staff_id and role are required")
+	}
+	if err = s.requireStaffManager(traceContext, tenant, req.StaffId); err != nil {
+		return nil, err
+	}
+
+	if err = s.repo.UpdateStaffMember(traceContext, tenant, req.StaffId, normalizeRole(req.Role)); err != nil {
+		return nil, domain.ToStatus(err, "failed to update staff member")
+	}
+
+	return &ups.UpdateStaffMemberResponse{Message: "Staff member updated successfully", Response: base}, nil
 }
 
-func (s *StudioService) ListStaffMembers(ctx context.Context, request *ups.ListStaffMembersRequest) (*ups.ListStaffMembersResponse, error) {
-	//TODO implement me
-	panic("implement me")
+// RemoveStaffMember removes a staff member from their studio. The user is kept.
+func (s *StudioService) RemoveStaffMember(ctx context.Context, req *ups.RemoveStaffMemberRequest) (*ups.RemoveStaffMemberResponse, error) {
+	traceContext, span, tenant, err := domain.StartCall(ctx, "RemoveStaffMember")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(traceContext)
+
+	if req.StaffId == "" {
+		return nil, status.Error(codes.InvalidArgument, "staff_id is required")
+	}
+	if err = s.requireStaffManager(traceContext, tenant, req.StaffId); err != nil {
+		return nil, err
+	}
+
+	if err = s.repo.RemoveStaffMember(traceContext, tenant, req.StaffId); err != nil {
+		return nil, domain.ToStatus(err, "failed to remove staff member")
+	}
+
+	return &ups.RemoveStaffMemberResponse{Message: "Staff member removed successfully", Response: base}, nil
 }
 
-func (s *StudioService) SetStaffPermissions(ctx context.Context, request *ups.SetStaffPermissionsRequest) (*ups.SetStaffPermissionsResponse, error) {
-	//TODO implement me
-	panic("implement me")
+// ListStaffMembers lists a studio's staff with their effective permissions
+func (s *StudioService) ListStaffMembers(ctx context.Context, req *ups.ListStaffMembersRequest) (*ups.ListStaffMembersResponse, error) {
+	traceContext, span, tenant, err := domain.StartCall(ctx, "ListStaffMembers")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(traceContext)
+
+	if req.StudioId == "" {
+		return nil, status.Error(codes.InvalidArgument, "studio_id is required")
+	}
+
+	staff, err := s.repo.ListStaffMembers(traceContext, tenant, req.StudioId)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list staff members")
+	}
+
+	res := &ups.ListStaffMembersResponse{Response: base}
+	for _, member := range staff {
+		res.StaffMembers = append(res.StaffMembers, &ups.StaffMember{
+			StaffId:     member.ID,
+			StudioId:    member.StudioID,
+			UserId:      member.UserID,
+			Role:        member.Role,
+			Permissions: member.Permissions,
+			CreatedAt:   timestamppb.New(member.CreatedAt),
+			UpdatedAt:   timestamppb.New(member.UpdatedAt),
+		})
+	}
+	return res, nil
 }
 
-func (s *StudioService) GetStaffPermissions(ctx context.Context, request *ups.GetStaffPermissionsRequest) (*ups.GetStaffPermissionsResponse, error) {
-	//TODO implement me
-	panic("implement me")
+// SetStaffPermissions replaces the permissions granted to a staff member on top
+// of their role
+func (s *StudioService) SetStaffPermissions(ctx context.Context, req *ups.SetStaffPermissionsRequest) (*ups.SetStaffPermissionsResponse, error) {
+	traceContext, span, tenant, err := domain.StartCall(ctx, "SetStaffPermissions")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(traceContext)
+
+	if req.StaffId == "" {
+		return nil, status.Error(codes.InvalidArgument, "staff_id is required")
+	}
+	if err = s.requireStaffManager(traceContext, tenant, req.StaffId); err != nil {
+		return nil, err
+	}
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.repo.SetStaffPermissions(traceContext, tenant, req.StaffId, permissions); err != nil {
+		return nil, domain.ToStatus(err, "failed to set staff permissions")
+	}
+
+	return &ups.SetStaffPermissionsResponse{Message: "Staff permissions updated successfully", Response: base}, nil
 }
 
-func (s *StudioService) mustEmbedUnimplementedStudioServiceServer() {
-	//TODO implement me
-	panic("implement me")
+// GetStaffPermissions returns the role's permissions and the staff member's own
+func (s *StudioService) GetStaffPermissions(ctx context.Context, req *ups.GetStaffPermissionsRequest) (*ups.GetStaffPermissionsResponse, error) {
+	traceContext, span, tenant, err := domain.StartCall(ctx, "GetStaffPermissions")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+	base := baseResponse(traceContext)
+
+	if req.StaffId == "" {
+		return nil, status.Error(codes.InvalidArgument, "staff_id is required")
+	}
+
+	permissions, err := s.repo.GetStaffPermissions(traceContext, tenant, req.StaffId)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get staff permissions")
+	}
+
+	return &ups.GetStaffPermissionsResponse{Permissions: permissions, Response: base}, nil
 }
 
-// NewStudioAuthService creates a new StudioAuthService
-func NewStudioService(repo domain.StudioRepository) *StudioService {
-	return &StudioService{repo: repo}
+func studioFromProto(studio *ups.Studio) *domain.Studio {
+	if studio == nil {
+		return nil
+	}
+	return &domain.Studio{
+		ID:      studio.StudioId,
+		Name:    studio.Name,
+		Address: studio.Address,
+		Phone:   studio.Phone,
+		Email:   studio.Email,
+		Website: studio.Website,
+	}
 }
 
-// extractTenantFromContext extracts tenant from gRPC metadata
-func extractTenantFromContext(ctx context.Context) (string, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return "", status.Error(codes.Unauthenticated, "no metadata provided")
+func studioToProto(studio *domain.Studio) *ups.Studio {
+	return &ups.Studio{
+		StudioId:  studio.ID,
+		Name:      studio.Name,
+		Address:   studio.Address,
+		Phone:     studio.Phone,
+		Email:     studio.Email,
+		Website:   studio.Website,
+		CreatedAt: timestamppb.New(studio.CreatedAt),
+		UpdatedAt: timestamppb.New(studio.UpdatedAt),
 	}
+}
 
-	tenantValues := md.Get("X-Tenant")
-	if len(tenantValues) > 0 && tenantValues[0] != "" {
-		return tenantValues[0], nil
+// normalizeRole stores roles upper-case, matching users.role and role_permissions
+func normalizeRole(role string) string {
+	return strings.ToUpper(strings.TrimSpace(role))
+}
+
+func normalizePermissions(permissions []string) ([]string, error) {
+	normalized := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		permission = strings.TrimSpace(permission)
+		if permission == "" {
+			return nil, status.Error(codes.InvalidArgument, "permissions cannot be empty")
+		}
+		normalized = append(normalized, permission)
+	}
+	return normalized, nil
+}
+
+// requireStudioManager fails with PermissionDenied unless the caller holds one
+// of managerRoles on the studio's own staff. The role claim of the token only
+// says the caller manages some studio of the tenant.
+func (s *StudioService) requireStudioManager(ctx context.Context, tenant, studioID string) error {
+	userID, err := domain.ExtractUserIDFromContext(ctx)
+	if err != nil {
+		return err
 	}
 
-	hostValues := md.Get(":authority")
-	if len(hostValues) > 0 {
-		host := hostValues[0]
-		parts := strings.Split(host, ".")
-		if len(parts) > 0 && parts[0] != "" {
-			return parts[0], nil
+	role, err := s.repo.GetStaffRole(ctx, tenant, studioID, userID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return status.Error(codes.PermissionDenied, "caller is not on the studio's staff")
+	}
+	if err != nil {
+		return domain.ToStatus(err, "failed to check studio role")
+	}
+	for _, allowed := range managerRoles {
+		if strings.EqualFold(role, allowed) {
+			return nil
 		}
 	}
+	return status.Error(codes.PermissionDenied, "action requires one of the studio roles "+strings.Join(managerRoles, ", "))
+}
 
-	return "", status.Error(codes.Unauthenticated, "tenant not specified")
+// requireStaffManager is requireStudioManager for the studio of a staff member
+func (s *StudioService) requireStaffManager(ctx context.Context, tenant, staffID string) error {
+	staff, err := s.repo.GetStaffMember(ctx, tenant, staffID)
+	if err != nil {
+		return domain.ToStatus(err, "failed to get staff member")
+	}
+	return s.requireStudioManager(ctx, tenant, staff.StudioID)
 }
 
-// Register registers a new user
-//func (s *StudioService) Register(ctx context.Context, req *ups.RegisterRequest) (*ups.RegisterResponse, error) {
-//	tenant, err := extractTenantFromContext(ctx)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	if req.Username == "" || req.Email == "" || req.Password == "" || req.Role == "" {
-//		return nil, status.Error(codes.InvalidArgument, "all fields are required")
-//	}
-//
-//	err = s.repo.Register(ctx, tenant, req.Username, req.Email, req.Password, req.Role)
-//	if err != nil {
-//		return nil, status.Errorf(codes.Internal, "failed to register user: %v", err)
-//	}
-//
-//	return &ups.RegisterResponse{Message: "User registered successfully"}, nil
-//}
-//
-//// Login authenticates a user
-//func (s *StudioService) Login(ctx context.Context, req *ups.LoginRequest) (*ups.LoginResponse, error) {
-//	tenant, err := extractTenantFromContext(ctx)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	if err = validateLoginRequest(req); err != nil {
-//		// Return InvalidArgument status code
-//		return nil, status.Errorf(codes.InvalidArgument, "invalid login request: %v", err)
-//	}
-//
-//	accessToken, newRefreshToken, err := s.repo.Login(ctx, tenant, req.Email, req.Password)
-//	if err != nil {
-//		return nil, status.Errorf(codes.Unauthenticated, "authentication failed: %v", err)
-//	}
-//
-//	return &ups.LoginResponse{
-//		AccessToken:  accessToken,
-//		RefreshToken: newRefreshToken,
-//		Message:      "Login successful",
-//	}, nil
-//}
-//
-//// Logout invalidates a session
-//func (s *StudioService) Logout(ctx context.Context, req *ups.LogoutRequest) (*ups.LogoutResponse, error) {
-//	tenant, err := extractTenantFromContext(ctx)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	if req.SessionId == "" {
-//		return nil, status.Error(codes.InvalidArgument, "session ID required")
-//	}
-//
-//	// Assuming session_id is the refresh token
-//	err = s.repo.Logout(ctx, tenant, req.SessionId)
-//	if err != nil {
-//		return nil, status.Errorf(codes.Internal, "failed to logout: %v", err)
-//	}
-//
-//	return &ups.LogoutResponse{
-//		Success: true,
-//		Message: "Logged out successfully",
-//	}, nil
-//}
-//
-//func (s *StudioService) RefreshToken(ctx context.Context, req *ups.RefreshTokenRequest) (*ups.TokenResponse, error) {
-//	tenant, err := extractTenantFromContext(ctx)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	if req.RefreshToken == "" {
-//		return nil, status.Error(codes.InvalidArgument, "refresh token required")
-//	}
-//
-//	newAccessToken, newRefreshToken, err := s.repo.RefreshSession(ctx, tenant, req.RefreshToken)
-//	if err != nil {
-//		return nil, status.Errorf(codes.Unauthenticated, "invalid refresh token: %v", err)
-//	}
-//
-//	return &ups.TokenResponse{
-//		AccessToken:  newAccessToken,
-//		RefreshToken: newRefreshToken,
-//	}, nil
-//}
-
-// ChangePassword updates a user's password
-//func (s *StudioService) ChangePassword(ctx context.Context, req *ups.ChangePasswordRequest) (*ups.ChangePasswordResponse, error) {
-//	tenant, err := extractTenantFromContext(ctx)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	if req.Username == "" || req.OldPassword == "" || req.NewPassword == "" {
-//		return nil, status.Error(codes.InvalidArgument, "all fields required")
-//	}
-//
-//	// Fetch email by username
-//	_, _, _, err = s.repo.GetUserByID(ctx, tenant, req.Username) // Assuming username can be email
-//	if err != nil {
-//		return nil, status.Errorf(codes.NotFound, "user not found: %v", err)
-//	}
-//
-//	err = s.repo.ChangePassword(ctx, tenant, req.Username, req.OldPassword, req.NewPassword)
-//	if err != nil {
-//		return nil, status.Errorf(codes.InvalidArgument, "failed to change password: %v", err)
-//	}
-//
-//	return &ups.ChangePasswordResponse{Message: "Password changed successfully"}, nil
-//}
-//
-//// ChangeEmail updates a user's email
-//func (s *StudioService) ChangeEmail(ctx context.Context, req *ups.ChangeEmailRequest) (*ups.ChangeEmailResponse, error) {
-//	tenant, err := extractTenantFromContext(ctx)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	if req.Username == "" || req.Password == "" || req.NewEmail == "" {
-//		return nil, status.Error(codes.InvalidArgument, "all fields required")
-//	}
-//
-//	// Fetch current email by username
-//	_, _, _, err = s.repo.GetUserByID(ctx, tenant, req.Username)
-//	if err != nil {
-//		return nil, status.Errorf(codes.NotFound, "user not found: %v", err)
-//	}
-//
-//	err = s.repo.ChangeEmail(ctx, tenant, req.Username, req.Password, req.NewEmail)
-//	if err != nil {
-//		return nil, status.Errorf(codes.InvalidArgument, "failed to change email: %v", err)
-//	}
-//
-//	return &ups.ChangeEmailResponse{Message: "Email changed successfully"}, nil
-//}
-
-// ValidateSession implements the ValidateSession RPC method
-//func (s *StudioService) ValidateSession(ctx context.Context, req *ups.ValidateSessionRequest) (*ups.ValidateSessionResponse, error) {
-//	// Extract tenant from context
-//	tenant, err := extractTenantFromContext(ctx)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	// Validate request
-//	if req.SessionId == "" {
-//		return nil, status.Error(codes.InvalidArgument, "session ID required")
-//	}
-//
-//	// Get session
-//	session, err := s.repo.GetSession(ctx, tenant, req.SessionId)
-//	if err != nil {
-//		return nil, status.Error(codes.Unauthenticated, "invalid or expired session")
-//	}
-//
-//	// Create response
-//	return &ups.ValidateSessionResponse{
-//		Valid:    true,
-//		UserId:   session.ID,
-//		Username: session.Username,
-//		Email:    session.Email,
-//	}, nil
-//}
-
-//
-//func (s *StudioService) GetUserByID(ctx context.Context, req *ups.GetUserByIDRequest) (*ups.GetUserByIDResponse, error) {
-//	tracer := otel.Tracer("InkMe")
-//	ctx, span := tracer.Start(ctx, "StudioAuth/GetUserByID")
-//	defer span.End()
-//
-//	requestID, ok := ctx.Value(grpcrequest.RequestIDKey{}).(string)
-//	if !ok {
-//		return nil, status.Error(codes.Internal, "request id not found in context")
-//	}
-//
-//	tenant, err := extractTenantFromContext(ctx)
-//	if err != nil {
-//		return nil, status.Errorf(codes.Unauthenticated, "invalid tenant: %v", err)
-//	}
-//
-//	if req.UserId == "" {
-//		return nil, status.Error(codes.InvalidArgument, "user ID required")
-//	}
-//
-//	user, err := s.repo.GetUserByID(ctx, tenant, req.UserId)
-//	if err != nil {
-//		return nil, status.Errorf(codes.NotFound, "user not found: %v", err)
-//	}
-//
-//	span.SetAttributes(
-//		attribute.String("request.id", req.UserId),
-//		attribute.String("request.details", req.String()),
-//	)
-//
-//	// Convert string role to User_Role enum
-//	var userRole ups.User_Role
-//	switch user.Role {
-//	case "admin":
-//		userRole = ups.User_ADMIN
-//	case "staff":
-//		userRole = ups.User_STAFF
-//	case "user":
-//		userRole = ups.User_USER
-//	case "moderator":
-//		userRole = ups.User_MODERATOR
-//	default:
-//		userRole = ups.User_ROLE_UNSPECIFIED
-//	}
-//
-//	return &ups.GetUserByIDResponse{
-//		User: &ups.User{
-//			UserId:   user.ID,
-//			Username: user.Username,
-//			Email:    user.Email,
-//			Role:     userRole,
-//		},
-//		Response: &ups.BaseResponse{
-//			RequestId: requestID,
-//			Status:    "success",
-//		},
-//	}, nil
-//}
-//
-//func (s *StudioAuthService) GetAllUsers(ctx context.Context, req *ups.GetAllUsersRequest) (*ups.GetAllUsersResponse, error) {
-//	return nil, status.Error(codes.Unimplemented, "method not implemented")
-//}
-//
-//func (s *StudioAuthService) UpdateUser(ctx context.Context, req *ups.UpdateUserRequest) (*ups.UpdateUserResponse, error) {
-//	return nil, status.Error(codes.Unimplemented, "method not implemented")
-//}
-//
-//func (s *StudioAuthService) DeleteUser(ctx context.Context, req *ups.DeleteUserRequest) (*ups.DeleteUserResponse, error) {
-//	return nil, status.Error(codes.Unimplemented, "method not implemented")
-//}
-//
-//func (s *StudioAuthService) InsertUser(ctx context.Context, req *ups.InsertUserRequest) (*ups.InsertUserResponse, error) {
-//	return nil, status.Error(codes.Unimplemented, "method not implemented")
-//}
-//
-//func (s *StudioAuthService) GetUserByID(ctx context.Context, req *ups.GetUserByIDRequest) (*ups.GetUserByIDResponse, error) {
-//	return nil, status.Error(codes.Unimplemented, "method not implemented")
-//}
-//
-//func (s *StudioAuthService) GetUserByUsername(ctx context.Context, req *ups.GetUserByUsernameRequest) (*ups.GetUserByUsernameResponse, error) {
-//	return nil, status.Error(codes.Unimplemented, "method not implemented")
-//}
+// baseResponse is the response header of a successful call started by
+// domain.StartCall
+func baseResponse(ctx context.Context) *ups.BaseResponse {
+	return &ups.BaseResponse{
+		Success:   true,
+		RequestId: domain.RequestIDFromContext(ctx),
+		TraceId:   domain.TraceIDFromContext(ctx),
+	}
+}
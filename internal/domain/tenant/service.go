@@ -0,0 +1,176 @@
+package tenant
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// ServiceName is the fully qualified gRPC name of the tenant admin service
+const ServiceName = "inkMe.admin.TenantService"
+
+// adminTokenHeader carries the operator token configured under admin.token
+const adminTokenHeader = "x-admin-token"
+
+type StudioDetails struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Phone   string `json:"phone"`
+	Email   string `json:"email"`
+	Website string `json:"website"`
+}
+
+type OwnerDetails struct {
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	DisplayName string `json:"display_name"`
+	Username    string `json:"username"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+}
+
+type DatabaseDetails struct {
+	Host string `json:"host"`
+	Port string `json:"port"`
+	// Username and Password are rejected: every tenant database is opened with
+	// the server's credentials
+	Username string `json:"username"`
+	Password string `json:"password"`
+	DB       string `json:"db"`
+	SSLMode  string `json:"sslmode"`
+	// Isolation is "database" or "schema"; empty picks the mode configured for the plan
+	Isolation string `json:"isolation"`
+}
+
+type ProvisionTenantRequest struct {
+	Subdomain string          `json:"subdomain"`
+	Plan      string          `json:"plan"`
+	Studio    StudioDetails   `json:"studio"`
+	Owner     OwnerDetails    `json:"owner"`
+	Database  DatabaseDetails `json:"database"`
+}
+
+type ProvisionTenantResponse struct {
+	Subdomain string `json:"subdomain"`
+	Database  string `json:"database"`
+	Schema    string `json:"schema,omitempty"`
+	Isolation string `json:"isolation"`
+	Message   string `json:"message"`
+}
+
+// TenantService implements the tenant admin gRPC service
+type TenantService struct {
+	provisioner domain.TenantProvisioner
+	adminToken  string
+}
+
+// NewTenantService creates a new TenantService
+func NewTenantService(provisioner domain.TenantProvisioner, adminToken string) *TenantService {
+	return &TenantService{provisioner: provisioner, adminToken: adminToken}
+}
+
+// Register adds the service to a gRPC server
+func (s *TenantService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(ServiceName,
+		structrpc.Unary(ServiceName, "ProvisionTenant", s.ProvisionTenant),
+	), s)
+}
+
+// ProvisionTenant creates the tenant database, migrates it, seeds the studio and
+// owner and makes the tenant servable without a restart
+func (s *TenantService) ProvisionTenant(ctx context.Context, req *ProvisionTenantRequest) (*ProvisionTenantResponse, error) {
+	ctx, span := otel.Tracer("ink-me").Start(ctx, "ProvisionTenant")
+	defer span.End()
+
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	if req.Subdomain == "" || req.Studio.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "subdomain and studio name are required")
+	}
+	if req.Owner.Email == "" || req.Owner.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "owner email and password are required")
+	}
+	if req.Database.Username != "" || req.Database.Password != "" {
+		return nil, status.Error(codes.InvalidArgument, "custom database credentials are not supported")
+	}
+
+	tenantCfg := ToTenantConfig(req)
+	if err := s.provisioner.Provision(ctx, tenantCfg); err != nil {
+		return nil, domain.ToStatus(err, "failed to provision tenant")
+	}
+
+	span.SetAttributes(
+		attribute.String("tenant.subdomain", tenantCfg.Subdomain),
+		attribute.String("tenant.database", tenantCfg.Database.DB),
+		attribute.String("tenant.isolation", tenantCfg.Database.Isolation),
+	)
+
+	return &ProvisionTenantResponse{
+		Subdomain: tenantCfg.Subdomain,
+		Database:  tenantCfg.Database.DB,
+		Schema:    tenantCfg.Database.Schema,
+		Isolation: tenantCfg.Database.Isolation,
+		Message:   "Tenant provisioned successfully",
+	}, nil
+}
+
+// ToTenantConfig maps a provisioning request onto the tenant configuration
+func ToTenantConfig(req *ProvisionTenantRequest) *config.TenantConfig {
+	return &config.TenantConfig{
+		Subdomain: req.Subdomain,
+		Plan:      req.Plan,
+		Studio: config.StudioConfig{
+			Name:    req.Studio.Name,
+			Address: req.Studio.Address,
+			Phone:   req.Studio.Phone,
+			Email:   req.Studio.Email,
+			Website: req.Studio.Website,
+		},
+		Owner: config.OwnerConfig{
+			Email:       req.Owner.Email,
+			Password:    req.Owner.Password,
+			DisplayName: req.Owner.DisplayName,
+			Username:    req.Owner.Username,
+			FirstName:   req.Owner.FirstName,
+			LastName:    req.Owner.LastName,
+		},
+		Database: config.DatabaseConfig{
+			Host:      req.Database.Host,
+			Port:      req.Database.Port,
+			Username:  req.Database.Username,
+			Password:  req.Database.Password,
+			DB:        req.Database.DB,
+			SSLMODE:   req.Database.SSLMode,
+			Isolation: req.Database.Isolation,
+		},
+	}
+}
+
+// authorize checks the admin token sent in the request metadata
+func (s *TenantService) authorize(ctx context.Context) error {
+	if s.adminToken == "" {
+		return status.Error(codes.PermissionDenied, "tenant admin API is disabled")
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing context metadata")
+	}
+	tokens := md.Get(adminTokenHeader)
+	if len(tokens) == 0 || subtle.ConstantTimeCompare([]byte(tokens[0]), []byte(s.adminToken)) != 1 {
+		return status.Error(codes.PermissionDenied, "invalid admin token")
+	}
+	return nil
+}
@@ -1,179 +1,87 @@
 package domain
 
-//type StudioRepository struct {
-//	pgpool *pgxpool.Pool
-//}
-//
-//func NewStudioRepository(pgpool *pgxpool.Pool) *StudioRepository {
-//	return &StudioRepository{
-//		pgpool: pgpool,
-//	}
-//}
-//
-//func (r *StudioRepository) UpdateStudio(ctx context.Context, req *pb.UpdateStudioRequest) (*pb.UpdateStudioResponse, error) {
-//	// Start a transaction
-//	tx, err := r.pgpool.BeginTx(ctx, pgx.TxOptions{})
-//	if err != nil {
-//		return nil, status.Error(codes.Internal, "failed to start transaction")
-//	}
-//
-//	defer func() {
-//		if err != nil {
-//			tx.Rollback(ctx)
-//		}
-//	}()
-//
-//	// First, get the current studio to apply updates
-//	currentStudio, err := r.getStudioByID(ctx, tx, req.StudioId)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	// Apply updates according to field mask
-//	updatedStudio, setClauses, args, err := applyFieldMaskUpdates(currentStudio, req.Studio, req.UpdateMask)
-//	if err != nil {
-//		return nil, status.Errorf(codes.InvalidArgument, "failed to apply updates: %v", err)
-//	}
-//
-//	// If no fields to update, return the current studio
-//	if len(setClauses) == 0 {
-//		return &pb.UpdateStudioResponse{
-//			Base: &pb.BaseResponse{
-//				RequestId: req.Base.RequestId,
-//				Status: &pb.Status{
-//					Code:    pb.Status_OK,
-//					Message: "No fields to update",
-//				},
-//			},
-//			Studio: currentStudio,
-//		}, nil
-//	}
-//
-//	// Build and execute the query
-//	query := "UPDATE studios SET " + strings.Join(setClauses, ", ") + " WHERE id = $" + fmt.Sprintf("%d", len(args)+1)
-//	args = append(args, req.StudioId)
-//
-//	_, err = tx.Exec(ctx, query, args...)
-//	if err != nil {
-//		return nil, status.Errorf(codes.Internal, "failed to update studio: %v", err)
-//	}
-//
-//	// Commit transaction
-//	if err = tx.Commit(ctx); err != nil {
-//		return nil, status.Errorf(codes.Internal, "failed to commit transaction: %v", err)
-//	}
-//
-//	return &pb.UpdateStudioResponse{
-//		Base: &pb.BaseResponse{
-//			RequestId: req.Base.RequestId,
-//			Status: &pb.Status{
-//				Code:    pb.Status_OK,
-//				Message: "Studio updated successfully",
-//			},
-//		},
-//		Studio: updatedStudio,
-//	}, nil
-//}
-//
-//func (r *StudioRepository) getStudioByID(ctx context.Context, tx pgx.Tx, studioID string) (*pb.Studio, error) {
-//	query := `
-//		SELECT id, name, address, phone, email, website, created_at, updated_at
-//		FROM studios
-//		WHERE id = $1
-//	`
-//
-//	row := tx.QueryRow(ctx, query, studioID)
-//
-//	studio := &pb.Studio{}
-//	err := row.Scan(
-//		&studio.Id,
-//		&studio.Name,
-//		&studio.Address,
-//		&studio.Phone,
-//		&studio.Email,
-//		&studio.Website,
-//		&studio.CreatedAt,
-//		&studio.UpdatedAt,
-//	)
-//
-//	if err != nil {
-//		if err == pgx.ErrNoRows {
-//			return nil, status.Errorf(codes.NotFound, "studio with ID %s not found", studioID)
-//		}
-//		return nil, status.Errorf(codes.Internal, "failed to get studio: %v", err)
-//	}
-//
-//	return studio, nil
-//}
-//
-//func applyFieldMaskUpdates(currentStudio *pb.Studio, updateStudio *pb.Studio, mask *field_mask.FieldMask) (*pb.Studio, []string, []interface{}, error) {
-//	if mask == nil || len(mask.Paths) == 0 {
-//		return nil, nil, nil, fmt.Errorf("update_mask is required")
-//	}
-//
-//	result := &pb.Studio{
-//		Id:        currentStudio.Id,
-//		Name:      currentStudio.Name,
-//		Address:   currentStudio.Address,
-//		Phone:     currentStudio.Phone,
-//		Email:     currentStudio.Email,
-//		Website:   currentStudio.Website,
-//		CreatedAt: currentStudio.CreatedAt,
-//		UpdatedAt: currentStudio.UpdatedAt,
-//	}
-//
-//	var setClauses []string
-//	var args []interface{}
-//	argIndex := 1
-//
-//	// Process each field in the mask
-//	for _, path := range mask.Paths {
-//		switch path {
-//		case "name":
-//			if updateStudio.Name != "" {
-//				result.Name = updateStudio.Name
-//				setClauses = append(setClauses, fmt.Sprintf("name = $%d", argIndex))
-//				args = append(args, updateStudio.Name)
-//				argIndex++
-//			}
-//		case "address":
-//			if updateStudio.Address != "" {
-//				result.Address = updateStudio.Address
-//				setClauses = append(setClauses, fmt.Sprintf("address = $%d", argIndex))
-//				args = append(args, updateStudio.Address)
-//				argIndex++
-//			}
-//		case "phone":
-//			if updateStudio.Phone != "" {
-//				result.Phone = updateStudio.Phone
-//				setClauses = append(setClauses, fmt.Sprintf("phone = $%d", argIndex))
-//				args = append(args, updateStudio.Phone)
-//				argIndex++
-//			}
-//		case "email":
-//			if updateStudio.Email != "" {
-//				result.Email = updateStudio.Email
-//				setClauses = append(setClauses, fmt.Sprintf("email = $%d", argIndex))
-//				args = append(args, updateStudio.Email)
-//				argIndex++
-//			}
-//		case "website":
-//			if updateStudio.Website != "" {
-//				result.Website = updateStudio.Website
-//				setClauses = append(setClauses, fmt.Sprintf("website = $%d", argIndex))
-//				args = append(args, updateStudio.Website)
-//				argIndex++
-//			}
-//		default:
-//			return nil, nil, nil, fmt.Errorf("unknown field in update_mask: %s", path)
-//		}
-//	}
-//
-//	// Always update the updated_at timestamp
-//	setClauses = append(setClauses, fmt.Sprintf("updated_at = $%d", argIndex))
-//	now := time.Now().Unix()
-//	args = append(args, now)
-//	result.UpdatedAt = now
-//
-//	return result, setClauses, args, nil
-//}
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// ApplyStudioFieldMask copies the fields named in mask from update onto a copy
+// of current. It returns the updated studio together with the SET clauses and
+// arguments of the matching UPDATE; the clauses use placeholders $1..$n so the
+// caller can append the WHERE arguments after them.
+func ApplyStudioFieldMask(current, update *Studio, mask *fieldmaskpb.FieldMask) (*Studio, []string, []interface{}, error) {
+	if mask == nil || len(mask.Paths) == 0 {
+		return nil, nil, nil, fmt.Errorf("%w: update_mask is required", ErrInvalidArgument)
+	}
+	if update == nil {
+		return nil, nil, nil, fmt.Errorf("%w: studio is required", ErrInvalidArgument)
+	}
+
+	result := *current
+
+	var setClauses []string
+	var args []interface{}
+	set := func(column, value string) {
+		args = append(args, value)
+		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	seen := make(map[string]bool, len(mask.Paths))
+	for _, path := range mask.Paths {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		switch path {
+		case "name":
+			if strings.TrimSpace(update.Name) == "" {
+				return nil, nil, nil, fmt.Errorf("%w: name cannot be empty", ErrInvalidArgument)
+			}
+			result.Name = update.Name
+			set("name", update.Name)
+		case "address":
+			result.Address = update.Address
+			set("address", update.Address)
+		case "phone":
+			result.Phone = update.Phone
+			set("phone", update.Phone)
+		case "email":
+			result.Email = update.Email
+			set("email", update.Email)
+		case "website":
+			result.Website = update.Website
+			set("website", update.Website)
+		default:
+			return nil, nil, nil, fmt.Errorf("%w: unknown field in update_mask: %s", ErrInvalidArgument, path)
+		}
+	}
+
+	// Always update the updated_at timestamp
+	result.UpdatedAt = time.Now()
+	args = append(args, result.UpdatedAt)
+	setClauses = append(setClauses, fmt.Sprintf("updated_at = $%d", len(args)))
+
+	return &result, setClauses, args, nil
+}
+
+// ValidateAppointmentTransition reports whether an appointment may move from
+// one status to another. Only SCHEDULED appointments change status; setting
+// the current status again is allowed.
+func ValidateAppointmentTransition(from, to string) error {
+	switch to {
+	case AppointmentScheduled, AppointmentCompleted, AppointmentCanceled, AppointmentNoShow:
+	default:
+		return fmt.Errorf("%w: unknown appointment status %q", ErrInvalidArgument, to)
+	}
+	if from == to {
+		return nil
+	}
+	if from != AppointmentScheduled {
+		return fmt.Errorf("%w: appointment is %s and cannot become %s", ErrFailedPrecondition, from, to)
+	}
+	return nil
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/FACorreiaa/ink-app-backend-grpc/config"
-	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
 )
 
 type UserRepository struct {
@@ -25,30 +28,11 @@ func NewUserRepository(dbManager *config.TenantDBManager, redisManager *config.T
 	}
 }
 
-// GetUserByID implements domain.UserRepository.
-func (r *UserRepository) GetUserByID(ctx context.Context, tenant, id string) (user *domain.User, err error) {
-	// Get tenant-specific database pool
-	pool, err := r.DBManager.GetTenantDB(tenant)
-	if err != nil {
-		return nil, fmt.Errorf("invalid tenant: %w", err)
-	}
-
-	var username, email, role string
-	err = pool.QueryRow(ctx,
-		"SELECT id, username, email, role FROM users WHERE id = $1",
-		id).Scan(&id, &username, &email, &role)
-	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
-	}
+const userColumns = `id, COALESCE(username, ''), email, role, studio_id, created_at, COALESCE(updated_at, created_at)`
 
-	user = &domain.User{
-		ID:       id,
-		Username: username,
-		Email:    email,
-		Role:     role,
-	}
-
-	return user, nil
+// GetUserByID implements domain.UserRepository.
+func (r *UserRepository) GetUserByID(ctx context.Context, tenant, id string) (*domain.User, error) {
+	return r.getUser(ctx, tenant, "id = $1", id)
 }
 
 func (r *UserRepository) ChangeEmail(ctx context.Context, tenant, email, password, newEmail string) error {
@@ -109,57 +93,189 @@ func (r *UserRepository) UpdateEmail(ctx context.Context, tenant, userID, newEma
 	return nil
 }
 
+// GetAllUsers returns every user of the tenant
 func (r *UserRepository) GetAllUsers(ctx context.Context, tenant string) ([]*domain.User, error) {
+	result, err := r.ListUsers(ctx, tenant, domain.UserFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*domain.User, 0, len(result.Items))
+	for i := range result.Items {
+		users = append(users, &result.Items[i])
+	}
+	return users, nil
+}
+
+// ListUsers pages through the tenant's users ordered by email. A zero PageSize
+// returns every match.
+func (r *UserRepository) ListUsers(ctx context.Context, tenant string, filter domain.UserFilter) (domain.PagedResult[domain.User], error) {
+	result := domain.PagedResult[domain.User]{Items: []domain.User{}, Page: filter.Page, PageSize: filter.PageSize}
+	if tenant == "" {
+		return result, errors.New("tenant subdomain is required")
+	}
+
 	// Get tenant-specific database pool
 	pool, err := r.DBManager.GetTenantDB(tenant)
 	if err != nil {
-		return nil, fmt.Errorf("invalid tenant: %w", err)
+		return result, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	where := []string{"TRUE"}
+	var args []interface{}
+	if filter.Query != "" {
+		args = append(args, filter.Query)
+		where = append(where, fmt.Sprintf(
+			"(email ILIKE '%%' || $%[1]d || '%%' OR username ILIKE '%%' || $%[1]d || '%%' OR display_name ILIKE '%%' || $%[1]d || '%%')",
+			len(args)))
+	}
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		where = append(where, fmt.Sprintf("upper(role) = upper($%d)", len(args)))
+	}
+	if filter.StudioID != "" {
+		args = append(args, filter.StudioID)
+		where = append(where, fmt.Sprintf("studio_id = $%d", len(args)))
+	}
+	whereClause := strings.Join(where, " AND ")
+
+	if err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE "+whereClause, args...).Scan(&result.TotalCount); err != nil {
+		return result, domain.WrapError("failed to count users", err)
 	}
 
-	rows, err := pool.Query(ctx,
-		"SELECT id, username, email, role FROM users")
+	query := "SELECT " + userColumns + " FROM users WHERE " + whereClause + " ORDER BY email"
+	if filter.PageSize > 0 {
+		page := max(filter.Page, 1)
+		args = append(args, filter.PageSize, (page-1)*filter.PageSize)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	rows, err := pool.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query users: %w", err)
+		return result, domain.WrapError("failed to query users", err)
 	}
 	defer rows.Close()
 
-	var users []*domain.User
 	for rows.Next() {
-		var user domain.User
-		err = rows.Scan(&user.ID, &user.Username, &user.Email, &user.Role)
+		user, err := scanUser(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
+			return result, fmt.Errorf("failed to scan user: %w", err)
 		}
-		users = append(users, &user)
+		result.Items = append(result.Items, *user)
+	}
+	if err = rows.Err(); err != nil {
+		return result, domain.WrapError("failed to query users", err)
 	}
 
-	return users, nil
+	return result, nil
 }
 
+// UpdateUser writes the non-empty username, email and role of user. A role
+// change is applied to the user's staff records too.
 func (r *UserRepository) UpdateUser(ctx context.Context, tenant string, user *domain.User) error {
-	return nil
+	if tenant == "" {
+		return errors.New("tenant subdomain is required")
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if user.Role != "" {
+			if err := ensureKnownRole(ctx, tx, user.Role); err != nil {
+				return err
+			}
+		}
+		if user.Role != "" && !strings.EqualFold(user.Role, "OWNER") {
+			if err := ensureNotLastOwner(ctx, tx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		tag, err := tx.Exec(ctx,
+			`UPDATE users SET
+				username = COALESCE(NULLIF($1, ''), username),
+				email = COALESCE(NULLIF($2, ''), email),
+				role = COALESCE(NULLIF($3, ''), role),
+				updated_at = $4
+			 WHERE id = $5`,
+			user.Username, user.Email, user.Role, time.Now(), user.ID)
+		if err != nil {
+			return domain.WrapError("failed to update user", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("user %s: %w", user.ID, domain.ErrNotFound)
+		}
+
+		if user.Role != "" {
+			_, err = tx.Exec(ctx, `UPDATE studio_staff SET role = $1, updated_at = now() WHERE user_id = $2`, user.Role, user.ID)
+			if err != nil {
+				return fmt.Errorf("failed to update staff role: %w", err)
+			}
+		}
+		return nil
+	})
 }
 
+// InsertUser creates a user in its studio, or the tenant's own studio when
+// StudioID is empty, and adds it to that studio's staff. user.Password must
+// already be hashed. The new ID is set on user.
 func (r *UserRepository) InsertUser(ctx context.Context, tenant string, user *domain.User) error {
 	if tenant == "" {
 		return errors.New("tenant subdomain is required")
 	}
+	if user.Password == "" {
+		return fmt.Errorf("%w: password is required", domain.ErrInvalidArgument)
+	}
 
 	pool, err := r.DBManager.GetTenantDB(tenant)
 	if err != nil {
 		return fmt.Errorf("invalid tenant: %w", err)
 	}
 
-	_, err = pool.Exec(ctx,
-		"INSERT INTO users (id, username, email, role, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)",
-		user.ID, user.Username, user.Email, user.Role, time.Now(), time.Now())
-	if err != nil {
-		return fmt.Errorf("failed to insert user: %w", err)
-	}
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if err := ensureKnownRole(ctx, tx, user.Role); err != nil {
+			return err
+		}
 
-	return nil
+		studioID := user.StudioID
+		query, arg := "SELECT id FROM studios WHERE id = $1", interface{}(studioID)
+		if studioID == "" {
+			query, arg = "SELECT id FROM studios WHERE subdomain = $1", tenant
+		}
+		err := tx.QueryRow(ctx, query, arg).Scan(&studioID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("studio: %w", domain.ErrNotFound)
+		}
+		if err != nil {
+			return domain.WrapError("failed to find studio", err)
+		}
+
+		now := time.Now()
+		err = tx.QueryRow(ctx,
+			`INSERT INTO users (studio_id, username, email, hashed_password, role, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $6) RETURNING id`,
+			studioID, user.Username, user.Email, user.Password, user.Role, now).Scan(&user.ID)
+		if err != nil {
+			return domain.WrapError("failed to insert user", err)
+		}
+
+		_, err = tx.Exec(ctx, `INSERT INTO studio_staff (studio_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)`,
+			studioID, user.ID, user.Role, now)
+		if err != nil {
+			return domain.WrapError("failed to add user to studio staff", err)
+		}
+
+		user.StudioID = studioID
+		user.CreatedAt, user.UpdatedAt = now, now
+		return nil
+	})
 }
 
+// DeleteUser removes a user and its staff records. The last owner of a studio
+// cannot be deleted.
 func (r *UserRepository) DeleteUser(ctx context.Context, tenant, userID string) error {
 	if tenant == "" {
 		return errors.New("tenant subdomain is required")
@@ -170,58 +286,108 @@ func (r *UserRepository) DeleteUser(ctx context.Context, tenant, userID string)
 		return fmt.Errorf("invalid tenant: %w", err)
 	}
 
-	_, err = pool.Exec(ctx,
-		"DELETE FROM users WHERE id = $1",
-		userID)
-	if err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
-	}
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if err := ensureNotLastOwner(ctx, tx, userID); err != nil {
+			return err
+		}
 
-	return nil
+		tag, err := tx.Exec(ctx, "DELETE FROM users WHERE id = $1", userID)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+				return fmt.Errorf("%w: user is still referenced: %s", domain.ErrFailedPrecondition, pgErr.Detail)
+			}
+			return domain.WrapError("failed to delete user", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("user %s: %w", userID, domain.ErrNotFound)
+		}
+		return nil
+	})
 }
 
 func (r *UserRepository) GetUserByEmail(ctx context.Context, tenant, email string) (*domain.User, error) {
+	return r.getUser(ctx, tenant, "email = $1", email)
+}
+
+func (r *UserRepository) ChangePassword(ctx context.Context, tenant, email, oldPassword, newPassword string) error {
+	return nil
+}
+
+func (r *UserRepository) GetUserByUsername(ctx context.Context, tenant, username string) (*domain.User, error) {
+	return r.getUser(ctx, tenant, "username = $1", username)
+}
+
+func (r *UserRepository) getUser(ctx context.Context, tenant, condition, value string) (*domain.User, error) {
 	if tenant == "" {
 		return nil, errors.New("tenant subdomain is required")
 	}
 
+	// Get tenant-specific database pool
 	pool, err := r.DBManager.GetTenantDB(tenant)
 	if err != nil {
 		return nil, fmt.Errorf("invalid tenant: %w", err)
 	}
 
-	var user domain.User
-	err = pool.QueryRow(ctx,
-		"SELECT id, username, email, role FROM users WHERE email = $1",
-		email).Scan(&user.ID, &user.Username, &user.Email, &user.Role)
+	user, err := scanUser(pool.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE "+condition, value))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("user not found: %w", domain.ErrNotFound)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
+		return nil, domain.WrapError("user not found", err)
 	}
-
-	return &user, nil
+	return user, nil
 }
 
-func (r *UserRepository) ChangePassword(ctx context.Context, tenant, email, oldPassword, newPassword string) error {
+// ensureKnownRole rejects roles that grant no permissions
+func ensureKnownRole(ctx context.Context, tx pgx.Tx, role string) error {
+	var known bool
+	err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM role_permissions WHERE role = $1)`, role).Scan(&known)
+	if err != nil {
+		return domain.WrapError("failed to check role", err)
+	}
+	if !known {
+		return fmt.Errorf("%w: unknown role %q", domain.ErrInvalidArgument, role)
+	}
 	return nil
 }
 
-func (r *UserRepository) GetUserByUsername(ctx context.Context, tenant, username string) (*domain.User, error) {
-	if tenant == "" {
-		return nil, errors.New("tenant subdomain is required")
+// ensureNotLastOwner fails when userID owns a studio nobody else owns. The
+// staff rows of the user's studios are locked so concurrent demotions cannot
+// race past the check.
+func ensureNotLastOwner(ctx context.Context, tx pgx.Tx, userID string) error {
+	_, err := tx.Exec(ctx,
+		`SELECT id FROM studio_staff
+		 WHERE studio_id IN (SELECT studio_id FROM studio_staff WHERE user_id = $1)
+		 FOR UPDATE`, userID)
+	if err != nil {
+		return domain.WrapError("failed to lock studio staff", err)
 	}
 
-	pool, err := r.DBManager.GetTenantDB(tenant)
+	var lastOwner bool
+	err = tx.QueryRow(ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM studio_staff s
+			WHERE s.user_id = $1 AND upper(s.role) = 'OWNER'
+			  AND NOT EXISTS (
+				SELECT 1 FROM studio_staff o
+				WHERE o.studio_id = s.studio_id AND o.user_id <> s.user_id AND upper(o.role) = 'OWNER'
+			  )
+		)`, userID).Scan(&lastOwner)
 	if err != nil {
-		return nil, fmt.Errorf("invalid tenant: %w", err)
+		return domain.WrapError("failed to check studio owners", err)
 	}
+	if lastOwner {
+		return fmt.Errorf("%w: user is the last owner of its studio", domain.ErrFailedPrecondition)
+	}
+	return nil
+}
 
+func scanUser(row pgx.Row) (*domain.User, error) {
 	var user domain.User
-	err = pool.QueryRow(ctx,
-		"SELECT id, username, email, role FROM users WHERE username = $1",
-		username).Scan(&user.ID, &user.Username, &user.Email, &user.Role)
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.StudioID, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
+		return nil, err
 	}
-
 	return &user, nil
 }
@@ -4,19 +4,20 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
-	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
-	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/middleware/grpcrequest"
 	pb "github.com/FACorreiaa/ink-app-backend-protos/modules/user/generated"
-	"go.opentelemetry.io/otel/attribute"
-	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
+// managerRoles may create, update and delete users
+var managerRoles = []string{"OWNER"}
+
 type UserService struct {
 	pb.UnimplementedUserServiceServer
 	repo domain.UserRepository
@@ -26,174 +27,115 @@ func NewUserService(repo domain.UserRepository) *UserService {
 	return &UserService{repo: repo}
 }
 
+// mapDomainRoleToProto reports a staff role the way the proto can express it;
+// ARTIST and ASSISTANT both read as STAFF
 func mapDomainRoleToProto(domainRole string) pb.User_Role {
-	var protoRole pb.User_Role
-
-	// Use ToUpper for case-insensitive matching
 	switch strings.ToUpper(domainRole) {
-	case "ADMIN", "OWNER": // Grouping similar roles if they map the same
-		protoRole = pb.User_ADMIN
-	case "STAFF":
-		protoRole = pb.User_STAFF
-	case "USER": // Assuming "customer" in domain might map to "USER" in proto? Adjust as needed.
-		protoRole = pb.User_USER
-	case "MODERATOR":
-		protoRole = pb.User_MODERATOR
+	case "OWNER":
+		return pb.User_ADMIN
+	case "ARTIST", "ASSISTANT":
+		return pb.User_STAFF
 	default:
-		// Log potentially unknown roles if helpful for debugging
-		// log.Printf("Warning: Mapping unknown domain role '%s' to ROLE_UNSPECIFIED", domainRole)
-		protoRole = pb.User_ROLE_UNSPECIFIED
+		return pb.User_ROLE_UNSPECIFIED
 	}
-
-	return protoRole
 }
 
+// mapProtoRoleToDomain picks the staff role a proto role is written as. Only
+// roles known to role_permissions are accepted; STAFF becomes ARTIST.
 func mapProtoRoleToDomain(protoRole pb.User_Role) (string, error) {
 	switch protoRole {
 	case pb.User_ADMIN:
-		return "ADMIN", nil // Or "OWNER" if that's your domain representation
+		return "OWNER", nil
 	case pb.User_STAFF:
-		return "STAFF", nil
-	case pb.User_USER:
-		return "USER", nil
-	case pb.User_MODERATOR:
-		return "MODERATOR", nil
+		return "ARTIST", nil
 	case pb.User_ROLE_UNSPECIFIED:
 		return "", fmt.Errorf("role cannot be ROLE_UNSPECIFIED")
 	default:
-		// This case handles potential future enum values not yet known
-		// or invalid integer values passed somehow.
-		return "", fmt.Errorf("unknown or invalid proto role value: %d", protoRole)
+		return "", fmt.Errorf("role %s has no studio permissions", protoRole)
 	}
 }
 
-func (s *UserService) GetAllUsers(ctx context.Context, req *pb.GetUsersReq) (*pb.GetUsersRes, error) {
-	traceContext, span := otel.Tracer("ink-me").Start(ctx, "Register")
-	defer span.End()
-	traceID := span.SpanContext().TraceID().String()
-	requestID, ok := ctx.Value(grpcrequest.RequestIDKey{}).(string)
-	if !ok {
-		return nil, status.Error(codes.Internal, "request id not found in context")
-	}
-
-	if req.Request == nil {
-		req.Request = &pb.BaseRequest{}
-	}
-
-	req.Request.RequestId = requestID
-
-	tenant, err := domain.ExtractTenantFromContext(traceContext)
+// GetUsers lists the tenant's users. Paging is read from the x-page and
+// x-page-size headers and the users can be filtered with x-query, x-role and
+// x-studio-id.
+func (s *UserService) GetUsers(ctx context.Context, req *pb.GetUsersReq) (*pb.GetUsersRes, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetUsers")
 	if err != nil {
 		return nil, err
 	}
+	defer span.End()
+	res := baseResponse(ctx)
 
-	users, err := s.repo.GetAllUsers(ctx, tenant)
+	page, pageSize, err := domain.PageFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var domainUser *domain.User
-
-	protoRole := mapDomainRoleToProto(domainUser.Role)
+	result, err := s.repo.ListUsers(ctx, tenant, domain.UserFilter{
+		Query:    domain.MetadataValue(ctx, domain.QueryHeader),
+		Role:     domain.MetadataValue(ctx, domain.RoleHeader),
+		StudioID: domain.MetadataValue(ctx, domain.StudioHeader),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to list users")
+	}
+	domain.SetTotalCount(ctx, result.TotalCount)
 
-	res := &pb.GetUsersRes{}
-	for _, user := range users {
-		res.Users = append(res.Users, &pb.User{
-			UserId:   user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-			Role:     protoRole,
-		})
+	users := make([]*pb.User, 0, len(result.Items))
+	for i := range result.Items {
+		users = append(users, userToProto(&result.Items[i]))
 	}
 
-	span.SetAttributes(
-		semconv.ServiceNameKey.String("Workout"),
-		attribute.String("grpc.method", "GetExercises"),
-		attribute.String("request.id", req.GetRequest().RequestId),
-	)
+	span.SetAttributes(attribute.Int("users.count", len(users)))
 
 	return &pb.GetUsersRes{
-		//Message: "User registered successfully",
-		Users: res.Users,
-		Response: &pb.BaseResponse{
-			Success:   true,
-			RequestId: req.Request.RequestId,
-			TraceId:   traceID,
-		}}, nil
+		Success:  true,
+		Message:  "Users retrieved successfully",
+		Users:    users,
+		Response: res,
+	}, nil
 }
-func (s *UserService) GetUserByID(ctx context.Context, req *pb.GetUserByIDReq) (*pb.GetUserByIDRes, error) {
-	traceContext, span := otel.Tracer("ink-me").Start(ctx, "Register")
-	defer span.End()
-	traceID := span.SpanContext().TraceID().String()
-	requestID, ok := ctx.Value(grpcrequest.RequestIDKey{}).(string)
-	if !ok {
-		return nil, status.Error(codes.Internal, "request id not found in context")
-	}
 
-	if req.Request == nil {
-		req.Request = &pb.BaseRequest{}
-	}
-
-	req.Request.RequestId = requestID
-
-	tenant, err := domain.ExtractTenantFromContext(traceContext)
+func (s *UserService) GetUserByID(ctx context.Context, req *pb.GetUserByIDReq) (*pb.GetUserByIDRes, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetUserByID")
 	if err != nil {
 		return nil, err
 	}
+	defer span.End()
+	res := baseResponse(ctx)
 
-	user, err := s.repo.GetUserByID(traceContext, tenant, req.UserId)
-	if err != nil {
-		return nil, err
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
 	}
 
-	var domainUser *domain.User
-	protoRole := mapDomainRoleToProto(domainUser.Role)
-
-	span.SetAttributes(
-		semconv.ServiceNameKey.String("Workout"),
-		attribute.String("grpc.method", "GetExercises"),
-		attribute.String("request.id", req.GetRequest().RequestId),
-	)
-
-	res := &pb.GetUserByIDRes{
-		User: &pb.User{
-			UserId:   user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-			Role:     protoRole,
-		},
+	user, err := s.repo.GetUserByID(ctx, tenant, req.UserId)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get user")
 	}
 
 	return &pb.GetUserByIDRes{
-		User: res.User,
-		Response: &pb.BaseResponse{
-			Success:   true,
-			RequestId: req.Request.RequestId,
-			TraceId:   traceID,
-		}}, nil
+		User:     userToProto(user),
+		Response: res,
+	}, nil
 }
 
+// InsertUser creates a user in the given studio, or the tenant's own studio.
+// The request's password_hash field carries the plain text password, which is
+// hashed before it is stored.
 func (s *UserService) InsertUser(ctx context.Context, req *pb.InsertUserReq) (*pb.InsertUserRes, error) {
-	traceContext, span := otel.Tracer("ink-me").Start(ctx, "Register")
-	defer span.End()
-	traceID := span.SpanContext().TraceID().String()
-	requestID, ok := ctx.Value(grpcrequest.RequestIDKey{}).(string)
-	if !ok {
-		return nil, status.Error(codes.Internal, "request id not found in context")
-	}
-
-	if req.Request == nil {
-		req.Request = &pb.BaseRequest{}
+	ctx, span, tenant, err := domain.StartCall(ctx, "InsertUser")
+	if err != nil {
+		return nil, err
 	}
+	defer span.End()
+	res := baseResponse(ctx)
 
-	req.Request.RequestId = requestID
-
-	tenant, err := domain.ExtractTenantFromContext(traceContext) // Assuming this function exists
-	if err != nil {
+	if err = domain.RequireRole(ctx, managerRoles...); err != nil {
 		return nil, err
 	}
 
-	// --- Validate Input ---
 	if req.User == nil {
 		return nil, status.Error(codes.InvalidArgument, "user data is required")
 	}
@@ -201,200 +143,187 @@ func (s *UserService) InsertUser(ctx context.Context, req *pb.InsertUserReq) (*p
 		return nil, status.Error(codes.InvalidArgument, "username, email, and password are required")
 	}
 
-	// --- Map Proto Role to Domain Role String ---
 	domainRoleString, err := mapProtoRoleToDomain(req.User.Role)
 	if err != nil {
-		// Return an error if the role is unspecified or invalid
 		return nil, status.Errorf(codes.InvalidArgument, "invalid user role provided: %v", err)
 	}
 
-	// --- Hash Password (Assuming PasswordHash in request is plain text) ---
-	// IMPORTANT: The request field is named PasswordHash, but it should contain the
-	// PLAIN TEXT password for a *new* user insertion. You need to hash it here.
-	// If the client is sending an already hashed password, this needs rethinking.
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.User.PasswordHash), bcrypt.DefaultCost)
 	if err != nil {
-		// Log internal error
 		return nil, status.Error(codes.Internal, "failed to hash password")
 	}
 
-	// --- Create Domain User Struct ---
 	user := &domain.User{
 		Username: req.User.Username,
 		Email:    req.User.Email,
-		Password: string(hashedPassword), // Store the *hashed* password
-		Role:     domainRoleString,       // Assign the converted string
-		StudioID: req.User.StudioId,      // Assign StudioID if provided
-		// CreatedAt/UpdatedAt will be set by DB usually or in repo
+		Password: string(hashedPassword),
+		Role:     domainRoleString,
+		StudioID: req.User.StudioId,
 	}
 
-	// --- Call Repository ---
-	// Assuming InsertUser in repo handles setting CreatedAt/UpdatedAt
-	// and potentially returning the generated ID if needed
-	err = s.repo.InsertUser(traceContext, tenant, user)
-	if err != nil {
-		// Check for specific errors like email/username already exists
-		return nil, status.Errorf(codes.Internal, "failed to insert user: %v", err) // Or more specific codes
+	if err = s.repo.InsertUser(ctx, tenant, user); err != nil {
+		return nil, domain.ToStatus(err, "failed to insert user")
 	}
 
-	span.SetAttributes(
-		semconv.ServiceNameKey.String("Workout"),
-		attribute.String("grpc.method", "GetExercises"),
-		attribute.String("request.id", req.GetRequest().RequestId),
-	)
+	span.SetAttributes(attribute.String("user.id", user.ID))
 
 	return &pb.InsertUserRes{
-		Message: "User created successfully",
-		Response: &pb.BaseResponse{
-			Success:   true,
-			RequestId: req.Request.RequestId,
-			TraceId:   traceID,
-		}}, nil
+		Message:  "User created successfully",
+		Response: res,
+	}, nil
 }
 
+// UpdateUser changes the username, email and role of a user; empty fields,
+// ROLE_UNSPECIFIED and the role the user already has keep their current value
 func (s *UserService) UpdateUser(ctx context.Context, req *pb.UpdateUserReq) (*pb.UpdateUserRes, error) {
-	return nil, nil
-}
-func (s *UserService) DeleteUser(ctx context.Context, req *pb.DeleteUserReq) (*pb.DeleteUserRes, error) {
-	traceContext, span := otel.Tracer("ink-me").Start(ctx, "Register")
+	ctx, span, tenant, err := domain.StartCall(ctx, "UpdateUser")
+	if err != nil {
+		return nil, err
+	}
 	defer span.End()
-	traceID := span.SpanContext().TraceID().String()
-	requestID, ok := ctx.Value(grpcrequest.RequestIDKey{}).(string)
-	if !ok {
-		return nil, status.Error(codes.Internal, "request id not found in context")
+	res := baseResponse(ctx)
+
+	if err = domain.RequireRole(ctx, managerRoles...); err != nil {
+		return nil, err
 	}
 
-	if req.Request == nil {
-		req.Request = &pb.BaseRequest{}
+	if req.User == nil || req.User.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user with user_id is required")
 	}
 
-	req.Request.RequestId = requestID
+	user := &domain.User{
+		ID:       req.User.UserId,
+		Username: strings.TrimSpace(req.User.Username),
+		Email:    strings.TrimSpace(req.User.Email),
+	}
+	if req.User.Role != pb.User_ROLE_UNSPECIFIED {
+		// STAFF stands for both ARTIST and ASSISTANT, so a role the user
+		// already reads as is kept rather than rewritten
+		current, err := s.repo.GetUserByID(ctx, tenant, user.ID)
+		if err != nil {
+			return nil, domain.ToStatus(err, "failed to get user")
+		}
+		if mapDomainRoleToProto(current.Role) != req.User.Role {
+			if user.Role, err = mapProtoRoleToDomain(req.User.Role); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid user role provided: %v", err)
+			}
+		}
+	}
+
+	if err = s.repo.UpdateUser(ctx, tenant, user); err != nil {
+		return nil, domain.ToStatus(err, "failed to update user")
+	}
+
+	span.SetAttributes(attribute.String("user.id", user.ID))
+
+	return &pb.UpdateUserRes{
+		Message:  "User updated successfully",
+		Response: res,
+	}, nil
+}
 
-	tenant, err := domain.ExtractTenantFromContext(traceContext)
+func (s *UserService) DeleteUser(ctx context.Context, req *pb.DeleteUserReq) (*pb.DeleteUserRes, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "DeleteUser")
 	if err != nil {
 		return nil, err
 	}
+	defer span.End()
+	res := baseResponse(ctx)
 
-	err = s.repo.DeleteUser(traceContext, tenant, req.UserId)
-	if err != nil {
+	if err = domain.RequireRole(ctx, managerRoles...); err != nil {
 		return nil, err
 	}
 
-	span.SetAttributes(
-		semconv.ServiceNameKey.String("Workout"),
-		attribute.String("grpc.method", "GetExercises"),
-		attribute.String("request.id", req.GetRequest().RequestId),
-	)
-
-	return &pb.DeleteUserRes{
-		//Message: "User registered successfully",
-		Response: &pb.BaseResponse{
-			Success:   true,
-			RequestId: req.Request.RequestId,
-			TraceId:   traceID,
-		}}, nil
-}
-func (s *UserService) GetUserByEmail(ctx context.Context, req *pb.GetUserByEmailReq) (*pb.GetUserByEmailRes, error) {
-	traceContext, span := otel.Tracer("ink-me").Start(ctx, "Register")
-	defer span.End()
-	traceID := span.SpanContext().TraceID().String()
-	requestID, ok := ctx.Value(grpcrequest.RequestIDKey{}).(string)
-	if !ok {
-		return nil, status.Error(codes.Internal, "request id not found in context")
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
 	}
-
-	if req.Request == nil {
-		req.Request = &pb.BaseRequest{}
+	if callerID, _ := domain.ExtractUserIDFromContext(ctx); callerID == req.UserId {
+		return nil, status.Error(codes.FailedPrecondition, "users cannot delete themselves")
 	}
 
-	req.Request.RequestId = requestID
-
-	tenant, err := domain.ExtractTenantFromContext(traceContext)
-	if err != nil {
-		return nil, err
+	if err = s.repo.DeleteUser(ctx, tenant, req.UserId); err != nil {
+		return nil, domain.ToStatus(err, "failed to delete user")
 	}
 
-	var domainUser *domain.User
-	protoRole := mapDomainRoleToProto(domainUser.Role)
+	span.SetAttributes(attribute.String("user.id", req.UserId))
+
+	return &pb.DeleteUserRes{
+		Response: res,
+	}, nil
+}
 
-	user, err := s.repo.GetUserByEmail(traceContext, tenant, req.Email)
+func (s *UserService) GetUserByEmail(ctx context.Context, req *pb.GetUserByEmailReq) (*pb.GetUserByEmailRes, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetUserByEmail")
 	if err != nil {
 		return nil, err
 	}
+	defer span.End()
+	res := baseResponse(ctx)
 
-	res := &pb.GetUserByEmailRes{
-		User: &pb.User{
-			UserId:   user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-			Role:     protoRole,
-		},
+	if req.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
 	}
 
-	span.SetAttributes(
-		semconv.ServiceNameKey.String("Workout"),
-		attribute.String("grpc.method", "GetExercises"),
-		attribute.String("request.id", req.GetRequest().RequestId),
-	)
+	user, err := s.repo.GetUserByEmail(ctx, tenant, req.Email)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get user")
+	}
 
 	return &pb.GetUserByEmailRes{
-		//Message: "User registered successfully",
-		User: res.User,
-		Response: &pb.BaseResponse{
-			Success:   true,
-			RequestId: req.Request.RequestId,
-			TraceId:   traceID,
-		}}, nil
+		User:     userToProto(user),
+		Response: res,
+	}, nil
 }
-func (s *UserService) GetUserByUsername(ctx context.Context, req *pb.GetUserByUsernameReq) (*pb.GetUserByUsernameRes, error) {
-	traceContext, span := otel.Tracer("ink-me").Start(ctx, "Register")
-	defer span.End()
-	traceID := span.SpanContext().TraceID().String()
-	requestID, ok := ctx.Value(grpcrequest.RequestIDKey{}).(string)
-	if !ok {
-		return nil, status.Error(codes.Internal, "request id not found in context")
-	}
-
-	if req.Request == nil {
-		req.Request = &pb.BaseRequest{}
-	}
 
-	req.Request.RequestId = requestID
-
-	tenant, err := domain.ExtractTenantFromContext(traceContext)
+func (s *UserService) GetUserByUsername(ctx context.Context, req *pb.GetUserByUsernameReq) (*pb.GetUserByUsernameRes, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetUserByUsername")
 	if err != nil {
 		return nil, err
 	}
+	defer span.End()
+	res := baseResponse(ctx)
 
-	var domainUser *domain.User
-	protoRole := mapDomainRoleToProto(domainUser.Role)
+	if req.Username == "" {
+		return nil, status.Error(codes.InvalidArgument, "username is required")
+	}
 
 	user, err := s.repo.GetUserByUsername(ctx, tenant, req.Username)
 	if err != nil {
-		return nil, err
+		return nil, domain.ToStatus(err, "failed to get user")
 	}
 
-	span.SetAttributes(
-		semconv.ServiceNameKey.String("Workout"),
-		attribute.String("grpc.method", "GetExercises"),
-		attribute.String("request.id", req.GetRequest().RequestId),
-	)
+	return &pb.GetUserByUsernameRes{
+		User:     userToProto(user),
+		Response: res,
+	}, nil
+}
 
-	res := &pb.GetUserByUsernameRes{
-		User: &pb.User{
-			UserId:   user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-			Role:     protoRole,
-		},
+// userToProto maps a user to its proto message. The password hash is never
+// returned.
+func userToProto(user *domain.User) *pb.User {
+	protoUser := &pb.User{
+		UserId:   user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Role:     mapDomainRoleToProto(user.Role),
+		IsAdmin:  mapDomainRoleToProto(user.Role) == pb.User_ADMIN,
+		StudioId: user.StudioID,
 	}
+	if !user.CreatedAt.IsZero() {
+		protoUser.CreatedAt = user.CreatedAt.Format(time.RFC3339)
+	}
+	if !user.UpdatedAt.IsZero() {
+		protoUser.UpdatedAt = user.UpdatedAt.Format(time.RFC3339)
+	}
+	return protoUser
+}
 
-	return &pb.GetUserByUsernameRes{
-		//Message: "User registered successfully",
-		User: res.User,
-		Response: &pb.BaseResponse{
-			Success:   true,
-			RequestId: req.Request.RequestId,
-			TraceId:   traceID,
-		}}, nil
+// baseResponse is the response header of a successful call started by
+// domain.StartCall
+func baseResponse(ctx context.Context) *pb.BaseResponse {
+	return &pb.BaseResponse{
+		Success:   true,
+		RequestId: domain.RequestIDFromContext(ctx),
+		TraceId:   domain.TraceIDFromContext(ctx),
+	}
 }
@@ -0,0 +1,89 @@
+package walkin
+
+import (
+	"time"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+const (
+	// defaultServiceTime is assumed per walk-in until the studio has finished
+	// some
+	defaultServiceTime = 30 * time.Minute
+	// throughputWindow is how far back finished walk-ins count towards the
+	// average service time
+	throughputWindow = 30 * 24 * time.Hour
+	// activeWindow is how recently an artist must have finished a walk-in to
+	// count as working the queue
+	activeWindow = 4 * time.Hour
+)
+
+// estimateStarts predicts when each waiting walk-in is seated. Every working
+// artist frees up when their current walk-in has taken their average service
+// time; waiting walk-ins then go, in order of arrival, to their requested
+// artist or to whoever frees up first. Working artists are the ones serving or
+// recently done with a walk-in; with none, a single artist is assumed.
+func estimateStarts(now time.Time, queue []domain.WalkIn, throughput []domain.ArtistThroughput) map[string]time.Time {
+	average := make(map[string]time.Duration, len(throughput))
+	var total time.Duration
+	var served int
+	for _, t := range throughput {
+		average[t.ArtistID] = t.Average
+		total += t.Average * time.Duration(t.Served)
+		served += t.Served
+	}
+	studioAverage := defaultServiceTime
+	if served > 0 {
+		studioAverage = total / time.Duration(served)
+	}
+	serviceTime := func(artistID string) time.Duration {
+		if d, ok := average[artistID]; ok && d > 0 {
+			return d
+		}
+		return studioAverage
+	}
+
+	freeAt := make(map[string]time.Time)
+	for _, t := range throughput {
+		if now.Sub(t.LastFinished) <= activeWindow {
+			freeAt[t.ArtistID] = now
+		}
+	}
+	for _, walkIn := range queue {
+		if walkIn.Status != domain.WalkInInService || walkIn.StartedAt == nil {
+			continue
+		}
+		free := walkIn.StartedAt.Add(serviceTime(walkIn.ArtistID))
+		if free.Before(now) {
+			free = now
+		}
+		freeAt[walkIn.ArtistID] = free
+	}
+	if len(freeAt) == 0 {
+		freeAt[""] = now
+	}
+
+	starts := make(map[string]time.Time)
+	for _, walkIn := range queue {
+		if walkIn.Status != domain.WalkInWaiting {
+			continue
+		}
+
+		artistID := walkIn.ArtistID
+		if artistID == "" {
+			first := true
+			for id, free := range freeAt {
+				// Ties go to the lowest id so estimates do not jump around
+				if first || free.Before(freeAt[artistID]) || (free.Equal(freeAt[artistID]) && id < artistID) {
+					artistID, first = id, false
+				}
+			}
+		} else if _, ok := freeAt[artistID]; !ok {
+			freeAt[artistID] = now
+		}
+
+		starts[walkIn.ID] = freeAt[artistID]
+		freeAt[artistID] = freeAt[artistID].Add(serviceTime(artistID))
+	}
+	return starts
+}
@@ -0,0 +1,351 @@
+package walkin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+// WalkInRepository keeps the walk-in queues in the tenant's database and
+// announces their changes on a Redis channel per studio
+type WalkInRepository struct {
+	DBManager    *config.TenantDBManager
+	RedisManager *config.TenantRedisManager
+}
+
+// NewWalkInRepository creates a new WalkInRepository
+func NewWalkInRepository(dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *WalkInRepository {
+	return &WalkInRepository{
+		DBManager:    dbManager,
+		RedisManager: redisManager,
+	}
+}
+
+const walkInColumns = `id, studio_id, COALESCE(customer_id::text, ''), name, COALESCE(design, ''),
+	COALESCE(artist_id::text, ''), status, joined_at, started_at, finished_at`
+
+func channel(tenant, studioID string) string {
+	return fmt.Sprintf("walkins:%s:%s", tenant, studioID)
+}
+
+// Add puts a walk-in at the end of the queue. A registered customer's name is
+// used when no name is given.
+func (r *WalkInRepository) Add(ctx context.Context, tenant string, walkIn *domain.WalkIn) error {
+	if walkIn == nil {
+		return fmt.Errorf("%w: walk-in is required", domain.ErrInvalidArgument)
+	}
+	if walkIn.Name == "" && walkIn.CustomerID == "" {
+		return fmt.Errorf("%w: a name or customer is required", domain.ErrInvalidArgument)
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		studioID := walkIn.StudioID
+		if studioID == "" {
+			err := tx.QueryRow(ctx, "SELECT id FROM studios WHERE subdomain = $1", tenant).Scan(&studioID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("studio: %w", domain.ErrNotFound)
+			}
+			if err != nil {
+				return domain.WrapError("failed to find studio", err)
+			}
+		}
+		if walkIn.ArtistID != "" {
+			if err := ensureArtist(ctx, tx, studioID, walkIn.ArtistID); err != nil {
+				return err
+			}
+		}
+
+		created, err := scanWalkIn(tx.QueryRow(ctx,
+			`INSERT INTO walk_ins (studio_id, customer_id, name, design, artist_id)
+			 VALUES ($1, NULLIF($2, '')::uuid,
+				COALESCE(NULLIF($3, ''), (SELECT full_name FROM customers WHERE id = NULLIF($2, '')::uuid)),
+				NULLIF($4, ''), NULLIF($5, '')::uuid)
+			 RETURNING `+walkInColumns,
+			studioID, walkIn.CustomerID, walkIn.Name, walkIn.Design, walkIn.ArtistID))
+		if err != nil {
+			return domain.WrapError("failed to add walk-in", err)
+		}
+		*walkIn = *created
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.publish(ctx, tenant, walkIn)
+	return nil
+}
+
+func (r *WalkInRepository) GetByID(ctx context.Context, tenant, id string) (*domain.WalkIn, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	walkIn, err := scanWalkIn(pool.QueryRow(ctx, "SELECT "+walkInColumns+" FROM walk_ins WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("walk-in %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get walk-in", err)
+	}
+	return walkIn, nil
+}
+
+// Queue returns the walk-ins still waiting or in service
+func (r *WalkInRepository) Queue(ctx context.Context, tenant, studioID string) ([]domain.WalkIn, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	rows, err := pool.Query(ctx,
+		`SELECT `+walkInColumns+` FROM walk_ins
+		 WHERE studio_id = $1 AND status IN ($2, $3)
+		 ORDER BY joined_at, id`, studioID, domain.WalkInWaiting, domain.WalkInInService)
+	if err != nil {
+		return nil, domain.WrapError("failed to query walk-ins", err)
+	}
+	defer rows.Close()
+
+	queue := []domain.WalkIn{}
+	for rows.Next() {
+		walkIn, err := scanWalkIn(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan walk-in: %w", err)
+		}
+		queue = append(queue, *walkIn)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain.WrapError("failed to query walk-ins", err)
+	}
+	return queue, nil
+}
+
+// Throughput averages the service time of the walk-ins each artist of the
+// studio finished since then
+func (r *WalkInRepository) Throughput(ctx context.Context, tenant, studioID string, since time.Time) ([]domain.ArtistThroughput, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	rows, err := pool.Query(ctx,
+		`SELECT artist_id::text, EXTRACT(EPOCH FROM avg(finished_at - started_at))::float8, count(*), max(finished_at)
+		 FROM walk_ins
+		 WHERE studio_id = $1 AND status = $2 AND finished_at >= $3 AND artist_id IS NOT NULL
+		 GROUP BY artist_id`, studioID, domain.WalkInDone, since)
+	if err != nil {
+		return nil, domain.WrapError("failed to query walk-in throughput", err)
+	}
+	throughput, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.ArtistThroughput, error) {
+		var t domain.ArtistThroughput
+		var seconds float64
+		err := row.Scan(&t.ArtistID, &seconds, &t.Served, &t.LastFinished)
+		t.Average = time.Duration(seconds * float64(time.Second))
+		return t, err
+	})
+	if err != nil {
+		return nil, domain.WrapError("failed to query walk-in throughput", err)
+	}
+	return throughput, nil
+}
+
+// Assign seats a waiting walk-in with an artist of the studio who is not
+// serving anyone else
+func (r *WalkInRepository) Assign(ctx context.Context, tenant, id, artistID string) (*domain.WalkIn, error) {
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var assigned *domain.WalkIn
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		current, err := lockWalkIn(ctx, tx, id, domain.WalkInWaiting)
+		if err != nil {
+			return err
+		}
+		if artistID == "" {
+			artistID = current.ArtistID
+		}
+		if artistID == "" {
+			return fmt.Errorf("%w: an artist is required", domain.ErrInvalidArgument)
+		}
+		if err := ensureArtist(ctx, tx, current.StudioID, artistID); err != nil {
+			return err
+		}
+
+		var busy bool
+		if err := tx.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM walk_ins WHERE studio_id = $1 AND artist_id = $2 AND status = $3)`,
+			current.StudioID, artistID, domain.WalkInInService).Scan(&busy); err != nil {
+			return domain.WrapError("failed to check artist", err)
+		}
+		if busy {
+			return fmt.Errorf("%w: artist is serving another walk-in", domain.ErrFailedPrecondition)
+		}
+
+		assigned, err = scanWalkIn(tx.QueryRow(ctx,
+			`UPDATE walk_ins SET status = $1, artist_id = $2, started_at = now()
+			 WHERE id = $3 RETURNING `+walkInColumns,
+			domain.WalkInInService, artistID, id))
+		if err != nil {
+			return domain.WrapError("failed to assign walk-in", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.publish(ctx, tenant, assigned)
+	return assigned, nil
+}
+
+// Finish marks an in-service walk-in DONE, or a waiting one a NO_SHOW
+func (r *WalkInRepository) Finish(ctx context.Context, tenant, id, status string) (*domain.WalkIn, error) {
+	var from string
+	switch status {
+	case domain.WalkInDone:
+		from = domain.WalkInInService
+	case domain.WalkInNoShow:
+		from = domain.WalkInWaiting
+	default:
+		return nil, fmt.Errorf("%w: status must be DONE or NO_SHOW", domain.ErrInvalidArgument)
+	}
+
+	pool, err := r.DBManager.GetTenantDB(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	var finished *domain.WalkIn
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := lockWalkIn(ctx, tx, id, from); err != nil {
+			return err
+		}
+		var err error
+		finished, err = scanWalkIn(tx.QueryRow(ctx,
+			`UPDATE walk_ins SET status = $1, finished_at = now() WHERE id = $2 RETURNING `+walkInColumns,
+			status, id))
+		if err != nil {
+			return domain.WrapError("failed to finish walk-in", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.publish(ctx, tenant, finished)
+	return finished, nil
+}
+
+// Watch subscribes to the studio's queue channel. Changes that arrive while
+// the previous one is still being handled are coalesced, since watchers
+// reload the whole queue anyway. The returned channel is closed once ctx is
+// done or the subscription fails.
+func (r *WalkInRepository) Watch(ctx context.Context, tenant, studioID string) (<-chan struct{}, error) {
+	client, err := r.RedisManager.GetTenantRedis(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	sub := client.Subscribe(ctx, channel(tenant, studioID))
+	// Wait for the confirmation so nothing published after Watch returns is missed
+	if _, err = sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, fmt.Errorf("failed to watch walk-ins: %w", err)
+	}
+
+	out := make(chan struct{}, 1)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+
+		messages := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case out <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// publish announces a change to the walk-in's queue. The change is already
+// committed, so a failure only delays the front desk until its next refresh.
+func (r *WalkInRepository) publish(ctx context.Context, tenant string, walkIn *domain.WalkIn) {
+	client, err := r.RedisManager.GetTenantRedis(tenant)
+	if err == nil {
+		err = client.Publish(ctx, channel(tenant, walkIn.StudioID), walkIn.ID).Err()
+	}
+	if err != nil {
+		logger.Log.Warn("failed to publish walk-in change",
+			zap.String("tenant", tenant),
+			zap.String("walk_in_id", walkIn.ID),
+			zap.Error(err))
+	}
+}
+
+// lockWalkIn locks a walk-in and fails unless its status is from
+func lockWalkIn(ctx context.Context, tx pgx.Tx, id, from string) (*domain.WalkIn, error) {
+	walkIn, err := scanWalkIn(tx.QueryRow(ctx, "SELECT "+walkInColumns+" FROM walk_ins WHERE id = $1 FOR UPDATE", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("walk-in %s: %w", id, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, domain.WrapError("failed to get walk-in", err)
+	}
+	if walkIn.Status != from {
+		return nil, fmt.Errorf("%w: walk-in is %s", domain.ErrFailedPrecondition, walkIn.Status)
+	}
+	return walkIn, nil
+}
+
+// ensureArtist fails unless userID is on the staff of the studio
+func ensureArtist(ctx context.Context, tx pgx.Tx, studioID, userID string) error {
+	var exists bool
+	err := tx.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM studio_staff WHERE studio_id = $1 AND user_id = $2)",
+		studioID, userID).Scan(&exists)
+	if err != nil {
+		return domain.WrapError("failed to check artist", err)
+	}
+	if !exists {
+		return fmt.Errorf("artist %s is not on the studio staff: %w", userID, domain.ErrNotFound)
+	}
+	return nil
+}
+
+func scanWalkIn(row pgx.Row) (*domain.WalkIn, error) {
+	var walkIn domain.WalkIn
+	err := row.Scan(&walkIn.ID, &walkIn.StudioID, &walkIn.CustomerID, &walkIn.Name, &walkIn.Design,
+		&walkIn.ArtistID, &walkIn.Status, &walkIn.JoinedAt, &walkIn.StartedAt, &walkIn.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &walkIn, nil
+}
@@ -0,0 +1,277 @@
+package walkin
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/structrpc"
+)
+
+// WalkInServiceName is the fully qualified gRPC name of the walk-in service.
+// Appointments are booked ahead of time; on flash and walk-in days customers
+// queue at the front desk instead and are seated as artists free up.
+const WalkInServiceName = "inkMe.studio.WalkInService"
+
+// refreshInterval is how often WatchQueue resends the queue without changes,
+// so the wait estimates on the front-desk screen keep up with the clock
+const refreshInterval = time.Minute
+
+type AddWalkInRequest struct {
+	StudioID   string `json:"studio_id"`
+	CustomerID string `json:"customer_id"`
+	// Name defaults to the customer's name
+	Name   string `json:"name"`
+	Design string `json:"design"`
+	// ArtistID is the artist the walk-in asked for, if any
+	ArtistID string `json:"artist_id"`
+}
+
+type WalkInOutput struct {
+	ID         string `json:"id"`
+	StudioID   string `json:"studio_id"`
+	CustomerID string `json:"customer_id,omitempty"`
+	Name       string `json:"name"`
+	Design     string `json:"design,omitempty"`
+	ArtistID   string `json:"artist_id,omitempty"`
+	Status     string `json:"status"`
+	JoinedAt   string `json:"joined_at"`
+	StartedAt  string `json:"started_at,omitempty"`
+	FinishedAt string `json:"finished_at,omitempty"`
+	// Position, EstimatedStart and EstimatedWaitMinutes are set on waiting
+	// walk-ins in queue snapshots
+	Position             int    `json:"position,omitempty"`
+	EstimatedStart       string `json:"estimated_start,omitempty"`
+	EstimatedWaitMinutes int    `json:"estimated_wait_minutes,omitempty"`
+}
+
+type QueueRequest struct {
+	StudioID string `json:"studio_id"`
+}
+
+type QueueResponse struct {
+	StudioID  string         `json:"studio_id"`
+	Waiting   []WalkInOutput `json:"waiting"`
+	InService []WalkInOutput `json:"in_service"`
+	UpdatedAt string         `json:"updated_at"`
+}
+
+type AssignWalkInRequest struct {
+	ID string `json:"id"`
+	// ArtistID defaults to the artist the walk-in asked for
+	ArtistID string `json:"artist_id"`
+}
+
+type FinishWalkInRequest struct {
+	ID string `json:"id"`
+	// Status is DONE for a served walk-in or NO_SHOW for one who left
+	Status string `json:"status"`
+}
+
+// WalkInService implements the walk-in queue gRPC service
+type WalkInService struct {
+	repo domain.WalkInRepository
+}
+
+// NewWalkInService creates a new WalkInService
+func NewWalkInService(repo domain.WalkInRepository) *WalkInService {
+	return &WalkInService{repo: repo}
+}
+
+// Register adds the service to a gRPC server
+func (s *WalkInService) Register(server *grpc.Server) {
+	server.RegisterService(structrpc.ServiceDesc(WalkInServiceName,
+		structrpc.Unary(WalkInServiceName, "AddWalkIn", s.AddWalkIn),
+		structrpc.Unary(WalkInServiceName, "GetQueue", s.GetQueue),
+		structrpc.Unary(WalkInServiceName, "AssignWalkIn", s.AssignWalkIn),
+		structrpc.Unary(WalkInServiceName, "FinishWalkIn", s.FinishWalkIn),
+		structrpc.ServerStream("WatchQueue", s.WatchQueue),
+	), s)
+}
+
+// AddWalkIn puts a customer at the end of the queue
+func (s *WalkInService) AddWalkIn(ctx context.Context, req *AddWalkInRequest) (*WalkInOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "AddWalkIn")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	walkIn := &domain.WalkIn{
+		StudioID:   req.StudioID,
+		CustomerID: req.CustomerID,
+		Name:       strings.TrimSpace(req.Name),
+		Design:     req.Design,
+		ArtistID:   req.ArtistID,
+	}
+	if err = s.repo.Add(ctx, tenant, walkIn); err != nil {
+		return nil, domain.ToStatus(err, "failed to add walk-in")
+	}
+
+	span.SetAttributes(attribute.String("walk_in.id", walkIn.ID))
+
+	return walkInOutput(walkIn), nil
+}
+
+// GetQueue returns a snapshot of the studio's queue with wait estimates
+func (s *WalkInService) GetQueue(ctx context.Context, req *QueueRequest) (*QueueResponse, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "GetQueue")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.StudioID == "" {
+		return nil, status.Error(codes.InvalidArgument, "studio_id is required")
+	}
+
+	return s.snapshot(ctx, tenant, req.StudioID)
+}
+
+// AssignWalkIn seats a waiting walk-in with an artist
+func (s *WalkInService) AssignWalkIn(ctx context.Context, req *AssignWalkInRequest) (*WalkInOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "AssignWalkIn")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	walkIn, err := s.repo.Assign(ctx, tenant, req.ID, req.ArtistID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to assign walk-in")
+	}
+
+	span.SetAttributes(attribute.String("walk_in.artist_id", walkIn.ArtistID))
+
+	return walkInOutput(walkIn), nil
+}
+
+// FinishWalkIn marks a walk-in DONE or NO_SHOW, taking it off the queue
+func (s *WalkInService) FinishWalkIn(ctx context.Context, req *FinishWalkInRequest) (*WalkInOutput, error) {
+	ctx, span, tenant, err := domain.StartCall(ctx, "FinishWalkIn")
+	if err != nil {
+		return nil, err
+	}
+	defer span.End()
+
+	if req.ID == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	walkIn, err := s.repo.Finish(ctx, tenant, req.ID, strings.ToUpper(req.Status))
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to finish walk-in")
+	}
+	return walkInOutput(walkIn), nil
+}
+
+// WatchQueue streams the studio's queue to the front-desk screen: a snapshot
+// right away, then another one after every change and at least once a minute,
+// until the client goes away
+func (s *WalkInService) WatchQueue(req *QueueRequest, stream *structrpc.Sender[QueueResponse]) error {
+	ctx, span := otel.Tracer("SyncInk").Start(stream.Context(), "WatchQueue")
+	defer span.End()
+
+	tenant, err := domain.ExtractTenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if req.StudioID == "" {
+		return status.Error(codes.InvalidArgument, "studio_id is required")
+	}
+	span.SetAttributes(attribute.String("tenant", tenant), attribute.String("studio.id", req.StudioID))
+
+	// Subscribe before the first snapshot so no change falls in between
+	changes, err := s.repo.Watch(ctx, tenant, req.StudioID)
+	if err != nil {
+		return domain.ToStatus(err, "failed to watch walk-ins")
+	}
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		queue, err := s.snapshot(ctx, tenant, req.StudioID)
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(queue); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-changes:
+			if !ok {
+				return ctx.Err()
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *WalkInService) snapshot(ctx context.Context, tenant, studioID string) (*QueueResponse, error) {
+	queue, err := s.repo.Queue(ctx, tenant, studioID)
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get walk-in queue")
+	}
+	now := time.Now()
+	throughput, err := s.repo.Throughput(ctx, tenant, studioID, now.Add(-throughputWindow))
+	if err != nil {
+		return nil, domain.ToStatus(err, "failed to get walk-in throughput")
+	}
+
+	starts := estimateStarts(now, queue, throughput)
+	res := &QueueResponse{
+		StudioID:  studioID,
+		Waiting:   []WalkInOutput{},
+		InService: []WalkInOutput{},
+		UpdatedAt: now.Format(time.RFC3339),
+	}
+	for i := range queue {
+		out := walkInOutput(&queue[i])
+		if queue[i].Status == domain.WalkInInService {
+			res.InService = append(res.InService, *out)
+			continue
+		}
+		out.Position = len(res.Waiting) + 1
+		if start, ok := starts[queue[i].ID]; ok {
+			out.EstimatedStart = start.Format(time.RFC3339)
+			out.EstimatedWaitMinutes = int(start.Sub(now).Round(time.Minute) / time.Minute)
+		}
+		res.Waiting = append(res.Waiting, *out)
+	}
+	return res, nil
+}
+
+func walkInOutput(walkIn *domain.WalkIn) *WalkInOutput {
+	out := &WalkInOutput{
+		ID:         walkIn.ID,
+		StudioID:   walkIn.StudioID,
+		CustomerID: walkIn.CustomerID,
+		Name:       walkIn.Name,
+		Design:     walkIn.Design,
+		ArtistID:   walkIn.ArtistID,
+		Status:     walkIn.Status,
+		JoinedAt:   walkIn.JoinedAt.Format(time.RFC3339),
+	}
+	if walkIn.StartedAt != nil {
+		out.StartedAt = walkIn.StartedAt.Format(time.RFC3339)
+	}
+	if walkIn.FinishedAt != nil {
+		out.FinishedAt = walkIn.FinishedAt.Format(time.RFC3339)
+	}
+	return out
+}
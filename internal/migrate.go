@@ -0,0 +1,363 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+// Migration states reported by Migrator.Status
+const (
+	MigrationApplied = "applied"
+	MigrationPending = "pending"
+	MigrationDrifted = "drifted" // applied, but the up file changed since
+	MigrationMissing = "missing" // recorded in _migrations with no file on disk
+)
+
+// Migration is a pair of N_name.up.sql / N_name.down.sql files
+type Migration struct {
+	Version int
+	Name    string // N_name, the key stored in _migrations
+	Up      string
+	Down    string
+}
+
+// MigrationState describes a migration as seen by one database or schema
+type MigrationState struct {
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type appliedMigration struct {
+	hash      string
+	appliedAt time.Time
+}
+
+// Migrator applies and reverts the migrations in one directory of fsys. Each run
+// holds a Postgres advisory lock scoped to the current database and schema so
+// replicas starting together do not migrate the same tenant twice.
+type Migrator struct {
+	conn *pgxpool.Pool
+	fsys fs.FS
+	dir  string
+}
+
+// NewMigrator creates a Migrator for the migrations in dir
+func NewMigrator(conn *pgxpool.Pool, fsys fs.FS, dir string) *Migrator {
+	return &Migrator{conn: conn, fsys: fsys, dir: dir}
+}
+
+// Up applies every pending migration in version order and returns their names.
+// With dryRun set nothing is executed and the pending names are returned.
+func (m *Migrator) Up(ctx context.Context, dryRun bool) ([]string, error) {
+	log := logger.Log
+	migrations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	err = m.withLock(ctx, func(conn *pgxpool.Conn, applied map[string]appliedMigration) error {
+		for _, migration := range migrations {
+			contentHash := migrationHash(migration.Up)
+			if prev, ok := applied[migration.Name]; ok {
+				if prev.hash != contentHash {
+					return fmt.Errorf("hash mismatch for migration %s", migration.Name)
+				}
+				continue
+			}
+
+			names = append(names, migration.Name)
+			if dryRun {
+				continue
+			}
+
+			if containsCreateDatabase(migration.Up) {
+				// CREATE DATABASE cannot run inside a transaction
+				if _, err := conn.Exec(ctx, migration.Up); err != nil {
+					return fmt.Errorf("failed to apply migration %s: %w", migration.Name, err)
+				}
+				if _, err := conn.Exec(ctx, `insert into _migrations (name, hash) values ($1, $2)`,
+					migration.Name, contentHash); err != nil {
+					return fmt.Errorf("failed to record migration %s: %w", migration.Name, err)
+				}
+			} else {
+				err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+					if _, err := tx.Exec(ctx, migration.Up); err != nil {
+						return err
+					}
+					_, err := tx.Exec(ctx, `insert into _migrations (name, hash) values ($1, $2)`,
+						migration.Name, contentHash)
+					return err
+				})
+				if err != nil {
+					return fmt.Errorf("failed to apply migration %s: %w", migration.Name, err)
+				}
+			}
+			log.Info(migration.Name+" applied", zap.String("dir", m.dir))
+		}
+		return nil
+	})
+	return names, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns their
+// names. With dryRun set nothing is executed.
+func (m *Migrator) Down(ctx context.Context, steps int, dryRun bool) ([]string, error) {
+	log := logger.Log
+	if steps <= 0 {
+		return nil, errors.New("steps must be positive")
+	}
+
+	migrations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	err = m.withLock(ctx, func(conn *pgxpool.Conn, applied map[string]appliedMigration) error {
+		for i := len(migrations) - 1; i >= 0 && len(names) < steps; i-- {
+			migration := migrations[i]
+			if _, ok := applied[migration.Name]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %s has no down file", migration.Name)
+			}
+
+			names = append(names, migration.Name)
+			if dryRun {
+				continue
+			}
+
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `delete from _migrations where name = $1`, migration.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %s: %w", migration.Name, err)
+			}
+			log.Info(migration.Name+" reverted", zap.String("dir", m.dir))
+		}
+		return nil
+	})
+	return names, err
+}
+
+// Status reports every migration as applied, pending, drifted or missing. It
+// only reads: no lock is taken and a database without _migrations has every
+// migration pending.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationState, error) {
+	migrations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	if err = m.conn.QueryRow(ctx, `select to_regclass('_migrations') is not null`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up migrations table: %w", err)
+	}
+	applied := make(map[string]appliedMigration)
+	if exists {
+		if applied, err = readApplied(ctx, m.conn); err != nil {
+			return nil, err
+		}
+	}
+
+	var states []MigrationState
+	known := make(map[string]bool, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Name] = true
+		state := MigrationState{Name: migration.Name, Status: MigrationPending}
+		if prev, ok := applied[migration.Name]; ok {
+			appliedAt := prev.appliedAt
+			state.AppliedAt = &appliedAt
+			state.Status = MigrationApplied
+			if prev.hash != migrationHash(migration.Up) {
+				state.Status = MigrationDrifted
+			}
+		}
+		states = append(states, state)
+	}
+
+	var missing []string
+	for name := range applied {
+		if !known[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return migrationVersion(missing[i]) < migrationVersion(missing[j]) })
+	for _, name := range missing {
+		appliedAt := applied[name].appliedAt
+		states = append(states, MigrationState{Name: name, Status: MigrationMissing, AppliedAt: &appliedAt})
+	}
+	return states, nil
+}
+
+// withLock runs fn on a single connection holding the migration advisory lock,
+// after making sure _migrations exists and uses current naming
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn, applied map[string]appliedMigration) error) error {
+	conn, err := m.conn.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire migration connection: %w", err)
+	}
+	defer conn.Release()
+
+	// Session-level lock keyed by database and schema; released before the
+	// connection goes back to the pool
+	lockKey := "ink_migrations." + m.dir
+	if _, err = conn.Exec(ctx, `select pg_advisory_lock(hashtext($1 || '.' || current_schema()))`, lockKey); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		_, _ = conn.Exec(context.WithoutCancel(ctx),
+			`select pg_advisory_unlock(hashtext($1 || '.' || current_schema()))`, lockKey)
+	}()
+
+	_, err = conn.Exec(ctx, `
+		create table if not exists _migrations (
+			name text primary key,
+			hash text not null,
+			created_at timestamp default now()
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	// Rows written before up/down pairs were introduced are named after the file
+	if _, err = conn.Exec(ctx, `
+		update _migrations set name = regexp_replace(name, '(\.up)?\.sql$', '')
+		where name like '%.sql'
+	`); err != nil {
+		return fmt.Errorf("failed to normalise migration names: %w", err)
+	}
+
+	applied, err := readApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
+
+// readApplied loads _migrations keyed by migration name. Names recorded before
+// up/down pairs were introduced are normalised in memory only.
+func readApplied(ctx context.Context, q interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}) (map[string]appliedMigration, error) {
+	rows, _ := q.Query(ctx, `select name, hash, created_at from _migrations`)
+	var (
+		name, hash string
+		createdAt  time.Time
+	)
+	applied := make(map[string]appliedMigration)
+	_, err := pgx.ForEachRow(rows, []any{&name, &hash, &createdAt}, func() error {
+		applied[strings.TrimSuffix(strings.TrimSuffix(name, ".sql"), ".up")] = appliedMigration{hash: hash, appliedAt: createdAt}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// load reads the migration pairs in dir ordered by their numeric prefix
+func (m *Migrator) load() ([]Migration, error) {
+	entries, err := fs.ReadDir(m.fsys, m.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var name string
+		var down bool
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			name = strings.TrimSuffix(fileName, ".up.sql")
+		case strings.HasSuffix(fileName, ".down.sql"):
+			name, down = strings.TrimSuffix(fileName, ".down.sql"), true
+		default:
+			return nil, fmt.Errorf("migration %s must end in .up.sql or .down.sql", fileName)
+		}
+
+		version := migrationVersion(name)
+		if version < 0 {
+			return nil, fmt.Errorf("migration %s must start with a numeric version", fileName)
+		}
+
+		contents, err := fs.ReadFile(m.fsys, m.dir+"/"+fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byName[name]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byName[name] = migration
+		}
+		if down {
+			migration.Down = string(contents)
+		} else {
+			migration.Up = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byName))
+	seen := make(map[int]string, len(byName))
+	for _, migration := range byName {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %s has no up file", migration.Name)
+		}
+		if other, ok := seen[migration.Version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, migration.Name, migration.Version)
+		}
+		seen[migration.Version] = migration.Name
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// migrationVersion parses the numeric prefix of "N_name", or returns -1
+func migrationVersion(name string) int {
+	prefix, _, _ := strings.Cut(name, "_")
+	version, err := strconv.Atoi(prefix)
+	if err != nil {
+		return -1
+	}
+	return version
+}
+
+// migrationHash matches the hashes recorded by earlier releases
+func migrationHash(contents string) string {
+	return fmt.Sprintf("%x", hashVal([]byte(contents)))
+}
+
+// NewTenantMigrator connects to a tenant's database, or its schema in schema
+// mode, and returns a Migrator for the embedded tenant migrations together with a
+// func that closes the connection
+func NewTenantMigrator(cfg *config.Config, tenant *config.TenantConfig) (*Migrator, func(), error) {
+	pool, err := openTenantPool(cfg, tenant.Database)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to tenant %s: %w", tenant.Subdomain, err)
+	}
+	return NewMigrator(pool, migrationFS, "migrations"), pool.Close, nil
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS customer_history;
+DROP TABLE IF EXISTS customer_notes;
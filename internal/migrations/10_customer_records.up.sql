@@ -0,0 +1,36 @@
+-- 12. customer_notes: Private notes about a customer (preferences, allergies, ...)
+CREATE TABLE customer_notes (
+                              id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                              customer_id   UUID NOT NULL,
+                              content       TEXT NOT NULL,
+                              created_by    UUID,                       -- the staff user who wrote the note
+                              created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+                              CONSTRAINT fk_customer_note
+                                FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE CASCADE,
+                              CONSTRAINT fk_note_author
+                                FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_customer_notes_customer ON customer_notes (customer_id, created_at);
+
+-- 13. customer_history: Audit trail of changes and interactions per customer
+CREATE TABLE customer_history (
+                                id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                customer_id     UUID NOT NULL,
+                                type            VARCHAR(50) NOT NULL,   -- e.g. 'created', 'updated', 'archived', 'note'
+                                description     TEXT,
+                                artist_id       UUID,
+                                appointment_id  UUID,
+                                created_by      UUID,
+                                created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                CONSTRAINT fk_customer_history
+                                  FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE CASCADE,
+                                CONSTRAINT fk_history_artist
+                                  FOREIGN KEY (artist_id) REFERENCES users (id) ON DELETE SET NULL,
+                                CONSTRAINT fk_history_appointment
+                                  FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE SET NULL,
+                                CONSTRAINT fk_history_author
+                                  FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_customer_history_customer ON customer_history (customer_id, created_at);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_appointments_customer;
+DROP INDEX IF EXISTS idx_appointments_studio_start;
+
+ALTER TABLE appointments
+  DROP CONSTRAINT IF EXISTS no_artist_double_booking,
+  DROP CONSTRAINT IF EXISTS check_appointment_status,
+  DROP CONSTRAINT IF EXISTS check_appointment_time,
+  DROP COLUMN IF EXISTS legacy_conflict;
@@ -0,0 +1,52 @@
+-- Installed in public so every schema-mode tenant of a shared database can use
+-- it and dropping one tenant's schema does not take it with it
+CREATE EXTENSION IF NOT EXISTS btree_gist WITH SCHEMA public;
+
+-- Status values are compared in upper case from now on. Legacy spellings map
+-- onto the four statuses; any other value counts as a booking and is kept in
+-- the notes.
+UPDATE appointments SET status = upper(btrim(status));
+UPDATE appointments SET status = 'CANCELED' WHERE status = 'CANCELLED';
+UPDATE appointments SET status = 'NO_SHOW' WHERE status IN ('NOSHOW', 'NO-SHOW', 'NO SHOW');
+UPDATE appointments SET status = 'COMPLETED' WHERE status IN ('COMPLETE', 'DONE');
+UPDATE appointments
+SET notes = concat_ws(E'\n', notes, 'Legacy status: ' || status), status = 'SCHEDULED'
+WHERE status NOT IN ('SCHEDULED', 'COMPLETED', 'CANCELED', 'NO_SHOW');
+
+-- Scheduled rows that already overlap, or end before they start, cannot be
+-- resolved without the studio. They are flagged and reported, and stay out of
+-- the overlap constraint until they are rescheduled or reassigned, which
+-- clears the flag.
+ALTER TABLE appointments ADD COLUMN legacy_conflict BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE appointments a
+SET legacy_conflict = true
+WHERE a.status = 'SCHEDULED' AND a.artist_id IS NOT NULL
+  AND (a.end_time <= a.start_time OR EXISTS (
+    SELECT 1 FROM appointments b
+    WHERE b.id <> a.id AND b.artist_id = a.artist_id AND b.status = 'SCHEDULED'
+      AND b.end_time > b.start_time
+      AND b.start_time < a.end_time AND a.start_time < b.end_time));
+
+DO $$
+DECLARE
+  conflicts int;
+BEGIN
+  SELECT count(*) INTO conflicts FROM appointments WHERE legacy_conflict;
+  IF conflicts > 0 THEN
+    RAISE WARNING '% scheduled appointments overlap or end before they start; they are flagged with legacy_conflict', conflicts;
+  END IF;
+END $$;
+
+-- NOT VALID leaves rows that end before they start alone
+ALTER TABLE appointments
+  ADD CONSTRAINT check_appointment_time CHECK (end_time > start_time) NOT VALID,
+  ADD CONSTRAINT check_appointment_status
+    CHECK (status IN ('SCHEDULED', 'COMPLETED', 'CANCELED', 'NO_SHOW')),
+  -- An artist cannot have two scheduled appointments that overlap
+  ADD CONSTRAINT no_artist_double_booking
+    EXCLUDE USING gist (artist_id WITH =, tstzrange(start_time, end_time, '[)') WITH &&)
+    WHERE (status = 'SCHEDULED' AND artist_id IS NOT NULL AND NOT legacy_conflict);
+
+CREATE INDEX idx_appointments_studio_start ON appointments (studio_id, start_time);
+CREATE INDEX idx_appointments_customer ON appointments (customers_id, start_time);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS artist_time_off;
+DROP TABLE IF EXISTS artist_schedule_overrides;
+DROP TABLE IF EXISTS artist_working_hours;
+
+ALTER TABLE studio_settings
+  DROP COLUMN IF EXISTS buffer_minutes,
+  DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,48 @@
+-- Scheduling settings of the studio; business_hours is a JSON object keyed by
+-- lower-case weekday, e.g. {"monday": [{"open": "10:00", "close": "19:00"}]}
+ALTER TABLE studio_settings
+  ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+  ADD COLUMN buffer_minutes INTEGER NOT NULL DEFAULT 0 CHECK (buffer_minutes >= 0);
+
+-- 14. artist_working_hours: Weekly shifts of an artist in the studio's time zone
+CREATE TABLE artist_working_hours (
+                                    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                    artist_id     UUID NOT NULL,
+                                    weekday       SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6), -- 0 = Sunday
+                                    start_time    TIME NOT NULL,
+                                    end_time      TIME NOT NULL,              -- '24:00' ends at midnight
+                                    CONSTRAINT fk_artist_working_hours
+                                      FOREIGN KEY (artist_id) REFERENCES users (id) ON DELETE CASCADE,
+                                    CONSTRAINT check_working_hours CHECK (end_time > start_time)
+);
+
+CREATE INDEX idx_artist_working_hours_artist ON artist_working_hours (artist_id, weekday);
+
+-- 15. artist_schedule_overrides: Replaces the weekly shifts on one date; no hours = day off
+CREATE TABLE artist_schedule_overrides (
+                                         artist_id     UUID NOT NULL,
+                                         date          DATE NOT NULL,
+                                         start_time    TIME,
+                                         end_time      TIME,
+                                         created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                         PRIMARY KEY (artist_id, date),
+                                         CONSTRAINT fk_artist_schedule_override
+                                           FOREIGN KEY (artist_id) REFERENCES users (id) ON DELETE CASCADE,
+                                         CONSTRAINT check_override_hours CHECK (
+                                           (start_time IS NULL AND end_time IS NULL) OR end_time > start_time)
+);
+
+-- 16. artist_time_off: Absolute periods an artist cannot be booked, e.g. holidays
+CREATE TABLE artist_time_off (
+                               id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                               artist_id     UUID NOT NULL,
+                               starts_at     TIMESTAMPTZ NOT NULL,
+                               ends_at       TIMESTAMPTZ NOT NULL,
+                               reason        TEXT,
+                               created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+                               CONSTRAINT fk_artist_time_off
+                                 FOREIGN KEY (artist_id) REFERENCES users (id) ON DELETE CASCADE,
+                               CONSTRAINT check_time_off CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX idx_artist_time_off_artist ON artist_time_off (artist_id, starts_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS booking_requests;
+DROP TABLE IF EXISTS notification_devices;
+DROP TABLE IF EXISTS notifications;
@@ -0,0 +1,68 @@
+-- 17. notifications: In-app messages for staff and the outbox for customers
+CREATE TABLE notifications (
+                             id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                             user_id        UUID,                   -- staff recipient
+                             customer_id    UUID,                   -- customer recipient
+                             kind           VARCHAR(50) NOT NULL,   -- e.g. 'booking_request.approved'
+                             title          VARCHAR(255) NOT NULL,
+                             body           TEXT,
+                             data           JSONB NOT NULL DEFAULT '{}'::jsonb,
+                             read_at        TIMESTAMPTZ,
+                             created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+                             CONSTRAINT fk_notification_user
+                               FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+                             CONSTRAINT fk_notification_customer
+                               FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE CASCADE,
+                             CONSTRAINT check_notification_recipient CHECK (num_nonnulls(user_id, customer_id) = 1)
+);
+
+CREATE INDEX idx_notifications_user ON notifications (user_id, created_at) WHERE user_id IS NOT NULL;
+CREATE INDEX idx_notifications_customer ON notifications (customer_id, created_at) WHERE customer_id IS NOT NULL;
+
+-- 18. notification_devices: Push tokens registered through NotificationService.Subscribe
+CREATE TABLE notification_devices (
+                                    user_id        UUID NOT NULL,
+                                    device_token   VARCHAR(512) NOT NULL,
+                                    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                    PRIMARY KEY (user_id, device_token),
+                                    CONSTRAINT fk_notification_device_user
+                                      FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- 19. booking_requests: A client's request for a slot, pending the artist's answer
+CREATE TABLE booking_requests (
+                                id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                studio_id        UUID NOT NULL,
+                                customer_id      UUID NOT NULL,
+                                artist_id        UUID,                 -- NULL = any artist of the studio
+                                requested_start  TIMESTAMPTZ NOT NULL,
+                                requested_end    TIMESTAMPTZ NOT NULL,
+                                description      TEXT NOT NULL,        -- the design the client has in mind
+                                placement        VARCHAR(100),         -- e.g. 'left forearm'
+                                size             VARCHAR(50),          -- e.g. '10x15 cm'
+                                reference_images TEXT[] NOT NULL DEFAULT '{}',
+                                status           VARCHAR(50) NOT NULL DEFAULT 'PENDING',
+                                proposed_start   TIMESTAMPTZ,          -- the artist's counter-proposal
+                                proposed_end     TIMESTAMPTZ,
+                                response_note    TEXT,                 -- reason for a decline or counter-proposal
+                                appointment_id   UUID,                 -- set once approved
+                                created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                updated_at       TIMESTAMPTZ,
+                                CONSTRAINT fk_booking_request_studio
+                                  FOREIGN KEY (studio_id) REFERENCES studios (id) ON DELETE CASCADE,
+                                CONSTRAINT fk_booking_request_customer
+                                  FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE CASCADE,
+                                CONSTRAINT fk_booking_request_artist
+                                  FOREIGN KEY (artist_id) REFERENCES users (id) ON DELETE SET NULL,
+                                CONSTRAINT fk_booking_request_appointment
+                                  FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE SET NULL,
+                                CONSTRAINT check_booking_request_range CHECK (requested_end > requested_start),
+                                CONSTRAINT check_booking_request_proposal CHECK (
+                                  (proposed_start IS NULL AND proposed_end IS NULL) OR proposed_end > proposed_start),
+                                CONSTRAINT check_booking_request_status CHECK (
+                                  status IN ('PENDING', 'COUNTER_PROPOSED', 'APPROVED', 'DECLINED', 'WITHDRAWN'))
+);
+
+CREATE INDEX idx_booking_requests_studio_status ON booking_requests (studio_id, status, requested_start);
+CREATE INDEX idx_booking_requests_artist ON booking_requests (artist_id, status);
+CREATE INDEX idx_booking_requests_customer ON booking_requests (customer_id);
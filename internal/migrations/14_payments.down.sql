@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS payments;
+
+ALTER TABLE appointments
+  DROP COLUMN IF EXISTS deposit_status,
+  DROP COLUMN IF EXISTS deposit_currency,
+  DROP COLUMN IF EXISTS deposit_amount;
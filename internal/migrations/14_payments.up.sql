@@ -0,0 +1,72 @@
+-- Deposit state of an appointment; amounts are in minor units of the currency,
+-- e.g. cents. deposit_status is NONE, REQUIRED, PAID, REFUNDED or FORFEITED.
+ALTER TABLE appointments
+  ADD COLUMN deposit_amount   BIGINT NOT NULL DEFAULT 0 CHECK (deposit_amount >= 0),
+  ADD COLUMN deposit_currency CHAR(3),
+  ADD COLUMN deposit_status   VARCHAR(20) NOT NULL DEFAULT 'NONE'
+    CHECK (deposit_status IN ('NONE', 'REQUIRED', 'PAID', 'REFUNDED', 'FORFEITED'));
+
+-- 20. payments: Money collected through the payment provider
+CREATE TABLE payments (
+                        id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                        studio_id        UUID NOT NULL,
+                        customer_id      UUID NOT NULL,
+                        appointment_id   UUID,
+                        kind             VARCHAR(20) NOT NULL,   -- 'DEPOSIT' or 'PAYMENT'
+                        amount           BIGINT NOT NULL CHECK (amount > 0),
+                        refunded_amount  BIGINT NOT NULL DEFAULT 0,
+                        currency         CHAR(3) NOT NULL,
+                        status           VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+                        provider         VARCHAR(50) NOT NULL,
+                        provider_ref     VARCHAR(255),           -- the provider's payment intent id
+                        failure_reason   TEXT,
+                        created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+                        updated_at       TIMESTAMPTZ,
+                        CONSTRAINT fk_payment_studio
+                          FOREIGN KEY (studio_id) REFERENCES studios (id) ON DELETE CASCADE,
+                        CONSTRAINT fk_payment_customer
+                          FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE RESTRICT,
+                        CONSTRAINT fk_payment_appointment
+                          FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE SET NULL,
+                        CONSTRAINT check_payment_kind CHECK (kind IN ('DEPOSIT', 'PAYMENT')),
+                        CONSTRAINT check_payment_status CHECK (status IN ('PENDING', 'SUCCEEDED', 'FAILED', 'REFUNDED')),
+                        CONSTRAINT check_payment_refund CHECK (refunded_amount BETWEEN 0 AND amount)
+);
+
+CREATE INDEX idx_payments_customer ON payments (customer_id, created_at);
+CREATE INDEX idx_payments_appointment ON payments (appointment_id);
+CREATE UNIQUE INDEX idx_payments_provider_ref ON payments (provider, provider_ref) WHERE provider_ref IS NOT NULL;
+
+-- 21. ledger_entries: Append-only money movements per customer. Amounts are
+-- the customer's credit: payments and deposits are positive; charges, refunds
+-- and forfeited deposits negative. The sum per currency is the balance.
+CREATE TABLE ledger_entries (
+                              id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                              studio_id        UUID NOT NULL,
+                              customer_id      UUID NOT NULL,
+                              appointment_id   UUID,
+                              payment_id       UUID,
+                              type             VARCHAR(20) NOT NULL,
+                              amount           BIGINT NOT NULL,
+                              currency         CHAR(3) NOT NULL,
+                              description      TEXT,
+                              created_by       UUID,
+                              created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+                              CONSTRAINT fk_ledger_studio
+                                FOREIGN KEY (studio_id) REFERENCES studios (id) ON DELETE CASCADE,
+                              CONSTRAINT fk_ledger_customer
+                                FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE RESTRICT,
+                              CONSTRAINT fk_ledger_appointment
+                                FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE SET NULL,
+                              CONSTRAINT fk_ledger_payment
+                                FOREIGN KEY (payment_id) REFERENCES payments (id) ON DELETE RESTRICT,
+                              CONSTRAINT fk_ledger_author
+                                FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL,
+                              CONSTRAINT check_ledger_type CHECK (type IN ('DEPOSIT', 'PAYMENT', 'CHARGE', 'REFUND', 'FORFEIT')),
+                              CONSTRAINT check_ledger_sign CHECK (
+                                (type IN ('DEPOSIT', 'PAYMENT') AND amount > 0) OR
+                                (type IN ('CHARGE', 'REFUND', 'FORFEIT') AND amount < 0))
+);
+
+CREATE INDEX idx_ledger_customer ON ledger_entries (customer_id, currency, created_at);
+CREATE INDEX idx_ledger_appointment ON ledger_entries (appointment_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS appointment_reminders;
+
+ALTER TABLE studio_settings
+  DROP COLUMN IF EXISTS artist_reminder_time,
+  DROP COLUMN IF EXISTS reminder_lead_minutes;
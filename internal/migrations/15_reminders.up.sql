@@ -0,0 +1,25 @@
+-- Reminder settings of the studio: clients are reminded the listed number of
+-- minutes before each appointment, artists at artist_reminder_time on the day
+-- of the appointment, both in the studio's time zone
+ALTER TABLE studio_settings
+  ADD COLUMN reminder_lead_minutes INTEGER[] NOT NULL DEFAULT '{1440}'
+    CHECK (0 < ALL (reminder_lead_minutes)),
+  ADD COLUMN artist_reminder_time  TIME NOT NULL DEFAULT '08:00';
+
+-- 22. appointment_reminders: Queue of reminders; the unique key deduplicates sends
+CREATE TABLE appointment_reminders (
+                                     id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                     appointment_id  UUID NOT NULL,
+                                     recipient       VARCHAR(10) NOT NULL CHECK (recipient IN ('CLIENT', 'ARTIST')),
+                                     starts_at       TIMESTAMPTZ NOT NULL,   -- appointment start the reminder was planned for
+                                     due_at          TIMESTAMPTZ NOT NULL,
+                                     status          VARCHAR(10) NOT NULL DEFAULT 'PENDING'
+                                       CHECK (status IN ('PENDING', 'SENT', 'SKIPPED')),
+                                     sent_at         TIMESTAMPTZ,
+                                     created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                     CONSTRAINT fk_appointment_reminder
+                                       FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE CASCADE,
+                                     CONSTRAINT unique_appointment_reminder UNIQUE (appointment_id, recipient, starts_at, due_at)
+);
+
+CREATE INDEX idx_appointment_reminders_due ON appointment_reminders (due_at) WHERE status = 'PENDING';
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_appointments_project_session;
+
+ALTER TABLE appointments
+  DROP COLUMN IF EXISTS session_number,
+  DROP COLUMN IF EXISTS project_id;
+
+DROP TABLE IF EXISTS projects;
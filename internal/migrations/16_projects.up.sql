@@ -0,0 +1,32 @@
+-- 23. projects: Work that takes several sessions for one customer and artist,
+-- e.g. a sleeve. Progress is derived from the statuses of its appointments.
+CREATE TABLE projects (
+                        id                    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                        studio_id             UUID NOT NULL,
+                        customer_id           UUID NOT NULL,
+                        artist_id             UUID NOT NULL,
+                        title                 VARCHAR(255) NOT NULL,
+                        description           TEXT,
+                        recurrence_frequency  VARCHAR(10)                -- 'DAILY', 'WEEKLY' or 'MONTHLY'
+                          CHECK (recurrence_frequency IN ('DAILY', 'WEEKLY', 'MONTHLY')),
+                        recurrence_interval   INTEGER CHECK (recurrence_interval > 0),
+                        created_at            TIMESTAMPTZ NOT NULL DEFAULT now(),
+                        updated_at            TIMESTAMPTZ,
+                        CONSTRAINT fk_project_studio
+                          FOREIGN KEY (studio_id) REFERENCES studios (id) ON DELETE CASCADE,
+                        CONSTRAINT fk_project_customer
+                          FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE CASCADE,
+                        CONSTRAINT fk_project_artist
+                          FOREIGN KEY (artist_id) REFERENCES users (id)
+);
+
+CREATE INDEX idx_projects_customer ON projects (customer_id);
+CREATE INDEX idx_projects_artist ON projects (artist_id);
+
+-- Sessions of a project are numbered from 1 in the order they were planned
+ALTER TABLE appointments
+  ADD COLUMN project_id     UUID REFERENCES projects (id) ON DELETE SET NULL,
+  ADD COLUMN session_number INTEGER CHECK (session_number > 0);
+
+CREATE UNIQUE INDEX idx_appointments_project_session ON appointments (project_id, session_number)
+  WHERE project_id IS NOT NULL;
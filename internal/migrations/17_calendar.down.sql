@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS appointments_busy_block_update ON appointments;
+DROP TRIGGER IF EXISTS appointments_busy_block_insert ON appointments;
+DROP FUNCTION IF EXISTS reject_busy_block_overlap();
+
+DROP TABLE IF EXISTS calendar_busy_blocks;
+DROP TABLE IF EXISTS calendar_feeds;
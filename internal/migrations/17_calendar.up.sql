@@ -0,0 +1,54 @@
+-- 24. calendar_feeds: Token of each artist's iCalendar feed, stored as a SHA-256 hash
+CREATE TABLE calendar_feeds (
+                              artist_id     UUID PRIMARY KEY,
+                              token_hash    CHAR(64) NOT NULL UNIQUE,
+                              created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+                              CONSTRAINT fk_calendar_feed_artist
+                                FOREIGN KEY (artist_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- 25. calendar_busy_blocks: Events imported from an artist's external calendars
+CREATE TABLE calendar_busy_blocks (
+                                    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                    artist_id     UUID NOT NULL,
+                                    source        VARCHAR(100) NOT NULL,  -- e.g. 'google', replaced on re-import
+                                    uid           TEXT,                   -- UID of the event in the source calendar
+                                    summary       TEXT,
+                                    starts_at     TIMESTAMPTZ NOT NULL,
+                                    ends_at       TIMESTAMPTZ NOT NULL,
+                                    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                    CONSTRAINT fk_calendar_busy_artist
+                                      FOREIGN KEY (artist_id) REFERENCES users (id) ON DELETE CASCADE,
+                                    CONSTRAINT check_busy_block CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX idx_calendar_busy_blocks_artist ON calendar_busy_blocks
+  USING gist (artist_id, tstzrange(starts_at, ends_at, '[)'));
+
+-- Scheduled appointments cannot overlap a busy block of their artist. Blocks
+-- imported later leave existing appointments alone until they are moved.
+CREATE FUNCTION reject_busy_block_overlap() RETURNS trigger AS $$
+BEGIN
+  IF NEW.status = 'SCHEDULED' AND NEW.artist_id IS NOT NULL AND EXISTS (
+    SELECT 1 FROM calendar_busy_blocks b
+    WHERE b.artist_id = NEW.artist_id
+      AND tstzrange(b.starts_at, b.ends_at, '[)') && tstzrange(NEW.start_time, NEW.end_time, '[)')
+  ) THEN
+    RAISE EXCEPTION 'artist % is busy in an external calendar at that time', NEW.artist_id
+      USING ERRCODE = 'exclusion_violation';
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER appointments_busy_block_insert
+  BEFORE INSERT ON appointments
+  FOR EACH ROW EXECUTE FUNCTION reject_busy_block_overlap();
+
+CREATE TRIGGER appointments_busy_block_update
+  BEFORE UPDATE OF start_time, end_time, artist_id ON appointments
+  FOR EACH ROW
+  WHEN (OLD.start_time IS DISTINCT FROM NEW.start_time
+    OR OLD.end_time IS DISTINCT FROM NEW.end_time
+    OR OLD.artist_id IS DISTINCT FROM NEW.artist_id)
+  EXECUTE FUNCTION reject_busy_block_overlap();
@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS appointments_held_slot_update ON appointments;
+DROP TRIGGER IF EXISTS appointments_held_slot_insert ON appointments;
+DROP FUNCTION IF EXISTS reject_held_slot_overlap();
+
+DROP TRIGGER IF EXISTS appointments_waitlist_opening ON appointments;
+DROP FUNCTION IF EXISTS record_waitlist_opening();
+
+DROP TABLE IF EXISTS waitlist_offers;
+DROP TABLE IF EXISTS waitlist_openings;
+DROP TABLE IF EXISTS waitlist_entries;
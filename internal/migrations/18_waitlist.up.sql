@@ -0,0 +1,132 @@
+-- 26. waitlist_entries: Customers waiting for a slot with an artist within a
+-- window of dates. Higher priority is offered first, then the oldest entry.
+CREATE TABLE waitlist_entries (
+                                id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                studio_id         UUID NOT NULL,
+                                customer_id       UUID NOT NULL,
+                                artist_id         UUID NOT NULL,
+                                window_start      TIMESTAMPTZ NOT NULL,
+                                window_end        TIMESTAMPTZ NOT NULL,
+                                duration_minutes  INTEGER NOT NULL CHECK (duration_minutes > 0),
+                                priority          INTEGER NOT NULL DEFAULT 0,
+                                notes             TEXT,
+                                status            VARCHAR(20) NOT NULL DEFAULT 'WAITING',
+                                appointment_id    UUID,                 -- set once an offer is accepted
+                                created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                updated_at        TIMESTAMPTZ,
+                                CONSTRAINT fk_waitlist_studio
+                                  FOREIGN KEY (studio_id) REFERENCES studios (id) ON DELETE CASCADE,
+                                CONSTRAINT fk_waitlist_customer
+                                  FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE CASCADE,
+                                CONSTRAINT fk_waitlist_artist
+                                  FOREIGN KEY (artist_id) REFERENCES users (id) ON DELETE CASCADE,
+                                CONSTRAINT fk_waitlist_appointment
+                                  FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE SET NULL,
+                                CONSTRAINT check_waitlist_window CHECK (window_end > window_start),
+                                CONSTRAINT check_waitlist_status CHECK (status IN ('WAITING', 'OFFERED', 'BOOKED', 'REMOVED'))
+);
+
+CREATE INDEX idx_waitlist_entries_artist ON waitlist_entries (artist_id, status, priority DESC, created_at);
+CREATE INDEX idx_waitlist_entries_customer ON waitlist_entries (customer_id);
+
+-- 27. waitlist_openings: Slots freed by cancelled or rescheduled appointments,
+-- offered to the waitlist until one is taken or nobody matches
+CREATE TABLE waitlist_openings (
+                                 id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                 studio_id       UUID NOT NULL,
+                                 artist_id       UUID NOT NULL,
+                                 appointment_id  UUID,                   -- the appointment that freed the slot
+                                 starts_at       TIMESTAMPTZ NOT NULL,
+                                 ends_at         TIMESTAMPTZ NOT NULL,
+                                 status          VARCHAR(20) NOT NULL DEFAULT 'OPEN',
+                                 created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                 closed_at       TIMESTAMPTZ,
+                                 CONSTRAINT fk_waitlist_opening_studio
+                                   FOREIGN KEY (studio_id) REFERENCES studios (id) ON DELETE CASCADE,
+                                 CONSTRAINT fk_waitlist_opening_artist
+                                   FOREIGN KEY (artist_id) REFERENCES users (id) ON DELETE CASCADE,
+                                 CONSTRAINT fk_waitlist_opening_appointment
+                                   FOREIGN KEY (appointment_id) REFERENCES appointments (id) ON DELETE SET NULL,
+                                 CONSTRAINT check_waitlist_opening_range CHECK (ends_at > starts_at),
+                                 CONSTRAINT check_waitlist_opening_status CHECK (status IN ('OPEN', 'FILLED', 'CLOSED'))
+);
+
+CREATE INDEX idx_waitlist_openings_open ON waitlist_openings (created_at) WHERE status = 'OPEN';
+
+-- 28. waitlist_offers: An opening offered to one waitlist entry. A PENDING
+-- offer holds its slot until it expires.
+CREATE TABLE waitlist_offers (
+                               id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                               opening_id      UUID NOT NULL,
+                               entry_id        UUID NOT NULL,
+                               artist_id       UUID NOT NULL,
+                               starts_at       TIMESTAMPTZ NOT NULL,
+                               ends_at         TIMESTAMPTZ NOT NULL,
+                               status          VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+                               expires_at      TIMESTAMPTZ NOT NULL,
+                               responded_at    TIMESTAMPTZ,
+                               created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+                               CONSTRAINT fk_waitlist_offer_opening
+                                 FOREIGN KEY (opening_id) REFERENCES waitlist_openings (id) ON DELETE CASCADE,
+                               CONSTRAINT fk_waitlist_offer_entry
+                                 FOREIGN KEY (entry_id) REFERENCES waitlist_entries (id) ON DELETE CASCADE,
+                               CONSTRAINT fk_waitlist_offer_artist
+                                 FOREIGN KEY (artist_id) REFERENCES users (id) ON DELETE CASCADE,
+                               CONSTRAINT check_waitlist_offer_range CHECK (ends_at > starts_at),
+                               CONSTRAINT check_waitlist_offer_status
+                                 CHECK (status IN ('PENDING', 'ACCEPTED', 'DECLINED', 'EXPIRED', 'WITHDRAWN')),
+                               -- An entry is offered each opening once
+                               CONSTRAINT unique_waitlist_offer UNIQUE (opening_id, entry_id)
+);
+
+CREATE INDEX idx_waitlist_offers_entry ON waitlist_offers (entry_id);
+CREATE INDEX idx_waitlist_offers_pending ON waitlist_offers
+  USING gist (artist_id, tstzrange(starts_at, ends_at, '[)')) WHERE status = 'PENDING';
+
+-- Record the slot of an upcoming scheduled appointment when it is cancelled
+-- or moved, so the waitlist can be offered it
+CREATE FUNCTION record_waitlist_opening() RETURNS trigger AS $$
+BEGIN
+  IF OLD.status = 'SCHEDULED' AND OLD.artist_id IS NOT NULL AND OLD.start_time > now()
+    AND (NEW.status = 'CANCELED'
+      OR (NEW.status = 'SCHEDULED' AND (OLD.start_time IS DISTINCT FROM NEW.start_time
+        OR OLD.end_time IS DISTINCT FROM NEW.end_time
+        OR OLD.artist_id IS DISTINCT FROM NEW.artist_id))) THEN
+    INSERT INTO waitlist_openings (studio_id, artist_id, appointment_id, starts_at, ends_at)
+    VALUES (OLD.studio_id, OLD.artist_id, OLD.id, OLD.start_time, OLD.end_time);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER appointments_waitlist_opening
+  AFTER UPDATE OF status, start_time, end_time, artist_id ON appointments
+  FOR EACH ROW EXECUTE FUNCTION record_waitlist_opening();
+
+-- Scheduled appointments cannot take a slot held by a pending offer. The
+-- offer is accepted before its appointment is inserted.
+CREATE FUNCTION reject_held_slot_overlap() RETURNS trigger AS $$
+BEGIN
+  IF NEW.status = 'SCHEDULED' AND NEW.artist_id IS NOT NULL AND EXISTS (
+    SELECT 1 FROM waitlist_offers o
+    WHERE o.artist_id = NEW.artist_id AND o.status = 'PENDING' AND o.expires_at > now()
+      AND tstzrange(o.starts_at, o.ends_at, '[)') && tstzrange(NEW.start_time, NEW.end_time, '[)')
+  ) THEN
+    RAISE EXCEPTION 'slot of artist % is held for the waitlist', NEW.artist_id
+      USING ERRCODE = 'exclusion_violation';
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER appointments_held_slot_insert
+  BEFORE INSERT ON appointments
+  FOR EACH ROW EXECUTE FUNCTION reject_held_slot_overlap();
+
+CREATE TRIGGER appointments_held_slot_update
+  BEFORE UPDATE OF start_time, end_time, artist_id ON appointments
+  FOR EACH ROW
+  WHEN (OLD.start_time IS DISTINCT FROM NEW.start_time
+    OR OLD.end_time IS DISTINCT FROM NEW.end_time
+    OR OLD.artist_id IS DISTINCT FROM NEW.artist_id)
+  EXECUTE FUNCTION reject_held_slot_overlap();
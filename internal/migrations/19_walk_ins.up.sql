@@ -0,0 +1,25 @@
+-- 29. walk_ins: The live walk-in queue of a studio, e.g. on flash days.
+-- Walk-ins are served in order of arrival; finished ones feed the wait estimate.
+CREATE TABLE walk_ins (
+                        id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                        studio_id      UUID NOT NULL,
+                        customer_id    UUID,                   -- NULL until the walk-in is registered as a customer
+                        name           VARCHAR(150) NOT NULL,
+                        design         TEXT,                   -- e.g. the flash piece picked
+                        artist_id      UUID,                   -- requested while waiting, serving once in service
+                        status         VARCHAR(20) NOT NULL DEFAULT 'WAITING',
+                        joined_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+                        started_at     TIMESTAMPTZ,
+                        finished_at    TIMESTAMPTZ,
+                        CONSTRAINT fk_walk_in_studio
+                          FOREIGN KEY (studio_id) REFERENCES studios (id) ON DELETE CASCADE,
+                        CONSTRAINT fk_walk_in_customer
+                          FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE SET NULL,
+                        CONSTRAINT fk_walk_in_artist
+                          FOREIGN KEY (artist_id) REFERENCES users (id) ON DELETE SET NULL,
+                        CONSTRAINT check_walk_in_status CHECK (status IN ('WAITING', 'IN_SERVICE', 'DONE', 'NO_SHOW')),
+                        CONSTRAINT check_walk_in_service CHECK (status <> 'IN_SERVICE' OR started_at IS NOT NULL)
+);
+
+CREATE INDEX idx_walk_ins_queue ON walk_ins (studio_id, joined_at) WHERE status IN ('WAITING', 'IN_SERVICE');
+CREATE INDEX idx_walk_ins_finished ON walk_ins (studio_id, finished_at) WHERE status = 'DONE';
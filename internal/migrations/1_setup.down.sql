@@ -0,0 +1,2 @@
+-- Extensions are left in place: schema-mode tenants share them through public
+drop function if exists set_updated_at();
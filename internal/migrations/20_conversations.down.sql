@@ -0,0 +1,7 @@
+ALTER TABLE messages
+  DROP CONSTRAINT IF EXISTS check_message_content,
+  DROP CONSTRAINT IF EXISTS check_message_sender;
+
+DROP INDEX IF EXISTS idx_messages_conversation_created;
+DROP INDEX IF EXISTS idx_conversations_customer;
+DROP INDEX IF EXISTS idx_conversation_participants_user;
@@ -0,0 +1,11 @@
+-- Inboxes list a user's conversations by latest activity, threads page back
+-- through their messages
+CREATE INDEX idx_conversation_participants_user ON conversation_participants (user_id);
+CREATE INDEX idx_conversations_customer ON conversations (customers_id);
+CREATE INDEX idx_messages_conversation_created ON messages (conversation_id, created_at DESC);
+
+-- A message comes from either a staff member or the conversation's customer.
+-- NOT VALID leaves rows written before this migration alone.
+ALTER TABLE messages
+  ADD CONSTRAINT check_message_sender CHECK (num_nonnulls(sender_user_id, sender_customer_id) = 1) NOT VALID,
+  ADD CONSTRAINT check_message_content CHECK (length(btrim(content)) > 0) NOT VALID;
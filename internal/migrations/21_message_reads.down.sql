@@ -0,0 +1,4 @@
+ALTER TABLE conversation_participants
+  DROP COLUMN IF EXISTS read_at,
+  DROP COLUMN IF EXISTS read_up_to,
+  DROP COLUMN IF EXISTS last_read_message_id;
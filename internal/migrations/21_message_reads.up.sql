@@ -0,0 +1,15 @@
+-- Read markers: each participant has read their conversation up to a message.
+-- read_up_to is that message's created_at, so unread messages are the later
+-- ones sent by someone else; read_at is when the marker last moved.
+ALTER TABLE conversation_participants
+  ADD COLUMN last_read_message_id UUID REFERENCES messages (id) ON DELETE SET NULL,
+  ADD COLUMN read_up_to           TIMESTAMPTZ,
+  ADD COLUMN read_at              TIMESTAMPTZ;
+
+-- Conversations held before read markers existed count as read
+UPDATE conversation_participants p
+SET last_read_message_id = m.id, read_up_to = m.created_at, read_at = now()
+FROM (SELECT DISTINCT ON (conversation_id) conversation_id, id, created_at
+      FROM messages
+      ORDER BY conversation_id, created_at DESC, id DESC) m
+WHERE m.conversation_id = p.conversation_id;
@@ -0,0 +1,4 @@
+ALTER TABLE messages
+  ADD CONSTRAINT check_message_content CHECK (length(btrim(content)) > 0) NOT VALID;
+
+DROP TABLE IF EXISTS attachments;
@@ -0,0 +1,29 @@
+-- 30. attachments: Files uploaded to the studio's blob store. An attachment
+-- is sent with a message or shown on a portfolio item; until then only its
+-- uploader can use it.
+CREATE TABLE attachments (
+                           id                 UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                           storage_key        TEXT NOT NULL UNIQUE,   -- key of the blob in the blob store
+                           filename           VARCHAR(255) NOT NULL,
+                           content_type       VARCHAR(100) NOT NULL,  -- sniffed from the upload, not taken from the client
+                           size_bytes         BIGINT NOT NULL,
+                           uploaded_by        UUID,
+                           message_id         UUID,
+                           portfolio_item_id  UUID,
+                           created_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
+                           CONSTRAINT fk_attachment_uploader
+                             FOREIGN KEY (uploaded_by) REFERENCES users (id) ON DELETE SET NULL,
+                           CONSTRAINT fk_attachment_message
+                             FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE CASCADE,
+                           CONSTRAINT fk_attachment_portfolio_item
+                             FOREIGN KEY (portfolio_item_id) REFERENCES portfolio_items (id) ON DELETE CASCADE,
+                           CONSTRAINT check_attachment_size CHECK (size_bytes > 0),
+                           CONSTRAINT check_attachment_owner CHECK (num_nonnulls(message_id, portfolio_item_id) <= 1)
+);
+
+CREATE INDEX idx_attachments_message ON attachments (message_id) WHERE message_id IS NOT NULL;
+CREATE INDEX idx_attachments_portfolio_item ON attachments (portfolio_item_id) WHERE portfolio_item_id IS NOT NULL;
+
+-- A message may carry only attachments; the service requires content or at
+-- least one attachment
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS check_message_content;
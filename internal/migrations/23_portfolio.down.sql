@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_attachments_portfolio_variant;
+CREATE INDEX idx_attachments_portfolio_item ON attachments (portfolio_item_id) WHERE portfolio_item_id IS NOT NULL;
+
+ALTER TABLE attachments
+  DROP CONSTRAINT IF EXISTS check_attachment_variant,
+  DROP COLUMN IF EXISTS variant;
+
+DROP INDEX IF EXISTS idx_portfolio_items_tags;
+DROP INDEX IF EXISTS idx_portfolio_items_studio;
+DROP INDEX IF EXISTS idx_portfolio_items_artist;
+
+ALTER TABLE portfolio_items
+  DROP COLUMN IF EXISTS position,
+  DROP COLUMN IF EXISTS body_placement,
+  DROP COLUMN IF EXISTS style,
+  DROP COLUMN IF EXISTS tags;
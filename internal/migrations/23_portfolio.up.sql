@@ -0,0 +1,20 @@
+-- Portfolio items are described for the gallery and ordered by their artist.
+-- Style and body placement are free text, e.g. "fine line" and "forearm".
+ALTER TABLE portfolio_items
+  ADD COLUMN tags           TEXT[] NOT NULL DEFAULT '{}',
+  ADD COLUMN style          VARCHAR(50),
+  ADD COLUMN body_placement VARCHAR(50),
+  ADD COLUMN position       INT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_portfolio_items_artist ON portfolio_items (artist_id, position);
+CREATE INDEX idx_portfolio_items_studio ON portfolio_items (studio_id, position);
+CREATE INDEX idx_portfolio_items_tags ON portfolio_items USING GIN (tags);
+
+-- A portfolio item's images are attachments, one per variant
+ALTER TABLE attachments
+  ADD COLUMN variant VARCHAR(20),
+  ADD CONSTRAINT check_attachment_variant
+    CHECK (variant IS NULL OR (portfolio_item_id IS NOT NULL AND variant IN ('original', 'web', 'thumbnail')));
+
+DROP INDEX IF EXISTS idx_attachments_portfolio_item;
+CREATE UNIQUE INDEX idx_attachments_portfolio_variant ON attachments (portfolio_item_id, variant) WHERE portfolio_item_id IS NOT NULL;
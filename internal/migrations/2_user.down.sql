@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS invitations;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS studio_settings;
+DROP TABLE IF EXISTS studios;
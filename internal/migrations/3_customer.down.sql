@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS customer_artists;
+DROP TABLE IF EXISTS customers;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS conversation_participants;
+DROP TABLE IF EXISTS conversations;
@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS staff_permissions;
+DROP TABLE IF EXISTS studio_staff;
+
+DELETE FROM role_permissions
+WHERE (role, permission) IN (
+  ('OWNER', 'studio.manage'),
+  ('OWNER', 'staff.manage'),
+  ('OWNER', 'customers.manage'),
+  ('OWNER', 'appointments.manage'),
+  ('OWNER', 'messages.manage'),
+  ('OWNER', 'portfolio.manage'),
+  ('ARTIST', 'customers.manage'),
+  ('ARTIST', 'appointments.manage'),
+  ('ARTIST', 'messages.manage'),
+  ('ARTIST', 'portfolio.manage'),
+  ('ASSISTANT', 'customers.manage'),
+  ('ASSISTANT', 'appointments.manage'),
+  ('ASSISTANT', 'messages.manage')
+);
@@ -0,0 +1,44 @@
+-- 10. studio_staff: Ties users to a studio with a role
+CREATE TABLE studio_staff (
+                            id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                            studio_id     UUID NOT NULL,
+                            user_id       UUID NOT NULL,
+                            role          VARCHAR(50) NOT NULL,       -- same values as users.role
+                            created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+                            updated_at    TIMESTAMPTZ,
+                            CONSTRAINT fk_studio_staff
+                              FOREIGN KEY (studio_id) REFERENCES studios (id) ON DELETE CASCADE,
+                            CONSTRAINT fk_user_staff
+                              FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+                            CONSTRAINT unique_studio_staff UNIQUE (studio_id, user_id)
+);
+
+-- 11. staff_permissions: Permissions granted to one staff member on top of their role
+CREATE TABLE staff_permissions (
+                                 staff_id      UUID NOT NULL,
+                                 permission    VARCHAR(100) NOT NULL,
+                                 PRIMARY KEY (staff_id, permission),
+                                 CONSTRAINT fk_staff_permission
+                                   FOREIGN KEY (staff_id) REFERENCES studio_staff (id) ON DELETE CASCADE
+);
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('OWNER', 'studio.manage'),
+  ('OWNER', 'staff.manage'),
+  ('OWNER', 'customers.manage'),
+  ('OWNER', 'appointments.manage'),
+  ('OWNER', 'messages.manage'),
+  ('OWNER', 'portfolio.manage'),
+  ('ARTIST', 'customers.manage'),
+  ('ARTIST', 'appointments.manage'),
+  ('ARTIST', 'messages.manage'),
+  ('ARTIST', 'portfolio.manage'),
+  ('ASSISTANT', 'customers.manage'),
+  ('ASSISTANT', 'appointments.manage'),
+  ('ASSISTANT', 'messages.manage')
+ON CONFLICT DO NOTHING;
+
+-- Existing users become staff of their studio with their current role
+INSERT INTO studio_staff (studio_id, user_id, role, created_at)
+SELECT studio_id, id, role, created_at FROM users
+ON CONFLICT DO NOTHING;
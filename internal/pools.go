@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+const defaultPoolIdleTTL = 10 * time.Minute
+
+// newTenantDBOpener returns the opener used by TenantDBManager to connect a tenant
+// on its first request. With migrate set, migrations run the first time a tenant
+// is opened by this process; pools reopened after eviction skip them.
+func newTenantDBOpener(cfg *config.Config, migrate bool) config.TenantDBOpener {
+	var migrated sync.Map
+
+	return func(ctx context.Context, subdomain string) (*pgxpool.Pool, error) {
+		tenant, err := cfg.GetTenantConfig(ctx, subdomain)
+		if err != nil {
+			return nil, err
+		}
+		if tenant.Status != "" && tenant.Status != config.TenantStatusActive {
+			return nil, fmt.Errorf("tenant %s is %s", subdomain, tenant.Status)
+		}
+
+		_, done := migrated.Load(subdomain)
+		pool, err := connectTenantDB(ctx, cfg, tenant, migrate && !done)
+		if err != nil {
+			return nil, err
+		}
+		migrated.Store(subdomain, true)
+
+		logger.Log.Info("Opened tenant pool",
+			zap.String("subdomain", subdomain),
+			zap.Int32("max_conns", pool.Config().MaxConns))
+		return pool, nil
+	}
+}
+
+// ReapIdleTenantPools closes tenant pools that have not been used for the
+// configured idle TTL. It returns when ctx is cancelled.
+func ReapIdleTenantPools(ctx context.Context, dbManager *config.TenantDBManager) {
+	ttl := dbManager.Config.TenantPools.IdleTTL
+	if ttl <= 0 {
+		ttl = defaultPoolIdleTTL
+	}
+
+	ticker := time.NewTicker(ttl / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, subdomain := range dbManager.EvictIdle(ttl) {
+				logger.Log.Info("Closed idle tenant pool", zap.String("subdomain", subdomain))
+			}
+		}
+	}
+}
+
+// TenantPoolCollector exports per-tenant pgxpool statistics to Prometheus
+type TenantPoolCollector struct {
+	dbManager *config.TenantDBManager
+
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	totalConns      *prometheus.Desc
+	maxConns        *prometheus.Desc
+	acquireCount    *prometheus.Desc
+	emptyAcquire    *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	openPools       *prometheus.Desc
+	evictions       *prometheus.Desc
+}
+
+// NewTenantPoolCollector creates a collector for the pools held by dbManager
+func NewTenantPoolCollector(dbManager *config.TenantDBManager) *TenantPoolCollector {
+	tenantLabel := []string{"tenant"}
+	return &TenantPoolCollector{
+		dbManager: dbManager,
+		acquiredConns: prometheus.NewDesc("ink_tenant_pool_acquired_conns",
+			"Connections currently acquired from the tenant pool.", tenantLabel, nil),
+		idleConns: prometheus.NewDesc("ink_tenant_pool_idle_conns",
+			"Idle connections in the tenant pool.", tenantLabel, nil),
+		totalConns: prometheus.NewDesc("ink_tenant_pool_total_conns",
+			"Total connections in the tenant pool.", tenantLabel, nil),
+		maxConns: prometheus.NewDesc("ink_tenant_pool_max_conns",
+			"Maximum size of the tenant pool.", tenantLabel, nil),
+		acquireCount: prometheus.NewDesc("ink_tenant_pool_acquire_total",
+			"Successful connection acquisitions from the tenant pool.", tenantLabel, nil),
+		emptyAcquire: prometheus.NewDesc("ink_tenant_pool_empty_acquire_total",
+			"Acquisitions that had to wait because the tenant pool was empty.", tenantLabel, nil),
+		acquireDuration: prometheus.NewDesc("ink_tenant_pool_acquire_wait_seconds_total",
+			"Time spent acquiring connections from the tenant pool.", tenantLabel, nil),
+		openPools: prometheus.NewDesc("ink_tenant_pools_open",
+			"Tenant pools currently open.", nil, nil),
+		evictions: prometheus.NewDesc("ink_tenant_pool_evictions_total",
+			"Tenant pools closed for being idle or to stay within the connection cap.", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *TenantPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.emptyAcquire
+	ch <- c.acquireDuration
+	ch <- c.openPools
+	ch <- c.evictions
+}
+
+// Collect implements prometheus.Collector
+func (c *TenantPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.dbManager.Stats()
+	for tenant, stat := range stats {
+		ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()), tenant)
+		ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()), tenant)
+		ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()), tenant)
+		ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()), tenant)
+		ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()), tenant)
+		ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(stat.EmptyAcquireCount()), tenant)
+		ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds(), tenant)
+	}
+	ch <- prometheus.MustNewConstMetric(c.openPools, prometheus.GaugeValue, float64(len(stats)))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(c.dbManager.Evictions()))
+}
@@ -0,0 +1,303 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+)
+
+var (
+	// databaseNamePattern keeps tenant database names safe to interpolate into DDL
+	databaseNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+	nonIdentifierChars  = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// TenantProvisioner onboards new tenants while the server keeps running: it creates
+// the tenant database, migrates it, seeds the studio and owner, records it in the
+// tenant registry and registers the pool and Redis client with the tenant managers.
+type TenantProvisioner struct {
+	cfg          *config.Config
+	dbManager    *config.TenantDBManager
+	redisManager *config.TenantRedisManager
+
+	// mu serialises provisioning so two calls for the same tenant cannot race
+	mu sync.Mutex
+}
+
+// NewTenantProvisioner creates a new TenantProvisioner
+func NewTenantProvisioner(cfg *config.Config, dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) *TenantProvisioner {
+	return &TenantProvisioner{
+		cfg:          cfg,
+		dbManager:    dbManager,
+		redisManager: redisManager,
+	}
+}
+
+// Provision creates and registers a tenant. It is idempotent: provisioning a tenant
+// that already exists only makes sure its studio and owner are seeded. On failure
+// any database, pool or Redis client created by this call is removed again.
+func (p *TenantProvisioner) Provision(ctx context.Context, tenant *config.TenantConfig) (err error) {
+	log := logger.Log
+
+	if err = p.validate(tenant); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidArgument, err)
+	}
+	p.applyDefaults(tenant)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cfg.Registry != nil {
+		if existing, lookupErr := p.cfg.Registry.GetTenant(ctx, tenant.Subdomain); lookupErr == nil &&
+			existing.Status != config.TenantStatusActive {
+			return fmt.Errorf("tenant %s is %s", tenant.Subdomain, existing.Status)
+		}
+	}
+
+	if p.dbManager.HasTenant(tenant.Subdomain) && p.redisManager.HasTenant(tenant.Subdomain) {
+		pool, err := p.dbManager.GetTenantDB(tenant.Subdomain)
+		if err != nil {
+			return err
+		}
+		log.Info("Tenant already provisioned", zap.String("subdomain", tenant.Subdomain))
+		if err = InitializeTenantSystem(ctx, pool, tenant); err != nil {
+			return err
+		}
+		return p.register(ctx, tenant)
+	}
+
+	var (
+		createdDB     bool
+		createdSchema bool
+		pool          *pgxpool.Pool
+		client        *redis.Client
+	)
+	defer func() {
+		if err == nil {
+			return
+		}
+		log.Warn("Rolling back tenant provisioning", zap.String("subdomain", tenant.Subdomain), zap.Error(err))
+		if client != nil {
+			_ = client.Close()
+		}
+		if pool != nil {
+			pool.Close()
+		}
+		if createdSchema && !createdDB {
+			if dropErr := p.dropSchema(context.WithoutCancel(ctx), tenant.Database); dropErr != nil {
+				log.Error("Failed to drop tenant schema during rollback",
+					zap.String("schema", tenant.Database.Schema), zap.Error(dropErr))
+			}
+		}
+		if createdDB {
+			if dropErr := p.dropDatabase(context.WithoutCancel(ctx), tenant.Database.DB); dropErr != nil {
+				log.Error("Failed to drop tenant database during rollback",
+					zap.String("db", tenant.Database.DB), zap.Error(dropErr))
+			}
+		}
+	}()
+
+	createdDB, err = createDatabaseIfNotExists(ctx, p.cfg, tenant.Database.DB)
+	if err != nil {
+		return fmt.Errorf("create tenant database: %w", err)
+	}
+
+	if tenant.Database.Isolation == config.IsolationSchema {
+		createdSchema, err = createSchemaIfNotExists(ctx, tenant.Database)
+		if err != nil {
+			return fmt.Errorf("create tenant schema: %w", err)
+		}
+	}
+
+	pool, err = openTenantPool(p.cfg, tenant.Database)
+	if err != nil {
+		return fmt.Errorf("connect to tenant database: %w", err)
+	}
+	WaitForDB(pool)
+
+	if err = Migrate(pool); err != nil {
+		return fmt.Errorf("migrate tenant database: %w", err)
+	}
+
+	if err = InitializeTenantSystem(ctx, pool, tenant); err != nil {
+		return fmt.Errorf("initialize tenant system: %w", err)
+	}
+
+	client = newTenantRedisClient(p.cfg, tenant.Subdomain)
+	if err = client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("connect to tenant redis: %w", err)
+	}
+
+	// The registry is written last so a failure above never leaves a tenant
+	// listed whose database is missing
+	if err = p.register(ctx, tenant); err != nil {
+		return fmt.Errorf("register tenant: %w", err)
+	}
+
+	// Replace anything left behind by an earlier, partially registered attempt
+	if old := p.dbManager.RemoveTenant(tenant.Subdomain); old != nil {
+		old.Close()
+	}
+	if old := p.redisManager.RemoveTenant(tenant.Subdomain); old != nil {
+		_ = old.Close()
+	}
+	p.dbManager.AddTenant(tenant.Subdomain, pool)
+	p.redisManager.AddTenant(tenant.Subdomain, client)
+
+	log.Info("Tenant provisioned",
+		zap.String("subdomain", tenant.Subdomain),
+		zap.String("database", tenant.Database.DB),
+		zap.String("isolation", tenant.Database.Isolation),
+		zap.Bool("created_database", createdDB),
+		zap.Bool("created_schema", createdSchema))
+	return nil
+}
+
+// register records the tenant in the control-plane registry, when one is configured
+func (p *TenantProvisioner) register(ctx context.Context, tenant *config.TenantConfig) error {
+	if p.cfg.Registry == nil {
+		return nil
+	}
+	tenant.Status = config.TenantStatusActive
+	return p.cfg.Registry.RegisterTenant(ctx, tenant)
+}
+
+func (p *TenantProvisioner) validate(tenant *config.TenantConfig) error {
+	if tenant == nil {
+		return errors.New("tenant is required")
+	}
+	if tenant.Subdomain == "" {
+		return errors.New("subdomain is required")
+	}
+	if tenant.Studio.Name == "" {
+		return errors.New("studio name is required")
+	}
+	if tenant.Owner.Email == "" || tenant.Owner.Password == "" {
+		return errors.New("owner email and password are required")
+	}
+	// The registry stores no secrets and reopens every tenant with the server's
+	// credentials, so a tenant-specific user would break on the next reconnect
+	if tenant.Database.Username != "" || tenant.Database.Password != "" {
+		return errors.New("custom database credentials are not supported; tenants use database.username")
+	}
+	if tenant.Database.DB != "" && !databaseNamePattern.MatchString(tenant.Database.DB) {
+		return fmt.Errorf("invalid database name %q", tenant.Database.DB)
+	}
+	switch tenant.Database.Isolation {
+	case "", config.IsolationDatabase, config.IsolationSchema:
+	default:
+		return fmt.Errorf("invalid isolation mode %q", tenant.Database.Isolation)
+	}
+	if tenant.Database.Schema != "" && !databaseNamePattern.MatchString(tenant.Database.Schema) {
+		return fmt.Errorf("invalid schema name %q", tenant.Database.Schema)
+	}
+	return nil
+}
+
+// applyDefaults fills the connection settings the caller left out, and the
+// credentials, from the server-level database configuration
+func (p *TenantProvisioner) applyDefaults(tenant *config.TenantConfig) {
+	defaults := p.cfg.Database
+	db := &tenant.Database
+	if db.Host == "" {
+		db.Host = defaults.Host
+	}
+	if db.Port == "" {
+		db.Port = defaults.Port
+	}
+	db.Username = defaults.Username
+	db.Password = defaults.Password
+	if db.SSLMODE == "" {
+		db.SSLMODE = defaults.SSLMODE
+	}
+	if db.MaxConWaitingTime == 0 {
+		db.MaxConWaitingTime = defaults.MaxConWaitingTime
+	}
+	if db.Isolation == "" {
+		db.Isolation = p.isolationForPlan(tenant.Plan)
+	}
+	if db.Isolation == config.IsolationSchema {
+		if db.DB == "" {
+			db.DB = p.cfg.Isolation.SharedDB
+		}
+		if db.Schema == "" {
+			db.Schema = schemaNameForSubdomain(tenant.Subdomain)
+		}
+	}
+	if db.DB == "" {
+		db.DB = databaseNameForSubdomain(tenant.Subdomain)
+	}
+}
+
+// isolationForPlan picks schema mode for the plans listed under isolation.schema_plans
+func (p *TenantProvisioner) isolationForPlan(plan string) string {
+	if plan == "" {
+		plan = "basic"
+	}
+	if p.cfg.Isolation.SharedDB == "" {
+		return config.IsolationDatabase
+	}
+	for _, schemaPlan := range p.cfg.Isolation.SchemaPlans {
+		if schemaPlan == plan {
+			return config.IsolationSchema
+		}
+	}
+	if p.cfg.Isolation.DefaultMode == config.IsolationSchema {
+		return config.IsolationSchema
+	}
+	return config.IsolationDatabase
+}
+
+// schemaNameForSubdomain derives a schema name such as "tenant_inkbyjohn"
+func schemaNameForSubdomain(subdomain string) string {
+	name := "tenant_" + nonIdentifierChars.ReplaceAllString(strings.ToLower(subdomain), "_")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// databaseNameForSubdomain derives a database name such as "tattoo_studio_inkbyjohn"
+func databaseNameForSubdomain(subdomain string) string {
+	name := "tattoo_studio_" + nonIdentifierChars.ReplaceAllString(strings.ToLower(subdomain), "_")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+func (p *TenantProvisioner) dropDatabase(ctx context.Context, name string) error {
+	serverURL := serverConnURL(p.cfg)
+	conn, err := pgx.Connect(ctx, serverURL.String())
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	_, err = conn.Exec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()+" WITH (FORCE)")
+	return err
+}
+
+func (p *TenantProvisioner) dropSchema(ctx context.Context, dbConfig config.DatabaseConfig) error {
+	connURL := tenantConnURL(dbConfig)
+	conn, err := pgx.Connect(ctx, connURL.String())
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	_, err = conn.Exec(ctx, "DROP SCHEMA IF EXISTS "+pgx.Identifier{dbConfig.Schema}.Sanitize()+" CASCADE")
+	return err
+}
@@ -0,0 +1,367 @@
+package internal
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
+	grpctenant "github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/middleware/grpctenant"
+)
+
+//go:embed controlplane/*.sql
+var controlPlaneFS embed.FS
+
+const defaultTenantRefresh = time.Minute
+
+// TenantRegistry stores tenants and their database locations in the shared
+// control-plane database. It implements config.TenantRegistry.
+type TenantRegistry struct {
+	pool *pgxpool.Pool
+
+	// credentials used for every tenant database; they are never stored in the registry
+	credentials config.DatabaseConfig
+}
+
+// NewTenantRegistry connects to the control-plane database, creating and
+// migrating it when needed
+func NewTenantRegistry(cfg *config.Config) (*TenantRegistry, error) {
+	log := logger.Log
+	ctx := context.Background()
+
+	dbConfig := cfg.ControlPlane
+	if dbConfig.Host == "" {
+		dbConfig.Host = cfg.Database.Host
+		dbConfig.Port = cfg.Database.Port
+	}
+	if dbConfig.Username == "" {
+		dbConfig.Username = cfg.Database.Username
+		dbConfig.Password = cfg.Database.Password
+	}
+	if dbConfig.SSLMODE == "" {
+		dbConfig.SSLMODE = cfg.Database.SSLMODE
+	}
+	if dbConfig.DB == "" {
+		dbConfig.DB = "ink_control_plane"
+	}
+
+	if _, err := createDatabaseIfNotExists(ctx, cfg, dbConfig.DB); err != nil {
+		log.Error("Failed to create control-plane database", zap.Error(err))
+		return nil, err
+	}
+
+	connURL := tenantConnURL(dbConfig)
+	pool, err := Init(connURL.String())
+	if err != nil {
+		log.Error("Failed to connect to control-plane database", zap.Error(err))
+		return nil, err
+	}
+	WaitForDB(pool)
+
+	if err = migrateFS(pool, controlPlaneFS, "controlplane"); err != nil {
+		pool.Close()
+		log.Error("Failed to migrate control-plane database", zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("Connected to tenant registry", zap.String("database", dbConfig.DB))
+	return &TenantRegistry{pool: pool, credentials: cfg.Database}, nil
+}
+
+// Close releases the control-plane connection pool
+func (r *TenantRegistry) Close() {
+	r.pool.Close()
+}
+
+const selectTenants = `
+	SELECT t.subdomain, t.studio_name, t.status, t.plan, t.created_at,
+	       d.host, d.port, d.db_name, d.sslmode, d.isolation, COALESCE(d.schema_name, '')
+	FROM tenants t
+	JOIN tenant_databases d ON d.tenant_id = t.id`
+
+func (r *TenantRegistry) scanTenant(row pgx.Row) (*config.TenantConfig, error) {
+	tenant := config.TenantConfig{Database: r.credentials}
+	err := row.Scan(&tenant.Subdomain, &tenant.Studio.Name, &tenant.Status, &tenant.Plan, &tenant.CreatedAt,
+		&tenant.Database.Host, &tenant.Database.Port, &tenant.Database.DB, &tenant.Database.SSLMODE,
+		&tenant.Database.Isolation, &tenant.Database.Schema)
+	if err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// ListTenants returns every tenant that has not been deleted
+func (r *TenantRegistry) ListTenants(ctx context.Context) ([]config.TenantConfig, error) {
+	rows, err := r.pool.Query(ctx, selectTenants+` WHERE t.status <> $1 ORDER BY t.created_at`, config.TenantStatusDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []config.TenantConfig
+	for rows.Next() {
+		tenant, err := r.scanTenant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		tenants = append(tenants, *tenant)
+	}
+	return tenants, rows.Err()
+}
+
+// GetTenant returns a tenant by subdomain, including suspended and deleted ones
+func (r *TenantRegistry) GetTenant(ctx context.Context, subdomain string) (*config.TenantConfig, error) {
+	tenant, err := r.scanTenant(r.pool.QueryRow(ctx, selectTenants+` WHERE t.subdomain = $1`, subdomain))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("tenant with subdomain %s not found", subdomain)
+		}
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	return tenant, nil
+}
+
+// RegisterTenant inserts or updates a tenant and its database location
+func (r *TenantRegistry) RegisterTenant(ctx context.Context, tenant *config.TenantConfig) error {
+	status := tenant.Status
+	if status == "" {
+		status = config.TenantStatusActive
+	}
+	plan := tenant.Plan
+	if plan == "" {
+		plan = "basic"
+	}
+	isolation := tenant.Database.Isolation
+	if isolation == "" {
+		isolation = config.IsolationDatabase
+	}
+	var schema *string
+	if isolation == config.IsolationSchema {
+		schema = &tenant.Database.Schema
+	}
+
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var tenantID string
+		err := tx.QueryRow(ctx, `
+			INSERT INTO tenants (subdomain, studio_name, status, plan)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (subdomain) DO UPDATE
+			SET studio_name = EXCLUDED.studio_name, status = EXCLUDED.status,
+			    plan = EXCLUDED.plan, updated_at = now()
+			RETURNING id`,
+			tenant.Subdomain, tenant.Studio.Name, status, plan).Scan(&tenantID)
+		if err != nil {
+			return fmt.Errorf("failed to register tenant: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO tenant_databases (tenant_id, host, port, db_name, sslmode, isolation, schema_name)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (tenant_id) DO UPDATE
+			SET host = EXCLUDED.host, port = EXCLUDED.port, db_name = EXCLUDED.db_name,
+			    sslmode = EXCLUDED.sslmode, isolation = EXCLUDED.isolation,
+			    schema_name = EXCLUDED.schema_name, updated_at = now()`,
+			tenantID, tenant.Database.Host, tenant.Database.Port, tenant.Database.DB, tenant.Database.SSLMODE,
+			isolation, schema)
+		if err != nil {
+			return fmt.Errorf("failed to register tenant database: %w", err)
+		}
+		return nil
+	})
+}
+
+// SetTenantStatus moves a tenant between active, suspended and deleted
+func (r *TenantRegistry) SetTenantStatus(ctx context.Context, subdomain, status string) error {
+	switch status {
+	case config.TenantStatusActive, config.TenantStatusSuspended, config.TenantStatusDeleted:
+	default:
+		return fmt.Errorf("invalid tenant status %q", status)
+	}
+
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE tenants SET status = $1, updated_at = now() WHERE subdomain = $2`, status, subdomain)
+	if err != nil {
+		return fmt.Errorf("failed to update tenant status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("tenant with subdomain %s not found", subdomain)
+	}
+	return nil
+}
+
+// DeleteTenant removes a tenant row entirely. It is used to undo a failed
+// provisioning; retiring a live tenant should use SetTenantStatus instead.
+func (r *TenantRegistry) DeleteTenant(ctx context.Context, subdomain string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM tenants WHERE subdomain = $1`, subdomain)
+	if err != nil {
+		return fmt.Errorf("failed to delete tenant: %w", err)
+	}
+	return nil
+}
+
+// ImportConfigTenants copies tenants still listed in config.yml into the registry
+// so existing deployments keep working after the move to the control plane
+func ImportConfigTenants(ctx context.Context, cfg *config.Config) error {
+	if cfg.Registry == nil {
+		return nil
+	}
+	for _, tenant := range cfg.Tenants {
+		if _, err := cfg.Registry.GetTenant(ctx, tenant.Subdomain); err == nil {
+			continue
+		}
+		logger.Log.Warn("Importing tenant from config file into the registry; remove it from config.yml",
+			zap.String("subdomain", tenant.Subdomain))
+		if err := cfg.Registry.RegisterTenant(ctx, &tenant); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WatchTenantRegistry periodically reconciles the tenant managers with the
+// registry: new active tenants get a Redis client and suspended or deleted ones
+// are closed. It returns when ctx is cancelled.
+func WatchTenantRegistry(ctx context.Context, cfg *config.Config, dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) {
+	interval := cfg.TenantRefresh
+	if interval <= 0 {
+		interval = defaultTenantRefresh
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := RefreshTenants(ctx, cfg, dbManager, redisManager); err != nil {
+				logger.Log.Error("Failed to refresh tenants from registry", zap.Error(err))
+			}
+		}
+	}
+}
+
+// RefreshTenants performs a single reconciliation pass against the registry
+func RefreshTenants(ctx context.Context, cfg *config.Config, dbManager *config.TenantDBManager, redisManager *config.TenantRedisManager) error {
+	log := logger.Log
+
+	tenants, err := LoadTenants(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	active := make(map[string]bool, len(tenants))
+	for _, tenant := range tenants {
+		if tenant.Status != config.TenantStatusActive {
+			continue
+		}
+		active[tenant.Subdomain] = true
+
+		// Database pools are opened lazily by the manager on first request
+		if !redisManager.HasTenant(tenant.Subdomain) {
+			client := newTenantRedisClient(cfg, tenant.Subdomain)
+			if err := client.Ping(ctx).Err(); err != nil {
+				_ = client.Close()
+				log.Error("Failed to connect tenant Redis", zap.String("subdomain", tenant.Subdomain), zap.Error(err))
+				continue
+			}
+			redisManager.AddTenant(tenant.Subdomain, client)
+		}
+	}
+
+	loaded := make(map[string]bool)
+	for _, subdomain := range append(dbManager.Subdomains(), redisManager.Subdomains()...) {
+		loaded[subdomain] = true
+	}
+	for subdomain := range loaded {
+		if active[subdomain] {
+			continue
+		}
+		if pool := dbManager.RemoveTenant(subdomain); pool != nil {
+			pool.Close()
+		}
+		if client := redisManager.RemoveTenant(subdomain); client != nil {
+			_ = client.Close()
+		}
+		log.Info("Unloaded tenant no longer active in registry", zap.String("subdomain", subdomain))
+	}
+	return nil
+}
+
+// LoadTenants lists tenants from the registry, or from the config file when no
+// registry is configured
+func LoadTenants(ctx context.Context, cfg *config.Config) ([]config.TenantConfig, error) {
+	if cfg.Registry == nil {
+		tenants := make([]config.TenantConfig, 0, len(cfg.Tenants))
+		for _, tenant := range cfg.Tenants {
+			if tenant.Status == "" {
+				tenant.Status = config.TenantStatusActive
+			}
+			tenants = append(tenants, tenant)
+		}
+		return tenants, nil
+	}
+	return cfg.Registry.ListTenants(ctx)
+}
+
+// tenantStatusTTL is how long the validator trusts a registry lookup
+const tenantStatusTTL = 30 * time.Second
+
+type tenantStatusEntry struct {
+	status  string
+	expires time.Time
+}
+
+// NewTenantValidator checks request tenants against the registry, or against the
+// loaded tenants when no registry is configured. Suspended, deleted and unknown
+// tenants are refused. Known tenants are cached briefly so the control plane is
+// not queried on every request; misses are not, so a tenant provisioned by
+// another process is served at once.
+func NewTenantValidator(cfg *config.Config, dbManager *config.TenantDBManager) grpctenant.TenantValidator {
+	var cache sync.Map
+
+	return func(ctx context.Context, tenant string) error {
+		tenantStatus := ""
+		if entry, ok := cache.Load(tenant); ok && time.Now().Before(entry.(tenantStatusEntry).expires) {
+			tenantStatus = entry.(tenantStatusEntry).status
+		} else {
+			tenantCfg, err := cfg.GetTenantConfig(ctx, tenant)
+			switch {
+			case err == nil:
+				tenantStatus = tenantCfg.Status
+				if tenantStatus == "" {
+					tenantStatus = config.TenantStatusActive
+				}
+			case dbManager.HasTenant(tenant):
+				tenantStatus = config.TenantStatusActive
+			default:
+				tenantStatus = config.TenantStatusDeleted
+			}
+			if tenantStatus == config.TenantStatusDeleted {
+				cache.Delete(tenant)
+			} else {
+				cache.Store(tenant, tenantStatusEntry{status: tenantStatus, expires: time.Now().Add(tenantStatusTTL)})
+			}
+		}
+
+		switch tenantStatus {
+		case config.TenantStatusActive:
+			return nil
+		case config.TenantStatusSuspended:
+			return status.Errorf(codes.PermissionDenied, "tenant %s is suspended", tenant)
+		default:
+			return status.Errorf(codes.PermissionDenied, "invalid tenant identifier: %s", tenant)
+		}
+	}
+}
@@ -8,7 +8,12 @@ import (
 	"os/signal"
 	"sync/atomic"
 
+	upa "github.com/FACorreiaa/ink-app-backend-protos/modules/appointment/generated"
+	upc "github.com/FACorreiaa/ink-app-backend-protos/modules/customer/generated"
+	upn "github.com/FACorreiaa/ink-app-backend-protos/modules/notifications/generated"
+	upp "github.com/FACorreiaa/ink-app-backend-protos/modules/payment/generated"
 	ups "github.com/FACorreiaa/ink-app-backend-protos/modules/studio/generated"
+	upu "github.com/FACorreiaa/ink-app-backend-protos/modules/user/generated"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel"
 
@@ -39,8 +44,14 @@ func ServeGRPC(ctx context.Context, port string, app *AppContainer, reg *prometh
 	}
 	tp := otel.GetTracerProvider()
 
+	// Per-tenant pool metrics
+	if err = reg.Register(NewTenantPoolCollector(app.DBManager)); err != nil {
+		return errors.Wrap(err, "failed to register tenant pool metrics")
+	}
+
 	// Bootstrap gRPC server
-	server, listener, err := grpc.BootstrapServer(port, log, reg, tp)
+	validator := NewTenantValidator(app.DBManager.Config, app.DBManager)
+	server, listener, err := grpc.BootstrapServer(port, log, reg, tp, validator)
 	if err != nil {
 		return errors.Wrap(err, "failed to configure gRPC server")
 	}
@@ -48,9 +59,27 @@ func ServeGRPC(ctx context.Context, port string, app *AppContainer, reg *prometh
 	// Register services
 	ups.RegisterAuthServiceServer(server, app.AuthService)
 	ups.RegisterStudioServiceServer(server, app.StudioService)
-	//ups.RegisterStudioServiceServer(server, app.StudioService)
+	upc.RegisterCustomerServiceServer(server, app.CustomerService)
+	upu.RegisterUserServiceServer(server, app.UserService)
+	upa.RegisterAppointmentServiceServer(server, app.AppointmentService)
+	upn.RegisterNotificationServiceServer(server, app.NotificationService)
+	upp.RegisterPaymentServiceServer(server, app.PaymentService)
+	app.TenantService.Register(server)
+	app.CustomerService.Register(server)
+	app.AvailabilityService.Register(server)
+	app.BookingService.Register(server)
+	app.ProjectService.Register(server)
+	app.WaitlistService.Register(server)
+	app.CalendarService.Register(server)
+	app.NotificationService.Register(server)
+	app.LedgerService.Register(server)
+	app.ReminderService.Register(server)
+	app.WalkInService.Register(server)
+	app.ConversationService.Register(server)
+	app.AttachmentService.Register(server)
+	app.PortfolioService.Register(server)
+	app.PublicService.Register(server)
 	//upb.RegisterAuthServer(server, app.AuthServiceManager)
-	//upc.RegisterCustomerServiceServer(server, app.CustomerService)
 
 	// Enable reflection for debugging
 	reflection.Register(server)
@@ -79,8 +108,11 @@ func ServeGRPC(ctx context.Context, port string, app *AppContainer, reg *prometh
 
 // ServeHTTP creates a simple server to serve Prometheus metrics for
 // the collector, and (not included) healthcheck endpoints for K8S to
-// query readiness. By default, these should serve on "/healthz" and "/readyz"
-func ServeHTTP(port string, reg *prometheus.Registry) error {
+// query readiness. By default, these should serve on "/healthz" and "/readyz".
+// It also serves the artists' iCalendar feeds, which calendar apps poll
+// without credentials other than the token in the URL, and the studios'
+// public galleries.
+func ServeHTTP(port string, reg *prometheus.Registry, calendarFeed, files, gallery http.Handler) error {
 	log := logger.Log
 	log.Info("running http server", zap.String("port", port))
 
@@ -108,6 +140,9 @@ func ServeHTTP(port string, reg *prometheus.Registry) error {
 
 	//server.HandleFunc("/metrics", promhttp.Handler().ServeHTTP) // This should use the correct registry.
 	server.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+	server.Handle("GET /calendar/{tenant}/{token}", calendarFeed)
+	server.Handle("GET /files/{tenant}/{id}", files)
+	server.Handle("GET /gallery/", gallery)
 
 	listener := &http.Server{
 		Addr:              fmt.Sprintf(":%s", port),
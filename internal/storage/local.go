@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// LocalStore keeps blobs as files under a directory. Keys map to paths below
+// it and can never reach outside of it.
+type LocalStore struct {
+	root *os.Root
+}
+
+// NewLocalStore opens dir as a blob store, creating it if needed
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if dir == "" {
+		return nil, errors.New("local storage needs a directory")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage directory: %w", err)
+	}
+	return &LocalStore{root: root}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key, _ string, body io.Reader, size int64) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	if err := s.mkdirAll(path.Dir(key)); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	f, err := s.root.OpenFile(key, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %w", err)
+	}
+	n, err := io.Copy(f, io.LimitReader(body, size))
+	if err == nil && n != size {
+		err = fmt.Errorf("got %d of %d bytes", n, size)
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = s.root.Remove(key)
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	f, err := s.root.Open(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("blob %s: %w", key, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	return f, nil
+}
+
+func (s *LocalStore) Delete(_ context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	if err := s.root.Remove(key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return nil
+}
+
+// mkdirAll creates dir and its parents below the root
+func (s *LocalStore) mkdirAll(dir string) error {
+	if dir == "." {
+		return nil
+	}
+	if err := s.mkdirAll(path.Dir(dir)); err != nil {
+		return err
+	}
+	if err := s.root.Mkdir(dir, 0o750); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// s3TestStore connects to the bucket named by the INK_S3_TEST_* variables and
+// skips the test when INK_S3_TEST_ENDPOINT is unset. The other variables
+// default to the MinIO of docker compose:
+//
+//	docker compose up -d minio minio-init
+//	INK_S3_TEST_ENDPOINT=http://localhost:9000 go test ./internal/storage/
+func s3TestStore(t *testing.T) *S3Store {
+	t.Helper()
+	endpoint := os.Getenv("INK_S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("INK_S3_TEST_ENDPOINT is not set")
+	}
+	env := func(name, fallback string) string {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+		return fallback
+	}
+	store, err := NewS3Store(config.S3StorageConfig{
+		Endpoint:  endpoint,
+		Region:    env("INK_S3_TEST_REGION", "us-east-1"),
+		Bucket:    env("INK_S3_TEST_BUCKET", "ink-me-uploads"),
+		AccessKey: env("INK_S3_TEST_ACCESS_KEY", "minioadmin"),
+		SecretKey: env("INK_S3_TEST_SECRET_KEY", "minioadmin"),
+		PathStyle: os.Getenv("INK_S3_TEST_VIRTUAL_HOST") == "",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestS3Store(t *testing.T) {
+	store := s3TestStore(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// Spaces and other reserved characters exercise the path escaping of the
+	// signature
+	key := fmt.Sprintf("test/%d/tattoo sketch (v1).png", time.Now().UnixNano())
+	body := []byte("not really a png")
+	t.Cleanup(func() { _ = store.Delete(context.Background(), key) })
+
+	if err := store.Put(ctx, key, "image/png", bytes.NewReader(body), int64(len(body))); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	rc, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("read blob: %v", err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Errorf("got %q, want %q", got, body)
+	}
+
+	if err = store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err = store.Get(ctx, key); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Get after Delete returned %v, want ErrNotFound", err)
+	}
+	// Deleting a missing key is not an error
+	if err = store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+}
+
+func TestS3StoreMissingKey(t *testing.T) {
+	store := s3TestStore(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err := store.Get(ctx, fmt.Sprintf("test/missing-%d", time.Now().UnixNano()))
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3StoreRejectsBadCredentials(t *testing.T) {
+	store := s3TestStore(t)
+	store.secretKey += "-wrong"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	body := []byte("x")
+	err := store.Put(ctx, "test/forbidden", "text/plain", bytes.NewReader(body), int64(len(body)))
+	if err == nil {
+		t.Error("Put with a wrong secret key succeeded")
+	}
+}
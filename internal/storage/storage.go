@@ -0,0 +1,43 @@
+// Package storage implements domain.BlobStore on a local directory and on
+// S3-compatible object stores.
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/config"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
+)
+
+// Storage backends
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// NewBlobStore opens the blob store chosen by cfg
+func NewBlobStore(cfg config.StorageConfig) (domain.BlobStore, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "", BackendLocal:
+		return NewLocalStore(cfg.Local.Dir)
+	case BackendS3:
+		return NewS3Store(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// validKey rejects keys that could address something other than a blob, such
+// as absolute paths or parent directories
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		return fmt.Errorf("%w: invalid blob key %q", domain.ErrInvalidArgument, key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("%w: invalid blob key %q", domain.ErrInvalidArgument, key)
+		}
+	}
+	return nil
+}
@@ -11,49 +11,57 @@ import (
 
 	"github.com/FACorreiaa/ink-app-backend-grpc/config"
 	"github.com/FACorreiaa/ink-app-backend-grpc/internal"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain/payment"
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/storage"
 	"github.com/FACorreiaa/ink-app-backend-grpc/logger"
 )
 
-func run() (*config.TenantDBManager, *config.TenantRedisManager, error) {
+func run(ctx context.Context) (*config.TenantDBManager, *config.TenantRedisManager, *internal.TenantRegistry, error) {
 	cfg, err := config.InitConfig()
 	if err != nil {
 		logger.Log.Error("failed to initialize config", zap.Error(err))
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	log := logger.Log
 
+	// Tenants are read from the control-plane registry
+	registry, err := internal.NewTenantRegistry(&cfg)
+	if err != nil {
+		log.Error("failed to initialize tenant registry", zap.Error(err))
+		return nil, nil, nil, err
+	}
+	cfg.Registry = registry
+
+	if err = internal.ImportConfigTenants(ctx, &cfg); err != nil {
+		registry.Close()
+		log.Error("failed to import tenants from config", zap.Error(err))
+		return nil, nil, nil, err
+	}
+
 	// Initialize tenant database manager
 	dbManager, err := internal.NewTenantDBManager(&cfg)
 	if err != nil {
+		registry.Close()
 		log.Error("failed to initialize tenant database manager", zap.Error(err))
-		return nil, nil, err
-	}
-
-	// Initialize each tenant system
-	ctx := context.Background()
-	for _, tenant := range cfg.Tenants {
-		pool, err := dbManager.GetTenantDB(tenant.Subdomain)
-		if err != nil {
-			log.Error("failed to get tenant DB", zap.String("subdomain", tenant.Subdomain), zap.Error(err))
-			return nil, nil, err
-		}
-		if err := internal.InitializeTenantSystem(ctx, pool, &tenant); err != nil {
-			log.Error("failed to initialize tenant system", zap.String("subdomain", tenant.Subdomain), zap.Error(err))
-			return nil, nil, err
-		}
-		log.Info("Tenant initialized", zap.String("subdomain", tenant.Subdomain))
+		return nil, nil, nil, err
 	}
 
 	// Initialize Redis (shared across tenants)
 	redisClient, err := internal.NewTenantRedisManager(&cfg)
 	if err != nil {
+		dbManager.Close()
+		registry.Close()
 		log.Error("failed to initialize Redis configuration", zap.Error(err))
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	log.Info("Connected to Redis", zap.String("host", cfg.Redis.Host))
 
-	return dbManager, redisClient, nil
+	// Pick up tenants added, suspended or deleted while we are running
+	go internal.WatchTenantRegistry(ctx, &cfg, dbManager, redisClient)
+	go internal.ReapIdleTenantPools(ctx, dbManager)
+
+	return dbManager, redisClient, registry, nil
 }
 
 func startServer(ctx context.Context, cfg *config.Config, container *internal.AppContainer, reg *prometheus.Registry) error {
@@ -70,7 +78,7 @@ func startServer(ctx context.Context, cfg *config.Config, container *internal.Ap
 
 	// Start HTTP server (for metrics, etc.)
 	go func() {
-		if err := internal.ServeHTTP(cfg.Server.HTTPPort, reg); err != nil {
+		if err := internal.ServeHTTP(cfg.Server.HTTPPort, reg, container.CalendarFeed, container.Files, container.Gallery); err != nil {
 			logger.Log.Error("HTTP server error", zap.Error(err))
 			errChan <- err
 		}
@@ -109,22 +117,14 @@ func main() {
 
 	log := logger.Log
 
-	dbManager, redisManager, err := run()
+	dbManager, redisManager, registry, err := run(ctx)
 	if err != nil {
 		log.Error("failed to run the application", zap.Error(err))
 		return
 	}
-	defer func() {
-		for _, tenantDB := range dbManager.Tenants {
-			tenantDB.Pool.Close()
-		}
-	}()
-
-	defer func() {
-		for _, tenantRedis := range redisManager.Tenants {
-			tenantRedis.Client.Close()
-		}
-	}()
+	defer registry.Close()
+	defer dbManager.Close()
+	defer redisManager.Close()
 
 	tu := new(utils.TransportUtils)
 	brokers := internal.ConfigureUpstreamClients(log, tu)
@@ -133,8 +133,22 @@ func main() {
 		return
 	}
 
+	blobs, err := storage.NewBlobStore(cfg.Storage)
+	if err != nil {
+		log.Error("failed to open blob storage", zap.Error(err))
+		return
+	}
+
+	paymentProvider, err := payment.NewProvider(cfg.Payments, cfg.Mode)
+	if err != nil {
+		log.Error("failed to configure payments", zap.Error(err))
+		return
+	}
+
 	// Pass dbManager to AppContainer instead of a single pool
-	appContainer := internal.NewAppContainer(ctx, dbManager, redisManager)
+	appContainer := internal.NewAppContainer(ctx, dbManager, redisManager, blobs, paymentProvider)
+	go appContainer.ReminderScheduler.Run(ctx)
+	go appContainer.WaitlistScheduler.Run(ctx)
 
 	if err = startServer(ctx, &cfg, appContainer, reg); err != nil {
 		logger.Log.Error("service error", zap.Error(err))
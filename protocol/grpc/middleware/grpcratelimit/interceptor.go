@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
@@ -92,32 +94,93 @@ type IPRateLimiter struct {
 	limiters map[string]*rate.Limiter
 	rate     rate.Limit
 	burst    int
+	// seen is when each IP last asked for its limiter
+	seen      map[string]time.Time
+	lastSweep time.Time
 }
 
+// limiterIdle is how long an IP's limiter is kept after its last request.
+// A limiter idle this long has refilled, so dropping it changes nothing.
+const limiterIdle = 10 * time.Minute
+
 // NewIPRateLimiter creates a new rate limiter manager
 func NewIPRateLimiter(r rate.Limit, b int) *IPRateLimiter {
 	return &IPRateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		rate:     r,
-		burst:    b,
+		limiters:  make(map[string]*rate.Limiter),
+		rate:      r,
+		burst:     b,
+		seen:      make(map[string]time.Time),
+		lastSweep: time.Now(),
 	}
 }
 
-// getLimiter retrieves or creates a limiter for a given IP
+// getLimiter retrieves or creates a limiter for a given IP. Limiters of IPs
+// that went quiet are dropped now and then, so anonymous traffic does not
+// grow the map forever.
 func (rl *IPRateLimiter) getLimiter(ip string) *rate.Limiter {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
+	now := time.Now()
+	if now.Sub(rl.lastSweep) > limiterIdle {
+		for seenIP, at := range rl.seen {
+			if now.Sub(at) > limiterIdle {
+				delete(rl.limiters, seenIP)
+				delete(rl.seen, seenIP)
+			}
+		}
+		rl.lastSweep = now
+	}
+
 	limiter, exists := rl.limiters[ip]
 	if !exists {
 		limiter = rate.NewLimiter(rl.rate, rl.burst)
 		rl.limiters[ip] = limiter
-		// Optional: Add cleanup logic for old limiters
-		// e.g., schedule a check to remove limiters not accessed recently
 	}
+	rl.seen[ip] = now
 	return limiter
 }
 
+// Allow reports whether the IP may make another request now
+func (rl *IPRateLimiter) Allow(ip string) bool {
+	return rl.getLimiter(ip).Allow()
+}
+
+// PrefixRateLimiterInterceptor limits the calls of every client IP to the
+// methods starting with prefix, e.g. "/inkMe.public." for the API anyone can
+// call without a session. The IP is the peer's: behind a proxy all clients
+// share the proxy's limit, while a forwarded header could be forged to dodge
+// it.
+func PrefixRateLimiterInterceptor(prefix string, r rate.Limit, burst int) grpc.UnaryServerInterceptor {
+	ipLimiter := NewIPRateLimiter(r, burst)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, prefix) {
+			return handler(ctx, req)
+		}
+		if clientIP := peerIP(ctx); clientIP != "" && !ipLimiter.Allow(clientIP) {
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for method %s", info.FullMethod)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// peerIP is the address of the calling client, or "" when it is unknown
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	switch addr := p.Addr.(type) {
+	case *net.TCPAddr:
+		return addr.IP.String()
+	case *net.UnixAddr:
+		return "unix:" + addr.Name
+	default:
+		return p.Addr.String()
+	}
+}
+
 // RateLimiterInterceptor provides rate limiting based on client IP
 func RateLimiterInterceptor() grpc.UnaryServerInterceptor {
 	// Configure the limiter (e.g., 5 requests per second with a burst of 10)
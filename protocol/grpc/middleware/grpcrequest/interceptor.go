@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	grpcmw "github.com/grpc-ecosystem/go-grpc-middleware/v2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
@@ -32,3 +33,18 @@ func RequestIDMiddleware() grpc.UnaryServerInterceptor {
 		return resp, err
 	}
 }
+
+// StreamRequestIDMiddleware is the streaming counterpart of RequestIDMiddleware
+func StreamRequestIDMiddleware() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		requestID := uuid.New().String()
+
+		if err := ss.SendHeader(metadata.Pairs("request-id", requestID)); err != nil {
+			return err
+		}
+
+		wrapped := grpcmw.WrapServerStream(ss)
+		wrapped.WrappedContext = context.WithValue(ss.Context(), RequestIDKey{}, requestID)
+		return handler(srv, wrapped)
+	}
+}
@@ -2,80 +2,131 @@ package grpctenantinterceptor
 
 import (
 	"context"
-	"fmt"
+	"net"
 	"strings"
 
+	grpcmw "github.com/grpc-ecosystem/go-grpc-middleware/v2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+
+	"github.com/FACorreiaa/ink-app-backend-grpc/internal/domain"
 )
 
-type tenantKey struct{}
+// TenantValidator checks that a tenant may be served. It returns a gRPC status
+// error for unknown, suspended or deleted tenants.
+type TenantValidator func(ctx context.Context, tenant string) error
+
+// tenantlessPrefixes are services that do not run on behalf of a tenant
+var tenantlessPrefixes = []string{
+	"/inkMe.admin.",
+	"/grpc.reflection.",
+	"/grpc.health.",
+}
 
-// TenantInterceptor extracts the tenant ID and injects it into the context.
-func TenantInterceptor(tenantValidator func(string) bool) grpc.UnaryServerInterceptor {
+// TenantInterceptor resolves the tenant of a unary call, validates it and stores
+// it in the context for domain.ExtractTenantFromContext.
+func TenantInterceptor(validator TenantValidator) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Error(codes.Unauthenticated, "metadata is not provided")
+		if isTenantless(info.FullMethod) {
+			return handler(ctx, req)
 		}
 
-		var tenantID string
-
-		// 1. Try X-Tenant Header
-		tenantValues := md.Get("X-Tenant")
-		if len(tenantValues) > 0 && tenantValues[0] != "" {
-			tenantID = tenantValues[0]
-		} else {
-			// 2. Try :authority (host) subdomain
-			hostValues := md.Get(":authority")
-			if len(hostValues) > 0 {
-				host := hostValues[0]
-				// Basic subdomain extraction (adjust if needed for ports, complex hosts)
-				parts := strings.Split(host, ".")
-				// Assuming structure like tenant.domain.com or tenant.localhost:port
-				if len(parts) > 1 && parts[0] != "" && parts[0] != "localhost" {
-					tenantID = parts[0]
-				}
-			}
+		newCtx, err := resolve(ctx, validator)
+		if err != nil {
+			return nil, err
 		}
+		return handler(newCtx, req)
+	}
+}
 
-		if tenantID == "" {
-			return nil, status.Error(codes.Unauthenticated, "tenant identifier not found in request metadata")
+// StreamTenantInterceptor is the streaming counterpart of TenantInterceptor
+func StreamTenantInterceptor(validator TenantValidator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isTenantless(info.FullMethod) {
+			return handler(srv, ss)
 		}
 
-		// 3. Validate Tenant (Optional but Recommended)
-		if tenantValidator != nil {
-			if !tenantValidator(tenantID) {
-				// Log the attempt with the invalid tenant ID
-				fmt.Printf("Attempt with invalid tenant ID: %s\n", tenantID)
-				return nil, status.Errorf(codes.PermissionDenied, "invalid tenant identifier: %s", tenantID)
-			}
+		newCtx, err := resolve(ss.Context(), validator)
+		if err != nil {
+			return err
 		}
+		wrapped := grpcmw.WrapServerStream(ss)
+		wrapped.WrappedContext = newCtx
+		return handler(srv, wrapped)
+	}
+}
 
-		// 4. Inject tenant ID into context
-		newCtx := context.WithValue(ctx, tenantKey{}, tenantID)
+// GetTenantFromContext retrieves the tenant injected by the interceptor.
+func GetTenantFromContext(ctx context.Context) (string, error) {
+	return domain.ExtractTenantFromContext(ctx)
+}
 
-		// Proceed with the handler
-		return handler(newCtx, req)
+func resolve(ctx context.Context, validator TenantValidator) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "metadata is not provided")
+	}
+
+	tenant, err := TenantFromMetadata(md)
+	if err != nil {
+		return nil, err
+	}
+
+	if validator != nil {
+		if err = validator(ctx, tenant); err != nil {
+			return nil, err
+		}
 	}
+
+	// A token is only valid for the studio it was issued by, so an
+	// authenticated call must carry a token with a tenant claim
+	if tokenTenant, ok := ctx.Value(domain.TokenTenantKey).(string); ok {
+		if tokenTenant == "" {
+			return nil, status.Error(codes.Unauthenticated, "token has no tenant claim")
+		}
+		if tokenTenant != tenant {
+			return nil, status.Error(codes.PermissionDenied, "token was issued for a different tenant")
+		}
+	}
+
+	return context.WithValue(ctx, domain.TenantKey, tenant), nil
 }
 
-// GetTenantFromContext retrieves the tenant ID injected by the interceptor.
-func GetTenantFromContext(ctx context.Context) (string, error) {
-	tenantID, ok := ctx.Value(tenantKey{}).(string)
-	if !ok || tenantID == "" {
-		// This should ideally not happen if the interceptor ran correctly
-		return "", status.Error(codes.Internal, "tenant ID not found in context")
+// TenantFromMetadata reads the tenant from the X-Tenant header, falling back to
+// the first label of the :authority host. Ports are ignored; hosts without a
+// subdomain (localhost, bare IPs) must send X-Tenant.
+func TenantFromMetadata(md metadata.MD) (string, error) {
+	if values := md.Get("X-Tenant"); len(values) > 0 && values[0] != "" {
+		return values[0], nil
+	}
+
+	values := md.Get(":authority")
+	if len(values) == 0 || values[0] == "" {
+		return "", status.Error(codes.Unauthenticated, "tenant identifier not found in request metadata")
 	}
-	return tenantID, nil
+
+	host := values[0]
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return "", status.Error(codes.Unauthenticated, "cannot resolve tenant from an IP address; send X-Tenant")
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) < 2 || parts[0] == "" {
+		return "", status.Error(codes.Unauthenticated, "cannot resolve tenant from host; send X-Tenant")
+	}
+	return parts[0], nil
 }
 
-// Example Tenant Validator (replace with your actual logic)
-func SimpleTenantValidator(tenantID string) bool {
-	// In a real app, query a DB table `tenants` or check a cached list
-	allowedTenants := map[string]bool{"artist1": true, "studioxyz": true, "test": true}
-	fmt.Printf("Validating tenant: %s\n", tenantID) // Log validation
-	return allowedTenants[tenantID]
+func isTenantless(fullMethod string) bool {
+	for _, prefix := range tenantlessPrefixes {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return true
+		}
+	}
+	return false
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/golang-jwt/jwt/v5"
+	grpcmw "github.com/grpc-ecosystem/go-grpc-middleware/v2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -16,6 +17,19 @@ import (
 
 // Claims struct
 
+// unauthenticatedMethods can be called without an access token
+var unauthenticatedMethods = map[string]bool{
+	"/inkMe.studio.AuthService/Register":    true,
+	"/inkMe.studio.AuthService/Login":       true,
+	"/inkMe.studio.AuthService/GetAllUsers": true,
+	// Guarded by the admin token instead of a user session
+	"/inkMe.admin.TenantService/ProvisionTenant": true,
+	// Read-only studio pages for the public, rate limited per IP
+	"/inkMe.public.PublicService/GetStudioProfile": true,
+	"/inkMe.public.PublicService/ListGallery":      true,
+	"/inkMe.public.PublicService/ListOpenSlots":    true,
+}
+
 func InterceptorSession() grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
@@ -23,46 +37,68 @@ func InterceptorSession() grpc.UnaryServerInterceptor {
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-		unauthenticatedMethods := map[string]bool{
-			"/inkMe.studio.AuthService/Register":    true,
-			"/inkMe.studio.AuthService/Login":       true,
-			"/inkMe.studio.AuthService/GetAllUsers": true,
-		}
 		if unauthenticatedMethods[info.FullMethod] {
 			return handler(ctx, req)
 		}
 
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Error(codes.Unauthenticated, "missing context metadata")
+		newCtx, err := authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(newCtx, req)
+	}
+}
+
+// StreamInterceptorSession is the streaming counterpart of InterceptorSession
+func StreamInterceptorSession() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if unauthenticatedMethods[info.FullMethod] {
+			return handler(srv, ss)
 		}
 
-		authHeader := md["authorization"]
-		//if len(authHeader) == 0 || len(authHeader[0]) < 8 || authHeader[0][:7] != "Bearer " {
-		//	return nil, status.Error(codes.Unauthenticated, "missing or invalid auth token")
-		//}
-		//
-		//tokenString := authHeader[0][7:]
-		if len(authHeader) == 0 {
-			return nil, status.Error(codes.Unauthenticated, "missing or invalid auth token")
+		newCtx, err := authenticate(ss.Context())
+		if err != nil {
+			return err
 		}
+		wrapped := grpcmw.WrapServerStream(ss)
+		wrapped.WrappedContext = newCtx
+		return handler(srv, wrapped)
+	}
+}
 
-		tokenString := authHeader[0]
+// authenticate validates the access token and stores its claims in the context
+func authenticate(ctx context.Context) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing context metadata")
+	}
 
-		claims := &domain.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return domain.JwtSecretKey, nil
-		})
+	authHeader := md["authorization"]
+	//if len(authHeader) == 0 || len(authHeader[0]) < 8 || authHeader[0][:7] != "Bearer " {
+	//	return nil, status.Error(codes.Unauthenticated, "missing or invalid auth token")
+	//}
+	//
+	//tokenString := authHeader[0][7:]
+	if len(authHeader) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid auth token")
+	}
 
-		if err != nil || !token.Valid {
-			return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
-		}
+	tokenString := authHeader[0]
 
-		newCtx := context.WithValue(ctx, domain.UserIDKey, claims.UserID)
-		newCtx = context.WithValue(newCtx, domain.RoleKey, claims.Role)
+	claims := &domain.Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return domain.JwtSecretKey, nil
+	})
 
-		return handler(newCtx, req)
+	if err != nil || !token.Valid {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
 	}
+
+	newCtx := context.WithValue(ctx, domain.UserIDKey, claims.UserID)
+	newCtx = context.WithValue(newCtx, domain.RoleKey, claims.Role)
+	// Checked against the request's tenant by the tenant interceptor
+	newCtx = context.WithValue(newCtx, domain.TokenTenantKey, claims.Tenant)
+	return newCtx, nil
 }
 
 func hasPermission(userPermissions []string, requiredPermission string) bool {
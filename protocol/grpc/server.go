@@ -17,6 +17,7 @@ import (
 	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/middleware/grpcrecovery"
 	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/middleware/grpcrequest"
 	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/middleware/grpcspan"
+	grpctenant "github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/middleware/grpctenant"
 	"github.com/FACorreiaa/ink-app-backend-grpc/protocol/grpc/middleware/session"
 )
 
@@ -27,6 +28,7 @@ func BootstrapServer(
 	log *zap.Logger,
 	registry *prometheus.Registry,
 	traceProvider trace.TracerProvider, // [currently not used directly, but available if needed
+	tenantValidator grpctenant.TenantValidator,
 	opts ...grpc.ServerOption,
 ) (*grpc.Server, net.Listener, error) {
 
@@ -53,11 +55,18 @@ func BootstrapServer(
 	_, logInterceptor := grpclog.Interceptors(log)
 	_, recoveryInterceptor := grpcrecovery.Interceptors(grpcrecovery.RegisterMetrics(registry))
 	sessionInterceptor := session.InterceptorSession()
+	sessionStreamInterceptor := session.StreamInterceptorSession()
 	requestIDInterceptor := grpcrequest.RequestIDMiddleware()
+	requestIDStreamInterceptor := grpcrequest.StreamRequestIDMiddleware()
 	// Simple rate limiter for demonstration (10 requests/sec, 20 burst).
 	// rateLimiter := grpcratelimit.NewRateLimiter(10, 20)
 	rateLimiter := grpcratelimit.RateLimiterInterceptor()
-	//tenantInterceptor := grpctenant.TenantInterceptor(grpctenant.SimpleTenantValidator())
+	// The public API needs no session, so it is limited per IP before any
+	// other work is done (2 requests/sec, 20 burst for a page load)
+	publicRateLimiter := grpcratelimit.PrefixRateLimiterInterceptor("/inkMe.public.", 2, 20)
+	// Tenant resolution runs after the session so the token's tenant can be checked
+	tenantInterceptor := grpctenant.TenantInterceptor(tenantValidator)
+	tenantStreamInterceptor := grpctenant.StreamTenantInterceptor(tenantValidator)
 	// Base gRPC server options.
 	serverOptions := []grpc.ServerOption{
 		// Adjust keepalive.
@@ -69,7 +78,9 @@ func BootstrapServer(
 			spanInterceptor.Unary,     // OTel first
 			promInterceptor.Unary,     // Prometheus
 			logInterceptor.Unary,      // Logging
+			publicRateLimiter,         // Per-IP limit of the public API
 			sessionInterceptor,        // Session management
+			tenantInterceptor,         // Tenant resolution
 			requestIDInterceptor,      // Request ID injection
 			recoveryInterceptor.Unary, // Recovery from panics
 
@@ -82,6 +93,9 @@ func BootstrapServer(
 			spanInterceptor.Stream,
 			promInterceptor.Stream,
 			logInterceptor.Stream,
+			sessionStreamInterceptor,
+			tenantStreamInterceptor,
+			requestIDStreamInterceptor,
 			recoveryInterceptor.Stream,
 		),
 	}
@@ -0,0 +1,201 @@
+// Package structrpc exposes Go handlers as gRPC methods whose wire messages are
+// google.protobuf.Struct values. It is used for services whose definitions have
+// not landed in ink-app-backend-protos yet, so they can still go through the
+// regular server, interceptor chain and gRPC clients.
+package structrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Decode converts a Struct message into v using its JSON field names
+func Decode(in *structpb.Struct, v any) error {
+	if in == nil {
+		in = &structpb.Struct{}
+	}
+	data, err := in.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Encode converts v into a Struct message using its JSON field names
+func Encode(v any) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	out := &structpb.Struct{}
+	if err = out.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Method is a unary or streaming method of a service
+type Method struct {
+	unary  *grpc.MethodDesc
+	stream *grpc.StreamDesc
+}
+
+// Unary builds the method descriptor for a unary RPC served by fn
+func Unary[Req, Res any](serviceName, methodName string, fn func(ctx context.Context, req *Req) (*Res, error)) Method {
+	fullMethod := fmt.Sprintf("/%s/%s", serviceName, methodName)
+
+	return Method{unary: &grpc.MethodDesc{
+		MethodName: methodName,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			in := new(structpb.Struct)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+
+			handler := func(ctx context.Context, msg any) (any, error) {
+				req := new(Req)
+				if err := Decode(msg.(*structpb.Struct), req); err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+				}
+				res, err := fn(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+				return Encode(res)
+			}
+
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+			return interceptor(ctx, in, info, handler)
+		},
+	}}
+}
+
+// Sender is the sending side of a server-streaming RPC
+type Sender[Res any] struct {
+	grpc.ServerStream
+}
+
+// Send encodes res and sends it to the client
+func (s *Sender[Res]) Send(res *Res) error {
+	msg, err := Encode(res)
+	if err != nil {
+		return status.Errorf(codes.Internal, "invalid response: %v", err)
+	}
+	return s.SendMsg(msg)
+}
+
+// ServerStream builds the method descriptor for a server-streaming RPC served
+// by fn. Stream interceptors run around it like around any other stream.
+func ServerStream[Req, Res any](methodName string, fn func(req *Req, stream *Sender[Res]) error) Method {
+	return Method{stream: &grpc.StreamDesc{
+		StreamName:    methodName,
+		ServerStreams: true,
+		Handler: func(srv any, stream grpc.ServerStream) error {
+			in := new(structpb.Struct)
+			if err := stream.RecvMsg(in); err != nil {
+				return err
+			}
+			req := new(Req)
+			if err := Decode(in, req); err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+			}
+			return fn(req, &Sender[Res]{ServerStream: stream})
+		},
+	}}
+}
+
+// Stream is a bidirectional stream. Recv and Send may be called from different
+// goroutines, but neither from several at once.
+type Stream[Req, Res any] struct {
+	Sender[Res]
+}
+
+// Recv receives and decodes the next request. It returns io.EOF once the
+// client has closed its side.
+func (s *Stream[Req, Res]) Recv() (*Req, error) {
+	return recv[Req](s.ServerStream)
+}
+
+// Receiver is the receiving side of a client-streaming RPC
+type Receiver[Req any] struct {
+	grpc.ServerStream
+}
+
+// Recv receives and decodes the next request. It returns io.EOF once the
+// client has sent all of them.
+func (r *Receiver[Req]) Recv() (*Req, error) {
+	return recv[Req](r.ServerStream)
+}
+
+func recv[Req any](stream grpc.ServerStream) (*Req, error) {
+	in := new(structpb.Struct)
+	if err := stream.RecvMsg(in); err != nil {
+		return nil, err
+	}
+	req := new(Req)
+	if err := Decode(in, req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+	}
+	return req, nil
+}
+
+// ClientStream builds the method descriptor for a client-streaming RPC served
+// by fn, which reads the requests and returns the single response
+func ClientStream[Req, Res any](methodName string, fn func(stream *Receiver[Req]) (*Res, error)) Method {
+	return Method{stream: &grpc.StreamDesc{
+		StreamName:    methodName,
+		ClientStreams: true,
+		Handler: func(srv any, stream grpc.ServerStream) error {
+			res, err := fn(&Receiver[Req]{ServerStream: stream})
+			if err != nil {
+				return err
+			}
+			out, err := Encode(res)
+			if err != nil {
+				return status.Errorf(codes.Internal, "invalid response: %v", err)
+			}
+			return stream.SendMsg(out)
+		},
+	}}
+}
+
+// BidiStream builds the method descriptor for a bidirectional streaming RPC
+// served by fn
+func BidiStream[Req, Res any](methodName string, fn func(stream *Stream[Req, Res]) error) Method {
+	return Method{stream: &grpc.StreamDesc{
+		StreamName:    methodName,
+		ServerStreams: true,
+		ClientStreams: true,
+		Handler: func(srv any, stream grpc.ServerStream) error {
+			return fn(&Stream[Req, Res]{Sender: Sender[Res]{ServerStream: stream}})
+		},
+	}}
+}
+
+// ServiceDesc assembles a service descriptor from method descriptors. Register it
+// with grpc.Server.RegisterService, passing the implementation as the server.
+func ServiceDesc(serviceName string, methods ...Method) *grpc.ServiceDesc {
+	desc := &grpc.ServiceDesc{
+		ServiceName: serviceName,
+		HandlerType: (*any)(nil),
+		Metadata:    "structrpc",
+	}
+	for _, method := range methods {
+		if method.unary != nil {
+			desc.Methods = append(desc.Methods, *method.unary)
+		}
+		if method.stream != nil {
+			desc.Streams = append(desc.Streams, *method.stream)
+		}
+	}
+	return desc
+}